@@ -0,0 +1,158 @@
+package queen
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"time"
+)
+
+// Analytics summarizes the history log for dashboards, as returned by
+// Queen.Analytics.
+type Analytics struct {
+	// Runs is the number of runs in the log and Executions the number of
+	// migration executions, rollbacks and failures included.
+	Runs       int `json:"runs"`
+	Executions int `json:"executions"`
+
+	// Failures is the number of failed executions.
+	Failures int `json:"failures"`
+
+	// Migrations holds the statistics of every migration in the log,
+	// slowest first by P95.
+	Migrations []MigrationStats `json:"migrations"`
+
+	// LockWaits holds the lock wait of every run, oldest first, to chart
+	// contention for the migration lock over time.
+	LockWaits []LockWaitSample `json:"lock_waits"`
+
+	// LockWaitP95 is the 95th percentile of LockWaits.
+	LockWaitP95 time.Duration `json:"lock_wait_p95_ns"`
+}
+
+// MigrationStats summarizes the executions of one migration.
+type MigrationStats struct {
+	// Version and Name identify the migration.
+	Version string `json:"version"`
+	Name    string `json:"name"`
+
+	// Applied, Rollbacks and Failures count its successful up, successful
+	// down and failed executions.
+	Applied   int `json:"applied"`
+	Rollbacks int `json:"rollbacks"`
+	Failures  int `json:"failures"`
+
+	// Mean, P50, P95 and Max are over its successful up executions, 0 if
+	// there are none.
+	Mean time.Duration `json:"mean_ns"`
+	P50  time.Duration `json:"p50_ns"`
+	P95  time.Duration `json:"p95_ns"`
+	Max  time.Duration `json:"max_ns"`
+
+	// LastRun is when it was last executed, in either direction.
+	LastRun time.Time `json:"last_run"`
+
+	// LastError is the error of its last execution if it failed.
+	LastError string `json:"last_error,omitempty"`
+}
+
+// LockWaitSample is how long a run waited for the migration lock.
+type LockWaitSample struct {
+	RunID     string        `json:"run_id"`
+	StartedAt time.Time     `json:"started_at"`
+	LockWait  time.Duration `json:"lock_wait_ns"`
+}
+
+// Analytics summarizes the history log: per-migration duration
+// percentiles and failure counts, and the lock wait of every run.
+// History recorded before lock waits were tracked reads as a wait of 0.
+//
+// Returns ErrUnsupported if the driver doesn't implement HistoryRecorder.
+func (q *Queen) Analytics(ctx context.Context) (*Analytics, error) {
+	history, err := q.History(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return analyze(history), nil
+}
+
+// analyze summarizes history, oldest entry first.
+func analyze(history []HistoryEntry) *Analytics {
+	a := &Analytics{
+		Executions: len(history),
+		Migrations: []MigrationStats{},
+		LockWaits:  []LockWaitSample{},
+	}
+
+	stats := make(map[string]*MigrationStats)
+	durations := make(map[string][]time.Duration)
+	runs := make(map[string]bool)
+
+	for _, e := range history {
+		s, ok := stats[e.Version]
+		if !ok {
+			s = &MigrationStats{Version: e.Version}
+			stats[e.Version] = s
+		}
+		s.Name = e.Name
+		s.LastRun = e.StartedAt
+		s.LastError = e.Error
+
+		switch {
+		case e.Error != "":
+			s.Failures++
+			a.Failures++
+		case e.Down:
+			s.Rollbacks++
+		default:
+			s.Applied++
+			durations[e.Version] = append(durations[e.Version], e.Duration)
+		}
+
+		// Entries of a run share its lock wait, sample it once
+		if e.RunID != "" && !runs[e.RunID] {
+			runs[e.RunID] = true
+			a.LockWaits = append(a.LockWaits, LockWaitSample{RunID: e.RunID, StartedAt: e.StartedAt, LockWait: e.LockWait})
+		}
+	}
+	a.Runs = len(runs)
+
+	for version, s := range stats {
+		ds := durations[version]
+		if len(ds) > 0 {
+			slices.Sort(ds)
+			var total time.Duration
+			for _, d := range ds {
+				total += d
+			}
+			s.Mean = total / time.Duration(len(ds))
+			s.P50 = percentile(ds, 50)
+			s.P95 = percentile(ds, 95)
+			s.Max = ds[len(ds)-1]
+		}
+		a.Migrations = append(a.Migrations, *s)
+	}
+	slices.SortFunc(a.Migrations, func(x, y MigrationStats) int {
+		return cmp.Or(cmp.Compare(y.P95, x.P95), cmp.Compare(x.Version, y.Version))
+	})
+
+	waits := make([]time.Duration, len(a.LockWaits))
+	for i, s := range a.LockWaits {
+		waits[i] = s.LockWait
+	}
+	slices.Sort(waits)
+	a.LockWaitP95 = percentile(waits, 95)
+
+	return a
+}
+
+// percentile returns the p-th percentile of sorted by the nearest-rank
+// method, 0 if sorted is empty.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := (p*len(sorted) + 99) / 100
+	return sorted[max(rank, 1)-1]
+}
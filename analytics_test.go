@@ -0,0 +1,80 @@
+package queen_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/honeynil/queen"
+	"github.com/honeynil/queen/drivers/mock"
+)
+
+func TestAnalytics(t *testing.T) {
+	driver := mock.New()
+	q := queen.New(driver)
+	ctx := context.Background()
+
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	entries := []queen.HistoryEntry{
+		{RunID: "r1", Version: "001", Name: "create_users", Duration: 10 * time.Millisecond, LockWait: time.Second},
+		{RunID: "r1", Version: "002", Name: "backfill", Duration: 2 * time.Second, LockWait: time.Second},
+		{RunID: "r2", Version: "002", Name: "backfill", Down: true, Duration: time.Second},
+		{RunID: "r3", Version: "002", Name: "backfill", Duration: 4 * time.Second, LockWait: 5 * time.Second},
+		{RunID: "r4", Version: "003", Name: "broken", Error: "boom", LockWait: 2 * time.Second},
+		// Recorded before run IDs, counted but not sampled for lock waits
+		{Version: "001", Name: "create_users", Duration: 30 * time.Millisecond},
+	}
+	for i, e := range entries {
+		e.StartedAt = start.Add(time.Duration(i) * time.Hour)
+		if err := driver.RecordHistory(ctx, e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	a, err := q.Analytics(ctx)
+	if err != nil {
+		t.Fatalf("Analytics failed: %v", err)
+	}
+
+	if a.Runs != 4 || a.Executions != 6 || a.Failures != 1 {
+		t.Errorf("Runs, Executions, Failures = %d, %d, %d; want 4, 6, 1", a.Runs, a.Executions, a.Failures)
+	}
+
+	if len(a.Migrations) != 3 {
+		t.Fatalf("Expected 3 migrations, got %+v", a.Migrations)
+	}
+	// Slowest first
+	backfill, users, broken := a.Migrations[0], a.Migrations[1], a.Migrations[2]
+	if backfill.Version != "002" || backfill.Applied != 2 || backfill.Rollbacks != 1 ||
+		backfill.Mean != 3*time.Second || backfill.P50 != 2*time.Second || backfill.P95 != 4*time.Second || backfill.Max != 4*time.Second {
+		t.Errorf("002 stats = %+v", backfill)
+	}
+	if users.Version != "001" || users.Applied != 2 || users.Mean != 20*time.Millisecond || users.P95 != 30*time.Millisecond {
+		t.Errorf("001 stats = %+v", users)
+	}
+	if broken.Version != "003" || broken.Failures != 1 || broken.LastError != "boom" || broken.P95 != 0 {
+		t.Errorf("003 stats = %+v", broken)
+	}
+
+	wantWaits := []time.Duration{time.Second, 0, 5 * time.Second, 2 * time.Second}
+	if len(a.LockWaits) != len(wantWaits) {
+		t.Fatalf("Expected %d lock wait samples, got %+v", len(wantWaits), a.LockWaits)
+	}
+	for i, w := range wantWaits {
+		if a.LockWaits[i].LockWait != w {
+			t.Errorf("Lock wait %d = %v; want %v", i, a.LockWaits[i].LockWait, w)
+		}
+	}
+	if a.LockWaitP95 != 5*time.Second {
+		t.Errorf("LockWaitP95 = %v; want 5s", a.LockWaitP95)
+	}
+}
+
+func TestAnalytics_Unsupported(t *testing.T) {
+	q := queen.New(minimalDriver{mock.New()})
+
+	if _, err := q.Analytics(context.Background()); !errors.Is(err, queen.ErrUnsupported) {
+		t.Errorf("Analytics = %v; want ErrUnsupported", err)
+	}
+}
@@ -0,0 +1,117 @@
+// Package archive provides queen.ArchiveSink implementations that persist
+// run reports as JSON artifacts.
+//
+// ObjectSink writes to any object store through the minimal ObjectStore
+// interface, so Queen does not depend on a particular cloud SDK. Adapting
+// the AWS SDK for S3 takes a few lines:
+//
+//	store := archive.ObjectStoreFunc(func(ctx context.Context, key string, body []byte) error {
+//	    _, err := client.PutObject(ctx, &s3.PutObjectInput{
+//	        Bucket:      aws.String("migrations-audit"),
+//	        Key:         aws.String(key),
+//	        Body:        bytes.NewReader(body),
+//	        ContentType: aws.String("application/json"),
+//	    })
+//	    return err
+//	})
+//
+// and likewise for Google Cloud Storage:
+//
+//	store := archive.ObjectStoreFunc(func(ctx context.Context, key string, body []byte) error {
+//	    w := client.Bucket("migrations-audit").Object(key).NewWriter(ctx)
+//	    w.ContentType = "application/json"
+//	    if _, err := w.Write(body); err != nil {
+//	        _ = w.Close()
+//	        return err
+//	    }
+//	    return w.Close()
+//	})
+//
+// Then configure Queen with the sink:
+//
+//	q := queen.NewWithConfig(driver, &queen.Config{
+//	    Archive: archive.NewObjectSink(store, "prod/"),
+//	})
+package archive
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/honeynil/queen"
+)
+
+// ObjectStore stores a blob under a key.
+type ObjectStore interface {
+	PutObject(ctx context.Context, key string, body []byte) error
+}
+
+// ObjectStoreFunc adapts a function to the ObjectStore interface.
+type ObjectStoreFunc func(ctx context.Context, key string, body []byte) error
+
+// PutObject calls f(ctx, key, body).
+func (f ObjectStoreFunc) PutObject(ctx context.Context, key string, body []byte) error {
+	return f(ctx, key, body)
+}
+
+// ObjectSink archives run reports to an ObjectStore.
+//
+// Reports are stored under "<prefix>YYYY/MM/DD/<run id>.json" so that
+// lifecycle rules and listings can work by date.
+type ObjectSink struct {
+	store  ObjectStore
+	prefix string
+}
+
+// NewObjectSink creates an ObjectSink writing keys under prefix.
+func NewObjectSink(store ObjectStore, prefix string) *ObjectSink {
+	return &ObjectSink{
+		store:  store,
+		prefix: prefix,
+	}
+}
+
+// Archive implements queen.ArchiveSink.
+func (s *ObjectSink) Archive(ctx context.Context, report *queen.RunReport) error {
+	body, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return s.store.PutObject(ctx, s.prefix+Key(report), body)
+}
+
+// DirSink archives run reports as files in a local directory, using the
+// same layout as ObjectSink. It is useful for development and for setups
+// where another agent ships files to long-term storage.
+type DirSink struct {
+	dir string
+}
+
+// NewDirSink creates a DirSink rooted at dir.
+func NewDirSink(dir string) *DirSink {
+	return &DirSink{dir: dir}
+}
+
+// Archive implements queen.ArchiveSink.
+func (s *DirSink) Archive(ctx context.Context, report *queen.RunReport) error {
+	body, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	name := filepath.Join(s.dir, filepath.FromSlash(Key(report)))
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(name, body, 0o644)
+}
+
+// Key returns the relative storage key for a report: "YYYY/MM/DD/<run id>.json".
+func Key(report *queen.RunReport) string {
+	return path.Join(report.StartedAt.UTC().Format("2006/01/02"), report.ID+".json")
+}
@@ -0,0 +1,94 @@
+package archive_test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/honeynil/queen"
+	"github.com/honeynil/queen/archive"
+	"github.com/honeynil/queen/drivers/mock"
+)
+
+func noop(ctx context.Context, tx *sql.Tx) error { return nil }
+
+func TestObjectSink(t *testing.T) {
+	objects := map[string][]byte{}
+	store := archive.ObjectStoreFunc(func(ctx context.Context, key string, body []byte) error {
+		objects[key] = body
+		return nil
+	})
+
+	q := queen.NewWithConfig(mock.New(), &queen.Config{
+		Archive: archive.NewObjectSink(store, "prod/"),
+	})
+	q.MustAdd(queen.M{Version: "001", Name: "first", UpFunc: noop})
+	q.MustAdd(queen.M{Version: "002", Name: "second", UpFunc: noop})
+
+	if err := q.Up(context.Background()); err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+
+	if len(objects) != 1 {
+		t.Fatalf("Expected 1 archived report, got %d", len(objects))
+	}
+
+	for key, body := range objects {
+		if !strings.HasPrefix(key, "prod/") || !strings.HasSuffix(key, ".json") {
+			t.Errorf("Unexpected key %q", key)
+		}
+
+		var report queen.RunReport
+		if err := json.Unmarshal(body, &report); err != nil {
+			t.Fatalf("Failed to decode report: %v", err)
+		}
+		if report.Operation != queen.OperationUp {
+			t.Errorf("operation = %q, want %q", report.Operation, queen.OperationUp)
+		}
+		if len(report.Plan) != 2 || len(report.Migrations) != 2 {
+			t.Errorf("Expected 2 planned and 2 executed migrations, got %d/%d",
+				len(report.Plan), len(report.Migrations))
+		}
+	}
+}
+
+func TestDirSink(t *testing.T) {
+	dir := t.TempDir()
+	upErr := errors.New("boom")
+
+	q := queen.NewWithConfig(mock.New(), &queen.Config{
+		Archive: archive.NewDirSink(dir),
+	})
+	q.MustAdd(queen.M{
+		Version: "001",
+		Name:    "failing",
+		UpFunc:  func(ctx context.Context, tx *sql.Tx) error { return upErr },
+	})
+
+	if err := q.Up(context.Background()); !errors.Is(err, upErr) {
+		t.Fatalf("Expected migration error, got %v", err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*", "*", "*", "*.json"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("Expected 1 archived file, got %v (err %v)", files, err)
+	}
+
+	body, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatalf("Failed to read report: %v", err)
+	}
+
+	var report queen.RunReport
+	if err := json.Unmarshal(body, &report); err != nil {
+		t.Fatalf("Failed to decode report: %v", err)
+	}
+	if report.Error == "" || len(report.Migrations) != 1 || report.Migrations[0].Error == "" {
+		t.Errorf("Expected failed run to be recorded, got %+v", report)
+	}
+}
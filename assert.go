@@ -0,0 +1,205 @@
+package queen
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Assertion is a check of the database made by Queen.Assert, built with
+// TableExists, ColumnType, IndexExists, ConstraintExists or
+// RowCountBetween. Schema checks go through the driver's Introspector, so
+// the same assertions work on every database.
+//
+// Use them in a hook as a precondition, or in a test after Up:
+//
+//	q.Hooks().MustRegister(queen.Hook{Name: "precondition", Func: func(ctx context.Context, e queen.Event) error {
+//	    if e.Kind != queen.EventBeforeUp || e.Migration.Version != "042" {
+//	        return nil
+//	    }
+//	    return q.Assert(ctx, queen.TableExists("users"), queen.ColumnType("users", "email", "text"))
+//	}})
+type Assertion struct {
+	desc  string
+	check func(ctx context.Context, q *Queen) error
+}
+
+// String describes the assertion, e.g. "table users exists".
+func (a Assertion) String() string {
+	return a.desc
+}
+
+// Assert checks every assertion and returns the failures, each matching
+// ErrAssertion, joined with errors.Join. Schema assertions return
+// ErrUnsupported if the driver doesn't implement Introspector.
+func (q *Queen) Assert(ctx context.Context, assertions ...Assertion) error {
+	if q.driver == nil {
+		return ErrNoDriver
+	}
+
+	var errs []error
+	for _, a := range assertions {
+		if err := a.check(ctx, q); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// failed returns the ErrAssertion error of a, with the reason it failed.
+func (a Assertion) failed(format string, args ...any) error {
+	return fmt.Errorf("%w: %s: %s", ErrAssertion, a.desc, fmt.Sprintf(format, args...))
+}
+
+// introspector returns the driver's Introspector for assertion a.
+func (q *Queen) introspector(a Assertion) (Introspector, error) {
+	in, ok := optional[Introspector](q.driver, FeatureIntrospection)
+	if !ok {
+		return nil, fmt.Errorf("%w: introspection for %s", ErrUnsupported, a.desc)
+	}
+	return in, nil
+}
+
+// TableExists asserts that the base table exists.
+func TableExists(table string) Assertion {
+	a := Assertion{desc: "table " + table + " exists"}
+	a.check = func(ctx context.Context, q *Queen) error {
+		in, err := q.introspector(a)
+		if err != nil {
+			return err
+		}
+
+		tables, err := in.ListTables(ctx)
+		if err != nil {
+			return err
+		}
+		if !slices.Contains(tables, table) {
+			return a.failed("no such table")
+		}
+		return nil
+	}
+	return a
+}
+
+// ColumnType asserts that the column of table exists and has type typ, in
+// the database's notation and compared case-insensitively. A typ without
+// parameters matches any, e.g. "varchar" matches "varchar(255)".
+func ColumnType(table, column, typ string) Assertion {
+	a := Assertion{desc: fmt.Sprintf("column %s.%s is %s", table, column, typ)}
+	a.check = func(ctx context.Context, q *Queen) error {
+		in, err := q.introspector(a)
+		if err != nil {
+			return err
+		}
+
+		columns, err := in.ListColumns(ctx, table)
+		if errors.Is(err, ErrObjectNotFound) {
+			return a.failed("no such table")
+		}
+		if err != nil {
+			return err
+		}
+
+		i := slices.IndexFunc(columns, func(c Column) bool { return c.Name == column })
+		if i < 0 {
+			return a.failed("no such column")
+		}
+		got := strings.ToLower(columns[i].Type)
+		if want := strings.ToLower(typ); got != want && !strings.HasPrefix(got, want+"(") {
+			return a.failed("type is %s", columns[i].Type)
+		}
+		return nil
+	}
+	return a
+}
+
+// IndexExists asserts that table has the index, including indexes backing
+// primary key and unique constraints.
+func IndexExists(table, index string) Assertion {
+	a := Assertion{desc: fmt.Sprintf("index %s on %s exists", index, table)}
+	a.check = func(ctx context.Context, q *Queen) error {
+		in, err := q.introspector(a)
+		if err != nil {
+			return err
+		}
+
+		indexes, err := in.ListIndexes(ctx, table)
+		if errors.Is(err, ErrObjectNotFound) {
+			return a.failed("no such table")
+		}
+		if err != nil {
+			return err
+		}
+
+		if !slices.ContainsFunc(indexes, func(idx Index) bool { return idx.Name == index }) {
+			return a.failed("no such index")
+		}
+		return nil
+	}
+	return a
+}
+
+// ConstraintExists asserts that table has the named constraint: a primary
+// key or unique constraint backed by an index of that name, or a
+// constraint declared with CONSTRAINT name in the table's DDL, such as a
+// foreign key or check.
+func ConstraintExists(table, name string) Assertion {
+	a := Assertion{desc: fmt.Sprintf("constraint %s on %s exists", name, table)}
+	a.check = func(ctx context.Context, q *Queen) error {
+		in, err := q.introspector(a)
+		if err != nil {
+			return err
+		}
+
+		indexes, err := in.ListIndexes(ctx, table)
+		if errors.Is(err, ErrObjectNotFound) {
+			return a.failed("no such table")
+		}
+		if err != nil {
+			return err
+		}
+		if slices.ContainsFunc(indexes, func(idx Index) bool { return idx.Unique && idx.Name == name }) {
+			return nil
+		}
+
+		ddl, err := in.ObjectDDL(ctx, table)
+		if err != nil {
+			return err
+		}
+		declared := regexp.MustCompile("(?i)\\bCONSTRAINT\\s+[\"`\\[]?" + regexp.QuoteMeta(name) + "[\"`\\]]?\\s")
+		if !declared.MatchString(ddl) {
+			return a.failed("no such constraint")
+		}
+		return nil
+	}
+	return a
+}
+
+// RowCountBetween asserts that table has between min and max rows,
+// inclusive. table must be a plain identifier, optionally qualified with
+// a schema.
+func RowCountBetween(table string, min, max int64) Assertion {
+	a := Assertion{desc: fmt.Sprintf("table %s has %d to %d rows", table, min, max)}
+	a.check = func(ctx context.Context, q *Queen) error {
+		if !plainTableName.MatchString(table) {
+			return fmt.Errorf("%w: %s: table name must be a plain identifier", ErrInvalidMigration, a.desc)
+		}
+
+		var n int64
+		err := q.driver.Exec(ctx, func(tx *sql.Tx) error {
+			return tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+table).Scan(&n)
+		})
+		if err != nil {
+			return err
+		}
+		if n < min || n > max {
+			return a.failed("table has %d rows", n)
+		}
+		return nil
+	}
+	return a
+}
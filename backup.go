@@ -0,0 +1,91 @@
+package queen
+
+import (
+	"context"
+	"fmt"
+)
+
+// BackupRequest describes the migration a backup is taken for.
+type BackupRequest struct {
+	// Migration is the migration about to run.
+	Migration *Migration
+
+	// Down is true when the migration is about to be rolled back.
+	Down bool
+
+	// Tables lists the tables the destructive statements of the SQL about
+	// to run drop, truncate, delete from or drop columns of, as written,
+	// e.g. "billing.invoices". It is a best effort: tables of Go functions
+	// and of statements matched by Config.DestructiveKeywords or
+	// Config.DestructivePatterns aren't known.
+	Tables []string
+}
+
+// BackupProvider backs up data before destructive migrations run, e.g. with
+// pg_dump of the affected tables, CREATE TABLE ... AS SELECT or a storage
+// snapshot API. See Config.Backup.
+type BackupProvider interface {
+	// Backup takes a backup for req and returns a reference to it, such
+	// as a file name or snapshot ID, recorded in the run report and the
+	// history log. An error stops the migration before it runs.
+	Backup(ctx context.Context, req BackupRequest) (string, error)
+
+	// Restore restores the backup ref returned by Backup.
+	Restore(ctx context.Context, ref string) error
+}
+
+// backup takes a backup before m runs, if Config.Backup is set and m is
+// destructive in that direction or sets Backup. It returns the reference,
+// or "" if no backup was needed.
+func (q *Queen) backup(ctx context.Context, m *Migration, down bool) (string, error) {
+	if q.config.Backup == nil || !(m.Backup || m.destructive(down)) {
+		return "", nil
+	}
+
+	query := m.UpSQL
+	if down {
+		query = m.DownSQL
+	}
+
+	ref, err := q.config.Backup.Backup(ctx, BackupRequest{Migration: m, Down: down, Tables: destructiveTables(query)})
+	if err != nil {
+		return "", fmt.Errorf("backup failed: %w", err)
+	}
+
+	return ref, nil
+}
+
+// RestoreBackup restores the latest backup taken before version ran, as
+// found in the history log, with Config.Backup. It restores data only:
+// roll the migration back first if it is still applied.
+//
+// Returns ErrUnsupported if Config.Backup is nil or the driver doesn't
+// implement HistoryRecorder, and ErrNoBackup if no backup was recorded.
+func (q *Queen) RestoreBackup(ctx context.Context, version string) error {
+	if q.config.Backup == nil {
+		return fmt.Errorf("%w: restore without Config.Backup", ErrUnsupported)
+	}
+
+	history, err := q.History(ctx)
+	if err != nil {
+		return err
+	}
+
+	var ref string
+	for _, e := range history {
+		if e.Version == version && e.Backup != "" {
+			ref = e.Backup
+		}
+	}
+	if ref == "" {
+		return fmt.Errorf("%w: %s", ErrNoBackup, version)
+	}
+
+	unlock, err := q.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	return q.config.Backup.Restore(ctx, ref)
+}
@@ -0,0 +1,89 @@
+package queen_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/honeynil/queen"
+	"github.com/honeynil/queen/drivers/mock"
+)
+
+// fakeBackup records backup requests and restores.
+type fakeBackup struct {
+	requests []queen.BackupRequest
+	restored []string
+	err      error
+}
+
+func (b *fakeBackup) Backup(ctx context.Context, req queen.BackupRequest) (string, error) {
+	if b.err != nil {
+		return "", b.err
+	}
+	b.requests = append(b.requests, req)
+	return "snapshot-" + req.Migration.Version, nil
+}
+
+func (b *fakeBackup) Restore(ctx context.Context, ref string) error {
+	b.restored = append(b.restored, ref)
+	return nil
+}
+
+func TestBackup(t *testing.T) {
+	ctx := context.Background()
+	provider := &fakeBackup{}
+	driver := mock.New()
+	q := queen.NewWithConfig(driver, &queen.Config{Backup: provider})
+
+	ran := false
+	q.MustAdd(queen.M{Version: "001", Name: "create", UpFunc: noop, DownFunc: noop})
+	q.MustAdd(queen.M{Version: "002", Name: "rewrite_emails", Backup: true,
+		UpFunc: func(ctx context.Context, tx *sql.Tx) error {
+			ran = true
+			return nil
+		},
+		DownFunc: noop,
+	})
+
+	if err := q.Up(ctx); err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+	if len(provider.requests) != 1 || provider.requests[0].Migration.Version != "002" || provider.requests[0].Down {
+		t.Fatalf("Expected one backup before 002 up, got %+v", provider.requests)
+	}
+
+	history, err := q.History(ctx)
+	if err != nil {
+		t.Fatalf("History failed: %v", err)
+	}
+	if len(history) != 2 || history[0].Backup != "" || history[1].Backup != "snapshot-002" {
+		t.Errorf("Expected the backup of 002 in history, got %+v", history)
+	}
+
+	if err := q.RestoreBackup(ctx, "002"); err != nil {
+		t.Fatalf("RestoreBackup failed: %v", err)
+	}
+	if len(provider.restored) != 1 || provider.restored[0] != "snapshot-002" {
+		t.Errorf("Expected snapshot-002 restored, got %v", provider.restored)
+	}
+	if err := q.RestoreBackup(ctx, "001"); !errors.Is(err, queen.ErrNoBackup) {
+		t.Errorf("Expected ErrNoBackup for 001, got %v", err)
+	}
+
+	if err := q.Down(ctx, 1); err != nil {
+		t.Fatalf("Down failed: %v", err)
+	}
+	if len(provider.requests) != 2 || !provider.requests[1].Down {
+		t.Errorf("Expected a backup before 002 down, got %+v", provider.requests)
+	}
+
+	provider.err = errors.New("disk full")
+	ran = false
+	if err := q.Up(ctx); err == nil {
+		t.Fatal("Expected Up to fail when the backup fails")
+	}
+	if ran || driver.HasVersion("002") {
+		t.Error("Expected 002 not to run without a backup")
+	}
+}
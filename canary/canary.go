@@ -0,0 +1,150 @@
+// Package canary helps roll out risky data migrations gradually.
+//
+// A Rollout processes only part of a table's ID range per Up run, records
+// how far it got, and reports itself incomplete so the migration stays
+// pending. Each later Up run with a higher Percent (or MaxID) extends the
+// range from where the previous run stopped, until the whole table is
+// covered and the migration is recorded as applied:
+//
+//	percent, _ := strconv.Atoi(os.Getenv("NORMALIZE_EMAILS_PERCENT"))
+//
+//	q.MustAdd(queen.M{
+//	    Version:        "042",
+//	    Name:           "normalize_emails",
+//	    ManualChecksum: "v1",
+//	    UpFunc: canary.Rollout{
+//	        Name:    "042_normalize_emails",
+//	        Percent: percent,
+//	        Range:   canary.TableRange("users", "id"),
+//	        Process: func(ctx context.Context, tx *sql.Tx, from, to int64) error {
+//	            _, err := tx.ExecContext(ctx,
+//	                "UPDATE users SET email = LOWER(email) WHERE id BETWEEN $1 AND $2", from, to)
+//	            return err
+//	        },
+//	    }.Up,
+//	})
+//
+// While a rollout is incomplete, migrations after it are not applied.
+package canary
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/honeynil/queen"
+)
+
+// DefaultChunkSize is the number of IDs handed to Process at a time.
+const DefaultChunkSize = 1000
+
+// ErrInvalidRollout is returned for a Rollout missing required fields.
+var ErrInvalidRollout = errors.New("invalid rollout")
+
+// Rollout processes a growing share of a table's ID range across Up runs.
+type Rollout struct {
+	// Name identifies the rollout's progress in the Store.
+	// Usually the migration's version and name.
+	Name string
+
+	// Percent of the ID range to cover by the end of this run, 1-100.
+	// Ignored when MaxID is set.
+	Percent int
+
+	// MaxID is the highest ID to cover by the end of this run. Zero means
+	// the range is given by Percent.
+	MaxID int64
+
+	// Range returns the lowest and highest ID to process. It is called once
+	// per run, so rows added between runs are included.
+	Range func(ctx context.Context, tx *sql.Tx) (first, last int64, err error)
+
+	// Process handles rows with IDs between from and to, inclusive.
+	Process func(ctx context.Context, tx *sql.Tx, from, to int64) error
+
+	// ChunkSize limits how many IDs Process handles per call.
+	// Default: DefaultChunkSize
+	ChunkSize int64
+
+	// Store persists progress between runs. Default: ProgressStore
+	Store Store
+}
+
+// Up runs the rollout. Use it as a migration's UpFunc.
+//
+// It returns nil once the whole ID range is processed, so the migration is
+// recorded as applied, and an error wrapping queen.ErrIncomplete otherwise.
+func (r Rollout) Up(ctx context.Context, tx *sql.Tx) error {
+	if r.Name == "" || r.Range == nil || r.Process == nil {
+		return ErrInvalidRollout
+	}
+	if r.MaxID == 0 && (r.Percent < 1 || r.Percent > 100) {
+		return fmt.Errorf("%w: percent %d is not between 1 and 100", ErrInvalidRollout, r.Percent)
+	}
+
+	first, last, err := r.Range(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	store := r.Store
+	if store == nil {
+		store = ProgressStore{}
+	}
+
+	done, ok, err := store.Load(ctx, tx, r.Name)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		done = first - 1
+	}
+
+	target := r.target(first, last)
+
+	chunk := r.ChunkSize
+	if chunk <= 0 {
+		chunk = DefaultChunkSize
+	}
+
+	for from := done + 1; from <= target; from += chunk {
+		to := min(from+chunk-1, target)
+
+		if err := r.Process(ctx, tx, from, to); err != nil {
+			return fmt.Errorf("ids %d-%d: %w", from, to, err)
+		}
+		if err := store.Save(ctx, tx, r.Name, to); err != nil {
+			return err
+		}
+		done = to
+	}
+
+	if done < last {
+		return fmt.Errorf("%w: rollout %s processed ids up to %d of %d",
+			queen.ErrIncomplete, r.Name, done, last)
+	}
+
+	return nil
+}
+
+// target returns the highest ID this run should cover.
+func (r Rollout) target(first, last int64) int64 {
+	if r.MaxID > 0 {
+		return min(r.MaxID, last)
+	}
+
+	return first + (last-first+1)*int64(r.Percent)/100 - 1
+}
+
+// TableRange returns a Range function reading MIN and MAX of column in
+// table. An empty table yields an empty range, which completes at once.
+func TableRange(table, column string) func(ctx context.Context, tx *sql.Tx) (int64, int64, error) {
+	query := fmt.Sprintf("SELECT COALESCE(MIN(%s), 0), COALESCE(MAX(%s), -1) FROM %s", column, column, table)
+
+	return func(ctx context.Context, tx *sql.Tx) (int64, int64, error) {
+		var first, last int64
+		err := tx.QueryRowContext(ctx, query).Scan(&first, &last)
+		return first, last, err
+	}
+}
@@ -0,0 +1,111 @@
+package canary_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/honeynil/queen"
+	"github.com/honeynil/queen/canary"
+	"github.com/honeynil/queen/drivers/mock"
+)
+
+// memStore keeps progress in memory.
+type memStore map[string]int64
+
+func (s memStore) Load(ctx context.Context, tx *sql.Tx, name string) (int64, bool, error) {
+	id, ok := s[name]
+	return id, ok, nil
+}
+
+func (s memStore) Save(ctx context.Context, tx *sql.Tx, name string, id int64) error {
+	s[name] = id
+	return nil
+}
+
+func noop(ctx context.Context, tx *sql.Tx) error { return nil }
+
+func TestRollout(t *testing.T) {
+	ctx := context.Background()
+	driver := mock.New()
+	store := memStore{}
+
+	var processed [][2]int64
+	newQueen := func(percent int) *queen.Queen {
+		q := queen.New(driver)
+		q.MustAdd(queen.M{
+			Version:        "001",
+			Name:           "backfill",
+			ManualChecksum: "v1",
+			UpFunc: canary.Rollout{
+				Name:    "001_backfill",
+				Percent: percent,
+				Range: func(ctx context.Context, tx *sql.Tx) (int64, int64, error) {
+					return 1, 100, nil
+				},
+				Process: func(ctx context.Context, tx *sql.Tx, from, to int64) error {
+					processed = append(processed, [2]int64{from, to})
+					return nil
+				},
+				ChunkSize: 20,
+				Store:     store,
+			}.Up,
+		})
+		q.MustAdd(queen.M{Version: "002", Name: "after", UpFunc: noop})
+		return q
+	}
+
+	// First run covers 30% and leaves both migrations pending
+	if err := newQueen(30).Up(ctx); err != nil {
+		t.Fatalf("Up(30%%) failed: %v", err)
+	}
+	if driver.AppliedCount() != 0 {
+		t.Errorf("Expected no migrations applied, got %d", driver.AppliedCount())
+	}
+	if want := [][2]int64{{1, 20}, {21, 30}}; !slices.Equal(processed, want) {
+		t.Errorf("processed = %v, want %v", processed, want)
+	}
+
+	// Second run extends the range from where the first stopped
+	processed = nil
+	if err := newQueen(100).Up(ctx); err != nil {
+		t.Fatalf("Up(100%%) failed: %v", err)
+	}
+	if want := [][2]int64{{31, 50}, {51, 70}, {71, 90}, {91, 100}}; !slices.Equal(processed, want) {
+		t.Errorf("processed = %v, want %v", processed, want)
+	}
+	if !driver.HasVersion("001") || !driver.HasVersion("002") {
+		t.Errorf("Expected both migrations applied")
+	}
+}
+
+func TestRolloutMaxID(t *testing.T) {
+	r := canary.Rollout{
+		Name:  "backfill",
+		MaxID: 42,
+		Range: func(ctx context.Context, tx *sql.Tx) (int64, int64, error) {
+			return 1, 100, nil
+		},
+		Process: func(ctx context.Context, tx *sql.Tx, from, to int64) error { return nil },
+		Store:   memStore{},
+	}
+
+	if err := r.Up(context.Background(), nil); !errors.Is(err, queen.ErrIncomplete) {
+		t.Errorf("Expected ErrIncomplete, got %v", err)
+	}
+	if id := r.Store.(memStore)["backfill"]; id != 42 {
+		t.Errorf("progress = %d, want 42", id)
+	}
+}
+
+func TestRolloutInvalid(t *testing.T) {
+	r := canary.Rollout{Name: "backfill", Percent: 0, Store: memStore{},
+		Range:   func(ctx context.Context, tx *sql.Tx) (int64, int64, error) { return 1, 1, nil },
+		Process: func(ctx context.Context, tx *sql.Tx, from, to int64) error { return nil },
+	}
+	if err := r.Up(context.Background(), nil); !errors.Is(err, canary.ErrInvalidRollout) {
+		t.Errorf("Expected ErrInvalidRollout, got %v", err)
+	}
+}
@@ -0,0 +1,122 @@
+package canary
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/honeynil/queen/progress"
+)
+
+// Store persists rollout progress: the highest ID processed so far.
+// Both methods run in the migration's transaction, so progress commits
+// together with the processed rows.
+type Store interface {
+	// Load returns the highest processed ID, or ok=false if the rollout
+	// hasn't started.
+	Load(ctx context.Context, tx *sql.Tx, name string) (id int64, ok bool, err error)
+
+	// Save records id as the highest processed ID.
+	Save(ctx context.Context, tx *sql.Tx, name string, id int64) error
+}
+
+// ProgressStore keeps progress with the progress package, in the driver's
+// progress table. It is the default Store.
+type ProgressStore struct{}
+
+// Load implements Store.
+func (ProgressStore) Load(ctx context.Context, tx *sql.Tx, name string) (int64, bool, error) {
+	return progress.GetInt(ctx, name)
+}
+
+// Save implements Store.
+func (ProgressStore) Save(ctx context.Context, tx *sql.Tx, name string, id int64) error {
+	return progress.SetInt(ctx, name, id)
+}
+
+// DefaultTable is the table used by TableStore when Table is empty.
+const DefaultTable = "queen_canary"
+
+// QuestionPlaceholder formats bind parameters for MySQL and SQLite.
+func QuestionPlaceholder(int) string { return "?" }
+
+// DollarPlaceholder formats bind parameters for PostgreSQL.
+func DollarPlaceholder(n int) string { return fmt.Sprintf("$%d", n) }
+
+// TableStore keeps progress in a database table, created on first use.
+//
+// MySQL commits implicitly on CREATE TABLE, so there the table should be
+// created by an earlier migration using CreateTableSQL.
+type TableStore struct {
+	// Table name. Default: DefaultTable
+	Table string
+
+	// Placeholder formats the n-th bind parameter (1-based).
+	// Default: QuestionPlaceholder
+	Placeholder func(n int) string
+}
+
+// CreateTableSQL returns the statement creating the progress table.
+func (s TableStore) CreateTableSQL() string {
+	return fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+		name VARCHAR(255) PRIMARY KEY,
+		last_id BIGINT NOT NULL
+	)`, s.table())
+}
+
+// Load implements Store.
+func (s TableStore) Load(ctx context.Context, tx *sql.Tx, name string) (int64, bool, error) {
+	if _, err := tx.ExecContext(ctx, s.CreateTableSQL()); err != nil {
+		return 0, false, err
+	}
+
+	var id int64
+	err := tx.QueryRowContext(ctx,
+		fmt.Sprintf("SELECT last_id FROM %s WHERE name = %s", s.table(), s.placeholder(1)),
+		name,
+	).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+
+	return id, true, nil
+}
+
+// Save implements Store.
+func (s TableStore) Save(ctx context.Context, tx *sql.Tx, name string, id int64) error {
+	res, err := tx.ExecContext(ctx,
+		fmt.Sprintf("UPDATE %s SET last_id = %s WHERE name = %s", s.table(), s.placeholder(1), s.placeholder(2)),
+		id, name,
+	)
+	if err != nil {
+		return err
+	}
+
+	if n, err := res.RowsAffected(); err != nil || n > 0 {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx,
+		fmt.Sprintf("INSERT INTO %s (name, last_id) VALUES (%s, %s)", s.table(), s.placeholder(1), s.placeholder(2)),
+		name, id,
+	)
+	return err
+}
+
+func (s TableStore) table() string {
+	if s.Table == "" {
+		return DefaultTable
+	}
+	return s.Table
+}
+
+func (s TableStore) placeholder(n int) string {
+	if s.Placeholder == nil {
+		return QuestionPlaceholder(n)
+	}
+	return s.Placeholder(n)
+}
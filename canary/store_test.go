@@ -0,0 +1,87 @@
+//go:build cgo
+// +build cgo
+
+package canary_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/honeynil/queen"
+	"github.com/honeynil/queen/canary"
+	"github.com/honeynil/queen/drivers/sqlite"
+)
+
+func TestTableStore(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open SQLite: %v", err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	ctx := context.Background()
+
+	if _, err := db.Exec("CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT)"); err != nil {
+		t.Fatalf("failed to create users: %v", err)
+	}
+	for i := 0; i < 10; i++ {
+		if _, err := db.Exec("INSERT INTO users (email) VALUES ('USER@EXAMPLE.COM')"); err != nil {
+			t.Fatalf("failed to insert user: %v", err)
+		}
+	}
+
+	run := func(percent int) {
+		t.Helper()
+		q := queen.New(sqlite.New(db))
+		q.MustAdd(queen.M{
+			Version:        "001",
+			Name:           "normalize_emails",
+			ManualChecksum: "v1",
+			UpFunc: canary.Rollout{
+				Name:    "001_normalize_emails",
+				Percent: percent,
+				Range:   canary.TableRange("users", "id"),
+				Process: func(ctx context.Context, tx *sql.Tx, from, to int64) error {
+					_, err := tx.ExecContext(ctx,
+						"UPDATE users SET email = LOWER(email) WHERE id BETWEEN ? AND ?", from, to)
+					return err
+				},
+				Store: canary.TableStore{},
+			}.Up,
+		})
+		if err := q.Up(ctx); err != nil {
+			t.Fatalf("Up(%d%%) failed: %v", percent, err)
+		}
+	}
+
+	countLower := func() int {
+		t.Helper()
+		var n int
+		if err := db.QueryRow("SELECT COUNT(*) FROM users WHERE email = 'user@example.com'").Scan(&n); err != nil {
+			t.Fatalf("failed to count: %v", err)
+		}
+		return n
+	}
+
+	run(40)
+	if n := countLower(); n != 4 {
+		t.Errorf("after 40%%: %d rows processed, want 4", n)
+	}
+
+	run(100)
+	if n := countLower(); n != 10 {
+		t.Errorf("after 100%%: %d rows processed, want 10", n)
+	}
+
+	var lastID int64
+	if err := db.QueryRow("SELECT last_id FROM queen_canary WHERE name = '001_normalize_emails'").Scan(&lastID); err != nil {
+		t.Fatalf("failed to read progress: %v", err)
+	}
+	if lastID != 10 {
+		t.Errorf("last_id = %d, want 10", lastID)
+	}
+}
@@ -0,0 +1,70 @@
+package queen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ChangeRequest describes a run about to start, for ChangeManagement.Open.
+type ChangeRequest struct {
+	// RunID identifies the run, matching RunReport.ID and
+	// HistoryEntry.RunID.
+	RunID string
+
+	// Operation is the kind of run.
+	Operation Operation
+
+	// Plan lists the migrations the run intends to apply or roll back, in
+	// order.
+	Plan []*Migration
+}
+
+// ChangeManagement ties runs to change tickets, e.g. in Jira or
+// ServiceNow, for organizations that require one before any production
+// schema change. See Config.ChangeManagement.
+type ChangeManagement interface {
+	// Open creates a ticket for req, or attaches to an existing approved
+	// one, and returns its reference, e.g. "CHG0031337". The reference is
+	// recorded in the run report and the history log. An error stops the
+	// run before anything executes.
+	Open(ctx context.Context, req ChangeRequest) (string, error)
+
+	// Close updates or closes the ticket ref with the results of the run
+	// once it completes, successfully or not. report.Error is empty if
+	// the run succeeded.
+	Close(ctx context.Context, ref string, report *RunReport) error
+}
+
+// openChange opens a change ticket for report, if Config.ChangeManagement
+// is set and plan isn't empty.
+func (q *Queen) openChange(ctx context.Context, report *RunReport, plan []*Migration) error {
+	if q.config.ChangeManagement == nil || len(plan) == 0 {
+		return nil
+	}
+
+	ref, err := q.config.ChangeManagement.Open(ctx, ChangeRequest{
+		RunID:     report.ID,
+		Operation: report.Operation,
+		Plan:      plan,
+	})
+	if err != nil {
+		return fmt.Errorf("open change ticket: %w", err)
+	}
+
+	report.ChangeTicket = ref
+	return nil
+}
+
+// closeChange closes the change ticket of report, if one was opened,
+// joining its error with err.
+func (q *Queen) closeChange(ctx context.Context, report *RunReport, err error) error {
+	if report.ChangeTicket == "" {
+		return err
+	}
+
+	if closeErr := q.config.ChangeManagement.Close(ctx, report.ChangeTicket, report); closeErr != nil {
+		err = errors.Join(err, fmt.Errorf("close change ticket %s: %w", report.ChangeTicket, closeErr))
+	}
+	return err
+}
@@ -0,0 +1,98 @@
+package queen_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/honeynil/queen"
+	"github.com/honeynil/queen/drivers/mock"
+)
+
+// fakeChanges records the tickets opened and closed.
+type fakeChanges struct {
+	opened  []queen.ChangeRequest
+	closed  map[string]*queen.RunReport
+	openErr error
+}
+
+func (c *fakeChanges) Open(ctx context.Context, req queen.ChangeRequest) (string, error) {
+	if c.openErr != nil {
+		return "", c.openErr
+	}
+	c.opened = append(c.opened, req)
+	return "CHG" + req.Plan[0].Version, nil
+}
+
+func (c *fakeChanges) Close(ctx context.Context, ref string, report *queen.RunReport) error {
+	if c.closed == nil {
+		c.closed = make(map[string]*queen.RunReport)
+	}
+	c.closed[ref] = report
+	return nil
+}
+
+func TestChangeManagement(t *testing.T) {
+	ctx := context.Background()
+	changes := &fakeChanges{}
+	sink := &reportSink{}
+	q := queen.NewWithConfig(mock.New(), &queen.Config{ChangeManagement: changes, Archive: sink})
+	q.MustAdd(queen.M{Version: "001", Name: "create", UpFunc: noop, DownFunc: noop})
+	q.MustAdd(queen.M{Version: "002", Name: "fail", UpFunc: func(ctx context.Context, tx *sql.Tx) error {
+		return errors.New("boom")
+	}})
+
+	if err := q.Up(ctx); err == nil {
+		t.Fatal("Expected Up to fail")
+	}
+	if len(changes.opened) != 1 || changes.opened[0].Operation != queen.OperationUp || len(changes.opened[0].Plan) != 2 ||
+		changes.opened[0].RunID != sink.report.ID {
+		t.Fatalf("Expected one ticket opened with the plan of the run, got %+v", changes.opened)
+	}
+	if report := changes.closed["CHG001"]; report == nil || !strings.Contains(report.Error, "boom") || len(report.Migrations) != 2 {
+		t.Errorf("Expected CHG001 closed with the failed run, got %+v", report)
+	}
+	if sink.report.ChangeTicket != "CHG001" {
+		t.Errorf("ChangeTicket = %q; want CHG001 in the run report", sink.report.ChangeTicket)
+	}
+
+	history, err := q.History(ctx)
+	if err != nil {
+		t.Fatalf("History failed: %v", err)
+	}
+	for _, e := range history {
+		if e.ChangeTicket != "CHG001" {
+			t.Errorf("Expected CHG001 in history, got %+v", e)
+		}
+	}
+
+	t.Run("open fails", func(t *testing.T) {
+		changes := &fakeChanges{openErr: errors.New("not approved")}
+		driver := mock.New()
+		q := queen.NewWithConfig(driver, &queen.Config{ChangeManagement: changes})
+		q.MustAdd(queen.M{Version: "001", Name: "create", UpFunc: noop})
+
+		if err := q.Up(ctx); err == nil || !strings.Contains(err.Error(), "not approved") {
+			t.Fatalf("Up = %v; want the ticket error", err)
+		}
+		if driver.AppliedCount() != 0 {
+			t.Error("Expected nothing applied without a ticket")
+		}
+	})
+
+	t.Run("nothing to do", func(t *testing.T) {
+		changes := &fakeChanges{}
+		q := queen.NewWithConfig(mock.New(), &queen.Config{ChangeManagement: changes})
+		q.MustAdd(queen.M{Version: "001", Name: "create", UpFunc: noop})
+		for range 2 {
+			if err := q.Up(ctx); err != nil {
+				t.Fatalf("Up failed: %v", err)
+			}
+		}
+		if len(changes.opened) != 1 {
+			t.Errorf("Expected no ticket once nothing is pending, got %+v", changes.opened)
+		}
+	})
+}
@@ -0,0 +1,59 @@
+// Command queen-mergecheck reports migration versions of a branch that
+// conflict with the branch it will be merged into, such as the same next
+// sequential version added on both, or timestamps that would reorder
+// migrations already applied from the target branch.
+//
+// It compares lock files written by Queen.WriteLockFile. In CI, check the
+// branch's queen.lock against the one of the target branch:
+//
+//	git show origin/main:queen.lock > /tmp/main.lock
+//	queen-mergecheck /tmp/main.lock queen.lock
+//
+// It prints each conflict with a suggested version to rename to, and exits
+// with status 1 if there are any.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/honeynil/queen"
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: queen-mergecheck <target lock file> [branch lock file]")
+		fmt.Fprintln(os.Stderr, "The branch lock file defaults to queen.lock.")
+	}
+	flag.Parse()
+
+	if flag.NArg() < 1 || flag.NArg() > 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	branchPath := "queen.lock"
+	if flag.NArg() == 2 {
+		branchPath = flag.Arg(1)
+	}
+
+	target, err := queen.ReadLockFile(flag.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "queen-mergecheck:", err)
+		os.Exit(2)
+	}
+	branch, err := queen.ReadLockFile(branchPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "queen-mergecheck:", err)
+		os.Exit(2)
+	}
+
+	conflicts := queen.CheckMerge(target, branch)
+	for _, c := range conflicts {
+		fmt.Println(c)
+	}
+	if len(conflicts) > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/honeynil/queen"
+	"github.com/honeynil/queen/drivers/mysql"
+	"github.com/honeynil/queen/drivers/postgres"
+	"github.com/honeynil/queen/drivers/sqlite"
+)
+
+// openDriver opens the database of dsn with the Queen driver named by
+// name, or by the scheme of dsn if name is empty.
+func openDriver(ctx context.Context, name, dsn string) (queen.Driver, error) {
+	if name == "" {
+		scheme, rest, ok := strings.Cut(dsn, "://")
+		if !ok {
+			return nil, fmt.Errorf("DSN has no scheme, set -driver")
+		}
+
+		switch scheme {
+		case "postgres", "postgresql":
+			name = "postgres"
+		case "mysql":
+			name, dsn = "mysql", rest
+		case "sqlite", "sqlite3":
+			name, dsn = "sqlite", rest
+		default:
+			return nil, fmt.Errorf("unknown DSN scheme %q, set -driver", scheme)
+		}
+	}
+
+	var sqlDriver string
+	switch name {
+	case "postgres":
+		sqlDriver = "postgres"
+	case "mysql":
+		sqlDriver = "mysql"
+	case "sqlite":
+		sqlDriver = "sqlite3"
+	default:
+		return nil, fmt.Errorf("unknown driver %q, expected postgres, mysql or sqlite", name)
+	}
+
+	db, err := sql.Open(sqlDriver, dsn)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.PingContext(ctx); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+
+	switch name {
+	case "postgres":
+		return postgres.New(db), nil
+	case "mysql":
+		return mysql.New(db), nil
+	default:
+		return sqlite.New(db), nil
+	}
+}
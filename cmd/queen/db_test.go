@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/honeynil/queen/drivers/postgres"
+)
+
+func TestOpenDriver_Postgres(t *testing.T) {
+	ctx := context.Background()
+
+	// Nothing listens on port 1: the DSN must get as far as connecting
+	_, err := openDriver(ctx, "", "postgres://queen@127.0.0.1:1/queen?sslmode=disable&connect_timeout=1")
+	if err == nil || strings.Contains(err.Error(), "driver") {
+		t.Errorf("openDriver(postgres://) = %v; want a connection error", err)
+	}
+
+	dsn := os.Getenv("DATABASE_URL")
+	if dsn == "" {
+		t.Skip("DATABASE_URL not set")
+	}
+	driver, err := openDriver(ctx, "", dsn)
+	if err != nil {
+		t.Fatalf("openDriver(%s) failed: %v", dsn, err)
+	}
+	defer driver.Close()
+	if _, ok := driver.(*postgres.Driver); !ok {
+		t.Errorf("openDriver returned %T; want *postgres.Driver", driver)
+	}
+}
@@ -0,0 +1,482 @@
+// Command queen runs the SQL migrations of a directory against a database,
+// so services don't need a Go program of their own to migrate:
+//
+//	queen create -dir migrations add_email_index
+//	queen up -dir migrations -dsn postgres://app@db/app
+//	QUEEN_DSN=postgres://app@db/app queen status -dir migrations
+//
+// Migrations are read with the source/sqlfs package: <version>_<name>.up.sql
+// files with optional .down.sql pairs, or annotated single .sql files.
+//
+// The subcommands are:
+//
+//	up        apply pending migrations (-n applies at most n)
+//	down      roll back the last migration (-n rolls back n)
+//	status    list migrations and whether they are applied (-format json or csv)
+//	plan      show what up, or down with -down, would execute (-format json)
+//	validate  check the database against the migrations, exit 1 on problems
+//	create    create the next migration's files (-go for a Go stub)
+//	serve     apply new migrations on an interval and serve status over HTTP
+//	version   print the version of queen
+//
+// The database is chosen by the scheme of the DSN: postgres://, mysql:// or
+// sqlite://. -driver names it instead, with the DSN passed to the
+// database/sql driver as is.
+//
+// Settings come from flags, then from a configuration file read with the
+// config package, queen.yaml, queen.yml or queen.toml in the working
+// directory unless -config names another, then from the QUEEN_DSN
+// environment variable for the DSN. -env selects the settings of an
+// environment in the file:
+//
+//	queen up -env production
+//
+// down shows the destructive statements of each rollback, such as DROP
+// TABLE, and asks for its version to be typed before anything runs. -yes
+// skips the confirmation, e.g. in scripts.
+//
+// PostgreSQL, MySQL and SQLite databases are opened with the database/sql
+// drivers built in: github.com/lib/pq, github.com/go-sql-driver/mysql and
+// github.com/mattn/go-sqlite3.
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"runtime/debug"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/honeynil/queen"
+	"github.com/honeynil/queen/config"
+	"github.com/honeynil/queen/source/sqlfs"
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	os.Exit(run(ctx, os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// usage is printed for a missing or unknown subcommand.
+const usage = `usage: queen <command> [flags] [args]
+
+commands:
+  up        apply pending migrations
+  down      roll back migrations
+  status    list migrations and whether they are applied
+  plan      show what up or down would execute
+  validate  check the database against the migrations
+  create    create the next migration's files
+  serve     apply new migrations on an interval and serve status over HTTP
+  version   print the version of queen
+
+Run queen <command> -h for the flags of a command.
+`
+
+// command is a subcommand.
+type command struct {
+	// db is set for commands that connect to the database.
+	db bool
+
+	run func(ctx context.Context, c *cli) error
+}
+
+var commands = map[string]command{
+	"up":       {db: true, run: up},
+	"down":     {db: true, run: down},
+	"status":   {db: true, run: status},
+	"plan":     {db: true, run: plan},
+	"validate": {db: true, run: validate},
+	"create":   {run: create},
+	"serve":    {db: true, run: serve},
+	"version":  {run: version},
+}
+
+// cli holds the flags, input and output of a run.
+type cli struct {
+	flags  *flag.FlagSet
+	stdin  *bufio.Reader
+	stdout io.Writer
+	stderr io.Writer
+
+	configPath  string
+	settings    config.Settings
+	lockTimeout time.Duration
+
+	dir       string
+	dsn       string
+	driver    string
+	table     string
+	env       string
+	operator  string
+	n         int
+	yes       bool
+	rollback  bool
+	format    string
+	goStub    bool
+	timestamp bool
+	addr      string
+	interval  time.Duration
+}
+
+// run runs the command of args and returns the exit status.
+func run(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(stderr, "queen: unknown command %q\n\n%s", args[0], usage)
+		return 2
+	}
+
+	c := &cli{
+		flags:  flag.NewFlagSet("queen "+args[0], flag.ContinueOnError),
+		stdin:  bufio.NewReader(stdin),
+		stdout: stdout,
+		stderr: stderr,
+	}
+	c.flags.SetOutput(stderr)
+	c.flags.StringVar(&c.configPath, "config", "", "configuration file (default queen.yaml, queen.yml or queen.toml if present)")
+	c.flags.StringVar(&c.dir, "dir", "migrations", "directory of the migration files")
+	if cmd.db {
+		c.flags.StringVar(&c.dsn, "dsn", "", "database DSN (default: the configuration file, then $QUEEN_DSN)")
+		c.flags.StringVar(&c.driver, "driver", "", "database: postgres, mysql or sqlite (default: the DSN scheme)")
+		c.flags.StringVar(&c.table, "table", "", "tracking table name (default queen_migrations)")
+		c.flags.DurationVar(&c.lockTimeout, "lock-timeout", 0, "time to wait for the migration lock (default 30m)")
+		c.flags.StringVar(&c.env, "env", "", "environment: selects its settings in the configuration file and\nthe migrations restricted to it")
+		c.flags.StringVar(&c.operator, "operator", "", "recorded with applied migrations, e.g. a change ticket")
+	}
+	switch args[0] {
+	case "up":
+		c.flags.IntVar(&c.n, "n", 0, "apply at most n migrations (default all)")
+	case "down":
+		c.flags.IntVar(&c.n, "n", 1, "number of migrations to roll back")
+		c.flags.BoolVar(&c.yes, "yes", false, "roll back destructive migrations without confirmation")
+	case "status":
+		c.flags.StringVar(&c.format, "format", "text", "output format: text, json or csv")
+	case "plan":
+		c.flags.BoolVar(&c.rollback, "down", false, "plan a rollback instead of up")
+		c.flags.IntVar(&c.n, "n", 0, "plan at most n migrations (default all for up, 1 for -down)")
+		c.flags.StringVar(&c.format, "format", "text", "output format: text or json")
+	case "create":
+		c.flags.BoolVar(&c.goStub, "go", false, "create a Go migration stub instead of SQL files")
+		c.flags.BoolVar(&c.timestamp, "timestamp", false, "use a timestamp version")
+	case "serve":
+		c.flags.StringVar(&c.addr, "addr", ":8080", "HTTP address of /status, /healthz and /metrics")
+		c.flags.DurationVar(&c.interval, "interval", time.Minute, "time between passes")
+	}
+
+	if err := c.flags.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if err := c.loadConfig(); err != nil {
+		fmt.Fprintln(stderr, "queen:", err)
+		return 2
+	}
+	if cmd.db && c.dsn == "" {
+		fmt.Fprintln(stderr, "queen: no DSN, set -dsn, dsn in the configuration file, or QUEEN_DSN")
+		return 2
+	}
+
+	if err := cmd.run(ctx, c); err != nil {
+		fmt.Fprintln(stderr, "queen:", err)
+		return 1
+	}
+	return 0
+}
+
+// loadConfig reads the configuration file, if any, and applies the settings
+// of the environment to the flags that weren't set. A file defining
+// environments must define the one given with -env.
+func (c *cli) loadConfig() error {
+	set := make(map[string]bool)
+	c.flags.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+	path := c.configPath
+	if path == "" {
+		path = config.Find(".")
+	}
+	if path != "" {
+		f, err := config.Load(path)
+		if err != nil {
+			return err
+		}
+
+		env := c.env
+		if len(f.Environments) == 0 {
+			env = ""
+		}
+		if c.settings, err = f.Resolve(env); err != nil {
+			return err
+		}
+	}
+
+	for _, s := range []struct {
+		flag  string
+		dst   *string
+		value string
+	}{
+		{"dir", &c.dir, c.settings.Dir},
+		{"dsn", &c.dsn, c.settings.DSN},
+		{"driver", &c.driver, c.settings.Driver},
+		{"table", &c.table, c.settings.Table},
+	} {
+		if !set[s.flag] && s.value != "" {
+			*s.dst = s.value
+		}
+	}
+	if c.dsn == "" {
+		c.dsn = os.Getenv("QUEEN_DSN")
+	}
+	return nil
+}
+
+// config returns the Queen configuration of the flags.
+func (c *cli) config() *queen.Config {
+	config := queen.DefaultConfig()
+	c.settings.Apply(config)
+	if c.table != "" {
+		config.TableName = c.table
+	}
+	if c.lockTimeout > 0 {
+		config.LockTimeout = c.lockTimeout
+	}
+	config.Environment = c.env
+	config.Operator = c.operator
+	if c.flags.Name() == "queen down" && !c.yes {
+		config.ConfirmDestructive = c.confirmDown
+	}
+	return config
+}
+
+// migrations loads the migrations of the directory.
+func (c *cli) migrations() ([]queen.M, error) {
+	return sqlfs.Load(os.DirFS(c.dir), ".")
+}
+
+// queen opens the database and returns a Queen with the migrations of the
+// directory registered.
+func (c *cli) queen(ctx context.Context) (*queen.Queen, error) {
+	migrations, err := c.migrations()
+	if err != nil {
+		return nil, err
+	}
+
+	driver, err := openDriver(ctx, c.driver, c.dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	q := queen.NewWithConfig(driver, c.config())
+	if err := q.AddAll(migrations...); err != nil {
+		_ = q.Close()
+		return nil, err
+	}
+	return q, nil
+}
+
+func up(ctx context.Context, c *cli) error {
+	q, err := c.queen(ctx)
+	if err != nil {
+		return err
+	}
+	defer q.Close()
+
+	q.Hooks().MustRegister(c.progressHook())
+	return q.UpSteps(ctx, c.n)
+}
+
+func down(ctx context.Context, c *cli) error {
+	q, err := c.queen(ctx)
+	if err != nil {
+		return err
+	}
+	defer q.Close()
+
+	q.Hooks().MustRegister(c.progressHook())
+	err = q.Down(ctx, c.n)
+	if errors.Is(err, queen.ErrNotConfirmed) {
+		return fmt.Errorf("%w, type the version at the prompt or pass -yes", err)
+	}
+	return err
+}
+
+// progressHook prints each migration as it starts and completes, with the
+// estimated time left in the run when history allows one.
+func (c *cli) progressHook() queen.Hook {
+	return queen.Hook{Name: "progress", Func: func(ctx context.Context, e queen.Event) error {
+		var line string
+		switch e.Kind {
+		case queen.EventBeforeUp:
+			line = "applying"
+		case queen.EventBeforeDown:
+			line = "rolling back"
+		case queen.EventAfterUp:
+			line = "applied"
+		case queen.EventAfterDown:
+			line = "rolled back"
+		default:
+			return nil
+		}
+
+		line += " " + e.Migration.Version + " " + e.Migration.Name
+		if e.Kind == queen.EventAfterUp || e.Kind == queen.EventAfterDown {
+			line += " in " + e.Duration.Round(time.Millisecond).String()
+		}
+		if eta := e.ETA.Round(time.Second); eta > 0 {
+			line += fmt.Sprintf(" (about %s left)", eta)
+		}
+		fmt.Fprintln(c.stdout, line)
+		return nil
+	}}
+}
+
+// confirmDown shows the destructive statements of rolling back m and
+// reports whether its version was typed in reply.
+func (c *cli) confirmDown(ctx context.Context, m *queen.Migration) (bool, error) {
+	fmt.Fprintf(c.stderr, "Rolling back %s (%s) runs destructive statements:\n", m.Version, m.Name)
+	for _, stmt := range m.DestructiveStatements(true) {
+		fmt.Fprintf(c.stderr, "  %s\n", stmt)
+	}
+	fmt.Fprintf(c.stderr, "Type %s to continue: ", m.Version)
+
+	line, err := c.stdin.ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return false, err
+	}
+	if errors.Is(err, io.EOF) {
+		fmt.Fprintln(c.stderr)
+	}
+	return strings.TrimSpace(line) == m.Version, nil
+}
+
+func plan(ctx context.Context, c *cli) error {
+	q, err := c.queen(ctx)
+	if err != nil {
+		return err
+	}
+	defer q.Close()
+
+	var p *queen.Plan
+	if c.rollback {
+		p, err = q.PlanDown(ctx, c.n)
+	} else {
+		p, err = q.Plan(ctx)
+		if err == nil && c.n > 0 && c.n < len(p.Steps) {
+			p.Steps = p.Steps[:c.n]
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	switch c.format {
+	case "text":
+		_, err = fmt.Fprint(c.stdout, p)
+		return err
+	case "json":
+		enc := json.NewEncoder(c.stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(p)
+	default:
+		return fmt.Errorf("unknown format %q, want text or json", c.format)
+	}
+}
+
+func status(ctx context.Context, c *cli) error {
+	q, err := c.queen(ctx)
+	if err != nil {
+		return err
+	}
+	defer q.Close()
+
+	statuses, err := q.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	switch c.format {
+	case "json":
+		if statuses == nil {
+			statuses = []queen.MigrationStatus{}
+		}
+		enc := json.NewEncoder(c.stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(statuses)
+	case "csv":
+		return queen.WriteStatusCSV(c.stdout, statuses)
+	case "text":
+	default:
+		return fmt.Errorf("unknown format %q, expected text, json or csv", c.format)
+	}
+
+	w := tabwriter.NewWriter(c.stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+	for _, s := range statuses {
+		appliedAt := "-"
+		if s.AppliedAt != nil {
+			appliedAt = s.AppliedAt.Local().Format(time.DateTime)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", s.Version, s.Name, s.Status, appliedAt)
+	}
+	return w.Flush()
+}
+
+func validate(ctx context.Context, c *cli) error {
+	q, err := c.queen(ctx)
+	if err != nil {
+		return err
+	}
+	defer q.Close()
+
+	if err := q.Validate(ctx); err != nil {
+		return err
+	}
+	fmt.Fprintln(c.stdout, "ok")
+	return nil
+}
+
+func create(ctx context.Context, c *cli) error {
+	if c.flags.NArg() != 1 {
+		return errors.New("usage: queen create [flags] <name>")
+	}
+
+	files, err := queen.Scaffold(c.dir, c.flags.Arg(0), queen.ScaffoldOptions{Go: c.goStub, Timestamp: c.timestamp})
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		fmt.Fprintln(c.stdout, f)
+	}
+	return nil
+}
+
+func version(ctx context.Context, c *cli) error {
+	v := "(devel)"
+	if info, ok := debug.ReadBuildInfo(); ok {
+		v = info.Main.Version
+		for _, s := range info.Settings {
+			if s.Key == "vcs.revision" && len(s.Value) >= 7 {
+				v += " " + s.Value[:7]
+			}
+		}
+	}
+	fmt.Fprintln(c.stdout, "queen", v)
+	return nil
+}
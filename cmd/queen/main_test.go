@@ -0,0 +1,148 @@
+//go:build cgo
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	tmp := t.TempDir()
+	dir := filepath.Join(tmp, "migrations")
+	dsn := "sqlite://" + filepath.Join(tmp, "app.db")
+
+	var input string
+	queen := func(args ...string) (string, int) {
+		t.Helper()
+		var stdout, stderr bytes.Buffer
+		code := run(context.Background(), args, strings.NewReader(input), &stdout, &stderr)
+		return stdout.String() + stderr.String(), code
+	}
+
+	out, code := queen("create", "-dir", dir, "create users")
+	if code != 0 || !strings.Contains(out, "001_create_users.up.sql") {
+		t.Fatalf("create = %d, %s", code, out)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "001_create_users.up.sql"), []byte("CREATE TABLE users (id INTEGER PRIMARY KEY)"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "001_create_users.down.sql"), []byte("DROP TABLE users"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	out, code = queen("plan", "-dir", dir, "-dsn", dsn)
+	if code != 0 || !strings.Contains(out, "up: 1 migration") || !strings.Contains(out, "CREATE TABLE users") {
+		t.Fatalf("plan = %d, %s", code, out)
+	}
+	out, code = queen("up", "-dir", dir, "-dsn", dsn)
+	if code != 0 || !strings.Contains(out, "applying 001 create_users\napplied 001 create_users in ") {
+		t.Fatalf("up = %d, %s", code, out)
+	}
+	out, code = queen("status", "-dir", dir, "-dsn", dsn)
+	if code != 0 || !strings.Contains(out, "create_users") || !strings.Contains(out, "applied") {
+		t.Errorf("status = %d, %s", code, out)
+	}
+	out, code = queen("status", "-dir", dir, "-dsn", dsn, "-format", "json")
+	if code != 0 || !strings.Contains(out, `"status": "applied"`) {
+		t.Errorf("status -format json = %d, %s", code, out)
+	}
+	if out, code := queen("validate", "-dir", dir, "-dsn", dsn); code != 0 {
+		t.Errorf("validate = %d, %s", code, out)
+	}
+
+	out, code = queen("plan", "-dir", dir, "-dsn", dsn, "-down", "-format", "json")
+	if code != 0 || !strings.Contains(out, `"sql": "DROP TABLE users"`) || !strings.Contains(out, `"destructive": true`) {
+		t.Errorf("plan -down = %d, %s", code, out)
+	}
+
+	// Rolling back DROP TABLE users asks for the version
+	out, code = queen("down", "-dir", dir, "-dsn", dsn)
+	if code != 1 || !strings.Contains(out, "DROP TABLE users") || !strings.Contains(out, "not confirmed") {
+		t.Fatalf("down without confirmation = %d, %s; want 1 after showing the statement", code, out)
+	}
+	input = "002\n"
+	if out, code := queen("down", "-dir", dir, "-dsn", dsn); code != 1 {
+		t.Fatalf("down with the wrong version = %d, %s; want 1", code, out)
+	}
+	input = "001\n"
+	if out, code := queen("down", "-dir", dir, "-dsn", dsn); code != 0 {
+		t.Fatalf("down = %d, %s", code, out)
+	}
+	input = ""
+	if out, _ := queen("status", "-dir", dir, "-dsn", dsn); !strings.Contains(out, "pending") {
+		t.Errorf("status after down = %s; want pending", out)
+	}
+	if out, code := queen("up", "-dir", dir, "-dsn", dsn); code != 0 {
+		t.Fatalf("up = %d, %s", code, out)
+	}
+	if out, code := queen("down", "-dir", dir, "-dsn", dsn, "-yes"); code != 0 {
+		t.Fatalf("down -yes = %d, %s", code, out)
+	}
+	if out, code := queen("up", "-dir", dir, "-dsn", dsn); code != 0 {
+		t.Fatalf("up = %d, %s", code, out)
+	}
+
+	// Editing an applied migration fails validation
+	if err := os.WriteFile(filepath.Join(dir, "001_create_users.up.sql"), []byte("CREATE TABLE users (id BIGINT)"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if out, code := queen("validate", "-dir", dir, "-dsn", dsn); code != 1 {
+		t.Errorf("validate = %d, %s; want 1 after an edit", code, out)
+	}
+
+	if _, code := queen("up", "-dir", dir); code != 2 {
+		t.Errorf("up without a DSN = %d; want 2", code)
+	}
+	if _, code := queen("migrate"); code != 2 {
+		t.Errorf("unknown command = %d; want 2", code)
+	}
+	if out, code := queen("version"); code != 0 || !strings.HasPrefix(out, "queen ") {
+		t.Errorf("version = %d, %s", code, out)
+	}
+}
+
+func TestRunConfigFile(t *testing.T) {
+	tmp := t.TempDir()
+	dir := filepath.Join(tmp, "migrations")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "001_create_users.up.sql"), []byte("CREATE TABLE users (id INTEGER PRIMARY KEY)"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("QUEEN_TEST_DB_DIR", tmp)
+	configPath := filepath.Join(tmp, "queen.yaml")
+	content := "dir: " + dir + "\ntable: app_migrations\nenvironments:\n" +
+		"  dev:\n    dsn: sqlite://${QUEEN_TEST_DB_DIR}/dev.db\n" +
+		"  qa:\n    dsn: sqlite://${QUEEN_TEST_DB_DIR}/qa.db\n"
+	if err := os.WriteFile(configPath, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if code := run(context.Background(), []string{"up", "-config", configPath, "-env", "dev"}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("up = %d, %s", code, stderr.String())
+	}
+	if _, err := os.Stat(filepath.Join(tmp, "dev.db")); err != nil {
+		t.Errorf("expected the dev database to be created: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(tmp, "qa.db")); err == nil {
+		t.Error("expected the qa database to be left alone")
+	}
+
+	// A flag overrides the file, and an environment missing from it fails
+	stdout.Reset()
+	code := run(context.Background(), []string{"status", "-config", configPath, "-env", "dev", "-table", "other_migrations"}, nil, &stdout, &stderr)
+	if code != 0 || !strings.Contains(stdout.String(), "pending") {
+		t.Errorf("status with another table = %d, %s; want pending", code, stdout.String())
+	}
+	if code := run(context.Background(), []string{"up", "-config", configPath, "-env", "prod"}, nil, &stdout, &stderr); code != 2 {
+		t.Errorf("up -env prod = %d; want 2", code)
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/honeynil/queen"
+	"github.com/honeynil/queen/server"
+)
+
+// serve runs a server.Server reloading the migration directory on every
+// pass, with its HTTP handler on -addr, until ctx is cancelled.
+func serve(ctx context.Context, c *cli) error {
+	driver, err := openDriver(ctx, c.driver, c.dsn)
+	if err != nil {
+		return err
+	}
+	defer driver.Close()
+
+	source := server.SourceFunc(func(ctx context.Context) ([]queen.M, error) {
+		return c.migrations()
+	})
+	s := server.New(driver, source, server.Options{
+		Interval: c.interval,
+		Config:   c.config(),
+		OnReconcile: func(r server.Result) {
+			switch {
+			case r.Err != nil:
+				log.Printf("queen: pass failed: %v", r.Err)
+			case len(r.Applied) > 0:
+				log.Printf("queen: applied %v", r.Applied)
+			}
+		},
+	})
+
+	srv := &http.Server{Addr: c.addr, Handler: s.Handler()}
+	errc := make(chan error, 1)
+	go func() { errc <- srv.ListenAndServe() }()
+	defer srv.Close()
+
+	fmt.Fprintf(c.stdout, "serving on %s, checking every %s\n", c.addr, c.interval)
+
+	runErr := make(chan error, 1)
+	go func() { runErr <- s.Run(ctx) }()
+
+	select {
+	case err := <-errc:
+		return err
+	case err := <-runErr:
+		if errors.Is(err, context.Canceled) {
+			return nil
+		}
+		return err
+	}
+}
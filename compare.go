@@ -0,0 +1,91 @@
+package queen
+
+import (
+	"context"
+	"sort"
+
+	naturalsort "github.com/honeynil/queen/internal/sort"
+)
+
+// Comparison is the difference between the migration histories of two
+// databases, as returned by CompareWith. Versions are in natural sort order.
+type Comparison struct {
+	// OnlyHere lists versions applied in this Queen's database only.
+	OnlyHere []string
+
+	// OnlyThere lists versions applied in the other database only.
+	OnlyThere []string
+
+	// ChecksumMismatch lists versions applied in both with different
+	// checksums.
+	ChecksumMismatch []string
+
+	// DirtyMismatch lists versions applied in both that are dirty in one
+	// of them only.
+	DirtyMismatch []string
+}
+
+// Equal reports whether both databases have the same migration history.
+func (c *Comparison) Equal() bool {
+	return len(c.OnlyHere) == 0 && len(c.OnlyThere) == 0 &&
+		len(c.ChecksumMismatch) == 0 && len(c.DirtyMismatch) == 0
+}
+
+// CompareWith compares the applied migrations of this Queen's database with
+// those of other, e.g. to check that a restored backup or a new replica has
+// the same migration history before cutting over to it. Registered
+// migrations don't matter; only the tracking tables are compared.
+//
+// other is initialized like Queen's own driver, which creates its tracking
+// table if it doesn't exist yet.
+func (q *Queen) CompareWith(ctx context.Context, other Driver) (*Comparison, error) {
+	if q.driver == nil || other == nil {
+		return nil, ErrNoDriver
+	}
+
+	if err := q.init(ctx); err != nil {
+		return nil, err
+	}
+	if err := q.loadApplied(ctx); err != nil {
+		return nil, err
+	}
+
+	if err := other.Init(ctx); err != nil {
+		return nil, err
+	}
+	applied, err := other.GetApplied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	there := make(map[string]Applied, len(applied))
+	for _, a := range applied {
+		there[a.Version] = a
+	}
+
+	c := &Comparison{}
+	for version, here := range q.applied {
+		a, ok := there[version]
+		switch {
+		case !ok:
+			c.OnlyHere = append(c.OnlyHere, version)
+		case a.Checksum != here.Checksum:
+			c.ChecksumMismatch = append(c.ChecksumMismatch, version)
+		case a.Dirty != here.Dirty:
+			c.DirtyMismatch = append(c.DirtyMismatch, version)
+		}
+	}
+	for version := range there {
+		if _, ok := q.applied[version]; !ok {
+			c.OnlyThere = append(c.OnlyThere, version)
+		}
+	}
+
+	for _, versions := range [][]string{c.OnlyHere, c.OnlyThere, c.ChecksumMismatch, c.DirtyMismatch} {
+		sort.Slice(versions, func(i, j int) bool {
+			return naturalsort.Compare(versions[i], versions[j]) < 0
+		})
+	}
+
+	return c, nil
+}
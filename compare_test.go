@@ -0,0 +1,63 @@
+package queen_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/honeynil/queen"
+	"github.com/honeynil/queen/drivers/mock"
+)
+
+func TestCompareWith(t *testing.T) {
+	ctx := context.Background()
+
+	record := func(d *mock.Driver, version, sum string, dirty bool) {
+		t.Helper()
+		m := &queen.M{Version: version, Name: "migration_" + version, ManualChecksum: sum, UpFunc: noop}
+		if err := d.RecordWithMeta(ctx, m, queen.RecordMeta{Batch: 1, Dirty: dirty}); err != nil {
+			t.Fatalf("Record failed: %v", err)
+		}
+	}
+
+	primary, replica := mock.New(), mock.New()
+	for _, v := range []string{"1", "2", "10"} {
+		record(primary, v, "v1", false)
+		record(replica, v, "v1", false)
+	}
+
+	q := queen.New(primary)
+	c, err := q.CompareWith(ctx, replica)
+	if err != nil {
+		t.Fatalf("CompareWith failed: %v", err)
+	}
+	if !c.Equal() {
+		t.Errorf("Expected identical histories, got %+v", c)
+	}
+
+	record(primary, "11", "v1", false)
+	record(primary, "12", "v1", false)
+	record(replica, "3", "v1", false)
+	record(replica, "2", "v2", false)
+	record(replica, "10", "v1", true)
+
+	c, err = q.CompareWith(ctx, replica)
+	if err != nil {
+		t.Fatalf("CompareWith failed: %v", err)
+	}
+	if c.Equal() {
+		t.Fatal("Expected histories to differ")
+	}
+	if !slices.Equal(c.OnlyHere, []string{"11", "12"}) {
+		t.Errorf("OnlyHere = %v", c.OnlyHere)
+	}
+	if !slices.Equal(c.OnlyThere, []string{"3"}) {
+		t.Errorf("OnlyThere = %v", c.OnlyThere)
+	}
+	if !slices.Equal(c.ChecksumMismatch, []string{"2"}) {
+		t.Errorf("ChecksumMismatch = %v", c.ChecksumMismatch)
+	}
+	if !slices.Equal(c.DirtyMismatch, []string{"10"}) {
+		t.Errorf("DirtyMismatch = %v", c.DirtyMismatch)
+	}
+}
@@ -0,0 +1,295 @@
+// Package compat applies the same migrations to several databases and
+// reports which migrations work on which, for libraries shipping
+// migrations that must run on PostgreSQL, MySQL and SQLite alike.
+//
+// Each Target opens an empty database, typically in a container started
+// with testcontainers-go, and returns a driver for it:
+//
+//	postgres := compat.Target{Name: "postgres", Open: func(ctx context.Context) (queen.Driver, func(), error) {
+//	    c, err := tcpostgres.Run(ctx, "postgres:16-alpine", tcpostgres.BasicWaitStrategies())
+//	    if err != nil {
+//	        return nil, nil, err
+//	    }
+//	    dsn, _ := c.ConnectionString(ctx, "sslmode=disable")
+//	    db, err := sql.Open("pgx", dsn)
+//	    if err != nil {
+//	        return nil, nil, err
+//	    }
+//	    return postgres.New(db), func() { _ = c.Terminate(context.Background()) }, nil
+//	}}
+//
+// Test runs the migrations against every target and fails the test with a
+// pass/fail matrix if any of them fails somewhere:
+//
+//	func TestMigrationsCompat(t *testing.T) {
+//	    compat.Test(t, migrations.All(), &compat.Options{Down: true}, postgres, mysql, sqlite)
+//	}
+//
+//	version  postgres  mysql  sqlite
+//	001      pass      pass   pass
+//	002      pass      FAIL   pass
+//	003      pass      skip   pass
+//
+//	mysql 002: Error 1064 (42000): You have an error in your SQL syntax ...
+//
+// The package also imports the state of other migration tools, for
+// projects switching to Queen: ImportGolangMigrate marks the migrations
+// golang-migrate applied as applied in Queen's tracking table, and
+// ImportGoose does the same for goose. Flyway goes both ways, for JVM
+// services sharing the database: it imports Flyway's state and records
+// Queen's migrations in Flyway's table.
+package compat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"text/tabwriter"
+
+	"github.com/honeynil/queen"
+)
+
+// Target is a database the migrations are applied to.
+type Target struct {
+	// Name labels the target in the matrix, e.g. "postgres-16".
+	Name string
+
+	// Open starts or connects to an empty database and returns a driver
+	// for it, and a function releasing the database, e.g. terminating its
+	// container. The driver is closed before release is called, which may
+	// be nil.
+	Open func(ctx context.Context) (driver queen.Driver, release func(), err error)
+}
+
+// Options configures Run.
+type Options struct {
+	// Config configures the Queen instance of each target.
+	// Default: queen.DefaultConfig()
+	Config *queen.Config
+
+	// Down rolls every migration back once all are applied, then applies
+	// them again, to check down migrations too. Rolling back stops at a
+	// migration without a down migration that sets IrreversibleOK.
+	Down bool
+}
+
+// config returns a copy of Config for a new Queen, or the default.
+func (o *Options) config() *queen.Config {
+	if o.Config == nil {
+		return queen.DefaultConfig()
+	}
+	c := *o.Config
+	return &c
+}
+
+// Status is the outcome of a migration on a target.
+type Status int
+
+const (
+	// Skip means the migration didn't run, because the target couldn't be
+	// opened or an earlier migration failed on it.
+	Skip Status = iota
+
+	// Pass means the migration was applied, and rolled back and applied
+	// again if Options.Down is set.
+	Pass
+
+	// Fail means the migration failed on the target.
+	Fail
+)
+
+// String returns "skip", "pass" or "FAIL".
+func (s Status) String() string {
+	switch s {
+	case Pass:
+		return "pass"
+	case Fail:
+		return "FAIL"
+	default:
+		return "skip"
+	}
+}
+
+// Result is the outcome of a migration on a target.
+type Result struct {
+	Status Status
+
+	// Err is why the migration failed, or why it was skipped if the
+	// target couldn't be opened.
+	Err error
+}
+
+// Matrix is the outcome of every migration on every target.
+type Matrix struct {
+	// Versions lists the migrations in the order they were applied, and
+	// Targets the targets in the order given.
+	Versions []string
+	Targets  []string
+
+	results map[[2]string]Result
+}
+
+// Result returns the outcome of the migration version on the target.
+func (m *Matrix) Result(version, target string) Result {
+	return m.results[[2]string{version, target}]
+}
+
+// Failed reports whether a migration failed or was skipped on any target.
+func (m *Matrix) Failed() bool {
+	for _, v := range m.Versions {
+		for _, target := range m.Targets {
+			if m.Result(v, target).Status != Pass {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// String returns the matrix as a table with a row per migration and a
+// column per target, followed by the errors.
+func (m *Matrix) String() string {
+	var b strings.Builder
+
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "version\t%s\n", strings.Join(m.Targets, "\t"))
+	for _, v := range m.Versions {
+		cells := make([]string, len(m.Targets))
+		for i, target := range m.Targets {
+			cells[i] = m.Result(v, target).Status.String()
+		}
+		fmt.Fprintf(w, "%s\t%s\n", v, strings.Join(cells, "\t"))
+	}
+	_ = w.Flush()
+
+	// An error of an unopened target is the same for every version
+	reported := make(map[string]bool)
+	for _, target := range m.Targets {
+		for _, v := range m.Versions {
+			r := m.Result(v, target)
+			switch {
+			case r.Err == nil:
+			case r.Status == Skip && !reported[target]:
+				reported[target] = true
+				fmt.Fprintf(&b, "\n%s: %v", target, r.Err)
+			case r.Status == Fail:
+				fmt.Fprintf(&b, "\n%s %s: %v", target, v, r.Err)
+			}
+		}
+	}
+
+	return b.String()
+}
+
+// Run applies migrations to every target, one at a time, and returns the
+// outcome of each migration on each target. A nil opts uses the defaults.
+func Run(ctx context.Context, migrations []queen.M, opts *Options, targets ...Target) *Matrix {
+	if opts == nil {
+		opts = &Options{}
+	}
+
+	m := &Matrix{results: make(map[[2]string]Result)}
+	for _, t := range targets {
+		m.Targets = append(m.Targets, t.Name)
+	}
+
+	// The order migrations are applied in, from a Queen without a database
+	plan := queen.NewWithConfig(nil, opts.config())
+	if err := plan.AddAll(migrations...); err == nil {
+		m.Versions = plan.Simulate(nil).Up(0).Plan
+	} else {
+		for _, mig := range migrations {
+			m.Versions = append(m.Versions, mig.Version)
+		}
+	}
+
+	for _, t := range targets {
+		for version, r := range run(ctx, t, migrations, opts) {
+			m.results[[2]string{version, t.Name}] = r
+		}
+	}
+
+	return m
+}
+
+// Test runs migrations like Run and fails t with the matrix if any of them
+// fails on a target. The matrix is logged either way.
+func Test(t testing.TB, migrations []queen.M, opts *Options, targets ...Target) {
+	t.Helper()
+
+	m := Run(context.Background(), migrations, opts, targets...)
+	if m.Failed() {
+		t.Errorf("migrations failed on some targets:\n%s", m)
+		return
+	}
+	t.Logf("migrations passed on every target:\n%s", m)
+}
+
+// run applies migrations to target and returns the outcome by version.
+func run(ctx context.Context, target Target, migrations []queen.M, opts *Options) map[string]Result {
+	results := make(map[string]Result)
+	skipAll := func(err error) map[string]Result {
+		for _, mig := range migrations {
+			results[mig.Version] = Result{Status: Skip, Err: err}
+		}
+		return results
+	}
+
+	driver, release, err := target.Open(ctx)
+	if err != nil {
+		return skipAll(fmt.Errorf("open: %w", err))
+	}
+	if release != nil {
+		defer release()
+	}
+
+	q := queen.NewWithConfig(driver, opts.config())
+	defer q.Close()
+
+	if err := q.AddAll(migrations...); err != nil {
+		return skipAll(err)
+	}
+
+	pending, err := q.Pending(ctx)
+	if err != nil {
+		return skipAll(err)
+	}
+
+	// Apply one at a time to tell which migration fails
+	for i, mig := range pending {
+		if err := q.UpSteps(ctx, 1); err != nil {
+			results[mig.Version] = Result{Status: Fail, Err: err}
+			for _, rest := range pending[i+1:] {
+				results[rest.Version] = Result{Status: Skip}
+			}
+			return results
+		}
+		results[mig.Version] = Result{Status: Pass}
+	}
+
+	if !opts.Down {
+		return results
+	}
+
+	for i := len(pending) - 1; i >= 0; i-- {
+		mig := pending[i]
+		if !mig.HasRollback() && mig.IrreversibleOK {
+			break
+		}
+		if err := q.Down(ctx, 1); err != nil {
+			results[mig.Version] = Result{Status: Fail, Err: fmt.Errorf("down: %w", err)}
+			return results
+		}
+	}
+
+	if err := q.Up(ctx); err != nil {
+		var migErr *queen.MigrationError
+		if errors.As(err, &migErr) {
+			results[migErr.Version] = Result{Status: Fail, Err: fmt.Errorf("reapply: %w", err)}
+		}
+	}
+
+	return results
+}
@@ -0,0 +1,94 @@
+//go:build cgo
+
+package compat_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/honeynil/queen"
+	"github.com/honeynil/queen/compat"
+	"github.com/honeynil/queen/drivers/sqlite"
+)
+
+// sqliteTarget opens an in-memory SQLite database, running setup first.
+func sqliteTarget(name, setup string) compat.Target {
+	return compat.Target{Name: name, Open: func(ctx context.Context) (queen.Driver, func(), error) {
+		db, err := sql.Open("sqlite3", ":memory:")
+		if err != nil {
+			return nil, nil, err
+		}
+		db.SetMaxOpenConns(1)
+		if setup != "" {
+			if _, err := db.ExecContext(ctx, setup); err != nil {
+				return nil, nil, err
+			}
+		}
+		return sqlite.New(db), nil, nil
+	}}
+}
+
+func TestRun(t *testing.T) {
+	migrations := []queen.M{
+		{Version: "001", Name: "create_users", UpSQL: "CREATE TABLE users (id INTEGER PRIMARY KEY)", DownSQL: "DROP TABLE users"},
+		{Version: "002", Name: "create_posts", UpSQL: "CREATE TABLE posts (id INTEGER PRIMARY KEY)", DownSQL: "DROP TABLE posts"},
+		{Version: "10", Name: "create_tags", UpSQL: "CREATE TABLE tags (id INTEGER PRIMARY KEY)", DownSQL: "DROP TABLE tags"},
+	}
+
+	unavailable := compat.Target{Name: "unavailable", Open: func(ctx context.Context) (queen.Driver, func(), error) {
+		return nil, nil, errors.New("no container runtime")
+	}}
+
+	m := compat.Run(context.Background(), migrations, &compat.Options{Down: true},
+		sqliteTarget("clean", ""),
+		sqliteTarget("conflict", "CREATE TABLE posts (id INTEGER)"),
+		unavailable,
+	)
+
+	want := map[string][]compat.Status{
+		"clean":       {compat.Pass, compat.Pass, compat.Pass},
+		"conflict":    {compat.Pass, compat.Fail, compat.Skip},
+		"unavailable": {compat.Skip, compat.Skip, compat.Skip},
+	}
+	for target, statuses := range want {
+		for i, version := range []string{"001", "002", "10"} {
+			if got := m.Result(version, target).Status; got != statuses[i] {
+				t.Errorf("%s %s = %v; want %v", target, version, got, statuses[i])
+			}
+		}
+	}
+
+	if !m.Failed() {
+		t.Error("expected Failed() with failing targets")
+	}
+
+	out := m.String()
+	for _, s := range []string{"version  clean  conflict  unavailable", "002      pass   FAIL      skip", "conflict 002: ", "unavailable: open: no container runtime"} {
+		if !strings.Contains(out, s) {
+			t.Errorf("String() lacks %q:\n%s", s, out)
+		}
+	}
+}
+
+func TestRunDown(t *testing.T) {
+	migrations := []queen.M{
+		{Version: "001", Name: "create_users", UpSQL: "CREATE TABLE users (id INTEGER PRIMARY KEY)", DownSQL: "DROP TABLE users"},
+		// The down migration leaves the table behind, so it can't be reapplied
+		{Version: "002", Name: "create_posts", UpSQL: "CREATE TABLE posts (id INTEGER PRIMARY KEY)", DownSQL: "SELECT 1"},
+	}
+
+	m := compat.Run(context.Background(), migrations, nil, sqliteTarget("sqlite", ""))
+	if m.Failed() {
+		t.Errorf("expected Up alone to pass:\n%s", m)
+	}
+
+	m = compat.Run(context.Background(), migrations, &compat.Options{Down: true}, sqliteTarget("sqlite", ""))
+	if r := m.Result("002", "sqlite"); r.Status != compat.Fail || !strings.Contains(r.Err.Error(), "reapply") {
+		t.Errorf("002 = %v (%v); want a reapply failure", r.Status, r.Err)
+	}
+}
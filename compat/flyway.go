@@ -0,0 +1,364 @@
+package compat
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"hash/crc32"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/honeynil/queen"
+	"github.com/honeynil/queen/internal/split"
+)
+
+// FlywayTable is the table Flyway records its state in by default.
+const FlywayTable = "flyway_schema_history"
+
+// Flyway reads and writes Flyway's schema history table, for JVM and Go
+// services sharing one database while moving between the two tools:
+//
+//   - Import marks the migrations Flyway applied as applied in Queen
+//   - Hook records the migrations Queen runs in Flyway's table, so Flyway
+//     sees them too
+//   - History merges both tools' history into one
+//
+// Flyway versions match registered versions by their leading number, so
+// Flyway's "42" matches "042_add_index"; other versions, such as dotted
+// ones, go through Map and Version.
+type Flyway struct {
+	// DB is the database holding Flyway's table.
+	DB *sql.DB
+
+	// Table is Flyway's table, as set with flyway.table. It is used in
+	// queries as is, so it may be qualified with a schema.
+	// Default: FlywayTable
+	Table string
+
+	// PostgreSQL makes Hook write with $1 placeholders instead of ?.
+	// Default: false
+	PostgreSQL bool
+
+	// Map returns the registered version of a Flyway version that doesn't
+	// match one by its leading number. Returning ok with an empty version
+	// skips it. Default: nil (unmatched versions fail Import)
+	Map func(flywayVersion string) (version string, ok bool)
+
+	// Version returns the Flyway version Hook records a migration under.
+	// Default: nil (the leading number of its version)
+	Version func(version string) string
+
+	// InstalledBy is recorded by Hook as the user who installed a
+	// migration. Default: "queen"
+	InstalledBy string
+
+	// OnError is called with Hook's failures to write Flyway's table,
+	// which Queen ignores after a migration ran. Default: nil
+	OnError func(err error)
+}
+
+// FlywayMigration is a row of Flyway's schema history table.
+type FlywayMigration struct {
+	InstalledRank int64
+	Version       string // empty for repeatable migrations
+	Description   string
+	Type          string // e.g. "SQL", "JDBC", "BASELINE", "UNDO_SQL"
+	Script        string
+	Checksum      *int32
+	InstalledBy   string
+	InstalledOn   time.Time
+	ExecutionTime time.Duration
+	Success       bool
+}
+
+// undo reports whether the row records a rollback or a removal.
+func (m FlywayMigration) undo() bool {
+	return strings.HasPrefix(m.Type, "UNDO_") || m.Type == "DELETE"
+}
+
+// FlywayChecksum returns the checksum Flyway records for a SQL script: the
+// CRC32 of its lines, without line breaks and byte order mark. Flyway
+// validates it against the script on its side, so migrations shared by
+// both tools must have the same SQL.
+func FlywayChecksum(script string) int32 {
+	script = strings.TrimPrefix(script, "\uFEFF")
+	script = strings.NewReplacer("\r", "", "\n", "").Replace(script)
+	return int32(crc32.ChecksumIEEE([]byte(script)))
+}
+
+func (f *Flyway) table() string {
+	if f.Table == "" {
+		return FlywayTable
+	}
+	return f.Table
+}
+
+// Read returns the rows of Flyway's table, in the order Flyway installed
+// them.
+func (f *Flyway) Read(ctx context.Context) ([]FlywayMigration, error) {
+	rows, err := f.DB.QueryContext(ctx, `SELECT installed_rank, COALESCE(version, ''), description, type, script,
+		checksum, installed_by, installed_on, execution_time, success FROM `+f.table()+` ORDER BY installed_rank`)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", f.table(), err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var migrations []FlywayMigration
+	for rows.Next() {
+		var m FlywayMigration
+		var checksum sql.NullInt32
+		var executionMS int64
+		if err := rows.Scan(&m.InstalledRank, &m.Version, &m.Description, &m.Type, &m.Script,
+			&checksum, &m.InstalledBy, &m.InstalledOn, &executionMS, &m.Success); err != nil {
+			return nil, fmt.Errorf("read %s: %w", f.table(), err)
+		}
+		if checksum.Valid {
+			m.Checksum = &checksum.Int32
+		}
+		m.ExecutionTime = time.Duration(executionMS) * time.Millisecond
+		migrations = append(migrations, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("read %s: %w", f.table(), err)
+	}
+
+	return migrations, nil
+}
+
+// History returns the history of q merged with the rows of Flyway's
+// table, oldest first. Flyway's rows have RunID "flyway" and Operator
+// "flyway", rows Hook wrote are left out as q has them already.
+func (f *Flyway) History(ctx context.Context, q *queen.Queen) ([]queen.HistoryEntry, error) {
+	history, err := q.History(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	migrations, err := f.Read(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range migrations {
+		if m.InstalledBy == f.installedBy() {
+			continue
+		}
+
+		e := queen.HistoryEntry{
+			ID:        m.InstalledRank,
+			RunID:     "flyway",
+			Version:   m.Version,
+			Name:      m.Description,
+			Down:      m.undo(),
+			StartedAt: m.InstalledOn,
+			Duration:  m.ExecutionTime,
+			AppliedBy: m.InstalledBy,
+			Operator:  "flyway",
+		}
+		if !m.Success {
+			e.Error = "failed in Flyway"
+		}
+		history = append(history, e)
+	}
+
+	slices.SortStableFunc(history, func(a, b queen.HistoryEntry) int {
+		return a.StartedAt.Compare(b.StartedAt)
+	})
+	return history, nil
+}
+
+// Import marks the registered migrations Flyway applied as applied in q,
+// with Queen.Force, without running any SQL. A baseline marks every
+// registered migration up to its version. SQL migrations Flyway recorded
+// a checksum for must have the same SQL in q, or nothing is imported and
+// the error wraps queen.ErrChecksumMismatch; so is nothing if an applied
+// version matches no registered one.
+//
+// Returns the versions marked applied, in order.
+func (f *Flyway) Import(ctx context.Context, q *queen.Queen) ([]string, error) {
+	migrations, err := f.Read(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// The latest row of a version wins, e.g. an undo over an apply
+	latest := make(map[string]FlywayMigration)
+	var versions []string
+	for _, m := range migrations {
+		if m.Version == "" {
+			continue
+		}
+		if _, ok := latest[m.Version]; !ok {
+			versions = append(versions, m.Version)
+		}
+		latest[m.Version] = m
+	}
+
+	statuses, err := q.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+	numbers, err := versionNumbers(statuses)
+	if err != nil {
+		return nil, err
+	}
+	byNumber := make(map[int64]string, len(numbers))
+	for version, n := range numbers {
+		byNumber[n] = version
+	}
+	registered := make(map[string]bool, len(statuses))
+	for _, s := range statuses {
+		registered[s.Version] = s.Status != queen.StatusUnknown
+	}
+
+	imported := make(map[string]FlywayMigration)
+	for _, v := range versions {
+		m := latest[v]
+		if !m.Success || m.undo() {
+			continue
+		}
+
+		if m.Type == "BASELINE" {
+			baseline, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%w: Flyway baseline %s", queen.ErrMigrationNotFound, v)
+			}
+			for version, n := range numbers {
+				if n <= baseline {
+					imported[version] = m
+				}
+			}
+			continue
+		}
+
+		version, ok := "", false
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+			version, ok = byNumber[n]
+		}
+		if !ok && f.Map != nil {
+			version, ok = f.Map(v)
+			if ok && version == "" {
+				continue
+			}
+		}
+		if !ok || !registered[version] {
+			return nil, fmt.Errorf("%w: Flyway version %s", queen.ErrMigrationNotFound, v)
+		}
+		imported[version] = m
+	}
+
+	pending, err := q.Pending(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var toForce []string
+	for _, p := range pending {
+		m, ok := imported[p.Version]
+		if !ok {
+			continue
+		}
+		if m.Type == "SQL" && m.Checksum != nil && p.UpSQL != "" && *m.Checksum != FlywayChecksum(p.UpSQL) {
+			return nil, fmt.Errorf("%w: %s differs from Flyway's %s", queen.ErrChecksumMismatch, p.Version, m.Script)
+		}
+		toForce = append(toForce, p.Version)
+	}
+
+	return force(ctx, q, toForce)
+}
+
+func (f *Flyway) installedBy() string {
+	if f.InstalledBy == "" {
+		return "queen"
+	}
+	return f.InstalledBy
+}
+
+// flywayVersion returns the Flyway version of a registered version.
+func (f *Flyway) flywayVersion(version string) (string, error) {
+	if f.Version != nil {
+		return f.Version(version), nil
+	}
+
+	digits := version[:len(version)-len(strings.TrimLeft(version, "0123456789"))]
+	if digits == "" {
+		return "", fmt.Errorf("%w: %s has no Flyway version, set Flyway.Version", queen.ErrInvalidMigration, version)
+	}
+	return digits, nil
+}
+
+// Hook returns a hook recording the migrations q applies in Flyway's
+// table, as SQL migrations checksummed with FlywayChecksum or, for Go
+// migrations, JDBC ones without checksum. A rollback deletes the rows of
+// the migration, so Flyway sees it pending again.
+//
+//	q.Hooks().MustRegister(flyway.Hook("flyway", 100))
+func (f *Flyway) Hook(name string, priority int) queen.Hook {
+	return queen.Hook{
+		Name:     name,
+		Priority: priority,
+		Func: func(ctx context.Context, e queen.Event) error {
+			var err error
+			switch e.Kind {
+			case queen.EventAfterUp:
+				err = f.record(ctx, e)
+			case queen.EventAfterDown:
+				err = f.remove(ctx, e.Migration)
+			default:
+				return nil
+			}
+
+			if err != nil && f.OnError != nil {
+				f.OnError(err)
+			}
+			return err
+		},
+	}
+}
+
+func (f *Flyway) record(ctx context.Context, e queen.Event) error {
+	version, err := f.flywayVersion(e.Migration.Version)
+	if err != nil {
+		return err
+	}
+
+	kind, script, checksum := "JDBC", e.Migration.Version, sql.NullInt32{}
+	if e.Migration.UpSQL != "" {
+		kind = "SQL"
+		script = "V" + version + "__" + e.Migration.Name + ".sql"
+		checksum = sql.NullInt32{Int32: FlywayChecksum(e.Migration.UpSQL), Valid: true}
+	}
+
+	query := `INSERT INTO ` + f.table() + ` (installed_rank, version, description, type, script, checksum,
+		installed_by, execution_time, success)
+		SELECT COALESCE(MAX(installed_rank), 0) + 1, ?, ?, ?, ?, ?, ?, ?, ? FROM ` + f.table()
+	if f.PostgreSQL {
+		query = split.Rebind(query)
+	}
+
+	description := strings.ReplaceAll(e.Migration.Name, "_", " ")
+	_, err = f.DB.ExecContext(ctx, query, version, description, kind, script, checksum,
+		f.installedBy(), e.Duration.Milliseconds(), true)
+	if err != nil {
+		return fmt.Errorf("record %s in %s: %w", e.Migration.Version, f.table(), err)
+	}
+	return nil
+}
+
+func (f *Flyway) remove(ctx context.Context, m *queen.Migration) error {
+	version, err := f.flywayVersion(m.Version)
+	if err != nil {
+		return err
+	}
+
+	query := "DELETE FROM " + f.table() + " WHERE version = ?"
+	if f.PostgreSQL {
+		query = split.Rebind(query)
+	}
+
+	if _, err := f.DB.ExecContext(ctx, query, version); err != nil {
+		return fmt.Errorf("remove %s from %s: %w", m.Version, f.table(), err)
+	}
+	return nil
+}
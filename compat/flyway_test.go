@@ -0,0 +1,170 @@
+//go:build cgo
+
+package compat_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/honeynil/queen"
+	"github.com/honeynil/queen/compat"
+	"github.com/honeynil/queen/drivers/sqlite"
+)
+
+// openFlyway opens a database with Flyway's table holding rows of
+// version, type, checksum and success, in order.
+func openFlyway(t *testing.T, rows ...[]any) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = db.Close() })
+
+	_, err = db.Exec(`CREATE TABLE flyway_schema_history (
+		installed_rank INT NOT NULL PRIMARY KEY,
+		version VARCHAR(50),
+		description VARCHAR(200) NOT NULL,
+		type VARCHAR(20) NOT NULL,
+		script VARCHAR(1000) NOT NULL,
+		checksum INT,
+		installed_by VARCHAR(100) NOT NULL,
+		installed_on TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		execution_time INT NOT NULL,
+		success BOOLEAN NOT NULL
+	)`)
+	for i, r := range rows {
+		if err != nil {
+			break
+		}
+		_, err = db.Exec(`INSERT INTO flyway_schema_history (installed_rank, version, description, type, script,
+			checksum, installed_by, execution_time, success) VALUES (?, ?, 'jvm', ?, 'V.sql', ?, 'app', 5, ?)`,
+			i+1, r[0], r[1], r[2], r[3])
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+const flywayPosts = "CREATE TABLE posts (id INTEGER);\n"
+
+func flywayQueen(db *sql.DB) *queen.Queen {
+	q := queen.New(sqlite.New(db))
+	q.MustAdd(queen.M{Version: "001_create_users", Name: "create_users", UpSQL: "CREATE TABLE users (id INTEGER)"})
+	q.MustAdd(queen.M{Version: "002_create_posts", Name: "create_posts", UpSQL: flywayPosts})
+	q.MustAdd(queen.M{Version: "003_create_tags", Name: "create_tags", UpSQL: "CREATE TABLE tags (id INTEGER)",
+		DownSQL: "DROP TABLE tags"})
+	return q
+}
+
+func TestFlywayChecksum(t *testing.T) {
+	// Flyway checksums lines, so line endings don't matter
+	if compat.FlywayChecksum("SELECT 1;\r\nSELECT 2;\n") != compat.FlywayChecksum("\uFEFFSELECT 1;\nSELECT 2;") {
+		t.Error("FlywayChecksum depends on line endings or the byte order mark")
+	}
+	if compat.FlywayChecksum("SELECT 1;") == compat.FlywayChecksum("SELECT 2;") {
+		t.Error("FlywayChecksum is the same for different scripts")
+	}
+}
+
+func TestFlywayImport(t *testing.T) {
+	ctx := context.Background()
+	db := openFlyway(t,
+		[]any{"1", "BASELINE", nil, true},
+		[]any{"2", "SQL", compat.FlywayChecksum(flywayPosts), true},
+		[]any{"3", "SQL", 0, false},
+	)
+	q := flywayQueen(db)
+	flyway := &compat.Flyway{DB: db}
+
+	imported, err := flyway.Import(ctx, q)
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if !slices.Equal(imported, []string{"001_create_users", "002_create_posts"}) {
+		t.Errorf("Imported %v; want 001 and 002", imported)
+	}
+
+	// Importing again is a no-op
+	if imported, err := flyway.Import(ctx, q); err != nil || len(imported) != 0 {
+		t.Errorf("Second import = %v, %v; want nothing", imported, err)
+	}
+}
+
+func TestFlywayImport_Refused(t *testing.T) {
+	ctx := context.Background()
+
+	db := openFlyway(t, []any{"2", "SQL", compat.FlywayChecksum("CREATE TABLE other (id INTEGER)"), true})
+	q := flywayQueen(db)
+	if _, err := (&compat.Flyway{DB: db}).Import(ctx, q); !errors.Is(err, queen.ErrChecksumMismatch) {
+		t.Errorf("Import of different SQL = %v; want ErrChecksumMismatch", err)
+	}
+
+	db = openFlyway(t, []any{"1.1", "SQL", nil, true})
+	q = flywayQueen(db)
+	if _, err := (&compat.Flyway{DB: db}).Import(ctx, q); !errors.Is(err, queen.ErrMigrationNotFound) {
+		t.Errorf("Import of an unmatched version = %v; want ErrMigrationNotFound", err)
+	}
+
+	flyway := &compat.Flyway{DB: db, Map: func(v string) (string, bool) { return "001_create_users", v == "1.1" }}
+	if imported, err := flyway.Import(ctx, q); err != nil || !slices.Equal(imported, []string{"001_create_users"}) {
+		t.Errorf("Import of a mapped version = %v, %v; want 001", imported, err)
+	}
+}
+
+func TestFlywayHook(t *testing.T) {
+	ctx := context.Background()
+	db := openFlyway(t, []any{"1", "BASELINE", nil, true})
+	q := flywayQueen(db)
+
+	var hookErr error
+	flyway := &compat.Flyway{DB: db, OnError: func(err error) { hookErr = err }}
+	q.Hooks().MustRegister(flyway.Hook("flyway", 100))
+
+	if err := q.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if hookErr != nil {
+		t.Fatalf("Hook failed: %v", hookErr)
+	}
+
+	rows, err := flyway.Read(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 4 {
+		t.Fatalf("Flyway has %d rows; want the baseline and 3 migrations", len(rows))
+	}
+	last := rows[3]
+	if last.InstalledRank != 4 || last.Version != "003" || last.Type != "SQL" || last.Script != "V003__create_tags.sql" ||
+		last.Description != "create tags" || last.InstalledBy != "queen" || !last.Success ||
+		last.Checksum == nil || *last.Checksum != compat.FlywayChecksum("CREATE TABLE tags (id INTEGER)") {
+		t.Errorf("Recorded %+v", last)
+	}
+
+	// Flyway's baseline shows up next to Queen's runs, which it doesn't
+	// repeat
+	history, err := flyway.History(ctx, q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	baseline := slices.IndexFunc(history, func(e queen.HistoryEntry) bool { return e.Operator == "flyway" })
+	if len(history) != 4 || baseline < 0 || history[baseline].Version != "1" {
+		t.Errorf("History = %+v; want Flyway's baseline and 3 migrations", history)
+	}
+
+	if err := q.Down(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	if rows, _ := flyway.Read(ctx); len(rows) != 3 {
+		t.Errorf("Flyway has %d rows after rolling 003 back; want 3", len(rows))
+	}
+}
@@ -0,0 +1,108 @@
+package compat
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/honeynil/queen"
+)
+
+// GolangMigrateTable is the table golang-migrate records its state in.
+const GolangMigrateTable = "schema_migrations"
+
+// ImportGolangMigrate seeds the tracking table of q from the state
+// golang-migrate left in db, so a project can switch tools without
+// replaying its migrations. golang-migrate records only the last applied
+// version: every registered migration up to it is marked applied with
+// Queen.Force, without running any SQL.
+//
+// Registered versions match golang-migrate's by their leading number, so
+// "000042_add_index" or "42" match version 42. Nothing is imported if
+// golang-migrate's version isn't registered, or if its state is dirty,
+// which needs fixing by hand and "migrate force" first.
+//
+// Returns the versions marked applied, in order.
+func ImportGolangMigrate(ctx context.Context, db *sql.DB, q *queen.Queen) ([]string, error) {
+	var version int64
+	var dirty bool
+	err := db.QueryRowContext(ctx, "SELECT version, dirty FROM "+GolangMigrateTable).Scan(&version, &dirty)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", GolangMigrateTable, err)
+	}
+	if dirty {
+		return nil, fmt.Errorf("%w: golang-migrate version %d is dirty, fix it and run migrate force first",
+			queen.ErrDirty, version)
+	}
+
+	statuses, err := q.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+	numbers, err := versionNumbers(statuses)
+	if err != nil {
+		return nil, err
+	}
+
+	var toForce []string
+	found := false
+	for _, s := range statuses {
+		n, ok := numbers[s.Version]
+		if !ok || n > version {
+			continue
+		}
+		found = found || n == version
+		if s.Status == queen.StatusPending {
+			toForce = append(toForce, s.Version)
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("%w: golang-migrate version %d", queen.ErrMigrationNotFound, version)
+	}
+
+	return force(ctx, q, toForce)
+}
+
+// force marks versions applied with one Queen.Force, so they share a
+// batch, returning those it marked.
+func force(ctx context.Context, q *queen.Queen, versions []string) ([]string, error) {
+	if len(versions) == 0 {
+		return nil, nil
+	}
+	if err := q.Force(ctx, versions...); err != nil {
+		return nil, fmt.Errorf("force: %w", err)
+	}
+	return versions, nil
+}
+
+// versionNumbers returns the leading number of the registered versions
+// that start with one, failing if two share a number.
+func versionNumbers(statuses []queen.MigrationStatus) (map[string]int64, error) {
+	numbers := make(map[string]int64, len(statuses))
+	byNumber := make(map[int64]string, len(statuses))
+
+	for _, s := range statuses {
+		if s.Status == queen.StatusUnknown {
+			continue
+		}
+
+		digits := s.Version[:len(s.Version)-len(strings.TrimLeft(s.Version, "0123456789"))]
+		n, err := strconv.ParseInt(digits, 10, 64)
+		if err != nil {
+			continue
+		}
+		if other, ok := byNumber[n]; ok {
+			return nil, fmt.Errorf("%w: versions %s and %s both number %d", queen.ErrVersionConflict, other, s.Version, n)
+		}
+		numbers[s.Version] = n
+		byNumber[n] = s.Version
+	}
+
+	return numbers, nil
+}
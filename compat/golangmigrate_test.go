@@ -0,0 +1,101 @@
+//go:build cgo
+
+package compat_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/honeynil/queen"
+	"github.com/honeynil/queen/compat"
+	"github.com/honeynil/queen/drivers/sqlite"
+)
+
+// openGolangMigrate opens a database golang-migrate left at version.
+func openGolangMigrate(t *testing.T, version int, dirty bool) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = db.Close() })
+
+	_, err = db.Exec("CREATE TABLE schema_migrations (version BIGINT NOT NULL PRIMARY KEY, dirty BOOLEAN NOT NULL)")
+	if err == nil {
+		_, err = db.Exec("INSERT INTO schema_migrations VALUES (?, ?)", version, dirty)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func golangMigrateQueen(db *sql.DB) *queen.Queen {
+	q := queen.New(sqlite.New(db))
+	q.MustAdd(queen.M{Version: "000001_create_users", Name: "create_users", UpSQL: "CREATE TABLE users (id INTEGER)"})
+	q.MustAdd(queen.M{Version: "000002_create_posts", Name: "create_posts", UpSQL: "CREATE TABLE posts (id INTEGER)"})
+	q.MustAdd(queen.M{Version: "000003_create_tags", Name: "create_tags", UpSQL: "CREATE TABLE tags (id INTEGER)"})
+	return q
+}
+
+func TestImportGolangMigrate(t *testing.T) {
+	ctx := context.Background()
+	db := openGolangMigrate(t, 2, false)
+	q := golangMigrateQueen(db)
+
+	imported, err := compat.ImportGolangMigrate(ctx, db, q)
+	if err != nil {
+		t.Fatalf("ImportGolangMigrate failed: %v", err)
+	}
+	if !slices.Equal(imported, []string{"000001_create_users", "000002_create_posts"}) {
+		t.Errorf("Imported %v; want 000001 and 000002", imported)
+	}
+
+	// Imported in one batch
+	applied, err := q.Applied(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(applied) != 2 || applied[0].Batch != 1 || applied[1].Batch != 1 {
+		t.Errorf("Applied = %+v; want 2 migrations in batch 1", applied)
+	}
+
+	// Only the migration golang-migrate never ran is left to apply
+	pending, err := q.Pending(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 1 || pending[0].Version != "000003_create_tags" {
+		t.Errorf("Pending = %v; want 000003", pending)
+	}
+
+	// Importing again is a no-op
+	if imported, err := compat.ImportGolangMigrate(ctx, db, q); err != nil || len(imported) != 0 {
+		t.Errorf("Second import = %v, %v; want nothing", imported, err)
+	}
+}
+
+func TestImportGolangMigrate_Refused(t *testing.T) {
+	ctx := context.Background()
+
+	db := openGolangMigrate(t, 2, true)
+	if _, err := compat.ImportGolangMigrate(ctx, db, golangMigrateQueen(db)); !errors.Is(err, queen.ErrDirty) {
+		t.Errorf("Import of a dirty state = %v; want ErrDirty", err)
+	}
+
+	db = openGolangMigrate(t, 7, false)
+	q := golangMigrateQueen(db)
+	if _, err := compat.ImportGolangMigrate(ctx, db, q); !errors.Is(err, queen.ErrMigrationNotFound) {
+		t.Errorf("Import of an unregistered version = %v; want ErrMigrationNotFound", err)
+	}
+	if applied, _ := q.Applied(ctx); len(applied) != 0 {
+		t.Errorf("Applied %d migrations after a refused import", len(applied))
+	}
+}
@@ -0,0 +1,121 @@
+package compat
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/honeynil/queen"
+)
+
+// GooseTable is the table goose records its state in by default.
+const GooseTable = "goose_db_version"
+
+// GooseOptions configures ImportGoose.
+type GooseOptions struct {
+	// Table is goose's table, as set with goose.SetTableName. It is used
+	// in the query as is, so it may be qualified with a schema.
+	// Default: GooseTable
+	Table string
+
+	// Map returns the registered version of a goose version id that
+	// doesn't match one by its leading number, e.g. for migrations
+	// renumbered when moving them over. Returning ok with an empty version
+	// skips the id, for goose migrations Queen won't take over.
+	// Default: nil (unmatched ids fail the import)
+	Map func(versionID int64) (version string, ok bool)
+}
+
+// ImportGoose seeds the tracking table of q from the state goose left in
+// db, so a goose-based codebase can adopt Queen without replaying its
+// migrations. Every migration goose applied, and didn't roll back since,
+// is marked applied with Queen.Force, without running any SQL.
+//
+// Goose version ids match registered versions by their leading number, so
+// "20240102150405_add_index" matches id 20240102150405, or through
+// opts.Map. Nothing is imported if an applied id matches no registered
+// version.
+//
+// Returns the versions marked applied, in order.
+func ImportGoose(ctx context.Context, db *sql.DB, q *queen.Queen, opts *GooseOptions) ([]string, error) {
+	if opts == nil {
+		opts = &GooseOptions{}
+	}
+	table := opts.Table
+	if table == "" {
+		table = GooseTable
+	}
+
+	// Each row records an apply or a rollback; the latest one per id wins
+	rows, err := db.QueryContext(ctx, "SELECT version_id, is_applied FROM "+table+" ORDER BY id")
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", table, err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	applied := make(map[int64]bool)
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		var isApplied bool
+		if err := rows.Scan(&id, &isApplied); err != nil {
+			return nil, fmt.Errorf("read %s: %w", table, err)
+		}
+		if _, ok := applied[id]; !ok {
+			ids = append(ids, id)
+		}
+		applied[id] = isApplied
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("read %s: %w", table, err)
+	}
+
+	statuses, err := q.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+	numbers, err := versionNumbers(statuses)
+	if err != nil {
+		return nil, err
+	}
+	byNumber := make(map[int64]string, len(numbers))
+	for version, n := range numbers {
+		byNumber[n] = version
+	}
+	registered := make(map[string]bool, len(statuses))
+	for _, s := range statuses {
+		registered[s.Version] = s.Status != queen.StatusUnknown
+	}
+
+	imported := make(map[string]bool)
+	for _, id := range ids {
+		// Goose's initial row records version 0, which isn't a migration
+		if id == 0 || !applied[id] {
+			continue
+		}
+
+		version, ok := byNumber[id]
+		if !ok && opts.Map != nil {
+			version, ok = opts.Map(id)
+			if ok && version == "" {
+				continue
+			}
+		}
+		if !ok {
+			return nil, fmt.Errorf("%w: goose version %d", queen.ErrMigrationNotFound, id)
+		}
+		if !registered[version] {
+			return nil, fmt.Errorf("%w: %s, mapped from goose version %d", queen.ErrMigrationNotFound, version, id)
+		}
+		imported[version] = true
+	}
+
+	var toForce []string
+	for _, s := range statuses {
+		if imported[s.Version] && s.Status == queen.StatusPending {
+			toForce = append(toForce, s.Version)
+		}
+	}
+
+	return force(ctx, q, toForce)
+}
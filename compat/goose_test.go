@@ -0,0 +1,104 @@
+//go:build cgo
+
+package compat_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/honeynil/queen"
+	"github.com/honeynil/queen/compat"
+	"github.com/honeynil/queen/drivers/sqlite"
+)
+
+// openGoose opens a database goose left with rows of version id and
+// is_applied, in order.
+func openGoose(t *testing.T, rows ...any) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = db.Close() })
+
+	_, err = db.Exec(`CREATE TABLE goose_db_version (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		version_id INTEGER NOT NULL,
+		is_applied INTEGER NOT NULL,
+		tstamp TIMESTAMP DEFAULT (datetime('now'))
+	)`)
+	for i := 0; err == nil && i < len(rows); i += 2 {
+		_, err = db.Exec("INSERT INTO goose_db_version (version_id, is_applied) VALUES (?, ?)", rows[i], rows[i+1])
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func gooseQueen(db *sql.DB) *queen.Queen {
+	q := queen.New(sqlite.New(db))
+	q.MustAdd(queen.M{Version: "20240101000000_create_users", Name: "create_users", UpSQL: "CREATE TABLE users (id INTEGER)"})
+	q.MustAdd(queen.M{Version: "20240102000000_create_posts", Name: "create_posts", UpSQL: "CREATE TABLE posts (id INTEGER)"})
+	q.MustAdd(queen.M{Version: "20240103000000_create_tags", Name: "create_tags", UpSQL: "CREATE TABLE tags (id INTEGER)"})
+	q.MustAdd(queen.M{Version: "20240104000000_create_likes", Name: "create_likes", UpSQL: "CREATE TABLE likes (id INTEGER)"})
+	return q
+}
+
+func TestImportGoose(t *testing.T) {
+	ctx := context.Background()
+	// 20240103 was applied and rolled back; 7 is a goose migration
+	// renumbered when moving it to Queen
+	db := openGoose(t, 0, true, 20240101000000, true, 7, true, 20240103000000, true, 20240103000000, false)
+	q := gooseQueen(db)
+
+	opts := &compat.GooseOptions{Map: func(id int64) (string, bool) {
+		return "20240102000000_create_posts", id == 7
+	}}
+	imported, err := compat.ImportGoose(ctx, db, q, opts)
+	if err != nil {
+		t.Fatalf("ImportGoose failed: %v", err)
+	}
+	if !slices.Equal(imported, []string{"20240101000000_create_users", "20240102000000_create_posts"}) {
+		t.Errorf("Imported %v; want 20240101 and 20240102", imported)
+	}
+
+	pending, err := q.Pending(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 2 || pending[0].Version != "20240103000000_create_tags" {
+		t.Errorf("Pending = %v; want 20240103 and 20240104", pending)
+	}
+
+	// Importing again is a no-op
+	if imported, err := compat.ImportGoose(ctx, db, q, opts); err != nil || len(imported) != 0 {
+		t.Errorf("Second import = %v, %v; want nothing", imported, err)
+	}
+}
+
+func TestImportGoose_Unmatched(t *testing.T) {
+	ctx := context.Background()
+
+	db := openGoose(t, 0, true, 20240101000000, true, 7, true)
+	q := gooseQueen(db)
+	if _, err := compat.ImportGoose(ctx, db, q, nil); !errors.Is(err, queen.ErrMigrationNotFound) {
+		t.Errorf("Import of an unmatched id = %v; want ErrMigrationNotFound", err)
+	}
+	if applied, _ := q.Applied(ctx); len(applied) != 0 {
+		t.Errorf("Applied %d migrations after a refused import", len(applied))
+	}
+
+	// Mapping the id to no version skips it
+	skip := &compat.GooseOptions{Map: func(int64) (string, bool) { return "", true }}
+	if imported, err := compat.ImportGoose(ctx, db, q, skip); err != nil || len(imported) != 1 {
+		t.Errorf("Import skipping id 7 = %v, %v; want 20240101", imported, err)
+	}
+}
@@ -0,0 +1,287 @@
+// Package config reads queen.yaml and queen.toml files, which hold the
+// settings of the queen command per environment, so running it against an
+// environment is a single command:
+//
+//	# queen.yaml
+//	driver: postgres
+//	dsn: ${DATABASE_URL}
+//	dir: db/migrations
+//	lock_timeout: 10m
+//
+//	environments:
+//	  staging:
+//	    dsn: ${STAGING_DATABASE_URL}
+//	  production:
+//	    dsn: ${PRODUCTION_DATABASE_URL}
+//	    lock_timeout: 30m
+//
+// The same file as TOML:
+//
+//	driver = "postgres"
+//	dsn = "${DATABASE_URL}"
+//	dir = "db/migrations"
+//	lock_timeout = "10m"
+//
+//	[environments.production]
+//	dsn = "${PRODUCTION_DATABASE_URL}"
+//	lock_timeout = "30m"
+//
+// Values may refer to environment variables as ${NAME}, or ${NAME:-default}
+// to fall back on a default when NAME is unset or empty. Only the TOML
+// needed for such files is supported: string values, comments and
+// [environments.<name>] tables.
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/honeynil/queen"
+)
+
+// FileNames are the names Find looks for, in order.
+var FileNames = []string{"queen.yaml", "queen.yml", "queen.toml"}
+
+// ErrInvalidConfig is returned for a configuration file that can't be
+// parsed, refers to an unset environment variable, or has no settings for
+// the requested environment.
+var ErrInvalidConfig = errors.New("invalid queen configuration")
+
+// Settings are the settings of an environment. Empty fields are unset.
+type Settings struct {
+	// Driver is the database: "postgres", "mysql" or "sqlite".
+	Driver string `yaml:"driver"`
+
+	// DSN is the data source name of the database.
+	DSN string `yaml:"dsn"`
+
+	// Table is the name of the tracking table, see queen.Config.TableName.
+	Table string `yaml:"table"`
+
+	// LockTimeout is a duration such as "10m", see queen.Config.LockTimeout.
+	LockTimeout string `yaml:"lock_timeout"`
+
+	// Dir is the directory of the migration files, relative to the
+	// working directory.
+	Dir string `yaml:"dir"`
+}
+
+// File is the content of a configuration file: the default settings and
+// those of each environment, which override them.
+type File struct {
+	Settings `yaml:",inline"`
+
+	Environments map[string]Settings `yaml:"environments"`
+}
+
+// Find returns the path of the first of FileNames found in dir, or "" if
+// there is none.
+func Find(dir string) string {
+	for _, name := range FileNames {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// Load reads the configuration file at path, as TOML if its name ends in
+// .toml and as YAML otherwise.
+func Load(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var f *File
+	if strings.HasSuffix(path, ".toml") {
+		f, err = parseTOML(data)
+	} else {
+		f, err = parseYAML(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return f, nil
+}
+
+// Resolve returns the settings of env: the default settings overridden by
+// the non-empty settings of env, with environment variables interpolated.
+// An empty env returns the default settings. Returns ErrInvalidConfig if
+// env has no settings or a variable is unset without a default.
+func (f *File) Resolve(env string) (Settings, error) {
+	s := f.Settings
+	if env != "" {
+		override, ok := f.Environments[env]
+		if !ok {
+			return Settings{}, fmt.Errorf("%w: no settings for environment %q", ErrInvalidConfig, env)
+		}
+		for _, field := range []struct {
+			dst *string
+			src string
+		}{
+			{&s.Driver, override.Driver},
+			{&s.DSN, override.DSN},
+			{&s.Table, override.Table},
+			{&s.LockTimeout, override.LockTimeout},
+			{&s.Dir, override.Dir},
+		} {
+			if field.src != "" {
+				*field.dst = field.src
+			}
+		}
+	}
+
+	for _, field := range []*string{&s.Driver, &s.DSN, &s.Table, &s.LockTimeout, &s.Dir} {
+		value, err := interpolate(*field)
+		if err != nil {
+			return Settings{}, err
+		}
+		*field = value
+	}
+
+	if s.LockTimeout != "" {
+		if _, err := time.ParseDuration(s.LockTimeout); err != nil {
+			return Settings{}, fmt.Errorf("%w: lock_timeout: %w", ErrInvalidConfig, err)
+		}
+	}
+
+	return s, nil
+}
+
+// Apply sets the table name and lock timeout of s on config.
+func (s Settings) Apply(config *queen.Config) {
+	if s.Table != "" {
+		config.TableName = s.Table
+	}
+	if timeout, err := time.ParseDuration(s.LockTimeout); err == nil {
+		config.LockTimeout = timeout
+	}
+}
+
+// variable matches ${NAME} and ${NAME:-default}.
+var variable = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// interpolate replaces the variables of value with their values.
+func interpolate(value string) (string, error) {
+	var err error
+	result := variable.ReplaceAllStringFunc(value, func(ref string) string {
+		m := variable.FindStringSubmatch(ref)
+		if v := os.Getenv(m[1]); v != "" {
+			return v
+		}
+		if m[2] != "" {
+			return m[3]
+		}
+		if err == nil {
+			err = fmt.Errorf("%w: environment variable %s is not set", ErrInvalidConfig, m[1])
+		}
+		return ""
+	})
+	return result, err
+}
+
+// parseYAML parses a YAML configuration file. Unknown keys are an error.
+func parseYAML(data []byte) (*File, error) {
+	var f File
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&f); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidConfig, err)
+	}
+	return &f, nil
+}
+
+// parseTOML parses the TOML subset of configuration files: key = "value"
+// lines, at the top level or in [environments.<name>] tables. Unknown keys
+// are an error.
+func parseTOML(data []byte) (*File, error) {
+	f := &File{}
+	envs := make(map[string]*Settings)
+	current := &f.Settings
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") {
+			table, ok := strings.CutSuffix(strings.TrimPrefix(line, "["), "]")
+			env, isEnv := strings.CutPrefix(strings.TrimSpace(table), "environments.")
+			env = strings.Trim(env, `"`)
+			if !ok || !isEnv || env == "" {
+				return nil, fmt.Errorf("%w: line %d: expected [environments.<name>]", ErrInvalidConfig, n)
+			}
+			if envs[env] == nil {
+				envs[env] = &Settings{}
+			}
+			current = envs[env]
+			continue
+		}
+
+		key, raw, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("%w: line %d: expected key = \"value\"", ErrInvalidConfig, n)
+		}
+		value, err := strconv.Unquote(stripComment(strings.TrimSpace(raw)))
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: value must be a quoted string", ErrInvalidConfig, n)
+		}
+
+		if err := current.set(strings.TrimSpace(key), value); err != nil {
+			return nil, fmt.Errorf("%w: line %d: %w", ErrInvalidConfig, n, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(envs) > 0 {
+		f.Environments = make(map[string]Settings, len(envs))
+		for env, s := range envs {
+			f.Environments[env] = *s
+		}
+	}
+	return f, nil
+}
+
+// stripComment removes a trailing # comment after a quoted value.
+func stripComment(raw string) string {
+	if i := strings.LastIndex(raw, `"`); i >= 0 {
+		return raw[:i+1]
+	}
+	return raw
+}
+
+// set sets the setting named key, as in YAML.
+func (s *Settings) set(key, value string) error {
+	switch key {
+	case "driver":
+		s.Driver = value
+	case "dsn":
+		s.DSN = value
+	case "table":
+		s.Table = value
+	case "lock_timeout":
+		s.LockTimeout = value
+	case "dir":
+		s.Dir = value
+	default:
+		return fmt.Errorf("unknown key %q", key)
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package config_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/honeynil/queen"
+	"github.com/honeynil/queen/config"
+)
+
+const yamlFile = `
+driver: postgres
+dsn: ${APP_DSN}
+dir: db/migrations
+lock_timeout: 10m
+
+environments:
+  staging:
+    dsn: ${STAGING_DSN:-postgres://staging/app}
+  production:
+    dsn: ${PRODUCTION_DSN}
+    table: schema_migrations
+    lock_timeout: 30m
+`
+
+const tomlFile = `
+driver = "postgres"
+dsn = "${APP_DSN}"
+dir = "db/migrations" # relative to the working directory
+lock_timeout = "10m"
+
+[environments.staging]
+dsn = "${STAGING_DSN:-postgres://staging/app}"
+
+[environments.production]
+dsn = "${PRODUCTION_DSN}"
+table = "schema_migrations"
+lock_timeout = "30m"
+`
+
+func TestLoad(t *testing.T) {
+	t.Setenv("APP_DSN", "postgres://localhost/app")
+	t.Setenv("PRODUCTION_DSN", "postgres://prod/app")
+
+	for name, content := range map[string]string{"queen.yaml": yamlFile, "queen.toml": tomlFile} {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			path := config.Find(dir)
+			if filepath.Base(path) != name {
+				t.Fatalf("Find() = %q; want %s", path, name)
+			}
+			f, err := config.Load(path)
+			if err != nil {
+				t.Fatalf("Load() failed: %v", err)
+			}
+
+			s, err := f.Resolve("")
+			if err != nil {
+				t.Fatalf("Resolve() failed: %v", err)
+			}
+			want := config.Settings{Driver: "postgres", DSN: "postgres://localhost/app", Dir: "db/migrations", LockTimeout: "10m"}
+			if s != want {
+				t.Errorf("Resolve(\"\") = %+v; want %+v", s, want)
+			}
+
+			s, err = f.Resolve("staging")
+			if err != nil || s.DSN != "postgres://staging/app" || s.LockTimeout != "10m" {
+				t.Errorf("Resolve(staging) = %+v, %v; want the default DSN and inherited lock timeout", s, err)
+			}
+
+			s, err = f.Resolve("production")
+			if err != nil {
+				t.Fatalf("Resolve(production) failed: %v", err)
+			}
+			c := queen.DefaultConfig()
+			s.Apply(c)
+			if s.DSN != "postgres://prod/app" || c.TableName != "schema_migrations" || c.LockTimeout != 30*time.Minute {
+				t.Errorf("Resolve(production) = %+v, config %+v", s, c)
+			}
+
+			if _, err := f.Resolve("qa"); !errors.Is(err, config.ErrInvalidConfig) {
+				t.Errorf("Resolve(qa) = %v; want ErrInvalidConfig", err)
+			}
+		})
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	tests := map[string]string{
+		"queen.yaml": "dsn: x\nlock_timout: 5m\n",
+		"queen.toml": "[database]\ndsn = \"x\"\n",
+	}
+	for name, content := range tests {
+		path := filepath.Join(t.TempDir(), name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := config.Load(path); !errors.Is(err, config.ErrInvalidConfig) {
+			t.Errorf("Load(%s) = %v; want ErrInvalidConfig", name, err)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "queen.yaml")
+	if err := os.WriteFile(path, []byte("dsn: ${QUEEN_TEST_UNSET_DSN}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if _, err := f.Resolve(""); !errors.Is(err, config.ErrInvalidConfig) {
+		t.Errorf("Resolve() = %v; want ErrInvalidConfig for an unset variable", err)
+	}
+
+	if config.Find(t.TempDir()) != "" {
+		t.Error("Find() found a file in an empty directory")
+	}
+}
@@ -0,0 +1,137 @@
+package queen
+
+import (
+	"context"
+	"slices"
+	"time"
+)
+
+// RunProgress is a snapshot of the run in progress, as returned by
+// CurrentRun.
+type RunProgress struct {
+	// ID and Operation identify the run, as in RunReport.
+	ID        string
+	Operation Operation
+
+	// StartedAt is when the run started, and Elapsed how long ago.
+	StartedAt time.Time
+	Elapsed   time.Duration
+
+	// Plan lists the versions the run applies or rolls back, in order.
+	Plan []string
+
+	// Completed lists the versions of Plan done so far, in order.
+	Completed []string
+
+	// Current is the version executing, "" between migrations, and
+	// CurrentName its name. CurrentElapsed is how long it has been
+	// running.
+	Current        string
+	CurrentName    string
+	CurrentElapsed time.Duration
+
+	// ETA is the estimated time until the run completes, from the
+	// durations of earlier runs (see Config.EstimateFrom). It is 0 if a
+	// migration left has nothing to estimate from.
+	ETA time.Duration
+}
+
+// liveRun is the state of the run in progress behind CurrentRun.
+type liveRun struct {
+	report    *RunReport
+	plan      []*Migration
+	estimates map[string]time.Duration
+
+	completed    []string
+	current      *Migration
+	currentStart time.Time
+}
+
+// CurrentRun returns a snapshot of the Up, Down, Reset or RollbackBatch
+// run in progress, or nil if none is. It is safe to call from other
+// goroutines while the run executes, e.g. to poll from an admin UI.
+func (q *Queen) CurrentRun() *RunProgress {
+	q.liveMu.Lock()
+	defer q.liveMu.Unlock()
+
+	live := q.live
+	if live == nil {
+		return nil
+	}
+
+	now := time.Now()
+	p := &RunProgress{
+		ID:        live.report.ID,
+		Operation: live.report.Operation,
+		StartedAt: live.report.StartedAt,
+		Elapsed:   now.Sub(live.report.StartedAt),
+		Plan:      slices.Clone(live.report.Plan),
+		Completed: slices.Clone(live.completed),
+	}
+	if live.current != nil {
+		p.Current = live.current.Version
+		p.CurrentName = live.current.Name
+		p.CurrentElapsed = now.Sub(live.currentStart)
+	}
+
+	for _, m := range live.plan {
+		if slices.Contains(live.completed, m.Version) {
+			continue
+		}
+
+		estimate, ok := live.estimates[m.Version]
+		if !ok {
+			p.ETA = 0
+			break
+		}
+		if m == live.current {
+			estimate = max(estimate-p.CurrentElapsed, 0)
+		}
+		p.ETA += estimate
+	}
+
+	return p
+}
+
+// startLive makes report the run CurrentRun reports on.
+func (q *Queen) startLive(ctx context.Context, report *RunReport, plan []*Migration) {
+	// Estimates are best effort: without them the ETA is unknown
+	estimates, _ := q.estimates(ctx, plan, report.Operation != OperationUp)
+
+	q.liveMu.Lock()
+	defer q.liveMu.Unlock()
+	q.live = &liveRun{
+		report:    report,
+		plan:      plan,
+		estimates: estimates,
+	}
+}
+
+// stopLive clears the run CurrentRun reports on.
+func (q *Queen) stopLive() {
+	q.liveMu.Lock()
+	defer q.liveMu.Unlock()
+	q.live = nil
+}
+
+// trackLive updates the run in progress with an event.
+func (q *Queen) trackLive(e Event) {
+	q.liveMu.Lock()
+	defer q.liveMu.Unlock()
+
+	live := q.live
+	if live == nil {
+		return
+	}
+
+	switch e.Kind {
+	case EventBeforeUp, EventBeforeDown:
+		live.current = e.Migration
+		live.currentStart = time.Now()
+	case EventAfterUp, EventAfterDown:
+		live.completed = append(live.completed, e.Migration.Version)
+		live.current = nil
+	case EventFailed:
+		live.current = nil
+	}
+}
@@ -0,0 +1,95 @@
+package queen_test
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/honeynil/queen"
+	"github.com/honeynil/queen/drivers/mock"
+)
+
+func TestCurrentRun(t *testing.T) {
+	ctx := context.Background()
+	q := queen.New(mock.New())
+
+	var snapshots []*queen.RunProgress
+	q.MustAdd(queen.M{Version: "001", Name: "first", DownFunc: noop,
+		UpFunc: func(ctx context.Context, tx *sql.Tx) error {
+			snapshots = append(snapshots, q.CurrentRun())
+			return nil
+		},
+	})
+	q.MustAdd(queen.M{Version: "002", Name: "slow", DownFunc: noop,
+		UpFunc: func(ctx context.Context, tx *sql.Tx) error {
+			snapshots = append(snapshots, q.CurrentRun())
+			time.Sleep(5 * time.Millisecond)
+			return nil
+		},
+	})
+
+	if q.CurrentRun() != nil {
+		t.Fatal("Expected no run in progress before Up")
+	}
+
+	var etas []time.Duration
+	q.Hooks().MustRegister(queen.Hook{Name: "eta", Func: func(ctx context.Context, e queen.Event) error {
+		if e.Kind == queen.EventBeforeUp && e.Migration.Version == "001" {
+			etas = append(etas, e.ETA)
+		}
+		return nil
+	}})
+
+	// Poll from another goroutine while the runs execute
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Go(func() {
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				_ = q.CurrentRun()
+			}
+		}
+	})
+
+	for _, run := range []func(context.Context) error{q.Up, q.Reset, q.Up} {
+		if err := run(ctx); err != nil {
+			t.Fatalf("run failed: %v", err)
+		}
+	}
+	close(done)
+	wg.Wait()
+
+	if q.CurrentRun() != nil {
+		t.Error("Expected no run in progress after Up")
+	}
+	if len(snapshots) != 4 {
+		t.Fatalf("got %d snapshots; want 4", len(snapshots))
+	}
+
+	first := snapshots[0]
+	if first == nil || first.Operation != queen.OperationUp || first.Current != "001" || first.CurrentName != "first" ||
+		len(first.Completed) != 0 || !slices.Equal(first.Plan, []string{"001", "002"}) {
+		t.Fatalf("first snapshot = %+v; want 001 running", first)
+	}
+	if first.ETA != 0 {
+		t.Errorf("ETA = %s; want unknown without history", first.ETA)
+	}
+
+	if s := snapshots[1]; s.Current != "002" || !slices.Equal(s.Completed, []string{"001"}) {
+		t.Errorf("second snapshot = %+v; want 002 running after 001", s)
+	}
+
+	// The second Up estimates from the first
+	if s := snapshots[2]; s.Current != "001" || s.ETA < 5*time.Millisecond {
+		t.Errorf("snapshot of the second Up = %+v; want an ETA covering 002", s)
+	}
+	if len(etas) != 2 || etas[0] != 0 || etas[1] < 5*time.Millisecond {
+		t.Errorf("Event.ETA = %v; want unknown, then covering 002", etas)
+	}
+}
@@ -0,0 +1,131 @@
+package queen
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/honeynil/queen/internal/checksum"
+	"github.com/honeynil/queen/internal/split"
+)
+
+// destructiveKeywords are statements that destroy data wherever they occur.
+var destructiveKeywords = []string{
+	"DROP TABLE",
+	"DROP DATABASE",
+	"DROP SCHEMA",
+	"TRUNCATE",
+	"DROP COLUMN",
+}
+
+// deleteAll matches DELETE statements, which destroy data without a WHERE.
+var deleteAll = regexp.MustCompile(`(?i)^DELETE\s+FROM\b`)
+
+// where matches a WHERE clause.
+var where = regexp.MustCompile(`(?i)\bWHERE\b`)
+
+// destructiveRules are the organization's own rules from Config, added to
+// the built-in detection.
+type destructiveRules struct {
+	keywords []string
+	patterns []*regexp.Regexp
+}
+
+// isDestructiveSQL reports whether a statement of query destroys data, see
+// destructiveStatements.
+func isDestructiveSQL(query string, rules destructiveRules) bool {
+	return len(destructiveStatements(query, rules)) > 0
+}
+
+// destructiveStatements returns the statements of query that destroy data:
+// they contain a built-in or configured keyword, match a configured
+// pattern, or delete from a table without a WHERE clause. Comments are
+// ignored and keywords match regardless of case and spacing. Statements
+// are returned with comments removed and whitespace collapsed.
+func destructiveStatements(query string, rules destructiveRules) []string {
+	if query == "" {
+		return nil
+	}
+
+	query = checksum.Normalize(query, checksum.StripComments|checksum.CollapseWhitespace)
+
+	var statements []string
+	for _, stmt := range split.Split(query, split.Postgres) {
+		if isDestructiveStatement(stmt, rules) {
+			statements = append(statements, stmt)
+		}
+	}
+
+	return statements
+}
+
+// isDestructiveStatement reports whether the normalized stmt destroys data.
+func isDestructiveStatement(stmt string, rules destructiveRules) bool {
+	upper := strings.ToUpper(stmt)
+
+	for _, keyword := range destructiveKeywords {
+		if strings.Contains(upper, keyword) {
+			return true
+		}
+	}
+	for _, keyword := range rules.keywords {
+		if strings.Contains(upper, strings.ToUpper(keyword)) {
+			return true
+		}
+	}
+	for _, pattern := range rules.patterns {
+		if pattern.MatchString(stmt) {
+			return true
+		}
+	}
+
+	return deleteAll.MatchString(stmt) && !where.MatchString(stmt)
+}
+
+// tableNamePattern matches a possibly quoted and qualified table name.
+const tableNamePattern = "(?:\"[^\"]+\"|`[^`]+`|[\\w$]+)(?:\\.(?:\"[^\"]+\"|`[^`]+`|[\\w$]+))*"
+
+// destructiveTablePatterns capture the tables of the built-in destructive
+// statements, comma-separated for DROP TABLE and TRUNCATE.
+var destructiveTablePatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)^DROP TABLE (?:IF EXISTS )?(` + tableNamePattern + `(?: ?, ?` + tableNamePattern + `)*)`),
+	regexp.MustCompile(`(?i)^TRUNCATE (?:TABLE )?(?:ONLY )?(` + tableNamePattern + `(?: ?, ?` + tableNamePattern + `)*)`),
+	regexp.MustCompile(`(?i)^ALTER TABLE (?:IF EXISTS )?(?:ONLY )?(` + tableNamePattern + `) .*\bDROP COLUMN\b`),
+}
+
+// deleteFrom captures the table of a DELETE statement.
+var deleteFrom = regexp.MustCompile(`(?i)^DELETE FROM (` + tableNamePattern + `)`)
+
+// destructiveTables returns the tables affected by the built-in destructive
+// statements of query, as written, in order and without duplicates.
+func destructiveTables(query string) []string {
+	if query == "" {
+		return nil
+	}
+
+	query = checksum.Normalize(query, checksum.StripComments|checksum.CollapseWhitespace)
+
+	var tables []string
+	for _, stmt := range split.Split(query, split.Postgres) {
+		var match []string
+		for _, pattern := range destructiveTablePatterns {
+			if match = pattern.FindStringSubmatch(stmt); match != nil {
+				break
+			}
+		}
+		if match == nil && !where.MatchString(stmt) {
+			match = deleteFrom.FindStringSubmatch(stmt)
+		}
+		if match == nil {
+			continue
+		}
+
+		for _, table := range strings.Split(match[1], ",") {
+			if table = strings.TrimSpace(table); !slices.Contains(tables, table) {
+				tables = append(tables, table)
+			}
+		}
+	}
+
+	return tables
+}
@@ -0,0 +1,129 @@
+package queen
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	naturalsort "github.com/honeynil/queen/internal/sort"
+)
+
+// DDLEvent is a schema change made outside Queen, e.g. a hotfix applied by
+// hand, as captured by a DDLAuditor.
+type DDLEvent struct {
+	// ID orders events. Acknowledging an ID acknowledges every event up
+	// to it.
+	ID int64
+
+	// OccurredAt is when the change was made.
+	OccurredAt time.Time
+
+	// Command is the command tag, e.g. "ALTER TABLE".
+	Command string
+
+	// ObjectType and Object identify the changed object, e.g. "table"
+	// and "public.users".
+	ObjectType string
+	Object     string
+
+	// Statement is the statement that made the change.
+	Statement string
+
+	// User is the database user who made the change.
+	User string
+}
+
+// DDLAuditor is implemented by drivers that capture schema changes made
+// outside migrations, so they show up in Drift until someone reconciles
+// them, e.g. by writing the migration the hotfix should have been.
+type DDLAuditor interface {
+	// GetDDLEvents returns the events not acknowledged yet, oldest first.
+	GetDDLEvents(ctx context.Context) ([]DDLEvent, error)
+
+	// AcknowledgeDDLEvents marks every event with an ID up to id as
+	// reconciled.
+	AcknowledgeDDLEvents(ctx context.Context, id int64) error
+}
+
+// DriftReport lists the ways a database differs from what its registered
+// migrations say it should be, as returned by Drift. Versions are in
+// natural sort order.
+type DriftReport struct {
+	// UnknownApplied lists applied versions that aren't registered.
+	UnknownApplied []string
+
+	// ChecksumMismatch lists applied versions whose registered migration
+	// changed since it was applied.
+	ChecksumMismatch []string
+
+	// Dirty lists versions left dirty by failed migrations.
+	Dirty []string
+
+	// ManualDDL lists schema changes made outside migrations and not
+	// acknowledged yet, if the driver implements DDLAuditor.
+	ManualDDL []DDLEvent
+}
+
+// Clean reports whether no drift was found.
+func (r *DriftReport) Clean() bool {
+	return len(r.UnknownApplied) == 0 && len(r.ChecksumMismatch) == 0 &&
+		len(r.Dirty) == 0 && len(r.ManualDDL) == 0
+}
+
+// Drift reports how the database drifted from the registered migrations.
+// Unlike Up it only reports, whatever the configured policies.
+func (q *Queen) Drift(ctx context.Context) (*DriftReport, error) {
+	if q.driver == nil {
+		return nil, ErrNoDriver
+	}
+
+	if err := q.init(ctx); err != nil {
+		return nil, err
+	}
+	if err := q.loadApplied(ctx); err != nil {
+		return nil, err
+	}
+
+	r := &DriftReport{UnknownApplied: q.unknownApplied()}
+	for _, m := range q.migrations {
+		if applied, ok := q.applied[m.Version]; ok && !m.checksumMatches(applied.Checksum) {
+			r.ChecksumMismatch = append(r.ChecksumMismatch, m.Version)
+		}
+	}
+	for version, applied := range q.applied {
+		if applied.Dirty {
+			r.Dirty = append(r.Dirty, version)
+		}
+	}
+	sort.Slice(r.Dirty, func(i, j int) bool {
+		return naturalsort.Compare(r.Dirty[i], r.Dirty[j]) < 0
+	})
+
+	if auditor, ok := optional[DDLAuditor](q.driver, FeatureDDLAudit); ok {
+		events, err := auditor.GetDDLEvents(ctx)
+		if err != nil {
+			return nil, err
+		}
+		r.ManualDDL = events
+	}
+
+	return r, nil
+}
+
+// AcknowledgeDDL marks the schema changes reported by Drift up to the
+// event id as reconciled, so they are no longer reported.
+//
+// Returns ErrUnsupported if the driver doesn't implement DDLAuditor.
+func (q *Queen) AcknowledgeDDL(ctx context.Context, id int64) error {
+	if q.driver == nil {
+		return ErrNoDriver
+	}
+
+	auditor, ok := optional[DDLAuditor](q.driver, FeatureDDLAudit)
+	if !ok {
+		return fmt.Errorf("%w: DDL audit", ErrUnsupported)
+	}
+
+	return auditor.AcknowledgeDDLEvents(ctx, id)
+}
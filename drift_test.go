@@ -0,0 +1,108 @@
+package queen_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/honeynil/queen"
+	"github.com/honeynil/queen/drivers/mock"
+)
+
+// auditDriver is a mock driver with a fixed DDL audit log.
+type auditDriver struct {
+	*mock.Driver
+	events []queen.DDLEvent
+}
+
+func (d *auditDriver) GetDDLEvents(ctx context.Context) ([]queen.DDLEvent, error) {
+	var pending []queen.DDLEvent
+	for _, e := range d.events {
+		if e.ID > 0 {
+			pending = append(pending, e)
+		}
+	}
+	return pending, nil
+}
+
+func (d *auditDriver) AcknowledgeDDLEvents(ctx context.Context, id int64) error {
+	for i := range d.events {
+		if d.events[i].ID <= id {
+			d.events[i].ID = 0
+		}
+	}
+	return nil
+}
+
+func TestDrift(t *testing.T) {
+	ctx := context.Background()
+	driver := &auditDriver{Driver: mock.New()}
+
+	record := func(version, sum string, dirty bool) {
+		t.Helper()
+		m := &queen.M{Version: version, Name: "migration_" + version, ManualChecksum: sum, UpFunc: noop}
+		if err := driver.RecordWithMeta(ctx, m, queen.RecordMeta{Batch: 1, Dirty: dirty}); err != nil {
+			t.Fatalf("Record failed: %v", err)
+		}
+	}
+
+	q := queen.New(driver)
+	q.MustAdd(queen.M{Version: "1", Name: "one", ManualChecksum: "v1", UpFunc: noop})
+	q.MustAdd(queen.M{Version: "2", Name: "two", ManualChecksum: "v1", UpFunc: noop})
+	q.MustAdd(queen.M{Version: "10", Name: "ten", ManualChecksum: "v1", UpFunc: noop})
+
+	record("1", "v1", false)
+	record("2", "v1", false)
+	r, err := q.Drift(ctx)
+	if err != nil {
+		t.Fatalf("Drift failed: %v", err)
+	}
+	if !r.Clean() {
+		t.Errorf("Expected no drift, got %+v", r)
+	}
+
+	record("2", "v2", false)
+	record("10", "v1", true)
+	record("3", "v1", false)
+	driver.events = []queen.DDLEvent{
+		{ID: 1, Command: "CREATE INDEX", ObjectType: "index", Object: "public.idx_users_email"},
+		{ID: 2, Command: "ALTER TABLE", ObjectType: "table", Object: "public.users"},
+	}
+
+	r, err = q.Drift(ctx)
+	if err != nil {
+		t.Fatalf("Drift failed: %v", err)
+	}
+	if r.Clean() {
+		t.Fatal("Expected drift")
+	}
+	if !slices.Equal(r.UnknownApplied, []string{"3"}) {
+		t.Errorf("UnknownApplied = %v", r.UnknownApplied)
+	}
+	if !slices.Equal(r.ChecksumMismatch, []string{"2"}) {
+		t.Errorf("ChecksumMismatch = %v", r.ChecksumMismatch)
+	}
+	if !slices.Equal(r.Dirty, []string{"10"}) {
+		t.Errorf("Dirty = %v", r.Dirty)
+	}
+	if len(r.ManualDDL) != 2 || r.ManualDDL[1].Object != "public.users" {
+		t.Errorf("ManualDDL = %+v", r.ManualDDL)
+	}
+
+	if err := q.AcknowledgeDDL(ctx, 1); err != nil {
+		t.Fatalf("AcknowledgeDDL failed: %v", err)
+	}
+	r, err = q.Drift(ctx)
+	if err != nil {
+		t.Fatalf("Drift failed: %v", err)
+	}
+	if len(r.ManualDDL) != 1 || r.ManualDDL[0].ID != 2 {
+		t.Errorf("Expected only event 2 after acknowledging 1, got %+v", r.ManualDDL)
+	}
+
+	plain := queen.New(mock.New())
+	if err := plain.AcknowledgeDDL(ctx, 1); !errors.Is(err, queen.ErrUnsupported) {
+		t.Errorf("Expected ErrUnsupported without a DDLAuditor, got %v", err)
+	}
+}
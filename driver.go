@@ -8,8 +8,8 @@ import (
 
 // Driver is the interface that database-specific drivers must implement.
 //
-// Driver abstracts database-specific migration tracking, locking, and
-// transaction management. This allows Queen to support multiple databases
+// Driver abstracts database-specific migration tracking and transaction
+// management. This allows Queen to support multiple databases
 // (PostgreSQL, MySQL, SQLite, etc.) without changing the core library.
 //
 // # Implementing a Driver
@@ -18,8 +18,10 @@ import (
 //
 //  1. Implement all Driver interface methods
 //  2. Create a migrations tracking table in Init()
-//  3. Use database-specific locking (advisory locks, named locks, etc.)
-//  4. Handle transactions properly in Exec()
+//  3. Handle transactions properly in Exec()
+//  4. Implement the optional interfaces the database supports, such as
+//     Locker with database-specific locking (advisory locks, named locks,
+//     etc.) and NoTxExecer; see Feature for the full list and fallbacks
 //
 // See drivers/postgres/postgres.go for a reference implementation.
 //
@@ -38,29 +40,16 @@ type Driver interface {
 	// The returned slice should be sorted by applied time in ascending order.
 	GetApplied(ctx context.Context) ([]Applied, error)
 
-	// Record marks a migration as applied in the database.
-	// This should be called after successfully executing a migration.
+	// Record marks a migration as applied in the database, replacing any
+	// existing record for its version. Drivers should store m.DownSQL so
+	// the migration can be rolled back after it is removed from code (see
+	// Applied.DownSQL). Run metadata is recorded by MetaRecorder.
 	Record(ctx context.Context, m *Migration) error
 
 	// Remove removes a migration record from the database.
 	// This should be called after successfully rolling back a migration.
 	Remove(ctx context.Context, version string) error
 
-	// Lock acquires an exclusive lock to prevent concurrent migrations.
-	//
-	// If the lock cannot be acquired within the specified timeout, it returns
-	// ErrLockTimeout. The lock must be held until Unlock() is called.
-	//
-	// Implementation notes:
-	// - Use database-specific locking (PostgreSQL advisory locks, MySQL named locks, etc.)
-	// - The lock should be exclusive to prevent concurrent migration runs
-	// - Consider using a unique lock identifier based on the migrations table name
-	Lock(ctx context.Context, timeout time.Duration) error
-
-	// Unlock releases the migration lock.
-	// This should be called in a defer statement after acquiring the lock.
-	Unlock(ctx context.Context) error
-
 	// Exec executes a function within a transaction.
 	// If the function returns an error, the transaction is rolled back.
 	// Otherwise, the transaction is committed.
@@ -70,6 +59,91 @@ type Driver interface {
 	Close() error
 }
 
+// ViewCreator is implemented by drivers that can create reporting views over
+// the tracking table, so dashboards can query migration state directly.
+// It is used when Config.ReportingViews is set.
+type ViewCreator interface {
+	// EnsureViews creates the reporting views, or updates them if their
+	// definitions changed. It must be idempotent.
+	EnsureViews(ctx context.Context) error
+}
+
+// StatementSplitter is implemented by drivers whose database or client
+// library accepts only one statement per Exec. Queen splits UpSQL and DownSQL
+// with it and executes the statements one at a time.
+type StatementSplitter interface {
+	// SplitStatements returns the individual statements in query.
+	SplitStatements(query string) []string
+}
+
+// PlaceholderCounter is implemented by statement splitters that can tell
+// how many bind args a statement takes, so Queen can hand each statement
+// of a split script its share of UpArgs or DownArgs.
+type PlaceholderCounter interface {
+	// CountPlaceholders returns the number of bind args query takes in the
+	// database's placeholder style, e.g. 2 for "VALUES ($1, $2)" in
+	// PostgreSQL.
+	CountPlaceholders(query string) int
+}
+
+// PlaceholderTranslator is implemented by drivers whose database doesn't
+// take ? placeholders, for migrations with TranslatePlaceholders set.
+type PlaceholderTranslator interface {
+	// TranslatePlaceholders rewrites the ? placeholders of query to the
+	// database's style, e.g. $1, $2 in PostgreSQL, and ?? to a literal ?.
+	TranslatePlaceholders(query string) string
+}
+
+// PermissionChecker is implemented by drivers that can verify the database
+// role has the privileges migrations need.
+type PermissionChecker interface {
+	// CheckPermissions verifies the role can create, alter and drop tables in
+	// the target schema and write the tracking table. A missing privilege is
+	// reported as a *PermissionError. It must leave no changes behind.
+	CheckPermissions(ctx context.Context) error
+}
+
+// Isolator is implemented by drivers that can give a test its own schema or
+// database, so tests running in parallel don't share a tracking table.
+// It is used by TestHelper.Isolated.
+type Isolator interface {
+	// Isolate creates an empty schema or database called name and returns a
+	// driver working in it, along with a function that drops it again.
+	Isolate(ctx context.Context, name string) (Driver, func(context.Context) error, error)
+}
+
+// SchemaDropper is implemented by drivers that can empty the database they
+// migrate. It is used by Queen.ResetHard.
+type SchemaDropper interface {
+	// DropSchema drops every table, view and other object in the driver's
+	// schema or database, including the tracking table, so Init starts
+	// from scratch.
+	DropSchema(ctx context.Context) error
+}
+
+// ChecksumUpdater is implemented by drivers that can rewrite the checksum
+// of an applied migration. It is used by Queen.RepairChecksums.
+type ChecksumUpdater interface {
+	// UpdateChecksum sets the stored checksum of version, leaving the rest
+	// of its record untouched. It is a no-op if the version has no record.
+	UpdateChecksum(ctx context.Context, version, checksum string) error
+}
+
+// ExtendedDriver is implemented by drivers that can store an opaque
+// metadata document with each applied migration, e.g. in a JSON or TEXT
+// column. Queen encodes Config.Metadata with Config.MetadataSerializer and
+// decodes it into Applied.Metadata, so new per-migration details don't
+// require changing Applied, RecordMeta and every driver at once.
+type ExtendedDriver interface {
+	// SetMetadata stores data with the record of version, replacing any
+	// stored before. It is a no-op if the version has no record.
+	SetMetadata(ctx context.Context, version string, data []byte) error
+
+	// GetMetadata returns the stored metadata by version. Versions
+	// without metadata may be omitted.
+	GetMetadata(ctx context.Context) (map[string][]byte, error)
+}
+
 // Applied represents a migration that has been applied to the database.
 // This is returned by Driver.GetApplied().
 type Applied struct {
@@ -84,4 +158,69 @@ type Applied struct {
 
 	// Checksum is the hash of the migration content at the time it was applied.
 	Checksum string
+
+	// Batch is the number of the Up run that applied the migration.
+	// Migrations applied before batches were tracked have batch 0.
+	Batch int
+
+	// Dirty is set while the migration is being applied or rolled back.
+	// A dirty record left behind means execution failed or was interrupted
+	// and the database may be partially migrated.
+	Dirty bool
+
+	// DownSQL is the rollback script stored when the migration was applied.
+	// Queen uses it to roll back migrations no longer registered, e.g. after
+	// deploying an older binary. Translated to the driver's placeholders
+	// for migrations with TranslatePlaceholders set. Empty for Go function
+	// migrations, migrations with DownArgs and records written before it
+	// was stored.
+	DownSQL string
+
+	// Duration is how long the migration took to execute, stored with
+	// millisecond precision. Zero while dirty and for records written before
+	// it was tracked.
+	Duration time.Duration
+
+	// AppliedBy is the operating system user that applied the migration.
+	AppliedBy string
+
+	// Hostname is the machine the migration was applied from.
+	Hostname string
+
+	// Operator is the free-form string from Config.Operator, e.g. a
+	// person's name or a ticket number.
+	Operator string
+
+	// BuildInfo identifies the binary that applied the migration, such as
+	// its module version and VCS revision. See Config.BuildInfo.
+	BuildInfo string
+
+	// Metadata holds the extension fields stored with the migration, see
+	// Config.Metadata. Nil if the driver doesn't implement ExtendedDriver
+	// or nothing was stored.
+	Metadata map[string]any
+}
+
+// RecordMeta holds run metadata passed to MetaRecorder and BatchRecorder.
+type RecordMeta struct {
+	// Batch is the number of the Up run applying the migration.
+	// All migrations applied by a single Up call share the same batch.
+	Batch int
+
+	// Dirty records the migration as in progress. See Applied.Dirty.
+	Dirty bool
+
+	// Duration is how long the migration took to execute.
+	// Zero for dirty markers.
+	Duration time.Duration
+
+	// AppliedBy, Hostname and Operator identify who ran the migration and
+	// from where. See Applied.
+	AppliedBy string
+	Hostname  string
+	Operator  string
+
+	// BuildInfo identifies the binary applying the migration.
+	// See Applied.BuildInfo.
+	BuildInfo string
 }
@@ -0,0 +1,94 @@
+// Package permcheck probes whether a database role has the privileges
+// migrations need.
+//
+// Privilege catalogs differ between databases and are hard to evaluate
+// correctly (roles, wildcards, default privileges), so the probe performs
+// the operations instead: it creates, alters and drops a scratch table, and
+// writes the tracking table inside a transaction that is rolled back.
+package permcheck
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/honeynil/queen"
+	"github.com/honeynil/queen/drivers/internal/sqlutil"
+)
+
+// probeVersion is the tracking-table row written and rolled back by Check.
+const probeVersion = "__queen_permission_probe__"
+
+// Check verifies that the role behind db can CREATE, ALTER and DROP tables
+// in schema and INSERT, UPDATE and DELETE rows in the tracking table. It
+// returns a *queen.PermissionError for the first operation that fails.
+func Check(ctx context.Context, db *sql.DB, dialect sqlutil.Dialect, schema, tracking string) error {
+	probe := dialect.QuoteQualified(schema, tracking+"_probe")
+	onSchema := "schema " + schema
+
+	steps := []struct {
+		privilege string
+		query     string
+	}{
+		{"CREATE", fmt.Sprintf("CREATE TABLE %s (id INTEGER)", probe)},
+		{"ALTER", fmt.Sprintf("ALTER TABLE %s ADD COLUMN note VARCHAR(16)", probe)},
+		{"DROP", fmt.Sprintf("DROP TABLE %s", probe)},
+	}
+
+	for i, step := range steps {
+		if _, err := db.ExecContext(ctx, step.query); err != nil {
+			// Don't leave the scratch table behind if only ALTER failed
+			if i > 0 {
+				_, _ = db.ExecContext(ctx, fmt.Sprintf("DROP TABLE %s", probe))
+			}
+			return &queen.PermissionError{Privilege: step.privilege, Object: onSchema, Err: err}
+		}
+	}
+
+	return checkTracking(ctx, db, dialect, schema, tracking)
+}
+
+// checkTracking writes a probe row to the tracking table and rolls back.
+func checkTracking(ctx context.Context, db *sql.DB, dialect sqlutil.Dialect, schema, tracking string) error {
+	table := dialect.QuoteQualified(schema, tracking)
+	p := dialect.Placeholder
+	onTable := "table " + tracking
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	steps := []struct {
+		privilege string
+		query     string
+		args      []any
+	}{
+		{
+			"INSERT",
+			fmt.Sprintf("INSERT INTO %s (version, name, checksum) VALUES (%s, %s, %s)", table, p(1), p(2), p(3)),
+			[]any{probeVersion, "permission_probe", "probe"},
+		},
+		{
+			"UPDATE",
+			fmt.Sprintf("UPDATE %s SET name = %s WHERE version = %s", table, p(1), p(2)),
+			[]any{"permission_probe", probeVersion},
+		},
+		{
+			"DELETE",
+			fmt.Sprintf("DELETE FROM %s WHERE version = %s", table, p(1)),
+			[]any{probeVersion},
+		},
+	}
+
+	for _, step := range steps {
+		if _, err := tx.ExecContext(ctx, step.query, step.args...); err != nil {
+			return &queen.PermissionError{Privilege: step.privilege, Object: onTable, Err: err}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,208 @@
+// Package sqlutil builds the SQL the bundled drivers share: quoting,
+// placeholders and the statements over Queen's tracking and history
+// tables, per dialect, so the drivers don't each carry their own subtly
+// different copy.
+package sqlutil
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/honeynil/queen"
+	"github.com/honeynil/queen/internal/split"
+)
+
+// Dialect is a SQL dialect of a bundled driver.
+type Dialect int
+
+// Dialects of the bundled drivers.
+const (
+	PostgreSQL Dialect = iota + 1
+	MySQL
+	SQLite
+)
+
+// RecordColumns are the tracking table columns written when recording a
+// migration, in the order drivers pass their values.
+var RecordColumns = []string{
+	"version", "name", "checksum", "batch", "dirty", "down_sql", "execution_ms",
+	"applied_by", "hostname", "operator", "build_info",
+}
+
+// RecordArgs returns the values of RecordColumns recording m with meta.
+func RecordArgs(m *queen.Migration, meta queen.RecordMeta) []any {
+	return []any{m.Version, m.Name, m.Checksum(), meta.Batch, meta.Dirty, m.DownSQL,
+		meta.Duration.Milliseconds(), meta.AppliedBy, meta.Hostname, meta.Operator, meta.BuildInfo}
+}
+
+// RecordAll executes the record statement query for every migration in
+// one transaction, so either all of them are recorded or none.
+func RecordAll(ctx context.Context, db *sql.DB, query string, migrations []*queen.Migration, meta queen.RecordMeta) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	for _, m := range migrations {
+		if _, err := tx.ExecContext(ctx, query, RecordArgs(m, meta)...); err != nil {
+			return fmt.Errorf("record %s: %w", m.Version, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// HistoryColumns are the <table>_history columns written when recording an
+// execution, in the order drivers pass their values.
+var HistoryColumns = []string{
+	"run_id", "version", "name", "direction", "error", "started_at", "duration_ms",
+	"applied_by", "hostname", "operator", "build_info", "backup", "change_ticket", "lock_wait_ms",
+}
+
+// QuoteIdentifier quotes a SQL identifier (table name, column name) to
+// prevent SQL injection: with backticks in MySQL and double quotes
+// elsewhere, doubling the quote character inside the name.
+//
+// Examples:
+//   - users -> "users"
+//   - my"table -> "my""table"
+func (d Dialect) QuoteIdentifier(name string) string {
+	q := `"`
+	if d == MySQL {
+		q = "`"
+	}
+	return q + strings.ReplaceAll(name, q, q+q) + q
+}
+
+// QuoteQualified quotes name qualified by schema, or name alone if schema
+// is empty.
+func (d Dialect) QuoteQualified(schema, name string) string {
+	if schema == "" {
+		return d.QuoteIdentifier(name)
+	}
+	return d.QuoteIdentifier(schema) + "." + d.QuoteIdentifier(name)
+}
+
+// QuoteLiteral quotes a string literal. MySQL also gets its backslashes
+// escaped, since it treats them as escapes by default.
+func (d Dialect) QuoteLiteral(s string) string {
+	if d == MySQL {
+		s = strings.ReplaceAll(s, `\`, `\\`)
+	}
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// Placeholder returns the placeholder of the n-th parameter, counting
+// from 1: $n in PostgreSQL, ? elsewhere.
+func (d Dialect) Placeholder(n int) string {
+	if d == PostgreSQL {
+		return "$" + strconv.Itoa(n)
+	}
+	return "?"
+}
+
+// Placeholders returns the comma-separated placeholders of n parameters.
+func (d Dialect) Placeholders(n int) string {
+	ps := make([]string, n)
+	for i := range ps {
+		ps[i] = d.Placeholder(i + 1)
+	}
+	return strings.Join(ps, ", ")
+}
+
+// Rebind rewrites a query written with ? placeholders to the dialect's
+// placeholder style. Question marks in string literals, quoted identifiers
+// and comments are left alone; see split.Rebind.
+func (d Dialect) Rebind(query string) string {
+	if d != PostgreSQL {
+		return query
+	}
+	return split.Rebind(query)
+}
+
+// Insert returns an INSERT of one row of columns into table, which must
+// be quoted already, with a placeholder per column.
+func (d Dialect) Insert(table string, columns []string) string {
+	return fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", table, strings.Join(columns, ", "), d.Placeholders(len(columns)))
+}
+
+// Upsert returns an Insert that updates the existing row instead when key
+// conflicts, setting every other column to its new value plus the extra
+// assignments in set, e.g. "applied_at = CURRENT_TIMESTAMP".
+func (d Dialect) Upsert(table, key string, columns []string, set ...string) string {
+	var b strings.Builder
+	b.WriteString(d.Insert(table, columns))
+
+	switch d {
+	case MySQL:
+		b.WriteString(" ON DUPLICATE KEY UPDATE ")
+	default:
+		fmt.Fprintf(&b, " ON CONFLICT (%s) DO UPDATE SET ", key)
+	}
+
+	var assignments []string
+	for _, c := range columns {
+		if c == key {
+			continue
+		}
+		if d == MySQL {
+			assignments = append(assignments, fmt.Sprintf("%s = VALUES(%s)", c, c))
+		} else {
+			assignments = append(assignments, fmt.Sprintf("%s = excluded.%s", c, c))
+		}
+	}
+	b.WriteString(strings.Join(append(assignments, set...), ", "))
+
+	return b.String()
+}
+
+// Column is a column added to a table after its original layout.
+type Column struct {
+	Name       string
+	Definition string
+}
+
+// UpgradeTable adds the columns missing from a table created by an
+// earlier version. existing is a query with args listing the table's
+// column names; alter is the statement adding a column up to the column
+// name, e.g. `ALTER TABLE "t" ADD COLUMN`.
+//
+// Existing columns are looked up first rather than relying on ADD COLUMN
+// IF NOT EXISTS, which not every dialect has and which still takes an
+// exclusive lock in PostgreSQL when it turns out to be a no-op.
+func (d Dialect) UpgradeTable(ctx context.Context, db *sql.DB, alter string, columns []Column, existing string, args ...any) error {
+	rows, err := db.QueryContext(ctx, existing, args...)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = rows.Close() }()
+
+	have := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return err
+		}
+		have[strings.ToLower(name)] = true
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, c := range columns {
+		if have[c.Name] {
+			continue
+		}
+
+		query := fmt.Sprintf("%s %s %s", alter, d.QuoteIdentifier(c.Name), c.Definition)
+		if _, err := db.ExecContext(ctx, query); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,62 @@
+package sqlutil
+
+import "testing"
+
+func TestQuote(t *testing.T) {
+	tests := []struct {
+		dialect    Dialect
+		identifier string
+		literal    string
+	}{
+		{PostgreSQL, `"my""table"`, `'it''s \'`},
+		{MySQL, "`my\"table`", `'it''s \\'`},
+		{SQLite, `"my""table"`, `'it''s \'`},
+	}
+	for _, tt := range tests {
+		if got := tt.dialect.QuoteIdentifier(`my"table`); got != tt.identifier {
+			t.Errorf("%d: QuoteIdentifier = %s; want %s", tt.dialect, got, tt.identifier)
+		}
+		if got := tt.dialect.QuoteLiteral(`it's \`); got != tt.literal {
+			t.Errorf("%d: QuoteLiteral = %s; want %s", tt.dialect, got, tt.literal)
+		}
+	}
+
+	if got, want := MySQL.QuoteIdentifier("my`table"), "`my``table`"; got != want {
+		t.Errorf("QuoteIdentifier = %s; want %s", got, want)
+	}
+	if got, want := PostgreSQL.QuoteQualified("s", "t"), `"s"."t"`; got != want {
+		t.Errorf("QuoteQualified = %s; want %s", got, want)
+	}
+	if got, want := PostgreSQL.QuoteQualified("", "t"), `"t"`; got != want {
+		t.Errorf("QuoteQualified = %s; want %s", got, want)
+	}
+}
+
+func TestUpsert(t *testing.T) {
+	columns := []string{"version", "name"}
+
+	tests := []struct {
+		dialect Dialect
+		want    string
+	}{
+		{PostgreSQL, `INSERT INTO "t" (version, name) VALUES ($1, $2) ON CONFLICT (version) DO UPDATE SET name = excluded.name, applied_at = CURRENT_TIMESTAMP`},
+		{MySQL, "INSERT INTO \"t\" (version, name) VALUES (?, ?) ON DUPLICATE KEY UPDATE name = VALUES(name), applied_at = CURRENT_TIMESTAMP"},
+		{SQLite, `INSERT INTO "t" (version, name) VALUES (?, ?) ON CONFLICT (version) DO UPDATE SET name = excluded.name, applied_at = CURRENT_TIMESTAMP`},
+	}
+	for _, tt := range tests {
+		if got := tt.dialect.Upsert(`"t"`, "version", columns, "applied_at = CURRENT_TIMESTAMP"); got != tt.want {
+			t.Errorf("%d: Upsert =\n%s\nwant\n%s", tt.dialect, got, tt.want)
+		}
+	}
+}
+
+func TestRebind(t *testing.T) {
+	query := "SELECT '?' FROM t WHERE a = ? AND b = ?"
+
+	if got, want := PostgreSQL.Rebind(query), "SELECT '?' FROM t WHERE a = $1 AND b = $2"; got != want {
+		t.Errorf("Rebind = %s; want %s", got, want)
+	}
+	if got := MySQL.Rebind(query); got != query {
+		t.Errorf("Rebind = %s; want it unchanged", got)
+	}
+}
@@ -0,0 +1,112 @@
+// Package views maintains versioned reporting views for drivers.
+//
+// Views are treated as repeatable objects: each definition's checksum is
+// stored in a small metadata table, and a view is recreated only when its
+// definition changes.
+package views
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/honeynil/queen/drivers/internal/sqlutil"
+	"github.com/honeynil/queen/internal/checksum"
+)
+
+// Definition is a named view query.
+type Definition struct {
+	Name  string
+	Query string
+}
+
+// Sync creates or replaces every view whose definition differs from the
+// checksum recorded in metaTable. The views and metaTable are qualified by
+// schema, unless it is empty.
+func Sync(ctx context.Context, db *sql.DB, dialect sqlutil.Dialect, schema, metaTable string, defs []Definition) error {
+	meta := dialect.QuoteQualified(schema, metaTable)
+
+	_, err := db.ExecContext(ctx, fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			name VARCHAR(255) PRIMARY KEY,
+			checksum VARCHAR(64) NOT NULL
+		)
+	`, meta))
+	if err != nil {
+		return err
+	}
+
+	existing, err := load(ctx, db, meta)
+	if err != nil {
+		return err
+	}
+
+	for _, def := range defs {
+		sum := checksum.Calculate(def.Query)
+		if existing[def.Name] == sum {
+			continue
+		}
+
+		if err := replace(ctx, db, dialect, schema, meta, def, sum); err != nil {
+			return fmt.Errorf("view %s: %w", def.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// load reads recorded view checksums.
+func load(ctx context.Context, db *sql.DB, meta string) (map[string]string, error) {
+	rows, err := db.QueryContext(ctx, fmt.Sprintf("SELECT name, checksum FROM %s", meta))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	existing := make(map[string]string)
+	for rows.Next() {
+		var name, sum string
+		if err := rows.Scan(&name, &sum); err != nil {
+			return nil, err
+		}
+		existing[name] = sum
+	}
+
+	return existing, rows.Err()
+}
+
+// replace drops and recreates a view and records its checksum.
+func replace(ctx context.Context, db *sql.DB, dialect sqlutil.Dialect, schema, meta string, def Definition, sum string) error {
+	view := dialect.QuoteQualified(schema, def.Name)
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	statements := []struct {
+		query string
+		args  []any
+	}{
+		{query: fmt.Sprintf("DROP VIEW IF EXISTS %s", view)},
+		{query: fmt.Sprintf("CREATE VIEW %s AS %s", view, def.Query)},
+		{
+			query: fmt.Sprintf("DELETE FROM %s WHERE name = %s", meta, dialect.Placeholder(1)),
+			args:  []any{def.Name},
+		},
+		{
+			query: fmt.Sprintf("INSERT INTO %s (name, checksum) VALUES (%s, %s)",
+				meta, dialect.Placeholder(1), dialect.Placeholder(2)),
+			args: []any{def.Name, sum},
+		},
+	}
+
+	for _, stmt := range statements {
+		if _, err := tx.ExecContext(ctx, stmt.query, stmt.args...); err != nil {
+			// Ignore rollback error, return original error
+			_ = tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
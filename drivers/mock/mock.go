@@ -11,6 +11,7 @@ package mock
 import (
 	"context"
 	"database/sql"
+	"fmt"
 	"sort"
 	"sync"
 	"time"
@@ -22,6 +23,9 @@ import (
 type Driver struct {
 	mu        sync.Mutex
 	applied   map[string]queen.Applied
+	metadata  map[string][]byte
+	progress  map[string]map[string]string
+	history   []queen.HistoryEntry
 	locked    bool
 	initErr   error
 	lockErr   error
@@ -31,8 +35,10 @@ type Driver struct {
 // New creates a new mock driver.
 func New() *Driver {
 	return &Driver{
-		applied: make(map[string]queen.Applied),
-		locked:  false,
+		applied:  make(map[string]queen.Applied),
+		metadata: make(map[string][]byte),
+		progress: make(map[string]map[string]string),
+		locked:   false,
 	}
 }
 
@@ -88,8 +94,19 @@ func (d *Driver) GetApplied(ctx context.Context) ([]queen.Applied, error) {
 	return result, nil
 }
 
-// Record marks a migration as applied.
+// Record marks a migration as applied, without run metadata.
 func (d *Driver) Record(ctx context.Context, m *queen.Migration) error {
+	return d.RecordWithMeta(ctx, m, queen.RecordMeta{})
+}
+
+// RecordWithMeta marks a migration as applied with run metadata.
+func (d *Driver) RecordWithMeta(ctx context.Context, m *queen.Migration, meta queen.RecordMeta) error {
+	return d.RecordBatch(ctx, []*queen.Migration{m}, meta)
+}
+
+// RecordBatch marks several migrations as applied with the same run
+// metadata, all or none.
+func (d *Driver) RecordBatch(ctx context.Context, migrations []*queen.Migration, meta queen.RecordMeta) error {
 	d.mu.Lock()
 	defer d.mu.Unlock()
 
@@ -97,13 +114,70 @@ func (d *Driver) Record(ctx context.Context, m *queen.Migration) error {
 		return d.recordErr
 	}
 
+	for _, m := range migrations {
+		d.record(m, meta)
+	}
+
+	return nil
+}
+
+func (d *Driver) record(m *queen.Migration, meta queen.RecordMeta) {
 	d.applied[m.Version] = queen.Applied{
 		Version:   m.Version,
 		Name:      m.Name,
 		AppliedAt: time.Now(),
 		Checksum:  m.Checksum(),
+		Batch:     meta.Batch,
+		Dirty:     meta.Dirty,
+		DownSQL:   m.DownSQL,
+		Duration:  meta.Duration,
+		AppliedBy: meta.AppliedBy,
+		Hostname:  meta.Hostname,
+		Operator:  meta.Operator,
+		BuildInfo: meta.BuildInfo,
+	}
+}
+
+// RecordScript returns a generic INSERT into queen_migrations recording m,
+// for queen.GenerateScript. Values are quoted, not escaped.
+func (d *Driver) RecordScript(m *queen.Migration, meta queen.RecordMeta) string {
+	return fmt.Sprintf("INSERT INTO queen_migrations (version, name, checksum, batch) VALUES ('%s', '%s', '%s', %d);",
+		m.Version, m.Name, m.Checksum(), meta.Batch)
+}
+
+// SetDirty sets or clears the dirty flag of a migration record.
+func (d *Driver) SetDirty(ctx context.Context, version string, dirty bool) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if a, ok := d.applied[version]; ok {
+		a.Dirty = dirty
+		d.applied[version] = a
+	}
+	return nil
+}
+
+// UpdateChecksum sets the stored checksum of a migration record.
+func (d *Driver) UpdateChecksum(ctx context.Context, version, checksum string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if a, ok := d.applied[version]; ok {
+		a.Checksum = checksum
+		d.applied[version] = a
 	}
+	return nil
+}
+
+// DropSchema clears all records, progress and history.
+func (d *Driver) DropSchema(ctx context.Context) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
 
+	d.applied = make(map[string]queen.Applied)
+	d.metadata = make(map[string][]byte)
+	d.progress = make(map[string]map[string]string)
+	d.history = nil
 	return nil
 }
 
@@ -113,9 +187,33 @@ func (d *Driver) Remove(ctx context.Context, version string) error {
 	defer d.mu.Unlock()
 
 	delete(d.applied, version)
+	delete(d.metadata, version)
 	return nil
 }
 
+// SetMetadata stores metadata with a migration record.
+func (d *Driver) SetMetadata(ctx context.Context, version string, data []byte) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, ok := d.applied[version]; ok {
+		d.metadata[version] = data
+	}
+	return nil
+}
+
+// GetMetadata returns the stored metadata by version.
+func (d *Driver) GetMetadata(ctx context.Context) (map[string][]byte, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	result := make(map[string][]byte, len(d.metadata))
+	for version, data := range d.metadata {
+		result[version] = data
+	}
+	return result, nil
+}
+
 // Lock acquires a lock.
 func (d *Driver) Lock(ctx context.Context, timeout time.Duration) error {
 	d.mu.Lock()
@@ -149,6 +247,62 @@ func (d *Driver) Exec(ctx context.Context, fn func(*sql.Tx) error) error {
 	return fn(nil)
 }
 
+// ExecNoTx executes a function without a connection (mock has no database, so we pass nil).
+func (d *Driver) ExecNoTx(ctx context.Context, fn func(*sql.Conn) error) error {
+	return fn(nil)
+}
+
+// GetProgress returns the progress value saved under key for version.
+// Mock progress is not transactional: it survives failed migrations.
+func (d *Driver) GetProgress(ctx context.Context, tx *sql.Tx, version, key string) (string, bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	value, ok := d.progress[version][key]
+	return value, ok, nil
+}
+
+// SetProgress saves a progress value under key for version.
+func (d *Driver) SetProgress(ctx context.Context, tx *sql.Tx, version, key, value string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.progress[version] == nil {
+		d.progress[version] = make(map[string]string)
+	}
+	d.progress[version][key] = value
+	return nil
+}
+
+// ClearProgress deletes all progress saved for version.
+func (d *Driver) ClearProgress(ctx context.Context, tx *sql.Tx, version string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	delete(d.progress, version)
+	return nil
+}
+
+// RecordHistory appends an entry to the in-memory history log.
+func (d *Driver) RecordHistory(ctx context.Context, e queen.HistoryEntry) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	e.ID = int64(len(d.history) + 1)
+	d.history = append(d.history, e)
+	return nil
+}
+
+// GetHistory returns the history log, oldest entry first.
+func (d *Driver) GetHistory(ctx context.Context) ([]queen.HistoryEntry, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	history := make([]queen.HistoryEntry, len(d.history))
+	copy(history, d.history)
+	return history, nil
+}
+
 // Close closes the mock driver (no-op).
 func (d *Driver) Close() error {
 	return nil
@@ -41,13 +41,22 @@ package mysql
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"math"
 	"strings"
 	"time"
 
 	"github.com/honeynil/queen"
+	"github.com/honeynil/queen/drivers/internal/permcheck"
+	"github.com/honeynil/queen/drivers/internal/sqlutil"
+	"github.com/honeynil/queen/drivers/internal/views"
+	"github.com/honeynil/queen/internal/split"
 )
 
+// dialect builds the driver's SQL.
+const dialect = sqlutil.MySQL
+
 // Driver implements the queen.Driver interface for MySQL.
 //
 // The driver is thread-safe and can be used concurrently by multiple goroutines.
@@ -56,6 +65,12 @@ type Driver struct {
 	db        *sql.DB
 	tableName string
 	lockName  string
+
+	// database and home are set for drivers returned by Isolate. Tables
+	// are then qualified with database, and transactions switch to it and
+	// back to home afterwards.
+	database string
+	home     string
 }
 
 // New creates a new MySQL driver.
@@ -91,14 +106,31 @@ func NewWithTableName(db *sql.DB, tableName string) *Driver {
 	}
 }
 
-// Init creates the migrations tracking table if it doesn't exist.
+// SetTableName sets the migrations table name, and the lock name derived
+// from it, for queen.Config.TableName.
+func (d *Driver) SetTableName(name string) {
+	d.tableName = name
+	d.lockName = "queen_lock_" + name
+}
+
+// Init creates the migrations tracking table if it doesn't exist, along with
+// the <table>_progress table used by the progress package and the
+// <table>_history execution log.
 //
 // The table schema:
 //   - version: VARCHAR(255) PRIMARY KEY - unique migration version
 //   - name: VARCHAR(255) NOT NULL - human-readable migration name
 //   - applied_at: TIMESTAMP - when the migration was applied
 //   - checksum: VARCHAR(64) - hash of migration content for validation
-//
+//   - batch: INT - number of the Up run that applied the migration
+//   - dirty: BOOLEAN - set while a migration runs and left set if it fails midway
+//   - down_sql: TEXT - rollback script, so Down works after the migration is removed from code
+//   - execution_ms: BIGINT - how long the migration took to execute
+//   - applied_by, hostname, operator: VARCHAR(255) - who applied the migration and from where
+//   - build_info: VARCHAR(255) - module version and VCS revision of the binary that applied it
+//   - metadata: TEXT - extension fields encoded by Config.MetadataSerializer
+//
+// Tables created by earlier versions are upgraded with any missing columns.
 // This method is idempotent and safe to call multiple times.
 func (d *Driver) Init(ctx context.Context) error {
 	query := fmt.Sprintf(`
@@ -106,12 +138,32 @@ func (d *Driver) Init(ctx context.Context) error {
 			version VARCHAR(255) PRIMARY KEY,
 			name VARCHAR(255) NOT NULL,
 			applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-			checksum VARCHAR(64) NOT NULL
+			checksum VARCHAR(64) NOT NULL,
+			batch INT NOT NULL DEFAULT 0,
+			dirty BOOLEAN NOT NULL DEFAULT FALSE,
+			down_sql TEXT,
+			execution_ms BIGINT NOT NULL DEFAULT 0,
+			applied_by VARCHAR(255) NOT NULL DEFAULT '',
+			hostname VARCHAR(255) NOT NULL DEFAULT '',
+			operator VARCHAR(255) NOT NULL DEFAULT '',
+			build_info VARCHAR(255) NOT NULL DEFAULT '',
+			metadata TEXT
 		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci
-	`, quoteIdentifier(d.tableName))
+	`, d.quote(d.tableName))
 
-	_, err := d.db.ExecContext(ctx, query)
-	return err
+	if _, err := d.db.ExecContext(ctx, query); err != nil {
+		return err
+	}
+
+	if err := d.upgradeTable(ctx, d.tableName, trackingColumns); err != nil {
+		return err
+	}
+
+	if err := d.initProgress(ctx); err != nil {
+		return err
+	}
+
+	return d.initHistory(ctx)
 }
 
 // GetApplied returns all applied migrations sorted by applied_at in ascending order.
@@ -120,10 +172,11 @@ func (d *Driver) Init(ctx context.Context) error {
 // and which are pending.
 func (d *Driver) GetApplied(ctx context.Context) ([]queen.Applied, error) {
 	query := fmt.Sprintf(`
-		SELECT version, name, applied_at, checksum
+		SELECT version, name, applied_at, checksum, batch, dirty, COALESCE(down_sql, ''), execution_ms,
+			applied_by, hostname, operator, build_info
 		FROM %s
 		ORDER BY applied_at ASC
-	`, quoteIdentifier(d.tableName))
+	`, d.quote(d.tableName))
 
 	rows, err := d.db.QueryContext(ctx, query)
 	if err != nil {
@@ -134,36 +187,114 @@ func (d *Driver) GetApplied(ctx context.Context) ([]queen.Applied, error) {
 	var applied []queen.Applied
 	for rows.Next() {
 		var a queen.Applied
-		if err := rows.Scan(&a.Version, &a.Name, &a.AppliedAt, &a.Checksum); err != nil {
+		var executionMS int64
+		if err := rows.Scan(&a.Version, &a.Name, &a.AppliedAt, &a.Checksum, &a.Batch, &a.Dirty, &a.DownSQL, &executionMS,
+			&a.AppliedBy, &a.Hostname, &a.Operator, &a.BuildInfo); err != nil {
 			return nil, err
 		}
+		a.Duration = time.Duration(executionMS) * time.Millisecond
 		applied = append(applied, a)
 	}
 
 	return applied, rows.Err()
 }
 
-// Record marks a migration as applied in the database.
-//
-// This should be called after successfully executing a migration's up function.
-// The checksum is automatically computed from the migration content.
+// Record marks a migration as applied, without run metadata.
+// An existing record for the version is replaced.
 func (d *Driver) Record(ctx context.Context, m *queen.Migration) error {
+	return d.RecordWithMeta(ctx, m, queen.RecordMeta{})
+}
+
+// RecordWithMeta marks a migration as applied with the run metadata in
+// meta. An existing record for the version is replaced.
+func (d *Driver) RecordWithMeta(ctx context.Context, m *queen.Migration, meta queen.RecordMeta) error {
+	_, err := d.db.ExecContext(ctx, d.recordQuery(), sqlutil.RecordArgs(m, meta)...)
+	return err
+}
+
+// RecordBatch records every migration like RecordWithMeta, in one
+// transaction.
+func (d *Driver) RecordBatch(ctx context.Context, migrations []*queen.Migration, meta queen.RecordMeta) error {
+	return sqlutil.RecordAll(ctx, d.db, d.recordQuery(), migrations, meta)
+}
+
+func (d *Driver) recordQuery() string {
+	return dialect.Upsert(d.quote(d.tableName), "version", sqlutil.RecordColumns, "applied_at = CURRENT_TIMESTAMP")
+}
+
+// RecordScript returns the statement recording m like Record, with its
+// values inlined, for queen.GenerateScript.
+func (d *Driver) RecordScript(m *queen.Migration, meta queen.RecordMeta) string {
+	lit := dialect.QuoteLiteral
+	return fmt.Sprintf(`INSERT INTO %s (version, name, checksum, batch, dirty, down_sql, execution_ms,
+	applied_by, hostname, operator, build_info)
+VALUES (%s, %s, %s, %d, FALSE, %s, %d, %s, %s, %s, %s);`,
+		d.quote(d.tableName), lit(m.Version), lit(m.Name), lit(m.Checksum()), meta.Batch,
+		lit(m.DownSQL), meta.Duration.Milliseconds(), lit(meta.AppliedBy), lit(meta.Hostname),
+		lit(meta.Operator), lit(meta.BuildInfo))
+}
+
+// SetDirty sets or clears the dirty flag of a migration record.
+func (d *Driver) SetDirty(ctx context.Context, version string, dirty bool) error {
+	query := fmt.Sprintf(`
+		UPDATE %s SET dirty = ? WHERE version = ?
+	`, d.quote(d.tableName))
+
+	_, err := d.db.ExecContext(ctx, query, dirty, version)
+	return err
+}
+
+// UpdateChecksum sets the stored checksum of a migration record.
+func (d *Driver) UpdateChecksum(ctx context.Context, version, checksum string) error {
+	query := fmt.Sprintf(`
+		UPDATE %s SET checksum = ? WHERE version = ?
+	`, d.quote(d.tableName))
+
+	_, err := d.db.ExecContext(ctx, query, checksum, version)
+	return err
+}
+
+// SetMetadata stores metadata with a migration record.
+func (d *Driver) SetMetadata(ctx context.Context, version string, data []byte) error {
 	query := fmt.Sprintf(`
-		INSERT INTO %s (version, name, checksum)
-		VALUES (?, ?, ?)
-	`, quoteIdentifier(d.tableName))
+		UPDATE %s SET metadata = ? WHERE version = ?
+	`, d.quote(d.tableName))
 
-	_, err := d.db.ExecContext(ctx, query, m.Version, m.Name, m.Checksum())
+	_, err := d.db.ExecContext(ctx, query, string(data), version)
 	return err
 }
 
+// GetMetadata returns the stored metadata by version.
+func (d *Driver) GetMetadata(ctx context.Context) (map[string][]byte, error) {
+	query := fmt.Sprintf(`
+		SELECT version, metadata FROM %s WHERE metadata IS NOT NULL
+	`, d.quote(d.tableName))
+
+	rows, err := d.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	metadata := make(map[string][]byte)
+	for rows.Next() {
+		var version, data string
+		if err := rows.Scan(&version, &data); err != nil {
+			return nil, err
+		}
+		metadata[version] = []byte(data)
+	}
+
+	return metadata, rows.Err()
+}
+
 // Remove removes a migration record from the database.
 //
 // This should be called after successfully rolling back a migration's down function.
 func (d *Driver) Remove(ctx context.Context, version string) error {
 	query := fmt.Sprintf(`
 		DELETE FROM %s WHERE version = ?
-	`, quoteIdentifier(d.tableName))
+	`, d.quote(d.tableName))
 
 	_, err := d.db.ExecContext(ctx, query, version)
 	return err
@@ -183,9 +314,16 @@ func (d *Driver) Lock(ctx context.Context, timeout time.Duration) error {
 	// 1 if the lock was obtained successfully
 	// 0 if the attempt timed out
 	// NULL if an error occurred
+	//
+	// The timeout is in whole seconds, rounded up so that a sub-second
+	// timeout still waits
 	var result sql.NullInt64
 	query := "SELECT GET_LOCK(?, ?)"
-	err := d.db.QueryRowContext(ctx, query, d.lockName, int(timeout.Seconds())).Scan(&result)
+	seconds := int64(math.Ceil(max(timeout, 0).Seconds()))
+	err := d.db.QueryRowContext(ctx, query, d.lockName, seconds).Scan(&result)
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("%w: %w", queen.ErrLockTimeout, err)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to acquire lock: %w", err)
 	}
@@ -225,7 +363,19 @@ func (d *Driver) Unlock(ctx context.Context) error {
 //
 // This provides ACID guarantees for migration execution.
 func (d *Driver) Exec(ctx context.Context, fn func(*sql.Tx) error) error {
-	tx, err := d.db.BeginTx(ctx, nil)
+	conn, err := d.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = conn.Close() }()
+
+	restore, err := d.use(ctx, conn)
+	if err != nil {
+		return err
+	}
+	defer restore()
+
+	tx, err := conn.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
@@ -239,6 +389,36 @@ func (d *Driver) Exec(ctx context.Context, fn func(*sql.Tx) error) error {
 	return tx.Commit()
 }
 
+// SplitStatements splits query into individual statements, honoring
+// DELIMITER directives, so multi-statement migrations work without
+// multiStatements=true in the DSN.
+func (d *Driver) SplitStatements(query string) []string {
+	return split.Split(query, split.MySQL)
+}
+
+// CountPlaceholders returns the number of bind args query takes, counting
+// its ? placeholders.
+func (d *Driver) CountPlaceholders(query string) int {
+	return split.CountPlaceholders(query, split.MySQL)
+}
+
+// ExecNoTx executes a function on a dedicated connection without a transaction.
+func (d *Driver) ExecNoTx(ctx context.Context, fn func(*sql.Conn) error) error {
+	conn, err := d.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = conn.Close() }()
+
+	restore, err := d.use(ctx, conn)
+	if err != nil {
+		return err
+	}
+	defer restore()
+
+	return fn(conn)
+}
+
 // Close closes the database connection.
 //
 // Any locks held by this connection will be automatically released.
@@ -246,15 +426,538 @@ func (d *Driver) Close() error {
 	return d.db.Close()
 }
 
-// quoteIdentifier quotes a SQL identifier (table name, column name) to prevent SQL injection.
+// Isolate creates the database name and returns a driver working in it, for
+// queen.TestHelper.Isolated. The driver shares the connection pool: it
+// switches connections to the database with USE while running migrations
+// and back afterwards. drop removes the database.
+func (d *Driver) Isolate(ctx context.Context, name string) (queen.Driver, func(context.Context) error, error) {
+	var home sql.NullString
+	if err := d.db.QueryRowContext(ctx, "SELECT DATABASE()").Scan(&home); err != nil {
+		return nil, nil, err
+	}
+	if !home.Valid {
+		return nil, nil, errors.New("connection has no default database to return to")
+	}
+
+	if _, err := d.db.ExecContext(ctx, "CREATE DATABASE "+dialect.QuoteIdentifier(name)); err != nil {
+		return nil, nil, err
+	}
+
+	drop := func(ctx context.Context) error {
+		_, err := d.db.ExecContext(ctx, "DROP DATABASE "+dialect.QuoteIdentifier(name))
+		return err
+	}
+
+	return &Driver{
+		db:        d.db,
+		tableName: d.tableName,
+		lockName:  "queen_lock_" + name + "." + d.tableName,
+		database:  name,
+		home:      home.String,
+	}, drop, nil
+}
+
+// DropSchema drops every table and view of the driver's database, with
+// foreign key checks disabled so the order doesn't matter. The database
+// itself is kept.
+func (d *Driver) DropSchema(ctx context.Context) error {
+	conn, err := d.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	back, err := d.use(ctx, conn)
+	if err != nil {
+		return err
+	}
+	defer back()
+
+	rows, err := conn.QueryContext(ctx, `
+		SELECT table_name, table_type FROM information_schema.tables
+		WHERE table_schema = DATABASE()
+	`)
+	if err != nil {
+		return err
+	}
+
+	var tables, views []string
+	for rows.Next() {
+		var name, kind string
+		if err := rows.Scan(&name, &kind); err != nil {
+			_ = rows.Close()
+			return err
+		}
+		if kind == "VIEW" {
+			views = append(views, dialect.QuoteIdentifier(name))
+		} else {
+			tables = append(tables, dialect.QuoteIdentifier(name))
+		}
+	}
+	_ = rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if _, err := conn.ExecContext(ctx, "SET FOREIGN_KEY_CHECKS = 0"); err != nil {
+		return err
+	}
+	defer func() { _, _ = conn.ExecContext(context.Background(), "SET FOREIGN_KEY_CHECKS = 1") }()
+
+	if len(views) > 0 {
+		if _, err := conn.ExecContext(ctx, "DROP VIEW IF EXISTS "+strings.Join(views, ", ")); err != nil {
+			return err
+		}
+	}
+	if len(tables) > 0 {
+		if _, err := conn.ExecContext(ctx, "DROP TABLE IF EXISTS "+strings.Join(tables, ", ")); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// use switches conn to the driver's database and returns a function that
+// switches it back. Both are no-ops unless the driver was returned by Isolate.
+func (d *Driver) use(ctx context.Context, conn *sql.Conn) (func(), error) {
+	if d.database == "" {
+		return func() {}, nil
+	}
+
+	if _, err := conn.ExecContext(ctx, "USE "+dialect.QuoteIdentifier(d.database)); err != nil {
+		return nil, err
+	}
+
+	return func() {
+		_, _ = conn.ExecContext(context.Background(), "USE "+dialect.QuoteIdentifier(d.home))
+	}, nil
+}
+
+// EnsureViews creates or updates the reporting views over the migrations table:
 //
-// In MySQL, identifiers are quoted with backticks (`). This function also escapes
-// any existing backticks in the identifier by doubling them.
+//   - <table>_batches: one row per Up run (batch) with counts and time range
+//   - <table>_namespaces: applied migrations per version prefix ("users_001" -> "users")
+//   - <table>_failures: dirty records left by failed or interrupted migrations
 //
-// Examples:
-//   - users -> `users`
-//   - my`table -> `my“table`
-func quoteIdentifier(name string) string {
-	escaped := strings.ReplaceAll(name, "`", "``")
-	return "`" + escaped + "`"
+// View definitions are versioned in <table>_views and only recreated when
+// they change.
+func (d *Driver) EnsureViews(ctx context.Context) error {
+	table := d.quote(d.tableName)
+	namespace := "CASE WHEN LOCATE('_', version) > 0 THEN SUBSTRING_INDEX(version, '_', 1) ELSE '' END"
+
+	defs := []views.Definition{
+		{
+			Name: d.tableName + "_batches",
+			Query: fmt.Sprintf(`SELECT batch, COUNT(*) AS migrations,
+				MIN(applied_at) AS started_at, MAX(applied_at) AS finished_at,
+				SUM(CASE WHEN dirty THEN 1 ELSE 0 END) AS dirty
+				FROM %s GROUP BY batch`, table),
+		},
+		{
+			Name: d.tableName + "_namespaces",
+			Query: fmt.Sprintf(`SELECT %s AS namespace, COUNT(*) AS applied,
+				MAX(applied_at) AS last_applied_at
+				FROM %s GROUP BY %s`, namespace, table, namespace),
+		},
+		{
+			Name: d.tableName + "_failures",
+			Query: fmt.Sprintf(`SELECT version, name, applied_at, batch
+				FROM %s WHERE dirty`, table),
+		},
+	}
+
+	return views.Sync(ctx, d.db, dialect, d.database, d.tableName+"_views", defs)
+}
+
+// CheckPermissions verifies the user can create, alter and drop tables in
+// the current database and write the tracking table. It leaves no changes
+// behind.
+func (d *Driver) CheckPermissions(ctx context.Context) error {
+	schema := d.database
+	if schema == "" {
+		if err := d.db.QueryRowContext(ctx, "SELECT DATABASE()").Scan(&schema); err != nil {
+			return err
+		}
+	}
+
+	return permcheck.Check(ctx, d.db, dialect, schema, d.tableName)
+}
+
+// Capabilities reports the server version. MySQL has no extensions, so
+// "extension:" requirements are never met.
+func (d *Driver) Capabilities(ctx context.Context) (queen.Capabilities, error) {
+	caps := queen.Capabilities{Dialect: "mysql"}
+	err := d.db.QueryRowContext(ctx, "SELECT VERSION()").Scan(&caps.Version)
+	return caps, err
+}
+
+// ReplicationPosition returns the binary log file and offset, and the
+// executed GTID set if GTIDs are enabled. It needs the REPLICATION CLIENT
+// privilege (BINLOG MONITOR on MariaDB) and fails if binary logging is
+// disabled.
+func (d *Driver) ReplicationPosition(ctx context.Context) (*queen.ReplicationPosition, error) {
+	// SHOW MASTER STATUS was renamed in MySQL 8.2 and removed in 8.4
+	rows, err := d.db.QueryContext(ctx, "SHOW BINARY LOG STATUS")
+	if err != nil {
+		rows, err = d.db.QueryContext(ctx, "SHOW MASTER STATUS")
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		return nil, errors.New("binary logging is disabled")
+	}
+
+	values := make([]sql.NullString, len(columns))
+	dest := make([]any, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	if err := rows.Scan(dest...); err != nil {
+		return nil, err
+	}
+
+	pos := &queen.ReplicationPosition{}
+	for i, column := range columns {
+		switch column {
+		case "File":
+			pos.File = values[i].String
+		case "Position":
+			if _, err := fmt.Sscan(values[i].String, &pos.Offset); err != nil {
+				return nil, fmt.Errorf("invalid binary log position %q: %w", values[i].String, err)
+			}
+		case "Executed_Gtid_Set":
+			// Long sets are broken across lines
+			pos.GTIDSet = strings.ReplaceAll(values[i].String, "\n", "")
+		}
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+
+	// MariaDB has no Executed_Gtid_Set column; an error means MySQL
+	if pos.GTIDSet == "" {
+		var gtid sql.NullString
+		if err := d.db.QueryRowContext(ctx, "SELECT @@GLOBAL.gtid_binlog_pos").Scan(&gtid); err == nil {
+			pos.GTIDSet = gtid.String
+		}
+	}
+
+	return pos, nil
+}
+
+// ListTables returns the tables of the driver's database, or the current
+// database.
+func (d *Driver) ListTables(ctx context.Context) ([]string, error) {
+	rows, err := d.db.QueryContext(ctx, `
+		SELECT TABLE_NAME FROM information_schema.TABLES
+		WHERE TABLE_SCHEMA = COALESCE(NULLIF(?, ''), DATABASE()) AND TABLE_TYPE = 'BASE TABLE'
+		ORDER BY TABLE_NAME
+	`, d.database)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		tables = append(tables, name)
+	}
+
+	return tables, rows.Err()
+}
+
+// ListColumns returns the columns of table. Type is the full column type,
+// e.g. "varchar(255)" or "int unsigned".
+func (d *Driver) ListColumns(ctx context.Context, table string) ([]queen.Column, error) {
+	rows, err := d.db.QueryContext(ctx, `
+		SELECT COLUMN_NAME, COLUMN_TYPE, IS_NULLABLE = 'YES', COALESCE(COLUMN_DEFAULT, '')
+		FROM information_schema.COLUMNS
+		WHERE TABLE_SCHEMA = COALESCE(NULLIF(?, ''), DATABASE()) AND TABLE_NAME = ?
+		ORDER BY ORDINAL_POSITION
+	`, d.database, table)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var columns []queen.Column
+	for rows.Next() {
+		var c queen.Column
+		if err := rows.Scan(&c.Name, &c.Type, &c.Nullable, &c.Default); err != nil {
+			return nil, err
+		}
+		columns = append(columns, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("%w: table %s", queen.ErrObjectNotFound, table)
+	}
+
+	return columns, nil
+}
+
+// ListIndexes returns the indexes of table. The primary key is the index
+// named PRIMARY.
+func (d *Driver) ListIndexes(ctx context.Context, table string) ([]queen.Index, error) {
+	if _, err := d.tableType(ctx, table); err != nil {
+		return nil, err
+	}
+
+	rows, err := d.db.QueryContext(ctx, `
+		SELECT INDEX_NAME, NON_UNIQUE = 0, COALESCE(COLUMN_NAME, '')
+		FROM information_schema.STATISTICS
+		WHERE TABLE_SCHEMA = COALESCE(NULLIF(?, ''), DATABASE()) AND TABLE_NAME = ?
+		ORDER BY INDEX_NAME, SEQ_IN_INDEX
+	`, d.database, table)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var indexes []queen.Index
+	for rows.Next() {
+		var name, column string
+		var unique bool
+		if err := rows.Scan(&name, &unique, &column); err != nil {
+			return nil, err
+		}
+
+		if len(indexes) == 0 || indexes[len(indexes)-1].Name != name {
+			indexes = append(indexes, queen.Index{Name: name, Unique: unique, Primary: name == "PRIMARY"})
+		}
+		last := &indexes[len(indexes)-1]
+		last.Columns = append(last.Columns, column)
+	}
+
+	return indexes, rows.Err()
+}
+
+// ObjectDDL returns the CREATE statement of a table or view from SHOW
+// CREATE TABLE or SHOW CREATE VIEW. Indexes are part of their table's
+// statement and have none of their own.
+func (d *Driver) ObjectDDL(ctx context.Context, name string) (string, error) {
+	kind, err := d.tableType(ctx, name)
+	if err != nil {
+		return "", err
+	}
+
+	var ddl string
+	if kind == "VIEW" {
+		var view, charset, collation string
+		err = d.db.QueryRowContext(ctx, "SHOW CREATE VIEW "+d.quote(name)).Scan(&view, &ddl, &charset, &collation)
+	} else {
+		var table string
+		err = d.db.QueryRowContext(ctx, "SHOW CREATE TABLE "+d.quote(name)).Scan(&table, &ddl)
+	}
+
+	return ddl, err
+}
+
+// tableType returns the TABLE_TYPE of name, e.g. "BASE TABLE" or "VIEW".
+func (d *Driver) tableType(ctx context.Context, name string) (string, error) {
+	var kind string
+	err := d.db.QueryRowContext(ctx, `
+		SELECT TABLE_TYPE FROM information_schema.TABLES
+		WHERE TABLE_SCHEMA = COALESCE(NULLIF(?, ''), DATABASE()) AND TABLE_NAME = ?
+	`, d.database, name).Scan(&kind)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", fmt.Errorf("%w: %s", queen.ErrObjectNotFound, name)
+	}
+
+	return kind, err
+}
+
+// progressTable returns the name of the table used by the progress package.
+func (d *Driver) progressTable() string {
+	return d.tableName + "_progress"
+}
+
+// initProgress creates the table used by the progress package.
+func (d *Driver) initProgress(ctx context.Context) error {
+	query := fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			version VARCHAR(255) NOT NULL,
+			name VARCHAR(255) NOT NULL,
+			value TEXT NOT NULL,
+			updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (version, name)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci
+	`, d.quote(d.progressTable()))
+
+	_, err := d.db.ExecContext(ctx, query)
+	return err
+}
+
+// GetProgress returns the progress value saved under key for version.
+func (d *Driver) GetProgress(ctx context.Context, tx *sql.Tx, version, key string) (string, bool, error) {
+	query := fmt.Sprintf("SELECT value FROM %s WHERE version = ? AND name = ?", d.quote(d.progressTable()))
+
+	var value string
+	err := tx.QueryRowContext(ctx, query, version, key).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+
+	return value, true, nil
+}
+
+// SetProgress saves a progress value under key for version.
+func (d *Driver) SetProgress(ctx context.Context, tx *sql.Tx, version, key, value string) error {
+	query := fmt.Sprintf(`INSERT INTO %s (version, name, value) VALUES (?, ?, ?)
+		ON DUPLICATE KEY UPDATE value = VALUES(value), updated_at = CURRENT_TIMESTAMP`, d.quote(d.progressTable()))
+
+	_, err := tx.ExecContext(ctx, query, version, key, value)
+	return err
+}
+
+// ClearProgress deletes all progress saved for version.
+func (d *Driver) ClearProgress(ctx context.Context, tx *sql.Tx, version string) error {
+	query := fmt.Sprintf("DELETE FROM %s WHERE version = ?", d.quote(d.progressTable()))
+
+	_, err := tx.ExecContext(ctx, query, version)
+	return err
+}
+
+// historyTable returns the name of the execution log table.
+func (d *Driver) historyTable() string {
+	return d.tableName + "_history"
+}
+
+// initHistory creates the execution log table.
+func (d *Driver) initHistory(ctx context.Context) error {
+	query := fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			id BIGINT AUTO_INCREMENT PRIMARY KEY,
+			run_id VARCHAR(255) NOT NULL DEFAULT '',
+			version VARCHAR(255) NOT NULL,
+			name VARCHAR(255) NOT NULL,
+			direction VARCHAR(255) NOT NULL,
+			error TEXT,
+			started_at DATETIME NOT NULL,
+			duration_ms BIGINT NOT NULL DEFAULT 0,
+			applied_by VARCHAR(255) NOT NULL DEFAULT '',
+			hostname VARCHAR(255) NOT NULL DEFAULT '',
+			operator VARCHAR(255) NOT NULL DEFAULT '',
+			build_info VARCHAR(255) NOT NULL DEFAULT '',
+			backup TEXT,
+			change_ticket TEXT,
+			lock_wait_ms BIGINT NOT NULL DEFAULT 0
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci
+	`, d.quote(d.historyTable()))
+
+	if _, err := d.db.ExecContext(ctx, query); err != nil {
+		return err
+	}
+
+	return d.upgradeTable(ctx, d.historyTable(), historyColumns)
+}
+
+// RecordHistory appends a migration execution to the <table>_history log.
+func (d *Driver) RecordHistory(ctx context.Context, e queen.HistoryEntry) error {
+	query := dialect.Insert(d.quote(d.historyTable()), sqlutil.HistoryColumns)
+
+	direction := "up"
+	if e.Down {
+		direction = "down"
+	}
+
+	_, err := d.db.ExecContext(ctx, query, e.RunID, e.Version, e.Name, direction, e.Error, e.StartedAt.UTC(),
+		e.Duration.Milliseconds(), e.AppliedBy, e.Hostname, e.Operator, e.BuildInfo, e.Backup, e.ChangeTicket,
+		e.LockWait.Milliseconds())
+	return err
+}
+
+// GetHistory returns the <table>_history log, oldest entry first.
+func (d *Driver) GetHistory(ctx context.Context) ([]queen.HistoryEntry, error) {
+	query := fmt.Sprintf(`
+		SELECT id, run_id, version, name, direction, COALESCE(error, ''), started_at, duration_ms,
+			applied_by, hostname, operator, build_info, COALESCE(backup, ''), COALESCE(change_ticket, ''), lock_wait_ms
+		FROM %s
+		ORDER BY id ASC
+	`, d.quote(d.historyTable()))
+
+	rows, err := d.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var history []queen.HistoryEntry
+	for rows.Next() {
+		var e queen.HistoryEntry
+		var direction string
+		var durationMS, lockWaitMS int64
+		if err := rows.Scan(&e.ID, &e.RunID, &e.Version, &e.Name, &direction, &e.Error, &e.StartedAt, &durationMS,
+			&e.AppliedBy, &e.Hostname, &e.Operator, &e.BuildInfo, &e.Backup, &e.ChangeTicket, &lockWaitMS); err != nil {
+			return nil, err
+		}
+
+		e.Down = direction == "down"
+		e.Duration = time.Duration(durationMS) * time.Millisecond
+		e.LockWait = time.Duration(lockWaitMS) * time.Millisecond
+
+		history = append(history, e)
+	}
+
+	return history, rows.Err()
+}
+
+// trackingColumns lists columns added to the tracking table after its
+// original layout, in the order they were introduced.
+var trackingColumns = []sqlutil.Column{
+	{Name: "batch", Definition: "INT NOT NULL DEFAULT 0"},
+	{Name: "dirty", Definition: "BOOLEAN NOT NULL DEFAULT FALSE"},
+	{Name: "down_sql", Definition: "TEXT"},
+	{Name: "execution_ms", Definition: "BIGINT NOT NULL DEFAULT 0"},
+	{Name: "applied_by", Definition: "VARCHAR(255) NOT NULL DEFAULT ''"},
+	{Name: "hostname", Definition: "VARCHAR(255) NOT NULL DEFAULT ''"},
+	{Name: "operator", Definition: "VARCHAR(255) NOT NULL DEFAULT ''"},
+	{Name: "build_info", Definition: "VARCHAR(255) NOT NULL DEFAULT ''"},
+	{Name: "metadata", Definition: "TEXT"},
+}
+
+// historyColumns lists columns added to the <table>_history log after its
+// original layout, in the order they were introduced.
+var historyColumns = []sqlutil.Column{
+	{Name: "backup", Definition: "TEXT"},
+	{Name: "change_ticket", Definition: "TEXT"},
+	{Name: "lock_wait_ms", Definition: "BIGINT NOT NULL DEFAULT 0"},
+}
+
+// upgradeTable adds columns missing from tables created by earlier versions.
+//
+// MySQL (unlike MariaDB) has no ADD COLUMN IF NOT EXISTS, so existing
+// columns are looked up in information_schema first.
+func (d *Driver) upgradeTable(ctx context.Context, table string, columns []sqlutil.Column) error {
+	return dialect.UpgradeTable(ctx, d.db, "ALTER TABLE "+d.quote(table)+" ADD COLUMN", columns, `
+		SELECT COLUMN_NAME FROM information_schema.COLUMNS
+		WHERE TABLE_SCHEMA = COALESCE(NULLIF(?, ''), DATABASE()) AND TABLE_NAME = ?
+	`, d.database, table)
+}
+
+// quote quotes the name of a table owned by the driver, qualified with its
+// database if it has one.
+func (d *Driver) quote(name string) string {
+	return dialect.QuoteQualified(d.database, name)
 }
@@ -45,7 +45,7 @@ func TestQuoteIdentifier(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := quoteIdentifier(tt.input)
+			result := dialect.QuoteIdentifier(tt.input)
 			if result != tt.expected {
 				t.Errorf("quoteIdentifier(%q) = %q; want %q", tt.input, result, tt.expected)
 			}
@@ -53,6 +53,21 @@ func TestQuoteIdentifier(t *testing.T) {
 	}
 }
 
+// TestQuoteLiteral tests the string literal quoting of RecordScript.
+func TestQuoteLiteral(t *testing.T) {
+	tests := map[string]string{
+		"plain":     "'plain'",
+		"O'Brien":   "'O''Brien'",
+		`C:\temp\n`: `'C:\\temp\\n'`,
+	}
+
+	for input, expected := range tests {
+		if result := dialect.QuoteLiteral(input); result != expected {
+			t.Errorf("quoteLiteral(%q) = %q; want %q", input, result, expected)
+		}
+	}
+}
+
 // TestDriverCreation tests driver creation functions.
 func TestDriverCreation(t *testing.T) {
 	db := &sql.DB{} // Mock DB for testing
@@ -222,6 +237,56 @@ func TestIntegrationRecordAndGetApplied(t *testing.T) {
 	}
 }
 
+func TestIntegrationRecordWithMeta(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	driver := New(db)
+	ctx := context.Background()
+
+	if err := driver.Init(ctx); err != nil {
+		t.Fatalf("Init() failed: %v", err)
+	}
+
+	m := &queen.Migration{
+		Version: "001",
+		Name:    "create_users",
+		UpSQL:   "CREATE TABLE users (id INTEGER)",
+		DownSQL: "DROP TABLE users",
+	}
+	if err := driver.RecordWithMeta(ctx, m, queen.RecordMeta{Batch: 2, Dirty: true}); err != nil {
+		t.Fatalf("RecordWithMeta() failed: %v", err)
+	}
+
+	// Recording again replaces the dirty marker
+	meta := queen.RecordMeta{
+		Batch:     2,
+		Duration:  1500 * time.Millisecond,
+		AppliedBy: "alice",
+		Hostname:  "build-1",
+		Operator:  "TICKET-7",
+		BuildInfo: "v1.2.3",
+	}
+	if err := driver.RecordWithMeta(ctx, m, meta); err != nil {
+		t.Fatalf("RecordWithMeta() failed: %v", err)
+	}
+
+	applied, err := driver.GetApplied(ctx)
+	if err != nil {
+		t.Fatalf("GetApplied() failed: %v", err)
+	}
+	if len(applied) != 1 {
+		t.Fatalf("expected 1 migration, got %d", len(applied))
+	}
+	a := applied[0]
+	if a.Dirty || a.Batch != 2 || a.Duration != meta.Duration || a.DownSQL != m.DownSQL {
+		t.Errorf("applied = %+v; want clean in batch 2 with duration and DownSQL", a)
+	}
+	if a.AppliedBy != "alice" || a.Hostname != "build-1" || a.Operator != "TICKET-7" || a.BuildInfo != "v1.2.3" {
+		t.Errorf("applied = %+v; want the run metadata", a)
+	}
+}
+
 func TestIntegrationRemove(t *testing.T) {
 	db, cleanup := setupTestDB(t)
 	defer cleanup()
@@ -445,3 +510,31 @@ func TestIntegrationFullMigrationCycle(t *testing.T) {
 		t.Errorf("expected 0 tables after reset, got %d", tableCount)
 	}
 }
+
+func TestIntegrationReplicationPosition(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	driver := New(db)
+
+	before, err := driver.ReplicationPosition(ctx)
+	if err != nil {
+		t.Skip("Binary log position not available:", err)
+	}
+	if before.File == "" || before.Offset <= 0 {
+		t.Fatalf("ReplicationPosition() = %+v; want a binary log file and offset", before)
+	}
+
+	if _, err := db.ExecContext(ctx, "CREATE TABLE test_users (id INT PRIMARY KEY)"); err != nil {
+		t.Fatalf("CREATE TABLE failed: %v", err)
+	}
+
+	after, err := driver.ReplicationPosition(ctx)
+	if err != nil {
+		t.Fatalf("ReplicationPosition() failed: %v", err)
+	}
+	if after.File == before.File && after.Offset <= before.Offset {
+		t.Errorf("Expected the position to advance from %+v, got %+v", before, after)
+	}
+}
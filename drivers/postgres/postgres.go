@@ -4,17 +4,33 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/honeynil/queen"
+	"github.com/honeynil/queen/drivers/internal/permcheck"
+	"github.com/honeynil/queen/drivers/internal/sqlutil"
+	"github.com/honeynil/queen/drivers/internal/views"
+	"github.com/honeynil/queen/internal/split"
 )
 
+// dialect builds the driver's SQL.
+const dialect = sqlutil.PostgreSQL
+
 // Driver implements the queen.Driver interface for PostgreSQL.
 type Driver struct {
 	db        *sql.DB
 	tableName string
 	lockID    int64
+
+	// schema is set for drivers returned by Isolate. Tables are then
+	// qualified with it and transactions use it as search_path.
+	schema string
+
+	// ddlAudit is set by WithDDLAudit.
+	ddlAudit bool
 }
 
 // New creates a new PostgreSQL driver.
@@ -33,28 +49,90 @@ func NewWithTableName(db *sql.DB, tableName string) *Driver {
 	}
 }
 
-// Init creates the migrations tracking table if it doesn't exist.
+// SetTableName sets the migrations table name, and the advisory lock ID
+// derived from it, for queen.Config.TableName.
+func (d *Driver) SetTableName(name string) {
+	d.tableName = name
+	d.lockID = hashTableName(name)
+}
+
+// WithDDLAudit makes Init install an event trigger logging schema changes
+// made outside Queen into <table>_ddl_audit, reported by queen.Drift. Only
+// changes to the current schema are logged, not those of Queen's own
+// tables. Migrations mark their transactions so the trigger skips them,
+// which only drivers with the audit enabled do: enable it on every driver
+// migrating the database.
+//
+// Creating event triggers requires a superuser. The trigger is shared by
+// every session, so it keeps logging when the driver is gone; drop the
+// event triggers <schema>_<table>_ddl_audit and <schema>_<table>_ddl_audit_drop
+// to stop it.
+func (d *Driver) WithDDLAudit() *Driver {
+	d.ddlAudit = true
+	return d
+}
+
+// SupportsFeature reports queen.FeatureDDLAudit as supported only with
+// WithDDLAudit.
+func (d *Driver) SupportsFeature(f queen.Feature) bool {
+	return f != queen.FeatureDDLAudit || d.ddlAudit
+}
+
+// Init creates the migrations tracking table if it doesn't exist, along with
+// the <table>_progress table used by the progress package and the
+// <table>_history execution log. With WithDDLAudit it also installs the
+// DDL audit trigger.
 func (d *Driver) Init(ctx context.Context) error {
 	query := fmt.Sprintf(`
 		CREATE TABLE IF NOT EXISTS %s (
 			version VARCHAR(255) PRIMARY KEY,
 			name VARCHAR(255) NOT NULL,
 			applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
-			checksum VARCHAR(64) NOT NULL
+			checksum VARCHAR(64) NOT NULL,
+			batch INTEGER NOT NULL DEFAULT 0,
+			dirty BOOLEAN NOT NULL DEFAULT FALSE,
+			down_sql TEXT,
+			execution_ms BIGINT NOT NULL DEFAULT 0,
+			applied_by VARCHAR(255) NOT NULL DEFAULT '',
+			hostname VARCHAR(255) NOT NULL DEFAULT '',
+			operator VARCHAR(255) NOT NULL DEFAULT '',
+			build_info VARCHAR(255) NOT NULL DEFAULT '',
+			metadata TEXT
 		)
-	`, quoteIdentifier(d.tableName))
+	`, d.quote(d.tableName))
 
-	_, err := d.db.ExecContext(ctx, query)
-	return err
+	if _, err := d.db.ExecContext(ctx, query); err != nil {
+		return err
+	}
+
+	// Upgrade tables created by earlier versions
+	if err := d.upgradeTable(ctx, d.tableName, trackingColumns); err != nil {
+		return err
+	}
+
+	if err := d.initProgress(ctx); err != nil {
+		return err
+	}
+
+	if err := d.initHistory(ctx); err != nil {
+		return err
+	}
+
+	if d.ddlAudit {
+		return d.initDDLAudit(ctx)
+	}
+
+	return nil
 }
 
 // GetApplied returns all applied migrations sorted by applied_at.
 func (d *Driver) GetApplied(ctx context.Context) ([]queen.Applied, error) {
 	query := fmt.Sprintf(`
-		SELECT version, name, applied_at, checksum
+		SELECT version, name, applied_at, checksum, batch, dirty, COALESCE(down_sql, ''), execution_ms,
+			applied_by, hostname, operator, build_info
 		FROM %s
 		ORDER BY applied_at ASC
-	`, quoteIdentifier(d.tableName))
+	`, d.quote(d.tableName))
 
 	rows, err := d.db.QueryContext(ctx, query)
 	if err != nil {
@@ -65,31 +143,112 @@ func (d *Driver) GetApplied(ctx context.Context) ([]queen.Applied, error) {
 	var applied []queen.Applied
 	for rows.Next() {
 		var a queen.Applied
-		if err := rows.Scan(&a.Version, &a.Name, &a.AppliedAt, &a.Checksum); err != nil {
+		var executionMS int64
+		if err := rows.Scan(&a.Version, &a.Name, &a.AppliedAt, &a.Checksum, &a.Batch, &a.Dirty, &a.DownSQL, &executionMS,
+			&a.AppliedBy, &a.Hostname, &a.Operator, &a.BuildInfo); err != nil {
 			return nil, err
 		}
+		a.Duration = time.Duration(executionMS) * time.Millisecond
 		applied = append(applied, a)
 	}
 
 	return applied, rows.Err()
 }
 
-// Record marks a migration as applied.
+// Record marks a migration as applied, without run metadata.
+// An existing record for the version is replaced.
 func (d *Driver) Record(ctx context.Context, m *queen.Migration) error {
+	return d.RecordWithMeta(ctx, m, queen.RecordMeta{})
+}
+
+// RecordWithMeta marks a migration as applied with the run metadata in
+// meta. An existing record for the version is replaced.
+func (d *Driver) RecordWithMeta(ctx context.Context, m *queen.Migration, meta queen.RecordMeta) error {
+	_, err := d.db.ExecContext(ctx, d.recordQuery(), sqlutil.RecordArgs(m, meta)...)
+	return err
+}
+
+// RecordBatch records every migration like RecordWithMeta, in one
+// transaction.
+func (d *Driver) RecordBatch(ctx context.Context, migrations []*queen.Migration, meta queen.RecordMeta) error {
+	return sqlutil.RecordAll(ctx, d.db, d.recordQuery(), migrations, meta)
+}
+
+func (d *Driver) recordQuery() string {
+	return dialect.Upsert(d.quote(d.tableName), "version", sqlutil.RecordColumns, "applied_at = CURRENT_TIMESTAMP")
+}
+
+// RecordScript returns the statement recording m like Record, with its
+// values inlined, for queen.GenerateScript.
+func (d *Driver) RecordScript(m *queen.Migration, meta queen.RecordMeta) string {
+	lit := dialect.QuoteLiteral
+	return fmt.Sprintf(`INSERT INTO %s (version, name, checksum, batch, dirty, down_sql, execution_ms,
+	applied_by, hostname, operator, build_info)
+VALUES (%s, %s, %s, %d, FALSE, %s, %d, %s, %s, %s, %s);`,
+		d.quote(d.tableName), lit(m.Version), lit(m.Name), lit(m.Checksum()), meta.Batch,
+		lit(m.DownSQL), meta.Duration.Milliseconds(), lit(meta.AppliedBy), lit(meta.Hostname),
+		lit(meta.Operator), lit(meta.BuildInfo))
+}
+
+// SetDirty sets or clears the dirty flag of a migration record.
+func (d *Driver) SetDirty(ctx context.Context, version string, dirty bool) error {
+	query := fmt.Sprintf(`
+		UPDATE %s SET dirty = $1 WHERE version = $2
+	`, d.quote(d.tableName))
+
+	_, err := d.db.ExecContext(ctx, query, dirty, version)
+	return err
+}
+
+// UpdateChecksum sets the stored checksum of a migration record.
+func (d *Driver) UpdateChecksum(ctx context.Context, version, checksum string) error {
+	query := fmt.Sprintf(`
+		UPDATE %s SET checksum = $1 WHERE version = $2
+	`, d.quote(d.tableName))
+
+	_, err := d.db.ExecContext(ctx, query, checksum, version)
+	return err
+}
+
+// SetMetadata stores metadata with a migration record.
+func (d *Driver) SetMetadata(ctx context.Context, version string, data []byte) error {
 	query := fmt.Sprintf(`
-		INSERT INTO %s (version, name, checksum)
-		VALUES ($1, $2, $3)
-	`, quoteIdentifier(d.tableName))
+		UPDATE %s SET metadata = $1 WHERE version = $2
+	`, d.quote(d.tableName))
 
-	_, err := d.db.ExecContext(ctx, query, m.Version, m.Name, m.Checksum())
+	_, err := d.db.ExecContext(ctx, query, string(data), version)
 	return err
 }
 
+// GetMetadata returns the stored metadata by version.
+func (d *Driver) GetMetadata(ctx context.Context) (map[string][]byte, error) {
+	query := fmt.Sprintf(`
+		SELECT version, metadata FROM %s WHERE metadata IS NOT NULL
+	`, d.quote(d.tableName))
+
+	rows, err := d.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	metadata := make(map[string][]byte)
+	for rows.Next() {
+		var version, data string
+		if err := rows.Scan(&version, &data); err != nil {
+			return nil, err
+		}
+		metadata[version] = []byte(data)
+	}
+
+	return metadata, rows.Err()
+}
+
 // Remove removes a migration record (for rollback).
 func (d *Driver) Remove(ctx context.Context, version string) error {
 	query := fmt.Sprintf(`
 		DELETE FROM %s WHERE version = $1
-	`, quoteIdentifier(d.tableName))
+	`, d.quote(d.tableName))
 
 	_, err := d.db.ExecContext(ctx, query, version)
 	return err
@@ -98,25 +257,15 @@ func (d *Driver) Remove(ctx context.Context, version string) error {
 // Lock acquires an advisory lock to prevent concurrent migrations.
 // PostgreSQL advisory locks are automatically released when the connection closes
 // or when explicitly unlocked.
+//
+// pg_try_advisory_lock doesn't wait, so it is polled until timeout, see
+// queen.PollLock. Returns queen.ErrLockTimeout if the lock stays taken.
 func (d *Driver) Lock(ctx context.Context, timeout time.Duration) error {
-	// Set lock timeout
-	_, err := d.db.ExecContext(ctx, fmt.Sprintf("SET lock_timeout = '%dms'", timeout.Milliseconds()))
-	if err != nil {
-		return err
-	}
-
-	// Try to acquire advisory lock
-	var acquired bool
-	err = d.db.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", d.lockID).Scan(&acquired)
-	if err != nil {
-		return err
-	}
-
-	if !acquired {
-		return queen.ErrLockTimeout
-	}
-
-	return nil
+	return queen.PollLock(ctx, timeout, func(ctx context.Context) (bool, error) {
+		var acquired bool
+		err := d.db.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", d.lockID).Scan(&acquired)
+		return acquired, err
+	})
 }
 
 // Unlock releases the advisory lock.
@@ -132,6 +281,20 @@ func (d *Driver) Exec(ctx context.Context, fn func(*sql.Tx) error) error {
 		return err
 	}
 
+	if d.schema != "" {
+		if _, err := tx.ExecContext(ctx, "SET LOCAL search_path TO "+dialect.QuoteIdentifier(d.schema)); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+
+	if d.ddlAudit {
+		if _, err := tx.ExecContext(ctx, "SET LOCAL "+migratingSetting+" = 'on'"); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+
 	if err := fn(tx); err != nil {
 		// Ignore rollback error, return original error
 		_ = tx.Rollback()
@@ -141,11 +304,663 @@ func (d *Driver) Exec(ctx context.Context, fn func(*sql.Tx) error) error {
 	return tx.Commit()
 }
 
+// SplitStatements splits query into individual statements. Running them one
+// at a time keeps a multi-statement NoTransaction migration, such as several
+// CREATE INDEX CONCURRENTLY statements, out of an implicit transaction.
+func (d *Driver) SplitStatements(query string) []string {
+	return split.Split(query, split.Postgres)
+}
+
+// CountPlaceholders returns the number of bind args query takes, counting
+// the highest $n.
+func (d *Driver) CountPlaceholders(query string) int {
+	return split.CountPlaceholders(query, split.Postgres)
+}
+
+// TranslatePlaceholders rewrites the ? placeholders of query to $1, $2, ...
+// and ?? to a literal ?, for migrations with TranslatePlaceholders set.
+func (d *Driver) TranslatePlaceholders(query string) string {
+	return dialect.Rebind(query)
+}
+
+// ExecNoTx executes a function on a dedicated connection without a transaction.
+func (d *Driver) ExecNoTx(ctx context.Context, fn func(*sql.Conn) error) error {
+	conn, err := d.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = conn.Close() }()
+
+	if d.schema != "" {
+		if _, err := conn.ExecContext(ctx, "SET search_path TO "+dialect.QuoteIdentifier(d.schema)); err != nil {
+			return err
+		}
+		// Don't hand the setting on to the next user of the connection
+		defer func() { _, _ = conn.ExecContext(context.Background(), "RESET search_path") }()
+	}
+
+	if d.ddlAudit {
+		if _, err := conn.ExecContext(ctx, "SET "+migratingSetting+" = 'on'"); err != nil {
+			return err
+		}
+		defer func() { _, _ = conn.ExecContext(context.Background(), "RESET "+migratingSetting) }()
+	}
+
+	return fn(conn)
+}
+
 // Close closes the database connection.
 func (d *Driver) Close() error {
 	return d.db.Close()
 }
 
+// Isolate creates the schema name and returns a driver working in it, for
+// queen.TestHelper.Isolated. The driver shares the connection pool: its
+// transactions set search_path, so migrations create their tables in the
+// schema. drop removes the schema with everything in it.
+func (d *Driver) Isolate(ctx context.Context, name string) (queen.Driver, func(context.Context) error, error) {
+	if _, err := d.db.ExecContext(ctx, "CREATE SCHEMA "+dialect.QuoteIdentifier(name)); err != nil {
+		return nil, nil, err
+	}
+
+	drop := func(ctx context.Context) error {
+		_, err := d.db.ExecContext(ctx, "DROP SCHEMA "+dialect.QuoteIdentifier(name)+" CASCADE")
+		return err
+	}
+
+	return &Driver{
+		db:        d.db,
+		tableName: d.tableName,
+		lockID:    hashTableName(name + "." + d.tableName),
+		schema:    name,
+	}, drop, nil
+}
+
+// DropSchema drops the driver's schema, or the current schema, with
+// everything in it, and creates it again empty. Privileges granted on the
+// schema are not restored, and it is owned by the current role afterwards.
+func (d *Driver) DropSchema(ctx context.Context) error {
+	schema := d.schema
+	if schema == "" {
+		if err := d.db.QueryRowContext(ctx, "SELECT current_schema()").Scan(&schema); err != nil {
+			return err
+		}
+	}
+
+	return d.Exec(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, "DROP SCHEMA "+dialect.QuoteIdentifier(schema)+" CASCADE"); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, "CREATE SCHEMA "+dialect.QuoteIdentifier(schema))
+		return err
+	})
+}
+
+// EnsureViews creates or updates the reporting views over the migrations table:
+//
+//   - <table>_batches: one row per Up run (batch) with counts and time range
+//   - <table>_namespaces: applied migrations per version prefix ("users_001" -> "users")
+//   - <table>_failures: dirty records left by failed or interrupted migrations
+//
+// View definitions are versioned in <table>_views and only recreated when
+// they change.
+func (d *Driver) EnsureViews(ctx context.Context) error {
+	table := d.quote(d.tableName)
+	namespace := "CASE WHEN strpos(version, '_') > 0 THEN split_part(version, '_', 1) ELSE '' END"
+
+	defs := []views.Definition{
+		{
+			Name: d.tableName + "_batches",
+			Query: fmt.Sprintf(`SELECT batch, COUNT(*) AS migrations,
+				MIN(applied_at) AS started_at, MAX(applied_at) AS finished_at,
+				SUM(CASE WHEN dirty THEN 1 ELSE 0 END) AS dirty
+				FROM %s GROUP BY batch`, table),
+		},
+		{
+			Name: d.tableName + "_namespaces",
+			Query: fmt.Sprintf(`SELECT %s AS namespace, COUNT(*) AS applied,
+				MAX(applied_at) AS last_applied_at
+				FROM %s GROUP BY %s`, namespace, table, namespace),
+		},
+		{
+			Name: d.tableName + "_failures",
+			Query: fmt.Sprintf(`SELECT version, name, applied_at, batch
+				FROM %s WHERE dirty`, table),
+		},
+	}
+
+	return views.Sync(ctx, d.db, dialect, d.schema, d.tableName+"_views", defs)
+}
+
+// CheckPermissions verifies the role can create, alter and drop tables in
+// the current schema and write the tracking table. It leaves no changes
+// behind.
+func (d *Driver) CheckPermissions(ctx context.Context) error {
+	schema := d.schema
+	if schema == "" {
+		if err := d.db.QueryRowContext(ctx, "SELECT current_schema()").Scan(&schema); err != nil {
+			return err
+		}
+	}
+
+	return permcheck.Check(ctx, d.db, dialect, schema, d.tableName)
+}
+
+// Capabilities reports the server version and installed extensions.
+func (d *Driver) Capabilities(ctx context.Context) (queen.Capabilities, error) {
+	caps := queen.Capabilities{Dialect: "postgres"}
+
+	if err := d.db.QueryRowContext(ctx, "SHOW server_version").Scan(&caps.Version); err != nil {
+		return caps, err
+	}
+
+	rows, err := d.db.QueryContext(ctx, "SELECT extname FROM pg_extension ORDER BY extname")
+	if err != nil {
+		return caps, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return caps, err
+		}
+		caps.Extensions = append(caps.Extensions, name)
+	}
+
+	return caps, rows.Err()
+}
+
+// ListTables returns the tables of the driver's schema, or the current
+// schema.
+func (d *Driver) ListTables(ctx context.Context) ([]string, error) {
+	rows, err := d.db.QueryContext(ctx, `
+		SELECT table_name FROM information_schema.tables
+		WHERE table_schema = COALESCE(NULLIF($1, ''), current_schema()) AND table_type = 'BASE TABLE'
+		ORDER BY table_name
+	`, d.schema)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		tables = append(tables, name)
+	}
+
+	return tables, rows.Err()
+}
+
+// ListColumns returns the columns of table. Type is as written by
+// format_type, e.g. "character varying(255)".
+func (d *Driver) ListColumns(ctx context.Context, table string) ([]queen.Column, error) {
+	oid, _, err := d.relation(ctx, table)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := d.db.QueryContext(ctx, `
+		SELECT a.attname, format_type(a.atttypid, a.atttypmod), NOT a.attnotnull,
+			COALESCE(pg_get_expr(ad.adbin, ad.adrelid), '')
+		FROM pg_attribute a
+		LEFT JOIN pg_attrdef ad ON ad.adrelid = a.attrelid AND ad.adnum = a.attnum
+		WHERE a.attrelid = $1 AND a.attnum > 0 AND NOT a.attisdropped
+		ORDER BY a.attnum
+	`, oid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var columns []queen.Column
+	for rows.Next() {
+		var c queen.Column
+		if err := rows.Scan(&c.Name, &c.Type, &c.Nullable, &c.Default); err != nil {
+			return nil, err
+		}
+		columns = append(columns, c)
+	}
+
+	return columns, rows.Err()
+}
+
+// ListIndexes returns the indexes of table. Expression columns are
+// returned as their expression.
+func (d *Driver) ListIndexes(ctx context.Context, table string) ([]queen.Index, error) {
+	oid, _, err := d.relation(ctx, table)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := d.db.QueryContext(ctx, `
+		SELECT i.relname, ix.indisunique, ix.indisprimary, pg_get_indexdef(ix.indexrelid, k, true)
+		FROM pg_index ix
+		JOIN pg_class i ON i.oid = ix.indexrelid
+		CROSS JOIN generate_series(1, ix.indnkeyatts) AS k
+		WHERE ix.indrelid = $1
+		ORDER BY i.relname, k
+	`, oid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var indexes []queen.Index
+	for rows.Next() {
+		var name, column string
+		var unique, primary bool
+		if err := rows.Scan(&name, &unique, &primary, &column); err != nil {
+			return nil, err
+		}
+
+		if len(indexes) == 0 || indexes[len(indexes)-1].Name != name {
+			indexes = append(indexes, queen.Index{Name: name, Unique: unique, Primary: primary})
+		}
+		last := &indexes[len(indexes)-1]
+		last.Columns = append(last.Columns, column)
+	}
+
+	return indexes, rows.Err()
+}
+
+// ObjectDDL returns a CREATE statement for a table, view, materialized
+// view or index. PostgreSQL doesn't keep the original statement: views and
+// indexes are deparsed by the server, and tables are rebuilt from their
+// columns and constraints, without storage parameters, partitioning or
+// ownership.
+func (d *Driver) ObjectDDL(ctx context.Context, name string) (string, error) {
+	oid, kind, err := d.relation(ctx, name)
+	if err != nil {
+		return "", err
+	}
+
+	var ddl string
+	switch kind {
+	case "v", "m":
+		create := "CREATE VIEW "
+		if kind == "m" {
+			create = "CREATE MATERIALIZED VIEW "
+		}
+		err = d.db.QueryRowContext(ctx, "SELECT pg_get_viewdef($1, true)", oid).Scan(&ddl)
+		ddl = create + d.quote(name) + " AS\n" + ddl
+	case "i":
+		err = d.db.QueryRowContext(ctx, "SELECT pg_get_indexdef($1)", oid).Scan(&ddl)
+	case "r", "p":
+		ddl, err = d.tableDDL(ctx, oid, name)
+	default:
+		return "", fmt.Errorf("%w: %s", queen.ErrObjectNotFound, name)
+	}
+
+	return ddl, err
+}
+
+// tableDDL rebuilds the CREATE TABLE statement of the table oid.
+func (d *Driver) tableDDL(ctx context.Context, oid uint32, name string) (string, error) {
+	columns, err := d.ListColumns(ctx, name)
+	if err != nil {
+		return "", err
+	}
+
+	var lines []string
+	for _, c := range columns {
+		line := "    " + dialect.QuoteIdentifier(c.Name) + " " + c.Type
+		if c.Default != "" {
+			line += " DEFAULT " + c.Default
+		}
+		if !c.Nullable {
+			line += " NOT NULL"
+		}
+		lines = append(lines, line)
+	}
+
+	rows, err := d.db.QueryContext(ctx, `
+		SELECT conname, pg_get_constraintdef(oid, true) FROM pg_constraint
+		WHERE conrelid = $1 AND contype <> 'n'
+		ORDER BY contype, conname
+	`, oid)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var conname, def string
+		if err := rows.Scan(&conname, &def); err != nil {
+			return "", err
+		}
+		lines = append(lines, "    CONSTRAINT "+dialect.QuoteIdentifier(conname)+" "+def)
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+
+	return "CREATE TABLE " + d.quote(name) + " (\n" + strings.Join(lines, ",\n") + "\n)", nil
+}
+
+// relation looks up name in the driver's schema, or the current schema,
+// returning its oid and pg_class.relkind.
+func (d *Driver) relation(ctx context.Context, name string) (uint32, string, error) {
+	var oid uint32
+	var kind string
+	err := d.db.QueryRowContext(ctx, `
+		SELECT c.oid, c.relkind FROM pg_class c
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE n.nspname = COALESCE(NULLIF($1, ''), current_schema()) AND c.relname = $2
+	`, d.schema, name).Scan(&oid, &kind)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, "", fmt.Errorf("%w: %s", queen.ErrObjectNotFound, name)
+	}
+
+	return oid, kind, err
+}
+
+// progressTable returns the name of the table used by the progress package.
+func (d *Driver) progressTable() string {
+	return d.tableName + "_progress"
+}
+
+// initProgress creates the table used by the progress package.
+func (d *Driver) initProgress(ctx context.Context) error {
+	query := fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			version VARCHAR(255) NOT NULL,
+			name VARCHAR(255) NOT NULL,
+			value TEXT NOT NULL,
+			updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (version, name)
+		)
+	`, d.quote(d.progressTable()))
+
+	_, err := d.db.ExecContext(ctx, query)
+	return err
+}
+
+// GetProgress returns the progress value saved under key for version.
+func (d *Driver) GetProgress(ctx context.Context, tx *sql.Tx, version, key string) (string, bool, error) {
+	query := fmt.Sprintf("SELECT value FROM %s WHERE version = $1 AND name = $2", d.quote(d.progressTable()))
+
+	var value string
+	err := tx.QueryRowContext(ctx, query, version, key).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+
+	return value, true, nil
+}
+
+// SetProgress saves a progress value under key for version.
+func (d *Driver) SetProgress(ctx context.Context, tx *sql.Tx, version, key, value string) error {
+	query := fmt.Sprintf(`INSERT INTO %s (version, name, value) VALUES ($1, $2, $3)
+		ON CONFLICT (version, name) DO UPDATE SET value = EXCLUDED.value, updated_at = CURRENT_TIMESTAMP`, d.quote(d.progressTable()))
+
+	_, err := tx.ExecContext(ctx, query, version, key, value)
+	return err
+}
+
+// ClearProgress deletes all progress saved for version.
+func (d *Driver) ClearProgress(ctx context.Context, tx *sql.Tx, version string) error {
+	query := fmt.Sprintf("DELETE FROM %s WHERE version = $1", d.quote(d.progressTable()))
+
+	_, err := tx.ExecContext(ctx, query, version)
+	return err
+}
+
+// historyTable returns the name of the execution log table.
+func (d *Driver) historyTable() string {
+	return d.tableName + "_history"
+}
+
+// initHistory creates the execution log table.
+func (d *Driver) initHistory(ctx context.Context) error {
+	query := fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			id BIGSERIAL PRIMARY KEY,
+			run_id VARCHAR(255) NOT NULL DEFAULT '',
+			version VARCHAR(255) NOT NULL,
+			name VARCHAR(255) NOT NULL,
+			direction VARCHAR(255) NOT NULL,
+			error TEXT,
+			started_at TIMESTAMP NOT NULL,
+			duration_ms BIGINT NOT NULL DEFAULT 0,
+			applied_by VARCHAR(255) NOT NULL DEFAULT '',
+			hostname VARCHAR(255) NOT NULL DEFAULT '',
+			operator VARCHAR(255) NOT NULL DEFAULT '',
+			build_info VARCHAR(255) NOT NULL DEFAULT '',
+			backup TEXT,
+			change_ticket TEXT,
+			lock_wait_ms BIGINT NOT NULL DEFAULT 0
+		)
+	`, d.quote(d.historyTable()))
+
+	if _, err := d.db.ExecContext(ctx, query); err != nil {
+		return err
+	}
+
+	return d.upgradeTable(ctx, d.historyTable(), historyColumns)
+}
+
+// RecordHistory appends a migration execution to the <table>_history log.
+func (d *Driver) RecordHistory(ctx context.Context, e queen.HistoryEntry) error {
+	query := dialect.Insert(d.quote(d.historyTable()), sqlutil.HistoryColumns)
+
+	direction := "up"
+	if e.Down {
+		direction = "down"
+	}
+
+	_, err := d.db.ExecContext(ctx, query, e.RunID, e.Version, e.Name, direction, e.Error, e.StartedAt.UTC(),
+		e.Duration.Milliseconds(), e.AppliedBy, e.Hostname, e.Operator, e.BuildInfo, e.Backup, e.ChangeTicket,
+		e.LockWait.Milliseconds())
+	return err
+}
+
+// GetHistory returns the <table>_history log, oldest entry first.
+func (d *Driver) GetHistory(ctx context.Context) ([]queen.HistoryEntry, error) {
+	query := fmt.Sprintf(`
+		SELECT id, run_id, version, name, direction, COALESCE(error, ''), started_at, duration_ms,
+			applied_by, hostname, operator, build_info, COALESCE(backup, ''), COALESCE(change_ticket, ''), lock_wait_ms
+		FROM %s
+		ORDER BY id ASC
+	`, d.quote(d.historyTable()))
+
+	rows, err := d.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var history []queen.HistoryEntry
+	for rows.Next() {
+		var e queen.HistoryEntry
+		var direction string
+		var durationMS, lockWaitMS int64
+		if err := rows.Scan(&e.ID, &e.RunID, &e.Version, &e.Name, &direction, &e.Error, &e.StartedAt, &durationMS,
+			&e.AppliedBy, &e.Hostname, &e.Operator, &e.BuildInfo, &e.Backup, &e.ChangeTicket, &lockWaitMS); err != nil {
+			return nil, err
+		}
+
+		e.Down = direction == "down"
+		e.Duration = time.Duration(durationMS) * time.Millisecond
+		e.LockWait = time.Duration(lockWaitMS) * time.Millisecond
+
+		history = append(history, e)
+	}
+
+	return history, rows.Err()
+}
+
+// migratingSetting is the setting marking sessions running migrations,
+// whose schema changes the DDL audit trigger skips.
+const migratingSetting = "queen.migrating"
+
+// ddlAuditTable returns the name of the table logging schema changes made
+// outside Queen.
+func (d *Driver) ddlAuditTable() string {
+	return d.tableName + "_ddl_audit"
+}
+
+// initDDLAudit creates the <table>_ddl_audit table and the event triggers
+// filling it. Event trigger names are database-wide, so they include the
+// schema.
+func (d *Driver) initDDLAudit(ctx context.Context) error {
+	schema := d.schema
+	if schema == "" {
+		if err := d.db.QueryRowContext(ctx, "SELECT current_schema()").Scan(&schema); err != nil {
+			return err
+		}
+	}
+
+	// The trigger fires in every session, whatever its search_path
+	table := dialect.QuoteIdentifier(schema) + "." + dialect.QuoteIdentifier(d.ddlAuditTable())
+	function := table
+	own := schema + "." + d.tableName
+
+	// The INSERT is repeated for both events: ddl_command_end doesn't list
+	// dropped objects, and sql_drop only lists those.
+	insert := `INSERT INTO %s (command_tag, object_type, object_identity, statement, username)
+			SELECT TG_TAG, object_type, object_identity, current_query(), session_user
+			FROM %s()
+			WHERE %sschema_name = %s AND position(%s in object_identity) <> 1;`
+	audit := func(source, filter string) string {
+		return fmt.Sprintf(insert, table, source, filter, dialect.QuoteLiteral(schema), dialect.QuoteLiteral(own))
+	}
+
+	return d.Exec(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf(`
+			CREATE TABLE IF NOT EXISTS %s (
+				id BIGSERIAL PRIMARY KEY,
+				occurred_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				command_tag VARCHAR(255) NOT NULL,
+				object_type VARCHAR(255) NOT NULL,
+				object_identity TEXT NOT NULL,
+				statement TEXT NOT NULL,
+				username VARCHAR(255) NOT NULL,
+				acknowledged BOOLEAN NOT NULL DEFAULT FALSE
+			)
+		`, table)); err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf(`
+			CREATE OR REPLACE FUNCTION %s() RETURNS event_trigger LANGUAGE plpgsql AS $queen$
+			BEGIN
+				IF current_setting('%s', true) = 'on' THEN
+					RETURN;
+				END IF;
+				IF TG_EVENT = 'sql_drop' THEN
+					%s
+				ELSE
+					%s
+				END IF;
+			END
+			$queen$
+		`, function, migratingSetting, audit("pg_event_trigger_dropped_objects", "original AND "),
+			audit("pg_event_trigger_ddl_commands", ""))); err != nil {
+			return err
+		}
+
+		// CREATE EVENT TRIGGER has neither IF NOT EXISTS nor OR REPLACE
+		trigger := schema + "_" + d.ddlAuditTable()
+		for _, t := range []struct{ name, event string }{
+			{trigger, "ddl_command_end"},
+			{trigger + "_drop", "sql_drop"},
+		} {
+			var exists bool
+			if err := tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM pg_event_trigger WHERE evtname = $1)",
+				t.name).Scan(&exists); err != nil {
+				return err
+			}
+			if exists {
+				continue
+			}
+			if _, err := tx.ExecContext(ctx, fmt.Sprintf("CREATE EVENT TRIGGER %s ON %s EXECUTE PROCEDURE %s()",
+				dialect.QuoteIdentifier(t.name), t.event, function)); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// GetDDLEvents returns the schema changes logged by the DDL audit trigger
+// and not acknowledged yet, oldest first.
+func (d *Driver) GetDDLEvents(ctx context.Context) ([]queen.DDLEvent, error) {
+	query := fmt.Sprintf(`
+		SELECT id, occurred_at, command_tag, object_type, object_identity, statement, username
+		FROM %s
+		WHERE NOT acknowledged
+		ORDER BY id ASC
+	`, d.quote(d.ddlAuditTable()))
+
+	rows, err := d.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var events []queen.DDLEvent
+	for rows.Next() {
+		var e queen.DDLEvent
+		if err := rows.Scan(&e.ID, &e.OccurredAt, &e.Command, &e.ObjectType, &e.Object, &e.Statement, &e.User); err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+
+	return events, rows.Err()
+}
+
+// AcknowledgeDDLEvents marks logged schema changes up to id as reconciled.
+// They stay in <table>_ddl_audit for the record.
+func (d *Driver) AcknowledgeDDLEvents(ctx context.Context, id int64) error {
+	query := fmt.Sprintf(`UPDATE %s SET acknowledged = TRUE WHERE id <= $1 AND NOT acknowledged`,
+		d.quote(d.ddlAuditTable()))
+	_, err := d.db.ExecContext(ctx, query, id)
+	return err
+}
+
+// trackingColumns lists columns added to the tracking table after its
+// original layout, in the order they were introduced.
+var trackingColumns = []sqlutil.Column{
+	{Name: "batch", Definition: "INTEGER NOT NULL DEFAULT 0"},
+	{Name: "dirty", Definition: "BOOLEAN NOT NULL DEFAULT FALSE"},
+	{Name: "down_sql", Definition: "TEXT"},
+	{Name: "execution_ms", Definition: "BIGINT NOT NULL DEFAULT 0"},
+	{Name: "applied_by", Definition: "VARCHAR(255) NOT NULL DEFAULT ''"},
+	{Name: "hostname", Definition: "VARCHAR(255) NOT NULL DEFAULT ''"},
+	{Name: "operator", Definition: "VARCHAR(255) NOT NULL DEFAULT ''"},
+	{Name: "build_info", Definition: "VARCHAR(255) NOT NULL DEFAULT ''"},
+	{Name: "metadata", Definition: "TEXT"},
+}
+
+// historyColumns lists columns added to the <table>_history log after its
+// original layout, in the order they were introduced.
+var historyColumns = []sqlutil.Column{
+	{Name: "backup", Definition: "TEXT"},
+	{Name: "change_ticket", Definition: "TEXT"},
+	{Name: "lock_wait_ms", Definition: "BIGINT NOT NULL DEFAULT 0"},
+}
+
+// upgradeTable adds columns missing from tables created by earlier versions.
+//
+// Existing columns are looked up first: ALTER TABLE takes an ACCESS EXCLUSIVE
+// lock even when ADD COLUMN IF NOT EXISTS turns out to be a no-op.
+func (d *Driver) upgradeTable(ctx context.Context, table string, columns []sqlutil.Column) error {
+	return dialect.UpgradeTable(ctx, d.db, "ALTER TABLE "+d.quote(table)+" ADD COLUMN IF NOT EXISTS", columns, `
+		SELECT column_name FROM information_schema.columns
+		WHERE table_schema = COALESCE(NULLIF($1, ''), current_schema()) AND table_name = $2
+	`, d.schema, table)
+}
+
 // hashTableName creates a unique int64 hash from the table name for advisory locks.
 // This ensures different migration tables use different locks.
 func hashTableName(name string) int64 {
@@ -156,18 +971,8 @@ func hashTableName(name string) int64 {
 	return hash
 }
 
-// quoteIdentifier quotes a SQL identifier (table name, column name) to prevent SQL injection.
-// In PostgreSQL, identifiers are quoted with double quotes.
-func quoteIdentifier(name string) string {
-	// Replace any existing double quotes with two double quotes (escaping)
-	// and wrap the identifier in double quotes
-	escaped := ""
-	for _, c := range name {
-		if c == '"' {
-			escaped += "\"\""
-		} else {
-			escaped += string(c)
-		}
-	}
-	return `"` + escaped + `"`
+// quote quotes the name of a table owned by the driver, qualified with its
+// schema if it has one.
+func (d *Driver) quote(name string) string {
+	return dialect.QuoteQualified(d.schema, name)
 }
@@ -0,0 +1,298 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/lib/pq"
+
+	"github.com/honeynil/queen"
+)
+
+// setupTestDB connects to the database in DATABASE_URL. Tests are skipped
+// if it is unset or the database is not reachable.
+func setupTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	dsn := os.Getenv("DATABASE_URL")
+	if dsn == "" {
+		t.Skip("DATABASE_URL not set")
+	}
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Skip("PostgreSQL not available:", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		t.Skip("PostgreSQL not available:", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return db
+}
+
+// isolated returns a driver working in a schema of its own, dropped when
+// the test ends.
+func isolated(t *testing.T, db *sql.DB) (*Driver, string) {
+	t.Helper()
+
+	ctx := context.Background()
+	schema := fmt.Sprintf("queen_test_%d", time.Now().UnixNano())
+
+	d, drop, err := New(db).Isolate(ctx, schema)
+	if err != nil {
+		t.Fatalf("Isolate failed: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := drop(context.Background()); err != nil {
+			t.Errorf("Dropping schema %s failed: %v", schema, err)
+		}
+	})
+
+	return d.(*Driver), schema
+}
+
+func TestIntegrationIsolate(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+
+	schema := fmt.Sprintf("queen_test_%d", time.Now().UnixNano())
+	d, drop, err := New(db).Isolate(ctx, schema)
+	if err != nil {
+		t.Fatalf("Isolate failed: %v", err)
+	}
+	dropped := false
+	defer func() {
+		if !dropped {
+			_ = drop(ctx)
+		}
+	}()
+
+	if err := d.Init(ctx); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+
+	// Unqualified names in migrations resolve to the schema
+	err = d.Exec(ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, "CREATE TABLE widgets (id INT)")
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+
+	tables, err := d.(*Driver).ListTables(ctx)
+	if err != nil {
+		t.Fatalf("ListTables failed: %v", err)
+	}
+	for _, want := range []string{"queen_migrations", "queen_migrations_history", "queen_migrations_progress", "widgets"} {
+		if !slices.Contains(tables, want) {
+			t.Errorf("Expected %s in the schema, got %v", want, tables)
+		}
+	}
+
+	m := &queen.Migration{Version: "001", Name: "create_widgets", UpSQL: "CREATE TABLE widgets (id INT)"}
+	if err := d.Record(ctx, m); err != nil {
+		t.Fatalf("Record failed: %v", err)
+	}
+	applied, err := d.GetApplied(ctx)
+	if err != nil {
+		t.Fatalf("GetApplied failed: %v", err)
+	}
+	if len(applied) != 1 || applied[0].Version != "001" {
+		t.Errorf("Expected 001 applied in the schema, got %+v", applied)
+	}
+
+	dropped = true
+	if err := drop(ctx); err != nil {
+		t.Fatalf("drop failed: %v", err)
+	}
+
+	var exists bool
+	err = db.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM pg_namespace WHERE nspname = $1)", schema).Scan(&exists)
+	if err != nil {
+		t.Fatalf("Failed to look up schema: %v", err)
+	}
+	if exists {
+		t.Errorf("Expected schema %s dropped", schema)
+	}
+}
+
+func TestIntegrationIntrospection(t *testing.T) {
+	db := setupTestDB(t)
+	d, _ := isolated(t, db)
+	ctx := context.Background()
+
+	err := d.Exec(ctx, func(tx *sql.Tx) error {
+		for _, stmt := range []string{
+			"CREATE TABLE widgets (id INT PRIMARY KEY, name VARCHAR(50) NOT NULL DEFAULT 'x', price NUMERIC)",
+			"CREATE UNIQUE INDEX widgets_name_idx ON widgets (lower(name))",
+			"CREATE VIEW widget_names AS SELECT name FROM widgets",
+		} {
+			if _, err := tx.ExecContext(ctx, stmt); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+
+	tables, err := d.ListTables(ctx)
+	if err != nil {
+		t.Fatalf("ListTables failed: %v", err)
+	}
+	if !slices.Equal(tables, []string{"widgets"}) {
+		t.Errorf("ListTables = %v; want [widgets]", tables)
+	}
+
+	columns, err := d.ListColumns(ctx, "widgets")
+	if err != nil {
+		t.Fatalf("ListColumns failed: %v", err)
+	}
+	want := []queen.Column{
+		{Name: "id", Type: "integer"},
+		{Name: "name", Type: "character varying(50)", Default: "'x'::character varying"},
+		{Name: "price", Type: "numeric", Nullable: true},
+	}
+	if !slices.Equal(columns, want) {
+		t.Errorf("ListColumns = %+v; want %+v", columns, want)
+	}
+
+	indexes, err := d.ListIndexes(ctx, "widgets")
+	if err != nil {
+		t.Fatalf("ListIndexes failed: %v", err)
+	}
+	if len(indexes) != 2 {
+		t.Fatalf("ListIndexes = %+v; want the primary key and widgets_name_idx", indexes)
+	}
+	if idx := indexes[0]; idx.Name != "widgets_name_idx" || !idx.Unique || idx.Primary ||
+		len(idx.Columns) != 1 || !strings.Contains(idx.Columns[0], "lower(") {
+		t.Errorf("Expression index = %+v", idx)
+	}
+	if idx := indexes[1]; idx.Name != "widgets_pkey" || !idx.Primary || !slices.Equal(idx.Columns, []string{"id"}) {
+		t.Errorf("Primary key = %+v", idx)
+	}
+
+	ddl, err := d.ObjectDDL(ctx, "widgets")
+	if err != nil {
+		t.Fatalf("ObjectDDL(widgets) failed: %v", err)
+	}
+	for _, part := range []string{"CREATE TABLE", `"name" character varying(50) DEFAULT 'x'::character varying NOT NULL`,
+		`"price" numeric`, "PRIMARY KEY (id)"} {
+		if !strings.Contains(ddl, part) {
+			t.Errorf("ObjectDDL(widgets) = %s; want it to contain %s", ddl, part)
+		}
+	}
+
+	if ddl, err := d.ObjectDDL(ctx, "widget_names"); err != nil || !strings.HasPrefix(ddl, "CREATE VIEW") {
+		t.Errorf("ObjectDDL(widget_names) = %q, %v; want a CREATE VIEW", ddl, err)
+	}
+	if ddl, err := d.ObjectDDL(ctx, "widgets_name_idx"); err != nil || !strings.HasPrefix(ddl, "CREATE UNIQUE INDEX") {
+		t.Errorf("ObjectDDL(widgets_name_idx) = %q, %v; want a CREATE UNIQUE INDEX", ddl, err)
+	}
+	if _, err := d.ObjectDDL(ctx, "missing"); !errors.Is(err, queen.ErrObjectNotFound) {
+		t.Errorf("ObjectDDL(missing) = %v; want ErrObjectNotFound", err)
+	}
+}
+
+func TestIntegrationDDLAudit(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+
+	var superuser bool
+	if err := db.QueryRowContext(ctx, "SELECT rolsuper FROM pg_roles WHERE rolname = current_user").Scan(&superuser); err != nil {
+		t.Fatalf("Failed to look up role: %v", err)
+	}
+	if !superuser {
+		t.Skip("event triggers need a superuser")
+	}
+
+	d, schema := isolated(t, db)
+	d.WithDDLAudit()
+
+	// Cleanups run last in, first out: drop the triggers before the schema
+	trigger := schema + "_" + d.ddlAuditTable()
+	t.Cleanup(func() {
+		for _, name := range []string{trigger, trigger + "_drop"} {
+			_, _ = db.ExecContext(context.Background(), "DROP EVENT TRIGGER IF EXISTS "+dialect.QuoteIdentifier(name))
+		}
+	})
+
+	if !d.SupportsFeature(queen.FeatureDDLAudit) {
+		t.Fatal("Expected FeatureDDLAudit with WithDDLAudit")
+	}
+	if err := d.Init(ctx); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	// Init is idempotent, the triggers already exist
+	if err := d.Init(ctx); err != nil {
+		t.Fatalf("Second Init failed: %v", err)
+	}
+
+	// Migrations are not logged
+	err := d.Exec(ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, "CREATE TABLE migrated (id INT)")
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	if err := d.Record(ctx, &queen.Migration{Version: "001", Name: "migrated", UpSQL: "CREATE TABLE migrated (id INT)"}); err != nil {
+		t.Fatalf("Record failed: %v", err)
+	}
+
+	// Changes made outside Queen are
+	for _, stmt := range []string{
+		"CREATE TABLE " + schema + ".manual (id INT)",
+		"DROP TABLE " + schema + ".manual",
+	} {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			t.Fatalf("%s failed: %v", stmt, err)
+		}
+	}
+
+	events, err := d.GetDDLEvents(ctx)
+	if err != nil {
+		t.Fatalf("GetDDLEvents failed: %v", err)
+	}
+
+	var commands []string
+	for _, e := range events {
+		if e.Object != schema+".manual" {
+			t.Errorf("Unexpected event for %s: %+v", e.Object, e)
+			continue
+		}
+		commands = append(commands, e.Command)
+		if e.ObjectType != "table" || e.User == "" || !strings.Contains(e.Statement, "manual") {
+			t.Errorf("Incomplete event: %+v", e)
+		}
+	}
+	if !slices.Equal(commands, []string{"CREATE TABLE", "DROP TABLE"}) {
+		t.Fatalf("Logged commands = %v; want CREATE TABLE, DROP TABLE", commands)
+	}
+
+	if err := d.AcknowledgeDDLEvents(ctx, events[len(events)-1].ID); err != nil {
+		t.Fatalf("AcknowledgeDDLEvents failed: %v", err)
+	}
+	events, err = d.GetDDLEvents(ctx)
+	if err != nil {
+		t.Fatalf("GetDDLEvents failed: %v", err)
+	}
+	if len(events) != 0 {
+		t.Errorf("Expected no events after acknowledging, got %+v", events)
+	}
+}
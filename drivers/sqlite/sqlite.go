@@ -55,13 +55,23 @@ package sqlite
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
+	"errors"
 	"fmt"
+	"os"
 	"strings"
 	"time"
 
 	"github.com/honeynil/queen"
+	"github.com/honeynil/queen/drivers/internal/permcheck"
+	"github.com/honeynil/queen/drivers/internal/sqlutil"
+	"github.com/honeynil/queen/drivers/internal/views"
+	"github.com/honeynil/queen/internal/split"
 )
 
+// dialect builds the driver's SQL.
+const dialect = sqlutil.SQLite
+
 // Driver implements the queen.Driver interface for SQLite.
 //
 // The driver is thread-safe for concurrent reads, but SQLite's database-level
@@ -108,14 +118,29 @@ func NewWithTableName(db *sql.DB, tableName string) *Driver {
 	}
 }
 
-// Init creates the migrations tracking table if it doesn't exist.
+// SetTableName sets the migrations table name, for queen.Config.TableName.
+func (d *Driver) SetTableName(name string) {
+	d.tableName = name
+}
+
+// Init creates the migrations tracking table if it doesn't exist, along with
+// the <table>_progress table used by the progress package and the
+// <table>_history execution log.
 //
 // The table schema:
 //   - version: TEXT PRIMARY KEY - unique migration version
 //   - name: TEXT NOT NULL - human-readable migration name
 //   - applied_at: TEXT - ISO8601 timestamp when migration was applied
 //   - checksum: TEXT - hash of migration content for validation
-//
+//   - batch: INTEGER - number of the Up run that applied the migration
+//   - dirty: INTEGER - set while a migration runs and left set if it fails midway
+//   - down_sql: TEXT - rollback script, so Down works after the migration is removed from code
+//   - execution_ms: INTEGER - how long the migration took to execute
+//   - applied_by, hostname, operator: TEXT - who applied the migration and from where
+//   - build_info: TEXT - module version and VCS revision of the binary that applied it
+//   - metadata: TEXT - extension fields encoded by Config.MetadataSerializer
+//
+// Tables created by earlier versions are upgraded with any missing columns.
 // This method is idempotent and safe to call multiple times.
 //
 // Note: SQLite doesn't have a native TIMESTAMP type. We use TEXT with
@@ -126,12 +151,32 @@ func (d *Driver) Init(ctx context.Context) error {
 			version TEXT PRIMARY KEY,
 			name TEXT NOT NULL,
 			applied_at TEXT NOT NULL DEFAULT (datetime('now')),
-			checksum TEXT NOT NULL
+			checksum TEXT NOT NULL,
+			batch INTEGER NOT NULL DEFAULT 0,
+			dirty INTEGER NOT NULL DEFAULT 0,
+			down_sql TEXT,
+			execution_ms INTEGER NOT NULL DEFAULT 0,
+			applied_by TEXT NOT NULL DEFAULT '',
+			hostname TEXT NOT NULL DEFAULT '',
+			operator TEXT NOT NULL DEFAULT '',
+			build_info TEXT NOT NULL DEFAULT '',
+			metadata TEXT
 		) WITHOUT ROWID
-	`, quoteIdentifier(d.tableName))
+	`, dialect.QuoteIdentifier(d.tableName))
 
-	_, err := d.db.ExecContext(ctx, query)
-	return err
+	if _, err := d.db.ExecContext(ctx, query); err != nil {
+		return err
+	}
+
+	if err := d.upgradeTable(ctx, d.tableName, trackingColumns); err != nil {
+		return err
+	}
+
+	if err := d.initProgress(ctx); err != nil {
+		return err
+	}
+
+	return d.initHistory(ctx)
 }
 
 // GetApplied returns all applied migrations sorted by applied_at in ascending order.
@@ -143,10 +188,11 @@ func (d *Driver) Init(ctx context.Context) error {
 // to time.Time for consistency with other drivers.
 func (d *Driver) GetApplied(ctx context.Context) ([]queen.Applied, error) {
 	query := fmt.Sprintf(`
-		SELECT version, name, applied_at, checksum
+		SELECT version, name, applied_at, checksum, batch, dirty, COALESCE(down_sql, ''), execution_ms,
+			applied_by, hostname, operator, build_info
 		FROM %s
 		ORDER BY applied_at ASC
-	`, quoteIdentifier(d.tableName))
+	`, dialect.QuoteIdentifier(d.tableName))
 
 	rows, err := d.db.QueryContext(ctx, query)
 	if err != nil {
@@ -157,8 +203,10 @@ func (d *Driver) GetApplied(ctx context.Context) ([]queen.Applied, error) {
 	var applied []queen.Applied
 	for rows.Next() {
 		var a queen.Applied
+		var executionMS int64
 		var appliedAtStr string
-		if err := rows.Scan(&a.Version, &a.Name, &appliedAtStr, &a.Checksum); err != nil {
+		if err := rows.Scan(&a.Version, &a.Name, &appliedAtStr, &a.Checksum, &a.Batch, &a.Dirty, &a.DownSQL, &executionMS,
+			&a.AppliedBy, &a.Hostname, &a.Operator, &a.BuildInfo); err != nil {
 			return nil, err
 		}
 
@@ -169,6 +217,7 @@ func (d *Driver) GetApplied(ctx context.Context) ([]queen.Applied, error) {
 			return nil, fmt.Errorf("failed to parse applied_at timestamp: %w", err)
 		}
 		a.AppliedAt = appliedAt
+		a.Duration = time.Duration(executionMS) * time.Millisecond
 
 		applied = append(applied, a)
 	}
@@ -176,29 +225,102 @@ func (d *Driver) GetApplied(ctx context.Context) ([]queen.Applied, error) {
 	return applied, rows.Err()
 }
 
-// Record marks a migration as applied in the database.
-//
-// This should be called after successfully executing a migration's up function.
-// The checksum is automatically computed from the migration content.
-//
-// The timestamp is automatically set by SQLite to the current time.
+// Record marks a migration as applied, without run metadata.
+// An existing record for the version is replaced.
 func (d *Driver) Record(ctx context.Context, m *queen.Migration) error {
+	return d.RecordWithMeta(ctx, m, queen.RecordMeta{})
+}
+
+// RecordWithMeta marks a migration as applied with the run metadata in
+// meta. An existing record for the version is replaced.
+func (d *Driver) RecordWithMeta(ctx context.Context, m *queen.Migration, meta queen.RecordMeta) error {
+	_, err := d.db.ExecContext(ctx, d.recordQuery(), sqlutil.RecordArgs(m, meta)...)
+	return err
+}
+
+// RecordBatch records every migration like RecordWithMeta, in one
+// transaction.
+func (d *Driver) RecordBatch(ctx context.Context, migrations []*queen.Migration, meta queen.RecordMeta) error {
+	return sqlutil.RecordAll(ctx, d.db, d.recordQuery(), migrations, meta)
+}
+
+func (d *Driver) recordQuery() string {
+	return dialect.Upsert(dialect.QuoteIdentifier(d.tableName), "version", sqlutil.RecordColumns, "applied_at = datetime('now')")
+}
+
+// RecordScript returns the statement recording m like Record, with its
+// values inlined, for queen.GenerateScript.
+func (d *Driver) RecordScript(m *queen.Migration, meta queen.RecordMeta) string {
+	lit := dialect.QuoteLiteral
+	return fmt.Sprintf(`INSERT INTO %s (version, name, checksum, batch, dirty, down_sql, execution_ms,
+	applied_by, hostname, operator, build_info)
+VALUES (%s, %s, %s, %d, 0, %s, %d, %s, %s, %s, %s);`,
+		dialect.QuoteIdentifier(d.tableName), lit(m.Version), lit(m.Name), lit(m.Checksum()), meta.Batch,
+		lit(m.DownSQL), meta.Duration.Milliseconds(), lit(meta.AppliedBy), lit(meta.Hostname),
+		lit(meta.Operator), lit(meta.BuildInfo))
+}
+
+// SetDirty sets or clears the dirty flag of a migration record.
+func (d *Driver) SetDirty(ctx context.Context, version string, dirty bool) error {
 	query := fmt.Sprintf(`
-		INSERT INTO %s (version, name, checksum)
-		VALUES (?, ?, ?)
-	`, quoteIdentifier(d.tableName))
+		UPDATE %s SET dirty = ? WHERE version = ?
+	`, dialect.QuoteIdentifier(d.tableName))
 
-	_, err := d.db.ExecContext(ctx, query, m.Version, m.Name, m.Checksum())
+	_, err := d.db.ExecContext(ctx, query, dirty, version)
 	return err
 }
 
+// UpdateChecksum sets the stored checksum of a migration record.
+func (d *Driver) UpdateChecksum(ctx context.Context, version, checksum string) error {
+	query := fmt.Sprintf(`
+		UPDATE %s SET checksum = ? WHERE version = ?
+	`, dialect.QuoteIdentifier(d.tableName))
+
+	_, err := d.db.ExecContext(ctx, query, checksum, version)
+	return err
+}
+
+// SetMetadata stores metadata with a migration record.
+func (d *Driver) SetMetadata(ctx context.Context, version string, data []byte) error {
+	query := fmt.Sprintf(`
+		UPDATE %s SET metadata = ? WHERE version = ?
+	`, dialect.QuoteIdentifier(d.tableName))
+
+	_, err := d.db.ExecContext(ctx, query, string(data), version)
+	return err
+}
+
+// GetMetadata returns the stored metadata by version.
+func (d *Driver) GetMetadata(ctx context.Context) (map[string][]byte, error) {
+	query := fmt.Sprintf(`
+		SELECT version, metadata FROM %s WHERE metadata IS NOT NULL
+	`, dialect.QuoteIdentifier(d.tableName))
+
+	rows, err := d.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	metadata := make(map[string][]byte)
+	for rows.Next() {
+		var version, data string
+		if err := rows.Scan(&version, &data); err != nil {
+			return nil, err
+		}
+		metadata[version] = []byte(data)
+	}
+
+	return metadata, rows.Err()
+}
+
 // Remove removes a migration record from the database.
 //
 // This should be called after successfully rolling back a migration's down function.
 func (d *Driver) Remove(ctx context.Context, version string) error {
 	query := fmt.Sprintf(`
 		DELETE FROM %s WHERE version = ?
-	`, quoteIdentifier(d.tableName))
+	`, dialect.QuoteIdentifier(d.tableName))
 
 	_, err := d.db.ExecContext(ctx, query, version)
 	return err
@@ -234,7 +356,7 @@ func (d *Driver) Lock(ctx context.Context, timeout time.Duration) error {
 	tx, err := d.db.BeginTx(ctx, nil)
 	if err != nil {
 		if strings.Contains(err.Error(), "database is locked") {
-			return queen.ErrLockTimeout
+			return fmt.Errorf("%w: %w", queen.ErrLockTimeout, err)
 		}
 		return fmt.Errorf("failed to begin lock transaction: %w", err)
 	}
@@ -244,7 +366,7 @@ func (d *Driver) Lock(ctx context.Context, timeout time.Duration) error {
 	if err != nil {
 		_ = tx.Rollback()
 		if strings.Contains(err.Error(), "database is locked") {
-			return queen.ErrLockTimeout
+			return fmt.Errorf("%w: %w", queen.ErrLockTimeout, err)
 		}
 		return fmt.Errorf("failed to acquire exclusive lock: %w", err)
 	}
@@ -311,6 +433,30 @@ func (d *Driver) Exec(ctx context.Context, fn func(*sql.Tx) error) error {
 	return tx.Commit()
 }
 
+// SplitStatements splits query into individual statements, keeping
+// CREATE TRIGGER bodies intact, so multi-statement migrations work with
+// SQLite drivers that execute only the first statement.
+func (d *Driver) SplitStatements(query string) []string {
+	return split.Split(query, split.SQLite)
+}
+
+// CountPlaceholders returns the number of bind args query takes, counting
+// its ?, ?NNN, :name, @name and $name parameters.
+func (d *Driver) CountPlaceholders(query string) int {
+	return split.CountPlaceholders(query, split.SQLite)
+}
+
+// ExecNoTx executes a function on a dedicated connection without a transaction.
+func (d *Driver) ExecNoTx(ctx context.Context, fn func(*sql.Conn) error) error {
+	conn, err := d.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = conn.Close() }()
+
+	return fn(conn)
+}
+
 // Close closes the database connection.
 //
 // If you're using a file-based database (not :memory:), the database file
@@ -319,15 +465,428 @@ func (d *Driver) Close() error {
 	return d.db.Close()
 }
 
-// quoteIdentifier quotes a SQL identifier (table name, column name) to prevent SQL injection.
-//
-// In SQLite, identifiers can be quoted with double quotes ("), square brackets [],
-// or backticks `. This function uses double quotes as it's the SQL standard.
-//
-// Examples:
-//   - users -> "users"
-//   - my"table -> "my""table"
-func quoteIdentifier(name string) string {
-	escaped := strings.ReplaceAll(name, `"`, `""`)
-	return `"` + escaped + `"`
+// DropSchema drops every table and view of the database, with foreign key
+// enforcement disabled so the order doesn't matter. Indexes and triggers go
+// with their tables.
+func (d *Driver) DropSchema(ctx context.Context) error {
+	conn, err := d.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	rows, err := conn.QueryContext(ctx, `
+		SELECT type, name FROM sqlite_master
+		WHERE type IN ('table', 'view') AND name NOT LIKE 'sqlite_%'
+	`)
+	if err != nil {
+		return err
+	}
+
+	var drops []string
+	for rows.Next() {
+		var kind, name string
+		if err := rows.Scan(&kind, &name); err != nil {
+			_ = rows.Close()
+			return err
+		}
+		drops = append(drops, fmt.Sprintf("DROP %s IF EXISTS %s", strings.ToUpper(kind), dialect.QuoteIdentifier(name)))
+	}
+	_ = rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	// The pragma is a no-op inside a transaction, so set it first
+	var foreignKeys int
+	if err := conn.QueryRowContext(ctx, "PRAGMA foreign_keys").Scan(&foreignKeys); err != nil {
+		return err
+	}
+	if _, err := conn.ExecContext(ctx, "PRAGMA foreign_keys = OFF"); err != nil {
+		return err
+	}
+	defer func() {
+		_, _ = conn.ExecContext(context.Background(), fmt.Sprintf("PRAGMA foreign_keys = %d", foreignKeys))
+	}()
+
+	for _, drop := range drops {
+		if _, err := conn.ExecContext(ctx, drop); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Isolate creates a new database file in the temporary directory and
+// returns a driver using it, for queen.TestHelper.Isolated. The file is
+// opened with the same database/sql driver but without the options of the
+// original DSN. drop closes it and removes the file.
+func (d *Driver) Isolate(ctx context.Context, name string) (queen.Driver, func(context.Context) error, error) {
+	f, err := os.CreateTemp("", name+"-*.db")
+	if err != nil {
+		return nil, nil, err
+	}
+	path := f.Name()
+	_ = f.Close()
+
+	db := sql.OpenDB(fileConnector{driver: d.db.Driver(), path: path})
+	if err := db.PingContext(ctx); err != nil {
+		_ = db.Close()
+		_ = os.Remove(path)
+		return nil, nil, err
+	}
+
+	drop := func(context.Context) error {
+		return errors.Join(db.Close(), os.Remove(path))
+	}
+
+	return NewWithTableName(db, d.tableName), drop, nil
+}
+
+// fileConnector opens connections to a database file with an existing
+// database/sql driver.
+type fileConnector struct {
+	driver driver.Driver
+	path   string
+}
+
+func (c fileConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	return c.driver.Open(c.path)
+}
+
+func (c fileConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+// EnsureViews creates or updates the reporting views over the migrations table:
+//
+//   - <table>_batches: one row per Up run (batch) with counts and time range
+//   - <table>_namespaces: applied migrations per version prefix ("users_001" -> "users")
+//   - <table>_failures: dirty records left by failed or interrupted migrations
+//
+// View definitions are versioned in <table>_views and only recreated when
+// they change.
+func (d *Driver) EnsureViews(ctx context.Context) error {
+	table := dialect.QuoteIdentifier(d.tableName)
+	namespace := "CASE WHEN instr(version, '_') > 0 THEN substr(version, 1, instr(version, '_') - 1) ELSE '' END"
+
+	defs := []views.Definition{
+		{
+			Name: d.tableName + "_batches",
+			Query: fmt.Sprintf(`SELECT batch, COUNT(*) AS migrations,
+				MIN(applied_at) AS started_at, MAX(applied_at) AS finished_at,
+				SUM(CASE WHEN dirty THEN 1 ELSE 0 END) AS dirty
+				FROM %s GROUP BY batch`, table),
+		},
+		{
+			Name: d.tableName + "_namespaces",
+			Query: fmt.Sprintf(`SELECT %s AS namespace, COUNT(*) AS applied,
+				MAX(applied_at) AS last_applied_at
+				FROM %s GROUP BY %s`, namespace, table, namespace),
+		},
+		{
+			Name: d.tableName + "_failures",
+			Query: fmt.Sprintf(`SELECT version, name, applied_at, batch
+				FROM %s WHERE dirty`, table),
+		},
+	}
+
+	return views.Sync(ctx, d.db, dialect, "", d.tableName+"_views", defs)
+}
+
+// CheckPermissions verifies the database file is writable: that tables can
+// be created, altered and dropped and the tracking table written. It leaves
+// no changes behind.
+func (d *Driver) CheckPermissions(ctx context.Context) error {
+	return permcheck.Check(ctx, d.db, dialect, "main", d.tableName)
+}
+
+// Capabilities reports the SQLite library version. Loadable extensions
+// aren't tracked, so "extension:" requirements are never met.
+func (d *Driver) Capabilities(ctx context.Context) (queen.Capabilities, error) {
+	caps := queen.Capabilities{Dialect: "sqlite"}
+	err := d.db.QueryRowContext(ctx, "SELECT sqlite_version()").Scan(&caps.Version)
+	return caps, err
+}
+
+// ListTables returns the tables of the database, excluding SQLite's
+// internal sqlite_ tables.
+func (d *Driver) ListTables(ctx context.Context) ([]string, error) {
+	rows, err := d.db.QueryContext(ctx, `
+		SELECT name FROM sqlite_master
+		WHERE type = 'table' AND name NOT LIKE 'sqlite_%'
+		ORDER BY name
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		tables = append(tables, name)
+	}
+
+	return tables, rows.Err()
+}
+
+// ListColumns returns the columns of table from PRAGMA table_info. Type is
+// the declared type, which SQLite doesn't enforce.
+func (d *Driver) ListColumns(ctx context.Context, table string) ([]queen.Column, error) {
+	rows, err := d.db.QueryContext(ctx, `
+		SELECT name, type, "notnull", COALESCE(dflt_value, '')
+		FROM pragma_table_info(?)
+		ORDER BY cid
+	`, table)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var columns []queen.Column
+	for rows.Next() {
+		var c queen.Column
+		var notNull bool
+		if err := rows.Scan(&c.Name, &c.Type, &notNull, &c.Default); err != nil {
+			return nil, err
+		}
+		c.Nullable = !notNull
+		columns = append(columns, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("%w: table %s", queen.ErrObjectNotFound, table)
+	}
+
+	return columns, nil
+}
+
+// ListIndexes returns the indexes of table from PRAGMA index_list,
+// including the automatic indexes of PRIMARY KEY and UNIQUE constraints.
+// An INTEGER PRIMARY KEY is the rowid and has no index.
+func (d *Driver) ListIndexes(ctx context.Context, table string) ([]queen.Index, error) {
+	if _, err := d.ObjectDDL(ctx, table); err != nil {
+		return nil, err
+	}
+
+	rows, err := d.db.QueryContext(ctx, `
+		SELECT il.name, il."unique", il.origin, COALESCE(ii.name, '')
+		FROM pragma_index_list(?) AS il, pragma_index_info(il.name) AS ii
+		ORDER BY il.name, ii.seqno
+	`, table)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var indexes []queen.Index
+	for rows.Next() {
+		var name, origin, column string
+		var unique bool
+		if err := rows.Scan(&name, &unique, &origin, &column); err != nil {
+			return nil, err
+		}
+
+		if len(indexes) == 0 || indexes[len(indexes)-1].Name != name {
+			indexes = append(indexes, queen.Index{Name: name, Unique: unique, Primary: origin == "pk"})
+		}
+		last := &indexes[len(indexes)-1]
+		last.Columns = append(last.Columns, column)
+	}
+
+	return indexes, rows.Err()
+}
+
+// ObjectDDL returns the CREATE statement of a table, view, index or trigger
+// as stored in sqlite_master. Automatic indexes have none.
+func (d *Driver) ObjectDDL(ctx context.Context, name string) (string, error) {
+	var ddl string
+	err := d.db.QueryRowContext(ctx, `
+		SELECT sql FROM sqlite_master WHERE name = ? AND sql IS NOT NULL
+	`, name).Scan(&ddl)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", fmt.Errorf("%w: %s", queen.ErrObjectNotFound, name)
+	}
+
+	return ddl, err
+}
+
+// progressTable returns the name of the table used by the progress package.
+func (d *Driver) progressTable() string {
+	return d.tableName + "_progress"
+}
+
+// initProgress creates the table used by the progress package.
+func (d *Driver) initProgress(ctx context.Context) error {
+	query := fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			version TEXT NOT NULL,
+			name TEXT NOT NULL,
+			value TEXT NOT NULL,
+			updated_at TEXT NOT NULL DEFAULT (datetime('now')),
+			PRIMARY KEY (version, name)
+		) WITHOUT ROWID
+	`, dialect.QuoteIdentifier(d.progressTable()))
+
+	_, err := d.db.ExecContext(ctx, query)
+	return err
+}
+
+// GetProgress returns the progress value saved under key for version.
+func (d *Driver) GetProgress(ctx context.Context, tx *sql.Tx, version, key string) (string, bool, error) {
+	query := fmt.Sprintf("SELECT value FROM %s WHERE version = ? AND name = ?", dialect.QuoteIdentifier(d.progressTable()))
+
+	var value string
+	err := tx.QueryRowContext(ctx, query, version, key).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+
+	return value, true, nil
+}
+
+// SetProgress saves a progress value under key for version.
+func (d *Driver) SetProgress(ctx context.Context, tx *sql.Tx, version, key, value string) error {
+	query := fmt.Sprintf(`INSERT INTO %s (version, name, value) VALUES (?, ?, ?)
+		ON CONFLICT (version, name) DO UPDATE SET value = excluded.value, updated_at = datetime('now')`, dialect.QuoteIdentifier(d.progressTable()))
+
+	_, err := tx.ExecContext(ctx, query, version, key, value)
+	return err
+}
+
+// ClearProgress deletes all progress saved for version.
+func (d *Driver) ClearProgress(ctx context.Context, tx *sql.Tx, version string) error {
+	query := fmt.Sprintf("DELETE FROM %s WHERE version = ?", dialect.QuoteIdentifier(d.progressTable()))
+
+	_, err := tx.ExecContext(ctx, query, version)
+	return err
+}
+
+// historyTable returns the name of the execution log table.
+func (d *Driver) historyTable() string {
+	return d.tableName + "_history"
+}
+
+// initHistory creates the execution log table.
+func (d *Driver) initHistory(ctx context.Context) error {
+	query := fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			run_id TEXT NOT NULL DEFAULT '',
+			version TEXT NOT NULL,
+			name TEXT NOT NULL,
+			direction TEXT NOT NULL,
+			error TEXT NOT NULL DEFAULT '',
+			started_at TEXT NOT NULL,
+			duration_ms INTEGER NOT NULL DEFAULT 0,
+			applied_by TEXT NOT NULL DEFAULT '',
+			hostname TEXT NOT NULL DEFAULT '',
+			operator TEXT NOT NULL DEFAULT '',
+			build_info TEXT NOT NULL DEFAULT '',
+			backup TEXT,
+			change_ticket TEXT,
+			lock_wait_ms INTEGER NOT NULL DEFAULT 0
+		)
+	`, dialect.QuoteIdentifier(d.historyTable()))
+
+	if _, err := d.db.ExecContext(ctx, query); err != nil {
+		return err
+	}
+
+	return d.upgradeTable(ctx, d.historyTable(), historyColumns)
+}
+
+// RecordHistory appends a migration execution to the <table>_history log.
+func (d *Driver) RecordHistory(ctx context.Context, e queen.HistoryEntry) error {
+	query := dialect.Insert(dialect.QuoteIdentifier(d.historyTable()), sqlutil.HistoryColumns)
+
+	direction := "up"
+	if e.Down {
+		direction = "down"
+	}
+
+	_, err := d.db.ExecContext(ctx, query, e.RunID, e.Version, e.Name, direction, e.Error, e.StartedAt.UTC().Format("2006-01-02 15:04:05"),
+		e.Duration.Milliseconds(), e.AppliedBy, e.Hostname, e.Operator, e.BuildInfo, e.Backup, e.ChangeTicket,
+		e.LockWait.Milliseconds())
+	return err
+}
+
+// GetHistory returns the <table>_history log, oldest entry first.
+func (d *Driver) GetHistory(ctx context.Context) ([]queen.HistoryEntry, error) {
+	query := fmt.Sprintf(`
+		SELECT id, run_id, version, name, direction, COALESCE(error, ''), started_at, duration_ms,
+			applied_by, hostname, operator, build_info, COALESCE(backup, ''), COALESCE(change_ticket, ''), lock_wait_ms
+		FROM %s
+		ORDER BY id ASC
+	`, dialect.QuoteIdentifier(d.historyTable()))
+
+	rows, err := d.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var history []queen.HistoryEntry
+	for rows.Next() {
+		var e queen.HistoryEntry
+		var direction string
+		var durationMS, lockWaitMS int64
+		var startedAtStr string
+		if err := rows.Scan(&e.ID, &e.RunID, &e.Version, &e.Name, &direction, &e.Error, &startedAtStr, &durationMS,
+			&e.AppliedBy, &e.Hostname, &e.Operator, &e.BuildInfo, &e.Backup, &e.ChangeTicket, &lockWaitMS); err != nil {
+			return nil, err
+		}
+
+		startedAt, err := time.Parse("2006-01-02 15:04:05", startedAtStr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse started_at timestamp: %w", err)
+		}
+		e.StartedAt = startedAt
+		e.Down = direction == "down"
+		e.Duration = time.Duration(durationMS) * time.Millisecond
+		e.LockWait = time.Duration(lockWaitMS) * time.Millisecond
+
+		history = append(history, e)
+	}
+
+	return history, rows.Err()
+}
+
+// trackingColumns lists columns added to the tracking table after its
+// original layout, in the order they were introduced.
+var trackingColumns = []sqlutil.Column{
+	{Name: "batch", Definition: "INTEGER NOT NULL DEFAULT 0"},
+	{Name: "dirty", Definition: "INTEGER NOT NULL DEFAULT 0"},
+	{Name: "down_sql", Definition: "TEXT"},
+	{Name: "execution_ms", Definition: "INTEGER NOT NULL DEFAULT 0"},
+	{Name: "applied_by", Definition: "TEXT NOT NULL DEFAULT ''"},
+	{Name: "hostname", Definition: "TEXT NOT NULL DEFAULT ''"},
+	{Name: "operator", Definition: "TEXT NOT NULL DEFAULT ''"},
+	{Name: "build_info", Definition: "TEXT NOT NULL DEFAULT ''"},
+	{Name: "metadata", Definition: "TEXT"},
+}
+
+// historyColumns lists columns added to the <table>_history log after its
+// original layout, in the order they were introduced.
+var historyColumns = []sqlutil.Column{
+	{Name: "backup", Definition: "TEXT"},
+	{Name: "change_ticket", Definition: "TEXT"},
+	{Name: "lock_wait_ms", Definition: "INTEGER NOT NULL DEFAULT 0"},
+}
+
+// upgradeTable adds columns missing from tables created by earlier versions.
+func (d *Driver) upgradeTable(ctx context.Context, table string, columns []sqlutil.Column) error {
+	return dialect.UpgradeTable(ctx, d.db, "ALTER TABLE "+dialect.QuoteIdentifier(table)+" ADD COLUMN", columns,
+		"SELECT name FROM pragma_table_info(?)", table)
 }
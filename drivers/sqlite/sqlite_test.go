@@ -6,13 +6,19 @@ package sqlite
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+	"testing/fstest"
 	"time"
 
 	_ "github.com/mattn/go-sqlite3"
 
 	"github.com/honeynil/queen"
+	"github.com/honeynil/queen/internal/split"
 )
 
 // TestQuoteIdentifier tests the identifier quoting function.
@@ -51,7 +57,7 @@ func TestQuoteIdentifier(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := quoteIdentifier(tt.input)
+			result := dialect.QuoteIdentifier(tt.input)
 			if result != tt.expected {
 				t.Errorf("quoteIdentifier(%q) = %q; want %q", tt.input, result, tt.expected)
 			}
@@ -82,6 +88,22 @@ func TestDriverCreation(t *testing.T) {
 			t.Errorf("driver.tableName = %q; want %q", driver.tableName, "custom_migrations")
 		}
 	})
+
+	t.Run("Config.TableName sets the table name", func(t *testing.T) {
+		driver := New(db)
+		queen.NewWithConfig(driver, &queen.Config{TableName: "config_migrations"})
+		if driver.tableName != "config_migrations" {
+			t.Errorf("driver.tableName = %q; want %q", driver.tableName, "config_migrations")
+		}
+	})
+
+	t.Run("default Config.TableName keeps a custom table name", func(t *testing.T) {
+		driver := NewWithTableName(db, "custom_migrations")
+		queen.New(driver)
+		if driver.tableName != "custom_migrations" {
+			t.Errorf("driver.tableName = %q; want %q", driver.tableName, "custom_migrations")
+		}
+	})
 }
 
 // setupTestDB creates a test database connection using in-memory SQLite.
@@ -259,6 +281,101 @@ func TestRecordAndGetApplied(t *testing.T) {
 	}
 }
 
+func TestRecordWithMeta(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	driver := New(db)
+	ctx := context.Background()
+
+	if err := driver.Init(ctx); err != nil {
+		t.Fatalf("Init() failed: %v", err)
+	}
+
+	m := &queen.Migration{
+		Version: "001",
+		Name:    "create_users",
+		UpSQL:   "CREATE TABLE users (id INTEGER)",
+		DownSQL: "DROP TABLE users",
+	}
+	if err := driver.RecordWithMeta(ctx, m, queen.RecordMeta{Batch: 2, Dirty: true}); err != nil {
+		t.Fatalf("RecordWithMeta() failed: %v", err)
+	}
+
+	// Recording again replaces the dirty marker
+	meta := queen.RecordMeta{
+		Batch:     2,
+		Duration:  1500 * time.Millisecond,
+		AppliedBy: "alice",
+		Hostname:  "build-1",
+		Operator:  "TICKET-7",
+		BuildInfo: "v1.2.3",
+	}
+	if err := driver.RecordWithMeta(ctx, m, meta); err != nil {
+		t.Fatalf("RecordWithMeta() failed: %v", err)
+	}
+
+	applied, err := driver.GetApplied(ctx)
+	if err != nil {
+		t.Fatalf("GetApplied() failed: %v", err)
+	}
+	if len(applied) != 1 {
+		t.Fatalf("expected 1 migration, got %d", len(applied))
+	}
+	a := applied[0]
+	if a.Dirty || a.Batch != 2 || a.Duration != meta.Duration || a.DownSQL != m.DownSQL {
+		t.Errorf("applied = %+v; want clean in batch 2 with duration and DownSQL", a)
+	}
+	if a.AppliedBy != "alice" || a.Hostname != "build-1" || a.Operator != "TICKET-7" || a.BuildInfo != "v1.2.3" {
+		t.Errorf("applied = %+v; want the run metadata", a)
+	}
+}
+
+func TestRecordBatch(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	driver := New(db)
+	ctx := context.Background()
+
+	if err := driver.Init(ctx); err != nil {
+		t.Fatalf("Init() failed: %v", err)
+	}
+
+	batch := []*queen.Migration{
+		{Version: "001", Name: "create_users"},
+		{Version: "002", Name: "create_posts"},
+	}
+	if err := driver.RecordBatch(ctx, batch, queen.RecordMeta{Batch: 4}); err != nil {
+		t.Fatalf("RecordBatch() failed: %v", err)
+	}
+
+	applied, err := driver.GetApplied(ctx)
+	if err != nil {
+		t.Fatalf("GetApplied() failed: %v", err)
+	}
+	if len(applied) != 2 || applied[0].Batch != 4 || applied[1].Batch != 4 {
+		t.Fatalf("expected 2 migrations in batch 4, got %+v", applied)
+	}
+
+	// A failing record leaves none of the batch recorded
+	_, err = db.ExecContext(ctx, `CREATE TRIGGER refuse BEFORE INSERT ON queen_migrations
+		WHEN NEW.version = '004' BEGIN SELECT RAISE(ABORT, 'refused'); END`)
+	if err != nil {
+		t.Fatalf("create trigger: %v", err)
+	}
+	batch = []*queen.Migration{
+		{Version: "003", Name: "create_tags"},
+		{Version: "004", Name: "create_likes"},
+	}
+	if err := driver.RecordBatch(ctx, batch, queen.RecordMeta{Batch: 5}); err == nil {
+		t.Fatal("expected RecordBatch() to fail")
+	}
+	if applied, _ := driver.GetApplied(ctx); len(applied) != 2 {
+		t.Errorf("expected 2 migrations after a failed batch, got %d", len(applied))
+	}
+}
+
 func TestRemove(t *testing.T) {
 	db, cleanup := setupTestDB(t)
 	defer cleanup()
@@ -585,3 +702,1429 @@ func TestTimestampParsing(t *testing.T) {
 		t.Errorf("AppliedAt timestamp seems incorrect: %v (elapsed: %v)", applied[0].AppliedAt, elapsed)
 	}
 }
+
+func TestInitUpgradesLegacyTable(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	// Table layout used before batches were tracked
+	_, err := db.ExecContext(ctx, `
+		CREATE TABLE queen_migrations (
+			version TEXT PRIMARY KEY,
+			name TEXT NOT NULL,
+			applied_at TEXT NOT NULL DEFAULT (datetime('now')),
+			checksum TEXT NOT NULL
+		) WITHOUT ROWID
+	`)
+	if err != nil {
+		t.Fatalf("failed to create legacy table: %v", err)
+	}
+	_, err = db.ExecContext(ctx,
+		"INSERT INTO queen_migrations (version, name, checksum) VALUES ('001', 'legacy', 'abc')")
+	if err != nil {
+		t.Fatalf("failed to insert legacy row: %v", err)
+	}
+
+	driver := New(db)
+	if err := driver.Init(ctx); err != nil {
+		t.Fatalf("Init() failed: %v", err)
+	}
+
+	m := &queen.Migration{Version: "002", Name: "new", UpSQL: "SELECT 1"}
+	if err := driver.RecordWithMeta(ctx, m, queen.RecordMeta{Batch: 3}); err != nil {
+		t.Fatalf("Record() failed: %v", err)
+	}
+
+	applied, err := driver.GetApplied(ctx)
+	if err != nil {
+		t.Fatalf("GetApplied() failed: %v", err)
+	}
+	if len(applied) != 2 {
+		t.Fatalf("expected 2 migrations, got %d", len(applied))
+	}
+
+	batches := map[string]int{}
+	for _, a := range applied {
+		batches[a.Version] = a.Batch
+	}
+	if batches["001"] != 0 || batches["002"] != 3 {
+		t.Errorf("batches = %v; want 001:0 002:3", batches)
+	}
+}
+
+func TestSetDirty(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	driver := New(db)
+	ctx := context.Background()
+
+	if err := driver.Init(ctx); err != nil {
+		t.Fatalf("Init() failed: %v", err)
+	}
+
+	m := &queen.Migration{Version: "001", Name: "test", UpSQL: "SELECT 1"}
+	if err := driver.RecordWithMeta(ctx, m, queen.RecordMeta{Batch: 1, Dirty: true}); err != nil {
+		t.Fatalf("Record() failed: %v", err)
+	}
+
+	applied, err := driver.GetApplied(ctx)
+	if err != nil {
+		t.Fatalf("GetApplied() failed: %v", err)
+	}
+	if len(applied) != 1 || !applied[0].Dirty {
+		t.Fatalf("expected one dirty record, got %+v", applied)
+	}
+
+	if err := driver.SetDirty(ctx, "001", false); err != nil {
+		t.Fatalf("SetDirty() failed: %v", err)
+	}
+
+	applied, err = driver.GetApplied(ctx)
+	if err != nil {
+		t.Fatalf("GetApplied() failed: %v", err)
+	}
+	if applied[0].Dirty {
+		t.Error("expected dirty flag to be cleared")
+	}
+}
+
+func TestEnsureViews(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	q := queen.NewWithConfig(New(db), &queen.Config{ReportingViews: true})
+	q.MustAdd(queen.M{Version: "users_001", Name: "create_users", UpSQL: "CREATE TABLE users (id INTEGER)"})
+	q.MustAdd(queen.M{Version: "users_002", Name: "create_emails", UpSQL: "CREATE TABLE emails (id INTEGER)"})
+	q.MustAdd(queen.M{Version: "posts_001", Name: "create_posts", UpSQL: "CREATE TABLE posts (id INTEGER)"})
+
+	if err := q.Up(ctx); err != nil {
+		t.Fatalf("Up() failed: %v", err)
+	}
+
+	var applied int
+	err := db.QueryRowContext(ctx,
+		"SELECT applied FROM queen_migrations_namespaces WHERE namespace = 'users'").Scan(&applied)
+	if err != nil {
+		t.Fatalf("failed to query namespaces view: %v", err)
+	}
+	if applied != 2 {
+		t.Errorf("users applied = %d; want 2", applied)
+	}
+
+	var batches int
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM queen_migrations_batches").Scan(&batches); err != nil {
+		t.Fatalf("failed to query batches view: %v", err)
+	}
+	if batches != 1 {
+		t.Errorf("batches = %d; want 1", batches)
+	}
+
+	var failures int
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM queen_migrations_failures").Scan(&failures); err != nil {
+		t.Fatalf("failed to query failures view: %v", err)
+	}
+	if failures != 0 {
+		t.Errorf("failures = %d; want 0", failures)
+	}
+
+	// Repeated initialization must not recreate unchanged views
+	if err := New(db).EnsureViews(ctx); err != nil {
+		t.Fatalf("second EnsureViews() failed: %v", err)
+	}
+}
+
+func TestNoTransaction(t *testing.T) {
+	db, cleanup := setupTestDBFile(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	// VACUUM cannot run inside a transaction
+	q := queen.New(New(db))
+	q.MustAdd(queen.M{Version: "001", Name: "vacuum", UpSQL: "VACUUM"})
+	if err := q.Up(ctx); err == nil {
+		t.Fatal("expected transactional VACUUM to fail")
+	}
+
+	q = queen.New(New(db))
+	q.MustAdd(queen.M{Version: "001", Name: "vacuum", UpSQL: "VACUUM", NoTransaction: true})
+	if err := q.Up(ctx); err != nil {
+		t.Fatalf("Up() with NoTransaction failed: %v", err)
+	}
+
+	// A failed non-transactional migration leaves the database dirty
+	q.MustAdd(queen.M{Version: "002", Name: "broken", UpSQL: "NOT VALID SQL", NoTransaction: true})
+	if err := q.Up(ctx); err == nil {
+		t.Fatal("expected invalid SQL to fail")
+	}
+
+	statuses, err := q.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status() failed: %v", err)
+	}
+	if statuses[1].Status != queen.StatusDirty {
+		t.Errorf("status = %s; want dirty", statuses[1].Status)
+	}
+}
+
+func TestSmokeTest(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	q := queen.New(New(db))
+	if err := q.SmokeTest(ctx); err != nil {
+		t.Fatalf("SmokeTest() failed: %v", err)
+	}
+
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM queen_migrations").Scan(&count); err != nil {
+		t.Fatalf("failed to count records: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("tracking table has %d records after SmokeTest; want 0", count)
+	}
+
+	applied, err := q.Applied(ctx)
+	if err != nil {
+		t.Fatalf("Applied() failed: %v", err)
+	}
+	if len(applied) != 0 {
+		t.Errorf("Applied() = %v; want none", applied)
+	}
+}
+
+func TestMultiStatementMigration(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	q := queen.New(New(db))
+	q.MustAdd(queen.M{
+		Version: "001",
+		Name:    "users_with_audit",
+		UpSQL: `
+			CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT);
+			CREATE TABLE audit (msg TEXT);
+			-- log every insert; the trigger body contains semicolons
+			CREATE TRIGGER users_audit AFTER INSERT ON users BEGIN
+				INSERT INTO audit (msg) VALUES ('added; ' || NEW.name);
+			END;
+			INSERT INTO users (name) VALUES ('alice');
+		`,
+		DownSQL: "DROP TABLE audit; DROP TABLE users;",
+	})
+
+	if err := q.Up(ctx); err != nil {
+		t.Fatalf("Up() failed: %v", err)
+	}
+
+	var msg string
+	if err := db.QueryRow("SELECT msg FROM audit").Scan(&msg); err != nil {
+		t.Fatalf("failed to read audit log: %v", err)
+	}
+	if msg != "added; alice" {
+		t.Errorf("audit msg = %q; want %q", msg, "added; alice")
+	}
+
+	if err := q.Reset(ctx); err != nil {
+		t.Fatalf("Reset() failed: %v", err)
+	}
+}
+
+func TestCheckPermissions(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	q := queen.New(New(db))
+	if err := q.CheckPermissions(ctx); err != nil {
+		t.Fatalf("CheckPermissions() failed: %v", err)
+	}
+
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE name = 'queen_migrations_probe'").Scan(&count); err != nil {
+		t.Fatalf("failed to query schema: %v", err)
+	}
+	if count != 0 {
+		t.Error("probe table left behind")
+	}
+	if err := db.QueryRow("SELECT COUNT(*) FROM queen_migrations").Scan(&count); err != nil {
+		t.Fatalf("failed to count records: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("tracking table has %d records; want 0", count)
+	}
+}
+
+func TestCheckPermissionsReadOnly(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "queen-test-*.db")
+	if err != nil {
+		t.Fatalf("failed to create temp file: %v", err)
+	}
+	tmpfile.Close()
+	defer os.Remove(tmpfile.Name())
+
+	ctx := context.Background()
+
+	// Create the tracking table, then reopen read-only
+	db, err := sql.Open("sqlite3", tmpfile.Name())
+	if err != nil {
+		t.Fatalf("failed to open SQLite: %v", err)
+	}
+	if err := New(db).Init(ctx); err != nil {
+		t.Fatalf("Init() failed: %v", err)
+	}
+	db.Close()
+
+	db, err = sql.Open("sqlite3", "file:"+tmpfile.Name()+"?mode=ro")
+	if err != nil {
+		t.Fatalf("failed to open SQLite: %v", err)
+	}
+	defer db.Close()
+
+	q := queen.NewWithConfig(New(db), &queen.Config{PreflightPermissions: true, SkipLock: true})
+	q.MustAdd(queen.M{Version: "001", Name: "create_users", UpSQL: "CREATE TABLE users (id INTEGER)"})
+
+	err = q.Up(ctx)
+	var permErr *queen.PermissionError
+	if !errors.As(err, &permErr) {
+		t.Fatalf("expected PermissionError, got %v", err)
+	}
+	if permErr.Privilege != "CREATE" || permErr.Object != "schema main" {
+		t.Errorf("got missing privilege %s on %s; want CREATE on schema main", permErr.Privilege, permErr.Object)
+	}
+}
+
+func TestCapabilities(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	caps, err := New(db).Capabilities(ctx)
+	if err != nil {
+		t.Fatalf("Capabilities() failed: %v", err)
+	}
+	if caps.Dialect != "sqlite" || !strings.HasPrefix(caps.Version, "3.") {
+		t.Errorf("Capabilities() = %+v; want sqlite 3.x", caps)
+	}
+
+	q := queen.New(New(db))
+	q.MustAdd(queen.M{
+		Version:  "001",
+		Name:     "create_users",
+		UpSQL:    "CREATE TABLE users (id INTEGER)",
+		Requires: []string{"min_sqlite_version:3.8"},
+	})
+	if err := q.Up(ctx); err != nil {
+		t.Fatalf("Up() failed: %v", err)
+	}
+
+	q.MustAdd(queen.M{
+		Version:  "002",
+		Name:     "create_geo",
+		UpSQL:    "CREATE TABLE geo (id INTEGER)",
+		Requires: []string{"min_pg_version:14"},
+	})
+	if err := q.Up(ctx); !errors.Is(err, queen.ErrUnmetRequirement) {
+		t.Errorf("expected ErrUnmetRequirement, got %v", err)
+	}
+}
+
+func TestIntrospector(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	driver := New(db)
+	if _, err := db.ExecContext(ctx, `
+		CREATE TABLE users (
+			id INTEGER NOT NULL,
+			email TEXT UNIQUE,
+			status TEXT DEFAULT 'active',
+			PRIMARY KEY (id, email)
+		);
+		CREATE INDEX idx_users_status ON users (status, email);
+		CREATE VIEW active_users AS SELECT id FROM users WHERE status = 'active';
+	`); err != nil {
+		t.Fatalf("failed to create schema: %v", err)
+	}
+
+	var _ queen.Introspector = driver
+	if !queen.Supports(driver, queen.FeatureIntrospection) {
+		t.Error("expected the driver to support introspection")
+	}
+
+	tables, err := driver.ListTables(ctx)
+	if err != nil {
+		t.Fatalf("ListTables() failed: %v", err)
+	}
+	if !reflect.DeepEqual(tables, []string{"users"}) {
+		t.Errorf("ListTables() = %v; want [users]", tables)
+	}
+
+	columns, err := driver.ListColumns(ctx, "users")
+	if err != nil {
+		t.Fatalf("ListColumns() failed: %v", err)
+	}
+	wantColumns := []queen.Column{
+		{Name: "id", Type: "INTEGER"},
+		{Name: "email", Type: "TEXT", Nullable: true},
+		{Name: "status", Type: "TEXT", Nullable: true, Default: "'active'"},
+	}
+	if !reflect.DeepEqual(columns, wantColumns) {
+		t.Errorf("ListColumns() = %+v; want %+v", columns, wantColumns)
+	}
+
+	indexes, err := driver.ListIndexes(ctx, "users")
+	if err != nil {
+		t.Fatalf("ListIndexes() failed: %v", err)
+	}
+	wantIndexes := []queen.Index{
+		{Name: "idx_users_status", Columns: []string{"status", "email"}},
+		{Name: "sqlite_autoindex_users_1", Columns: []string{"email"}, Unique: true},
+		{Name: "sqlite_autoindex_users_2", Columns: []string{"id", "email"}, Unique: true, Primary: true},
+	}
+	if !reflect.DeepEqual(indexes, wantIndexes) {
+		t.Errorf("ListIndexes() = %+v; want %+v", indexes, wantIndexes)
+	}
+
+	ddl, err := driver.ObjectDDL(ctx, "active_users")
+	if err != nil {
+		t.Fatalf("ObjectDDL() failed: %v", err)
+	}
+	if !strings.HasPrefix(ddl, "CREATE VIEW active_users") {
+		t.Errorf("ObjectDDL() = %q; want the CREATE VIEW statement", ddl)
+	}
+
+	if _, err := driver.ListColumns(ctx, "missing"); !errors.Is(err, queen.ErrObjectNotFound) {
+		t.Errorf("ListColumns(missing): expected ErrObjectNotFound, got %v", err)
+	}
+	if _, err := driver.ListIndexes(ctx, "missing"); !errors.Is(err, queen.ErrObjectNotFound) {
+		t.Errorf("ListIndexes(missing): expected ErrObjectNotFound, got %v", err)
+	}
+	if _, err := driver.ObjectDDL(ctx, "missing"); !errors.Is(err, queen.ErrObjectNotFound) {
+		t.Errorf("ObjectDDL(missing): expected ErrObjectNotFound, got %v", err)
+	}
+}
+
+func TestTemplateVars(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	m := queen.M{
+		Version: "001",
+		Name:    "create_users",
+		UpSQL:   "CREATE TABLE {{.TablePrefix}}users (id INTEGER)",
+		DownSQL: "DROP TABLE {{.TablePrefix}}users",
+	}
+
+	q := queen.NewWithConfig(New(db), &queen.Config{
+		TableName:    "queen_migrations",
+		TemplateVars: map[string]any{"TablePrefix": "staging_"},
+	})
+	q.MustAdd(m)
+
+	if err := q.Up(ctx); err != nil {
+		t.Fatalf("Up() failed: %v", err)
+	}
+
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM staging_users").Scan(&count); err != nil {
+		t.Fatalf("expected staging_users table: %v", err)
+	}
+
+	// The checksum covers the template, not the expanded SQL
+	q2 := queen.NewWithConfig(New(db), &queen.Config{
+		TableName:    "queen_migrations",
+		TemplateVars: map[string]any{"TablePrefix": "prod_"},
+	})
+	q2.MustAdd(m)
+	if err := q2.Validate(ctx); err != nil {
+		t.Errorf("Validate() with different vars failed: %v", err)
+	}
+
+	if err := q.Reset(ctx); err != nil {
+		t.Fatalf("Reset() failed: %v", err)
+	}
+	if err := db.QueryRow("SELECT COUNT(*) FROM staging_users").Scan(&count); err == nil {
+		t.Error("expected staging_users to be dropped")
+	}
+}
+
+func TestProgress(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	driver := New(db)
+
+	if err := driver.Init(ctx); err != nil {
+		t.Fatalf("Init() failed: %v", err)
+	}
+
+	err := driver.Exec(ctx, func(tx *sql.Tx) error {
+		if err := driver.SetProgress(ctx, tx, "001", "last_id", "10"); err != nil {
+			return err
+		}
+		return driver.SetProgress(ctx, tx, "001", "last_id", "20")
+	})
+	if err != nil {
+		t.Fatalf("SetProgress() failed: %v", err)
+	}
+
+	// Progress written in a rolled back transaction is discarded
+	_ = driver.Exec(ctx, func(tx *sql.Tx) error {
+		if err := driver.SetProgress(ctx, tx, "001", "last_id", "30"); err != nil {
+			return err
+		}
+		return errors.New("rollback")
+	})
+
+	err = driver.Exec(ctx, func(tx *sql.Tx) error {
+		value, ok, err := driver.GetProgress(ctx, tx, "001", "last_id")
+		if err != nil {
+			return err
+		}
+		if !ok || value != "20" {
+			t.Errorf("GetProgress() = %q, %v; want \"20\", true", value, ok)
+		}
+
+		if err := driver.ClearProgress(ctx, tx, "001"); err != nil {
+			return err
+		}
+		if _, ok, _ := driver.GetProgress(ctx, tx, "001", "last_id"); ok {
+			t.Error("expected progress to be cleared")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Exec() failed: %v", err)
+	}
+}
+
+func TestDownWithStoredSQL(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	users := queen.M{
+		Version: "001",
+		Name:    "create_users",
+		UpSQL:   "CREATE TABLE users (id INTEGER PRIMARY KEY)",
+		DownSQL: "DROP TABLE users",
+	}
+
+	q := queen.New(New(db))
+	q.MustAdd(users)
+	q.MustAdd(queen.M{
+		Version: "002",
+		Name:    "create_posts",
+		UpSQL:   "CREATE TABLE posts (id INTEGER PRIMARY KEY)",
+		DownSQL: "DROP TABLE posts",
+	})
+	if err := q.Up(ctx); err != nil {
+		t.Fatalf("Up() failed: %v", err)
+	}
+
+	// An older binary without migration 002 can still roll it back
+	older := queen.New(New(db))
+	older.MustAdd(users)
+	if err := older.Down(ctx, 1); err != nil {
+		t.Fatalf("Down() failed: %v", err)
+	}
+
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE name = 'posts'").Scan(&count); err != nil {
+		t.Fatalf("failed to query schema: %v", err)
+	}
+	if count != 0 {
+		t.Error("expected posts table to be dropped")
+	}
+
+	applied, err := older.Applied(ctx)
+	if err != nil {
+		t.Fatalf("Applied() failed: %v", err)
+	}
+	if len(applied) != 1 || applied[0].Version != "001" || applied[0].DownSQL != "DROP TABLE users" {
+		t.Errorf("Applied() = %+v; want only 001 with its DownSQL", applied)
+	}
+}
+
+func TestExecutionDuration(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	q := queen.New(New(db))
+	q.MustAdd(queen.M{
+		Version:        "001",
+		Name:           "slow",
+		ManualChecksum: "v1",
+		UpFunc: func(ctx context.Context, tx *sql.Tx) error {
+			time.Sleep(20 * time.Millisecond)
+			return nil
+		},
+	})
+	if err := q.Up(ctx); err != nil {
+		t.Fatalf("Up() failed: %v", err)
+	}
+
+	applied, err := New(db).GetApplied(ctx)
+	if err != nil {
+		t.Fatalf("GetApplied() failed: %v", err)
+	}
+	if len(applied) != 1 || applied[0].Duration < 20*time.Millisecond || applied[0].Dirty {
+		t.Errorf("GetApplied() = %+v; want one clean record of at least 20ms", applied)
+	}
+}
+
+func TestAppliedByMetadata(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	config := queen.DefaultConfig()
+	config.Operator = "CHG-1234"
+
+	q := queen.NewWithConfig(New(db), config)
+	q.MustAdd(queen.M{Version: "001", Name: "create_users", UpSQL: "CREATE TABLE users (id INTEGER)"})
+	if err := q.Up(ctx); err != nil {
+		t.Fatalf("Up() failed: %v", err)
+	}
+
+	applied, err := New(db).GetApplied(ctx)
+	if err != nil {
+		t.Fatalf("GetApplied() failed: %v", err)
+	}
+	if len(applied) != 1 {
+		t.Fatalf("GetApplied() returned %d records; want 1", len(applied))
+	}
+
+	hostname, _ := os.Hostname()
+	if a := applied[0]; a.Operator != "CHG-1234" || a.Hostname != hostname || a.AppliedBy == "" {
+		t.Errorf("GetApplied() = %+v; want operator CHG-1234, hostname %q and a user", a, hostname)
+	}
+
+	statuses, err := q.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status() failed: %v", err)
+	}
+	if s := statuses[0]; s.Operator != "CHG-1234" || s.AppliedBy != applied[0].AppliedBy {
+		t.Errorf("Status() = %+v; want metadata of the applied record", s)
+	}
+}
+
+func TestLoadFixtures(t *testing.T) {
+	// Each subtest's helper closes its connection, so share a file database
+	path := filepath.Join(t.TempDir(), "fixtures.db")
+	open := func(t *testing.T) *sql.DB {
+		t.Helper()
+		db, err := sql.Open("sqlite3", path+"?_foreign_keys=on")
+		if err != nil {
+			t.Fatalf("failed to open SQLite: %v", err)
+		}
+		return db
+	}
+
+	fixtures := fstest.MapFS{
+		"testdata/01_users.yaml": {Data: []byte(`
+users:
+  - id: 1
+    email: alice@example.com
+    order: 1
+  - id: 2
+    email: bob@example.com
+    order: 2
+`)},
+		"testdata/02_posts.sql": {Data: []byte(`
+INSERT INTO posts (id, user_id, title) VALUES (1, 1, 'Hello; world');
+INSERT INTO posts (id, user_id, title) VALUES (2, 2, 'Second');
+`)},
+		"testdata/README.md": {Data: []byte("ignored")},
+	}
+
+	count := func(t *testing.T, db *sql.DB, table string) int {
+		t.Helper()
+		var n int
+		if err := db.QueryRow("SELECT COUNT(*) FROM " + table).Scan(&n); err != nil {
+			t.Fatalf("failed to count %s: %v", table, err)
+		}
+		return n
+	}
+
+	// Each case gets the same seeded data, removed again when it ends
+	for _, name := range []string{"first", "second"} {
+		t.Run(name, func(t *testing.T) {
+			db := open(t)
+			q := queen.NewTest(t, New(db))
+			q.MustAdd(queen.M{Version: "001", Name: "create_users",
+				UpSQL: `CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT, "order" INTEGER)`})
+			q.MustAdd(queen.M{Version: "002", Name: "create_posts",
+				UpSQL: "CREATE TABLE posts (id INTEGER PRIMARY KEY, user_id INTEGER REFERENCES users (id), title TEXT)"})
+
+			q.LoadFixtures(fixtures, "testdata")
+
+			if n := count(t, db, "users"); n != 2 {
+				t.Errorf("users = %d; want 2", n)
+			}
+			if n := count(t, db, "posts"); n != 2 {
+				t.Errorf("posts = %d; want 2", n)
+			}
+		})
+	}
+
+	db := open(t)
+	defer db.Close()
+
+	if n := count(t, db, "users") + count(t, db, "posts"); n != 0 {
+		t.Errorf("expected fixtures to be removed, %d rows left", n)
+	}
+}
+
+func TestRunInRollback(t *testing.T) {
+	db, cleanup := setupTestDBFile(t)
+	defer cleanup()
+
+	q := queen.NewTest(t, New(db))
+	q.MustAdd(queen.M{
+		Version: "001",
+		Name:    "create_users",
+		UpSQL:   "CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT UNIQUE)",
+	})
+
+	// The same row can be inserted by every case, as none of them commits
+	for _, name := range []string{"first", "second"} {
+		t.Run(name, func(t *testing.T) {
+			q.RunInRollback(t, func(tx *sql.Tx) {
+				if _, err := tx.Exec("INSERT INTO users (id, email) VALUES (1, 'alice@example.com')"); err != nil {
+					t.Fatalf("insert failed: %v", err)
+				}
+
+				var n int
+				if err := tx.QueryRow("SELECT COUNT(*) FROM users").Scan(&n); err != nil {
+					t.Fatalf("count failed: %v", err)
+				}
+				if n != 1 {
+					t.Errorf("users = %d; want 1", n)
+				}
+			})
+		})
+	}
+
+	var n int
+	if err := db.QueryRow("SELECT COUNT(*) FROM users").Scan(&n); err != nil {
+		t.Fatalf("count failed: %v", err)
+	}
+	if n != 0 {
+		t.Errorf("expected changes to be rolled back, found %d users", n)
+	}
+}
+
+func TestIsolated(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	q := queen.NewTest(t, New(db))
+	q.MustAdd(queen.M{
+		Version: "001",
+		Name:    "create_users",
+		UpSQL:   "CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT UNIQUE)",
+	})
+
+	t.Run("parallel", func(t *testing.T) {
+		for _, name := range []string{"first", "second", "third"} {
+			t.Run(name, func(t *testing.T) {
+				t.Parallel()
+
+				iso := q.Isolated(t)
+
+				applied, err := iso.Applied(context.Background())
+				if err != nil {
+					t.Fatalf("Applied() failed: %v", err)
+				}
+				if len(applied) != 1 {
+					t.Errorf("Applied() returned %d migrations; want 1", len(applied))
+				}
+
+				iso.RunInRollback(t, func(tx *sql.Tx) {
+					if _, err := tx.Exec("INSERT INTO users (id, email) VALUES (1, 'alice@example.com')"); err != nil {
+						t.Errorf("insert failed: %v", err)
+					}
+				})
+			})
+		}
+	})
+
+	// Nothing was migrated in the shared database
+	var n int
+	if err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE name = 'users'").Scan(&n); err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	if n != 0 {
+		t.Error("expected users table only in isolated databases")
+	}
+}
+
+func TestHistory(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	q := queen.New(New(db))
+	q.MustAdd(queen.M{
+		Version: "001",
+		Name:    "create_users",
+		UpSQL:   "CREATE TABLE users (id INTEGER)",
+		DownSQL: "DROP TABLE users",
+	})
+	q.MustAdd(queen.M{Version: "002", Name: "broken", UpSQL: "CREATE TABLE"})
+
+	if err := q.Up(ctx); err == nil {
+		t.Fatal("Up() should fail on invalid SQL")
+	}
+	if err := q.Down(ctx, 1); err != nil {
+		t.Fatalf("Down() failed: %v", err)
+	}
+
+	history, err := q.History(ctx)
+	if err != nil {
+		t.Fatalf("History() failed: %v", err)
+	}
+	if len(history) != 3 {
+		t.Fatalf("History() returned %d entries; want 3", len(history))
+	}
+
+	up, failed, down := history[0], history[1], history[2]
+	if up.Version != "001" || up.Down || up.Error != "" || up.StartedAt.IsZero() {
+		t.Errorf("first entry = %+v; want successful up of 001", up)
+	}
+	if failed.Version != "002" || failed.Down || failed.Error == "" {
+		t.Errorf("second entry = %+v; want failed up of 002", failed)
+	}
+	if down.Version != "001" || !down.Down || down.Error != "" {
+		t.Errorf("third entry = %+v; want successful down of 001", down)
+	}
+	if up.ID >= failed.ID || failed.ID >= down.ID {
+		t.Errorf("expected increasing IDs, got %d, %d, %d", up.ID, failed.ID, down.ID)
+	}
+}
+
+func TestHistoryLockWait(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	driver := New(db)
+	if err := driver.Init(ctx); err != nil {
+		t.Fatalf("Init() failed: %v", err)
+	}
+
+	entry := queen.HistoryEntry{RunID: "r1", Version: "001", StartedAt: time.Now(), LockWait: 1500 * time.Millisecond}
+	if err := driver.RecordHistory(ctx, entry); err != nil {
+		t.Fatalf("RecordHistory() failed: %v", err)
+	}
+
+	history, err := driver.GetHistory(ctx)
+	if err != nil {
+		t.Fatalf("GetHistory() failed: %v", err)
+	}
+	if len(history) != 1 || history[0].LockWait != 1500*time.Millisecond {
+		t.Errorf("GetHistory() = %+v; want lock wait 1.5s", history)
+	}
+}
+
+// tableBackup backs up tables with CREATE TABLE ... AS SELECT.
+type tableBackup struct {
+	db     *sql.DB
+	tables []string
+}
+
+func (b *tableBackup) Backup(ctx context.Context, req queen.BackupRequest) (string, error) {
+	b.tables = append(b.tables, req.Tables...)
+	ref := "backup_" + req.Migration.Version
+	_, err := b.db.ExecContext(ctx, "CREATE TABLE "+ref+" AS SELECT * FROM "+req.Tables[0])
+	return ref, err
+}
+
+func (b *tableBackup) Restore(ctx context.Context, ref string) error {
+	return errors.New("not implemented")
+}
+
+func TestBackupInHistory(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	// History layout used before backups were recorded
+	if _, err := db.ExecContext(ctx, `
+		CREATE TABLE queen_migrations_history (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			run_id TEXT NOT NULL DEFAULT '',
+			version TEXT NOT NULL,
+			name TEXT NOT NULL,
+			direction TEXT NOT NULL,
+			error TEXT,
+			started_at TEXT NOT NULL,
+			duration_ms INTEGER NOT NULL DEFAULT 0,
+			applied_by TEXT NOT NULL DEFAULT '',
+			hostname TEXT NOT NULL DEFAULT '',
+			operator TEXT NOT NULL DEFAULT '',
+			build_info TEXT NOT NULL DEFAULT ''
+		)
+	`); err != nil {
+		t.Fatalf("failed to create legacy history table: %v", err)
+	}
+
+	provider := &tableBackup{db: db}
+	q := queen.NewWithConfig(New(db), &queen.Config{Backup: provider})
+	q.MustAdd(queen.M{
+		Version: "001",
+		Name:    "create_users",
+		UpSQL:   "CREATE TABLE users (id INTEGER); INSERT INTO users VALUES (1)",
+		DownSQL: "DROP TABLE users",
+	})
+
+	if err := q.Up(ctx); err != nil {
+		t.Fatalf("Up() failed: %v", err)
+	}
+	if err := q.Down(ctx, 1); err != nil {
+		t.Fatalf("Down() failed: %v", err)
+	}
+
+	if !reflect.DeepEqual(provider.tables, []string{"users"}) {
+		t.Errorf("backed up tables = %v; want [users]", provider.tables)
+	}
+
+	var rows int
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM backup_001").Scan(&rows); err != nil || rows != 1 {
+		t.Errorf("expected 1 row in backup_001, got %d (%v)", rows, err)
+	}
+
+	history, err := q.History(ctx)
+	if err != nil {
+		t.Fatalf("History() failed: %v", err)
+	}
+	if len(history) != 2 || history[0].Backup != "" || history[1].Backup != "backup_001" {
+		t.Errorf("History() = %+v; want backup_001 recorded for the down of 001", history)
+	}
+}
+
+func TestSnapshotTable(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	q := queen.New(New(db))
+	q.MustAdd(queen.M{
+		Version: "001",
+		Name:    "create_users",
+		UpSQL:   "CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT); INSERT INTO users VALUES (1, 'Alice@Example.com'), (2, 'BOB@example.com')",
+		DownSQL: "DROP TABLE users",
+	})
+	q.MustAdd(queen.M{
+		Version: "002",
+		Name:    "lowercase_emails",
+		UpFunc: func(ctx context.Context, tx *sql.Tx) error {
+			if _, err := queen.SnapshotTable(ctx, tx, "users"); err != nil {
+				return err
+			}
+			_, err := tx.ExecContext(ctx, "UPDATE users SET email = LOWER(email)")
+			return err
+		},
+		DownFunc: queen.RestoreSnapshots("users"),
+	})
+
+	emails := func(t *testing.T) []string {
+		t.Helper()
+		rows, err := db.QueryContext(ctx, "SELECT email FROM users ORDER BY id")
+		if err != nil {
+			t.Fatalf("failed to query users: %v", err)
+		}
+		defer rows.Close()
+		var emails []string
+		for rows.Next() {
+			var email string
+			if err := rows.Scan(&email); err != nil {
+				t.Fatalf("failed to scan email: %v", err)
+			}
+			emails = append(emails, email)
+		}
+		return emails
+	}
+
+	if err := q.Up(ctx); err != nil {
+		t.Fatalf("Up() failed: %v", err)
+	}
+	if got, want := emails(t), []string{"alice@example.com", "bob@example.com"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("emails after Up = %v; want %v", got, want)
+	}
+
+	var snapshots int
+	if err := db.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name LIKE 'users_snapshot_%'").Scan(&snapshots); err != nil || snapshots != 1 {
+		t.Errorf("expected 1 snapshot table, got %d (%v)", snapshots, err)
+	}
+
+	if err := q.Down(ctx, 1); err != nil {
+		t.Fatalf("Down() failed: %v", err)
+	}
+	if got, want := emails(t), []string{"Alice@Example.com", "BOB@example.com"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("emails after Down = %v; want %v", got, want)
+	}
+
+	if err := db.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name LIKE 'users_snapshot_%'").Scan(&snapshots); err != nil || snapshots != 0 {
+		t.Errorf("expected snapshot table to be dropped, got %d (%v)", snapshots, err)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatalf("BeginTx() failed: %v", err)
+	}
+	defer tx.Rollback()
+
+	if err := queen.RestoreSnapshots("users")(ctx, tx); !errors.Is(err, queen.ErrObjectNotFound) {
+		t.Errorf("RestoreSnapshots() without snapshot = %v; want ErrObjectNotFound", err)
+	}
+	if _, err := queen.SnapshotTable(ctx, tx, "users; DROP TABLE users"); !errors.Is(err, queen.ErrInvalidMigration) {
+		t.Errorf("SnapshotTable() with invalid name = %v; want ErrInvalidMigration", err)
+	}
+}
+
+func TestExpectRowDelta(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	q := queen.New(New(db))
+	q.MustAdd(queen.M{
+		Version: "001",
+		Name:    "create_users",
+		UpSQL:   "CREATE TABLE users (id INTEGER PRIMARY KEY, active INTEGER); INSERT INTO users VALUES (1, 1), (2, 1), (3, 0)",
+	})
+	q.MustAdd(queen.M{
+		Version: "002",
+		Name:    "purge_inactive",
+		// The WHERE clause is missing, deleting every user
+		UpSQL:          "DELETE FROM users",
+		ExpectRowDelta: []queen.RowDelta{{Table: "users", Min: -1, Max: 0}},
+	})
+
+	if err := q.Up(ctx); !errors.Is(err, queen.ErrRowDelta) {
+		t.Fatalf("Up() = %v; want ErrRowDelta", err)
+	}
+
+	var rows int
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM users").Scan(&rows); err != nil || rows != 3 {
+		t.Errorf("expected the delete rolled back with 3 users left, got %d (%v)", rows, err)
+	}
+
+	if err := q.Replace(queen.M{
+		Version:        "002",
+		Name:           "purge_inactive",
+		UpSQL:          "DELETE FROM users WHERE active = 0",
+		ExpectRowDelta: []queen.RowDelta{{Table: "users", Min: -1, Max: 0}},
+	}); err != nil {
+		t.Fatalf("Replace() failed: %v", err)
+	}
+	if err := q.Up(ctx); err != nil {
+		t.Fatalf("Up() failed: %v", err)
+	}
+}
+
+func TestAssert(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	q := queen.New(New(db))
+	q.MustAdd(queen.M{
+		Version: "001",
+		Name:    "create_users",
+		UpSQL: `CREATE TABLE orgs (id INTEGER PRIMARY KEY);
+		CREATE TABLE users (
+			id INTEGER PRIMARY KEY,
+			email VARCHAR(255) NOT NULL,
+			org_id INTEGER,
+			CONSTRAINT users_org_fk FOREIGN KEY (org_id) REFERENCES orgs (id),
+			CONSTRAINT users_email_key UNIQUE (email)
+		);
+		CREATE INDEX idx_users_org ON users (org_id);
+		INSERT INTO users (email) VALUES ('alice@example.com'), ('bob@example.com')`,
+	})
+	if err := q.Up(ctx); err != nil {
+		t.Fatalf("Up() failed: %v", err)
+	}
+
+	err := q.Assert(ctx,
+		queen.TableExists("users"),
+		queen.ColumnType("users", "email", "varchar"),
+		queen.ColumnType("users", "email", "VARCHAR(255)"),
+		queen.IndexExists("users", "idx_users_org"),
+		queen.ConstraintExists("users", "users_org_fk"),
+		queen.ConstraintExists("users", "users_email_key"),
+		queen.RowCountBetween("users", 1, 2),
+	)
+	if err != nil {
+		t.Errorf("Assert() failed: %v", err)
+	}
+
+	failing := []queen.Assertion{
+		queen.TableExists("posts"),
+		queen.ColumnType("users", "email", "text"),
+		queen.ColumnType("users", "name", "text"),
+		queen.IndexExists("users", "idx_users_email"),
+		queen.ConstraintExists("users", "users_org"),
+		queen.RowCountBetween("users", 3, 10),
+	}
+	for _, a := range failing {
+		if err := q.Assert(ctx, a); !errors.Is(err, queen.ErrAssertion) {
+			t.Errorf("Assert(%s) = %v; want ErrAssertion", a, err)
+		}
+	}
+
+	err = q.Assert(ctx, failing...)
+	if n := strings.Count(err.Error(), "assertion failed"); n != len(failing) {
+		t.Errorf("expected %d failures reported, got %d: %v", len(failing), n, err)
+	}
+	if !strings.Contains(err.Error(), "column users.email is text: type is VARCHAR(255)") {
+		t.Errorf("expected the actual type reported, got %v", err)
+	}
+}
+
+func TestChecksumNormalization(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	original := queen.M{
+		Version: "001",
+		Name:    "create_users",
+		UpSQL:   "CREATE TABLE users (id INTEGER, email TEXT);",
+		DownSQL: "DROP TABLE users;",
+	}
+	reformatted := queen.M{
+		Version: "001",
+		Name:    "create_users",
+		UpSQL: `
+			-- Application users
+			CREATE TABLE users (id  INTEGER,
+			                    email TEXT)`,
+		DownSQL: "DROP TABLE users",
+	}
+	changed := queen.M{
+		Version: "001",
+		Name:    "create_users",
+		UpSQL:   "CREATE TABLE users (id INTEGER, name TEXT)",
+		DownSQL: "DROP TABLE users",
+	}
+
+	normalized := queen.DefaultConfig()
+	normalized.ChecksumNormalization = queen.ChecksumIgnoreFormatting
+
+	status := func(config *queen.Config, m queen.M) queen.Status {
+		t.Helper()
+		q := queen.NewWithConfig(New(db), config)
+		q.MustAdd(m)
+		statuses, err := q.Status(ctx)
+		if err != nil {
+			t.Fatalf("Status() failed: %v", err)
+		}
+		return statuses[0].Status
+	}
+
+	q := queen.New(New(db))
+	q.MustAdd(original)
+	if err := q.Up(ctx); err != nil {
+		t.Fatalf("Up() failed: %v", err)
+	}
+
+	if got := status(queen.DefaultConfig(), reformatted); got != queen.StatusModified {
+		t.Errorf("without normalization status = %v; want modified", got)
+	}
+
+	// The record holds the raw checksum, which still matches
+	if got := status(normalized, original); got != queen.StatusApplied {
+		t.Errorf("raw checksum status = %v; want applied", got)
+	}
+
+	// Apply again to record the normalized checksum
+	q = queen.NewWithConfig(New(db), normalized)
+	q.MustAdd(original)
+	if err := q.Reset(ctx); err != nil {
+		t.Fatalf("Reset() failed: %v", err)
+	}
+	if err := q.Up(ctx); err != nil {
+		t.Fatalf("Up() failed: %v", err)
+	}
+
+	if got := status(normalized, reformatted); got != queen.StatusApplied {
+		t.Errorf("reformatted status = %v; want applied", got)
+	}
+	if got := status(normalized, changed); got != queen.StatusModified {
+		t.Errorf("changed SQL status = %v; want modified", got)
+	}
+}
+
+func TestUpdateChecksum(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	driver := New(db)
+	ctx := context.Background()
+
+	if err := driver.Init(ctx); err != nil {
+		t.Fatalf("Init() failed: %v", err)
+	}
+
+	m := &queen.Migration{Version: "001", Name: "test", UpSQL: "SELECT 1"}
+	if err := driver.RecordWithMeta(ctx, m, queen.RecordMeta{Batch: 3}); err != nil {
+		t.Fatalf("Record() failed: %v", err)
+	}
+
+	if err := driver.UpdateChecksum(ctx, "001", "rebased"); err != nil {
+		t.Fatalf("UpdateChecksum() failed: %v", err)
+	}
+
+	applied, err := driver.GetApplied(ctx)
+	if err != nil {
+		t.Fatalf("GetApplied() failed: %v", err)
+	}
+	if applied[0].Checksum != "rebased" || applied[0].Batch != 3 {
+		t.Errorf("expected only the checksum to change, got %+v", applied[0])
+	}
+}
+
+func TestMetadata(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	driver := New(db)
+	ctx := context.Background()
+
+	if err := driver.Init(ctx); err != nil {
+		t.Fatalf("Init() failed: %v", err)
+	}
+
+	m := &queen.Migration{Version: "001", Name: "test", UpSQL: "SELECT 1"}
+	if err := driver.RecordWithMeta(ctx, m, queen.RecordMeta{Dirty: true}); err != nil {
+		t.Fatalf("Record() failed: %v", err)
+	}
+	if err := driver.SetMetadata(ctx, "001", []byte(`{"app_version":"1.4.2"}`)); err != nil {
+		t.Fatalf("SetMetadata() failed: %v", err)
+	}
+
+	// Clearing the dirty marker keeps the metadata
+	if err := driver.RecordWithMeta(ctx, m, queen.RecordMeta{}); err != nil {
+		t.Fatalf("Record() failed: %v", err)
+	}
+
+	metadata, err := driver.GetMetadata(ctx)
+	if err != nil {
+		t.Fatalf("GetMetadata() failed: %v", err)
+	}
+	if len(metadata) != 1 || string(metadata["001"]) != `{"app_version":"1.4.2"}` {
+		t.Errorf("GetMetadata() = %q, want the stored document for 001", metadata)
+	}
+}
+
+func TestResetHard(t *testing.T) {
+	db, cleanup := setupTestDBFile(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	q := queen.New(New(db))
+	q.MustAdd(queen.M{
+		Version: "001",
+		Name:    "create_users",
+		UpSQL:   "CREATE TABLE users (id INTEGER PRIMARY KEY)",
+	})
+	q.MustAdd(queen.M{
+		Version: "002",
+		Name:    "create_posts",
+		UpSQL: `CREATE TABLE posts (id INTEGER PRIMARY KEY, user_id INTEGER REFERENCES users (id));
+			CREATE VIEW post_count AS SELECT COUNT(*) AS n FROM posts`,
+	})
+
+	if err := q.Up(ctx); err != nil {
+		t.Fatalf("Up() failed: %v", err)
+	}
+	if _, err := db.Exec("INSERT INTO users (id) VALUES (1); INSERT INTO posts (id, user_id) VALUES (1, 1)"); err != nil {
+		t.Fatalf("insert failed: %v", err)
+	}
+
+	// No down migrations, so only a hard reset works
+	if err := q.Reset(ctx); err == nil {
+		t.Fatal("Reset() succeeded without down migrations")
+	}
+	if err := q.ResetHard(ctx); err != nil {
+		t.Fatalf("ResetHard() failed: %v", err)
+	}
+
+	var posts int
+	if err := db.QueryRow("SELECT COUNT(*) FROM posts").Scan(&posts); err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	if posts != 0 {
+		t.Errorf("expected empty posts table, got %d rows", posts)
+	}
+
+	applied, err := q.Applied(ctx)
+	if err != nil {
+		t.Fatalf("Applied() failed: %v", err)
+	}
+	if len(applied) != 2 {
+		t.Errorf("expected 2 applied migrations, got %d", len(applied))
+	}
+
+	var foreignKeys int
+	if err := db.QueryRow("PRAGMA foreign_keys").Scan(&foreignKeys); err != nil || foreignKeys != 1 {
+		t.Errorf("expected foreign keys to stay enabled, got %d (%v)", foreignKeys, err)
+	}
+}
+
+func TestGenerateScript(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	q := queen.New(New(db))
+	defer q.Close()
+	q.MustAdd(queen.M{Version: "001", Name: "create_users", UpSQL: "CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT)"})
+	q.MustAdd(queen.M{Version: "002", Name: "seed_o'brien", UpSQL: "INSERT INTO users (name) VALUES ('O''Brien');",
+		DownSQL: "DELETE FROM users WHERE name = 'O''Brien'"})
+
+	var script strings.Builder
+	if err := q.GenerateScript(ctx, &script); err != nil {
+		t.Fatalf("GenerateScript() failed: %v", err)
+	}
+	if _, err := db.ExecContext(ctx, script.String()); err != nil {
+		t.Fatalf("applying the script failed: %v\n%s", err, script.String())
+	}
+
+	if err := q.Validate(ctx); err != nil {
+		t.Errorf("Validate() after the script = %v", err)
+	}
+	pending, err := q.Pending(ctx)
+	if err != nil || len(pending) != 0 {
+		t.Errorf("Pending() = %v, %v; want none after the script", pending, err)
+	}
+	applied, err := q.Applied(ctx)
+	if err != nil || len(applied) != 2 || applied[1].Name != "seed_o'brien" || applied[1].DownSQL == "" {
+		t.Errorf("Applied() = %+v, %v; want both recorded with their down SQL", applied, err)
+	}
+}
+
+// numberedDriver translates placeholders to SQLite's ?NNN style, standing
+// in for a database that doesn't take plain ?, and collects the translated
+// statements.
+type numberedDriver struct {
+	*Driver
+	translated *[]string
+}
+
+func (d numberedDriver) TranslatePlaceholders(query string) string {
+	query = strings.ReplaceAll(split.Rebind(query), "$", "?")
+	*d.translated = append(*d.translated, query)
+	return query
+}
+
+func TestTranslatePlaceholders(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	var translated []string
+	q := queen.New(numberedDriver{New(db), &translated})
+	defer q.Close()
+	q.MustAdd(queen.M{
+		Version: "001",
+		Name:    "seed_users",
+		UpSQL: `CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT, note TEXT);
+			INSERT INTO users (id, name, note) VALUES (?, ?, 'why?'), (?, ?, '');`,
+		UpArgs:                []any{1, "alice", 2, "bob"},
+		DownSQL:               "DELETE FROM users WHERE name = ?; DROP TABLE users",
+		DownArgs:              []any{"alice"},
+		TranslatePlaceholders: true,
+	})
+
+	if err := q.Up(ctx); err != nil {
+		t.Fatalf("Up() failed: %v", err)
+	}
+
+	var names, notes string
+	err := db.QueryRowContext(ctx, "SELECT group_concat(name), group_concat(note) FROM users ORDER BY id").Scan(&names, &notes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names != "alice,bob" || notes != "why?," {
+		t.Errorf("Seeded %q with notes %q; want alice,bob with why?", names, notes)
+	}
+	if len(translated) != 2 || !strings.Contains(translated[1], "VALUES (?1, ?2, 'why?'), (?3, ?4, '')") {
+		t.Errorf("Translated %q; want both statements, numbered", translated)
+	}
+
+	if err := q.Down(ctx, 1); err != nil {
+		t.Errorf("Down() failed: %v", err)
+	}
+}
+
+func TestTranslatePlaceholders_Stored(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	var translated []string
+	driver := New(db)
+	q := queen.New(numberedDriver{driver, &translated})
+	defer q.Close()
+	q.MustAdd(queen.M{
+		Version:               "001",
+		Name:                  "seed_users",
+		UpSQL:                 "CREATE TABLE users (id INTEGER); INSERT INTO users (id) VALUES (?)",
+		UpArgs:                []any{1},
+		DownSQL:               "DELETE FROM users WHERE id = ?; DROP TABLE users",
+		DownArgs:              []any{1},
+		TranslatePlaceholders: true,
+	})
+	q.MustAdd(queen.M{
+		Version:               "002",
+		Name:                  "create_tags",
+		UpSQL:                 "CREATE TABLE tags (id INTEGER)",
+		DownSQL:               "DROP TABLE tags; SELECT 1 WHERE 1 = ??",
+		TranslatePlaceholders: true,
+	})
+
+	if err := q.Up(ctx); err != nil {
+		t.Fatalf("Up() failed: %v", err)
+	}
+
+	// The record keeps a DownSQL that runs without the registered migration
+	applied, err := driver.GetApplied(ctx)
+	if err != nil {
+		t.Fatalf("GetApplied() failed: %v", err)
+	}
+	if len(applied) != 2 || applied[0].DownSQL != "" || applied[1].DownSQL != "DROP TABLE tags; SELECT 1 WHERE 1 = ?" {
+		t.Errorf("Stored %+v; want no DownSQL with DownArgs and a translated one without", applied)
+	}
+}
@@ -15,6 +15,36 @@ var (
 	ErrNoDriver          = errors.New("driver not initialized")
 	ErrInvalidMigration  = errors.New("invalid migration")
 	ErrAlreadyApplied    = errors.New("migration already applied")
+	ErrNoneApplied       = errors.New("no migrations applied")
+	ErrOutOfOrder        = errors.New("out-of-order migration")
+	ErrDirty             = errors.New("database is dirty")
+	ErrUnsupported       = errors.New("not supported by driver")
+	ErrTimeout           = errors.New("migration timed out")
+	ErrPermission        = errors.New("missing privilege")
+	ErrUnmetRequirement  = errors.New("unmet requirement")
+	ErrNotLocked         = errors.New("migration not locked")
+	ErrUnknownApplied    = errors.New("applied migration not registered")
+	ErrNotConfirmed      = errors.New("destructive migration not confirmed")
+	ErrProduction        = errors.New("refusing to run in production")
+	ErrLockHazard        = errors.New("lock hazard")
+	ErrObjectNotFound    = errors.New("schema object not found")
+	ErrNoBackup          = errors.New("no backup recorded")
+	ErrRowDelta          = errors.New("unexpected row count change")
+	ErrAssertion         = errors.New("assertion failed")
+	ErrPlanMismatch      = errors.New("plan does not match")
+	ErrPlanNotSigned     = errors.New("plan not signed")
+
+	// ErrIncomplete is returned, possibly wrapped, by an UpFunc that made
+	// progress but isn't finished, such as a canary rollout covering part of
+	// a table. Its transaction is committed, the migration stays pending for
+	// the next Up, and Up stops there without error.
+	ErrIncomplete = errors.New("migration incomplete")
+
+	// ErrContinue is returned, possibly wrapped, by an UpFunc that finished a
+	// chunk of work. Its transaction is committed and the UpFunc is called
+	// again in a new one, until it returns nil or another error. Use it with
+	// the progress package to make long migrations resumable.
+	ErrContinue = errors.New("migration continues")
 )
 
 // MigrationError wraps an error with migration context.
@@ -40,3 +70,28 @@ func newMigrationError(version, name string, err error) error {
 		Err:     err,
 	}
 }
+
+// PermissionError reports a privilege the database role lacks, found by a
+// permission check. It matches ErrPermission with errors.Is.
+type PermissionError struct {
+	Privilege string // e.g. "CREATE"
+	Object    string // e.g. "schema public" or "table queen_migrations"
+	Err       error  // underlying database error, if any
+}
+
+func (e *PermissionError) Error() string {
+	msg := fmt.Sprintf("missing privilege %s on %s", e.Privilege, e.Object)
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+func (e *PermissionError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is ErrPermission.
+func (e *PermissionError) Is(target error) bool {
+	return target == ErrPermission
+}
@@ -0,0 +1,114 @@
+package queen
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// similarMigrations is the number of recorded migrations closest in SQL
+// length averaged to estimate a migration that never ran.
+const similarMigrations = 3
+
+// estimates returns the expected duration of the migrations of plan in
+// one direction, by version. A migration is estimated by the average of
+// its successful runs in the history log, else in Config.EstimateFrom,
+// else by the average of the recorded SQL migrations closest to it in SQL
+// length. Migrations with nothing to estimate from are left out.
+func (q *Queen) estimates(ctx context.Context, plan []*Migration, down bool) (map[string]time.Duration, error) {
+	recorded := make(map[string]time.Duration)
+
+	if q.config.EstimateFrom != nil {
+		history, err := q.config.EstimateFrom.GetHistory(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("estimate from Config.EstimateFrom: %w", err)
+		}
+		averageDurations(recorded, history, down)
+	}
+
+	// The database's own history takes precedence
+	if recorder, ok := optional[HistoryRecorder](q.driver, FeatureHistory); ok {
+		history, err := recorder.GetHistory(ctx)
+		if err != nil {
+			return nil, err
+		}
+		averageDurations(recorded, history, down)
+	}
+
+	// Recorded SQL migrations and their length, to compare sizes with
+	type sized struct {
+		length   int
+		duration time.Duration
+	}
+	var samples []sized
+	for _, m := range q.migrations {
+		if d, ok := recorded[m.Version]; ok && scriptLength(m, down) > 0 {
+			samples = append(samples, sized{scriptLength(m, down), d})
+		}
+	}
+
+	estimates := make(map[string]time.Duration, len(plan))
+	for _, m := range plan {
+		if d, ok := recorded[m.Version]; ok {
+			estimates[m.Version] = d
+			continue
+		}
+
+		length := scriptLength(m, down)
+		if length == 0 || len(samples) == 0 {
+			continue
+		}
+		slices.SortStableFunc(samples, func(a, b sized) int {
+			return abs(a.length-length) - abs(b.length-length)
+		})
+		var total time.Duration
+		n := min(len(samples), similarMigrations)
+		for _, s := range samples[:n] {
+			total += s.duration
+		}
+		estimates[m.Version] = total / time.Duration(n)
+	}
+
+	return estimates, nil
+}
+
+// averageDurations sets the average duration of the successful runs in
+// history in one direction, by version, replacing those in averages.
+func averageDurations(averages map[string]time.Duration, history []HistoryEntry, down bool) {
+	totals := make(map[string]time.Duration)
+	counts := make(map[string]int)
+	for _, e := range history {
+		if e.Error != "" || e.Down != down {
+			continue
+		}
+		totals[e.Version] += e.Duration
+		counts[e.Version]++
+	}
+	for version, total := range totals {
+		averages[version] = total / time.Duration(counts[version])
+	}
+}
+
+// scriptLength returns the length of the SQL m runs in one direction, or
+// 0 if it runs a Go function.
+func scriptLength(m *Migration, down bool) int {
+	if down {
+		if m.DownFunc != nil {
+			return 0
+		}
+		return len(m.DownSQL)
+	}
+	if m.UpFunc != nil {
+		return 0
+	}
+	return len(m.UpSQL)
+}
+
+// abs returns the absolute value of n.
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,67 @@
+package queen_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/honeynil/queen"
+	"github.com/honeynil/queen/drivers/mock"
+)
+
+func TestEstimates(t *testing.T) {
+	ctx := context.Background()
+	driver := mock.New()
+	staging := mock.New()
+	q := queen.NewWithConfig(driver, &queen.Config{EstimateFrom: staging})
+
+	sql := func(n int) string { return "SELECT " + strings.Repeat("1", n-7) }
+	q.MustAdd(queen.M{Version: "001", Name: "tiny", UpSQL: sql(10)})
+	q.MustAdd(queen.M{Version: "002", Name: "small", UpSQL: sql(20)})
+	q.MustAdd(queen.M{Version: "003", Name: "large", UpSQL: sql(2000)})
+	q.MustAdd(queen.M{Version: "004", Name: "larger", UpSQL: sql(2100)})
+	q.MustAdd(queen.M{Version: "005", Name: "staged", UpSQL: sql(10)})
+	q.MustAdd(queen.M{Version: "006", Name: "new", UpSQL: sql(1950)})
+	q.MustAdd(queen.M{Version: "007", Name: "backfill", UpFunc: noop})
+
+	for _, e := range []queen.HistoryEntry{
+		{Version: "001", Duration: time.Millisecond},
+		{Version: "002", Duration: time.Millisecond},
+		{Version: "003", Duration: 100 * time.Millisecond},
+		{Version: "003", Duration: 0, Error: "deadlock"},
+		{Version: "004", Duration: 100 * time.Millisecond},
+		{Version: "004", Duration: 300 * time.Millisecond, Down: true},
+	} {
+		if err := driver.RecordHistory(ctx, e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, e := range []queen.HistoryEntry{
+		{Version: "001", Duration: time.Hour},
+		{Version: "005", Duration: 7 * time.Millisecond},
+	} {
+		if err := staging.RecordHistory(ctx, e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	p, err := q.Plan(ctx)
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+
+	want := map[string]time.Duration{
+		"001": time.Millisecond,       // the database's own history wins
+		"003": 100 * time.Millisecond, // failures don't count
+		"004": 100 * time.Millisecond, // rollbacks don't count
+		"005": 7 * time.Millisecond,   // from staging
+		"006": 67 * time.Millisecond,  // the 3 closest in length: 003, 004 and 002
+		"007": 0,                      // Go functions have no length
+	}
+	for _, s := range p.Steps {
+		if d, ok := want[s.Version]; ok && s.EstimatedDuration != d {
+			t.Errorf("estimate of %s = %s; want %s", s.Version, s.EstimatedDuration, d)
+		}
+	}
+}
@@ -0,0 +1,344 @@
+package queen
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/honeynil/queen/progress"
+)
+
+// Feature names an optional driver capability. Driver covers only what
+// every database can do; each feature is an optional interface a driver
+// may implement, so new features don't break existing drivers. Queen
+// checks for them at runtime and falls back as documented per feature.
+type Feature string
+
+const (
+	// FeatureLocking is provided by Locker. Without it, operations that
+	// take the migration lock fail with ErrUnsupported unless
+	// Config.SkipLock is set.
+	FeatureLocking Feature = "locking"
+
+	// FeatureNoTransaction is provided by NoTxExecer. Without it,
+	// migrations with NoTransaction set fail with ErrUnsupported before
+	// anything executes.
+	FeatureNoTransaction Feature = "no-transaction"
+
+	// FeatureRecordMeta is provided by MetaRecorder. Without it,
+	// migrations are recorded with Driver.Record once they are applied:
+	// no batches, durations or operators are stored, and no dirty marker
+	// is recorded while a migration runs.
+	FeatureRecordMeta Feature = "record-meta"
+
+	// FeatureBatchRecord is provided by BatchRecorder. Without it,
+	// Force and the compat importers record migrations one at a time, and
+	// a failure leaves the ones before it recorded.
+	FeatureBatchRecord Feature = "batch-record"
+
+	// FeatureDirty is provided by DirtyMarker. Without it, rollbacks
+	// aren't marked dirty while they run, and Force clears a dirty record
+	// by recording the migration again.
+	FeatureDirty Feature = "dirty"
+
+	// FeatureHistory is provided by HistoryRecorder. Without it, no
+	// execution log is kept and History returns ErrUnsupported.
+	FeatureHistory Feature = "history"
+
+	// FeatureViews is provided by ViewCreator. Without it,
+	// Config.ReportingViews is ignored.
+	FeatureViews Feature = "views"
+
+	// FeatureStatementSplitting is provided by StatementSplitter, and by
+	// PlaceholderCounter for scripts with bind args. Without it, each
+	// script is executed as a whole; without a PlaceholderCounter, split
+	// scripts with bind args fail with ErrUnsupported.
+	FeatureStatementSplitting Feature = "statement-splitting"
+
+	// FeaturePlaceholders is provided by PlaceholderTranslator. Without
+	// it, TranslatePlaceholders leaves ? placeholders as they are.
+	FeaturePlaceholders Feature = "placeholders"
+
+	// FeaturePermissionCheck is provided by PermissionChecker. Without it,
+	// CheckPermissions returns ErrUnsupported.
+	FeaturePermissionCheck Feature = "permission-check"
+
+	// FeatureCapabilities is provided by CapabilityReporter. Without it,
+	// only flag requirements can be met.
+	FeatureCapabilities Feature = "capabilities"
+
+	// FeatureIsolation is provided by Isolator. Without it,
+	// TestHelper.Isolated fails the test.
+	FeatureIsolation Feature = "isolation"
+
+	// FeatureDropSchema is provided by SchemaDropper. Without it,
+	// ResetHard returns ErrUnsupported.
+	FeatureDropSchema Feature = "drop-schema"
+
+	// FeatureChecksumUpdate is provided by ChecksumUpdater. Without it,
+	// RepairChecksums returns ErrUnsupported.
+	FeatureChecksumUpdate Feature = "checksum-update"
+
+	// FeatureMetadata is provided by ExtendedDriver. Without it,
+	// Config.Metadata is not stored.
+	FeatureMetadata Feature = "metadata"
+
+	// FeatureIntrospection is provided by Introspector. Without it, schema
+	// inspection returns ErrUnsupported.
+	FeatureIntrospection Feature = "introspection"
+
+	// FeatureTableName is provided by TableNamer. Without it,
+	// Config.TableName is ignored and the driver uses the table name it
+	// was created with.
+	FeatureTableName Feature = "table-name"
+
+	// FeatureDDLAudit is provided by DDLAuditor. Without it, Drift doesn't
+	// report schema changes made outside migrations, and AcknowledgeDDL
+	// returns ErrUnsupported.
+	FeatureDDLAudit Feature = "ddl-audit"
+
+	// FeatureReplicationPosition is provided by PositionReporter. Without
+	// it, run reports have no replication positions.
+	FeatureReplicationPosition Feature = "replication-position"
+
+	// FeatureProgress is provided by progress.Store. Without it, the
+	// progress package returns progress.ErrNoStore.
+	FeatureProgress Feature = "progress"
+
+	// FeatureScript is provided by RecordScripter. Without it,
+	// GenerateScript returns ErrUnsupported.
+	FeatureScript Feature = "script"
+)
+
+// features lists every feature, in the order Features reports them.
+var features = []Feature{
+	FeatureLocking, FeatureNoTransaction, FeatureRecordMeta, FeatureBatchRecord, FeatureHistory,
+	FeatureViews, FeatureStatementSplitting, FeaturePermissionCheck,
+	FeatureCapabilities, FeatureIsolation, FeatureDropSchema,
+	FeatureChecksumUpdate, FeatureMetadata, FeatureIntrospection,
+	FeatureTableName, FeatureDDLAudit, FeatureReplicationPosition,
+	FeatureProgress, FeatureScript, FeaturePlaceholders, FeatureDirty,
+}
+
+// FeatureReporter is implemented by drivers that implement an optional
+// interface but can't always provide the feature, e.g. wrappers that
+// forward to another driver, or drivers whose database version lacks it.
+// Queen treats a feature reported as unsupported as if the interface
+// weren't implemented and falls back.
+type FeatureReporter interface {
+	// SupportsFeature reports whether the driver provides f.
+	SupportsFeature(f Feature) bool
+}
+
+// Locker is implemented by drivers that can take an exclusive lock across
+// processes, so concurrent deployments don't migrate at the same time.
+type Locker interface {
+	// Lock acquires an exclusive lock to prevent concurrent migrations.
+	//
+	// If the lock cannot be acquired within the specified timeout, it returns
+	// ErrLockTimeout, wrapping the database's error if there is one. The
+	// lock must be held until Unlock() is called. Queen already bounds
+	// timeout by the deadline of ctx.
+	//
+	// Implementation notes:
+	// - Use database-specific locking (PostgreSQL advisory locks, MySQL named locks, etc.)
+	// - The lock should be exclusive to prevent concurrent migration runs
+	// - Consider using a unique lock identifier based on the migrations table name
+	// - If the database can only try the lock without waiting, use PollLock
+	Lock(ctx context.Context, timeout time.Duration) error
+
+	// Unlock releases the migration lock.
+	// This should be called in a defer statement after acquiring the lock.
+	Unlock(ctx context.Context) error
+}
+
+// NoTxExecer is implemented by drivers that can run statements outside a
+// transaction, for migrations with NoTransaction set.
+type NoTxExecer interface {
+	// ExecNoTx executes a function on a dedicated connection without a
+	// transaction. The connection must be returned to the pool when fn
+	// returns.
+	ExecNoTx(ctx context.Context, fn func(*sql.Conn) error) error
+}
+
+// MetaRecorder is implemented by drivers that store run metadata with the
+// record of a migration: its batch, dirty flag, duration and who applied
+// it. Queen records a dirty marker with it before executing a migration
+// and records it again, clean and with its duration, once execution
+// succeeds.
+type MetaRecorder interface {
+	// RecordWithMeta records m like Driver.Record, with the run metadata
+	// in meta.
+	RecordWithMeta(ctx context.Context, m *Migration, meta RecordMeta) error
+}
+
+// BatchRecorder is implemented by drivers that can record several
+// migrations at once, all or none, e.g. in one transaction. It is used by
+// Force and when importing history from another tool.
+type BatchRecorder interface {
+	// RecordBatch records every migration in migrations like
+	// MetaRecorder.RecordWithMeta, all or none.
+	RecordBatch(ctx context.Context, migrations []*Migration, meta RecordMeta) error
+}
+
+// DirtyMarker is implemented by drivers that can flag an existing record
+// as dirty without rewriting it, so a rollback that fails halfway is left
+// marked. See Applied.Dirty.
+type DirtyMarker interface {
+	// SetDirty sets or clears the dirty flag of an existing migration
+	// record. It is a no-op if the version has no record.
+	SetDirty(ctx context.Context, version string, dirty bool) error
+}
+
+// TableNamer is implemented by drivers whose tracking table name can be
+// set after construction, so Config.TableName applies without repeating
+// it in the driver's constructor.
+type TableNamer interface {
+	// SetTableName sets the name of the tracking table. Tables derived
+	// from it, such as <table>_history, follow. It is called before Init.
+	SetTableName(name string)
+}
+
+// Supports reports whether d provides f: it implements the feature's
+// interface and, if it implements FeatureReporter, reports it supported.
+func Supports(d Driver, f Feature) bool {
+	var ok bool
+	switch f {
+	case FeatureLocking:
+		_, ok = d.(Locker)
+	case FeatureNoTransaction:
+		_, ok = d.(NoTxExecer)
+	case FeatureRecordMeta:
+		_, ok = d.(MetaRecorder)
+	case FeatureBatchRecord:
+		_, ok = d.(BatchRecorder)
+	case FeatureDirty:
+		_, ok = d.(DirtyMarker)
+	case FeatureHistory:
+		_, ok = d.(HistoryRecorder)
+	case FeatureViews:
+		_, ok = d.(ViewCreator)
+	case FeatureStatementSplitting:
+		_, ok = d.(StatementSplitter)
+	case FeaturePermissionCheck:
+		_, ok = d.(PermissionChecker)
+	case FeatureCapabilities:
+		_, ok = d.(CapabilityReporter)
+	case FeatureIsolation:
+		_, ok = d.(Isolator)
+	case FeatureDropSchema:
+		_, ok = d.(SchemaDropper)
+	case FeatureChecksumUpdate:
+		_, ok = d.(ChecksumUpdater)
+	case FeatureMetadata:
+		_, ok = d.(ExtendedDriver)
+	case FeatureIntrospection:
+		_, ok = d.(Introspector)
+	case FeatureTableName:
+		_, ok = d.(TableNamer)
+	case FeatureDDLAudit:
+		_, ok = d.(DDLAuditor)
+	case FeatureReplicationPosition:
+		_, ok = d.(PositionReporter)
+	case FeatureProgress:
+		_, ok = d.(progress.Store)
+	case FeatureScript:
+		_, ok = d.(RecordScripter)
+	case FeaturePlaceholders:
+		_, ok = d.(PlaceholderTranslator)
+	}
+	if !ok {
+		return false
+	}
+
+	if reporter, isReporter := d.(FeatureReporter); isReporter {
+		return reporter.SupportsFeature(f)
+	}
+	return true
+}
+
+// Features returns the features the driver provides.
+func (q *Queen) Features() []Feature {
+	var supported []Feature
+	for _, f := range features {
+		if q.driver != nil && Supports(q.driver, f) {
+			supported = append(supported, f)
+		}
+	}
+	return supported
+}
+
+// optional returns the driver as the interface T of feature f, if the
+// driver supports it.
+func optional[T any](d Driver, f Feature) (T, bool) {
+	impl, ok := d.(T)
+	if !ok || !Supports(d, f) {
+		var zero T
+		return zero, false
+	}
+	return impl, true
+}
+
+// lock takes the migration lock unless Config.SkipLock is set, and turns
+// on Config.Maintenance, returning a function that turns it off and
+// releases the lock.
+func (q *Queen) lock(ctx context.Context) (func(), error) {
+	q.lockWait = 0
+	unlock := func() {}
+	if !q.config.SkipLock {
+		locker, ok := optional[Locker](q.driver, FeatureLocking)
+		if !ok {
+			return nil, fmt.Errorf("%w: locking, set Config.SkipLock to migrate without a lock", ErrUnsupported)
+		}
+
+		timeout := q.config.LockTimeout
+		if deadline, ok := ctx.Deadline(); ok {
+			timeout = min(timeout, time.Until(deadline))
+		}
+
+		start := time.Now()
+		if err := locker.Lock(ctx, timeout); err != nil {
+			return nil, lockError(err, time.Since(start))
+		}
+		q.lockWait = time.Since(start)
+
+		unlock = func() {
+			// Unlock uses background context to complete even if parent context is cancelled.
+			// Unlock errors are non-critical and safely ignored.
+			_ = locker.Unlock(context.Background())
+		}
+	}
+
+	maintenance := q.config.Maintenance
+	if maintenance == nil {
+		return unlock, nil
+	}
+
+	if err := maintenance.Set(ctx); err != nil {
+		unlock()
+		return nil, fmt.Errorf("set maintenance switch: %w", err)
+	}
+
+	return func() {
+		_ = maintenance.Clear(context.Background())
+		unlock()
+	}, nil
+}
+
+// checkFeatures verifies the driver provides the features migrations
+// need, before any of them runs.
+func (q *Queen) checkFeatures(migrations []*Migration) error {
+	if Supports(q.driver, FeatureNoTransaction) {
+		return nil
+	}
+
+	for _, m := range migrations {
+		if m.NoTransaction {
+			return newMigrationError(m.Version, m.Name,
+				fmt.Errorf("%w: running outside a transaction", ErrUnsupported))
+		}
+	}
+
+	return nil
+}
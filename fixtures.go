@@ -0,0 +1,236 @@
+package queen
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// insertTable matches the target table of an INSERT statement in SQL fixtures.
+var insertTable = regexp.MustCompile(`(?i)^\s*INSERT\s+(?:OR\s+\w+\s+)?(?:IGNORE\s+)?INTO\s+([^\s(]+)`)
+
+// LoadFixtures applies pending migrations and inserts the fixture files in
+// dir of fsys, in file name order, so a test gets a migrated and seeded
+// database from one call. Tables that received fixtures are emptied again
+// when the test ends, keeping test cases independent.
+//
+// SQL fixtures (.sql) are executed statement by statement. YAML fixtures
+// (.yaml, .yml) map table names to lists of rows:
+//
+//	users:
+//	  - id: 1
+//	    email: alice@example.com
+//	posts:
+//	  - id: 1
+//	    user_id: 1
+//
+// Tables are filled in the order they appear and emptied in reverse, so
+// list parent tables first.
+func (th *TestHelper) LoadFixtures(fsys fs.FS, dir string) {
+	th.t.Helper()
+
+	th.MustUp()
+
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		th.t.Fatalf("Failed to read fixtures: %v", err)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+
+	var tables []string
+	track := func(table string) {
+		if !slices.Contains(tables, table) {
+			tables = append(tables, table)
+		}
+	}
+
+	// Empty tables even if loading fails halfway
+	th.t.Cleanup(func() {
+		if err := th.truncate(tables); err != nil {
+			th.t.Errorf("Failed to remove fixtures: %v", err)
+		}
+	})
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		name := path.Join(dir, entry.Name())
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			th.t.Fatalf("Failed to read fixture %s: %v", name, err)
+		}
+
+		switch path.Ext(name) {
+		case ".sql":
+			err = th.loadSQLFixture(string(data), track)
+		case ".yaml", ".yml":
+			err = th.loadYAMLFixture(data, track)
+		default:
+			continue
+		}
+		if err != nil {
+			th.t.Fatalf("Failed to load fixture %s: %v", name, err)
+		}
+	}
+}
+
+// loadSQLFixture executes a SQL fixture file.
+func (th *TestHelper) loadSQLFixture(query string, track func(string)) error {
+	statements := []string{query}
+	if split := th.sqlRunner().split; split != nil {
+		statements = split(query)
+	}
+
+	for _, stmt := range statements {
+		if match := insertTable.FindStringSubmatch(stmt); match != nil {
+			track(match[1])
+		}
+	}
+
+	return th.driver.Exec(th.ctx, func(tx *sql.Tx) error {
+		for _, stmt := range statements {
+			if _, err := tx.ExecContext(th.ctx, stmt); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// loadYAMLFixture inserts the rows of a YAML fixture file.
+func (th *TestHelper) loadYAMLFixture(data []byte, track func(string)) error {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	if len(doc.Content) == 0 {
+		return nil
+	}
+
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return fmt.Errorf("expected a mapping of table names to rows")
+	}
+
+	dialect, err := th.fixtureDialect(th.ctx)
+	if err != nil {
+		return err
+	}
+
+	return th.driver.Exec(th.ctx, func(tx *sql.Tx) error {
+		// Mapping nodes hold keys and values alternately, in document order
+		for i := 0; i+1 < len(root.Content); i += 2 {
+			table, rows := root.Content[i].Value, root.Content[i+1]
+			if rows.Kind != yaml.SequenceNode {
+				return fmt.Errorf("table %s: expected a list of rows", table)
+			}
+			track(dialect.quote(table))
+
+			for _, row := range rows.Content {
+				if err := insertRow(th.ctx, tx, table, row, dialect); err != nil {
+					return fmt.Errorf("table %s: %w", table, err)
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// insertRow inserts a YAML mapping node as a row of table.
+func insertRow(ctx context.Context, tx *sql.Tx, table string, row *yaml.Node, dialect fixtureDialect) error {
+	if row.Kind != yaml.MappingNode {
+		return fmt.Errorf("line %d: expected a mapping of columns to values", row.Line)
+	}
+
+	var columns, params []string
+	var args []any
+	for i := 0; i+1 < len(row.Content); i += 2 {
+		var value any
+		if err := row.Content[i+1].Decode(&value); err != nil {
+			return err
+		}
+		columns = append(columns, dialect.quote(row.Content[i].Value))
+		args = append(args, value)
+		params = append(params, dialect.placeholder(len(args)))
+	}
+
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
+		dialect.quote(table), strings.Join(columns, ", "), strings.Join(params, ", "))
+
+	_, err := tx.ExecContext(ctx, query, args...)
+	return err
+}
+
+// fixtureDialect is the identifier quoting and bind parameter style YAML
+// fixtures are inserted with.
+type fixtureDialect struct {
+	quote       func(name string) string
+	placeholder func(n int) string
+}
+
+// fixtureDialect returns the dialect of the driver's database: backticks
+// for MySQL, $n placeholders for PostgreSQL, double quotes and ?
+// placeholders otherwise.
+func (q *Queen) fixtureDialect(ctx context.Context) (fixtureDialect, error) {
+	dialect := fixtureDialect{
+		quote:       quoteWith(`"`),
+		placeholder: func(int) string { return "?" },
+	}
+
+	reporter, ok := optional[CapabilityReporter](q.driver, FeatureCapabilities)
+	if !ok {
+		return dialect, nil
+	}
+
+	caps, err := reporter.Capabilities(ctx)
+	if err != nil {
+		return fixtureDialect{}, err
+	}
+	switch caps.Dialect {
+	case "postgres":
+		dialect.placeholder = func(n int) string { return fmt.Sprintf("$%d", n) }
+	case "mysql":
+		dialect.quote = quoteWith("`")
+	}
+
+	return dialect, nil
+}
+
+// quoteWith returns a function quoting identifiers with q, each part of a
+// schema-qualified name on its own.
+func quoteWith(q string) func(name string) string {
+	return func(name string) string {
+		parts := strings.Split(name, ".")
+		for i, part := range parts {
+			parts[i] = q + strings.ReplaceAll(part, q, q+q) + q
+		}
+		return strings.Join(parts, ".")
+	}
+}
+
+// truncate deletes all rows from tables, in reverse order. Tables are
+// named as in SQL, quoted if they came from YAML fixtures.
+func (th *TestHelper) truncate(tables []string) error {
+	if len(tables) == 0 {
+		return nil
+	}
+
+	return th.driver.Exec(th.ctx, func(tx *sql.Tx) error {
+		for i := len(tables) - 1; i >= 0; i-- {
+			if _, err := tx.ExecContext(th.ctx, "DELETE FROM "+tables[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
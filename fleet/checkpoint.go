@@ -0,0 +1,206 @@
+package fleet
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Checkpoint records that a target completed a plan.
+type Checkpoint struct {
+	// Target is the name of the target.
+	Target string `json:"target"`
+
+	// Plan is the plan hash of the target when it completed, see
+	// Runner.Plan.
+	Plan string `json:"plan"`
+
+	// Completed is when the target completed the plan.
+	Completed time.Time `json:"completed"`
+}
+
+// CheckpointStore persists checkpoints, so a fleet run that was
+// interrupted, by a crash or by cancelling its context to pause it, resumes
+// where it left off: targets that completed their current plan are not
+// opened again. See Options.Checkpoints.
+type CheckpointStore interface {
+	// Checkpoints returns the latest checkpoint of every target.
+	Checkpoints(ctx context.Context) ([]Checkpoint, error)
+
+	// SaveCheckpoint records c, replacing the checkpoint of its target.
+	SaveCheckpoint(ctx context.Context, c Checkpoint) error
+}
+
+// FileCheckpoints returns a CheckpointStore keeping checkpoints in a JSON
+// file at path, created when the first checkpoint is saved. Each save
+// rewrites the file atomically.
+func FileCheckpoints(path string) CheckpointStore {
+	return &fileCheckpoints{path: path}
+}
+
+type fileCheckpoints struct {
+	mu   sync.Mutex
+	path string
+}
+
+func (f *fileCheckpoints) Checkpoints(ctx context.Context) ([]Checkpoint, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	byTarget, err := f.read()
+	if err != nil {
+		return nil, err
+	}
+	return slices.SortedFunc(maps.Values(byTarget), func(a, b Checkpoint) int {
+		return cmp.Compare(a.Target, b.Target)
+	}), nil
+}
+
+func (f *fileCheckpoints) SaveCheckpoint(ctx context.Context, c Checkpoint) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	byTarget, err := f.read()
+	if err != nil {
+		return err
+	}
+	byTarget[c.Target] = c
+
+	data, err := json.MarshalIndent(byTarget, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(f.path), filepath.Base(f.path)+".*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), f.path)
+}
+
+// read returns the checkpoints in the file by target, none if it doesn't
+// exist.
+func (f *fileCheckpoints) read() (map[string]Checkpoint, error) {
+	byTarget := make(map[string]Checkpoint)
+
+	data, err := os.ReadFile(f.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return byTarget, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, &byTarget); err != nil {
+		return nil, fmt.Errorf("%s: %w", f.path, err)
+	}
+	return byTarget, nil
+}
+
+// Plan returns the plan hash of target: a hash of the versions and
+// checksums of the migrations applied to it, and of its overrides. It
+// changes when migrations are added or edited, so a checkpoint only
+// covers the migration set the target completed.
+func (r *Runner) Plan(target Target) (string, error) {
+	migrations, err := r.migrationsFor(target)
+	if err != nil {
+		return "", err
+	}
+
+	h := sha256.New()
+	for _, m := range migrations {
+		// m is a copy, so concurrent runs don't share the checksum cache
+		fmt.Fprintf(h, "%s %s\n", m.Version, m.Checksum())
+	}
+	fmt.Fprintf(h, "overrides %s\n", target.Overrides)
+	for _, k := range slices.Sorted(maps.Keys(target.Overrides.TemplateVars)) {
+		fmt.Fprintf(h, "%s=%v\n", k, target.Overrides.TemplateVars[k])
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// TargetStatus is the progress of a target, as returned by Status.
+type TargetStatus struct {
+	Target Target
+
+	// Plan is the current plan hash of the target.
+	Plan string
+
+	// Checkpoint is the latest checkpoint of the target, nil if it never
+	// completed a plan.
+	Checkpoint *Checkpoint
+}
+
+// Done reports whether the target completed its current plan.
+func (s TargetStatus) Done() bool {
+	return s.Checkpoint != nil && s.Checkpoint.Plan == s.Plan
+}
+
+// Status returns the progress of every target found by discovery, from
+// the checkpoints of Options.Checkpoints, without opening any target.
+// Returns an error if Options.Checkpoints is nil.
+func (r *Runner) Status(ctx context.Context, discovery Discovery) ([]TargetStatus, error) {
+	if r.opts.Checkpoints == nil {
+		return nil, errors.New("fleet status without Options.Checkpoints")
+	}
+
+	targets, err := discovery.Targets(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("discover targets: %w", err)
+	}
+	checkpoints, err := r.checkpoints(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	status := make([]TargetStatus, len(targets))
+	for i, t := range targets {
+		status[i].Target = t
+		if status[i].Plan, err = r.Plan(t); err != nil {
+			return nil, err
+		}
+		if c, ok := checkpoints[t.Name]; ok {
+			status[i].Checkpoint = &c
+		}
+	}
+	return status, nil
+}
+
+// checkpoints returns the checkpoints of Options.Checkpoints by target,
+// none if it is nil.
+func (r *Runner) checkpoints(ctx context.Context) (map[string]Checkpoint, error) {
+	byTarget := make(map[string]Checkpoint)
+	if r.opts.Checkpoints == nil {
+		return byTarget, nil
+	}
+
+	checkpoints, err := r.opts.Checkpoints.Checkpoints(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("load checkpoints: %w", err)
+	}
+	for _, c := range checkpoints {
+		byTarget[c.Target] = c
+	}
+	return byTarget, nil
+}
@@ -0,0 +1,123 @@
+package fleet
+
+import (
+	"context"
+	"fmt"
+)
+
+// Secret is a Kubernetes secret, as listed by a SecretLister.
+type Secret struct {
+	Namespace string
+	Name      string
+	Labels    map[string]string
+	Data      map[string][]byte
+}
+
+// SecretLister lists Kubernetes secrets. It keeps this package free of
+// Kubernetes dependencies; with client-go it is a few lines:
+//
+//	type lister struct{ client kubernetes.Interface }
+//
+//	func (l lister) ListSecrets(ctx context.Context, namespace, selector string) ([]fleet.Secret, error) {
+//	    list, err := l.client.CoreV1().Secrets(namespace).List(ctx, metav1.ListOptions{LabelSelector: selector})
+//	    if err != nil {
+//	        return nil, err
+//	    }
+//	    var secrets []fleet.Secret
+//	    for _, s := range list.Items {
+//	        secrets = append(secrets, fleet.Secret{Namespace: s.Namespace, Name: s.Name, Labels: s.Labels, Data: s.Data})
+//	    }
+//	    return secrets, nil
+//	}
+type SecretLister interface {
+	// ListSecrets returns the secrets of namespace matching the label
+	// selector, e.g. "app=billing,tier=db".
+	ListSecrets(ctx context.Context, namespace, selector string) ([]Secret, error)
+}
+
+// FromKubernetesSecrets returns a Discovery finding a target per secret of
+// namespace matching the label selector, on every run. The DSN is the
+// value of key in the secret, the name is the secret's name and the labels
+// are the secret's labels. A matching secret without key is an error.
+func FromKubernetesSecrets(lister SecretLister, namespace, selector, key string) Discovery {
+	return DiscoveryFunc(func(ctx context.Context) ([]Target, error) {
+		secrets, err := lister.ListSecrets(ctx, namespace, selector)
+		if err != nil {
+			return nil, err
+		}
+
+		var targets []Target
+		for _, s := range secrets {
+			dsn, ok := s.Data[key]
+			if !ok {
+				return nil, fmt.Errorf("secret %s/%s has no key %s", s.Namespace, s.Name, key)
+			}
+			targets = append(targets, Target{Name: s.Name, DSN: string(dsn), Labels: s.Labels})
+		}
+		return targets, nil
+	})
+}
+
+// RDSInstance is an AWS RDS database instance, as listed by an RDSLister.
+type RDSInstance struct {
+	// ID is the DB instance identifier.
+	ID string
+
+	// Engine is the database engine, e.g. "postgres" or "mysql".
+	Engine string
+
+	// Host, Port and DBName locate the database.
+	Host   string
+	Port   int
+	DBName string
+
+	// Tags are the tags of the instance.
+	Tags map[string]string
+}
+
+// RDSLister lists AWS RDS instances. It keeps this package free of AWS
+// dependencies; with the AWS SDK it wraps a paginated DescribeDBInstances,
+// converting each instance and its TagList.
+type RDSLister interface {
+	ListDBInstances(ctx context.Context) ([]RDSInstance, error)
+}
+
+// FromRDS returns a Discovery finding a target per RDS instance carrying
+// every tag of tags, on every run. A tag with an empty value matches any
+// value. The name is the instance ID and the labels are its tags; dsn
+// builds the DSN of an instance, e.g. with credentials from a secret
+// store or an IAM authentication token.
+func FromRDS(lister RDSLister, tags map[string]string, dsn func(ctx context.Context, instance RDSInstance) (string, error)) Discovery {
+	return DiscoveryFunc(func(ctx context.Context) ([]Target, error) {
+		instances, err := lister.ListDBInstances(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		var targets []Target
+		for _, inst := range instances {
+			if !hasTags(inst.Tags, tags) {
+				continue
+			}
+
+			d, err := dsn(ctx, inst)
+			if err != nil {
+				return nil, fmt.Errorf("DSN of RDS instance %s: %w", inst.ID, err)
+			}
+			targets = append(targets, Target{Name: inst.ID, DSN: d, Labels: inst.Tags})
+		}
+		return targets, nil
+	})
+}
+
+// hasTags reports whether have carries every tag of want. An empty value
+// in want matches any value.
+func hasTags(have, want map[string]string) bool {
+	for k, v := range want {
+		got, ok := have[k]
+		if !ok || v != "" && got != v {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,44 @@
+//go:build cgo
+
+package fleet_test
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/honeynil/queen/fleet"
+)
+
+func TestFromQuery(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(`CREATE TABLE tenants (id TEXT, dsn TEXT, region TEXT, active INTEGER);
+		INSERT INTO tenants VALUES ('acme', 'postgres://acme', 'eu', 1), ('globex', 'postgres://globex', NULL, 1), ('gone', 'postgres://gone', 'us', 0)`); err != nil {
+		t.Fatal(err)
+	}
+
+	targets, err := fleet.FromQuery(db, "SELECT id, dsn, region FROM tenants WHERE active = ? ORDER BY id", 1).Targets(context.Background())
+	if err != nil {
+		t.Fatalf("Targets() failed: %v", err)
+	}
+	want := []fleet.Target{
+		{Name: "acme", DSN: "postgres://acme", Labels: map[string]string{"region": "eu"}},
+		{Name: "globex", DSN: "postgres://globex"},
+	}
+	if !reflect.DeepEqual(targets, want) {
+		t.Errorf("Targets() = %+v; want %+v", targets, want)
+	}
+
+	if _, err := fleet.FromQuery(db, "SELECT id FROM tenants").Targets(context.Background()); err == nil {
+		t.Error("expected an error for a query without a DSN column")
+	}
+}
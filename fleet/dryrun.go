@@ -0,0 +1,204 @@
+package fleet
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/honeynil/queen"
+)
+
+// DryRunReport is the outcome of a dry run: what a run would do to each
+// target and how the targets differ, as returned by Runner.DryRun.
+type DryRunReport struct {
+	// Started and Duration time the dry run.
+	Started  time.Time
+	Duration time.Duration
+
+	// Targets lists the plan of every target, in discovery order.
+	Targets []TargetPlan
+
+	// Common lists the versions most targets would apply, in order. A
+	// target whose pending versions differ diverges.
+	Common []string
+}
+
+// TargetPlan is what a run would do to a target.
+type TargetPlan struct {
+	Target Target
+
+	// Pending lists the versions a run would apply, in order.
+	Pending []string
+
+	// Drift is how the target drifted from the migration set: versions
+	// applied but unknown, edited, or dirty, and schema changes made by
+	// hand. Nil if Err is set.
+	Drift *queen.DriftReport
+
+	// Err is the error planning the target, e.g. failing to connect.
+	Err error
+}
+
+// Diverges reports whether the target would not end up like the others:
+// it has drift, its pending versions differ from common, or it couldn't be
+// planned.
+func (p TargetPlan) Diverges(common []string) bool {
+	return p.Err != nil || !p.Drift.Clean() || !slices.Equal(p.Pending, common)
+}
+
+// Divergent returns the plans of the targets that diverge.
+func (r *DryRunReport) Divergent() []TargetPlan {
+	var divergent []TargetPlan
+	for _, p := range r.Targets {
+		if p.Diverges(r.Common) {
+			divergent = append(divergent, p)
+		}
+	}
+	return divergent
+}
+
+// String returns a summary of what most targets would apply, followed by a
+// line per divergent target saying how it differs.
+func (r *DryRunReport) String() string {
+	var b strings.Builder
+
+	divergent := r.Divergent()
+	fmt.Fprintf(&b, "%d targets, %d would apply %s, %d diverge\n",
+		len(r.Targets), len(r.Targets)-len(divergent), versionList(r.Common), len(divergent))
+	if len(divergent) == 0 {
+		return b.String()
+	}
+
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "target\tdivergence")
+	for _, p := range divergent {
+		fmt.Fprintf(w, "%s\t%s\n", p.Target.Name, strings.Join(p.divergence(r.Common), "; "))
+	}
+	_ = w.Flush()
+
+	return b.String()
+}
+
+// divergence describes how the plan differs from common.
+func (p TargetPlan) divergence(common []string) []string {
+	if p.Err != nil {
+		return []string{"FAILED: " + p.Err.Error()}
+	}
+
+	var reasons []string
+	if !slices.Equal(p.Pending, common) {
+		reasons = append(reasons, "would apply "+versionList(p.Pending))
+	}
+	d := p.Drift
+	if len(d.UnknownApplied) > 0 {
+		reasons = append(reasons, "unknown applied "+strings.Join(d.UnknownApplied, ","))
+	}
+	if len(d.ChecksumMismatch) > 0 {
+		reasons = append(reasons, "checksum mismatch "+strings.Join(d.ChecksumMismatch, ","))
+	}
+	if len(d.Dirty) > 0 {
+		reasons = append(reasons, "dirty "+strings.Join(d.Dirty, ","))
+	}
+	if len(d.ManualDDL) > 0 {
+		reasons = append(reasons, fmt.Sprintf("%d manual DDL changes", len(d.ManualDDL)))
+	}
+	return reasons
+}
+
+// versionList returns versions joined with commas, or "nothing".
+func versionList(versions []string) string {
+	if len(versions) == 0 {
+		return "nothing"
+	}
+	return strings.Join(versions, ",")
+}
+
+// DryRun plans every target found by discovery without applying anything,
+// at most Options.Concurrency at once, and aggregates the plans, so targets
+// that differ from the rest show up before a rollout. It returns an error
+// only if discovery fails. Targets are opened once, without retries, and
+// their tracking tables are created if missing.
+func (r *Runner) DryRun(ctx context.Context, discovery Discovery) (*DryRunReport, error) {
+	targets, err := discovery.Targets(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("discover targets: %w", err)
+	}
+
+	report := &DryRunReport{Started: time.Now(), Targets: make([]TargetPlan, len(targets))}
+
+	sem := make(chan struct{}, r.opts.Concurrency)
+	var wg sync.WaitGroup
+	for i, t := range targets {
+		report.Targets[i].Target = t
+
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			report.Targets[i].Err = ctx.Err()
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			report.Targets[i] = r.plan(ctx, t)
+		}()
+	}
+	wg.Wait()
+
+	report.Common = commonPending(report.Targets)
+	report.Duration = time.Since(report.Started)
+	return report, nil
+}
+
+// plan returns the plan of target.
+func (r *Runner) plan(ctx context.Context, target Target) TargetPlan {
+	p := TargetPlan{Target: target}
+
+	q, err := r.newQueen(ctx, target)
+	if err != nil {
+		p.Err = err
+		return p
+	}
+	defer q.Close()
+
+	pending, err := q.Pending(ctx)
+	if err != nil {
+		p.Err = err
+		return p
+	}
+	for _, m := range pending {
+		p.Pending = append(p.Pending, m.Version)
+	}
+
+	if p.Drift, err = q.Drift(ctx); err != nil {
+		p.Drift, p.Err = nil, err
+	}
+	return p
+}
+
+// commonPending returns the most frequent pending versions among the
+// plans without errors, the first seen on ties.
+func commonPending(plans []TargetPlan) []string {
+	counts := make(map[string]int)
+	var common []string
+	best := 0
+	for _, p := range plans {
+		if p.Err != nil {
+			continue
+		}
+		key := strings.Join(p.Pending, "\x00")
+		counts[key]++
+		if counts[key] > best {
+			best, common = counts[key], p.Pending
+		}
+	}
+	return common
+}
@@ -0,0 +1,106 @@
+package fleet
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"slices"
+	"time"
+)
+
+// EventKind identifies a stage of a fleet run.
+type EventKind string
+
+const (
+	// EventWaveStarted is sent before the targets of a wave start, with
+	// the targets in Results.
+	EventWaveStarted EventKind = "wave_started"
+
+	// EventWaveCompleted is sent once every target of a wave is done,
+	// with their outcomes in Results.
+	EventWaveCompleted EventKind = "wave_completed"
+
+	// EventTargetFailed is sent when a target fails after its retries,
+	// with its outcome in Results.
+	EventTargetFailed EventKind = "target_failed"
+
+	// EventHalted is sent when the rollout policy halts the run after a
+	// wave, with the reason in Err.
+	EventHalted EventKind = "rollout_halted"
+)
+
+// Event is a stage of a fleet run, sent to Options.OnEvent, e.g. to drive
+// a dashboard or update a change ticket as the rollout progresses.
+type Event struct {
+	Kind EventKind
+	Time time.Time
+
+	// Wave is the index of the wave, 0 for the canary or a run without
+	// Options.Rollout.
+	Wave int
+
+	// Results are the targets the event is about, see EventKind.
+	Results []TargetResult
+
+	// Err is why the rollout halted, for EventHalted.
+	Err error
+}
+
+// MarshalJSON encodes e with stable field names for webhooks. Targets are
+// encoded by name and labels, without their DSN.
+func (e Event) MarshalJSON() ([]byte, error) {
+	type targetJSON struct {
+		Name       string            `json:"name"`
+		Labels     map[string]string `json:"labels,omitempty"`
+		Overrides  string            `json:"overrides,omitempty"`
+		Applied    []string          `json:"applied,omitempty"`
+		Attempts   int               `json:"attempts"`
+		DurationMS int64             `json:"duration_ms"`
+		Error      string            `json:"error,omitempty"`
+	}
+	type eventJSON struct {
+		Kind    EventKind    `json:"kind"`
+		Time    time.Time    `json:"time"`
+		Wave    int          `json:"wave"`
+		Targets []targetJSON `json:"targets"`
+		Error   string       `json:"error,omitempty"`
+	}
+
+	out := eventJSON{Kind: e.Kind, Time: e.Time, Wave: e.Wave, Targets: []targetJSON{}}
+	if e.Err != nil {
+		out.Error = e.Err.Error()
+	}
+	for _, r := range e.Results {
+		t := targetJSON{
+			Name:       r.Target.Name,
+			Labels:     r.Target.Labels,
+			Overrides:  r.Target.Overrides.String(),
+			Applied:    r.Applied,
+			Attempts:   r.Attempts,
+			DurationMS: r.Duration.Milliseconds(),
+		}
+		if r.Err != nil {
+			t.Error = r.Err.Error()
+		}
+		out.Targets = append(out.Targets, t)
+	}
+
+	return json.Marshal(out)
+}
+
+// emit sends an event to Options.OnEvent, recording its error in report.
+// It is only called from the goroutine of Run, so events arrive in order.
+func (r *Runner) emit(ctx context.Context, report *Report, e Event) {
+	if r.opts.OnEvent == nil {
+		return
+	}
+
+	e.Time = time.Now()
+	e.Results = slices.Clone(e.Results)
+	for i := range e.Results {
+		e.Results[i].Target.Labels = maps.Clone(e.Results[i].Target.Labels)
+	}
+	if err := r.opts.OnEvent(ctx, e); err != nil {
+		report.EventErrors = append(report.EventErrors, err)
+	}
+}
@@ -0,0 +1,664 @@
+// Package fleet applies one migration set to many databases, such as the
+// per-customer databases of a multi-tenant service, with bounded
+// concurrency, per-target retries and a consolidated report:
+//
+//	r := fleet.New(migrations, func(ctx context.Context, t fleet.Target) (queen.Driver, error) {
+//	    db, err := sql.Open("pgx", t.DSN)
+//	    if err != nil {
+//	        return nil, err
+//	    }
+//	    return postgres.New(db), nil
+//	}, fleet.Options{Concurrency: 8, Retries: 2})
+//
+//	report, err := r.Run(ctx, fleet.FromFile("targets.txt"))
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	fmt.Print(report)
+//	if len(report.Failed()) > 0 {
+//	    os.Exit(1)
+//	}
+//
+// Options.Rollout migrates a canary first, then the rest in waves, halting
+// when too many targets fail or a health check says so. Options.OnEvent
+// follows the rollout as waves start and complete, targets fail and the
+// rollout halts, e.g. with notify.Webhook.
+//
+// Runner.DryRun plans every target without applying anything and reports
+// the targets that diverge from the rest: different pending versions,
+// unknown or edited applied migrations, or other drift.
+//
+// Options.Checkpoints records the targets that completed their plan, so an
+// interrupted or paused run resumes where it left off, and Runner.Status
+// shows the progress of every target.
+//
+// Targets come from a Discovery: a static list, a file, an environment
+// variable, a query against a control-plane database, Kubernetes secrets
+// matching a label selector, AWS RDS instances matching tags, any function
+// returning them, or several of these merged.
+package fleet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/honeynil/queen"
+)
+
+// Defaults for Options.
+const (
+	DefaultConcurrency  = 4
+	DefaultRetryBackoff = time.Second
+)
+
+// Target is a database of the fleet.
+type Target struct {
+	// Name identifies the target in reports, e.g. a customer ID. It must
+	// not contain secrets.
+	Name string
+
+	// DSN is the data source name the Opener connects with.
+	DSN string
+
+	// Labels describe the target, e.g. {"region": "eu-west-1"}.
+	Labels map[string]string
+
+	// Overrides are the deviations of the target from the migration set
+	// and Options.Config.
+	Overrides Overrides
+}
+
+// Overrides let a target deviate from the rest of the fleet in a controlled
+// way. They show in the report.
+type Overrides struct {
+	// Skip lists versions not applied to the target, e.g. a migration for
+	// a feature the customer doesn't have. Each must be in the migration
+	// set. Skipped versions already applied are reported as unknown.
+	Skip []string
+
+	// TemplateVars are set over Config.TemplateVars, e.g. {"Shard": "eu3"}.
+	TemplateVars map[string]any
+
+	// Environment replaces Config.Environment, e.g. "staging" for a
+	// customer's test database.
+	Environment string
+}
+
+// String returns a short description of o, e.g.
+// "skip=003,007 env=staging vars=Shard", or "" if o is empty.
+func (o Overrides) String() string {
+	var parts []string
+	if len(o.Skip) > 0 {
+		parts = append(parts, "skip="+strings.Join(o.Skip, ","))
+	}
+	if o.Environment != "" {
+		parts = append(parts, "env="+o.Environment)
+	}
+	if len(o.TemplateVars) > 0 {
+		parts = append(parts, "vars="+strings.Join(slices.Sorted(maps.Keys(o.TemplateVars)), ","))
+	}
+	return strings.Join(parts, " ")
+}
+
+// Opener opens a driver for target. The driver is closed when the target
+// is done.
+type Opener func(ctx context.Context, target Target) (queen.Driver, error)
+
+// Options configures a Runner.
+type Options struct {
+	// Concurrency is the number of targets migrated at once.
+	// Default: DefaultConcurrency
+	Concurrency int
+
+	// Retries is how many times a failed target is tried again, with a
+	// new driver each time. Default: 0
+	Retries int
+
+	// RetryBackoff is the wait before the first retry, doubled for each
+	// next one. Default: DefaultRetryBackoff
+	RetryBackoff time.Duration
+
+	// Config configures the Queen of each target. Default: queen.DefaultConfig()
+	Config *queen.Config
+
+	// Rollout migrates targets in waves, starting with a canary, instead
+	// of all at once. Default: nil (a single wave)
+	Rollout *Rollout
+
+	// GroupLabel names the label grouping targets that share resources,
+	// e.g. "instance_class" or "host", for the budgets of Groups.
+	// Default: "" (all targets are in one group)
+	GroupLabel string
+
+	// Groups are the budgets of the groups, by the value of GroupLabel,
+	// e.g. at most 2 concurrent migrations per database host, on top of
+	// Concurrency overall. Groups not listed use GroupBudget.
+	// Default: nil
+	Groups map[string]Budget
+
+	// GroupBudget is the budget of groups not listed in Groups.
+	// Default: Budget{} (no limit besides Concurrency)
+	GroupBudget Budget
+
+	// OnEvent is called as the run progresses: when waves start and
+	// complete, targets fail and the rollout halts. Its errors are recorded
+	// in Report.EventErrors and don't stop the run. Calls don't overlap.
+	// Default: nil
+	OnEvent func(ctx context.Context, e Event) error
+
+	// Checkpoints records the targets that completed their plan, so a run
+	// skips them and resumes where an interrupted one left off. It must be
+	// safe for concurrent use. Default: nil (every target is opened)
+	Checkpoints CheckpointStore
+}
+
+// Budget limits how hard a group of targets is migrated at once, so a
+// mass rollout doesn't exhaust a shared database host.
+type Budget struct {
+	// Concurrency is the number of targets of the group migrated at once.
+	// Default: 0 (no limit besides Options.Concurrency)
+	Concurrency int
+
+	// Rate is the number of targets of the group started per second, e.g.
+	// 0.5 for one every two seconds. Retries aren't counted.
+	// Default: 0 (no limit)
+	Rate float64
+}
+
+// ErrHalted is the error of the targets a rollout didn't reach because it
+// halted.
+var ErrHalted = errors.New("rollout halted")
+
+// Rollout is a rollout policy: a canary wave is migrated first, then the
+// other targets in waves, each started only if the targets migrated so far
+// look healthy, as a deployment rolls out code:
+//
+//	fleet.Options{Rollout: &fleet.Rollout{
+//	    Canary:       2,
+//	    WaveSize:     20,
+//	    Bake:         10 * time.Minute,
+//	    MaxErrorRate: 0.05,
+//	    Health: func(ctx context.Context, w fleet.Wave) error {
+//	        return checkErrorBudget(ctx, w.Results) // e.g. query the APM
+//	    },
+//	}}
+//
+// Waves are in discovery order. When the rollout halts, the remaining
+// targets fail with ErrHalted and Report.Halted says why.
+type Rollout struct {
+	// Canary is the number of targets of the first wave.
+	Canary int
+
+	// WaveSize is the number of targets of each next wave.
+	// Default: 0 (all targets left, in one wave)
+	WaveSize int
+
+	// Bake is the wait after each wave but the last, before Health is
+	// called and the next wave starts. Default: 0
+	Bake time.Duration
+
+	// MaxErrorRate is the share of failed targets, among all migrated so
+	// far, above which the rollout halts, from 0 to 1.
+	// Default: 0 (any failure halts)
+	MaxErrorRate float64
+
+	// Health, if set, is called after each wave but the last, once Bake
+	// has passed. An error halts the rollout.
+	Health func(ctx context.Context, wave Wave) error
+}
+
+// Wave is a group of targets a rollout migrated together.
+type Wave struct {
+	// Index is the number of the wave, 0 for the canary.
+	Index int
+
+	// Results lists the outcome of each target of the wave.
+	Results []TargetResult
+}
+
+// Runner applies a migration set to the targets of a fleet.
+type Runner struct {
+	migrations []queen.M
+	open       Opener
+	opts       Options
+}
+
+// New creates a Runner applying migrations to targets opened with open.
+func New(migrations []queen.M, open Opener, opts Options) *Runner {
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = DefaultConcurrency
+	}
+	if opts.RetryBackoff <= 0 {
+		opts.RetryBackoff = DefaultRetryBackoff
+	}
+	return &Runner{migrations: migrations, open: open, opts: opts}
+}
+
+// Report is the outcome of a fleet run.
+type Report struct {
+	// Started and Duration time the run.
+	Started  time.Time
+	Duration time.Duration
+
+	// Targets lists the outcome of every target, in discovery order.
+	Targets []TargetResult
+
+	// Halted is why the rollout halted, wrapping ErrHalted, or nil if it
+	// reached every target.
+	Halted error
+
+	// EventErrors lists the errors returned by Options.OnEvent.
+	EventErrors []error
+}
+
+// TargetResult is the outcome of a target.
+type TargetResult struct {
+	Target Target
+
+	// Wave is the rollout wave of the target, 0 without Options.Rollout.
+	Wave int
+
+	// Plan is the plan hash of the target, see Runner.Plan.
+	Plan string
+
+	// Resumed is set if the target was skipped because a checkpoint shows
+	// it completed Plan in an earlier run.
+	Resumed bool
+
+	// Applied lists the versions applied to the target, by every attempt.
+	Applied []string
+
+	// Attempts is how many times the target was tried, 0 if the run was
+	// cancelled before it started.
+	Attempts int
+
+	// Duration is the time spent on the target, retries included.
+	Duration time.Duration
+
+	// Err is the error of the last attempt, nil if it succeeded.
+	Err error
+}
+
+// Failed returns the results of the targets that failed.
+func (r *Report) Failed() []TargetResult {
+	var failed []TargetResult
+	for _, t := range r.Targets {
+		if t.Err != nil {
+			failed = append(failed, t)
+		}
+	}
+	return failed
+}
+
+// String returns a table with a line per target and a summary line.
+func (r *Report) String() string {
+	var b strings.Builder
+
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "target\tresult\tapplied\tattempts\tduration\toverrides")
+	for _, t := range r.Targets {
+		result := "ok"
+		switch {
+		case t.Err != nil:
+			result = "FAILED: " + t.Err.Error()
+		case t.Resumed:
+			result = "done earlier"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%s\t%s\n", t.Target.Name, result, len(t.Applied), t.Attempts,
+			t.Duration.Round(time.Millisecond), t.Target.Overrides)
+	}
+	_ = w.Flush()
+
+	failed := len(r.Failed())
+	fmt.Fprintf(&b, "%d targets, %d succeeded, %d failed in %s\n",
+		len(r.Targets), len(r.Targets)-failed, failed, r.Duration.Round(time.Millisecond))
+	if r.Halted != nil {
+		fmt.Fprintln(&b, r.Halted)
+	}
+
+	return b.String()
+}
+
+// Run migrates every target found by discovery and reports the outcome of
+// each. It returns an error only if discovery fails; failed targets are in
+// the report. Cancelling ctx stops starting new targets and retries.
+func (r *Runner) Run(ctx context.Context, discovery Discovery) (*Report, error) {
+	targets, err := discovery.Targets(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("discover targets: %w", err)
+	}
+
+	checkpoints, err := r.checkpoints(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{Started: time.Now(), Targets: make([]TargetResult, len(targets))}
+	var pending []TargetResult
+	var indexes []int
+	for i, t := range targets {
+		report.Targets[i].Target = t
+
+		plan, err := r.Plan(t)
+		if err != nil {
+			report.Targets[i].Err = err
+			continue
+		}
+		report.Targets[i].Plan = plan
+		if c, ok := checkpoints[t.Name]; ok && c.Plan == plan {
+			report.Targets[i].Resumed = true
+			continue
+		}
+
+		pending = append(pending, report.Targets[i])
+		indexes = append(indexes, i)
+	}
+
+	waves := r.waves(len(pending))
+	for i, end := range waves {
+		if len(pending) == 0 {
+			break
+		}
+
+		start := 0
+		if i > 0 {
+			start = waves[i-1]
+		}
+		wave := pending[start:end]
+		r.emit(ctx, report, Event{Kind: EventWaveStarted, Wave: i, Results: wave})
+		r.runWave(ctx, i, wave, func(res TargetResult) {
+			r.emit(ctx, report, Event{Kind: EventTargetFailed, Wave: i, Results: []TargetResult{res}})
+		})
+		r.emit(ctx, report, Event{Kind: EventWaveCompleted, Wave: i, Results: wave})
+
+		if end == len(pending) {
+			break
+		}
+		if report.Halted = r.evaluate(ctx, Wave{Index: i, Results: wave}, pending[:end]); report.Halted != nil {
+			for j := end; j < len(pending); j++ {
+				pending[j].Err = report.Halted
+			}
+			r.emit(ctx, report, Event{Kind: EventHalted, Wave: i, Err: report.Halted})
+			break
+		}
+	}
+	for j, i := range indexes {
+		report.Targets[i] = pending[j]
+	}
+
+	report.Duration = time.Since(report.Started)
+	return report, nil
+}
+
+// waves returns the end index of each wave of n targets.
+func (r *Runner) waves(n int) []int {
+	rollout := r.opts.Rollout
+	if rollout == nil || rollout.Canary <= 0 || rollout.Canary >= n {
+		return []int{n}
+	}
+
+	ends := []int{rollout.Canary}
+	for end := rollout.Canary; end < n; {
+		if rollout.WaveSize <= 0 {
+			end = n
+		} else {
+			end = min(end+rollout.WaveSize, n)
+		}
+		ends = append(ends, end)
+	}
+	return ends
+}
+
+// runWave migrates the targets of results, within Options.Concurrency and
+// the budgets of their groups, storing the outcome of each in place and
+// calling failed with those that fail.
+// Targets start in order, except that a target whose group is out of budget
+// lets the next ones of other groups go first.
+func (r *Runner) runWave(ctx context.Context, index int, results []TargetResult, failed func(TargetResult)) {
+	queue := make([]int, len(results))
+	for i := range results {
+		results[i].Wave = index
+		queue[i] = i
+	}
+
+	done := make(chan int)
+	running := 0
+	perGroup := make(map[string]int)
+	nextStart := make(map[string]time.Time)
+
+	for len(queue) > 0 && ctx.Err() == nil {
+		now := time.Now()
+		var wait time.Duration
+		started := -1
+		for qi, i := range queue {
+			if running >= r.opts.Concurrency {
+				break
+			}
+
+			group := r.group(results[i].Target)
+			budget := r.budget(group)
+			if budget.Concurrency > 0 && perGroup[group] >= budget.Concurrency {
+				continue
+			}
+			if next := nextStart[group]; now.Before(next) {
+				if wait == 0 || next.Sub(now) < wait {
+					wait = next.Sub(now)
+				}
+				continue
+			}
+
+			running++
+			perGroup[group]++
+			if budget.Rate > 0 {
+				nextStart[group] = now.Add(time.Duration(float64(time.Second) / budget.Rate))
+			}
+			go func() {
+				results[i] = r.migrate(ctx, results[i].Target, results[i].Plan)
+				results[i].Wave = index
+				done <- i
+			}()
+			started = qi
+			break
+		}
+		if started >= 0 {
+			queue = slices.Delete(queue, started, started+1)
+			continue
+		}
+
+		var timer <-chan time.Time
+		if wait > 0 {
+			timer = time.After(wait)
+		}
+		select {
+		case i := <-done:
+			running--
+			perGroup[r.group(results[i].Target)]--
+			if results[i].Err != nil {
+				failed(results[i])
+			}
+		case <-timer:
+		case <-ctx.Done():
+		}
+	}
+
+	for _, i := range queue {
+		results[i].Err = ctx.Err()
+	}
+	for ; running > 0; running-- {
+		if i := <-done; results[i].Err != nil {
+			failed(results[i])
+		}
+	}
+}
+
+// group returns the group of target, its GroupLabel label.
+func (r *Runner) group(target Target) string {
+	if r.opts.GroupLabel == "" {
+		return ""
+	}
+	return target.Labels[r.opts.GroupLabel]
+}
+
+// budget returns the budget of group.
+func (r *Runner) budget(group string) Budget {
+	if b, ok := r.opts.Groups[group]; ok {
+		return b
+	}
+	return r.opts.GroupBudget
+}
+
+// evaluate decides whether the rollout continues after wave, given the
+// results of every target migrated so far. It returns why it halts, or nil.
+func (r *Runner) evaluate(ctx context.Context, wave Wave, done []TargetResult) error {
+	rollout := r.opts.Rollout
+	if ctx.Err() != nil {
+		return fmt.Errorf("%w: %w", ErrHalted, ctx.Err())
+	}
+
+	failed := 0
+	for _, t := range done {
+		if t.Err != nil {
+			failed++
+		}
+	}
+	if rate := float64(failed) / float64(len(done)); rate > rollout.MaxErrorRate {
+		return fmt.Errorf("%w after wave %d: %d of %d targets failed, above the %.0f%% error rate limit",
+			ErrHalted, wave.Index, failed, len(done), rollout.MaxErrorRate*100)
+	}
+
+	if rollout.Bake > 0 {
+		select {
+		case <-time.After(rollout.Bake):
+		case <-ctx.Done():
+			return fmt.Errorf("%w: %w", ErrHalted, ctx.Err())
+		}
+	}
+
+	if rollout.Health != nil {
+		if err := rollout.Health(ctx, wave); err != nil {
+			return fmt.Errorf("%w after wave %d: health check: %w", ErrHalted, wave.Index, err)
+		}
+	}
+
+	return nil
+}
+
+// migrate applies the migrations to target, retrying on failure, and
+// saves a checkpoint of plan once it succeeds.
+func (r *Runner) migrate(ctx context.Context, target Target, plan string) TargetResult {
+	result := TargetResult{Target: target, Plan: plan}
+	start := time.Now()
+	defer func() { result.Duration = time.Since(start) }()
+
+	backoff := r.opts.RetryBackoff
+	for {
+		result.Attempts++
+		result.Err = r.attempt(ctx, target, &result.Applied)
+		if result.Err == nil && r.opts.Checkpoints != nil {
+			c := Checkpoint{Target: target.Name, Plan: plan, Completed: time.Now()}
+			if err := r.opts.Checkpoints.SaveCheckpoint(ctx, c); err != nil {
+				result.Err = fmt.Errorf("save checkpoint: %w", err)
+			}
+			return result
+		}
+		if result.Err == nil || result.Attempts > r.opts.Retries || ctx.Err() != nil {
+			return result
+		}
+
+		select {
+		case <-time.After(backoff):
+			backoff *= 2
+		case <-ctx.Done():
+			result.Err = errors.Join(result.Err, ctx.Err())
+			return result
+		}
+	}
+}
+
+// attempt applies the migrations to target once, appending the applied
+// versions to applied.
+func (r *Runner) attempt(ctx context.Context, target Target, applied *[]string) error {
+	q, err := r.newQueen(ctx, target)
+	if err != nil {
+		return err
+	}
+	defer q.Close()
+
+	q.Hooks().MustRegister(queen.Hook{Name: "fleet", Func: func(ctx context.Context, e queen.Event) error {
+		if e.Kind == queen.EventAfterUp {
+			*applied = append(*applied, e.Migration.Version)
+		}
+		return nil
+	}})
+
+	return q.Up(ctx)
+}
+
+// newQueen opens target and returns a Queen for it, configured with its
+// overrides and with its migrations registered.
+func (r *Runner) newQueen(ctx context.Context, target Target) (*queen.Queen, error) {
+	driver, err := r.open(ctx, target)
+	if err != nil {
+		return nil, fmt.Errorf("open: %w", err)
+	}
+
+	config := queen.DefaultConfig()
+	if r.opts.Config != nil {
+		c := *r.opts.Config
+		config = &c
+	}
+
+	o := target.Overrides
+	if o.Environment != "" {
+		config.Environment = o.Environment
+	}
+	if len(o.TemplateVars) > 0 {
+		vars := maps.Clone(config.TemplateVars)
+		if vars == nil {
+			vars = make(map[string]any)
+		}
+		maps.Copy(vars, o.TemplateVars)
+		config.TemplateVars = vars
+	}
+
+	q := queen.NewWithConfig(driver, config)
+
+	migrations, err := r.migrationsFor(target)
+	if err == nil {
+		err = q.AddAll(migrations...)
+	}
+	if err != nil {
+		_ = q.Close()
+		return nil, err
+	}
+
+	return q, nil
+}
+
+// migrationsFor returns the migration set without the versions target
+// skips.
+func (r *Runner) migrationsFor(target Target) ([]queen.M, error) {
+	skip := target.Overrides.Skip
+	if len(skip) == 0 {
+		return r.migrations, nil
+	}
+
+	var migrations []queen.M
+	for _, m := range r.migrations {
+		if !slices.Contains(skip, m.Version) {
+			migrations = append(migrations, m)
+		}
+	}
+	for _, v := range skip {
+		if !slices.ContainsFunc(r.migrations, func(m queen.M) bool { return m.Version == v }) {
+			return nil, fmt.Errorf("%w: skipped version %s", queen.ErrMigrationNotFound, v)
+		}
+	}
+	return migrations, nil
+}
@@ -0,0 +1,57 @@
+package queen_test
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/honeynil/queen"
+	"github.com/honeynil/queen/drivers/mock"
+	"github.com/honeynil/queen/plantest"
+)
+
+// FuzzPlan checks that the order Up applies migrations in depends only on
+// their versions, not on the order they were registered in.
+func FuzzPlan(f *testing.F) {
+	f.Add(strings.Join(plantest.Seeds, ","))
+	f.Add("002,001,010,001a")
+
+	f.Fuzz(func(t *testing.T, list string) {
+		versions := strings.Split(list, ",")
+
+		reversed := slices.Clone(versions)
+		slices.Reverse(reversed)
+
+		forward, reverse := plan(t, versions), plan(t, reversed)
+
+		if !slices.Equal(forward, reverse) {
+			t.Fatalf("plan depends on registration order: %q vs %q", forward, reverse)
+		}
+		if err := plantest.CheckPlan(plantest.Natural, forward); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+// plan registers versions, skipping invalid and duplicate ones, and
+// returns the pending versions in the order Up would apply them.
+func plan(t *testing.T, versions []string) []string {
+	t.Helper()
+
+	q := queen.New(mock.New())
+	for _, v := range versions {
+		_ = q.Add(queen.M{Version: v, Name: "m", UpFunc: noop})
+	}
+
+	pending, err := q.Pending(context.Background())
+	if err != nil {
+		t.Fatalf("Pending failed: %v", err)
+	}
+
+	order := make([]string, len(pending))
+	for i, m := range pending {
+		order[i] = m.Version
+	}
+	return order
+}
@@ -0,0 +1,88 @@
+// Package fxqueen integrates Queen with go.uber.org/fx.
+//
+// The module builds a Queen instance from a queen.Driver and any migrations
+// provided to the "queen.migrations" value group, then registers lifecycle
+// hooks that run (or validate) migrations when the application starts.
+//
+// # Usage
+//
+//	app := fx.New(
+//	    fx.Provide(func(db *sql.DB) queen.Driver { return postgres.New(db) }),
+//	    fxqueen.Migrations(
+//	        queen.M{Version: "001", Name: "create_users", UpSQL: "...", DownSQL: "..."},
+//	        queen.M{Version: "002", Name: "add_users_name", UpSQL: "...", DownSQL: "..."},
+//	    ),
+//	    fxqueen.Module(lifecycle.Options{RunOnStart: true}),
+//	)
+//
+// A *queen.Config may optionally be provided; otherwise queen.DefaultConfig is
+// used. The module exposes both *queen.Queen and *lifecycle.Component, the
+// latter being suitable for readiness probes.
+package fxqueen
+
+import (
+	"go.uber.org/fx"
+
+	"github.com/honeynil/queen"
+	"github.com/honeynil/queen/lifecycle"
+)
+
+// MigrationsGroup is the fx value group migrations are collected from.
+const MigrationsGroup = "queen.migrations"
+
+// Params are the dependencies required to build a Queen instance.
+type Params struct {
+	fx.In
+
+	Driver     queen.Driver
+	Config     *queen.Config `optional:"true"`
+	Migrations []queen.M     `group:"queen.migrations"`
+}
+
+// Module returns an fx.Option that provides *queen.Queen and
+// *lifecycle.Component and hooks the component into the fx lifecycle.
+func Module(opts lifecycle.Options) fx.Option {
+	return fx.Module("queen",
+		fx.Provide(
+			NewQueen,
+			func(q *queen.Queen) *lifecycle.Component {
+				return lifecycle.New(q, opts)
+			},
+		),
+		fx.Invoke(registerHooks),
+	)
+}
+
+// Migrations supplies migrations to the MigrationsGroup value group.
+// It may be used any number of times, e.g. once per feature module.
+func Migrations(migrations ...queen.M) fx.Option {
+	options := make([]fx.Option, 0, len(migrations))
+	for _, m := range migrations {
+		options = append(options, fx.Supply(fx.Annotated{
+			Group:  MigrationsGroup,
+			Target: m,
+		}))
+	}
+	return fx.Options(options...)
+}
+
+// NewQueen creates a Queen instance and registers all grouped migrations.
+func NewQueen(p Params) (*queen.Queen, error) {
+	q := queen.NewWithConfig(p.Driver, p.Config)
+
+	for _, m := range p.Migrations {
+		if err := q.Add(m); err != nil {
+			return nil, err
+		}
+	}
+
+	return q, nil
+}
+
+// registerHooks binds the component to the application lifecycle.
+func registerHooks(lc fx.Lifecycle, c *lifecycle.Component) {
+	lc.Append(fx.Hook{
+		OnStart: c.Start,
+		OnStop:  c.Stop,
+	})
+}
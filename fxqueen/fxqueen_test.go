@@ -0,0 +1,42 @@
+package fxqueen_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"go.uber.org/fx"
+	"go.uber.org/fx/fxtest"
+
+	"github.com/honeynil/queen"
+	"github.com/honeynil/queen/drivers/mock"
+	"github.com/honeynil/queen/fxqueen"
+	"github.com/honeynil/queen/lifecycle"
+)
+
+func TestModule(t *testing.T) {
+	driver := mock.New()
+	noop := func(ctx context.Context, tx *sql.Tx) error { return nil }
+
+	var c *lifecycle.Component
+	app := fxtest.New(t,
+		fx.Supply(fx.Annotate(driver, fx.As(new(queen.Driver)))),
+		fxqueen.Migrations(
+			queen.M{Version: "001", Name: "first", ManualChecksum: "v1", UpFunc: noop},
+			queen.M{Version: "002", Name: "second", ManualChecksum: "v1", UpFunc: noop},
+		),
+		fxqueen.Module(lifecycle.Options{RunOnStart: true}),
+		fx.Populate(&c),
+	)
+
+	app.RequireStart()
+	defer app.RequireStop()
+
+	if err := c.Ready(context.Background()); err != nil {
+		t.Errorf("Expected component to be ready, got %v", err)
+	}
+
+	if driver.AppliedCount() != 2 {
+		t.Errorf("Expected 2 applied migrations, got %d", driver.AppliedCount())
+	}
+}
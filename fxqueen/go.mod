@@ -0,0 +1,20 @@
+module github.com/honeynil/queen/fxqueen
+
+go 1.25.5
+
+require (
+	github.com/honeynil/queen v0.0.0-20261018045953-b1da0e9ee158
+	go.uber.org/fx v1.24.0
+)
+
+require (
+	go.uber.org/dig v1.19.0 // indirect
+	go.uber.org/multierr v1.10.0 // indirect
+	go.uber.org/zap v1.26.0 // indirect
+	golang.org/x/sys v0.0.0-20220412211240-33da011f77ad // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+// Builds in this repository use the queen next to this module; users get
+// the version required above, the first with this module split out.
+replace github.com/honeynil/queen => ../
@@ -0,0 +1,126 @@
+package queen
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// HistoryEntry is one execution of a migration, successful or not, as kept
+// in the history log. Unlike the tracking table, which only holds what is
+// currently applied, the log is append-only: rollbacks and failures stay
+// on record.
+type HistoryEntry struct {
+	// ID orders entries in the log.
+	ID int64
+
+	// RunID identifies the Up or Down call the execution belonged to,
+	// matching RunReport.ID.
+	RunID string
+
+	// Version is the version of the migration.
+	Version string
+
+	// Name is the name of the migration.
+	Name string
+
+	// Down is true for rollbacks.
+	Down bool
+
+	// Error is the failure message, empty if the execution succeeded.
+	Error string
+
+	// StartedAt is when execution started.
+	StartedAt time.Time
+
+	// Duration is how long execution took, stored with millisecond precision.
+	Duration time.Duration
+
+	// AppliedBy, Hostname, Operator and BuildInfo identify who ran the
+	// migration, from where and with which binary. See Applied.
+	AppliedBy string
+	Hostname  string
+	Operator  string
+	BuildInfo string
+
+	// Backup is the reference of the backup taken before execution, if
+	// Config.Backup took one.
+	Backup string
+
+	// ChangeTicket is the reference of the change ticket of the run, if
+	// Config.ChangeManagement opened one.
+	ChangeTicket string
+
+	// LockWait is how long the run waited for the migration lock, stored
+	// with millisecond precision.
+	LockWait time.Duration
+}
+
+// HistoryRecorder is implemented by drivers that keep an append-only log of
+// migration executions. Queen appends an entry after every up, down and
+// failure.
+type HistoryRecorder interface {
+	// RecordHistory appends e to the log. e.ID is assigned by the driver.
+	RecordHistory(ctx context.Context, e HistoryEntry) error
+
+	// GetHistory returns the whole log, oldest entry first.
+	GetHistory(ctx context.Context) ([]HistoryEntry, error)
+}
+
+// History returns every recorded migration execution, oldest first,
+// including rollbacks and failures.
+//
+// Returns ErrUnsupported if the driver doesn't implement HistoryRecorder.
+func (q *Queen) History(ctx context.Context) ([]HistoryEntry, error) {
+	if q.driver == nil {
+		return nil, ErrNoDriver
+	}
+
+	recorder, ok := optional[HistoryRecorder](q.driver, FeatureHistory)
+	if !ok {
+		return nil, fmt.Errorf("%w: history", ErrUnsupported)
+	}
+
+	if err := q.init(ctx); err != nil {
+		return nil, err
+	}
+
+	return recorder.GetHistory(ctx)
+}
+
+// recordHistory appends a finished execution to the history log, if the
+// driver keeps one. Failing to write it doesn't fail the migration, which
+// has already run; it is reported as a warning of the run instead.
+func (q *Queen) recordHistory(ctx context.Context, e Event) {
+	recorder, ok := optional[HistoryRecorder](q.driver, FeatureHistory)
+	if !ok {
+		return
+	}
+
+	meta := q.recordMeta()
+	entry := HistoryEntry{
+		Version:   e.Migration.Version,
+		Name:      e.Migration.Name,
+		Down:      e.Down,
+		StartedAt: time.Now().Add(-e.Duration),
+		Duration:  e.Duration,
+		AppliedBy: meta.AppliedBy,
+		Hostname:  meta.Hostname,
+		Operator:  meta.Operator,
+		BuildInfo: meta.BuildInfo,
+		Backup:    e.Backup,
+	}
+	if e.Err != nil {
+		entry.Error = e.Err.Error()
+	}
+	if q.report != nil {
+		entry.RunID = q.report.ID
+		entry.ChangeTicket = q.report.ChangeTicket
+		entry.LockWait = q.report.LockWait
+	}
+
+	if err := recorder.RecordHistory(ctx, entry); err != nil && q.report != nil {
+		q.report.Warnings = append(q.report.Warnings,
+			fmt.Sprintf("record history of %s: %v", e.Migration.Version, err))
+	}
+}
@@ -0,0 +1,248 @@
+package queen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrHookExists is returned when registering a hook under a name that is already taken.
+var ErrHookExists = errors.New("hook already registered")
+
+// EventKind identifies the point in a migration run at which hooks fire.
+type EventKind int
+
+const (
+	// EventBeforeUp fires before a migration is applied.
+	// Returning an error from the hook aborts the migration.
+	EventBeforeUp EventKind = iota
+
+	// EventAfterUp fires after a migration has been applied and recorded.
+	EventAfterUp
+
+	// EventBeforeDown fires before a migration is rolled back.
+	// Returning an error from the hook aborts the rollback.
+	EventBeforeDown
+
+	// EventAfterDown fires after a migration has been rolled back.
+	EventAfterDown
+
+	// EventFailed fires when applying or rolling back a migration fails.
+	EventFailed
+
+	// EventWarning reports a non-fatal problem, such as an out-of-order
+	// migration under OutOfOrderWarn. Err describes the problem.
+	EventWarning
+)
+
+// String returns a human-readable representation of the event kind.
+func (k EventKind) String() string {
+	switch k {
+	case EventBeforeUp:
+		return "before_up"
+	case EventAfterUp:
+		return "after_up"
+	case EventBeforeDown:
+		return "before_down"
+	case EventAfterDown:
+		return "after_down"
+	case EventFailed:
+		return "failed"
+	case EventWarning:
+		return "warning"
+	default:
+		return "unknown"
+	}
+}
+
+// Event describes a migration lifecycle event passed to hooks.
+type Event struct {
+	// Kind is the lifecycle point the event was emitted at.
+	Kind EventKind
+
+	// Migration is the migration being applied or rolled back.
+	Migration *Migration
+
+	// Down is true when the event belongs to a rollback.
+	Down bool
+
+	// Duration is how long execution took. Zero for Before* events.
+	Duration time.Duration
+
+	// Err is the execution error for EventFailed or the problem reported
+	// by EventWarning, nil otherwise.
+	Err error
+
+	// Backup is the reference of the backup Config.Backup took before
+	// execution, for EventAfterUp, EventAfterDown and EventFailed.
+	Backup string
+
+	// ETA is the estimated time until the run completes, as in
+	// RunProgress, for the Before* and After* events of a run. 0 if
+	// unknown.
+	ETA time.Duration
+}
+
+// HookFunc handles a lifecycle event.
+//
+// Errors returned for EventBeforeUp and EventBeforeDown abort the operation,
+// which lets hooks act as policies. Errors for the other kinds are ignored;
+// hooks such as notifiers should handle their own failures.
+type HookFunc func(ctx context.Context, e Event) error
+
+// Hook is a named, prioritized HookFunc.
+type Hook struct {
+	// Name identifies the hook for replacement and removal.
+	Name string
+
+	// Priority orders hooks: lower values run first. Hooks with equal
+	// priority run in registration order.
+	Priority int
+
+	// Func is called for every event.
+	Func HookFunc
+}
+
+// HookRegistry is an ordered set of hooks. It is safe for concurrent use.
+//
+// Multiple integrations (metrics, notifications, audit) can register hooks
+// independently and be enabled, replaced or removed by name, e.g. per
+// environment:
+//
+//	q.Hooks().MustRegister(queen.Hook{Name: "audit", Priority: 10, Func: audit})
+//	if env == "dev" {
+//	    q.Hooks().Remove("audit")
+//	}
+type HookRegistry struct {
+	mu    sync.RWMutex
+	hooks []Hook
+	seq   map[string]int
+	next  int
+}
+
+// newHookRegistry creates an empty registry.
+func newHookRegistry() *HookRegistry {
+	return &HookRegistry{
+		seq: make(map[string]int),
+	}
+}
+
+// Register adds a hook. Returns ErrHookExists if the name is already registered.
+func (r *HookRegistry) Register(h Hook) error {
+	if h.Name == "" || h.Func == nil {
+		return fmt.Errorf("invalid hook: name and func are required")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.seq[h.Name]; ok {
+		return fmt.Errorf("%w: %s", ErrHookExists, h.Name)
+	}
+
+	r.seq[h.Name] = r.next
+	r.next++
+	r.hooks = append(r.hooks, h)
+	r.sortLocked()
+
+	return nil
+}
+
+// MustRegister is like Register but panics on error.
+func (r *HookRegistry) MustRegister(h Hook) {
+	if err := r.Register(h); err != nil {
+		panic(err)
+	}
+}
+
+// Replace registers h, replacing any hook with the same name.
+// A replaced hook keeps its original registration order among equal priorities.
+func (r *HookRegistry) Replace(h Hook) error {
+	if h.Name == "" || h.Func == nil {
+		return fmt.Errorf("invalid hook: name and func are required")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.seq[h.Name]; !ok {
+		r.seq[h.Name] = r.next
+		r.next++
+		r.hooks = append(r.hooks, h)
+	} else {
+		for i := range r.hooks {
+			if r.hooks[i].Name == h.Name {
+				r.hooks[i] = h
+				break
+			}
+		}
+	}
+	r.sortLocked()
+
+	return nil
+}
+
+// Remove deletes the hook with the given name and reports whether it existed.
+func (r *HookRegistry) Remove(name string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.seq[name]; !ok {
+		return false
+	}
+
+	delete(r.seq, name)
+	for i := range r.hooks {
+		if r.hooks[i].Name == name {
+			r.hooks = append(r.hooks[:i], r.hooks[i+1:]...)
+			break
+		}
+	}
+
+	return true
+}
+
+// List returns registered hooks in execution order.
+func (r *HookRegistry) List() []Hook {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	hooks := make([]Hook, len(r.hooks))
+	copy(hooks, r.hooks)
+	return hooks
+}
+
+// sortLocked orders hooks by priority, then registration order.
+func (r *HookRegistry) sortLocked() {
+	sort.SliceStable(r.hooks, func(i, j int) bool {
+		if r.hooks[i].Priority != r.hooks[j].Priority {
+			return r.hooks[i].Priority < r.hooks[j].Priority
+		}
+		return r.seq[r.hooks[i].Name] < r.seq[r.hooks[j].Name]
+	})
+}
+
+// emit calls every hook in order. For Before* events the first error stops
+// the chain and is returned; for other events errors are ignored.
+func (r *HookRegistry) emit(ctx context.Context, e Event) error {
+	for _, h := range r.List() {
+		err := h.Func(ctx, e)
+		if err == nil {
+			continue
+		}
+
+		if e.Kind == EventBeforeUp || e.Kind == EventBeforeDown {
+			return fmt.Errorf("hook %s: %w", h.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// Hooks returns the hook registry for this Queen instance.
+func (q *Queen) Hooks() *HookRegistry {
+	return q.hooks
+}
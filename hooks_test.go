@@ -0,0 +1,99 @@
+package queen_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/honeynil/queen"
+)
+
+func TestHookRegistryOrder(t *testing.T) {
+	q, _ := newMockQueen(t, "001")
+	ctx := context.Background()
+
+	var calls []string
+	record := func(name string) queen.HookFunc {
+		return func(ctx context.Context, e queen.Event) error {
+			if e.Kind == queen.EventBeforeUp {
+				calls = append(calls, name)
+			}
+			return nil
+		}
+	}
+
+	q.Hooks().MustRegister(queen.Hook{Name: "notify", Priority: 20, Func: record("notify")})
+	q.Hooks().MustRegister(queen.Hook{Name: "metrics", Priority: 10, Func: record("metrics")})
+	q.Hooks().MustRegister(queen.Hook{Name: "audit", Priority: 10, Func: record("audit")})
+
+	if err := q.Hooks().Register(queen.Hook{Name: "audit", Func: record("dup")}); !errors.Is(err, queen.ErrHookExists) {
+		t.Errorf("Expected ErrHookExists, got %v", err)
+	}
+
+	if err := q.Hooks().Replace(queen.Hook{Name: "metrics", Priority: 10, Func: record("metrics-v2")}); err != nil {
+		t.Fatalf("Replace failed: %v", err)
+	}
+
+	if !q.Hooks().Remove("notify") {
+		t.Error("Expected notify hook to be removed")
+	}
+	if q.Hooks().Remove("notify") {
+		t.Error("Expected second Remove to report false")
+	}
+
+	if err := q.Up(ctx); err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+
+	want := []string{"metrics-v2", "audit"}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("hook calls = %v, want %v", calls, want)
+	}
+}
+
+func TestHookVeto(t *testing.T) {
+	q, driver := newMockQueen(t, "001")
+	veto := errors.New("blocked by policy")
+
+	q.Hooks().MustRegister(queen.Hook{
+		Name: "policy",
+		Func: func(ctx context.Context, e queen.Event) error {
+			if e.Kind == queen.EventBeforeUp {
+				return veto
+			}
+			return nil
+		},
+	})
+
+	if err := q.Up(context.Background()); !errors.Is(err, veto) {
+		t.Errorf("Expected policy error, got %v", err)
+	}
+	if driver.AppliedCount() != 0 {
+		t.Errorf("Expected no migrations applied, got %d", driver.AppliedCount())
+	}
+}
+
+func TestHookFailedEvent(t *testing.T) {
+	q, driver := newMockQueen(t, "001")
+	recordErr := errors.New("record failed")
+	driver.SetRecordError(recordErr)
+
+	var failed error
+	q.Hooks().MustRegister(queen.Hook{
+		Name: "notify",
+		Func: func(ctx context.Context, e queen.Event) error {
+			if e.Kind == queen.EventFailed {
+				failed = e.Err
+			}
+			return nil
+		},
+	})
+
+	if err := q.Up(context.Background()); !errors.Is(err, recordErr) {
+		t.Fatalf("Expected record error, got %v", err)
+	}
+	if !errors.Is(failed, recordErr) {
+		t.Errorf("Expected failed event with record error, got %v", failed)
+	}
+}
@@ -0,0 +1,246 @@
+package httpqueen
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/honeynil/queen"
+)
+
+// AdminOptions configures Admin.
+type AdminOptions struct {
+	// Token is the bearer token requests must send in the Authorization
+	// header. Default: "" (no token accepted)
+	Token string
+
+	// Authorize accepts or rejects requests not carrying Token, e.g. by
+	// checking an SSO header set by a proxy. An error rejects the request
+	// with 403 Forbidden. Default: nil (only Token is accepted)
+	Authorize func(r *http.Request) error
+
+	// ReadOnly disables /up and /down. Default: false
+	ReadOnly bool
+}
+
+// adminHandler serves the admin endpoints of a Queen.
+type adminHandler struct {
+	q    *queen.Queen
+	opts AdminOptions
+
+	// mu serializes the calls that load state into q
+	mu sync.Mutex
+}
+
+// Admin returns an http.Handler serving the admin endpoints of q, see the
+// package documentation. Without Token or Authorize, every request is
+// rejected.
+func Admin(q *queen.Queen, opts AdminOptions) http.Handler {
+	h := &adminHandler{q: q, opts: opts}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /status", h.serveStatus)
+	mux.HandleFunc("GET /pending", h.servePending)
+	mux.HandleFunc("GET /run", h.serveRun)
+	mux.HandleFunc("GET /down", h.servePlanDown)
+	mux.HandleFunc("POST /up", h.serveUp)
+	mux.HandleFunc("POST /down", h.serveDown)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !h.authorized(w, r) {
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// authorized checks the credentials of r, replying if they are rejected.
+func (h *adminHandler) authorized(w http.ResponseWriter, r *http.Request) bool {
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok && h.opts.Token != "" &&
+		subtle.ConstantTimeCompare([]byte(token), []byte(h.opts.Token)) == 1 {
+		return true
+	}
+
+	if h.opts.Authorize == nil {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeError(w, http.StatusUnauthorized, errors.New("missing or invalid bearer token"))
+		return false
+	}
+	if err := h.opts.Authorize(r); err != nil {
+		writeError(w, http.StatusForbidden, err)
+		return false
+	}
+	return true
+}
+
+// lock takes h.mu, replying with 409 Conflict if a run holds it.
+func (h *adminHandler) lock(w http.ResponseWriter) bool {
+	if !h.mu.TryLock() {
+		writeError(w, http.StatusConflict, errors.New("a run is in progress, see /run"))
+		return false
+	}
+	return true
+}
+
+func (h *adminHandler) serveStatus(w http.ResponseWriter, r *http.Request) {
+	if !h.lock(w) {
+		return
+	}
+	defer h.mu.Unlock()
+
+	statuses, err := h.q.Status(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newStatusResponse(statuses, nil))
+}
+
+func (h *adminHandler) servePending(w http.ResponseWriter, r *http.Request) {
+	if !h.lock(w) {
+		return
+	}
+	defer h.mu.Unlock()
+
+	p, err := h.q.Plan(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, p)
+}
+
+func (h *adminHandler) servePlanDown(w http.ResponseWriter, r *http.Request) {
+	n, err := count(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if !h.lock(w) {
+		return
+	}
+	defer h.mu.Unlock()
+
+	p, err := h.q.PlanDown(r.Context(), n)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, p)
+}
+
+func (h *adminHandler) serveRun(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.q.CurrentRun())
+}
+
+func (h *adminHandler) serveUp(w http.ResponseWriter, r *http.Request) {
+	h.serveRunRequest(w, r, h.q.Plan, h.q.UpSteps)
+}
+
+func (h *adminHandler) serveDown(w http.ResponseWriter, r *http.Request) {
+	h.serveRunRequest(w, r, func(ctx context.Context) (*queen.Plan, error) {
+		return h.q.PlanDown(ctx, len(versions(r)))
+	}, h.q.Down)
+}
+
+// serveRunRequest runs the first migrations of the plan returned by plan
+// with run, once the request passes the safeguards.
+func (h *adminHandler) serveRunRequest(w http.ResponseWriter, r *http.Request,
+	plan func(ctx context.Context) (*queen.Plan, error), run func(ctx context.Context, n int) error) {
+	if h.opts.ReadOnly {
+		writeError(w, http.StatusForbidden, errors.New("read-only"))
+		return
+	}
+
+	want := versions(r)
+	if len(want) == 0 {
+		writeError(w, http.StatusBadRequest, errors.New("versions must list the migrations to run"))
+		return
+	}
+
+	if !h.lock(w) {
+		return
+	}
+	defer h.mu.Unlock()
+
+	// The run goes on if the client disconnects, rather than stopping
+	// between migrations
+	ctx := context.WithoutCancel(r.Context())
+
+	p, err := plan(ctx)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	steps := p.Steps
+	if len(steps) > len(want) {
+		steps = steps[:len(want)]
+	}
+	var got, destructive []string
+	for _, s := range steps {
+		got = append(got, s.Version)
+		if s.Destructive {
+			destructive = append(destructive, s.Version)
+		}
+	}
+	if !slices.Equal(got, want) {
+		writeError(w, http.StatusConflict, fmt.Errorf("the plan is %s, not %s", strings.Join(got, ","), strings.Join(want, ",")))
+		return
+	}
+	if len(destructive) > 0 && r.FormValue("destructive") != "true" {
+		writeError(w, http.StatusConflict, fmt.Errorf("%s destructive, set destructive=true to run anyway",
+			strings.Join(destructive, ",")))
+		return
+	}
+
+	resp := runResponse{Operation: p.Operation, Versions: want}
+	code := http.StatusOK
+	if err := run(ctx, len(want)); err != nil {
+		resp.Error = err.Error()
+		code = http.StatusInternalServerError
+	}
+	writeJSON(w, code, resp)
+}
+
+// runResponse is the reply to /up and /down.
+type runResponse struct {
+	Operation queen.Operation `json:"operation"`
+	Versions  []string        `json:"versions"`
+	Error     string          `json:"error,omitempty"`
+}
+
+// versions returns the comma-separated versions parameter of r.
+func versions(r *http.Request) []string {
+	var vs []string
+	for _, v := range strings.Split(r.FormValue("versions"), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			vs = append(vs, v)
+		}
+	}
+	return vs
+}
+
+// count returns the n parameter of r, 1 if missing.
+func count(r *http.Request) (int, error) {
+	s := r.FormValue("n")
+	if s == "" {
+		return 1, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("n must be a positive number, got %q", s)
+	}
+	return n, nil
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,200 @@
+package httpqueen_test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/honeynil/queen"
+	"github.com/honeynil/queen/drivers/mock"
+	"github.com/honeynil/queen/httpqueen"
+)
+
+func noop(ctx context.Context, tx *sql.Tx) error { return nil }
+
+func newAdminQueen(t *testing.T, migrations ...queen.M) (*queen.Queen, *mock.Driver) {
+	t.Helper()
+	driver := mock.New()
+	q := queen.New(driver)
+	t.Cleanup(func() { _ = q.Close() })
+	if err := q.AddAll(migrations...); err != nil {
+		t.Fatal(err)
+	}
+	return q, driver
+}
+
+func serve(h http.Handler, method, target, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestAdmin_Auth(t *testing.T) {
+	q, _ := newAdminQueen(t)
+
+	h := httpqueen.Admin(q, httpqueen.AdminOptions{Token: "secret"})
+	if rec := serve(h, http.MethodGet, "/status", ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("No token = %d; want 401", rec.Code)
+	}
+	if rec := serve(h, http.MethodGet, "/status", "wrong"); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Wrong token = %d; want 401", rec.Code)
+	}
+	if rec := serve(h, http.MethodGet, "/status", "secret"); rec.Code != http.StatusOK {
+		t.Errorf("Token = %d; want 200: %s", rec.Code, rec.Body)
+	}
+
+	// Without Token or Authorize nothing is accepted, not even an empty token
+	h = httpqueen.Admin(q, httpqueen.AdminOptions{})
+	req := httptest.NewRequest(http.MethodGet, "/status", nil)
+	req.Header.Set("Authorization", "Bearer ")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Unconfigured = %d; want 401", rec.Code)
+	}
+
+	h = httpqueen.Admin(q, httpqueen.AdminOptions{Authorize: func(r *http.Request) error {
+		if r.Header.Get("X-User") == "" {
+			return errors.New("no user")
+		}
+		return nil
+	}})
+	if rec := serve(h, http.MethodGet, "/status", ""); rec.Code != http.StatusForbidden {
+		t.Errorf("Rejected by Authorize = %d; want 403", rec.Code)
+	}
+	req = httptest.NewRequest(http.MethodGet, "/status", nil)
+	req.Header.Set("X-User", "alice")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("Accepted by Authorize = %d; want 200", rec.Code)
+	}
+}
+
+func TestAdmin_Status(t *testing.T) {
+	q, _ := newAdminQueen(t, queen.M{Version: "001", Name: "create_users", UpFunc: noop})
+	h := httpqueen.Admin(q, httpqueen.AdminOptions{Token: "secret"})
+
+	// Same body as Monitor.Handler, but pending migrations are not an error
+	rec := serve(h, http.MethodGet, "/status", "secret")
+	var body struct {
+		Ready      bool `json:"ready"`
+		Pending    int  `json:"pending"`
+		Migrations []struct {
+			Version string `json:"version"`
+			Status  string `json:"status"`
+		} `json:"migrations"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("/status = %s: %v", rec.Body, err)
+	}
+	if rec.Code != http.StatusOK || body.Ready || body.Pending != 1 || len(body.Migrations) != 1 ||
+		body.Migrations[0].Version != "001" {
+		t.Errorf("/status = %d %s; want 200 with 001 pending", rec.Code, rec.Body)
+	}
+}
+
+func TestAdmin_UpDown(t *testing.T) {
+	q, driver := newAdminQueen(t,
+		queen.M{Version: "001", Name: "create_users", UpFunc: noop, DownFunc: noop},
+		queen.M{Version: "002", Name: "create_posts", UpFunc: noop, DownFunc: noop},
+	)
+	h := httpqueen.Admin(q, httpqueen.AdminOptions{Token: "secret"})
+
+	rec := serve(h, http.MethodGet, "/pending", "secret")
+	var plan queen.Plan
+	if err := json.Unmarshal(rec.Body.Bytes(), &plan); err != nil {
+		t.Fatalf("/pending = %s: %v", rec.Body, err)
+	}
+	if len(plan.Steps) != 2 || plan.Steps[0].Version != "001" {
+		t.Fatalf("/pending steps = %+v; want 001, 002", plan.Steps)
+	}
+
+	if rec := serve(h, http.MethodGet, "/up", "secret"); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET /up = %d; want 405", rec.Code)
+	}
+	if rec := serve(h, http.MethodPost, "/up", "secret"); rec.Code != http.StatusBadRequest {
+		t.Errorf("/up without versions = %d; want 400", rec.Code)
+	}
+
+	// Versions not matching the plan are refused
+	if rec := serve(h, http.MethodPost, "/up?versions=002", "secret"); rec.Code != http.StatusConflict {
+		t.Errorf("/up?versions=002 = %d; want 409", rec.Code)
+	}
+	if driver.AppliedCount() != 0 {
+		t.Fatalf("Applied %d migrations after a refused run", driver.AppliedCount())
+	}
+
+	rec = serve(h, http.MethodPost, "/up?versions=001", "secret")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("/up?versions=001 = %d: %s", rec.Code, rec.Body)
+	}
+	if !driver.HasVersion("001") || driver.HasVersion("002") {
+		t.Error("Expected only 001 applied")
+	}
+
+	if rec := serve(h, http.MethodPost, "/up?versions=002", "secret"); rec.Code != http.StatusOK {
+		t.Fatalf("/up?versions=002 = %d: %s", rec.Code, rec.Body)
+	}
+
+	rec = serve(h, http.MethodGet, "/down?n=2", "secret")
+	if err := json.Unmarshal(rec.Body.Bytes(), &plan); err != nil {
+		t.Fatalf("GET /down = %s: %v", rec.Body, err)
+	}
+	if len(plan.Steps) != 2 || plan.Steps[0].Version != "002" {
+		t.Fatalf("GET /down steps = %+v; want 002, 001", plan.Steps)
+	}
+
+	if rec := serve(h, http.MethodPost, "/down?versions=001", "secret"); rec.Code != http.StatusConflict {
+		t.Errorf("/down?versions=001 = %d; want 409", rec.Code)
+	}
+	rec = serve(h, http.MethodPost, "/down?versions=002,001", "secret")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("/down?versions=002,001 = %d: %s", rec.Code, rec.Body)
+	}
+	if driver.AppliedCount() != 0 {
+		t.Errorf("Applied %d migrations after rolling back all", driver.AppliedCount())
+	}
+}
+
+func TestAdmin_Safeguards(t *testing.T) {
+	q, driver := newAdminQueen(t,
+		queen.M{Version: "001", Name: "drop_users", UpSQL: "DROP TABLE users"},
+	)
+
+	h := httpqueen.Admin(q, httpqueen.AdminOptions{Token: "secret", ReadOnly: true})
+	if rec := serve(h, http.MethodPost, "/up?versions=001", "secret"); rec.Code != http.StatusForbidden {
+		t.Errorf("/up read-only = %d; want 403", rec.Code)
+	}
+	if rec := serve(h, http.MethodGet, "/pending", "secret"); rec.Code != http.StatusOK {
+		t.Errorf("/pending read-only = %d; want 200", rec.Code)
+	}
+
+	h = httpqueen.Admin(q, httpqueen.AdminOptions{Token: "secret"})
+	rec := serve(h, http.MethodPost, "/up?versions=001", "secret")
+	if rec.Code != http.StatusConflict || !strings.Contains(rec.Body.String(), "destructive") {
+		t.Errorf("/up destructive = %d %s; want 409 naming destructive", rec.Code, rec.Body)
+	}
+	if driver.AppliedCount() != 0 {
+		t.Error("Expected the destructive migration not applied")
+	}
+}
+
+func TestAdmin_Run(t *testing.T) {
+	q, _ := newAdminQueen(t)
+	h := httpqueen.Admin(q, httpqueen.AdminOptions{Token: "secret"})
+
+	rec := serve(h, http.MethodGet, "/run", "secret")
+	if rec.Code != http.StatusOK || strings.TrimSpace(rec.Body.String()) != "null" {
+		t.Errorf("/run idle = %d %s; want 200 null", rec.Code, rec.Body)
+	}
+}
@@ -0,0 +1,240 @@
+// Package httpqueen exposes migration status over HTTP, and an admin API
+// to drive migrations.
+//
+// A Monitor provides a status handler and a standard net/http middleware that
+// plugs into any router built on http.Handler (net/http, chi, gorilla/mux,
+// echo via echo.WrapMiddleware). In strict mode the middleware answers
+// 503 Service Unavailable while migrations are pending.
+//
+// # Usage
+//
+//	m := httpqueen.New(q, httpqueen.Options{Strict: true})
+//
+//	r := chi.NewRouter()
+//	r.Use(m.Middleware)
+//	r.Handle("/migrations", m.Handler())
+//
+// Gin users can adapt the middleware with a small wrapper:
+//
+//	r.Use(func(c *gin.Context) {
+//	    if blocked, _ := m.Blocked(c.Request.Context()); blocked {
+//	        c.AbortWithStatus(http.StatusServiceUnavailable)
+//	        return
+//	    }
+//	    c.Next()
+//	})
+//
+// Status lookups are cached for Options.CacheTTL so that busy endpoints do
+// not query the migrations table on every request.
+//
+// # Admin endpoints
+//
+// Admin serves a Queen to internal admin UIs and runbooks, so they can
+// inspect and drive migrations without shelling into pods:
+//
+//	http.Handle("/admin/migrations/", http.StripPrefix("/admin/migrations",
+//	    httpqueen.Admin(q, httpqueen.AdminOptions{Token: os.Getenv("QUEEN_ADMIN_TOKEN")})))
+//
+// Every request must authenticate with AdminOptions.Token as a bearer token,
+// or pass AdminOptions.Authorize. The endpoints are:
+//
+//   - GET /status: the status of every migration, in the format of Monitor.Handler
+//   - GET /pending: the plan of Up, as Queen.Plan
+//   - GET /run: the run in progress, as Queen.CurrentRun, or null
+//   - POST /up?versions=001,002: applies the pending migrations
+//   - POST /down?versions=003: rolls back the listed applied migrations
+//
+// Runs are guarded: versions must list exactly what the run would execute,
+// as shown by /pending or a GET of /down?n=1, so a plan that changed since
+// it was reviewed is refused with 409 Conflict; plans with destructive
+// migrations also need destructive=true; and one run at a time is allowed.
+// A run continues if the client disconnects.
+package httpqueen
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/honeynil/queen"
+)
+
+// Options configures a Monitor.
+type Options struct {
+	// Strict makes the middleware reject requests with 503 while migrations
+	// are pending or the status cannot be determined. Default: false
+	Strict bool
+
+	// CacheTTL controls how long a status lookup is reused. Default: 5 seconds
+	CacheTTL time.Duration
+
+	// RetryAfter is sent in the Retry-After header of 503 responses.
+	// Default: 5 seconds
+	RetryAfter time.Duration
+}
+
+// Monitor reports migration status for HTTP handlers.
+//
+// Monitor serializes its own status lookups, but Queen itself is not safe
+// for concurrent use: Statuses and Blocked must not run while Up or another
+// method runs on the same Queen. To serve requests while the process
+// migrates, give the Monitor a Queen of its own, on its own connection,
+// with the same migrations registered and never used to migrate:
+//
+//	db, err := sql.Open("postgres", dsn) // separate pool for status lookups
+//	...
+//	status := queen.New(postgres.New(db))
+//	if err := status.AddAll(migrations...); err != nil {
+//	    ...
+//	}
+//	m := httpqueen.New(status, httpqueen.Options{Strict: true})
+//
+// The migrating Queen then runs Up as usual, and the Monitor reports the
+// migrations as pending until they are committed.
+type Monitor struct {
+	q    *queen.Queen
+	opts Options
+
+	mu       sync.Mutex
+	checked  time.Time
+	statuses []queen.MigrationStatus
+	err      error
+}
+
+// New creates a Monitor for q.
+func New(q *queen.Queen, opts Options) *Monitor {
+	if opts.CacheTTL <= 0 {
+		opts.CacheTTL = 5 * time.Second
+	}
+	if opts.RetryAfter <= 0 {
+		opts.RetryAfter = 5 * time.Second
+	}
+
+	return &Monitor{
+		q:    q,
+		opts: opts,
+	}
+}
+
+// Statuses returns the (possibly cached) status of all registered migrations.
+func (m *Monitor) Statuses(ctx context.Context) ([]queen.MigrationStatus, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.checked.IsZero() && time.Since(m.checked) < m.opts.CacheTTL {
+		return m.statuses, m.err
+	}
+
+	m.statuses, m.err = m.q.Status(ctx)
+	m.checked = time.Now()
+
+	return m.statuses, m.err
+}
+
+// Blocked reports whether requests should be rejected.
+// It always returns false outside of strict mode.
+func (m *Monitor) Blocked(ctx context.Context) (bool, error) {
+	if !m.opts.Strict {
+		return false, nil
+	}
+
+	statuses, err := m.Statuses(ctx)
+	if err != nil {
+		return true, err
+	}
+
+	return countPending(statuses) > 0, nil
+}
+
+// Middleware rejects requests with 503 while migrations are pending in strict mode.
+func (m *Monitor) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if blocked, _ := m.Blocked(r.Context()); blocked {
+			m.unavailable(w)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Handler returns an http.Handler that writes migration status as JSON.
+// It responds 200 when all migrations are applied and 503 otherwise.
+func (m *Monitor) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		resp := newStatusResponse(m.Statuses(r.Context()))
+
+		code := http.StatusOK
+		if !resp.Ready {
+			code = http.StatusServiceUnavailable
+		}
+		writeJSON(w, code, resp)
+	})
+}
+
+// unavailable writes a 503 response with a Retry-After header.
+func (m *Monitor) unavailable(w http.ResponseWriter) {
+	w.Header().Set("Retry-After", retryAfterSeconds(m.opts.RetryAfter))
+	http.Error(w, "migrations pending", http.StatusServiceUnavailable)
+}
+
+type statusResponse struct {
+	Ready      bool            `json:"ready"`
+	Pending    int             `json:"pending"`
+	Error      string          `json:"error,omitempty"`
+	Migrations []migrationJSON `json:"migrations"`
+}
+
+type migrationJSON struct {
+	Version   string     `json:"version"`
+	Name      string     `json:"name"`
+	Status    string     `json:"status"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+}
+
+// newStatusResponse encodes the result of a status lookup, shared by
+// Monitor.Handler and the admin /status endpoint.
+func newStatusResponse(statuses []queen.MigrationStatus, err error) statusResponse {
+	resp := statusResponse{
+		Migrations: make([]migrationJSON, 0, len(statuses)),
+	}
+	for _, s := range statuses {
+		resp.Migrations = append(resp.Migrations, migrationJSON{
+			Version:   s.Version,
+			Name:      s.Name,
+			Status:    s.Status.String(),
+			AppliedAt: s.AppliedAt,
+		})
+	}
+	resp.Pending = countPending(statuses)
+	resp.Ready = err == nil && resp.Pending == 0
+	if err != nil {
+		resp.Error = err.Error()
+	}
+	return resp
+}
+
+// countPending returns the number of migrations that are not applied yet.
+func countPending(statuses []queen.MigrationStatus) int {
+	n := 0
+	for _, s := range statuses {
+		if s.Status == queen.StatusPending {
+			n++
+		}
+	}
+	return n
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// retryAfterSeconds formats d as whole seconds, rounding up.
+func retryAfterSeconds(d time.Duration) string {
+	secs := int64((d + time.Second - 1) / time.Second)
+	return strconv.FormatInt(secs, 10)
+}
@@ -0,0 +1,95 @@
+package httpqueen_test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/honeynil/queen"
+	"github.com/honeynil/queen/drivers/mock"
+	"github.com/honeynil/queen/httpqueen"
+)
+
+func newQueen() *queen.Queen {
+	q := queen.New(mock.New())
+	q.MustAdd(queen.M{
+		Version:        "001",
+		Name:           "first",
+		ManualChecksum: "v1",
+		UpFunc:         func(ctx context.Context, tx *sql.Tx) error { return nil },
+	})
+	return q
+}
+
+var okHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+})
+
+func TestMiddleware_Strict(t *testing.T) {
+	q := newQueen()
+	m := httpqueen.New(q, httpqueen.Options{Strict: true, CacheTTL: time.Nanosecond})
+	h := m.Middleware(okHandler)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 while pending, got %d", rec.Code)
+	}
+	if rec.Header().Get("Retry-After") != "5" {
+		t.Errorf("Expected Retry-After 5, got %q", rec.Header().Get("Retry-After"))
+	}
+
+	if err := q.Up(context.Background()); err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+	time.Sleep(time.Millisecond)
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected 200 after migrations, got %d", rec.Code)
+	}
+}
+
+func TestMiddleware_NonStrict(t *testing.T) {
+	m := httpqueen.New(newQueen(), httpqueen.Options{})
+
+	rec := httptest.NewRecorder()
+	m.Middleware(okHandler).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected 200 in non-strict mode, got %d", rec.Code)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	m := httpqueen.New(newQueen(), httpqueen.Options{})
+
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/migrations", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 while pending, got %d", rec.Code)
+	}
+
+	var body struct {
+		Ready      bool `json:"ready"`
+		Pending    int  `json:"pending"`
+		Migrations []struct {
+			Version string `json:"version"`
+			Status  string `json:"status"`
+		} `json:"migrations"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	if body.Ready || body.Pending != 1 {
+		t.Errorf("Expected not ready with 1 pending, got ready=%v pending=%d", body.Ready, body.Pending)
+	}
+	if len(body.Migrations) != 1 || body.Migrations[0].Status != "pending" {
+		t.Errorf("Unexpected migrations in response: %+v", body.Migrations)
+	}
+}
@@ -0,0 +1,65 @@
+package queen
+
+import (
+	"crypto/rand"
+	"time"
+)
+
+// IDGenerator creates the identifiers Queen assigns, such as RunReport.ID
+// and HistoryEntry.RunID. Plug in your own to match the correlation IDs of
+// the rest of your observability stack. Identifiers must be unique, and
+// should sort by creation time.
+type IDGenerator interface {
+	// NewID returns a new identifier.
+	NewID() string
+}
+
+// IDGeneratorFunc adapts a function to IDGenerator.
+type IDGeneratorFunc func() string
+
+// NewID calls f.
+func (f IDGeneratorFunc) NewID() string {
+	return f()
+}
+
+// ULID generates ULIDs: 26-character, lexicographically sortable
+// identifiers made of a millisecond timestamp and 80 random bits, encoded
+// in Crockford's base32. It is the default IDGenerator.
+var ULID IDGenerator = IDGeneratorFunc(newULID)
+
+// crockford is the base32 alphabet of ULIDs.
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// newULID returns a ULID for the current time.
+func newULID() string {
+	var b [16]byte
+	ms := uint64(time.Now().UnixMilli())
+	for i := 5; i >= 0; i-- {
+		b[i] = byte(ms)
+		ms >>= 8
+	}
+	_, _ = rand.Read(b[6:])
+
+	// 128 bits as 26 base32 digits, the first holding the top 3 bits
+	var out [26]byte
+	var hi, lo uint64
+	for i := range 8 {
+		hi = hi<<8 | uint64(b[i])
+		lo = lo<<8 | uint64(b[i+8])
+	}
+	for i := 25; i >= 0; i-- {
+		out[i] = crockford[lo&31]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+
+	return string(out[:])
+}
+
+// newID returns an identifier from Config.IDGenerator, or a ULID.
+func (q *Queen) newID() string {
+	if q.config.IDGenerator != nil {
+		return q.config.IDGenerator.NewID()
+	}
+	return ULID.NewID()
+}
@@ -0,0 +1,145 @@
+package checksum
+
+import (
+	"bytes"
+	"strings"
+)
+
+// Normalization selects formatting differences to ignore when hashing SQL.
+// Values can be combined with |.
+type Normalization int
+
+const (
+	// StripComments removes -- line comments and /* */ block comments.
+	StripComments Normalization = 1 << iota
+
+	// CollapseWhitespace replaces each run of whitespace with a single
+	// space and trims leading and trailing whitespace.
+	CollapseWhitespace
+
+	// TrimSemicolons removes semicolons (and whitespace) at the end of
+	// the script.
+	TrimSemicolons
+)
+
+// Normalize returns query with the given normalizations applied. String
+// literals, quoted identifiers and dollar-quoted bodies are kept verbatim.
+func Normalize(query string, n Normalization) string {
+	if n == 0 {
+		return query
+	}
+
+	out := make([]byte, 0, len(query))
+	separate := false // a removed comment or whitespace run separates tokens
+
+	for i := 0; i < len(query); {
+		c := query[i]
+
+		switch {
+		case c == '-' && strings.HasPrefix(query[i:], "--") && n&StripComments != 0:
+			out = bytes.TrimRight(out, " \t")
+			i = lineEnd(query, i)
+			separate = true
+			continue
+
+		case c == '/' && strings.HasPrefix(query[i:], "/*") && n&StripComments != 0:
+			i = indexAfter(query, i+2, "*/")
+			separate = true
+			continue
+
+		case isSpace(c) && n&CollapseWhitespace != 0:
+			for i < len(query) && isSpace(query[i]) {
+				i++
+			}
+			separate = true
+			continue
+		}
+
+		// Keep "a/**/b" from becoming "ab", without doubling spaces
+		if separate {
+			if len(out) > 0 && !isSpace(out[len(out)-1]) && !isSpace(c) {
+				out = append(out, ' ')
+			}
+			separate = false
+		}
+
+		end := i + 1
+		switch c {
+		case '\'', '"', '`':
+			end = quotedEnd(query, i, c)
+		case '$':
+			end = dollarQuotedEnd(query, i)
+		}
+
+		out = append(out, query[i:end]...)
+		i = end
+	}
+
+	if n&TrimSemicolons != 0 {
+		out = bytes.TrimRight(out, "; \t\n\r\f\v")
+	}
+
+	return string(out)
+}
+
+// lineEnd returns the offset of the newline ending the line at i, or
+// len(s) if it is the last line.
+func lineEnd(s string, i int) int {
+	n := strings.IndexByte(s[i:], '\n')
+	if n < 0 {
+		return len(s)
+	}
+	return i + n
+}
+
+// indexAfter returns the offset just past the next end at or after i, or
+// len(s) if there is none.
+func indexAfter(s string, i int, end string) int {
+	n := strings.Index(s[i:], end)
+	if n < 0 {
+		return len(s)
+	}
+	return i + n + len(end)
+}
+
+// quotedEnd returns the offset just past the string or identifier quoted
+// with q that starts at i. A doubled quote doesn't end it.
+func quotedEnd(s string, i int, q byte) int {
+	for j := i + 1; j < len(s); j++ {
+		if s[j] != q {
+			continue
+		}
+		if j+1 < len(s) && s[j+1] == q {
+			j++
+			continue
+		}
+		return j + 1
+	}
+	return len(s)
+}
+
+// dollarQuotedEnd returns the offset just past the $tag$ ... $tag$ string
+// starting at i, or i+1 if the '$' doesn't open one, as in $1.
+func dollarQuotedEnd(s string, i int) int {
+	j := i + 1
+	for j < len(s) && (s[j] == '_' || isAlnum(s[j])) {
+		j++
+	}
+	if j >= len(s) || s[j] != '$' || (j > i+1 && isDigit(s[i+1])) {
+		return i + 1
+	}
+
+	return indexAfter(s, j+1, s[i:j+1])
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isAlnum(c byte) bool {
+	return isDigit(c) || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c >= 0x80
+}
@@ -0,0 +1,51 @@
+package checksum
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	all := StripComments | CollapseWhitespace | TrimSemicolons
+
+	tests := []struct {
+		name  string
+		query string
+		n     Normalization
+		want  string
+	}{
+		{"none", "SELECT  1; -- x", 0, "SELECT  1; -- x"},
+		{"line comment", "SELECT 1 -- one\nFROM t", StripComments, "SELECT 1\nFROM t"},
+		{"block comment", "SELECT /* cols */ 1", StripComments | CollapseWhitespace, "SELECT 1"},
+		{"block comment between tokens", "a/**/b", StripComments, "a b"},
+		{"whitespace", "\n\tCREATE TABLE  users (\n\t\tid INT\n\t)\n", CollapseWhitespace, "CREATE TABLE users ( id INT )"},
+		{"semicolons", "DROP TABLE users;;\n", TrimSemicolons, "DROP TABLE users"},
+		{"inner semicolons kept", "SELECT 1; SELECT 2;", TrimSemicolons, "SELECT 1; SELECT 2"},
+		{"string literal", "SELECT '--  not /* a */ comment'", all, "SELECT '--  not /* a */ comment'"},
+		{"escaped quote", "SELECT 'it''s  -- here'", all, "SELECT 'it''s  -- here'"},
+		{"quoted identifier", `SELECT "a  b" FROM t`, all, `SELECT "a  b" FROM t`},
+		{"dollar quoted", "SELECT $$ --  body $$, $1", all, "SELECT $$ --  body $$, $1"},
+		{"tagged dollar quote", "DO $fn$ BEGIN  NULL; END $fn$;", all, "DO $fn$ BEGIN  NULL; END $fn$"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Normalize(tt.query, tt.n); got != tt.want {
+				t.Errorf("Normalize(%q) = %q, want %q", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeEquivalent(t *testing.T) {
+	a := `CREATE TABLE users ( id INT, email TEXT );`
+	b := `
+		-- Users of the application
+		CREATE TABLE users (
+			id    INT,  /* primary key */
+			email TEXT
+		)
+	`
+
+	n := StripComments | CollapseWhitespace | TrimSemicolons
+	if Normalize(a, n) != Normalize(b, n) {
+		t.Errorf("expected equal normalization:\n%q\n%q", Normalize(a, n), Normalize(b, n))
+	}
+}
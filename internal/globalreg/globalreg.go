@@ -0,0 +1,35 @@
+// Package globalreg stores the migrations registered with the public
+// registry package. It lives apart from both so that package queen can
+// load them without importing registry, which imports queen.
+package globalreg
+
+import "sync"
+
+var (
+	mu       sync.Mutex
+	versions = make(map[string]bool)
+	entries  []any
+)
+
+// Add stores m under version. It reports false, storing nothing, if the
+// version is already stored.
+func Add(version string, m any) bool {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if versions[version] {
+		return false
+	}
+	versions[version] = true
+	entries = append(entries, m)
+
+	return true
+}
+
+// All returns the stored migrations in the order they were added.
+func All() []any {
+	mu.Lock()
+	defer mu.Unlock()
+
+	return append([]any(nil), entries...)
+}
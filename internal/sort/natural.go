@@ -4,6 +4,7 @@
 package sort
 
 import (
+	"strings"
 	"unicode"
 )
 
@@ -20,18 +21,21 @@ import (
 //	Compare("10", "2") = 1
 //	Compare("v1", "v10") = -1
 //	Compare("user_001", "user_002") = -1
+//
+// Compare is a total order: it returns 0 only for equal strings, and
+// versions equal as numbers, such as "1" and "001", order by length.
 func Compare(a, b string) int {
 	ia, ib := 0, 0
 
 	for ia < len(a) && ib < len(b) {
 		// Extract numeric parts
-		numA, nextA := extractNumber(a, ia)
-		numB, nextB := extractNumber(b, ib)
+		_, nextA := extractNumber(a, ia)
+		_, nextB := extractNumber(b, ib)
 
 		// If both have numbers, compare numerically
 		if nextA > ia && nextB > ib {
-			if numA != numB {
-				return sign(numA - numB)
+			if c := compareDigits(a[ia:nextA], b[ib:nextB]); c != 0 {
+				return c
 			}
 			ia, ib = nextA, nextB
 			continue
@@ -51,8 +55,31 @@ func Compare(a, b string) int {
 		ia, ib = nextA, nextB
 	}
 
-	// If one string is a prefix of the other, shorter comes first
-	return sign(len(a) - len(b))
+	// If one version is a prefix of the other, shorter comes first
+	switch {
+	case ia < len(a):
+		return 1
+	case ib < len(b):
+		return -1
+	}
+
+	// Same parts, differing only in zero padding
+	if c := sign(len(a) - len(b)); c != 0 {
+		return c
+	}
+	return strings.Compare(a, b)
+}
+
+// compareDigits compares two runs of digits by numeric value without
+// converting them, so arbitrarily long numbers compare correctly.
+func compareDigits(a, b string) int {
+	a = strings.TrimLeft(a, "0")
+	b = strings.TrimLeft(b, "0")
+
+	if c := sign(len(a) - len(b)); c != 0 {
+		return c
+	}
+	return strings.Compare(a, b)
 }
 
 // extractNumber extracts a number from the string starting at position i.
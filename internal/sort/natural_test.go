@@ -49,6 +49,14 @@ func TestCompare(t *testing.T) {
 
 		// Length differences
 		{"abc < abcd", "abc", "abcd", -1},
+
+		// Zero padding: equal numbers never compare equal
+		{"1 < 01", "1", "01", -1},
+		{"01 < 1a", "01", "1a", -1},
+		{"001 < 1a", "001", "1a", -1},
+
+		// Numbers beyond int64
+		{"long numbers", "99999999999999999999", "100000000000000000000", -1},
 	}
 
 	for _, tt := range tests {
@@ -0,0 +1,171 @@
+package split
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Rebind rewrites the ? placeholders of a PostgreSQL query to $1, $2, ...
+// in order. Question marks in string literals, quoted identifiers,
+// comments and dollar-quoted bodies are left alone, and ?? stands for a
+// literal ?, e.g. for the jsonb ? operator.
+//
+// Examples:
+//
+//	Rebind("INSERT INTO t (a, b) VALUES (?, '?')") = "INSERT INTO t (a, b) VALUES ($1, '?')"
+//	Rebind("SELECT data ?? 'key' FROM t WHERE id = ?") = "SELECT data ? 'key' FROM t WHERE id = $1"
+func Rebind(query string) string {
+	if !strings.Contains(query, "?") {
+		return query
+	}
+
+	s := splitter{src: query, dialect: Postgres}
+	var b strings.Builder
+	n := 0
+
+	i := 0
+	for i < len(s.src) {
+		c := s.src[i]
+		start := i
+
+		switch {
+		case c == '?' && i+1 < len(s.src) && s.src[i+1] == '?':
+			b.WriteByte('?')
+			i += 2
+			continue
+
+		case c == '?':
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			i++
+			continue
+
+		case c == '-' && s.isLineComment(i):
+			i = skipLine(s.src, i)
+
+		case c == '/' && i+1 < len(s.src) && s.src[i+1] == '*':
+			i = s.skipBlockComment(i)
+
+		case c == '\'':
+			backslash := s.lastWordEnd == i && strings.EqualFold(s.lastWord, "E")
+			i = skipQuoted(s.src, i, '\'', backslash)
+
+		case c == '"':
+			i = skipQuoted(s.src, i, '"', false)
+
+		case c == '$':
+			i = s.skipDollarQuoted(i)
+
+		case isWordStart(c):
+			i = s.word(i)
+
+		default:
+			i++
+		}
+
+		b.WriteString(s.src[start:i])
+	}
+
+	return b.String()
+}
+
+// CountPlaceholders returns the number of bind args query takes in the
+// native placeholder style of dialect, skipping literals, quoted
+// identifiers and comments:
+//
+//   - Postgres: the highest $n
+//   - MySQL: the number of ? placeholders
+//   - SQLite: the highest parameter number, with ? taking the next number,
+//     ?NNN number NNN, and :name, @name and $name the next number on their
+//     first use
+//
+// Examples:
+//
+//	CountPlaceholders("INSERT INTO t VALUES ($1, $2, $1)", Postgres) = 2
+//	CountPlaceholders("INSERT INTO t VALUES (?, '?')", MySQL) = 1
+func CountPlaceholders(query string, dialect Dialect) int {
+	s := splitter{src: query, dialect: dialect}
+	var named map[string]bool
+	n := 0
+
+	i := 0
+	for i < len(s.src) {
+		c := s.src[i]
+
+		switch {
+		case c == '-' && s.isLineComment(i):
+			i = skipLine(s.src, i)
+
+		case c == '#' && dialect == MySQL:
+			i = skipLine(s.src, i)
+
+		case c == '/' && i+1 < len(s.src) && s.src[i+1] == '*':
+			i = s.skipBlockComment(i)
+
+		case c == '\'':
+			backslash := dialect == MySQL ||
+				(dialect == Postgres && s.lastWordEnd == i && strings.EqualFold(s.lastWord, "E"))
+			i = skipQuoted(s.src, i, '\'', backslash)
+
+		case c == '"':
+			i = skipQuoted(s.src, i, '"', dialect == MySQL)
+
+		case c == '`' && dialect != Postgres:
+			i = skipQuoted(s.src, i, '`', false)
+
+		case c == '[' && dialect == SQLite:
+			i = skipTo(s.src, i+1, "]")
+
+		case c == '$' && dialect == Postgres:
+			if j := skipDigits(s.src, i+1); j > i+1 {
+				num, _ := strconv.Atoi(s.src[i+1 : j])
+				n, i = max(n, num), j
+			} else {
+				i = s.skipDollarQuoted(i)
+			}
+
+		case c == '?' && dialect == MySQL:
+			n++
+			i++
+
+		case c == '?' && dialect == SQLite:
+			if j := skipDigits(s.src, i+1); j > i+1 {
+				num, _ := strconv.Atoi(s.src[i+1 : j])
+				n, i = max(n, num), j
+			} else {
+				n++
+				i++
+			}
+
+		case (c == ':' || c == '@' || c == '$') && dialect == SQLite && i+1 < len(s.src) && isWordStart(s.src[i+1]):
+			j := i + 1
+			for j < len(s.src) && isWordPart(s.src[j]) {
+				j++
+			}
+			if name := s.src[i:j]; !named[name] {
+				if named == nil {
+					named = make(map[string]bool)
+				}
+				named[name] = true
+				n++
+			}
+			i = j
+
+		case isWordStart(c):
+			i = s.word(i)
+
+		default:
+			i++
+		}
+	}
+
+	return n
+}
+
+// skipDigits returns the offset of the first non-digit at or after i.
+func skipDigits(src string, i int) int {
+	for i < len(src) && src[i] >= '0' && src[i] <= '9' {
+		i++
+	}
+	return i
+}
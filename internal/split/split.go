@@ -0,0 +1,306 @@
+// Package split breaks multi-statement SQL into individual statements for
+// databases and drivers that accept only one statement per Exec.
+//
+// The splitter understands enough of each dialect to avoid splitting on
+// semicolons that aren't statement terminators: string literals, quoted
+// identifiers, comments, PostgreSQL dollar quoting, MySQL DELIMITER
+// directives and SQLite trigger bodies.
+package split
+
+import "strings"
+
+// Dialect selects the lexical rules used when splitting.
+type Dialect int
+
+const (
+	// Postgres supports dollar quoting, E'' strings and nested block comments.
+	Postgres Dialect = iota
+
+	// MySQL supports backslash escapes, backtick identifiers, # comments
+	// and the DELIMITER directive of the mysql client.
+	MySQL
+
+	// SQLite supports backtick and [bracket] identifiers and keeps
+	// CREATE TRIGGER ... BEGIN ... END bodies together.
+	SQLite
+)
+
+// Split returns the statements in query, trimmed and without their
+// terminating delimiter. Statements consisting only of whitespace and
+// comments are dropped.
+//
+// Examples:
+//
+//	Split("CREATE TABLE a (id INT); CREATE TABLE b (id INT);", Postgres)
+//	  = []string{"CREATE TABLE a (id INT)", "CREATE TABLE b (id INT)"}
+func Split(query string, dialect Dialect) []string {
+	s := splitter{src: query, dialect: dialect, delim: ";"}
+	return s.run()
+}
+
+type splitter struct {
+	src     string
+	dialect Dialect
+	delim   string
+	stmts   []string
+
+	start int  // offset of the current statement
+	code  bool // current statement has more than whitespace and comments
+
+	// Leading keywords, used to detect SQLite triggers.
+	words   []string
+	trigger bool
+	depth   int
+
+	// Last word and where it ended, used to detect PostgreSQL E'' strings.
+	lastWord    string
+	lastWordEnd int
+}
+
+func (s *splitter) run() []string {
+	i := 0
+	for i < len(s.src) {
+		c := s.src[i]
+
+		switch {
+		case s.dialect == MySQL && !s.code && hasPrefixFold(s.src[i:], "DELIMITER") &&
+			i+9 < len(s.src) && isSpace(s.src[i+9]):
+			i = s.delimiter(i)
+			s.start = i
+
+		case strings.HasPrefix(s.src[i:], s.delim) && (!s.trigger || s.depth == 0):
+			s.flush(i)
+			i += len(s.delim)
+			s.start = i
+
+		case isSpace(c):
+			i++
+
+		case c == '-' && s.isLineComment(i):
+			i = skipLine(s.src, i)
+
+		case c == '#' && s.dialect == MySQL:
+			i = skipLine(s.src, i)
+
+		case c == '/' && i+1 < len(s.src) && s.src[i+1] == '*':
+			// MySQL executable comments (/*! ... */) carry real SQL
+			if s.dialect == MySQL && i+2 < len(s.src) && s.src[i+2] == '!' {
+				s.code = true
+			}
+			i = s.skipBlockComment(i)
+
+		case c == '\'':
+			s.code = true
+			backslash := s.dialect == MySQL ||
+				(s.dialect == Postgres && s.lastWordEnd == i && strings.EqualFold(s.lastWord, "E"))
+			i = skipQuoted(s.src, i, '\'', backslash)
+
+		case c == '"':
+			s.code = true
+			i = skipQuoted(s.src, i, '"', s.dialect == MySQL)
+
+		case c == '`' && s.dialect != Postgres:
+			s.code = true
+			i = skipQuoted(s.src, i, '`', false)
+
+		case c == '[' && s.dialect == SQLite:
+			s.code = true
+			i = skipTo(s.src, i+1, "]")
+
+		case c == '$' && s.dialect == Postgres:
+			s.code = true
+			i = s.skipDollarQuoted(i)
+
+		case isWordStart(c):
+			s.code = true
+			i = s.word(i)
+
+		default:
+			s.code = true
+			i++
+		}
+	}
+
+	s.flush(len(s.src))
+	return s.stmts
+}
+
+// flush ends the current statement at end.
+func (s *splitter) flush(end int) {
+	if s.code {
+		s.stmts = append(s.stmts, strings.TrimSpace(s.src[s.start:end]))
+	}
+
+	s.code = false
+	s.words = s.words[:0]
+	s.trigger = false
+	s.depth = 0
+}
+
+// delimiter handles a MySQL DELIMITER directive starting at i and returns
+// the offset of the next line.
+func (s *splitter) delimiter(i int) int {
+	end := skipLine(s.src, i)
+	if fields := strings.Fields(s.src[i:end]); len(fields) > 1 {
+		s.delim = fields[1]
+	}
+	return end
+}
+
+// word consumes an identifier or keyword starting at i.
+func (s *splitter) word(i int) int {
+	j := i + 1
+	for j < len(s.src) && isWordPart(s.src[j]) {
+		j++
+	}
+
+	w := strings.ToUpper(s.src[i:j])
+	s.lastWord, s.lastWordEnd = w, j
+
+	if s.dialect != SQLite {
+		return j
+	}
+
+	// CREATE [TEMP | TEMPORARY] TRIGGER ... BEGIN ... END
+	if len(s.words) < 3 {
+		s.words = append(s.words, w)
+		if w == "TRIGGER" && s.words[0] == "CREATE" {
+			s.trigger = true
+		}
+	}
+
+	if s.trigger {
+		switch w {
+		case "BEGIN", "CASE":
+			s.depth++
+		case "END":
+			if s.depth > 0 {
+				s.depth--
+			}
+		}
+	}
+
+	return j
+}
+
+// isLineComment reports whether a "--" comment starts at i.
+func (s *splitter) isLineComment(i int) bool {
+	if i+1 >= len(s.src) || s.src[i+1] != '-' {
+		return false
+	}
+
+	// MySQL requires whitespace after the dashes
+	if s.dialect == MySQL {
+		return i+2 >= len(s.src) || isSpace(s.src[i+2])
+	}
+
+	return true
+}
+
+// skipBlockComment consumes a /* */ comment starting at i. PostgreSQL block
+// comments nest.
+func (s *splitter) skipBlockComment(i int) int {
+	if s.dialect != Postgres {
+		return skipTo(s.src, i+2, "*/")
+	}
+
+	depth := 0
+	for i < len(s.src) {
+		switch {
+		case strings.HasPrefix(s.src[i:], "/*"):
+			depth++
+			i += 2
+		case strings.HasPrefix(s.src[i:], "*/"):
+			depth--
+			i += 2
+			if depth == 0 {
+				return i
+			}
+		default:
+			i++
+		}
+	}
+
+	return i
+}
+
+// skipDollarQuoted consumes a $tag$ ... $tag$ string starting at i. A '$'
+// that doesn't open a dollar quote, as in $1, is consumed on its own.
+func (s *splitter) skipDollarQuoted(i int) int {
+	j := i + 1
+	if j < len(s.src) && isWordStart(s.src[j]) {
+		for j < len(s.src) && isWordPart(s.src[j]) && s.src[j] != '$' {
+			j++
+		}
+	}
+
+	if j >= len(s.src) || s.src[j] != '$' {
+		return i + 1
+	}
+
+	tag := s.src[i : j+1]
+	return skipTo(s.src, j+1, tag)
+}
+
+// skipQuoted consumes a string or identifier quoted with q starting at i.
+// A doubled quote is an escaped quote; with backslash set, so is \q.
+func skipQuoted(src string, i int, q byte, backslash bool) int {
+	j := i + 1
+	for j < len(src) {
+		switch {
+		case backslash && src[j] == '\\':
+			j += 2
+		case src[j] == q:
+			if j+1 < len(src) && src[j+1] == q {
+				j += 2
+				continue
+			}
+			return j + 1
+		default:
+			j++
+		}
+	}
+
+	return len(src)
+}
+
+// skipTo returns the offset just past the next occurrence of end at or
+// after i, or len(src) if there is none.
+func skipTo(src string, i int, end string) int {
+	if i > len(src) {
+		return len(src)
+	}
+
+	n := strings.Index(src[i:], end)
+	if n < 0 {
+		return len(src)
+	}
+
+	return i + n + len(end)
+}
+
+// skipLine returns the offset of the line following i.
+func skipLine(src string, i int) int {
+	n := strings.IndexByte(src[i:], '\n')
+	if n < 0 {
+		return len(src)
+	}
+
+	return i + n + 1
+}
+
+func hasPrefixFold(s, prefix string) bool {
+	return len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix)
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v'
+}
+
+func isWordStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c >= 0x80
+}
+
+func isWordPart(c byte) bool {
+	return isWordStart(c) || (c >= '0' && c <= '9') || c == '$'
+}
@@ -0,0 +1,125 @@
+package split
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		name    string
+		dialect Dialect
+		query   string
+		want    []string
+	}{
+		// Basics
+		{"single statement", Postgres, "SELECT 1", []string{"SELECT 1"}},
+		{"trailing semicolon", Postgres, "SELECT 1;", []string{"SELECT 1"}},
+		{"two statements", Postgres, "SELECT 1; SELECT 2;", []string{"SELECT 1", "SELECT 2"}},
+		{"empty", Postgres, "", nil},
+		{"only whitespace", Postgres, " ;\n; ", nil},
+		{"only comments", Postgres, "-- nothing here\n/* or here */;", nil},
+		{"comment kept with statement", Postgres, "-- users\nCREATE TABLE users (id INT);",
+			[]string{"-- users\nCREATE TABLE users (id INT)"}},
+
+		// String literals and identifiers
+		{"semicolon in string", Postgres, "INSERT INTO t VALUES ('a;b'); SELECT 2",
+			[]string{"INSERT INTO t VALUES ('a;b')", "SELECT 2"}},
+		{"doubled quote", Postgres, "SELECT 'it''s;'; SELECT 2", []string{"SELECT 'it''s;'", "SELECT 2"}},
+		{"quoted identifier", Postgres, `SELECT "a;b" FROM t; SELECT 2`, []string{`SELECT "a;b" FROM t`, "SELECT 2"}},
+		{"semicolon in line comment", Postgres, "SELECT 1 -- a;b\n; SELECT 2",
+			[]string{"SELECT 1 -- a;b", "SELECT 2"}},
+		{"semicolon in block comment", Postgres, "SELECT /* ; */ 1; SELECT 2", []string{"SELECT /* ; */ 1", "SELECT 2"}},
+
+		// PostgreSQL
+		{"dollar quoted body", Postgres,
+			"CREATE FUNCTION f() RETURNS INT AS $$ BEGIN RETURN 1; END; $$ LANGUAGE plpgsql; SELECT f();",
+			[]string{"CREATE FUNCTION f() RETURNS INT AS $$ BEGIN RETURN 1; END; $$ LANGUAGE plpgsql", "SELECT f()"}},
+		{"tagged dollar quote", Postgres, "SELECT $fn$ a; $$ b; $fn$; SELECT 2",
+			[]string{"SELECT $fn$ a; $$ b; $fn$", "SELECT 2"}},
+		{"positional parameter", Postgres, "SELECT $1; SELECT 2", []string{"SELECT $1", "SELECT 2"}},
+		{"escape string", Postgres, `SELECT E'a\';b'; SELECT 2`, []string{`SELECT E'a\';b'`, "SELECT 2"}},
+		{"backslash in standard string", Postgres, `SELECT 'a\'; SELECT 2`, []string{`SELECT 'a\'`, "SELECT 2"}},
+		{"nested block comment", Postgres, "SELECT /* a /* ; */ ; */ 1; SELECT 2",
+			[]string{"SELECT /* a /* ; */ ; */ 1", "SELECT 2"}},
+
+		// MySQL
+		{"backslash escape", MySQL, `INSERT INTO t VALUES ('a\';b'); SELECT 2`,
+			[]string{`INSERT INTO t VALUES ('a\';b')`, "SELECT 2"}},
+		{"backtick identifier", MySQL, "SELECT `a;b` FROM t; SELECT 2", []string{"SELECT `a;b` FROM t", "SELECT 2"}},
+		{"hash comment", MySQL, "SELECT 1 # a;b\n; SELECT 2", []string{"SELECT 1 # a;b", "SELECT 2"}},
+		{"double dash without space", MySQL, "SELECT 5--1; SELECT 2", []string{"SELECT 5--1", "SELECT 2"}},
+		{"executable comment", MySQL, "/*!40101 SET NAMES utf8 */; SELECT 2",
+			[]string{"/*!40101 SET NAMES utf8 */", "SELECT 2"}},
+		{"delimiter block", MySQL,
+			"DELIMITER //\nCREATE PROCEDURE p() BEGIN SELECT 1; SELECT 2; END//\nDELIMITER ;\nCALL p();",
+			[]string{"CREATE PROCEDURE p() BEGIN SELECT 1; SELECT 2; END", "CALL p()"}},
+
+		// SQLite
+		{"bracket identifier", SQLite, "SELECT [a;b] FROM t; SELECT 2", []string{"SELECT [a;b] FROM t", "SELECT 2"}},
+		{"trigger body", SQLite,
+			"CREATE TRIGGER tr AFTER INSERT ON t BEGIN UPDATE t SET n = CASE WHEN n > 0 THEN 1 ELSE 0 END; DELETE FROM u; END; SELECT 2;",
+			[]string{"CREATE TRIGGER tr AFTER INSERT ON t BEGIN UPDATE t SET n = CASE WHEN n > 0 THEN 1 ELSE 0 END; DELETE FROM u; END", "SELECT 2"}},
+		{"temp trigger", SQLite, "CREATE TEMP TRIGGER tr AFTER INSERT ON t BEGIN SELECT 1; END; SELECT 2",
+			[]string{"CREATE TEMP TRIGGER tr AFTER INSERT ON t BEGIN SELECT 1; END", "SELECT 2"}},
+		{"begin transaction", SQLite, "BEGIN; SELECT 1; COMMIT;", []string{"BEGIN", "SELECT 1", "COMMIT"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Split(tt.query, tt.dialect)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Split(%q) = %q, want %q", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRebind(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+		n     int
+	}{
+		{"SELECT 1", "SELECT 1", 0},
+		{"INSERT INTO t (a, b) VALUES (?, ?)", "INSERT INTO t (a, b) VALUES ($1, $2)", 2},
+		{"SELECT '?', \"?\", E'\\'?', ? -- ?\n/* ? */", "SELECT '?', \"?\", E'\\'?', $1 -- ?\n/* ? */", 1},
+		{"CREATE FUNCTION f() AS $$ SELECT ? $$; SELECT ?", "CREATE FUNCTION f() AS $$ SELECT ? $$; SELECT $1", 1},
+		{"SELECT data ?? 'key' FROM t WHERE id = ?", "SELECT data ? 'key' FROM t WHERE id = $1", 1},
+	}
+	for _, tt := range tests {
+		if got := Rebind(tt.query); got != tt.want {
+			t.Errorf("Rebind(%q) = %q; want %q", tt.query, got, tt.want)
+		}
+		if got := CountPlaceholders(tt.want, Postgres); got != tt.n {
+			t.Errorf("CountPlaceholders(%q) = %d; want %d", tt.want, got, tt.n)
+		}
+	}
+}
+
+func TestCountPlaceholders(t *testing.T) {
+	tests := []struct {
+		name    string
+		dialect Dialect
+		query   string
+		want    int
+	}{
+		{"postgres", Postgres, "INSERT INTO t VALUES ($1, $2, $1)", 2},
+		{"postgres out of order", Postgres, "SELECT $3, $1", 3},
+		{"postgres literals", Postgres, "SELECT '$1', \"$2\", $$ $3 $$, $tag$ $4 $tag$, a$5 -- $6\n/* $7 */", 0},
+		{"postgres ignores ?", Postgres, "SELECT ?", 0},
+		{"mysql", MySQL, "INSERT INTO t VALUES (?, '?', \"?\", `?`) # ?\n-- ?\n/* ? */", 1},
+		{"mysql backslash", MySQL, `SELECT 'it\'s ?', ?`, 1},
+		{"sqlite", SQLite, "INSERT INTO t VALUES (?, ?, [?])", 2},
+		{"sqlite numbered", SQLite, "SELECT ?3, ?", 4},
+		{"sqlite named", SQLite, "SELECT :a, @b, $c, :a", 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CountPlaceholders(tt.query, tt.dialect); got != tt.want {
+				t.Errorf("CountPlaceholders(%q) = %d; want %d", tt.query, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package queen
+
+import "context"
+
+// Introspector is implemented by drivers that can describe the schema they
+// migrate. It is the basis for schema snapshots, drift detection and
+// assertions that work the same on every database.
+//
+// Names are as stored by the database, e.g. lowercase for unquoted
+// PostgreSQL identifiers. Objects are looked up in the driver's schema or
+// database. Methods taking a name return ErrObjectNotFound if it doesn't
+// exist there.
+type Introspector interface {
+	// ListTables returns the base tables, including Queen's own, sorted
+	// by name. Views are not included.
+	ListTables(ctx context.Context) ([]string, error)
+
+	// ListColumns returns the columns of table in definition order.
+	ListColumns(ctx context.Context, table string) ([]Column, error)
+
+	// ListIndexes returns the indexes of table sorted by name, including
+	// those backing primary key and unique constraints.
+	ListIndexes(ctx context.Context, table string) ([]Index, error)
+
+	// ObjectDDL returns a statement creating the table, view or index
+	// called name, as the database reports or reconstructs it.
+	ObjectDDL(ctx context.Context, name string) (string, error)
+}
+
+// Column describes a table column.
+type Column struct {
+	// Name is the column name.
+	Name string
+
+	// Type is the column type in the database's own notation, e.g.
+	// "character varying(255)" or "varchar(255)".
+	Type string
+
+	// Nullable is set unless the column is NOT NULL.
+	Nullable bool
+
+	// Default is the default expression, or "" if the column has none.
+	Default string
+}
+
+// Index describes a table index.
+type Index struct {
+	// Name is the index name.
+	Name string
+
+	// Columns are the indexed columns or expressions, in key order.
+	Columns []string
+
+	// Unique is set for unique indexes, including primary keys.
+	Unique bool
+
+	// Primary is set for the index backing the primary key.
+	Primary bool
+}
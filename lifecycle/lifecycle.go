@@ -0,0 +1,111 @@
+// Package lifecycle wraps a Queen instance as a component with start, stop
+// and readiness semantics.
+//
+// It is the shared building block behind the dependency injection
+// integrations (fxqueen, wirequeen), but it has no DI dependencies of its
+// own and can be wired into any service framework by hand:
+//
+//	c := lifecycle.New(q, lifecycle.Options{RunOnStart: true})
+//	if err := c.Start(ctx); err != nil {
+//	    log.Fatal(err)
+//	}
+//	defer c.Stop(context.Background())
+//
+//	http.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+//	    if err := c.Ready(r.Context()); err != nil {
+//	        http.Error(w, err.Error(), http.StatusServiceUnavailable)
+//	    }
+//	})
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/honeynil/queen"
+)
+
+// ErrNotStarted is returned by Ready before Start has completed.
+var ErrNotStarted = errors.New("migrations not started")
+
+// Options configures a Component.
+type Options struct {
+	// RunOnStart applies all pending migrations when the component starts.
+	// When false, Start only validates the registered migrations. Default: false
+	RunOnStart bool
+
+	// CloseOnStop closes the underlying Queen (and its driver) when the
+	// component stops. Leave false when the database connection is owned by
+	// another component. Default: false
+	CloseOnStop bool
+}
+
+// Component is a lifecycle-managed wrapper around Queen.
+//
+// Component is safe for concurrent use. Ready may be called from health
+// check handlers while Start is still running.
+type Component struct {
+	q    *queen.Queen
+	opts Options
+
+	mu      sync.RWMutex
+	started bool
+	err     error
+}
+
+// New creates a Component for q.
+func New(q *queen.Queen, opts Options) *Component {
+	return &Component{
+		q:    q,
+		opts: opts,
+	}
+}
+
+// Queen returns the wrapped Queen instance.
+func (c *Component) Queen() *queen.Queen {
+	return c.q
+}
+
+// Start runs pending migrations (if RunOnStart is set) or validates them.
+// The result is remembered and reported by Ready.
+func (c *Component) Start(ctx context.Context) error {
+	var err error
+	if c.opts.RunOnStart {
+		err = c.q.Up(ctx)
+	} else {
+		err = c.q.Validate(ctx)
+	}
+
+	// A component without registered migrations is considered ready.
+	if errors.Is(err, queen.ErrNoMigrations) {
+		err = nil
+	}
+
+	c.mu.Lock()
+	c.started = true
+	c.err = err
+	c.mu.Unlock()
+
+	return err
+}
+
+// Stop closes the wrapped Queen if CloseOnStop is set.
+func (c *Component) Stop(ctx context.Context) error {
+	if !c.opts.CloseOnStop {
+		return nil
+	}
+	return c.q.Close()
+}
+
+// Ready reports whether the component has started successfully.
+// It returns ErrNotStarted before Start completes, or the error Start returned.
+func (c *Component) Ready(ctx context.Context) error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if !c.started {
+		return ErrNotStarted
+	}
+	return c.err
+}
@@ -0,0 +1,75 @@
+package lifecycle_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/honeynil/queen"
+	"github.com/honeynil/queen/drivers/mock"
+	"github.com/honeynil/queen/lifecycle"
+)
+
+func newQueen(driver *mock.Driver) *queen.Queen {
+	q := queen.New(driver)
+	q.MustAdd(queen.M{
+		Version:        "001",
+		Name:           "first",
+		ManualChecksum: "v1",
+		UpFunc:         func(ctx context.Context, tx *sql.Tx) error { return nil },
+	})
+	return q
+}
+
+func TestComponent_RunOnStart(t *testing.T) {
+	driver := mock.New()
+	c := lifecycle.New(newQueen(driver), lifecycle.Options{RunOnStart: true})
+	ctx := context.Background()
+
+	if err := c.Ready(ctx); !errors.Is(err, lifecycle.ErrNotStarted) {
+		t.Fatalf("Expected ErrNotStarted before Start, got %v", err)
+	}
+
+	if err := c.Start(ctx); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	if err := c.Ready(ctx); err != nil {
+		t.Errorf("Expected ready after Start, got %v", err)
+	}
+
+	if !driver.HasVersion("001") {
+		t.Error("Expected version 001 to be applied on start")
+	}
+}
+
+func TestComponent_ValidateOnly(t *testing.T) {
+	driver := mock.New()
+	c := lifecycle.New(newQueen(driver), lifecycle.Options{})
+
+	if err := c.Start(context.Background()); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	if driver.AppliedCount() != 0 {
+		t.Errorf("Expected no migrations applied, got %d", driver.AppliedCount())
+	}
+}
+
+func TestComponent_StartError(t *testing.T) {
+	driver := mock.New()
+	initErr := errors.New("init failed")
+	driver.SetInitError(initErr)
+
+	c := lifecycle.New(newQueen(driver), lifecycle.Options{RunOnStart: true})
+	ctx := context.Background()
+
+	if err := c.Start(ctx); !errors.Is(err, initErr) {
+		t.Fatalf("Expected init error from Start, got %v", err)
+	}
+
+	if err := c.Ready(ctx); !errors.Is(err, initErr) {
+		t.Errorf("Expected Ready to report start error, got %v", err)
+	}
+}
@@ -0,0 +1,41 @@
+package queen
+
+import (
+	"sort"
+
+	naturalsort "github.com/honeynil/queen/internal/sort"
+	"github.com/honeynil/queen/queenlint"
+)
+
+// Lint checks the SQL of the registered migrations with queenlint, using
+// Config.Lint, and returns the findings in natural version order. SQL
+// templates are expanded first; Go function migrations are not checked.
+//
+// Run it in CI or a unit test to catch risky SQL before it is deployed:
+//
+//	for _, f := range q.Lint() {
+//	    t.Error(f)
+//	}
+func (q *Queen) Lint() []queenlint.Finding {
+	migrations := make([]queenlint.Migration, 0, len(q.migrations))
+	for _, m := range q.migrations {
+		rendered, err := q.render(m)
+		if err != nil {
+			// Add rejects migrations whose templates don't expand
+			rendered = m
+		}
+
+		migrations = append(migrations, queenlint.Migration{
+			Version: m.Version,
+			Name:    m.Name,
+			UpSQL:   rendered.UpSQL,
+			DownSQL: rendered.DownSQL,
+		})
+	}
+
+	sort.SliceStable(migrations, func(i, j int) bool {
+		return naturalsort.Compare(migrations[i].Version, migrations[j].Version) < 0
+	})
+
+	return queenlint.Lint(migrations, q.config.Lint)
+}
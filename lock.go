@@ -0,0 +1,79 @@
+package queen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+)
+
+// Bounds of the wait between PollLock attempts.
+const (
+	minLockPoll = 50 * time.Millisecond
+	maxLockPoll = 2 * time.Second
+)
+
+// PollLock calls try until it takes the lock, for Locker implementations
+// whose database can only try a lock without waiting, such as
+// pg_try_advisory_lock. Attempts back off exponentially with jitter, so
+// replicas waiting together don't poll in lockstep.
+//
+// It returns ErrLockTimeout once timeout has passed or ctx is done without
+// try returning true, and the error of try as is.
+func PollLock(ctx context.Context, timeout time.Duration, try func(ctx context.Context) (bool, error)) error {
+	deadline := time.Now().Add(timeout)
+	wait := minLockPoll
+
+	for {
+		acquired, err := try(ctx)
+		if err != nil {
+			return err
+		}
+		if acquired {
+			return nil
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return ErrLockTimeout
+		}
+
+		timer := time.NewTimer(min(wait/2+rand.N(wait/2+1), remaining))
+		select {
+		case <-timer.C:
+			wait = min(wait*2, maxLockPoll)
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("%w: %w", ErrLockTimeout, ctx.Err())
+		}
+	}
+}
+
+// lockError makes a failure of Locker.Lock after waiting for waited
+// consistent across drivers: timeouts, including the deadline of the
+// context passing, are reported as ErrLockTimeout with the time waited,
+// wrapping the driver's error if it has more to say.
+func lockError(err error, waited time.Duration) error {
+	waited = waited.Round(time.Millisecond)
+
+	switch {
+	case err == ErrLockTimeout:
+		return fmt.Errorf("%w after %s", ErrLockTimeout, waited)
+	case errors.Is(err, ErrLockTimeout), errors.Is(err, context.DeadlineExceeded):
+		return fmt.Errorf("%w after %s: %w", ErrLockTimeout, waited, unwrapLockTimeout(err))
+	default:
+		return err
+	}
+}
+
+// unwrapLockTimeout returns the cause of a "lock timeout: cause" error
+// made by PollLock or a driver, so it isn't repeated.
+func unwrapLockTimeout(err error) error {
+	if wrapped, ok := err.(interface{ Unwrap() []error }); ok {
+		if errs := wrapped.Unwrap(); len(errs) == 2 && errs[0] == ErrLockTimeout {
+			return errs[1]
+		}
+	}
+	return err
+}
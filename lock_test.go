@@ -0,0 +1,77 @@
+package queen_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/honeynil/queen"
+	"github.com/honeynil/queen/drivers/mock"
+)
+
+func TestPollLock(t *testing.T) {
+	ctx := context.Background()
+
+	tries := 0
+	err := queen.PollLock(ctx, time.Second, func(ctx context.Context) (bool, error) {
+		tries++
+		return tries == 3, nil
+	})
+	if err != nil || tries != 3 {
+		t.Errorf("PollLock = %v after %d tries; want the lock on the third", err, tries)
+	}
+
+	err = queen.PollLock(ctx, 100*time.Millisecond, func(ctx context.Context) (bool, error) { return false, nil })
+	if !errors.Is(err, queen.ErrLockTimeout) {
+		t.Errorf("PollLock of a held lock = %v; want ErrLockTimeout", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	err = queen.PollLock(ctx, time.Hour, func(ctx context.Context) (bool, error) { return false, nil })
+	if !errors.Is(err, queen.ErrLockTimeout) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("PollLock past the deadline = %v; want ErrLockTimeout wrapping DeadlineExceeded", err)
+	}
+
+	boom := errors.New("boom")
+	err = queen.PollLock(context.Background(), time.Second, func(ctx context.Context) (bool, error) { return false, boom })
+	if err != boom {
+		t.Errorf("PollLock = %v; want the error of try", err)
+	}
+}
+
+// timeoutDriver records the timeout Lock is called with.
+type timeoutDriver struct {
+	*mock.Driver
+	timeout time.Duration
+}
+
+func (d *timeoutDriver) Lock(ctx context.Context, timeout time.Duration) error {
+	d.timeout = timeout
+	return d.Driver.Lock(ctx, timeout)
+}
+
+func TestLockTimeout(t *testing.T) {
+	driver := &timeoutDriver{Driver: mock.New()}
+	q := queen.NewWithConfig(driver, &queen.Config{LockTimeout: time.Hour})
+	q.MustAdd(queen.M{Version: "001", Name: "create_users", UpFunc: noop})
+
+	// The deadline of the context bounds LockTimeout
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if err := q.Up(ctx); err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+	if driver.timeout <= 0 || driver.timeout > time.Minute {
+		t.Errorf("Lock timeout = %v; want at most the 1m deadline", driver.timeout)
+	}
+
+	// Timeouts report the time waited
+	driver.SetLockError(queen.ErrLockTimeout)
+	err := q.Up(context.Background())
+	if !errors.Is(err, queen.ErrLockTimeout) || !strings.HasPrefix(err.Error(), "lock timeout after ") {
+		t.Errorf("Up with the lock held = %v; want lock timeout after ...", err)
+	}
+}
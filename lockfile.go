@@ -0,0 +1,151 @@
+package queen
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	naturalsort "github.com/honeynil/queen/internal/sort"
+)
+
+// LockFile pins every known migration version to its checksum. Commit it
+// next to the migrations and set Config.LockFile in CI: registering a
+// version missing from it, or one whose checksum differs, then fails, so
+// new migrations must come with a regenerated lock file and edits to
+// historical migrations show up at review time.
+//
+// The file lists one migration per line, in natural version order:
+//
+//	# queen.lock: generated by Queen, do not edit
+//	001 3f2a9c1e... create_users
+//	002 9b1d04aa... add_email
+type LockFile struct {
+	entries map[string]lockEntry
+}
+
+type lockEntry struct {
+	checksum string
+	name     string
+}
+
+const lockFileHeader = "# queen.lock: generated by Queen, do not edit"
+
+// LockFile returns a lock file pinning the registered migrations.
+func (q *Queen) LockFile() *LockFile {
+	l := &LockFile{entries: make(map[string]lockEntry, len(q.migrations))}
+	for _, m := range q.migrations {
+		l.entries[m.Version] = lockEntry{checksum: m.Checksum(), name: m.Name}
+	}
+	return l
+}
+
+// WriteLockFile writes the lock file of the registered migrations to path.
+func (q *Queen) WriteLockFile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if _, err := q.LockFile().WriteTo(f); err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// ReadLockFile reads a lock file written by WriteLockFile.
+func ReadLockFile(path string) (*LockFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	l, err := ParseLockFile(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return l, nil
+}
+
+// ParseLockFile parses a lock file from r. Blank lines and lines starting
+// with # are ignored.
+func ParseLockFile(r io.Reader) (*LockFile, error) {
+	l := &LockFile{entries: make(map[string]lockEntry)}
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.SplitN(text, " ", 3)
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("line %d: expected version and checksum", line)
+		}
+		if _, ok := l.entries[fields[0]]; ok {
+			return nil, fmt.Errorf("line %d: %w: %s", line, ErrVersionConflict, fields[0])
+		}
+
+		entry := lockEntry{checksum: fields[1]}
+		if len(fields) == 3 {
+			entry.name = fields[2]
+		}
+		l.entries[fields[0]] = entry
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return l, nil
+}
+
+// WriteTo writes the lock file to w.
+func (l *LockFile) WriteTo(w io.Writer) (int64, error) {
+	var b strings.Builder
+	b.WriteString(lockFileHeader + "\n")
+	for _, version := range l.versions() {
+		e := l.entries[version]
+		b.WriteString(strings.TrimSpace(version + " " + e.checksum + " " + e.name))
+		b.WriteByte('\n')
+	}
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+// Verify returns ErrNotLocked if m's version isn't in the lock file or its
+// checksum differs from the pinned one.
+func (l *LockFile) Verify(m *Migration) error {
+	e, ok := l.entries[m.Version]
+	if !ok {
+		return fmt.Errorf("%w: migration %s is not in the lock file", ErrNotLocked, m.Version)
+	}
+
+	if e.checksum != m.Checksum() {
+		return fmt.Errorf("%w: migration %s (locked %s, got %s)", ErrNotLocked, m.Version, e.checksum, m.Checksum())
+	}
+
+	return nil
+}
+
+// versions returns the locked versions in natural sort order.
+func (l *LockFile) versions() []string {
+	versions := make([]string, 0, len(l.entries))
+	for version := range l.entries {
+		versions = append(versions, version)
+	}
+
+	sort.Slice(versions, func(i, j int) bool {
+		return naturalsort.Compare(versions[i], versions[j]) < 0
+	})
+
+	return versions
+}
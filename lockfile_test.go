@@ -0,0 +1,57 @@
+package queen_test
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/honeynil/queen"
+)
+
+func TestLockFile(t *testing.T) {
+	q := queen.New(nil)
+	q.MustAdd(queen.M{Version: "10", Name: "add_index", UpSQL: "CREATE INDEX i ON users (email)"})
+	q.MustAdd(queen.M{Version: "2", Name: "create_users", UpSQL: "CREATE TABLE users (id INT)"})
+
+	path := filepath.Join(t.TempDir(), "queen.lock")
+	if err := q.WriteLockFile(path); err != nil {
+		t.Fatalf("WriteLockFile failed: %v", err)
+	}
+
+	lock, err := queen.ReadLockFile(path)
+	if err != nil {
+		t.Fatalf("ReadLockFile failed: %v", err)
+	}
+
+	var b strings.Builder
+	if _, err := lock.WriteTo(&b); err != nil {
+		t.Fatalf("WriteTo failed: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[1], "2 ") || !strings.HasPrefix(lines[2], "10 ") {
+		t.Errorf("unexpected lock file:\n%s", b.String())
+	}
+
+	config := queen.DefaultConfig()
+	config.LockFile = lock
+	ci := queen.NewWithConfig(nil, config)
+
+	if err := ci.Add(queen.M{Version: "2", Name: "create_users", UpSQL: "CREATE TABLE users (id INT)"}); err != nil {
+		t.Errorf("Add of locked migration failed: %v", err)
+	}
+	if err := ci.Add(queen.M{Version: "10", Name: "add_index", UpSQL: "CREATE INDEX i ON users (name)"}); !errors.Is(err, queen.ErrNotLocked) {
+		t.Errorf("Add of edited migration error = %v, want ErrNotLocked", err)
+	}
+	if err := ci.Submit(queen.M{Version: "11", Name: "new", UpSQL: "SELECT 1"}); !errors.Is(err, queen.ErrNotLocked) {
+		t.Errorf("Submit of unlocked migration error = %v, want ErrNotLocked", err)
+	}
+}
+
+func TestParseLockFileInvalid(t *testing.T) {
+	for _, input := range []string{"001\n", "001 abc\n001 def\n"} {
+		if _, err := queen.ParseLockFile(strings.NewReader(input)); err == nil {
+			t.Errorf("ParseLockFile(%q) succeeded", input)
+		}
+	}
+}
@@ -0,0 +1,20 @@
+package queen
+
+import "context"
+
+// MaintenanceSwitch pauses application writes while migrations run, e.g. a
+// feature flag or maintenance mode the application checks before writing.
+// See Config.Maintenance.
+type MaintenanceSwitch interface {
+	// Set turns maintenance on. It is called right after the migration
+	// lock is acquired; an error releases the lock and stops the
+	// operation before anything runs.
+	Set(ctx context.Context) error
+
+	// Clear turns maintenance off. It is called right before the lock is
+	// released, with a background context so it runs even if the
+	// operation was cancelled. Its error can't stop anything and is
+	// ignored, so implementations should retry, or set a switch that
+	// expires on its own.
+	Clear(ctx context.Context) error
+}
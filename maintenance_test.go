@@ -0,0 +1,70 @@
+package queen_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/honeynil/queen"
+	"github.com/honeynil/queen/drivers/mock"
+)
+
+// fakeSwitch records whether the lock was held when it was set and cleared.
+type fakeSwitch struct {
+	driver *mock.Driver
+	calls  []string
+	setErr error
+}
+
+func (s *fakeSwitch) Set(ctx context.Context) error {
+	s.calls = append(s.calls, "set", lockState(s.driver))
+	return s.setErr
+}
+
+func (s *fakeSwitch) Clear(ctx context.Context) error {
+	s.calls = append(s.calls, "clear", lockState(s.driver))
+	return nil
+}
+
+func lockState(d *mock.Driver) string {
+	if d.IsLocked() {
+		return "locked"
+	}
+	return "unlocked"
+}
+
+func TestMaintenance(t *testing.T) {
+	ctx := context.Background()
+	driver := mock.New()
+	maintenance := &fakeSwitch{driver: driver}
+	q := queen.NewWithConfig(driver, &queen.Config{Maintenance: maintenance})
+	q.MustAdd(queen.M{Version: "001", Name: "create", UpFunc: noop})
+
+	if err := q.Up(ctx); err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+	if want := []string{"set", "locked", "clear", "locked"}; !slices.Equal(maintenance.calls, want) {
+		t.Errorf("calls = %v; want %v, within the lock window", maintenance.calls, want)
+	}
+	if driver.IsLocked() {
+		t.Error("Expected the lock released")
+	}
+
+	t.Run("set fails", func(t *testing.T) {
+		driver := mock.New()
+		maintenance := &fakeSwitch{driver: driver, setErr: errors.New("flag service down")}
+		q := queen.NewWithConfig(driver, &queen.Config{Maintenance: maintenance})
+		q.MustAdd(queen.M{Version: "001", Name: "create", UpFunc: noop})
+
+		if err := q.Up(ctx); err == nil {
+			t.Fatal("Expected Up to fail")
+		}
+		if driver.AppliedCount() != 0 || driver.IsLocked() {
+			t.Error("Expected nothing applied and the lock released")
+		}
+		if slices.Contains(maintenance.calls, "clear") {
+			t.Errorf("calls = %v; want no clear after a failed set", maintenance.calls)
+		}
+	})
+}
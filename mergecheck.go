@@ -0,0 +1,167 @@
+package queen
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	naturalsort "github.com/honeynil/queen/internal/sort"
+)
+
+// ConflictKind classifies a MergeConflict.
+type ConflictKind int
+
+const (
+	// ConflictDuplicate means both branches added the same version with
+	// different content, e.g. both took the next sequential number.
+	ConflictDuplicate ConflictKind = iota
+
+	// ConflictReorder means a version added by the branch sorts before a
+	// version of the target branch, so merging would apply it out of order
+	// on databases already migrated by the target branch.
+	ConflictReorder
+
+	// ConflictShift means a version added by the branch doesn't conflict
+	// itself but follows a conflicting one, and must be renumbered with it
+	// to keep the branch's migrations in order.
+	ConflictShift
+)
+
+// String returns a human-readable representation of the kind.
+func (k ConflictKind) String() string {
+	switch k {
+	case ConflictDuplicate:
+		return "duplicate"
+	case ConflictReorder:
+		return "reorder"
+	case ConflictShift:
+		return "shift"
+	default:
+		return "unknown"
+	}
+}
+
+// MergeConflict is a version of a branch that conflicts with the branch it
+// is merged into.
+type MergeConflict struct {
+	// Kind is the kind of conflict.
+	Kind ConflictKind
+
+	// Version is the conflicting version of the branch.
+	Version string
+
+	// Latest is the highest version of the target branch.
+	Latest string
+
+	// Suggested is a version to rename the migration to, sorting after
+	// Latest and keeping the branch's versions in order. Empty if no
+	// suggestion can be derived from the version scheme.
+	Suggested string
+}
+
+// String describes the conflict and the suggested fix.
+func (c MergeConflict) String() string {
+	var s string
+	switch c.Kind {
+	case ConflictDuplicate:
+		s = fmt.Sprintf("%s: added on both branches with different content", c.Version)
+	case ConflictReorder:
+		s = fmt.Sprintf("%s: sorts before %s of the target branch", c.Version, c.Latest)
+	default:
+		s = fmt.Sprintf("%s: follows a conflicting version of the branch", c.Version)
+	}
+
+	if c.Suggested != "" {
+		s += fmt.Sprintf(", rename to %s", c.Suggested)
+	}
+	return s
+}
+
+// CheckMerge reports the versions of branch that conflict with target,
+// the lock file of the branch it is merged into, in natural version order.
+// Versions only in branch are taken to be added by it; they must sort
+// after every version of target. Versions in both must have the same
+// checksum.
+//
+// Suggested renumbering moves every version the branch added, from the
+// first conflicting one on, after the latest version of target, keeping
+// their order; versions that only move along are reported as ConflictShift.
+//
+// Run it in CI against the lock file of the target branch to catch
+// conflicting version numbers before merge rather than at deploy time.
+func CheckMerge(target, branch *LockFile) []MergeConflict {
+	latest := ""
+	for version := range target.entries {
+		if latest == "" || naturalsort.Compare(version, latest) > 0 {
+			latest = version
+		}
+	}
+
+	var conflicts []MergeConflict
+	for _, version := range branch.versions() {
+		e := branch.entries[version]
+
+		c := MergeConflict{Kind: ConflictShift, Version: version, Latest: latest}
+		t, ok := target.entries[version]
+		switch {
+		case ok && t.checksum == e.checksum:
+			continue
+		case ok:
+			c.Kind = ConflictDuplicate
+		case naturalsort.Compare(version, latest) < 0:
+			c.Kind = ConflictReorder
+		case len(conflicts) == 0:
+			// Added after latest and nothing to follow
+			continue
+		}
+
+		c.Suggested = renumber(version, latest, len(conflicts)+1)
+		conflicts = append(conflicts, c)
+	}
+
+	return conflicts
+}
+
+// timestampLayout is the usual layout of timestamp versions.
+const timestampLayout = "20060102150405"
+
+// renumber returns version with its numeric prefix replaced by the one n
+// steps after the numeric prefix of latest, keeping the prefix width and
+// the rest of version, e.g. renumber("003_add_email", "004_init", 1) is
+// "005_add_email". Timestamp prefixes advance by n seconds. Returns "" if
+// either version has no numeric prefix.
+func renumber(version, latest string, n int) string {
+	digits := numericPrefix(latest)
+	if digits == "" || numericPrefix(version) == "" {
+		return ""
+	}
+	suffix := version[len(numericPrefix(version)):]
+
+	if len(digits) == len(timestampLayout) {
+		if ts, err := time.Parse(timestampLayout, digits); err == nil {
+			return ts.Add(time.Duration(n)*time.Second).Format(timestampLayout) + suffix
+		}
+	}
+
+	num, err := strconv.ParseUint(digits, 10, 64)
+	if err != nil {
+		return ""
+	}
+
+	next := strconv.FormatUint(num+uint64(n), 10)
+	if len(next) < len(digits) {
+		next = strings.Repeat("0", len(digits)-len(next)) + next
+	}
+
+	return next + suffix
+}
+
+// numericPrefix returns the leading digits of s.
+func numericPrefix(s string) string {
+	i := 0
+	for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+		i++
+	}
+	return s[:i]
+}
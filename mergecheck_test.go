@@ -0,0 +1,71 @@
+package queen_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/honeynil/queen"
+)
+
+func mustParseLockFile(t *testing.T, s string) *queen.LockFile {
+	t.Helper()
+
+	l, err := queen.ParseLockFile(strings.NewReader(s))
+	if err != nil {
+		t.Fatalf("ParseLockFile failed: %v", err)
+	}
+	return l
+}
+
+func TestCheckMerge(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string
+		branch string
+		want   []string
+	}{
+		{
+			name:   "no conflict",
+			target: "001 a\n002 b\n",
+			branch: "001 a\n002 b\n003 c\n",
+		},
+		{
+			name:   "same next version",
+			target: "001 a\n002 b\n003 main\n",
+			branch: "001 a\n002 b\n003 feature\n004 more\n",
+			want: []string{
+				"003: added on both branches with different content, rename to 004",
+				"004: follows a conflicting version of the branch, rename to 005",
+			},
+		},
+		{
+			name:   "interleaved timestamps",
+			target: "20240101120000_init a\n20240301090000_orders b\n",
+			branch: "20240101120000_init a\n20240215100000_users c\n",
+			want: []string{
+				"20240215100000_users: sorts before 20240301090000_orders of the target branch, rename to 20240301090001_users",
+			},
+		},
+		{
+			name:   "no numeric scheme",
+			target: "init a\nusers b\n",
+			branch: "init a\norders c\n",
+			want:   []string{"orders: sorts before users of the target branch"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conflicts := queen.CheckMerge(mustParseLockFile(t, tt.target), mustParseLockFile(t, tt.branch))
+
+			if len(conflicts) != len(tt.want) {
+				t.Fatalf("got %d conflicts %v, want %d", len(conflicts), conflicts, len(tt.want))
+			}
+			for i, c := range conflicts {
+				if c.String() != tt.want[i] {
+					t.Errorf("conflict %d = %q, want %q", i, c, tt.want[i])
+				}
+			}
+		})
+	}
+}
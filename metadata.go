@@ -0,0 +1,90 @@
+package queen
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// MetadataSerializer encodes and decodes the metadata stored with applied
+// migrations by drivers implementing ExtendedDriver.
+type MetadataSerializer interface {
+	// Marshal encodes metadata for storage.
+	Marshal(metadata map[string]any) ([]byte, error)
+
+	// Unmarshal decodes metadata encoded by Marshal.
+	Unmarshal(data []byte) (map[string]any, error)
+}
+
+// JSONSerializer encodes metadata as a JSON object. It is the default
+// MetadataSerializer. Numbers decode as float64, as with encoding/json.
+var JSONSerializer MetadataSerializer = jsonSerializer{}
+
+type jsonSerializer struct{}
+
+func (jsonSerializer) Marshal(metadata map[string]any) ([]byte, error) {
+	return json.Marshal(metadata)
+}
+
+func (jsonSerializer) Unmarshal(data []byte) (map[string]any, error) {
+	var metadata map[string]any
+	if err := json.Unmarshal(data, &metadata); err != nil {
+		return nil, err
+	}
+	return metadata, nil
+}
+
+// serializer returns Config.MetadataSerializer, or JSONSerializer.
+func (q *Queen) serializer() MetadataSerializer {
+	if q.config.MetadataSerializer != nil {
+		return q.config.MetadataSerializer
+	}
+	return JSONSerializer
+}
+
+// storeMetadata stores Config.Metadata with the record of m, if the driver
+// implements ExtendedDriver. It returns the stored metadata.
+func (q *Queen) storeMetadata(ctx context.Context, m *Migration) (map[string]any, error) {
+	ext, ok := optional[ExtendedDriver](q.driver, FeatureMetadata)
+	if !ok || len(q.config.Metadata) == 0 {
+		return nil, nil
+	}
+
+	data, err := q.serializer().Marshal(q.config.Metadata)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode metadata: %w", err)
+	}
+	if err := ext.SetMetadata(ctx, m.Version, data); err != nil {
+		return nil, err
+	}
+
+	return q.config.Metadata, nil
+}
+
+// loadMetadata decodes the stored metadata into the applied cache, if the
+// driver implements ExtendedDriver.
+func (q *Queen) loadMetadata(ctx context.Context) error {
+	ext, ok := optional[ExtendedDriver](q.driver, FeatureMetadata)
+	if !ok {
+		return nil
+	}
+
+	stored, err := ext.GetMetadata(ctx)
+	if err != nil {
+		return err
+	}
+
+	for version, data := range stored {
+		a, ok := q.applied[version]
+		if !ok || len(data) == 0 {
+			continue
+		}
+		metadata, err := q.serializer().Unmarshal(data)
+		if err != nil {
+			return fmt.Errorf("failed to decode metadata of %s: %w", version, err)
+		}
+		a.Metadata = metadata
+	}
+
+	return nil
+}
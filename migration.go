@@ -3,8 +3,11 @@ package queen
 import (
 	"context"
 	"database/sql"
-	"strings"
+	"database/sql/driver"
+	"fmt"
+	"slices"
 	"sync"
+	"time"
 
 	"github.com/honeynil/queen/internal/checksum"
 )
@@ -86,6 +89,86 @@ type Migration struct {
 	// Optional but recommended for safe rollbacks.
 	DownFunc MigrationFunc
 
+	// UpArgs and DownArgs are bound to the placeholders of UpSQL and
+	// DownSQL, e.g. for seed data. A script split into statements hands
+	// them out to the statements in order, as the driver counts their
+	// placeholders (see PlaceholderCounter). Running fails with
+	// ErrInvalidMigration if the placeholders don't take exactly the args.
+	// Changing them changes the checksum, computed from the values the
+	// database receives, so args must be of types database/sql converts
+	// by default: basic kinds, time.Time, []byte, pointers to them,
+	// driver.Valuer and sql.NamedArg. Validate rejects other types.
+	//
+	// The arguments aren't stored in the tracking table, so a migration
+	// with DownArgs is recorded without its DownSQL and can't be rolled
+	// back once removed from code (see Applied.DownSQL).
+	UpArgs   []any
+	DownArgs []any
+
+	// TranslatePlaceholders rewrites the ? placeholders of UpSQL and
+	// DownSQL to the driver's style, such as $1 for PostgreSQL, so the
+	// same parameterized migration runs on every driver. Question marks in
+	// strings and comments are left alone.
+	//
+	// Drivers implementing PlaceholderTranslator, such as PostgreSQL's,
+	// take ?? for a literal ?, e.g. the jsonb operator. Drivers whose
+	// database takes ? placeholders, such as MySQL and SQLite, run the SQL
+	// as written, where ?? is two placeholders.
+	TranslatePlaceholders bool
+
+	// NoTransaction runs UpSQL and DownSQL on a plain connection instead of
+	// inside a transaction. Required for statements such as PostgreSQL's
+	// CREATE INDEX CONCURRENTLY. Only SQL migrations are supported.
+	//
+	// A failed non-transactional migration may leave the database partially
+	// migrated; it is left dirty and must be resolved with Repair or Force.
+	NoTransaction bool
+
+	// Timeout limits how long the up or down part of the migration may run.
+	// The context passed to the migration is cancelled once it expires.
+	// Zero means no limit.
+	Timeout time.Duration
+
+	// Requires lists prerequisites checked before the migration runs, as
+	// "kind:value" pairs: "extension:postgis", "min_pg_version:14",
+	// "flag:allow_big_table_rewrite". Extensions and versions are checked
+	// against the driver's Capabilities, flags against Config.Flags.
+	Requires []string
+
+	// MinDBVersion is the minimum database server version the migration
+	// needs, e.g. "8.0.16" for MySQL CHECK constraints. It is checked against
+	// the driver's Capabilities by Validate and before Up, and is shorthand
+	// for a "min_version:" entry in Requires.
+	MinDBVersion string
+
+	// Environments restricts the migration to the listed environments,
+	// e.g. []string{"dev", "staging"} for test data or debug indexes.
+	// When Config.Environment is set and not listed, the migration is
+	// skipped. Empty means every environment.
+	Environments []string
+
+	// IrreversibleOK exempts the migration from Config.RequireRollback, for
+	// changes that can't be undone, such as dropping a column with data.
+	IrreversibleOK bool
+
+	// Backup makes Config.Backup back up before the migration runs in
+	// either direction, e.g. for Go functions rewriting data, which aren't
+	// detected as destructive.
+	Backup bool
+
+	// LockHazardOK exempts the migration from Config.LockHazards, for
+	// statements known to be safe, e.g. on a table that is always small.
+	LockHazardOK bool
+
+	// ExpectRowDelta bounds how much applying the migration may change the
+	// row counts of tables, a cheap guard against a mistaken WHERE clause.
+	// Tables are counted before and after the up part, in its transaction;
+	// a change out of bounds fails the migration with ErrRowDelta and rolls
+	// it back. NoTransaction migrations can't be rolled back and are left
+	// dirty. For UpFuncs returning ErrContinue, the change is counted over
+	// all their transactions but only the last one is rolled back.
+	ExpectRowDelta []RowDelta
+
 	// ManualChecksum tracks changes to function migrations.
 	// Required when using UpFunc/DownFunc for validation.
 	// Examples: "v1", "v2", "normalize-emails-v1"
@@ -96,6 +179,12 @@ type Migration struct {
 	// when Migration is passed by value.
 	checksumOnce *sync.Once
 	checksum     string
+
+	// Formatting ignored by the checksum, from Config.ChecksumNormalization
+	normalize checksum.Normalization
+
+	// Rules added to IsDestructive, from Config
+	destructiveRules destructiveRules
 }
 
 // M is a convenient alias for Migration, used in registration:
@@ -108,7 +197,10 @@ type Migration struct {
 //	})
 type M = Migration
 
-// Validate ensures Version, Name, and at least one Up method are defined.
+// Validate ensures Version, Name, and at least one Up method are defined,
+// that NoTransaction is only used with SQL migrations, that Timeout is not
+// negative, and that Requires, MinDBVersion and ExpectRowDelta are
+// well-formed.
 func (m *Migration) Validate() error {
 	if m.Version == "" {
 		return ErrInvalidMigration
@@ -123,7 +215,34 @@ func (m *Migration) Validate() error {
 		return ErrInvalidMigration
 	}
 
-	return nil
+	// Go functions receive a transaction, so they can't run without one
+	if m.NoTransaction && (m.UpFunc != nil || m.DownFunc != nil) {
+		return ErrInvalidMigration
+	}
+
+	if m.Timeout < 0 {
+		return ErrInvalidMigration
+	}
+
+	// Go functions bind their own arguments
+	if len(m.UpArgs) > 0 && m.UpSQL == "" || len(m.DownArgs) > 0 && m.DownSQL == "" {
+		return ErrInvalidMigration
+	}
+
+	// Args are checksummed, so they need a stable encoding
+	for _, arg := range append(slices.Clip(m.UpArgs), m.DownArgs...) {
+		if _, err := stableArg(arg); err != nil {
+			return fmt.Errorf("%w: args: %w", ErrInvalidMigration, err)
+		}
+	}
+
+	for _, req := range m.requirements() {
+		if _, _, _, ok := parseRequirement(req); !ok {
+			return fmt.Errorf("%w: unknown requirement %q", ErrInvalidMigration, req)
+		}
+	}
+
+	return m.validateRowDeltas()
 }
 
 // noChecksumMarker indicates that checksum validation is disabled for Go function
@@ -146,7 +265,10 @@ func (m *Migration) Checksum() string {
 
 		// For SQL migrations, calculate checksum
 		if m.UpSQL != "" || m.DownSQL != "" {
-			m.checksum = checksum.Calculate(m.UpSQL, m.DownSQL)
+			m.checksum = checksum.Calculate(append([]string{
+				checksum.Normalize(m.UpSQL, m.normalize),
+				checksum.Normalize(m.DownSQL, m.normalize),
+			}, m.argsChecksumContent()...)...)
 			return
 		}
 
@@ -157,59 +279,208 @@ func (m *Migration) Checksum() string {
 	return m.checksum
 }
 
+// checksumMatches reports whether stored, the checksum recorded when the
+// migration was applied, matches its current content. With normalization
+// enabled, records written before it still match the raw checksum.
+func (m *Migration) checksumMatches(stored string) bool {
+	sum := m.Checksum()
+	if sum == stored || sum == noChecksumMarker {
+		return true
+	}
+
+	return m.normalize != 0 && m.ManualChecksum == "" &&
+		stored == checksum.Calculate(append([]string{m.UpSQL, m.DownSQL}, m.argsChecksumContent()...)...)
+}
+
+// argsChecksumContent returns the checksummed form of UpArgs and DownArgs,
+// nothing without them so checksums of other migrations stay the same.
+func (m *Migration) argsChecksumContent() []string {
+	if len(m.UpArgs) == 0 && len(m.DownArgs) == 0 {
+		return nil
+	}
+	return []string{fmt.Sprintf("%#v", stableArgs(m.UpArgs)), fmt.Sprintf("%#v", stableArgs(m.DownArgs))}
+}
+
+// stableArgs returns args as the database receives them, so their
+// checksum doesn't depend on pointer addresses or the Go types wrapping
+// them. Args without a stable form, which Validate rejects, are kept as
+// they are.
+func stableArgs(args []any) []any {
+	if args == nil {
+		return nil
+	}
+
+	stable := make([]any, len(args))
+	for i, arg := range args {
+		v, err := stableArg(arg)
+		if err != nil {
+			v = arg
+		}
+		stable[i] = v
+	}
+	return stable
+}
+
+// stableArg converts arg as database/sql does by default: driver.Valuer
+// values are resolved, pointers dereferenced and basic kinds widened, e.g.
+// int to int64. sql.NamedArg keeps its name.
+func stableArg(arg any) (any, error) {
+	if named, ok := arg.(sql.NamedArg); ok {
+		v, err := stableArg(named.Value)
+		return sql.NamedArg{Name: named.Name, Value: v}, err
+	}
+	return driver.DefaultParameterConverter.ConvertValue(arg)
+}
+
+// ChecksumNormalization selects formatting differences ignored by the
+// checksums of SQL migrations. Values can be combined with |.
+type ChecksumNormalization int
+
+const (
+	// ChecksumStripComments ignores -- and /* */ comments.
+	ChecksumStripComments = ChecksumNormalization(checksum.StripComments)
+
+	// ChecksumCollapseWhitespace ignores the amount and kind of whitespace
+	// between tokens, including indentation and blank lines.
+	ChecksumCollapseWhitespace = ChecksumNormalization(checksum.CollapseWhitespace)
+
+	// ChecksumTrimSemicolons ignores trailing semicolons.
+	ChecksumTrimSemicolons = ChecksumNormalization(checksum.TrimSemicolons)
+
+	// ChecksumIgnoreFormatting combines all normalizations.
+	ChecksumIgnoreFormatting = ChecksumStripComments | ChecksumCollapseWhitespace | ChecksumTrimSemicolons
+)
+
+// inEnvironment reports whether the migration runs in env. An empty env or
+// an empty Environments list matches everything.
+func (m *Migration) inEnvironment(env string) bool {
+	return env == "" || len(m.Environments) == 0 || slices.Contains(m.Environments, env)
+}
+
 // HasRollback checks if DownSQL or DownFunc is defined.
 func (m *Migration) HasRollback() bool {
 	return m.DownSQL != "" || m.DownFunc != nil
 }
 
-// IsDestructive checks DownSQL for destructive keywords: DROP TABLE, DROP DATABASE, TRUNCATE, etc.
-// Up migrations are assumed constructive and not checked.
+// IsDestructive reports whether UpSQL or DownSQL destroys data: DROP
+// TABLE, DROP COLUMN, TRUNCATE, DELETE without WHERE and the like, plus the
+// rules from Config.DestructiveKeywords and Config.DestructivePatterns.
 func (m *Migration) IsDestructive() bool {
-	if m.DownSQL == "" {
-		return false
+	return m.destructive(false) || m.destructive(true)
+}
+
+// DestructiveStatements returns the statements of UpSQL, or DownSQL if down
+// is set, that make the migration destructive (see IsDestructive), with
+// comments removed and whitespace collapsed, e.g. to show them before
+// asking for confirmation.
+func (m *Migration) DestructiveStatements(down bool) []string {
+	if down {
+		return destructiveStatements(m.DownSQL, m.destructiveRules)
 	}
+	return destructiveStatements(m.UpSQL, m.destructiveRules)
+}
 
-	sql := strings.ToUpper(m.DownSQL)
+// destructive reports whether the up or down part of the migration
+// destroys data.
+func (m *Migration) destructive(down bool) bool {
+	if down {
+		return isDestructiveSQL(m.DownSQL, m.destructiveRules)
+	}
+	return isDestructiveSQL(m.UpSQL, m.destructiveRules)
+}
 
-	destructiveKeywords := []string{
-		"DROP TABLE",
-		"DROP DATABASE",
-		"DROP SCHEMA",
-		"TRUNCATE",
+// execer is implemented by *sql.Tx and *sql.Conn.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// sqlRunner is how a driver runs UpSQL and DownSQL: split into statements,
+// with placeholders counted and translated, by functions that are nil if it
+// doesn't.
+type sqlRunner struct {
+	split     func(string) []string
+	count     func(string) int
+	translate func(string) string
+}
+
+// execSQL executes UpSQL, or DownSQL if down is set, one statement at a
+// time if r splits it.
+//
+// Bind args are handed out to the statements in order, as many as r counts
+// placeholders in each, once translated. Nothing is executed unless the
+// placeholders take exactly the given args, if there are any.
+func (m *Migration) execSQL(ctx context.Context, ex execer, down bool, r sqlRunner) error {
+	query, args := m.UpSQL, m.UpArgs
+	if down {
+		query, args = m.DownSQL, m.DownArgs
+	}
+
+	statements := []string{query}
+	if r.split != nil {
+		statements = r.split(query)
 	}
 
-	for _, keyword := range destructiveKeywords {
-		if strings.Contains(sql, keyword) {
-			return true
+	counts := make([]int, len(statements))
+	total := 0
+	for i, stmt := range statements {
+		if m.TranslatePlaceholders && r.translate != nil {
+			statements[i] = r.translate(stmt)
+		}
+		if r.count != nil {
+			counts[i] = r.count(statements[i])
+			total += counts[i]
 		}
 	}
 
-	return false
+	switch {
+	case len(args) == 0:
+		// Nothing to hand out, e.g. PREPARE ... AS SELECT $1 runs as written
+	case r.count == nil && len(statements) == 1:
+		// A single statement takes all the args, the database checks them
+		counts[0] = len(args)
+	case r.count == nil:
+		return fmt.Errorf("%w: bind args for a script split into %d statements, the driver can't count their placeholders",
+			ErrUnsupported, len(statements))
+	case total != len(args):
+		return fmt.Errorf("%w: %d bind args for %d placeholders", ErrInvalidMigration, len(args), total)
+	}
+
+	for i, stmt := range statements {
+		var stmtArgs []any
+		stmtArgs, args = args[:counts[i]], args[counts[i]:]
+
+		if _, err := ex.ExecContext(ctx, stmt, stmtArgs...); err != nil {
+			if len(statements) > 1 {
+				return fmt.Errorf("statement %d: %w", i+1, err)
+			}
+			return err
+		}
+	}
+
+	return nil
 }
 
 // executeUp runs UpFunc or UpSQL within the transaction.
-func (m *Migration) executeUp(ctx context.Context, tx *sql.Tx) error {
+func (m *Migration) executeUp(ctx context.Context, tx *sql.Tx, r sqlRunner) error {
 	if m.UpFunc != nil {
 		return m.UpFunc(ctx, tx)
 	}
 
 	if m.UpSQL != "" {
-		_, err := tx.ExecContext(ctx, m.UpSQL)
-		return err
+		return m.execSQL(ctx, tx, false, r)
 	}
 
 	return ErrInvalidMigration
 }
 
 // executeDown runs DownFunc or DownSQL within the transaction.
-func (m *Migration) executeDown(ctx context.Context, tx *sql.Tx) error {
+func (m *Migration) executeDown(ctx context.Context, tx *sql.Tx, r sqlRunner) error {
 	if m.DownFunc != nil {
 		return m.DownFunc(ctx, tx)
 	}
 
 	if m.DownSQL != "" {
-		_, err := tx.ExecContext(ctx, m.DownSQL)
-		return err
+		return m.execSQL(ctx, tx, true, r)
 	}
 
 	return ErrInvalidMigration
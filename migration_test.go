@@ -4,7 +4,13 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"regexp"
+	"slices"
+	"strings"
 	"testing"
+
+	"github.com/honeynil/queen/internal/checksum"
+	"github.com/honeynil/queen/internal/split"
 )
 
 func TestMigrationValidate(t *testing.T) {
@@ -23,6 +29,17 @@ func TestMigrationValidate(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "DownArgs without DownSQL",
+			m: Migration{
+				Version:  "001",
+				Name:     "seed",
+				UpSQL:    "INSERT INTO t VALUES (?)",
+				UpArgs:   []any{1},
+				DownArgs: []any{1},
+			},
+			wantErr: true,
+		},
 		{
 			name: "valid with UpSQL only",
 			m: Migration{
@@ -56,6 +73,28 @@ func TestMigrationValidate(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "NoTransaction SQL migration",
+			m: Migration{
+				Version:       "001",
+				Name:          "create_index",
+				UpSQL:         "CREATE INDEX CONCURRENTLY idx ON users (email)",
+				NoTransaction: true,
+			},
+			wantErr: false,
+		},
+		{
+			name: "NoTransaction with Go function",
+			m: Migration{
+				Version:       "001",
+				Name:          "seed_data",
+				NoTransaction: true,
+				UpFunc: func(ctx context.Context, tx *sql.Tx) error {
+					return nil
+				},
+			},
+			wantErr: true,
+		},
 		{
 			name: "valid Go function migration",
 			m: Migration{
@@ -67,6 +106,36 @@ func TestMigrationValidate(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "valid row delta",
+			m: Migration{
+				Version:        "001",
+				Name:           "purge_sessions",
+				UpSQL:          "DELETE FROM sessions WHERE expired",
+				ExpectRowDelta: []RowDelta{{Table: "public.sessions", Min: -1000, Max: 0}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "row delta min above max",
+			m: Migration{
+				Version:        "001",
+				Name:           "purge_sessions",
+				UpSQL:          "DELETE FROM sessions WHERE expired",
+				ExpectRowDelta: []RowDelta{{Table: "sessions", Min: 0, Max: -1000}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "row delta of quoted table",
+			m: Migration{
+				Version:        "001",
+				Name:           "purge_sessions",
+				UpSQL:          "DELETE FROM sessions WHERE expired",
+				ExpectRowDelta: []RowDelta{{Table: `"sessions"`, Min: -1000, Max: 0}},
+			},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -216,15 +285,81 @@ func TestMigrationIsDestructive(t *testing.T) {
 			want: true,
 		},
 		{
-			name: "safe ALTER",
+			name: "DROP COLUMN",
 			m: Migration{
 				Version: "001",
 				Name:    "test",
 				UpSQL:   "ALTER TABLE users ADD COLUMN email VARCHAR(255)",
 				DownSQL: "ALTER TABLE users DROP COLUMN email",
 			},
+			want: true,
+		},
+		{
+			name: "safe ALTER",
+			m: Migration{
+				Version: "001",
+				Name:    "test",
+				UpSQL:   "ALTER TABLE users ADD COLUMN email VARCHAR(255)",
+				DownSQL: "DROP INDEX users_email",
+			},
 			want: false,
 		},
+		{
+			name: "destructive Up",
+			m: Migration{
+				Version: "001",
+				Name:    "test",
+				UpSQL:   "CREATE TABLE accounts (id INT);\nDROP   table users;",
+			},
+			want: true,
+		},
+		{
+			name: "DELETE without WHERE",
+			m: Migration{
+				Version: "001",
+				Name:    "test",
+				UpSQL:   "DELETE FROM sessions",
+			},
+			want: true,
+		},
+		{
+			name: "DELETE with WHERE",
+			m: Migration{
+				Version: "001",
+				Name:    "test",
+				UpSQL:   "DELETE FROM sessions WHERE expires_at < now()",
+			},
+			want: false,
+		},
+		{
+			name: "keyword in comment",
+			m: Migration{
+				Version: "001",
+				Name:    "test",
+				UpSQL:   "-- replaces DROP TABLE from 003\nCREATE TABLE users (id INT)",
+			},
+			want: false,
+		},
+		{
+			name: "configured keyword",
+			m: Migration{
+				Version:          "001",
+				Name:             "test",
+				UpSQL:            "ALTER TABLE users RENAME COLUMN mail TO email",
+				destructiveRules: destructiveRules{keywords: []string{"rename column"}},
+			},
+			want: true,
+		},
+		{
+			name: "configured pattern",
+			m: Migration{
+				Version:          "001",
+				Name:             "test",
+				UpSQL:            "UPDATE users SET email = NULL",
+				destructiveRules: destructiveRules{patterns: []*regexp.Regexp{regexp.MustCompile(`(?i)^UPDATE\b`)}},
+			},
+			want: true,
+		},
 		{
 			name: "no Down",
 			m: Migration{
@@ -245,6 +380,45 @@ func TestMigrationIsDestructive(t *testing.T) {
 	}
 }
 
+func TestDestructiveTables(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"DROP TABLE users", []string{"users"}},
+		{"DROP TABLE IF EXISTS users, billing.invoices CASCADE", []string{"users", "billing.invoices"}},
+		{"TRUNCATE TABLE ONLY \"Audit Log\"", []string{`"Audit Log"`}},
+		{"ALTER TABLE users DROP COLUMN email", []string{"users"}},
+		{"ALTER TABLE users DROP CONSTRAINT users_email_key", nil},
+		{"DELETE FROM sessions; DELETE FROM users WHERE id = 1", []string{"sessions"}},
+		{"DROP TABLE users; TRUNCATE users", []string{"users"}},
+		{"CREATE TABLE users (id INT)", nil},
+		{"", nil},
+	}
+
+	for _, tt := range tests {
+		if got := destructiveTables(tt.query); !slices.Equal(got, tt.want) {
+			t.Errorf("destructiveTables(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestMigrationDestructiveStatements(t *testing.T) {
+	m := Migration{
+		Version: "003",
+		Name:    "drop_legacy",
+		UpSQL:   "CREATE TABLE archive (id INT); -- keep\nDROP   TABLE legacy;",
+		DownSQL: "DELETE FROM archive; DELETE FROM legacy WHERE id = 1",
+	}
+
+	if got, want := m.DestructiveStatements(false), []string{"DROP TABLE legacy"}; !slices.Equal(got, want) {
+		t.Errorf("DestructiveStatements(false) = %q, want %q", got, want)
+	}
+	if got, want := m.DestructiveStatements(true), []string{"DELETE FROM archive"}; !slices.Equal(got, want) {
+		t.Errorf("DestructiveStatements(true) = %q, want %q", got, want)
+	}
+}
+
 func TestMigrationExecuteUp(t *testing.T) {
 	t.Run("invalid migration", func(t *testing.T) {
 		m := Migration{
@@ -253,7 +427,7 @@ func TestMigrationExecuteUp(t *testing.T) {
 			// No Up method
 		}
 
-		err := m.executeUp(context.Background(), nil)
+		err := m.executeUp(context.Background(), nil, sqlRunner{})
 		if !errors.Is(err, ErrInvalidMigration) {
 			t.Errorf("Expected ErrInvalidMigration, got %v", err)
 		}
@@ -271,10 +445,149 @@ func TestMigrationExecuteUp(t *testing.T) {
 			},
 		}
 
-		m.executeUp(context.Background(), nil)
+		m.executeUp(context.Background(), nil, sqlRunner{})
 
 		if !called {
 			t.Error("UpFunc was not called")
 		}
 	})
 }
+
+// recordingExecer records the statements executed and their arguments.
+type recordingExecer struct {
+	statements []string
+	args       [][]any
+}
+
+func (e *recordingExecer) ExecContext(_ context.Context, query string, args ...any) (sql.Result, error) {
+	e.statements = append(e.statements, query)
+	e.args = append(e.args, args)
+	return nil, nil
+}
+
+func TestMigrationExecSQL_Args(t *testing.T) {
+	r := sqlRunner{
+		split:     func(q string) []string { return strings.Split(q, ";") },
+		count:     func(q string) int { return split.CountPlaceholders(q, split.Postgres) },
+		translate: split.Rebind,
+	}
+	m := Migration{
+		UpSQL:                 "INSERT INTO a VALUES (?, '?');INSERT INTO b VALUES (?, ?)",
+		UpArgs:                []any{1, 2, 3},
+		TranslatePlaceholders: true,
+	}
+
+	var ex recordingExecer
+	if err := m.execSQL(context.Background(), &ex, false, r); err != nil {
+		t.Fatal(err)
+	}
+
+	// Each statement gets the arguments of its placeholders
+	if want := []string{"INSERT INTO a VALUES ($1, '?')", "INSERT INTO b VALUES ($1, $2)"}; !slices.Equal(ex.statements, want) {
+		t.Errorf("Executed %q; want %q", ex.statements, want)
+	}
+	if len(ex.args) != 2 || !slices.Equal(ex.args[0], []any{1}) || !slices.Equal(ex.args[1], []any{2, 3}) {
+		t.Errorf("Arguments %v; want [1] and [2 3]", ex.args)
+	}
+
+	// Without TranslatePlaceholders, the driver's translator isn't used,
+	// and placeholders are counted as the database takes them
+	m.TranslatePlaceholders = false
+	r.count = func(q string) int { return split.CountPlaceholders(q, split.MySQL) }
+	ex = recordingExecer{}
+	if err := m.execSQL(context.Background(), &ex, false, r); err != nil {
+		t.Fatal(err)
+	}
+	if ex.statements[0] != "INSERT INTO a VALUES (?, '?')" {
+		t.Errorf("Executed %q untranslated", ex.statements[0])
+	}
+}
+
+func TestMigrationExecSQL_ArgsMismatch(t *testing.T) {
+	r := sqlRunner{
+		split: func(q string) []string { return strings.Split(q, ";") },
+		count: func(q string) int { return split.CountPlaceholders(q, split.MySQL) },
+	}
+
+	tests := []struct {
+		name string
+		r    sqlRunner
+		args []any
+		want error
+	}{
+		{"surplus", r, []any{1, 2, 3, 4}, ErrInvalidMigration},
+		{"missing", r, []any{1, 2}, ErrInvalidMigration},
+		{"no counter", sqlRunner{split: r.split}, []any{1, 2, 3}, ErrUnsupported},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := Migration{UpSQL: "INSERT INTO a VALUES (?);INSERT INTO b VALUES (?, ?)", UpArgs: tt.args}
+
+			var ex recordingExecer
+			if err := m.execSQL(context.Background(), &ex, false, tt.r); !errors.Is(err, tt.want) {
+				t.Errorf("execSQL = %v; want %v", err, tt.want)
+			}
+			if len(ex.statements) != 0 {
+				t.Errorf("Executed %q before refusing the args", ex.statements)
+			}
+		})
+	}
+
+	// A single statement takes every arg without a counter
+	m := Migration{UpSQL: "INSERT INTO a VALUES (?, ?)", UpArgs: []any{1, 2}}
+	var ex recordingExecer
+	if err := m.execSQL(context.Background(), &ex, false, sqlRunner{}); err != nil {
+		t.Fatal(err)
+	}
+	if len(ex.args) != 1 || !slices.Equal(ex.args[0], []any{1, 2}) {
+		t.Errorf("Arguments %v; want [1 2]", ex.args)
+	}
+}
+
+func TestMigrationChecksum_Args(t *testing.T) {
+	a := Migration{UpSQL: "INSERT INTO t VALUES (?)", UpArgs: []any{"a"}}
+	b := Migration{UpSQL: "INSERT INTO t VALUES (?)", UpArgs: []any{"b"}}
+	if a.Checksum() == b.Checksum() {
+		t.Error("Checksum ignores UpArgs")
+	}
+
+	// Checksums of migrations without arguments stay as they were
+	c := Migration{UpSQL: "CREATE TABLE t (id INT)"}
+	if want := checksum.Calculate("CREATE TABLE t (id INT)", ""); c.Checksum() != want {
+		t.Errorf("Checksum = %s; want %s", c.Checksum(), want)
+	}
+
+	// Args are hashed as the database receives them, not by address or
+	// wrapping type
+	name1, name2 := "alice", "alice"
+	stable := Migration{UpSQL: "INSERT INTO t VALUES (?, ?)", UpArgs: []any{int64(1), "alice"}}
+	for _, args := range [][]any{
+		{1, &name1},
+		{int32(1), &name2},
+		{1, sql.NullString{String: "alice", Valid: true}},
+	} {
+		m := Migration{UpSQL: stable.UpSQL, UpArgs: args}
+		if m.Checksum() != stable.Checksum() {
+			t.Errorf("Checksum of %#v differs from %#v", args, stable.UpArgs)
+		}
+	}
+
+	// Args written before stay the same
+	if want := checksum.Calculate(a.UpSQL, "", `[]interface {}{"a"}`, `[]interface {}(nil)`); a.Checksum() != want {
+		t.Errorf("Checksum = %s; want %s", a.Checksum(), want)
+	}
+}
+
+func TestMigrationValidate_Args(t *testing.T) {
+	m := Migration{Version: "001", Name: "seed", UpSQL: "INSERT INTO t VALUES (?, ?)",
+		UpArgs: []any{sql.Named("id", 1), struct{ ID int }{1}}}
+	if err := m.Validate(); !errors.Is(err, ErrInvalidMigration) {
+		t.Errorf("Validate = %v; want ErrInvalidMigration for an arg without a stable encoding", err)
+	}
+
+	m.UpArgs = m.UpArgs[:1]
+	if err := m.Validate(); err != nil {
+		t.Errorf("Validate = %v; want a named arg accepted", err)
+	}
+}
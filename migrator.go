@@ -0,0 +1,61 @@
+package queen
+
+import (
+	"context"
+	"database/sql"
+)
+
+// Migrator is a migration defined as a type instead of an M, so it can
+// carry its dependencies, such as repositories or configuration, as fields
+// rather than capturing them in closures:
+//
+//	type backfillPlans struct{ plans *PlanRepository }
+//
+//	func (backfillPlans) Version() string { return "042" }
+//	func (backfillPlans) Name() string    { return "backfill_plans" }
+//
+//	func (b backfillPlans) Up(ctx context.Context, tx *sql.Tx) error {
+//	    return b.plans.Backfill(ctx, tx)
+//	}
+//
+//	func (b backfillPlans) Down(ctx context.Context, tx *sql.Tx) error {
+//	    return b.plans.Clear(ctx, tx)
+//	}
+//
+//	q.MustAddMigrator(backfillPlans{plans: repo})
+//
+// A Migrator that also has a Checksum() string method sets the migration's
+// ManualChecksum, to be changed whenever Up or Down changes.
+type Migrator interface {
+	Version() string
+	Name() string
+	Up(ctx context.Context, tx *sql.Tx) error
+	Down(ctx context.Context, tx *sql.Tx) error
+}
+
+// FromMigrator returns the M running mg, e.g. for the registry package or
+// to set further fields before Add.
+func FromMigrator(mg Migrator) M {
+	m := M{
+		Version:  mg.Version(),
+		Name:     mg.Name(),
+		UpFunc:   mg.Up,
+		DownFunc: mg.Down,
+	}
+	if c, ok := mg.(interface{ Checksum() string }); ok {
+		m.ManualChecksum = c.Checksum()
+	}
+	return m
+}
+
+// AddMigrator registers mg like Add.
+func (q *Queen) AddMigrator(mg Migrator) error {
+	return q.Add(FromMigrator(mg))
+}
+
+// MustAddMigrator is like AddMigrator but panics on error.
+func (q *Queen) MustAddMigrator(mg Migrator) {
+	if err := q.AddMigrator(mg); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,131 @@
+package queen
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Module is a named stream of migrations registered on a Queen, so the
+// domains of a large application can each own their migrations without
+// separate Queen instances and tracking tables:
+//
+//	billing := q.Module("billing")
+//	billing.MustAdd(queen.M{Version: "001", Name: "create_invoices", UpSQL: "..."})
+//
+//	q.UpModule(ctx, "billing")
+//
+// Migrations of a module are tracked with the module name as a version
+// prefix, e.g. "billing/001", and Up applies them along with the others.
+// Versions of a module only need to increase within the module:
+// Config.OutOfOrder compares them with the applied versions of the same
+// module.
+type Module struct {
+	q    *Queen
+	name string
+}
+
+// Module returns the module with the given name. Its name must be
+// non-empty and not contain "/", or adding migrations to it fails with
+// ErrInvalidMigration.
+func (q *Queen) Module(name string) *Module {
+	if q.modules == nil {
+		q.modules = make(map[string]bool)
+	}
+	q.modules[name] = true
+
+	return &Module{q: q, name: name}
+}
+
+// Name returns the name of the module.
+func (mod *Module) Name() string {
+	return mod.name
+}
+
+// Add registers m in the module like Queen.Add, prefixing its version with
+// the module name.
+func (mod *Module) Add(m M) error {
+	m, err := mod.prefixed(m)
+	if err != nil {
+		return err
+	}
+	return mod.q.Add(m)
+}
+
+// MustAdd is like Add but panics on error.
+func (mod *Module) MustAdd(m M) {
+	if err := mod.Add(m); err != nil {
+		panic(err)
+	}
+}
+
+// AddAll registers every migration in ms in the module like Queen.AddAll.
+func (mod *Module) AddAll(ms ...M) error {
+	prefixed := make([]M, 0, len(ms))
+	for _, m := range ms {
+		m, err := mod.prefixed(m)
+		if err != nil {
+			return err
+		}
+		prefixed = append(prefixed, m)
+	}
+	return mod.q.AddAll(prefixed...)
+}
+
+// prefixed returns m with its version prefixed with the module name.
+func (mod *Module) prefixed(m M) (M, error) {
+	if mod.name == "" || strings.Contains(mod.name, "/") {
+		return m, fmt.Errorf("%w: module name %q", ErrInvalidMigration, mod.name)
+	}
+	if m.Version != "" {
+		m.Version = mod.name + "/" + m.Version
+	}
+	return m, nil
+}
+
+// UpModule applies the pending migrations of the module name, leaving the
+// other modules' pending. Returns ErrNoMigrations if the module has no
+// registered migrations.
+func (q *Queen) UpModule(ctx context.Context, name string) error {
+	inModule := q.inModule(name)
+	if !q.hasMigrations(inModule) {
+		return fmt.Errorf("%w: module %s", ErrNoMigrations, name)
+	}
+
+	return q.withLock(ctx, func() error {
+		return q.upLocked(ctx, 0, inModule)
+	})
+}
+
+// DownModule rolls back the last n applied migrations of the module name.
+// If n <= 0, it rolls back only the last one.
+func (q *Queen) DownModule(ctx context.Context, name string, n int) error {
+	return q.down(ctx, n, q.inModule(name))
+}
+
+// inModule returns a filter accepting the migrations of the module name.
+func (q *Queen) inModule(name string) func(*Migration) bool {
+	return func(m *Migration) bool {
+		return q.moduleOf(m.Version) == name
+	}
+}
+
+// hasMigrations reports whether a registered migration matches only.
+func (q *Queen) hasMigrations(only func(*Migration) bool) bool {
+	for _, m := range q.migrations {
+		if only(m) {
+			return true
+		}
+	}
+	return false
+}
+
+// moduleOf returns the module version belongs to, or "" if it belongs to
+// none.
+func (q *Queen) moduleOf(version string) string {
+	name, _, ok := strings.Cut(version, "/")
+	if !ok || !q.modules[name] {
+		return ""
+	}
+	return name
+}
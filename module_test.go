@@ -0,0 +1,67 @@
+package queen_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/honeynil/queen"
+	"github.com/honeynil/queen/drivers/mock"
+)
+
+func TestModules(t *testing.T) {
+	driver := mock.New()
+	q := queen.NewWithConfig(driver, &queen.Config{OutOfOrder: queen.OutOfOrderError})
+	ctx := context.Background()
+
+	billing := q.Module("billing")
+	billing.MustAdd(queen.M{Version: "001", Name: "create_invoices", UpFunc: noop, DownFunc: noop})
+	billing.MustAdd(queen.M{Version: "002", Name: "create_payments", UpFunc: noop, DownFunc: noop})
+	if err := q.Module("users").AddAll(
+		queen.M{Version: "001", Name: "create_users", UpFunc: noop, DownFunc: noop},
+		queen.M{Version: "002", Name: "create_sessions", UpFunc: noop, DownFunc: noop},
+	); err != nil {
+		t.Fatalf("AddAll failed: %v", err)
+	}
+	q.MustAdd(queen.M{Version: "001", Name: "create_settings", UpFunc: noop})
+
+	if err := billing.Add(queen.M{Version: "001", Name: "duplicate", UpFunc: noop}); !errors.Is(err, queen.ErrVersionConflict) {
+		t.Errorf("Expected ErrVersionConflict within a module, got %v", err)
+	}
+	if err := q.Module("a/b").Add(queen.M{Version: "001", Name: "nested", UpFunc: noop}); !errors.Is(err, queen.ErrInvalidMigration) {
+		t.Errorf("Expected ErrInvalidMigration for a module name with a slash, got %v", err)
+	}
+
+	if err := q.UpModule(ctx, "users"); err != nil {
+		t.Fatalf("UpModule(users) failed: %v", err)
+	}
+	if !driver.HasVersion("users/001") || !driver.HasVersion("users/002") || driver.AppliedCount() != 2 {
+		t.Errorf("Expected only the users module applied, got %d migrations", driver.AppliedCount())
+	}
+
+	// billing sorts before users, but only orders within its module
+	if err := q.UpModule(ctx, "billing"); err != nil {
+		t.Fatalf("UpModule(billing) failed: %v", err)
+	}
+	if !driver.HasVersion("billing/002") || driver.HasVersion("001") {
+		t.Error("Expected the billing module applied and the rest pending")
+	}
+
+	if err := q.DownModule(ctx, "users", 1); err != nil {
+		t.Fatalf("DownModule failed: %v", err)
+	}
+	if driver.HasVersion("users/002") || !driver.HasVersion("billing/002") {
+		t.Error("Expected only users/002 rolled back")
+	}
+
+	if err := q.UpModule(ctx, "unknown"); !errors.Is(err, queen.ErrNoMigrations) {
+		t.Errorf("Expected ErrNoMigrations for an unknown module, got %v", err)
+	}
+
+	if err := q.Up(ctx); err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+	if driver.AppliedCount() != 5 {
+		t.Errorf("Expected every migration applied, got %d", driver.AppliedCount())
+	}
+}
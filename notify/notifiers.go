@@ -0,0 +1,183 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/honeynil/queen/fleet"
+)
+
+// Slack posts notifications to a Slack incoming webhook.
+type Slack struct {
+	// WebhookURL is the incoming webhook URL.
+	WebhookURL string
+
+	// Client is used for requests. Default: http.DefaultClient
+	Client *http.Client
+}
+
+// Notify implements Notifier.
+func (s *Slack) Notify(ctx context.Context, n Notification) error {
+	return postJSON(ctx, s.Client, s.WebhookURL, nil, map[string]string{
+		"text": ":rotating_light: " + n.Summary(),
+	})
+}
+
+// PagerDutyEventsURL is the PagerDuty Events API v2 endpoint.
+const PagerDutyEventsURL = "https://events.pagerduty.com/v2/enqueue"
+
+// PagerDuty triggers incidents through the PagerDuty Events API v2.
+// Repeated failures of the same version share a dedup key, so they update
+// a single incident instead of opening new ones.
+type PagerDuty struct {
+	// RoutingKey is the integration key of the PagerDuty service.
+	RoutingKey string
+
+	// Source identifies the affected system. Default: "queen"
+	Source string
+
+	// URL overrides the Events API endpoint. Default: PagerDutyEventsURL
+	URL string
+
+	// Client is used for requests. Default: http.DefaultClient
+	Client *http.Client
+}
+
+// Notify implements Notifier.
+func (p *PagerDuty) Notify(ctx context.Context, n Notification) error {
+	source := p.Source
+	if source == "" {
+		source = "queen"
+	}
+	url := p.URL
+	if url == "" {
+		url = PagerDutyEventsURL
+	}
+
+	return postJSON(ctx, p.Client, url, nil, map[string]any{
+		"routing_key":  p.RoutingKey,
+		"event_action": "trigger",
+		"dedup_key":    "queen-migration-" + n.Version,
+		"payload": map[string]any{
+			"summary":  n.Summary(),
+			"source":   source,
+			"severity": "critical",
+			"custom_details": map[string]any{
+				"version":  n.Version,
+				"name":     n.Name,
+				"down":     n.Down,
+				"failures": n.Failures,
+			},
+		},
+	})
+}
+
+// Email sends notifications through an SMTP server.
+type Email struct {
+	// Addr is the SMTP server address, e.g. "smtp.example.com:587".
+	Addr string
+
+	// Auth authenticates with the server. Optional.
+	Auth smtp.Auth
+
+	// From and To are the envelope and header addresses.
+	From string
+	To   []string
+}
+
+// Notify implements Notifier.
+func (e *Email) Notify(ctx context.Context, n Notification) error {
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", e.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(e.To, ", "))
+	fmt.Fprintf(&msg, "Subject: [queen] migration %s failed %d times\r\n", n.Version, n.Failures)
+	fmt.Fprintf(&msg, "\r\n%s\r\n", n.Summary())
+
+	return smtp.SendMail(e.Addr, e.Auth, e.From, e.To, msg.Bytes())
+}
+
+// Webhook posts notifications and fleet events as JSON to an HTTP
+// endpoint, e.g. a dashboard or a change-management system. Its FleetEvent
+// method can be used as fleet.Options.OnEvent:
+//
+//	hook := &notify.Webhook{URL: "https://deploys.example.com/queen"}
+//	runner := fleet.New(migrations, open, fleet.Options{OnEvent: hook.FleetEvent})
+type Webhook struct {
+	// URL is the endpoint requests are posted to.
+	URL string
+
+	// Headers are added to every request, e.g. for authentication.
+	Headers map[string]string
+
+	// Client is used for requests. Default: http.DefaultClient
+	Client *http.Client
+}
+
+// Notify implements Notifier. The body has the kind "migration_failed"
+// and the fields of n.
+func (w *Webhook) Notify(ctx context.Context, n Notification) error {
+	body := map[string]any{
+		"kind":     "migration_failed",
+		"time":     n.Time,
+		"version":  n.Version,
+		"name":     n.Name,
+		"down":     n.Down,
+		"failures": n.Failures,
+		"summary":  n.Summary(),
+	}
+	if n.Err != nil {
+		body["error"] = n.Err.Error()
+	}
+
+	return postJSON(ctx, w.Client, w.URL, w.Headers, body)
+}
+
+// FleetEvent posts a fleet event, encoded by fleet.Event.MarshalJSON, so
+// rollout progress reaches the endpoint as each wave starts and completes,
+// targets fail and the rollout halts.
+func (w *Webhook) FleetEvent(ctx context.Context, e fleet.Event) error {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	return postJSON(ctx, w.Client, w.URL, w.Headers, e)
+}
+
+// postJSON sends body as JSON with headers and treats non-2xx responses as
+// errors.
+func postJSON(ctx context.Context, client *http.Client, url string, headers map[string]string, body any) error {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("notify: %s responded %s", url, resp.Status)
+	}
+
+	return nil
+}
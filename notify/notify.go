@@ -0,0 +1,157 @@
+// Package notify sends migration failure notifications with escalation.
+//
+// An Escalator counts consecutive failures per migration version and picks
+// the notifiers of the highest matching rule, so the first failure can go
+// to chat while a migration that keeps failing pages someone:
+//
+//	esc := notify.NewEscalator(notify.NewFileStore("/var/lib/app/queen-failures.json"),
+//	    notify.Rule{MinFailures: 1, Notifiers: []notify.Notifier{slack}},
+//	    notify.Rule{MinFailures: 3, Notifiers: []notify.Notifier{pagerduty, email}},
+//	)
+//	q.Hooks().MustRegister(esc.Hook("notify", 100))
+//
+// A successful run of the version resets its counter.
+//
+// Webhook also posts fleet rollout events, through fleet.Options.OnEvent.
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/honeynil/queen"
+)
+
+// Notification describes a failed migration.
+type Notification struct {
+	// Version and Name identify the failed migration.
+	Version string
+	Name    string
+
+	// Down is true if the failure happened during a rollback.
+	Down bool
+
+	// Failures is the number of consecutive failures of this version,
+	// including this one.
+	Failures int
+
+	// Err is the error the migration failed with.
+	Err error
+
+	// Time is when the failure was observed.
+	Time time.Time
+}
+
+// Summary returns a one-line description suitable for chat messages and subjects.
+func (n Notification) Summary() string {
+	direction := "up"
+	if n.Down {
+		direction = "down"
+	}
+	return fmt.Sprintf("migration %s (%s) failed %s (%d consecutive failures): %v",
+		n.Version, n.Name, direction, n.Failures, n.Err)
+}
+
+// Notifier delivers a notification.
+type Notifier interface {
+	Notify(ctx context.Context, n Notification) error
+}
+
+// NotifierFunc adapts a function to the Notifier interface.
+type NotifierFunc func(ctx context.Context, n Notification) error
+
+// Notify calls f(ctx, n).
+func (f NotifierFunc) Notify(ctx context.Context, n Notification) error {
+	return f(ctx, n)
+}
+
+// Rule routes notifications once a version has failed at least MinFailures
+// times in a row.
+type Rule struct {
+	MinFailures int
+	Notifiers   []Notifier
+}
+
+// Escalator tracks consecutive failures and dispatches notifications
+// according to its rules.
+type Escalator struct {
+	store FailureStore
+	rules []Rule
+}
+
+// NewEscalator creates an Escalator. For each failure only the rule with
+// the highest MinFailures not exceeding the failure count is used.
+func NewEscalator(store FailureStore, rules ...Rule) *Escalator {
+	sorted := make([]Rule, len(rules))
+	copy(sorted, rules)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].MinFailures > sorted[j].MinFailures
+	})
+
+	return &Escalator{
+		store: store,
+		rules: sorted,
+	}
+}
+
+// Hook returns a queen.Hook that feeds migration events to the escalator.
+func (e *Escalator) Hook(name string, priority int) queen.Hook {
+	return queen.Hook{
+		Name:     name,
+		Priority: priority,
+		Func: func(ctx context.Context, ev queen.Event) error {
+			switch ev.Kind {
+			case queen.EventFailed:
+				return e.Failed(ctx, ev.Migration, ev.Down, ev.Err)
+			case queen.EventAfterUp, queen.EventAfterDown:
+				return e.store.Reset(ctx, ev.Migration.Version)
+			}
+			return nil
+		},
+	}
+}
+
+// Failed records a failure of m and notifies the matching rule's notifiers.
+// Errors from individual notifiers are joined; all notifiers are attempted.
+func (e *Escalator) Failed(ctx context.Context, m *queen.Migration, down bool, cause error) error {
+	count, err := e.store.Increment(ctx, m.Version)
+	if err != nil {
+		return err
+	}
+
+	rule, ok := e.rule(count)
+	if !ok {
+		return nil
+	}
+
+	n := Notification{
+		Version:  m.Version,
+		Name:     m.Name,
+		Down:     down,
+		Failures: count,
+		Err:      cause,
+		Time:     time.Now(),
+	}
+
+	var errs []error
+	for _, notifier := range rule.Notifiers {
+		if err := notifier.Notify(ctx, n); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// rule returns the rule with the highest MinFailures <= count.
+func (e *Escalator) rule(count int) (Rule, bool) {
+	for _, r := range e.rules {
+		if count >= r.MinFailures {
+			return r, true
+		}
+	}
+	return Rule{}, false
+}
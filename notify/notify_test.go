@@ -0,0 +1,162 @@
+package notify_test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/honeynil/queen"
+	"github.com/honeynil/queen/drivers/mock"
+	"github.com/honeynil/queen/fleet"
+	"github.com/honeynil/queen/notify"
+)
+
+type recorder struct {
+	notifications []notify.Notification
+}
+
+func (r *recorder) Notify(ctx context.Context, n notify.Notification) error {
+	r.notifications = append(r.notifications, n)
+	return nil
+}
+
+func TestEscalation(t *testing.T) {
+	chat := &recorder{}
+	pager := &recorder{}
+	esc := notify.NewEscalator(notify.NewMemoryStore(),
+		notify.Rule{MinFailures: 1, Notifiers: []notify.Notifier{chat}},
+		notify.Rule{MinFailures: 3, Notifiers: []notify.Notifier{pager}},
+	)
+
+	fail := true
+	q := queen.New(mock.New())
+	q.Hooks().MustRegister(esc.Hook("notify", 0))
+	q.MustAdd(queen.M{
+		Version: "001",
+		Name:    "flaky",
+		UpFunc: func(ctx context.Context, tx *sql.Tx) error {
+			if fail {
+				return errors.New("boom")
+			}
+			return nil
+		},
+		DownFunc: func(ctx context.Context, tx *sql.Tx) error { return nil },
+	})
+
+	ctx := context.Background()
+	for i := 0; i < 4; i++ {
+		if err := q.Up(ctx); err == nil {
+			t.Fatal("Expected Up to fail")
+		}
+	}
+
+	if len(chat.notifications) != 2 || len(pager.notifications) != 2 {
+		t.Fatalf("Expected 2 chat and 2 pager notifications, got %d and %d",
+			len(chat.notifications), len(pager.notifications))
+	}
+	if pager.notifications[1].Failures != 4 {
+		t.Errorf("Expected 4 consecutive failures, got %d", pager.notifications[1].Failures)
+	}
+
+	// Success resets the counter
+	fail = false
+	if err := q.Up(ctx); err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+	if err := q.Reset(ctx); err != nil {
+		t.Fatalf("Reset failed: %v", err)
+	}
+
+	fail = true
+	_ = q.Up(ctx)
+	last := chat.notifications[len(chat.notifications)-1]
+	if len(chat.notifications) != 3 || last.Failures != 1 {
+		t.Errorf("Expected counter to restart at 1, got %d notifications (last failures %d)",
+			len(chat.notifications), last.Failures)
+	}
+}
+
+func TestFileStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "failures.json")
+	ctx := context.Background()
+
+	for want := 1; want <= 2; want++ {
+		// A new store per iteration simulates separate processes
+		got, err := notify.NewFileStore(path).Increment(ctx, "001")
+		if err != nil {
+			t.Fatalf("Increment failed: %v", err)
+		}
+		if got != want {
+			t.Errorf("Increment = %d, want %d", got, want)
+		}
+	}
+
+	store := notify.NewFileStore(path)
+	if err := store.Reset(ctx, "001"); err != nil {
+		t.Fatalf("Reset failed: %v", err)
+	}
+	if got, _ := store.Increment(ctx, "001"); got != 1 {
+		t.Errorf("Increment after Reset = %d, want 1", got)
+	}
+}
+
+func TestSlack(t *testing.T) {
+	var body map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&body)
+	}))
+	defer srv.Close()
+
+	slack := &notify.Slack{WebhookURL: srv.URL}
+	err := slack.Notify(context.Background(), notify.Notification{
+		Version: "001", Name: "test", Failures: 1, Err: errors.New("boom"),
+	})
+	if err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+	if body["text"] == "" {
+		t.Error("Expected Slack message text")
+	}
+}
+
+func TestWebhook(t *testing.T) {
+	var body map[string]any
+	var auth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		_ = json.NewDecoder(r.Body).Decode(&body)
+	}))
+	defer srv.Close()
+
+	hook := &notify.Webhook{URL: srv.URL, Headers: map[string]string{"Authorization": "Bearer token"}}
+	err := hook.FleetEvent(context.Background(), fleet.Event{
+		Kind:    fleet.EventWaveCompleted,
+		Wave:    1,
+		Results: []fleet.TargetResult{{Target: fleet.Target{Name: "acme", DSN: "postgres://secret"}, Applied: []string{"001"}}},
+	})
+	if err != nil {
+		t.Fatalf("FleetEvent failed: %v", err)
+	}
+	if auth != "Bearer token" {
+		t.Errorf("Authorization = %q; want the configured header", auth)
+	}
+	if body["kind"] != "wave_completed" || body["wave"] != 1.0 {
+		t.Errorf("body = %v; want the wave_completed event of wave 1", body)
+	}
+	if targets, _ := body["targets"].([]any); len(targets) != 1 || targets[0].(map[string]any)["name"] != "acme" {
+		t.Errorf("targets = %v; want acme", body["targets"])
+	}
+
+	err = hook.Notify(context.Background(), notify.Notification{Version: "001", Failures: 2, Err: errors.New("boom")})
+	if err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+	if body["kind"] != "migration_failed" || body["error"] != "boom" {
+		t.Errorf("body = %v; want the failed migration", body)
+	}
+}
@@ -0,0 +1,129 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"sync"
+)
+
+// FailureStore keeps consecutive failure counts per migration version.
+//
+// Use a persistent store when each deploy runs in a fresh process (CI jobs,
+// init containers); otherwise the count never exceeds one.
+type FailureStore interface {
+	// Increment adds one failure for version and returns the new count.
+	Increment(ctx context.Context, version string) (int, error)
+
+	// Reset clears the failure count for version.
+	Reset(ctx context.Context, version string) error
+}
+
+// MemoryStore is an in-process FailureStore.
+type MemoryStore struct {
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+// NewMemoryStore creates an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{counts: make(map[string]int)}
+}
+
+// Increment implements FailureStore.
+func (s *MemoryStore) Increment(ctx context.Context, version string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.counts[version]++
+	return s.counts[version], nil
+}
+
+// Reset implements FailureStore.
+func (s *MemoryStore) Reset(ctx context.Context, version string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.counts, version)
+	return nil
+}
+
+// FileStore is a FailureStore persisted as a JSON file.
+type FileStore struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewFileStore creates a FileStore backed by path. The file is created on
+// first use.
+func NewFileStore(path string) *FileStore {
+	return &FileStore{path: path}
+}
+
+// Increment implements FailureStore.
+func (s *FileStore) Increment(ctx context.Context, version string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counts, err := s.load()
+	if err != nil {
+		return 0, err
+	}
+
+	counts[version]++
+	return counts[version], s.save(counts)
+}
+
+// Reset implements FailureStore.
+func (s *FileStore) Reset(ctx context.Context, version string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counts, err := s.load()
+	if err != nil {
+		return err
+	}
+
+	if _, ok := counts[version]; !ok {
+		return nil
+	}
+
+	delete(counts, version)
+	return s.save(counts)
+}
+
+// load reads the counts file, returning an empty map if it doesn't exist.
+func (s *FileStore) load() (map[string]int, error) {
+	counts := make(map[string]int)
+
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return counts, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, &counts); err != nil {
+		return nil, err
+	}
+
+	return counts, nil
+}
+
+// save writes the counts file atomically.
+func (s *FileStore) save(counts map[string]int) error {
+	data, err := json.Marshal(counts)
+	if err != nil {
+		return err
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, s.path)
+}
@@ -0,0 +1,35 @@
+package queen
+
+import (
+	"context"
+	"fmt"
+)
+
+// CheckPermissions verifies that the database role can create, alter and
+// drop tables in the target schema and write the tracking table, without
+// applying any migration. A missing privilege is reported as a
+// *PermissionError naming the privilege and object, e.g.
+// "missing privilege CREATE on schema public".
+//
+// Returns ErrUnsupported if the driver doesn't implement PermissionChecker.
+func (q *Queen) CheckPermissions(ctx context.Context) error {
+	if q.driver == nil {
+		return ErrNoDriver
+	}
+
+	if err := q.init(ctx); err != nil {
+		return err
+	}
+
+	return q.checkPermissions(ctx)
+}
+
+// checkPermissions runs the driver's permission check.
+func (q *Queen) checkPermissions(ctx context.Context) error {
+	checker, ok := optional[PermissionChecker](q.driver, FeaturePermissionCheck)
+	if !ok {
+		return fmt.Errorf("%w: permission checks", ErrUnsupported)
+	}
+
+	return checker.CheckPermissions(ctx)
+}
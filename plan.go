@@ -0,0 +1,198 @@
+package queen
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Plan is the ordered list of migrations an operation would execute, as
+// returned by Queen.Plan and Queen.PlanDown, for reviewers to see what a
+// deploy will do before it runs.
+type Plan struct {
+	// Operation is OperationUp or OperationDown.
+	Operation Operation `json:"operation"`
+
+	// Steps are the migrations in execution order.
+	Steps []PlanStep `json:"steps"`
+
+	// Signatures are the approvals of the plan, see Plan.Sign.
+	Signatures []PlanSignature `json:"signatures,omitempty"`
+}
+
+// PlanStep is a migration of a Plan.
+type PlanStep struct {
+	// Version and Name identify the migration.
+	Version string `json:"version"`
+	Name    string `json:"name"`
+
+	// Down is true for rollbacks.
+	Down bool `json:"down"`
+
+	// SQL is the SQL that would run, with templates expanded, or "" if
+	// GoFunc is set.
+	SQL string `json:"sql,omitempty"`
+
+	// Args are the values bound to the placeholders of SQL, as a JSON
+	// array, see Migration.UpArgs.
+	Args json.RawMessage `json:"args,omitempty"`
+
+	// GoFunc is true if the step runs a Go function rather than SQL.
+	GoFunc bool `json:"go_func"`
+
+	// NoTransaction is true if the step runs outside a transaction.
+	NoTransaction bool `json:"no_transaction"`
+
+	// Destructive is true if SQL destroys data, see
+	// Migration.IsDestructive.
+	Destructive bool `json:"destructive"`
+
+	// EstimatedDuration is how long the step is expected to take, from
+	// the durations of earlier runs (see Config.EstimateFrom), or 0 if
+	// there are none to estimate from.
+	EstimatedDuration time.Duration `json:"estimated_duration_ns"`
+}
+
+// EstimatedDuration returns the sum of the estimated durations of the
+// steps. Steps without an estimate count as 0.
+func (p *Plan) EstimatedDuration() time.Duration {
+	var total time.Duration
+	for _, s := range p.Steps {
+		total += s.EstimatedDuration
+	}
+	return total
+}
+
+// String returns the plan as text: a summary line, then each step with
+// its SQL indented, e.g.
+//
+//	up: 1 migration (estimated 120ms)
+//
+//	001 create_users (estimated 120ms)
+//	    CREATE TABLE users (id INT)
+func (p *Plan) String() string {
+	var b strings.Builder
+
+	noun := "migrations"
+	if len(p.Steps) == 1 {
+		noun = "migration"
+	}
+	fmt.Fprintf(&b, "%s: %d %s", p.Operation, len(p.Steps), noun)
+	if d := p.EstimatedDuration(); d > 0 {
+		fmt.Fprintf(&b, " (estimated %s)", d.Round(time.Millisecond))
+	}
+	b.WriteString("\n")
+
+	for _, s := range p.Steps {
+		fmt.Fprintf(&b, "\n%s %s", s.Version, s.Name)
+		var notes []string
+		if s.Destructive {
+			notes = append(notes, "destructive")
+		}
+		if s.NoTransaction {
+			notes = append(notes, "no transaction")
+		}
+		if s.EstimatedDuration > 0 {
+			notes = append(notes, "estimated "+s.EstimatedDuration.Round(time.Millisecond).String())
+		}
+		if len(notes) > 0 {
+			fmt.Fprintf(&b, " (%s)", strings.Join(notes, ", "))
+		}
+		b.WriteString("\n")
+
+		if s.GoFunc {
+			b.WriteString("    go func\n")
+			continue
+		}
+		for line := range strings.Lines(strings.TrimSpace(s.SQL)) {
+			fmt.Fprintf(&b, "    %s", line)
+		}
+		b.WriteString("\n")
+	}
+
+	return b.String()
+}
+
+// Plan returns the migrations Up would apply, in order. Like Pending it
+// only reads: the checks Up runs before applying anything, such as
+// Validate's, aren't run.
+func (q *Queen) Plan(ctx context.Context) (*Plan, error) {
+	pending, err := q.Pending(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return q.plan(ctx, OperationUp, pending)
+}
+
+// PlanDown returns the migrations Down(ctx, n) would roll back, in order.
+func (q *Queen) PlanDown(ctx context.Context, n int) (*Plan, error) {
+	if n <= 0 {
+		n = 1
+	}
+
+	if q.driver == nil {
+		return nil, ErrNoDriver
+	}
+
+	if err := q.init(ctx); err != nil {
+		return nil, err
+	}
+	if err := q.loadApplied(ctx); err != nil {
+		return nil, err
+	}
+
+	applied := q.getAppliedMigrations()
+	if n > len(applied) {
+		n = len(applied)
+	}
+
+	return q.plan(ctx, OperationDown, applied[:n])
+}
+
+// plan builds the plan of op over migrations, with their estimates.
+func (q *Queen) plan(ctx context.Context, op Operation, migrations []*Migration) (*Plan, error) {
+	down := op == OperationDown
+	estimates, err := q.estimates(ctx, migrations, down)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Plan{Operation: op, Steps: make([]PlanStep, 0, len(migrations))}
+	for _, m := range migrations {
+		rendered, err := q.render(m)
+		if err != nil {
+			return nil, newMigrationError(m.Version, m.Name, err)
+		}
+
+		step := PlanStep{
+			Version:           m.Version,
+			Name:              m.Name,
+			Down:              down,
+			NoTransaction:     m.NoTransaction,
+			Destructive:       m.destructive(down),
+			EstimatedDuration: estimates[m.Version],
+		}
+		args := m.UpArgs
+		if down {
+			step.SQL, step.GoFunc = rendered.DownSQL, m.DownFunc != nil
+			args = m.DownArgs
+		} else {
+			step.SQL, step.GoFunc = rendered.UpSQL, m.UpFunc != nil
+		}
+		if step.GoFunc {
+			step.SQL = ""
+		}
+		if len(args) > 0 {
+			if step.Args, err = json.Marshal(args); err != nil {
+				return nil, newMigrationError(m.Version, m.Name, fmt.Errorf("%w: args: %w", ErrInvalidMigration, err))
+			}
+		}
+
+		p.Steps = append(p.Steps, step)
+	}
+
+	return p, nil
+}
@@ -0,0 +1,61 @@
+package queen_test
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/honeynil/queen"
+	"github.com/honeynil/queen/drivers/mock"
+)
+
+func TestPlan(t *testing.T) {
+	ctx := context.Background()
+	q := queen.NewWithConfig(mock.New(), &queen.Config{TemplateVars: map[string]any{"Schema": "app"}})
+	q.MustAdd(queen.M{Version: "001", Name: "backfill", DownFunc: noop,
+		UpFunc: func(ctx context.Context, tx *sql.Tx) error {
+			time.Sleep(2 * time.Millisecond)
+			return nil
+		},
+	})
+	q.MustAdd(queen.M{Version: "002", Name: "drop_legacy", UpSQL: "DROP TABLE {{.Schema}}.legacy"})
+
+	if err := q.UpSteps(ctx, 1); err != nil {
+		t.Fatalf("UpSteps failed: %v", err)
+	}
+
+	p, err := q.PlanDown(ctx, 5)
+	if err != nil {
+		t.Fatalf("PlanDown failed: %v", err)
+	}
+	if p.Operation != queen.OperationDown || len(p.Steps) != 1 || !p.Steps[0].Down || !p.Steps[0].GoFunc {
+		t.Fatalf("PlanDown = %+v; want the rollback of 001", p)
+	}
+
+	if err := q.Down(ctx, 1); err != nil {
+		t.Fatalf("Down failed: %v", err)
+	}
+
+	p, err = q.Plan(ctx)
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	if p.Operation != queen.OperationUp || len(p.Steps) != 2 {
+		t.Fatalf("Plan = %+v; want both migrations", p)
+	}
+	if s := p.Steps[0]; s.Version != "001" || !s.GoFunc || s.SQL != "" || s.EstimatedDuration < 2*time.Millisecond {
+		t.Errorf("step 001 = %+v; want a Go function estimated from history", s)
+	}
+	if s := p.Steps[1]; s.SQL != "DROP TABLE app.legacy" || !s.Destructive || s.EstimatedDuration != 0 {
+		t.Errorf("step 002 = %+v; want rendered, destructive SQL without an estimate", s)
+	}
+
+	out := p.String()
+	for _, want := range []string{"up: 2 migrations (estimated", "001 backfill (estimated", "    go func", "002 drop_legacy (destructive)", "    DROP TABLE app.legacy"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("String() =\n%s\nwant %q", out, want)
+		}
+	}
+}
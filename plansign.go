@@ -0,0 +1,222 @@
+package queen
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// PlanSignature is an approver's signature of a plan's hash.
+type PlanSignature struct {
+	// Signer identifies the approver, or the key that signed.
+	Signer string `json:"signer"`
+
+	// Signature is the signature of the hash, in a format known to the
+	// PlanVerifier.
+	Signature []byte `json:"signature"`
+
+	// SignedAt is when the plan was signed.
+	SignedAt time.Time `json:"signed_at"`
+}
+
+// PlanSigner signs plan hashes on behalf of an approver, e.g. with a key
+// held in a KMS.
+type PlanSigner interface {
+	SignPlan(ctx context.Context, hash []byte) (PlanSignature, error)
+}
+
+// PlanVerifier checks signatures made by a PlanSigner.
+type PlanVerifier interface {
+	// VerifyPlan returns an error unless sig is a valid signature of hash
+	// by a trusted approver.
+	VerifyPlan(ctx context.Context, hash []byte, sig PlanSignature) error
+}
+
+// Ed25519Signer signs plans with an Ed25519 private key, as Name.
+type Ed25519Signer struct {
+	Name string
+	Key  ed25519.PrivateKey
+}
+
+// SignPlan signs hash.
+func (s Ed25519Signer) SignPlan(ctx context.Context, hash []byte) (PlanSignature, error) {
+	return PlanSignature{Signer: s.Name, Signature: ed25519.Sign(s.Key, hash), SignedAt: time.Now().UTC()}, nil
+}
+
+// Ed25519Verifier verifies plan signatures with the Ed25519 public keys of
+// trusted approvers, by signer name.
+type Ed25519Verifier map[string]ed25519.PublicKey
+
+// VerifyPlan verifies sig with the key of sig.Signer.
+func (v Ed25519Verifier) VerifyPlan(ctx context.Context, hash []byte, sig PlanSignature) error {
+	key, ok := v[sig.Signer]
+	if !ok {
+		return fmt.Errorf("unknown signer %q", sig.Signer)
+	}
+	if !ed25519.Verify(key, hash, sig.Signature) {
+		return fmt.Errorf("invalid signature by %q", sig.Signer)
+	}
+	return nil
+}
+
+// Hash returns the hex SHA-256 of what the plan executes: its operation,
+// and the version, name, SQL and execution mode of each step. Estimates
+// and signatures are left out, so a plan keeps its hash as history grows.
+// The code of Go function migrations can't be hashed; their steps only
+// count by version and name.
+func (p *Plan) Hash() string {
+	sum := p.sum()
+	return hex.EncodeToString(sum[:])
+}
+
+func (p *Plan) sum() [sha256.Size]byte {
+	type step struct {
+		Version       string `json:"version"`
+		Name          string `json:"name"`
+		Down          bool   `json:"down"`
+		SQL           string `json:"sql"`
+		Args          string `json:"args,omitempty"`
+		GoFunc        bool   `json:"go_func"`
+		NoTransaction bool   `json:"no_transaction"`
+	}
+	content := struct {
+		Operation Operation `json:"operation"`
+		Steps     []step    `json:"steps"`
+	}{Operation: p.Operation, Steps: make([]step, len(p.Steps))}
+	for i, s := range p.Steps {
+		// Args are hashed compacted, so reformatting the plan file keeps
+		// the hash
+		var args bytes.Buffer
+		if json.Compact(&args, s.Args) != nil {
+			args.Write(s.Args)
+		}
+		content.Steps[i] = step{s.Version, s.Name, s.Down, s.SQL, args.String(), s.GoFunc, s.NoTransaction}
+	}
+
+	// Marshaling plain strings and bools can't fail
+	data, _ := json.Marshal(content)
+	return sha256.Sum256(data)
+}
+
+// planJSON is the JSON layout of a Plan.
+type planJSON struct {
+	planFields
+	Hash string `json:"hash"`
+}
+
+// planFields has the fields of Plan without its methods.
+type planFields Plan
+
+// MarshalJSON encodes the plan with its Hash, so that edits to a plan
+// file are caught when it is read back.
+func (p *Plan) MarshalJSON() ([]byte, error) {
+	return json.Marshal(planJSON{planFields: planFields(*p), Hash: p.Hash()})
+}
+
+// UnmarshalJSON decodes a plan encoded by MarshalJSON. It returns
+// ErrPlanMismatch if the steps don't match the encoded hash.
+func (p *Plan) UnmarshalJSON(data []byte) error {
+	var decoded planJSON
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+
+	*p = Plan(decoded.planFields)
+	if decoded.Hash != "" && decoded.Hash != p.Hash() {
+		return fmt.Errorf("%w: steps were edited, hash %s is now %s", ErrPlanMismatch, decoded.Hash, p.Hash())
+	}
+	return nil
+}
+
+// Sign adds the signature of the plan's hash by signer to Signatures.
+func (p *Plan) Sign(ctx context.Context, signer PlanSigner) error {
+	sum := p.sum()
+	sig, err := signer.SignPlan(ctx, sum[:])
+	if err != nil {
+		return fmt.Errorf("sign plan: %w", err)
+	}
+
+	p.Signatures = append(p.Signatures, sig)
+	return nil
+}
+
+// Verify checks that at least one of Signatures is valid according to
+// verifier, returning ErrPlanNotSigned otherwise.
+func (p *Plan) Verify(ctx context.Context, verifier PlanVerifier) error {
+	sum := p.sum()
+
+	var errs []error
+	for _, sig := range p.Signatures {
+		err := verifier.VerifyPlan(ctx, sum[:], sig)
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, err)
+	}
+
+	if len(errs) == 0 {
+		return ErrPlanNotSigned
+	}
+	return fmt.Errorf("%w: %w", ErrPlanNotSigned, errors.Join(errs...))
+}
+
+// ApplyPlan executes a plan generated by Plan or PlanDown, typically in
+// CI, once approved: it must carry a signature verifier accepts, and the
+// plan of the database when the lock is taken must have the same Hash,
+// else nothing runs and ErrPlanNotSigned or ErrPlanMismatch is returned.
+func (q *Queen) ApplyPlan(ctx context.Context, p *Plan, verifier PlanVerifier) error {
+	if err := p.Verify(ctx, verifier); err != nil {
+		return err
+	}
+
+	if p.Operation != OperationUp && p.Operation != OperationDown {
+		return fmt.Errorf("%w: can't apply a %s plan", ErrPlanMismatch, p.Operation)
+	}
+	if p.Operation == OperationDown && len(p.Steps) == 0 {
+		return nil
+	}
+
+	if q.driver == nil {
+		return ErrNoDriver
+	}
+
+	if err := q.init(ctx); err != nil {
+		return err
+	}
+
+	unlock, err := q.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	// The live plan, read under the lock so it can't change before it runs
+	var live *Plan
+	if p.Operation == OperationUp {
+		live, err = q.Plan(ctx)
+	} else {
+		live, err = q.PlanDown(ctx, len(p.Steps))
+	}
+	if err != nil {
+		return err
+	}
+	if live.Hash() != p.Hash() {
+		return fmt.Errorf("%w: the database's %s plan has hash %s, the approved one %s",
+			ErrPlanMismatch, p.Operation, live.Hash(), p.Hash())
+	}
+
+	if len(p.Steps) == 0 {
+		return nil
+	}
+
+	if p.Operation == OperationUp {
+		return q.upLocked(ctx, len(p.Steps), nil)
+	}
+	return q.downLocked(ctx, len(p.Steps), nil)
+}
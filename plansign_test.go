@@ -0,0 +1,145 @@
+package queen_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/honeynil/queen"
+	"github.com/honeynil/queen/drivers/mock"
+)
+
+func TestPlanJSON(t *testing.T) {
+	q := queen.New(mock.New())
+	ctx := context.Background()
+	q.MustAdd(queen.M{Version: "001", Name: "create_users", UpFunc: noop})
+
+	p, err := q.Plan(ctx)
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+
+	data, err := json.Marshal(p)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if !strings.Contains(string(data), `"hash":"`+p.Hash()+`"`) {
+		t.Errorf("Marshal = %s; want hash %s", data, p.Hash())
+	}
+
+	var decoded queen.Plan
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if decoded.Hash() != p.Hash() || len(decoded.Steps) != 1 {
+		t.Errorf("Unmarshal = %+v; want the plan back", decoded)
+	}
+
+	// Editing a step breaks the hash
+	edited := strings.Replace(string(data), "create_users", "drop_users", 1)
+	if err := json.Unmarshal([]byte(edited), &decoded); !errors.Is(err, queen.ErrPlanMismatch) {
+		t.Errorf("Unmarshal of an edited plan = %v; want ErrPlanMismatch", err)
+	}
+}
+
+func TestPlanJSON_Args(t *testing.T) {
+	q := queen.New(mock.New())
+	ctx := context.Background()
+	q.MustAdd(queen.M{Version: "001", Name: "seed_users", UpSQL: "INSERT INTO users (name) VALUES (?)",
+		UpArgs: []any{"alice"}})
+
+	p, err := q.Plan(ctx)
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	if string(p.Steps[0].Args) != `["alice"]` {
+		t.Errorf("Args = %s; want [\"alice\"]", p.Steps[0].Args)
+	}
+
+	// Reformatting the plan file keeps the hash, editing the values breaks it
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		t.Fatalf("MarshalIndent failed: %v", err)
+	}
+	var decoded queen.Plan
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Errorf("Unmarshal of an indented plan = %v", err)
+	}
+	edited := strings.Replace(string(data), "alice", "mallory", 1)
+	if err := json.Unmarshal([]byte(edited), &decoded); !errors.Is(err, queen.ErrPlanMismatch) {
+		t.Errorf("Unmarshal of edited args = %v; want ErrPlanMismatch", err)
+	}
+}
+
+func TestApplyPlan(t *testing.T) {
+	driver := mock.New()
+	q := queen.New(driver)
+	ctx := context.Background()
+	q.MustAdd(queen.M{Version: "001", Name: "create_users", UpFunc: noop, DownFunc: noop})
+
+	public, private, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	approver := queen.Ed25519Signer{Name: "alice", Key: private}
+	trusted := queen.Ed25519Verifier{"alice": public}
+
+	p, err := q.Plan(ctx)
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+
+	if err := q.ApplyPlan(ctx, p, trusted); !errors.Is(err, queen.ErrPlanNotSigned) {
+		t.Errorf("ApplyPlan unsigned = %v; want ErrPlanNotSigned", err)
+	}
+
+	// A signature by an untrusted key doesn't count
+	_, other, _ := ed25519.GenerateKey(nil)
+	if err := p.Sign(ctx, queen.Ed25519Signer{Name: "mallory", Key: other}); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.ApplyPlan(ctx, p, trusted); !errors.Is(err, queen.ErrPlanNotSigned) {
+		t.Errorf("ApplyPlan signed by mallory = %v; want ErrPlanNotSigned", err)
+	}
+
+	if err := p.Sign(ctx, approver); err != nil {
+		t.Fatal(err)
+	}
+
+	// A migration registered after approval changes the live plan
+	q.MustAdd(queen.M{Version: "002", Name: "create_posts", UpFunc: noop, DownFunc: noop})
+	if err := q.ApplyPlan(ctx, p, trusted); !errors.Is(err, queen.ErrPlanMismatch) {
+		t.Errorf("ApplyPlan of a stale plan = %v; want ErrPlanMismatch", err)
+	}
+	if driver.AppliedCount() != 0 {
+		t.Fatal("Expected nothing applied from a stale plan")
+	}
+
+	p, _ = q.Plan(ctx)
+	if err := p.Sign(ctx, approver); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.ApplyPlan(ctx, p, trusted); err != nil {
+		t.Fatalf("ApplyPlan failed: %v", err)
+	}
+	if driver.AppliedCount() != 2 {
+		t.Errorf("Applied %d migrations; want 2", driver.AppliedCount())
+	}
+
+	down, err := q.PlanDown(ctx, 1)
+	if err != nil {
+		t.Fatalf("PlanDown failed: %v", err)
+	}
+	if err := down.Sign(ctx, approver); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.ApplyPlan(ctx, down, trusted); err != nil {
+		t.Fatalf("ApplyPlan down failed: %v", err)
+	}
+	if driver.HasVersion("002") || !driver.HasVersion("001") {
+		t.Error("Expected only 002 rolled back")
+	}
+}
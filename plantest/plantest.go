@@ -0,0 +1,163 @@
+// Package plantest checks the properties Queen relies on when ordering
+// migration versions, so version comparators and version schemes can be
+// verified in tests and fuzz tests.
+//
+// A comparator must be a total order: Queen sorts pending migrations with
+// it and compares them against the latest applied version, so a comparator
+// that reports two different versions as equal, or that isn't transitive,
+// can make the plan depend on registration order.
+//
+// Check a comparator with fuzzing:
+//
+//	func FuzzCompare(f *testing.F) {
+//	    plantest.FuzzCompare(f, myCompare)
+//	}
+//
+//	func FuzzPlan(f *testing.F) {
+//	    plantest.FuzzPlan(f, myCompare)
+//	}
+//
+// and run it with go test -fuzz=FuzzCompare. Without -fuzz, only the seed
+// corpus runs, like a regular test.
+package plantest
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"testing"
+
+	naturalsort "github.com/honeynil/queen/internal/sort"
+)
+
+// Compare compares two versions, returning a negative number if a sorts
+// before b, a positive number if after, and 0 if they are equal.
+type Compare func(a, b string) int
+
+// Natural is the natural sort order Queen uses for versions, where numeric
+// parts compare as numbers: "1" < "2" < "10", "user_2" < "user_10".
+func Natural(a, b string) int {
+	return naturalsort.Compare(a, b)
+}
+
+// Seeds are versions in common naming schemes, used as seed corpus by
+// FuzzCompare and FuzzPlan.
+var Seeds = []string{
+	"", "0", "1", "01", "001", "2", "10", "100", "9999999999999999999999",
+	"v1", "v2", "v10", "v1.2.3", "v1.10.0",
+	"001_create_users", "002_add_email", "001a", "001b",
+	"users_001", "users_010", "posts_001",
+	"20240101120000", "20240101120000_init",
+	"1a", "a1", "a01", "a", "A",
+}
+
+// CheckCompare verifies that cmp orders a, b and c consistently: a version
+// equals only itself, swapping arguments flips the result, and the order
+// is transitive. It returns an error describing the first violation.
+func CheckCompare(cmp Compare, a, b, c string) error {
+	for _, v := range []string{a, b, c} {
+		if got := sign(cmp(v, v)); got != 0 {
+			return fmt.Errorf("compare(%q, %q) = %d, want 0", v, v, got)
+		}
+	}
+
+	pairs := [][2]string{{a, b}, {b, c}, {a, c}}
+	for _, p := range pairs {
+		x, y := p[0], p[1]
+		xy, yx := sign(cmp(x, y)), sign(cmp(y, x))
+
+		if xy != -yx {
+			return fmt.Errorf("compare(%q, %q) = %d but compare(%q, %q) = %d", x, y, xy, y, x, yx)
+		}
+		if xy == 0 && x != y {
+			return fmt.Errorf("compare(%q, %q) = 0 for different versions", x, y)
+		}
+	}
+
+	// Check transitivity for every ordering of the three versions
+	vs := []string{a, b, c}
+	for _, perm := range [][3]int{{0, 1, 2}, {0, 2, 1}, {1, 0, 2}, {1, 2, 0}, {2, 0, 1}, {2, 1, 0}} {
+		x, y, z := vs[perm[0]], vs[perm[1]], vs[perm[2]]
+		if cmp(x, y) < 0 && cmp(y, z) < 0 && cmp(x, z) >= 0 {
+			return fmt.Errorf("compare is not transitive: %q < %q < %q but not %q < %q", x, y, z, x, z)
+		}
+	}
+
+	return nil
+}
+
+// CheckPlan verifies that sorting versions with cmp yields one plan no
+// matter the order they are registered in, and that the plan is strictly
+// increasing. Duplicate versions are ignored, as Queen rejects them.
+func CheckPlan(cmp Compare, versions []string) error {
+	versions = slices.Clone(versions)
+	slices.Sort(versions)
+	versions = slices.Compact(versions)
+
+	plan := slices.Clone(versions)
+	slices.SortStableFunc(plan, cmp)
+
+	for i := 1; i < len(plan); i++ {
+		if cmp(plan[i-1], plan[i]) >= 0 {
+			return fmt.Errorf("plan is not strictly increasing at %q, %q", plan[i-1], plan[i])
+		}
+	}
+
+	// A deterministic shuffle keeps failures reproducible
+	rng := rand.New(rand.NewPCG(uint64(len(versions)), 0))
+	for range 4 {
+		shuffled := slices.Clone(versions)
+		rng.Shuffle(len(shuffled), func(i, j int) { shuffled[i], shuffled[j] = shuffled[j], shuffled[i] })
+		slices.SortStableFunc(shuffled, cmp)
+
+		if !slices.Equal(shuffled, plan) {
+			return fmt.Errorf("plan depends on registration order: %q vs %q", plan, shuffled)
+		}
+	}
+
+	return nil
+}
+
+// FuzzCompare fuzzes cmp with CheckCompare, seeded with Seeds.
+func FuzzCompare(f *testing.F, cmp Compare) {
+	f.Helper()
+
+	for i, a := range Seeds {
+		b := Seeds[(i+1)%len(Seeds)]
+		c := Seeds[(i+7)%len(Seeds)]
+		f.Add(a, b, c)
+	}
+
+	f.Fuzz(func(t *testing.T, a, b, c string) {
+		if err := CheckCompare(cmp, a, b, c); err != nil {
+			t.Error(err)
+		}
+	})
+}
+
+// FuzzPlan fuzzes cmp with CheckPlan. The fuzzed input is a comma-separated
+// list of versions, seeded with Seeds.
+func FuzzPlan(f *testing.F, cmp Compare) {
+	f.Helper()
+
+	f.Add(strings.Join(Seeds, ","))
+	f.Add("3,1,2,10")
+
+	f.Fuzz(func(t *testing.T, list string) {
+		if err := CheckPlan(cmp, strings.Split(list, ",")); err != nil {
+			t.Error(err)
+		}
+	})
+}
+
+// sign normalizes a comparison result to -1, 0 or 1.
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,55 @@
+package plantest_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/honeynil/queen/plantest"
+)
+
+func FuzzCompare(f *testing.F) {
+	plantest.FuzzCompare(f, plantest.Natural)
+}
+
+func FuzzPlan(f *testing.F) {
+	plantest.FuzzPlan(f, plantest.Natural)
+}
+
+func TestCheckCompare(t *testing.T) {
+	byLength := func(a, b string) int { return len(a) - len(b) }
+
+	if err := plantest.CheckCompare(byLength, "ab", "cd", "e"); err == nil {
+		t.Error("expected error for comparator treating different versions as equal")
+	}
+
+	// Rock, paper, scissors
+	cyclic := func(a, b string) int {
+		beats := map[string]string{"rock": "scissors", "paper": "rock", "scissors": "paper"}
+		switch {
+		case a == b:
+			return 0
+		case beats[a] == b:
+			return 1
+		}
+		return -1
+	}
+	err := plantest.CheckCompare(cyclic, "rock", "paper", "scissors")
+	if err == nil || !strings.Contains(err.Error(), "transitive") {
+		t.Errorf("expected transitivity error, got %v", err)
+	}
+
+	if err := plantest.CheckCompare(plantest.Natural, "1", "01", "1a"); err != nil {
+		t.Errorf("Natural: %v", err)
+	}
+}
+
+func TestCheckPlan(t *testing.T) {
+	if err := plantest.CheckPlan(plantest.Natural, plantest.Seeds); err != nil {
+		t.Errorf("Natural: %v", err)
+	}
+
+	byLength := func(a, b string) int { return len(a) - len(b) }
+	if err := plantest.CheckPlan(byLength, []string{"ab", "cd"}); err == nil {
+		t.Error("expected error for plan with ambiguous order")
+	}
+}
@@ -0,0 +1,354 @@
+package queen
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	naturalsort "github.com/honeynil/queen/internal/sort"
+	"github.com/honeynil/queen/queenlint"
+)
+
+// OutOfOrderPolicy controls how Up treats pending migrations whose version
+// is lower than the highest applied version, e.g. migrations merged late
+// from another branch.
+type OutOfOrderPolicy int
+
+const (
+	// OutOfOrderAllow applies out-of-order migrations silently.
+	OutOfOrderAllow OutOfOrderPolicy = iota
+
+	// OutOfOrderWarn applies out-of-order migrations and emits an
+	// EventWarning hook event for each of them.
+	OutOfOrderWarn
+
+	// OutOfOrderError refuses to run and returns an *OrderError.
+	OutOfOrderError
+)
+
+// String returns a human-readable representation of the policy.
+func (p OutOfOrderPolicy) String() string {
+	switch p {
+	case OutOfOrderAllow:
+		return "allow"
+	case OutOfOrderWarn:
+		return "warn"
+	case OutOfOrderError:
+		return "error"
+	default:
+		return "unknown"
+	}
+}
+
+// OrderError reports a pending migration that sorts before an applied one.
+// It matches ErrOutOfOrder with errors.Is.
+type OrderError struct {
+	// Version is the pending migration's version.
+	Version string
+
+	// LatestApplied is the highest applied version.
+	LatestApplied string
+}
+
+func (e *OrderError) Error() string {
+	return fmt.Sprintf("%v: %s is lower than applied version %s", ErrOutOfOrder, e.Version, e.LatestApplied)
+}
+
+// Is reports whether target is ErrOutOfOrder.
+func (e *OrderError) Is(target error) bool {
+	return target == ErrOutOfOrder
+}
+
+// checkOutOfOrder applies the configured OutOfOrder policy to migrations
+// about to be applied.
+func (q *Queen) checkOutOfOrder(ctx context.Context, pending []*Migration) error {
+	if q.config.OutOfOrder == OutOfOrderAllow {
+		return nil
+	}
+
+	for _, m := range pending {
+		err := q.orderError(m)
+		if err == nil {
+			continue
+		}
+
+		if q.config.OutOfOrder == OutOfOrderError {
+			return err
+		}
+
+		_ = q.emit(ctx, Event{Kind: EventWarning, Migration: m, Err: err})
+	}
+
+	return nil
+}
+
+// orderError returns an *OrderError if m sorts before the highest applied
+// version of its module, nil otherwise.
+func (q *Queen) orderError(m *Migration) *OrderError {
+	module := q.moduleOf(m.Version)
+	latest := ""
+	for version := range q.applied {
+		if q.moduleOf(version) != module {
+			continue
+		}
+		if latest == "" || naturalsort.Compare(version, latest) > 0 {
+			latest = version
+		}
+	}
+
+	if latest == "" || naturalsort.Compare(m.Version, latest) >= 0 {
+		return nil
+	}
+
+	return &OrderError{Version: m.Version, LatestApplied: latest}
+}
+
+// ChecksumPolicy controls how Up and Validate treat applied migrations
+// whose checksum no longer matches the registered migration.
+type ChecksumPolicy int
+
+const (
+	// ChecksumMismatchError refuses to run and returns ErrChecksumMismatch.
+	ChecksumMismatchError ChecksumPolicy = iota
+
+	// ChecksumMismatchWarn continues and emits an EventWarning hook event
+	// for each modified migration.
+	ChecksumMismatchWarn
+
+	// ChecksumMismatchIgnore continues silently.
+	ChecksumMismatchIgnore
+)
+
+// String returns a human-readable representation of the policy.
+func (p ChecksumPolicy) String() string {
+	switch p {
+	case ChecksumMismatchError:
+		return "error"
+	case ChecksumMismatchWarn:
+		return "warn"
+	case ChecksumMismatchIgnore:
+		return "ignore"
+	default:
+		return "unknown"
+	}
+}
+
+type checksumPolicyKey struct{}
+
+// WithChecksumPolicy returns a context that overrides Config.ChecksumPolicy
+// for the Up or Validate call it is passed to, e.g. to accept a deliberate
+// edit of an applied migration once without relaxing the policy for every
+// run:
+//
+//	err := q.Up(queen.WithChecksumPolicy(ctx, queen.ChecksumMismatchWarn))
+func WithChecksumPolicy(ctx context.Context, policy ChecksumPolicy) context.Context {
+	return context.WithValue(ctx, checksumPolicyKey{}, policy)
+}
+
+// checkChecksums applies the checksum policy to applied migrations that are
+// still registered.
+func (q *Queen) checkChecksums(ctx context.Context) error {
+	policy := q.config.ChecksumPolicy
+	if p, ok := ctx.Value(checksumPolicyKey{}).(ChecksumPolicy); ok {
+		policy = p
+	}
+	if policy == ChecksumMismatchIgnore {
+		return nil
+	}
+
+	for _, m := range q.migrations {
+		applied, ok := q.applied[m.Version]
+		if !ok || m.checksumMatches(applied.Checksum) {
+			continue
+		}
+
+		err := fmt.Errorf("%w: migration %s (expected %s, got %s)",
+			ErrChecksumMismatch, m.Version, applied.Checksum, m.Checksum())
+		if policy != ChecksumMismatchWarn {
+			return err
+		}
+
+		_ = q.emit(ctx, Event{Kind: EventWarning, Migration: m, Err: err})
+	}
+
+	return nil
+}
+
+// UnknownAppliedPolicy controls how Queen treats applied migrations that
+// are not registered, e.g. after deploying an older binary or deleting a
+// migration from code.
+type UnknownAppliedPolicy int
+
+const (
+	// UnknownAppliedIgnore ignores unregistered migrations.
+	UnknownAppliedIgnore UnknownAppliedPolicy = iota
+
+	// UnknownAppliedReport lists unregistered migrations in Status with
+	// StatusUnknown.
+	UnknownAppliedReport
+
+	// UnknownAppliedWarn reports unregistered migrations in Status, and
+	// makes Up and Validate emit an EventWarning hook event for them.
+	UnknownAppliedWarn
+
+	// UnknownAppliedError reports unregistered migrations in Status, and
+	// makes Up and Validate return an *UnregisteredError.
+	UnknownAppliedError
+)
+
+// String returns a human-readable representation of the policy.
+func (p UnknownAppliedPolicy) String() string {
+	switch p {
+	case UnknownAppliedIgnore:
+		return "ignore"
+	case UnknownAppliedReport:
+		return "report"
+	case UnknownAppliedWarn:
+		return "warn"
+	case UnknownAppliedError:
+		return "error"
+	default:
+		return "unknown"
+	}
+}
+
+// UnregisteredError reports applied migrations that are not registered.
+// It matches ErrUnknownApplied with errors.Is.
+type UnregisteredError struct {
+	// Versions are the unregistered versions, in natural sort order.
+	Versions []string
+}
+
+func (e *UnregisteredError) Error() string {
+	return fmt.Sprintf("%v: %s", ErrUnknownApplied, strings.Join(e.Versions, ", "))
+}
+
+// Is reports whether target is ErrUnknownApplied.
+func (e *UnregisteredError) Is(target error) bool {
+	return target == ErrUnknownApplied
+}
+
+// unknownApplied returns applied versions that are not registered, in
+// natural sort order.
+func (q *Queen) unknownApplied() []string {
+	var versions []string
+	for version := range q.applied {
+		if !q.hasVersion(version) {
+			versions = append(versions, version)
+		}
+	}
+
+	sort.Slice(versions, func(i, j int) bool {
+		return naturalsort.Compare(versions[i], versions[j]) < 0
+	})
+
+	return versions
+}
+
+// checkUnknownApplied applies the configured UnknownApplied policy.
+func (q *Queen) checkUnknownApplied(ctx context.Context) error {
+	if q.config.UnknownApplied < UnknownAppliedWarn {
+		return nil
+	}
+
+	versions := q.unknownApplied()
+	if len(versions) == 0 {
+		return nil
+	}
+
+	err := &UnregisteredError{Versions: versions}
+	if q.config.UnknownApplied == UnknownAppliedError {
+		return err
+	}
+
+	for _, version := range versions {
+		a := q.applied[version]
+		_ = q.emit(ctx, Event{Kind: EventWarning, Migration: storedMigration(a), Err: &UnregisteredError{Versions: []string{version}}})
+	}
+
+	return nil
+}
+
+// LockHazardPolicy controls how Up treats statements that hold long locks
+// on existing tables, found by queenlint.PostgresLockHazards.
+type LockHazardPolicy int
+
+const (
+	// LockHazardWarn emits an EventWarning hook event for each hazard and
+	// applies the migration.
+	LockHazardWarn LockHazardPolicy = iota
+
+	// LockHazardIgnore doesn't check for hazards.
+	LockHazardIgnore
+
+	// LockHazardError refuses to run a migration with a hazard, returning
+	// ErrLockHazard, unless it sets LockHazardOK.
+	LockHazardError
+)
+
+// String returns a human-readable representation of the policy.
+func (p LockHazardPolicy) String() string {
+	switch p {
+	case LockHazardWarn:
+		return "warn"
+	case LockHazardIgnore:
+		return "ignore"
+	case LockHazardError:
+		return "error"
+	default:
+		return "unknown"
+	}
+}
+
+// checkLockHazards applies the configured LockHazards policy to the UpSQL
+// of migrations about to be applied, if the database is PostgreSQL.
+func (q *Queen) checkLockHazards(ctx context.Context, pending []*Migration) error {
+	if q.config.LockHazards == LockHazardIgnore {
+		return nil
+	}
+
+	var checked []*Migration
+	for _, m := range pending {
+		if m.UpSQL != "" && !m.LockHazardOK {
+			checked = append(checked, m)
+		}
+	}
+	if len(checked) == 0 {
+		return nil
+	}
+
+	reporter, ok := optional[CapabilityReporter](q.driver, FeatureCapabilities)
+	if !ok {
+		return nil
+	}
+	caps, err := reporter.Capabilities(ctx)
+	if err != nil {
+		return err
+	}
+	if caps.Dialect != "postgres" {
+		return nil
+	}
+
+	major := 0
+	if parts := versionParts(caps.Version); len(parts) > 0 {
+		major = parts[0]
+	}
+
+	for _, m := range checked {
+		rendered, err := q.render(m)
+		if err != nil {
+			return newMigrationError(m.Version, m.Name, err)
+		}
+
+		for _, f := range queenlint.PostgresLockHazards(rendered.UpSQL, major, q.config.Lint) {
+			err := fmt.Errorf("%w: %s", ErrLockHazard, f.Message)
+			if q.config.LockHazards == LockHazardError {
+				return newMigrationError(m.Version, m.Name, err)
+			}
+			_ = q.emit(ctx, Event{Kind: EventWarning, Migration: m, Err: err})
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,343 @@
+package queen_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/honeynil/queen"
+	"github.com/honeynil/queen/drivers/mock"
+)
+
+func TestOutOfOrderPolicy(t *testing.T) {
+	tests := []struct {
+		policy      queen.OutOfOrderPolicy
+		wantErr     bool
+		wantWarning bool
+	}{
+		{policy: queen.OutOfOrderAllow},
+		{policy: queen.OutOfOrderWarn, wantWarning: true},
+		{policy: queen.OutOfOrderError, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy.String(), func(t *testing.T) {
+			driver := mock.New()
+			q := queen.NewWithConfig(driver, &queen.Config{OutOfOrder: tt.policy})
+			ctx := context.Background()
+
+			q.MustAdd(queen.M{Version: "001", Name: "first", UpFunc: noop})
+			q.MustAdd(queen.M{Version: "003", Name: "third", UpFunc: noop})
+			if err := q.Up(ctx); err != nil {
+				t.Fatalf("Up failed: %v", err)
+			}
+
+			warned := false
+			q.Hooks().MustRegister(queen.Hook{
+				Name: "warn",
+				Func: func(ctx context.Context, e queen.Event) error {
+					if e.Kind == queen.EventWarning && errors.Is(e.Err, queen.ErrOutOfOrder) {
+						warned = true
+					}
+					return nil
+				},
+			})
+
+			// Merged late from another branch
+			q.MustAdd(queen.M{Version: "002", Name: "second", UpFunc: noop})
+			err := q.Up(ctx)
+
+			if tt.wantErr {
+				var ooo *queen.OrderError
+				if !errors.As(err, &ooo) || !errors.Is(err, queen.ErrOutOfOrder) {
+					t.Fatalf("Expected OrderError, got %v", err)
+				}
+				if ooo.Version != "002" || ooo.LatestApplied != "003" {
+					t.Errorf("Unexpected error details: %+v", ooo)
+				}
+				if driver.HasVersion("002") {
+					t.Error("Out-of-order migration must not be applied")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Up failed: %v", err)
+			}
+			if !driver.HasVersion("002") {
+				t.Error("Expected out-of-order migration to be applied")
+			}
+			if warned != tt.wantWarning {
+				t.Errorf("warned = %v, want %v", warned, tt.wantWarning)
+			}
+		})
+	}
+}
+
+func TestChecksumPolicy(t *testing.T) {
+	tests := []struct {
+		policy      queen.ChecksumPolicy
+		wantErr     bool
+		wantWarning bool
+	}{
+		{policy: queen.ChecksumMismatchError, wantErr: true},
+		{policy: queen.ChecksumMismatchWarn, wantWarning: true},
+		{policy: queen.ChecksumMismatchIgnore},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy.String(), func(t *testing.T) {
+			driver := mock.New()
+			ctx := context.Background()
+
+			q := queen.New(driver)
+			q.MustAdd(queen.M{Version: "001", Name: "first", ManualChecksum: "v1", UpFunc: noop})
+			if err := q.Up(ctx); err != nil {
+				t.Fatalf("Up failed: %v", err)
+			}
+
+			// Edited after it was applied
+			q = queen.NewWithConfig(driver, &queen.Config{ChecksumPolicy: tt.policy})
+			q.MustAdd(queen.M{Version: "001", Name: "first", ManualChecksum: "v2", UpFunc: noop})
+			q.MustAdd(queen.M{Version: "002", Name: "second", UpFunc: noop})
+
+			warnings := 0
+			q.Hooks().MustRegister(queen.Hook{
+				Name: "warn",
+				Func: func(ctx context.Context, e queen.Event) error {
+					if e.Kind == queen.EventWarning && errors.Is(e.Err, queen.ErrChecksumMismatch) {
+						warnings++
+					}
+					return nil
+				},
+			})
+
+			for name, run := range map[string]func(context.Context) error{"Validate": q.Validate, "Up": q.Up} {
+				err := run(ctx)
+				if tt.wantErr != errors.Is(err, queen.ErrChecksumMismatch) {
+					t.Errorf("%s error = %v, want checksum mismatch: %v", name, err, tt.wantErr)
+				}
+			}
+
+			// One warning from each call
+			want := 0
+			if tt.wantWarning {
+				want = 2
+			}
+			if warnings != want {
+				t.Errorf("warnings = %d, want %d", warnings, want)
+			}
+			if driver.HasVersion("002") == tt.wantErr {
+				t.Errorf("002 applied = %v, want %v", driver.HasVersion("002"), !tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWithChecksumPolicy(t *testing.T) {
+	driver := mock.New()
+	ctx := context.Background()
+
+	q := queen.New(driver)
+	q.MustAdd(queen.M{Version: "001", Name: "first", ManualChecksum: "v1", UpFunc: noop})
+	if err := q.Up(ctx); err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+
+	q = queen.New(driver)
+	q.MustAdd(queen.M{Version: "001", Name: "first", ManualChecksum: "v2", UpFunc: noop})
+
+	if err := q.Validate(ctx); !errors.Is(err, queen.ErrChecksumMismatch) {
+		t.Fatalf("Validate error = %v, want ErrChecksumMismatch", err)
+	}
+	if err := q.Validate(queen.WithChecksumPolicy(ctx, queen.ChecksumMismatchIgnore)); err != nil {
+		t.Errorf("Validate with override failed: %v", err)
+	}
+}
+
+func TestUnknownAppliedPolicy(t *testing.T) {
+	tests := []struct {
+		policy      queen.UnknownAppliedPolicy
+		wantStatus  bool
+		wantWarning bool
+		wantErr     bool
+	}{
+		{policy: queen.UnknownAppliedIgnore},
+		{policy: queen.UnknownAppliedReport, wantStatus: true},
+		{policy: queen.UnknownAppliedWarn, wantStatus: true, wantWarning: true},
+		{policy: queen.UnknownAppliedError, wantStatus: true, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy.String(), func(t *testing.T) {
+			driver := mock.New()
+			ctx := context.Background()
+
+			// Applied by a newer binary than this one
+			for _, v := range []string{"001", "002"} {
+				m := &queen.M{Version: v, Name: "migration_" + v, UpFunc: noop}
+				if err := driver.RecordWithMeta(ctx, m, queen.RecordMeta{Batch: 1}); err != nil {
+					t.Fatalf("Record failed: %v", err)
+				}
+			}
+
+			q := queen.NewWithConfig(driver, &queen.Config{UnknownApplied: tt.policy})
+			q.MustAdd(queen.M{Version: "001", Name: "first", UpFunc: noop})
+			q.MustAdd(queen.M{Version: "003", Name: "third", UpFunc: noop})
+
+			warned := false
+			q.Hooks().MustRegister(queen.Hook{
+				Name: "warn",
+				Func: func(ctx context.Context, e queen.Event) error {
+					if e.Kind == queen.EventWarning && errors.Is(e.Err, queen.ErrUnknownApplied) {
+						warned = true
+					}
+					return nil
+				},
+			})
+
+			statuses, err := q.Status(ctx)
+			if err != nil {
+				t.Fatalf("Status failed: %v", err)
+			}
+			reported := len(statuses) == 3 && statuses[2].Version == "002" && statuses[2].Status == queen.StatusUnknown
+			if reported != tt.wantStatus {
+				t.Errorf("002 reported in Status = %v, want %v", reported, tt.wantStatus)
+			}
+
+			err = q.Up(ctx)
+			var unregistered *queen.UnregisteredError
+			if tt.wantErr != errors.As(err, &unregistered) {
+				t.Fatalf("Up error = %v, want UnregisteredError: %v", err, tt.wantErr)
+			}
+			if tt.wantErr && (len(unregistered.Versions) != 1 || unregistered.Versions[0] != "002") {
+				t.Errorf("Unexpected error details: %+v", unregistered)
+			}
+			if warned != tt.wantWarning {
+				t.Errorf("warned = %v, want %v", warned, tt.wantWarning)
+			}
+		})
+	}
+}
+
+func TestRequireRollback(t *testing.T) {
+	q := queen.NewWithConfig(mock.New(), &queen.Config{RequireRollback: true})
+
+	err := q.Add(queen.M{Version: "001", Name: "no_down", UpSQL: "CREATE TABLE t (id INT)"})
+	if !errors.Is(err, queen.ErrInvalidMigration) {
+		t.Errorf("Add without rollback error = %v, want ErrInvalidMigration", err)
+	}
+	if err := q.Submit(queen.M{Version: "001", Name: "no_down", UpFunc: noop}); !errors.Is(err, queen.ErrInvalidMigration) {
+		t.Errorf("Submit without rollback error = %v, want ErrInvalidMigration", err)
+	}
+
+	q.MustAdd(queen.M{Version: "001", Name: "reversible", UpSQL: "CREATE TABLE t (id INT)", DownSQL: "DROP TABLE t"})
+	q.MustAdd(queen.M{Version: "002", Name: "drop_column", UpSQL: "ALTER TABLE t DROP COLUMN id", IrreversibleOK: true})
+
+	if err := q.Validate(context.Background()); err != nil {
+		t.Errorf("Validate failed: %v", err)
+	}
+}
+
+func TestConfirmDestructive(t *testing.T) {
+	driver := mock.New()
+	ctx := context.Background()
+
+	var asked []string
+	config := queen.DefaultConfig()
+	config.ConfirmDestructive = func(ctx context.Context, m *queen.Migration) (bool, error) {
+		asked = append(asked, m.Version)
+		return false, nil
+	}
+
+	q := queen.NewWithConfig(driver, config)
+	q.MustAdd(queen.M{Version: "001", Name: "create", UpFunc: noop, DownSQL: "DROP TABLE users"})
+	q.MustAdd(queen.M{Version: "002", Name: "drop_legacy", UpSQL: "DROP TABLE legacy"})
+
+	if err := q.Up(ctx); !errors.Is(err, queen.ErrNotConfirmed) {
+		t.Fatalf("Up error = %v, want ErrNotConfirmed", err)
+	}
+	if driver.HasVersion("001") {
+		t.Error("Nothing may be applied before confirmation")
+	}
+	if len(asked) != 1 || asked[0] != "002" {
+		t.Errorf("asked about %v, want [002]", asked)
+	}
+
+	if err := q.UpSteps(ctx, 1); err != nil {
+		t.Fatalf("UpSteps failed: %v", err)
+	}
+
+	asked = nil
+	if err := q.Down(ctx, 1); !errors.Is(err, queen.ErrNotConfirmed) {
+		t.Fatalf("Down error = %v, want ErrNotConfirmed", err)
+	}
+	if !driver.HasVersion("001") || len(asked) != 1 {
+		t.Errorf("Down must ask once and roll back nothing, asked about %v", asked)
+	}
+}
+
+func TestLockHazardPolicy(t *testing.T) {
+	ctx := context.Background()
+	errStop := errors.New("stop before executing")
+
+	run := func(policy queen.LockHazardPolicy, caps queen.Capabilities, m queen.M) ([]string, error) {
+		config := queen.DefaultConfig()
+		config.LockHazards = policy
+
+		q := queen.NewWithConfig(&capsDriver{Driver: mock.New(), caps: caps}, config)
+		q.MustAdd(m)
+
+		var warnings []string
+		q.Hooks().MustRegister(queen.Hook{Name: "test", Func: func(ctx context.Context, e queen.Event) error {
+			switch e.Kind {
+			case queen.EventWarning:
+				warnings = append(warnings, e.Err.Error())
+			case queen.EventBeforeUp:
+				// The mock driver can't execute SQL
+				return errStop
+			}
+			return nil
+		}})
+
+		return warnings, q.Up(ctx)
+	}
+
+	postgres := queen.Capabilities{Dialect: "postgres", Version: "15.4"}
+	index := queen.M{Version: "001", Name: "index", UpSQL: "CREATE INDEX idx_users_email ON users (email)"}
+
+	warnings, err := run(queen.LockHazardWarn, postgres, index)
+	if !errors.Is(err, errStop) || len(warnings) != 1 || !strings.Contains(warnings[0], "CREATE INDEX CONCURRENTLY") {
+		t.Errorf("warn: got warnings %v, err %v", warnings, err)
+	}
+
+	if _, err := run(queen.LockHazardError, postgres, index); !errors.Is(err, queen.ErrLockHazard) {
+		t.Errorf("error: expected ErrLockHazard, got %v", err)
+	}
+
+	exempt := index
+	exempt.LockHazardOK = true
+	if _, err := run(queen.LockHazardError, postgres, exempt); !errors.Is(err, errStop) {
+		t.Errorf("LockHazardOK: expected no hazard, got %v", err)
+	}
+
+	if warnings, _ := run(queen.LockHazardIgnore, postgres, index); len(warnings) != 0 {
+		t.Errorf("ignore: got warnings %v", warnings)
+	}
+
+	mysql := queen.Capabilities{Dialect: "mysql", Version: "8.0.36"}
+	if _, err := run(queen.LockHazardError, mysql, index); !errors.Is(err, errStop) {
+		t.Errorf("mysql: expected no hazard, got %v", err)
+	}
+
+	// Any default rewrites the table before PostgreSQL 11
+	addColumn := queen.M{Version: "001", Name: "add_active", UpSQL: "ALTER TABLE users ADD COLUMN active BOOLEAN DEFAULT TRUE"}
+	if _, err := run(queen.LockHazardError, postgres, addColumn); !errors.Is(err, errStop) {
+		t.Errorf("postgres 15: expected no hazard, got %v", err)
+	}
+	old := queen.Capabilities{Dialect: "postgres", Version: "10.23"}
+	if _, err := run(queen.LockHazardError, old, addColumn); !errors.Is(err, queen.ErrLockHazard) {
+		t.Errorf("postgres 10: expected ErrLockHazard, got %v", err)
+	}
+}
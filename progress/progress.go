@@ -0,0 +1,122 @@
+// Package progress lets long-running Go migrations record how far they got,
+// so an interrupted migration resumes from the last completed chunk instead
+// of starting over.
+//
+// Queen scopes the store to the running migration: Get and Set take only a
+// key, and read and write in the migration's transaction, so saved progress
+// always matches the committed work. Combined with queen.ErrContinue, which
+// commits the current transaction and calls the UpFunc again, a backfill
+// commits chunk by chunk:
+//
+//	UpFunc: func(ctx context.Context, tx *sql.Tx) error {
+//	    last, _, err := progress.GetInt(ctx, "last_id")
+//	    if err != nil {
+//	        return err
+//	    }
+//
+//	    var maxID int64
+//	    if err := tx.QueryRowContext(ctx, "SELECT COALESCE(MAX(id), 0) FROM users").Scan(&maxID); err != nil {
+//	        return err
+//	    }
+//	    if last >= maxID {
+//	        return nil // done
+//	    }
+//
+//	    next := min(last+1000, maxID)
+//	    if _, err := tx.ExecContext(ctx,
+//	        "UPDATE users SET email = LOWER(email) WHERE id > $1 AND id <= $2", last, next); err != nil {
+//	        return err
+//	    }
+//	    if err := progress.SetInt(ctx, "last_id", next); err != nil {
+//	        return err
+//	    }
+//	    return queen.ErrContinue
+//	},
+//
+// Progress of a migration is cleared once it is applied.
+package progress
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+)
+
+// ErrNoStore is returned when Get or Set is called outside a migration run
+// by Queen, or with a driver that doesn't implement Store.
+var ErrNoStore = errors.New("progress store not available")
+
+// Store is implemented by drivers that persist migration progress. All
+// methods run in the given transaction.
+type Store interface {
+	// GetProgress returns the value saved under key for version.
+	GetProgress(ctx context.Context, tx *sql.Tx, version, key string) (value string, ok bool, err error)
+
+	// SetProgress saves value under key for version.
+	SetProgress(ctx context.Context, tx *sql.Tx, version, key, value string) error
+
+	// ClearProgress deletes everything saved for version.
+	ClearProgress(ctx context.Context, tx *sql.Tx, version string) error
+}
+
+// scope is the store bound to a running migration.
+type scope struct {
+	store   Store
+	tx      *sql.Tx
+	version string
+}
+
+type scopeKey struct{}
+
+// WithScope returns a context in which Get and Set read and write progress
+// of version through store, in tx. Queen calls it before running an UpFunc.
+func WithScope(ctx context.Context, store Store, tx *sql.Tx, version string) context.Context {
+	return context.WithValue(ctx, scopeKey{}, &scope{store: store, tx: tx, version: version})
+}
+
+func fromContext(ctx context.Context) (*scope, error) {
+	s, ok := ctx.Value(scopeKey{}).(*scope)
+	if !ok {
+		return nil, ErrNoStore
+	}
+	return s, nil
+}
+
+// Get returns the value saved under key for the running migration.
+func Get(ctx context.Context, key string) (string, bool, error) {
+	s, err := fromContext(ctx)
+	if err != nil {
+		return "", false, err
+	}
+	return s.store.GetProgress(ctx, s.tx, s.version, key)
+}
+
+// Set saves value under key for the running migration.
+func Set(ctx context.Context, key, value string) error {
+	s, err := fromContext(ctx)
+	if err != nil {
+		return err
+	}
+	return s.store.SetProgress(ctx, s.tx, s.version, key, value)
+}
+
+// GetInt is like Get for integer values, such as the last processed ID.
+func GetInt(ctx context.Context, key string) (int64, bool, error) {
+	value, ok, err := Get(ctx, key)
+	if err != nil || !ok {
+		return 0, ok, err
+	}
+
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, false, err
+	}
+
+	return n, true, nil
+}
+
+// SetInt is like Set for integer values.
+func SetInt(ctx context.Context, key string, value int64) error {
+	return Set(ctx, key, strconv.FormatInt(value, 10))
+}
@@ -0,0 +1,89 @@
+package progress_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/honeynil/queen"
+	"github.com/honeynil/queen/drivers/mock"
+	"github.com/honeynil/queen/progress"
+)
+
+func TestOutsideMigration(t *testing.T) {
+	ctx := context.Background()
+
+	if _, _, err := progress.Get(ctx, "last_id"); !errors.Is(err, progress.ErrNoStore) {
+		t.Errorf("Get: expected ErrNoStore, got %v", err)
+	}
+	if err := progress.Set(ctx, "last_id", "1"); !errors.Is(err, progress.ErrNoStore) {
+		t.Errorf("Set: expected ErrNoStore, got %v", err)
+	}
+}
+
+func TestResume(t *testing.T) {
+	ctx := context.Background()
+	driver := mock.New()
+	errBoom := errors.New("boom")
+
+	var chunks []int64
+	failAt := int64(3)
+
+	newQueen := func() *queen.Queen {
+		q := queen.New(driver)
+		q.MustAdd(queen.M{
+			Version: "001",
+			Name:    "backfill",
+			UpFunc: func(ctx context.Context, tx *sql.Tx) error {
+				last, _, err := progress.GetInt(ctx, "chunk")
+				if err != nil {
+					return err
+				}
+				if last == 5 {
+					return nil
+				}
+
+				next := last + 1
+				if next == failAt {
+					return errBoom
+				}
+				chunks = append(chunks, next)
+
+				if err := progress.SetInt(ctx, "chunk", next); err != nil {
+					return err
+				}
+				return queen.ErrContinue
+			},
+		})
+		return q
+	}
+
+	// The first run fails at chunk 3 after committing chunks 1 and 2
+	if err := newQueen().Up(ctx); !errors.Is(err, errBoom) {
+		t.Fatalf("Expected errBoom, got %v", err)
+	}
+	if driver.HasVersion("001") {
+		t.Fatal("Expected failed migration not to be applied")
+	}
+
+	// The second run resumes at chunk 3
+	failAt = 0
+	if err := newQueen().Up(ctx); err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+	if !driver.HasVersion("001") {
+		t.Fatal("Expected migration to be applied")
+	}
+
+	want := []int64{1, 2, 3, 4, 5}
+	if !slices.Equal(chunks, want) {
+		t.Errorf("chunks = %v, want %v", chunks, want)
+	}
+
+	// Progress is cleared once the migration is applied
+	if _, ok, _ := driver.GetProgress(ctx, nil, "001", "chunk"); ok {
+		t.Error("Expected progress to be cleared")
+	}
+}
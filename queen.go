@@ -106,19 +106,36 @@
 //	q.Up(ctx)              // Apply all pending migrations
 //	q.UpSteps(ctx, 3)      // Apply next 3 migrations
 //	q.Down(ctx, 1)         // Rollback last migration
+//	q.RollbackBatch(ctx)   // Rollback migrations applied by the last Up
 //	q.Reset(ctx)           // Rollback all migrations
+//	q.Repair(ctx)          // Clear dirty records after a failed run
 //	statuses, _ := q.Status(ctx)  // Get migration status
+//	pending, _ := q.Pending(ctx)  // Get migrations not yet applied
+//	applied, _ := q.Applied(ctx)  // Get migrations recorded in the database
+//	version, _ := q.CurrentVersion(ctx) // Get highest applied version
 //	q.Validate(ctx)        // Validate migrations
 package queen
 
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"os"
+	"os/user"
+	"regexp"
+	"runtime/debug"
+	"slices"
 	"sort"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/honeynil/queen/internal/checksum"
+	"github.com/honeynil/queen/internal/globalreg"
 	naturalsort "github.com/honeynil/queen/internal/sort"
+	"github.com/honeynil/queen/progress"
+	"github.com/honeynil/queen/queenlint"
 )
 
 // Queen manages database migrations.
@@ -129,11 +146,36 @@ type Queen struct {
 
 	// Track which migrations have been applied (cache)
 	applied map[string]*Applied
+
+	// Hooks called around migration execution
+	hooks *HookRegistry
+
+	// Report of the run in progress, nil between runs
+	report *RunReport
+
+	// How long the last lock call waited for the migration lock
+	lockWait time.Duration
+
+	// State of the run in progress for CurrentRun, read by other goroutines
+	liveMu sync.Mutex
+	live   *liveRun
+
+	// Migrations submitted at runtime, registered on Flush
+	queueMu sync.Mutex
+	queue   []*Migration
+
+	// Serializes Flush calls, which may come from any goroutine
+	flushMu sync.Mutex
+
+	// Names of the modules created with Module
+	modules map[string]bool
 }
 
 // Config configures Queen behavior.
 type Config struct {
-	// TableName for migration tracking. Default: "queen_migrations"
+	// TableName for migration tracking, set on drivers implementing
+	// TableNamer. The default isn't set, so drivers created with a table
+	// name of their own keep it. Default: "queen_migrations"
 	TableName string
 
 	// LockTimeout for acquiring migration lock. Default: 30 minutes
@@ -141,6 +183,153 @@ type Config struct {
 
 	// SkipLock disables locking (not recommended for production). Default: false
 	SkipLock bool
+
+	// Maintenance is turned on for exactly as long as the migration lock
+	// is held, so applications pause writes during the lock window rather
+	// than around it. With SkipLock it covers the same operations.
+	// Default: nil (no switch)
+	Maintenance MaintenanceSwitch
+
+	// OutOfOrder controls pending migrations older than the latest applied one.
+	// Default: OutOfOrderAllow
+	OutOfOrder OutOfOrderPolicy
+
+	// VersionPattern is matched against every version at registration,
+	// e.g. regexp.MustCompile(`^\d{14}_`), so a mistyped version fails Add
+	// with ErrInvalidMigration rather than sorting unexpectedly later.
+	// Versions of a Module are matched without the module prefix.
+	// Default: nil (any version)
+	VersionPattern *regexp.Regexp
+
+	// ValidateVersion checks every version at registration like
+	// VersionPattern, for rules a pattern can't express. Its error fails
+	// Add, wrapped with ErrInvalidMigration. Default: nil
+	ValidateVersion func(version string) error
+
+	// Archive receives a RunReport after every run. Default: nil (disabled)
+	Archive ArchiveSink
+
+	// ReportingViews creates reporting views over the tracking table during
+	// initialization. Requires a driver implementing ViewCreator. Default: false
+	ReportingViews bool
+
+	// PreflightPermissions runs CheckPermissions before Up applies anything,
+	// so a missing privilege fails the run before it is half done.
+	// Requires a driver implementing PermissionChecker. Default: false
+	PreflightPermissions bool
+
+	// Flags enables "flag:<name>" requirements in Migration.Requires,
+	// e.g. {"allow_big_table_rewrite": true}. Default: nil
+	Flags map[string]bool
+
+	// TemplateVars enables templating of UpSQL and DownSQL with text/template
+	// syntax, e.g. "CREATE TABLE {{.Schema}}.{{.TablePrefix}}users (...)",
+	// so one migration set can serve environments with different schema
+	// names or prefixes. Default: nil (SQL is used verbatim)
+	TemplateVars map[string]any
+
+	// Environment names the environment Queen runs in, e.g. "production".
+	// Migrations whose Environments don't include it are skipped.
+	// Default: "" (all migrations run)
+	Environment string
+
+	// Operator is recorded with every applied migration alongside the OS
+	// user and hostname, e.g. a person's name or a change ticket, so audits
+	// can tell who ran a migration. Default: ""
+	Operator string
+
+	// BuildInfo is recorded with every applied migration to tie schema
+	// changes to the binary that made them, e.g. a release tag or git SHA.
+	// Default: "" (derived from the module version and VCS revision
+	// embedded by the Go toolchain, see runtime/debug.ReadBuildInfo)
+	BuildInfo string
+
+	// ChecksumNormalization makes SQL checksums ignore formatting, such as
+	// comments and whitespace, so reformatting an applied migration doesn't
+	// mark it modified. Records written without it still match.
+	// Default: 0 (checksums cover the exact SQL text)
+	ChecksumNormalization ChecksumNormalization
+
+	// ChecksumPolicy controls how Up and Validate treat applied migrations
+	// that were modified since. Override it for a single call with
+	// WithChecksumPolicy. Default: ChecksumMismatchError
+	ChecksumPolicy ChecksumPolicy
+
+	// LockFile makes Add and Submit reject migrations that aren't pinned by
+	// it with their current checksum, returning ErrNotLocked. Set it in CI,
+	// e.g. from ReadLockFile("queen.lock"). Default: nil (not checked)
+	LockFile *LockFile
+
+	// IDGenerator creates run identifiers, see RunReport.ID.
+	// Default: nil (ULID)
+	IDGenerator IDGenerator
+
+	// UnknownApplied controls applied migrations that are not registered,
+	// e.g. after a binary rollback. Default: UnknownAppliedIgnore
+	UnknownApplied UnknownAppliedPolicy
+
+	// RequireRollback makes Add, Submit and Validate reject migrations
+	// without DownSQL or DownFunc, unless they set IrreversibleOK.
+	// Default: false
+	RequireRollback bool
+
+	// DestructiveKeywords adds case-insensitive keywords to the detection
+	// of destructive statements, e.g. "DROP INDEX" or "RENAME COLUMN".
+	// See Migration.IsDestructive. Default: nil (built-in rules only)
+	DestructiveKeywords []string
+
+	// DestructivePatterns adds regular expressions matched against each
+	// statement, with comments removed and whitespace collapsed, to the
+	// detection of destructive statements, e.g.
+	// regexp.MustCompile(`(?i)^ALTER TABLE \w+ RENAME\b`) for renamed
+	// tables. Default: nil (built-in rules only)
+	DestructivePatterns []*regexp.Regexp
+
+	// ConfirmDestructive is called before Up applies a migration whose
+	// UpSQL, or a rollback runs a migration whose DownSQL, contains
+	// destructive statements (see Migration.IsDestructive). Returning false
+	// stops the run with ErrNotConfirmed before anything executes. CLIs can
+	// prompt, CI can deny, services can check an override flag.
+	// Default: nil (no confirmation)
+	ConfirmDestructive func(ctx context.Context, m *Migration) (bool, error)
+
+	// Backup backs up data before a migration runs whose SQL for that
+	// direction is destructive (see Migration.IsDestructive), or that sets
+	// Migration.Backup. The backup reference is recorded in the run report
+	// and the history log for RestoreBackup. Default: nil (no backups)
+	Backup BackupProvider
+
+	// ChangeManagement opens a change ticket with the plan before every
+	// run that has something to apply or roll back, and closes it with the
+	// results. The ticket reference is recorded in the run report and the
+	// history log. Default: nil (no tickets)
+	ChangeManagement ChangeManagement
+
+	// EstimateFrom is the history log of another database, such as the
+	// staging driver, used to estimate migrations this database has no
+	// record of, for Plan, CurrentRun and Event.ETA. Migrations neither
+	// recorded are estimated from the recorded ones closest in SQL
+	// length. Default: nil (the database's own history only)
+	EstimateFrom HistoryRecorder
+
+	// Metadata is stored with every applied migration by drivers that
+	// implement ExtendedDriver, e.g. the application version or a deploy
+	// ID, and returned in Applied.Metadata. Default: nil (nothing stored)
+	Metadata map[string]any
+
+	// MetadataSerializer encodes Metadata for storage.
+	// Default: nil (JSONSerializer)
+	MetadataSerializer MetadataSerializer
+
+	// LockHazards controls how Up treats statements that hold long locks
+	// on existing PostgreSQL tables, such as CREATE INDEX without
+	// CONCURRENTLY or ALTER COLUMN TYPE. Checked only when the driver
+	// reports a PostgreSQL database. Default: LockHazardWarn
+	LockHazards LockHazardPolicy
+
+	// Lint configures the rules checked by Lint.
+	// Default: nil (all rules with their defaults)
+	Lint *queenlint.Config
 }
 
 // DefaultConfig returns default settings: "queen_migrations" table, 30min lock timeout.
@@ -171,34 +360,226 @@ func NewWithConfig(driver Driver, config *Config) *Queen {
 		config.LockTimeout = 30 * time.Minute
 	}
 
+	if namer, ok := optional[TableNamer](driver, FeatureTableName); ok && config.TableName != DefaultConfig().TableName {
+		namer.SetTableName(config.TableName)
+	}
+
 	return &Queen{
 		driver:     driver,
 		migrations: make([]*Migration, 0),
 		config:     config,
 		applied:    make(map[string]*Applied),
+		hooks:      newHookRegistry(),
+	}
+}
+
+// NewFromRegistry creates a Queen instance with default configuration and
+// adds every migration registered with the registry package.
+func NewFromRegistry(driver Driver) (*Queen, error) {
+	return NewFromRegistryWithConfig(driver, DefaultConfig())
+}
+
+// NewFromRegistryWithConfig is like NewFromRegistry with custom settings.
+// It returns the errors of AddAll, e.g. ErrNotLocked when Config.LockFile
+// doesn't pin a registered migration.
+func NewFromRegistryWithConfig(driver Driver, config *Config) (*Queen, error) {
+	q := NewWithConfig(driver, config)
+
+	var ms []M
+	for _, m := range globalreg.All() {
+		ms = append(ms, m.(M))
+	}
+	if err := q.AddAll(ms...); err != nil {
+		return nil, err
 	}
+	return q, nil
 }
 
-// Add registers a migration after validation.
-// Returns ErrVersionConflict if version already exists.
+// Add registers a migration after validation, including expanding its SQL
+// templates when Config.TemplateVars is set.
+// Returns ErrVersionConflict if version already exists, and ErrNotLocked if
+// Config.LockFile is set and doesn't pin the migration.
 func (q *Queen) Add(m M) error {
-	if err := m.Validate(); err != nil {
+	migration, err := q.prepare(m)
+	if err != nil {
 		return err
 	}
 
-	for _, existing := range q.migrations {
-		if existing.Version == m.Version {
-			return fmt.Errorf("%w: %s", ErrVersionConflict, m.Version)
+	q.migrations = append(q.migrations, migration)
+
+	return nil
+}
+
+// AddAll registers every migration in ms like Add, all or none. Instead of
+// stopping at the first problem, it returns every one of them, each as a
+// MigrationError, joined with errors.Join. Versions repeated within ms are
+// reported as ErrVersionConflict.
+func (q *Queen) AddAll(ms ...M) error {
+	var (
+		migrations []*Migration
+		errs       []error
+	)
+	seen := make(map[string]bool, len(ms))
+	for _, m := range ms {
+		if m.Version != "" && seen[m.Version] {
+			errs = append(errs, newMigrationError(m.Version, m.Name,
+				fmt.Errorf("%w: %s is repeated", ErrVersionConflict, m.Version)))
+			continue
 		}
+		seen[m.Version] = true
+
+		migration, err := q.prepare(m)
+		if err != nil {
+			errs = append(errs, newMigrationError(m.Version, m.Name, err))
+			continue
+		}
+		migrations = append(migrations, migration)
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	q.migrations = append(q.migrations, migrations...)
+
+	return nil
+}
+
+// prepare validates m for Add and returns the migration to register.
+func (q *Queen) prepare(m M) (*Migration, error) {
+	if err := q.checkNew(&m); err != nil {
+		return nil, err
+	}
+
+	if q.hasVersion(m.Version) {
+		return nil, fmt.Errorf("%w: %s", ErrVersionConflict, m.Version)
+	}
+
+	migration := q.registered(m)
+	if err := q.checkLocked(migration); err != nil {
+		return nil, err
+	}
+
+	return migration, nil
+}
+
+// Remove unregisters the migration with the given version, for frameworks
+// that build the migration set dynamically, e.g. when a feature module is
+// disabled. The database is not touched: if the migration was applied, it
+// stays applied and is treated as unregistered (see Config.UnknownApplied).
+//
+// Returns ErrMigrationNotFound if the version isn't registered.
+func (q *Queen) Remove(version string) error {
+	i := q.indexOf(version)
+	if i < 0 {
+		return fmt.Errorf("%w: %s", ErrMigrationNotFound, version)
+	}
+
+	q.migrations = slices.Delete(q.migrations, i, i+1)
+
+	return nil
+}
+
+// Replace substitutes m for the registered migration with the same version,
+// validated like Add. If the migration was applied with different SQL or
+// ManualChecksum, Up reports a checksum mismatch as usual.
+//
+// Returns ErrMigrationNotFound if the version isn't registered, and
+// ErrNotLocked if Config.LockFile is set and doesn't pin m.
+func (q *Queen) Replace(m M) error {
+	if err := q.checkNew(&m); err != nil {
+		return err
+	}
+
+	i := q.indexOf(m.Version)
+	if i < 0 {
+		return fmt.Errorf("%w: %s", ErrMigrationNotFound, m.Version)
 	}
 
-	// Store pointer to prevent mutation after registration
+	migration := q.registered(m)
+	if err := q.checkLocked(migration); err != nil {
+		return err
+	}
+
+	q.migrations[i] = migration
+
+	return nil
+}
+
+// checkNew validates m for Add, Replace and Submit.
+func (q *Queen) checkNew(m *M) error {
+	if err := m.Validate(); err != nil {
+		return err
+	}
+
+	if err := q.checkVersion(m.Version); err != nil {
+		return err
+	}
+
+	if err := q.checkRollback(m); err != nil {
+		return err
+	}
+
+	// Catch template errors at registration rather than mid-run
+	if _, err := q.render(m); err != nil {
+		return fmt.Errorf("migration %s: %w", m.Version, err)
+	}
+
+	return nil
+}
+
+// registered returns a copy of m to register, set up with the instance's
+// checksum normalization and destructive-statement rules. The copy prevents mutation after registration.
+func (q *Queen) registered(m M) *Migration {
 	migration := m
-	q.migrations = append(q.migrations, &migration)
+	migration.normalize = checksum.Normalization(q.config.ChecksumNormalization)
+	migration.destructiveRules = destructiveRules{
+		keywords: q.config.DestructiveKeywords,
+		patterns: q.config.DestructivePatterns,
+	}
+
+	// Drop a checksum cached before normalization was set
+	migration.checksumOnce = nil
+	migration.checksum = ""
+
+	return &migration
+}
+
+// checkVersion enforces Config.VersionPattern and Config.ValidateVersion.
+func (q *Queen) checkVersion(version string) error {
+	if module := q.moduleOf(version); module != "" {
+		version = strings.TrimPrefix(version, module+"/")
+	}
+
+	if q.config.VersionPattern != nil && !q.config.VersionPattern.MatchString(version) {
+		return fmt.Errorf("%w: version %q doesn't match %s", ErrInvalidMigration, version, q.config.VersionPattern)
+	}
+
+	if q.config.ValidateVersion != nil {
+		if err := q.config.ValidateVersion(version); err != nil {
+			return fmt.Errorf("%w: version %q: %w", ErrInvalidMigration, version, err)
+		}
+	}
 
 	return nil
 }
 
+// checkRollback enforces Config.RequireRollback for m.
+func (q *Queen) checkRollback(m *Migration) error {
+	if !q.config.RequireRollback || m.HasRollback() || m.IrreversibleOK {
+		return nil
+	}
+	return fmt.Errorf("%w: migration %s has no down migration (set IrreversibleOK if intended)",
+		ErrInvalidMigration, m.Version)
+}
+
+// checkLocked verifies m against Config.LockFile, if set.
+func (q *Queen) checkLocked(m *Migration) error {
+	if q.config.LockFile == nil {
+		return nil
+	}
+	return q.config.LockFile.Verify(m)
+}
+
 // MustAdd is like Add but panics on error.
 // Use during initialization when registration must succeed.
 func (q *Queen) MustAdd(m M) {
@@ -224,26 +605,35 @@ func (q *Queen) UpSteps(ctx context.Context, n int) error {
 		return ErrNoMigrations
 	}
 
-	if err := q.driver.Init(ctx); err != nil {
+	if err := q.init(ctx); err != nil {
 		return err
 	}
 
-	if !q.config.SkipLock {
-		if err := q.driver.Lock(ctx, q.config.LockTimeout); err != nil {
-			return err
-		}
-		defer func() {
-			// Unlock uses background context to complete even if parent context is cancelled.
-			// Unlock errors are non-critical and safely ignored.
-			_ = q.driver.Unlock(context.Background())
-		}()
+	unlock, err := q.lock(ctx)
+	if err != nil {
+		return err
 	}
+	defer unlock()
+
+	return q.upLocked(ctx, n, nil)
+}
 
+// upLocked applies up to n pending migrations like UpSteps, with the
+// migration lock already held. If only is set, only pending migrations it
+// accepts are applied.
+func (q *Queen) upLocked(ctx context.Context, n int, only func(*Migration) bool) error {
 	if err := q.loadApplied(ctx); err != nil {
 		return err
 	}
 
+	if err := q.checkDirty(); err != nil {
+		return err
+	}
+
 	pending := q.getPending()
+	if only != nil {
+		pending = slices.DeleteFunc(pending, func(m *Migration) bool { return !only(m) })
+	}
 	if len(pending) == 0 {
 		return nil
 	}
@@ -252,18 +642,66 @@ func (q *Queen) UpSteps(ctx context.Context, n int) error {
 		pending = pending[:n]
 	}
 
-	for _, m := range pending {
-		if err := q.applyMigration(ctx, m); err != nil {
-			return newMigrationError(m.Version, m.Name, err)
+	return q.run(ctx, OperationUp, pending, func() error {
+		if err := q.checkChecksums(ctx); err != nil {
+			return err
 		}
-	}
 
-	return nil
+		if err := q.checkUnknownApplied(ctx); err != nil {
+			return err
+		}
+
+		if err := q.checkOutOfOrder(ctx, pending); err != nil {
+			return err
+		}
+
+		if err := q.checkRequirements(ctx, pending); err != nil {
+			return err
+		}
+
+		if err := q.checkFeatures(pending); err != nil {
+			return err
+		}
+
+		if err := q.checkLockHazards(ctx, pending); err != nil {
+			return err
+		}
+
+		if q.config.PreflightPermissions {
+			if err := q.checkPermissions(ctx); err != nil {
+				return err
+			}
+		}
+
+		if err := q.confirmDestructive(ctx, pending, false); err != nil {
+			return err
+		}
+
+		meta := q.recordMeta()
+		for _, m := range pending {
+			err := q.applyMigration(ctx, m, meta)
+			if errors.Is(err, ErrIncomplete) {
+				// Later migrations wait until this one completes
+				return nil
+			}
+			if err != nil {
+				return newMigrationError(m.Version, m.Name, err)
+			}
+		}
+
+		return nil
+	})
 }
 
 // Down rolls back the last n migrations.
 // If n <= 0, rolls back only the last migration.
 func (q *Queen) Down(ctx context.Context, n int) error {
+	return q.down(ctx, n, nil)
+}
+
+// down rolls back the last n migrations like Down. If only is set, only
+// applied migrations it accepts are rolled back.
+func (q *Queen) down(ctx context.Context, n int, only func(*Migration) bool) error {
 	if n <= 0 {
 		n = 1
 	}
@@ -272,24 +710,34 @@ func (q *Queen) Down(ctx context.Context, n int) error {
 		return ErrNoDriver
 	}
 
-	if err := q.driver.Init(ctx); err != nil {
+	if err := q.init(ctx); err != nil {
 		return err
 	}
 
-	if !q.config.SkipLock {
-		if err := q.driver.Lock(ctx, q.config.LockTimeout); err != nil {
-			return err
-		}
-		defer func() {
-			_ = q.driver.Unlock(context.Background())
-		}()
+	unlock, err := q.lock(ctx)
+	if err != nil {
+		return err
 	}
+	defer unlock()
+
+	return q.downLocked(ctx, n, only)
+}
 
+// downLocked rolls back the last n migrations like down, with the
+// migration lock already held.
+func (q *Queen) downLocked(ctx context.Context, n int, only func(*Migration) bool) error {
 	if err := q.loadApplied(ctx); err != nil {
 		return err
 	}
 
+	if err := q.checkDirty(); err != nil {
+		return err
+	}
+
 	applied := q.getAppliedMigrations()
+	if only != nil {
+		applied = slices.DeleteFunc(applied, func(m *Migration) bool { return !only(m) })
+	}
 	if len(applied) == 0 {
 		return nil
 	}
@@ -300,17 +748,9 @@ func (q *Queen) Down(ctx context.Context, n int) error {
 
 	toRollback := applied[:n]
 
-	for _, m := range toRollback {
-		if !m.HasRollback() {
-			return newMigrationError(m.Version, m.Name, fmt.Errorf("no down migration defined"))
-		}
-
-		if err := q.rollbackMigration(ctx, m); err != nil {
-			return newMigrationError(m.Version, m.Name, err)
-		}
-	}
-
-	return nil
+	return q.run(ctx, OperationDown, toRollback, func() error {
+		return q.rollbackAll(ctx, toRollback)
+	})
 }
 
 // Reset rolls back all applied migrations.
@@ -319,40 +759,76 @@ func (q *Queen) Reset(ctx context.Context) error {
 		return ErrNoDriver
 	}
 
-	if err := q.driver.Init(ctx); err != nil {
+	if err := q.init(ctx); err != nil {
 		return err
 	}
 
-	if !q.config.SkipLock {
-		if err := q.driver.Lock(ctx, q.config.LockTimeout); err != nil {
-			return err
-		}
-		defer func() {
-			_ = q.driver.Unlock(context.Background())
-		}()
+	unlock, err := q.lock(ctx)
+	if err != nil {
+		return err
 	}
+	defer unlock()
 
 	if err := q.loadApplied(ctx); err != nil {
 		return err
 	}
 
+	if err := q.checkDirty(); err != nil {
+		return err
+	}
+
 	applied := q.getAppliedMigrations()
 	if len(applied) == 0 {
 		return nil
 	}
 
 	// Don't call Down() to avoid double-locking
-	for _, m := range applied {
-		if !m.HasRollback() {
-			return newMigrationError(m.Version, m.Name, fmt.Errorf("no down migration defined"))
-		}
+	return q.run(ctx, OperationReset, applied, func() error {
+		return q.rollbackAll(ctx, applied)
+	})
+}
 
-		if err := q.rollbackMigration(ctx, m); err != nil {
-			return newMigrationError(m.Version, m.Name, err)
-		}
+// RollbackBatch rolls back every migration applied by the last Up run.
+// Migrations applied before batches were tracked share batch 0 and are
+// rolled back together.
+//
+// Returns ErrMigrationNotFound if a migration in the batch is no longer
+// registered and has no stored DownSQL.
+func (q *Queen) RollbackBatch(ctx context.Context) error {
+	if q.driver == nil {
+		return ErrNoDriver
 	}
 
-	return nil
+	if err := q.init(ctx); err != nil {
+		return err
+	}
+
+	unlock, err := q.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := q.loadApplied(ctx); err != nil {
+		return err
+	}
+
+	if err := q.checkDirty(); err != nil {
+		return err
+	}
+
+	if len(q.applied) == 0 {
+		return nil
+	}
+
+	toRollback, err := q.lastBatchMigrations()
+	if err != nil {
+		return err
+	}
+
+	return q.run(ctx, OperationRollbackBatch, toRollback, func() error {
+		return q.rollbackAll(ctx, toRollback)
+	})
 }
 
 // Status returns the status of all registered migrations.
@@ -361,7 +837,7 @@ func (q *Queen) Status(ctx context.Context) ([]MigrationStatus, error) {
 		return nil, ErrNoDriver
 	}
 
-	if err := q.driver.Init(ctx); err != nil {
+	if err := q.init(ctx); err != nil {
 		return nil, err
 	}
 
@@ -380,23 +856,113 @@ func (q *Queen) Status(ctx context.Context) ([]MigrationStatus, error) {
 			Status:      StatusPending,
 		}
 
+		if !m.inEnvironment(q.config.Environment) {
+			status.Status = StatusSkipped
+		}
+
 		if applied, ok := q.applied[m.Version]; ok {
 			status.Status = StatusApplied
 			status.AppliedAt = &applied.AppliedAt
+			status.Duration = applied.Duration
+			status.AppliedBy = applied.AppliedBy
+			status.Hostname = applied.Hostname
+			status.Operator = applied.Operator
+			status.BuildInfo = applied.BuildInfo
+			status.Metadata = applied.Metadata
 
 			// Check for checksum mismatch
-			if applied.Checksum != m.Checksum() && m.Checksum() != noChecksumMarker {
+			if !m.checksumMatches(applied.Checksum) {
 				status.Status = StatusModified
 			}
+
+			if applied.Dirty {
+				status.Status = StatusDirty
+			}
 		}
 
 		statuses[i] = status
 	}
 
+	if q.config.UnknownApplied != UnknownAppliedIgnore {
+		for _, version := range q.unknownApplied() {
+			applied := q.applied[version]
+			statuses = append(statuses, MigrationStatus{
+				Version:     applied.Version,
+				Name:        applied.Name,
+				Status:      StatusUnknown,
+				AppliedAt:   &applied.AppliedAt,
+				Duration:    applied.Duration,
+				AppliedBy:   applied.AppliedBy,
+				Hostname:    applied.Hostname,
+				Operator:    applied.Operator,
+				BuildInfo:   applied.BuildInfo,
+				Metadata:    applied.Metadata,
+				Checksum:    applied.Checksum,
+				HasRollback: applied.DownSQL != "",
+			})
+		}
+	}
+
 	return statuses, nil
 }
 
-// Validate checks for duplicate versions, invalid migrations, and checksum mismatches.
+// Pending returns registered migrations that have not been applied yet,
+// sorted by version in the order Up would apply them.
+func (q *Queen) Pending(ctx context.Context) ([]*Migration, error) {
+	if q.driver == nil {
+		return nil, ErrNoDriver
+	}
+
+	if err := q.init(ctx); err != nil {
+		return nil, err
+	}
+
+	if err := q.loadApplied(ctx); err != nil {
+		return nil, err
+	}
+
+	return q.getPending(), nil
+}
+
+// Applied returns migrations recorded as applied in the database,
+// sorted by applied time in ascending order.
+// The result may include versions that are no longer registered.
+func (q *Queen) Applied(ctx context.Context) ([]Applied, error) {
+	if q.driver == nil {
+		return nil, ErrNoDriver
+	}
+
+	if err := q.init(ctx); err != nil {
+		return nil, err
+	}
+
+	return q.driver.GetApplied(ctx)
+}
+
+// CurrentVersion returns the highest applied version in natural sort order.
+// Returns ErrNoneApplied if no migrations have been applied yet.
+func (q *Queen) CurrentVersion(ctx context.Context) (string, error) {
+	applied, err := q.Applied(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	if len(applied) == 0 {
+		return "", ErrNoneApplied
+	}
+
+	current := applied[0].Version
+	for _, a := range applied[1:] {
+		if naturalsort.Compare(a.Version, current) > 0 {
+			current = a.Version
+		}
+	}
+
+	return current, nil
+}
+
+// Validate checks for duplicate versions, invalid migrations, dirty records, and
+// checksum mismatches, subject to Config.ChecksumPolicy.
 func (q *Queen) Validate(ctx context.Context) error {
 	if len(q.migrations) == 0 {
 		return ErrNoMigrations
@@ -413,10 +979,14 @@ func (q *Queen) Validate(ctx context.Context) error {
 		if err := m.Validate(); err != nil {
 			return fmt.Errorf("invalid migration %s: %w", m.Version, err)
 		}
+
+		if err := q.checkRollback(m); err != nil {
+			return err
+		}
 	}
 
 	if q.driver != nil {
-		if err := q.driver.Init(ctx); err != nil {
+		if err := q.init(ctx); err != nil {
 			return err
 		}
 
@@ -424,13 +994,24 @@ func (q *Queen) Validate(ctx context.Context) error {
 			return err
 		}
 
-		for _, m := range q.migrations {
-			if applied, ok := q.applied[m.Version]; ok {
-				if applied.Checksum != m.Checksum() && m.Checksum() != noChecksumMarker {
-					return fmt.Errorf("%w: migration %s (expected %s, got %s)",
-						ErrChecksumMismatch, m.Version, applied.Checksum, m.Checksum())
-				}
-			}
+		if err := q.checkDirty(); err != nil {
+			return err
+		}
+
+		if err := q.checkChecksums(ctx); err != nil {
+			return err
+		}
+
+		if err := q.checkUnknownApplied(ctx); err != nil {
+			return err
+		}
+
+		if err := q.checkRequirements(ctx, q.getPending()); err != nil {
+			return err
+		}
+
+		if err := q.checkFeatures(q.getPending()); err != nil {
+			return err
 		}
 	}
 
@@ -445,6 +1026,25 @@ func (q *Queen) Close() error {
 	return nil
 }
 
+// init initializes the driver and creates optional database objects.
+func (q *Queen) init(ctx context.Context) error {
+	if err := q.driver.Init(ctx); err != nil {
+		return err
+	}
+
+	if q.config.ReportingViews {
+		vc, ok := optional[ViewCreator](q.driver, FeatureViews)
+		if !ok {
+			return fmt.Errorf("%w: reporting views", ErrUnsupported)
+		}
+		if err := vc.EnsureViews(ctx); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // loadApplied caches applied migrations from database.
 func (q *Queen) loadApplied(ctx context.Context) error {
 	applied, err := q.driver.GetApplied(ctx)
@@ -457,15 +1057,16 @@ func (q *Queen) loadApplied(ctx context.Context) error {
 		q.applied[applied[i].Version] = &applied[i]
 	}
 
-	return nil
+	return q.loadMetadata(ctx)
 }
 
-// getPending returns unapplied migrations sorted by version.
+// getPending returns unapplied migrations for the configured environment,
+// sorted by version.
 func (q *Queen) getPending() []*Migration {
 	pending := make([]*Migration, 0)
 
 	for _, m := range q.migrations {
-		if _, applied := q.applied[m.Version]; !applied {
+		if _, applied := q.applied[m.Version]; !applied && m.inEnvironment(q.config.Environment) {
 			pending = append(pending, m)
 		}
 	}
@@ -478,16 +1079,92 @@ func (q *Queen) getPending() []*Migration {
 	return pending
 }
 
+// recordMeta returns the metadata recorded with migrations applied by a new
+// Up run. The OS user and hostname are best effort and empty if unknown.
+func (q *Queen) recordMeta() RecordMeta {
+	meta := RecordMeta{
+		Batch:     q.lastBatch() + 1,
+		Operator:  q.config.Operator,
+		BuildInfo: q.config.BuildInfo,
+	}
+	if meta.BuildInfo == "" {
+		meta.BuildInfo = buildInfo()
+	}
+
+	if u, err := user.Current(); err == nil {
+		meta.AppliedBy = u.Username
+	} else {
+		meta.AppliedBy = os.Getenv("USER")
+	}
+	meta.Hostname, _ = os.Hostname()
+
+	return meta
+}
+
+// buildInfo describes the running binary as "<module>@<version> <revision>",
+// leaving out parts the Go toolchain didn't embed. The revision gets a
+// "-dirty" suffix if the working tree had local changes.
+func buildInfo() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+
+	build := info.Main.Path
+	if v := info.Main.Version; v != "" && v != "(devel)" {
+		build += "@" + v
+	}
+
+	var revision string
+	var modified bool
+	for _, s := range info.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			revision = s.Value
+		case "vcs.modified":
+			modified = s.Value == "true"
+		}
+	}
+	if revision != "" {
+		if modified {
+			revision += "-dirty"
+		}
+		build = strings.TrimSpace(build + " " + revision)
+	}
+
+	return build
+}
+
+// lastBatch returns the highest batch number among applied migrations.
+func (q *Queen) lastBatch() int {
+	last := 0
+	for _, a := range q.applied {
+		if a.Batch > last {
+			last = a.Batch
+		}
+	}
+	return last
+}
+
 // getAppliedMigrations returns applied migrations sorted newest-first.
 func (q *Queen) getAppliedMigrations() []*Migration {
 	applied := make([]*Migration, 0)
 
+	registered := make(map[string]bool, len(q.migrations))
 	for _, m := range q.migrations {
+		registered[m.Version] = true
 		if _, ok := q.applied[m.Version]; ok {
 			applied = append(applied, m)
 		}
 	}
 
+	// Migrations removed from code can still be rolled back with their stored DownSQL
+	for version, a := range q.applied {
+		if !registered[version] && a.DownSQL != "" {
+			applied = append(applied, storedMigration(a))
+		}
+	}
+
 	// Sort by version using natural sort, then reverse
 	sort.Slice(applied, func(i, j int) bool {
 		return naturalsort.Compare(applied[i].Version, applied[j].Version) > 0
@@ -496,18 +1173,145 @@ func (q *Queen) getAppliedMigrations() []*Migration {
 	return applied
 }
 
+// lastBatchMigrations returns the migrations of the last batch, newest-first.
+func (q *Queen) lastBatchMigrations() ([]*Migration, error) {
+	batch := q.lastBatch()
+	registered := make(map[string]bool, len(q.migrations))
+	for _, m := range q.migrations {
+		registered[m.Version] = true
+	}
+	for version, a := range q.applied {
+		if a.Batch == batch && !registered[version] && a.DownSQL == "" {
+			return nil, fmt.Errorf("%w: %s (batch %d)", ErrMigrationNotFound, version, batch)
+		}
+	}
+
+	var migrations []*Migration
+	for _, m := range q.getAppliedMigrations() {
+		if q.applied[m.Version].Batch == batch {
+			migrations = append(migrations, m)
+		}
+	}
+
+	return migrations, nil
+}
+
+// storedMigration rebuilds an applied migration that is no longer registered
+// from its tracking record, so it can be rolled back with the stored DownSQL.
+func storedMigration(a *Applied) *Migration {
+	return &Migration{
+		Version: a.Version,
+		Name:    a.Name,
+		DownSQL: a.DownSQL,
+	}
+}
+
+// record records m with meta. Without MetaRecorder, m is recorded without
+// its metadata, and a dirty marker isn't recorded at all.
+func (q *Queen) record(ctx context.Context, m *Migration, meta RecordMeta) error {
+	m = q.recordable(m)
+	if recorder, ok := optional[MetaRecorder](q.driver, FeatureRecordMeta); ok {
+		return recorder.RecordWithMeta(ctx, m, meta)
+	}
+	if meta.Dirty {
+		return nil
+	}
+	return q.driver.Record(ctx, m)
+}
+
+// recordAll records migrations with meta, all or none if the driver
+// implements BatchRecorder and one at a time otherwise.
+func (q *Queen) recordAll(ctx context.Context, migrations []*Migration, meta RecordMeta) error {
+	if recorder, ok := optional[BatchRecorder](q.driver, FeatureBatchRecord); ok {
+		recordable := make([]*Migration, len(migrations))
+		for i, m := range migrations {
+			recordable[i] = q.recordable(m)
+		}
+		return recorder.RecordBatch(ctx, recordable, meta)
+	}
+
+	for _, m := range migrations {
+		if err := q.record(ctx, m, meta); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recordable returns m as its record stores it, with a DownSQL that runs
+// without m: translated to the driver's placeholders if
+// TranslatePlaceholders is set, and left out if m has DownArgs, which
+// aren't stored.
+func (q *Queen) recordable(m *Migration) *Migration {
+	translate := q.sqlRunner().translate
+	if len(m.DownArgs) == 0 && (!m.TranslatePlaceholders || translate == nil) {
+		return m
+	}
+
+	// The copy shares the checksum, which must come from m
+	m.Checksum()
+	stored := *m
+	if len(m.DownArgs) > 0 {
+		stored.DownSQL = ""
+	} else {
+		stored.DownSQL = translate(m.DownSQL)
+	}
+
+	return &stored
+}
+
 // applyMigration applies a single migration.
-func (q *Queen) applyMigration(ctx context.Context, m *Migration) error {
-	// Execute migration in transaction
-	err := q.driver.Exec(ctx, func(tx *sql.Tx) error {
-		return m.executeUp(ctx, tx)
-	})
+func (q *Queen) applyMigration(ctx context.Context, m *Migration, meta RecordMeta) error {
+	if err := q.emit(ctx, Event{Kind: EventBeforeUp, Migration: m}); err != nil {
+		return err
+	}
+
+	start := time.Now()
+
+	backup, err := q.backup(ctx, m, false)
 	if err != nil {
+		_ = q.emit(ctx, Event{Kind: EventFailed, Migration: m, Duration: time.Since(start), Err: err})
+		return err
+	}
+
+	// Record as dirty first so an interrupted run leaves a trace
+	dirty := meta
+	dirty.Dirty = true
+	if err := q.record(ctx, m, dirty); err != nil {
+		_ = q.emit(ctx, Event{Kind: EventFailed, Migration: m, Duration: time.Since(start), Err: err, Backup: backup})
 		return err
 	}
 
-	// Record in database
-	if err := q.driver.Record(ctx, m); err != nil {
+	var metadata map[string]any
+	execStart := time.Now()
+	err = q.execute(ctx, m, false)
+	duration := time.Since(execStart)
+	switch {
+	case errors.Is(err, ErrIncomplete):
+		// The migration made progress but stays pending for the next run
+		if rmErr := q.driver.Remove(ctx, m.Version); rmErr != nil {
+			err = rmErr
+			break
+		}
+		_ = q.emit(ctx, Event{Kind: EventWarning, Migration: m, Duration: time.Since(start), Err: err})
+		return err
+	case err != nil:
+		// A failed transaction was rolled back, so the database is clean again
+		// and the marker can go. Non-transactional migrations stay dirty, as
+		// does the record if removing the marker fails.
+		if !m.NoTransaction {
+			_ = q.driver.Remove(ctx, m.Version)
+		}
+	default:
+		// Record again to clear the marker and store the duration
+		done := meta
+		done.Duration = duration
+		if err = q.record(ctx, m, done); err == nil {
+			metadata, err = q.storeMetadata(ctx, m)
+		}
+	}
+	if err != nil {
+		_ = q.emit(ctx, Event{Kind: EventFailed, Migration: m, Duration: time.Since(start), Err: err, Backup: backup})
 		return err
 	}
 
@@ -517,6 +1321,189 @@ func (q *Queen) applyMigration(ctx context.Context, m *Migration) error {
 		Name:      m.Name,
 		AppliedAt: time.Now(),
 		Checksum:  m.Checksum(),
+		Batch:     meta.Batch,
+		DownSQL:   q.recordable(m).DownSQL,
+		Duration:  duration,
+		AppliedBy: meta.AppliedBy,
+		Hostname:  meta.Hostname,
+		Operator:  meta.Operator,
+		BuildInfo: meta.BuildInfo,
+		Metadata:  metadata,
+	}
+
+	_ = q.emit(ctx, Event{Kind: EventAfterUp, Migration: m, Duration: time.Since(start), Backup: backup})
+
+	return nil
+}
+
+// execute runs the up or down part of a migration, inside a transaction
+// unless the migration sets NoTransaction.
+func (q *Queen) execute(ctx context.Context, m *Migration, down bool) error {
+	if m.Timeout > 0 {
+		parent := ctx
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, m.Timeout)
+		defer cancel()
+
+		err := q.executeIn(ctx, m, down)
+		if err != nil && parent.Err() == nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return fmt.Errorf("%w after %s: %w", ErrTimeout, m.Timeout, err)
+		}
+		return err
+	}
+
+	return q.executeIn(ctx, m, down)
+}
+
+// executeIn runs the up or down part of a migration with the given context.
+func (q *Queen) executeIn(ctx context.Context, m *Migration, down bool) error {
+	m, err := q.render(m)
+	if err != nil {
+		return err
+	}
+
+	if m.NoTransaction {
+		execer, ok := optional[NoTxExecer](q.driver, FeatureNoTransaction)
+		if !ok {
+			return fmt.Errorf("%w: running outside a transaction", ErrUnsupported)
+		}
+		return execer.ExecNoTx(ctx, func(conn *sql.Conn) error {
+			if down {
+				return m.execSQL(ctx, conn, true, q.sqlRunner())
+			}
+
+			before, err := m.countRows(ctx, conn)
+			if err != nil {
+				return err
+			}
+			if err := m.execSQL(ctx, conn, false, q.sqlRunner()); err != nil {
+				return err
+			}
+			return m.checkRowDeltas(ctx, conn, before)
+		})
+	}
+
+	if down {
+		return q.driver.Exec(ctx, func(tx *sql.Tx) error {
+			return m.executeDown(ctx, tx, q.sqlRunner())
+		})
+	}
+
+	store, _ := optional[progress.Store](q.driver, FeatureProgress)
+
+	// Row counts before the first transaction, for ExpectRowDelta
+	var before []int64
+	counted := false
+
+	for {
+		// Incomplete and continuing UpFuncs keep the work they did, so their
+		// transaction commits
+		var partial error
+		err := q.driver.Exec(ctx, func(tx *sql.Tx) error {
+			if !counted {
+				var err error
+				if before, err = m.countRows(ctx, tx); err != nil {
+					return err
+				}
+				counted = true
+			}
+
+			upCtx := ctx
+			if store != nil {
+				upCtx = progress.WithScope(ctx, store, tx, m.Version)
+			}
+
+			err := m.executeUp(upCtx, tx, q.sqlRunner())
+			switch {
+			case errors.Is(err, ErrIncomplete), errors.Is(err, ErrContinue):
+				partial = err
+				return nil
+			case err != nil:
+				return err
+			}
+
+			if err := m.checkRowDeltas(ctx, tx, before); err != nil {
+				return err
+			}
+			if store != nil {
+				return store.ClearProgress(ctx, tx, m.Version)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		if !errors.Is(partial, ErrContinue) {
+			return partial
+		}
+	}
+}
+
+// sqlRunner returns how the driver runs SQL: with its statement splitter,
+// or as a whole without one, and its placeholder counter and translator,
+// if any.
+func (q *Queen) sqlRunner() sqlRunner {
+	var r sqlRunner
+	if s, ok := optional[StatementSplitter](q.driver, FeatureStatementSplitting); ok {
+		r.split = s.SplitStatements
+	}
+	if c, ok := optional[PlaceholderCounter](q.driver, FeatureStatementSplitting); ok {
+		r.count = c.CountPlaceholders
+	}
+	if t, ok := optional[PlaceholderTranslator](q.driver, FeaturePlaceholders); ok {
+		r.translate = t.TranslatePlaceholders
+	}
+	return r
+}
+
+// rollbackAll rolls back migrations in the given order, stopping at the
+// first migration without a down method.
+func (q *Queen) rollbackAll(ctx context.Context, migrations []*Migration) error {
+	if err := q.checkRequirements(ctx, migrations); err != nil {
+		return err
+	}
+
+	if err := q.checkFeatures(migrations); err != nil {
+		return err
+	}
+
+	if err := q.confirmDestructive(ctx, migrations, true); err != nil {
+		return err
+	}
+
+	for _, m := range migrations {
+		if !m.HasRollback() {
+			return newMigrationError(m.Version, m.Name, fmt.Errorf("no down migration defined"))
+		}
+
+		if err := q.rollbackMigration(ctx, m); err != nil {
+			return newMigrationError(m.Version, m.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// confirmDestructive asks Config.ConfirmDestructive about every destructive
+// migration in the plan before any of them runs.
+func (q *Queen) confirmDestructive(ctx context.Context, migrations []*Migration, down bool) error {
+	if q.config.ConfirmDestructive == nil {
+		return nil
+	}
+
+	for _, m := range migrations {
+		if !m.destructive(down) {
+			continue
+		}
+
+		ok, err := q.config.ConfirmDestructive(ctx, m)
+		if err != nil {
+			return newMigrationError(m.Version, m.Name, err)
+		}
+		if !ok {
+			return newMigrationError(m.Version, m.Name, ErrNotConfirmed)
+		}
 	}
 
 	return nil
@@ -524,21 +1511,43 @@ func (q *Queen) applyMigration(ctx context.Context, m *Migration) error {
 
 // rollbackMigration rolls back a single migration.
 func (q *Queen) rollbackMigration(ctx context.Context, m *Migration) error {
-	// Execute rollback in transaction
-	err := q.driver.Exec(ctx, func(tx *sql.Tx) error {
-		return m.executeDown(ctx, tx)
-	})
+	if err := q.emit(ctx, Event{Kind: EventBeforeDown, Migration: m, Down: true}); err != nil {
+		return err
+	}
+
+	start := time.Now()
+
+	backup, err := q.backup(ctx, m, true)
 	if err != nil {
+		_ = q.emit(ctx, Event{Kind: EventFailed, Migration: m, Down: true, Duration: time.Since(start), Err: err})
 		return err
 	}
 
-	// Remove from database
-	if err := q.driver.Remove(ctx, m.Version); err != nil {
+	if err := q.setDirty(ctx, m, true); err != nil {
+		_ = q.emit(ctx, Event{Kind: EventFailed, Migration: m, Down: true, Duration: time.Since(start), Err: err, Backup: backup})
+		return err
+	}
+
+	err = q.execute(ctx, m, true)
+	if err != nil {
+		// A failed transaction was rolled back, so the migration is still
+		// cleanly applied. Non-transactional migrations stay dirty.
+		if !m.NoTransaction {
+			_ = q.setDirty(ctx, m, false)
+		}
+	} else {
+		// Remove from database
+		err = q.driver.Remove(ctx, m.Version)
+	}
+	if err != nil {
+		_ = q.emit(ctx, Event{Kind: EventFailed, Migration: m, Down: true, Duration: time.Since(start), Err: err, Backup: backup})
 		return err
 	}
 
 	// Update cache
 	delete(q.applied, m.Version)
 
+	_ = q.emit(ctx, Event{Kind: EventAfterDown, Migration: m, Down: true, Duration: time.Since(start), Backup: backup})
+
 	return nil
 }
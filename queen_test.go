@@ -0,0 +1,890 @@
+package queen_test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/honeynil/queen"
+	"github.com/honeynil/queen/drivers/mock"
+)
+
+func noop(ctx context.Context, tx *sql.Tx) error { return nil }
+
+// newMockQueen creates a Queen with the given versions registered as no-op migrations.
+func newMockQueen(t *testing.T, versions ...string) (*queen.Queen, *mock.Driver) {
+	t.Helper()
+
+	driver := mock.New()
+	q := queen.New(driver)
+	for _, v := range versions {
+		q.MustAdd(queen.M{
+			Version:        v,
+			Name:           "migration_" + v,
+			ManualChecksum: "v1",
+			UpFunc:         noop,
+			DownFunc:       noop,
+		})
+	}
+
+	return q, driver
+}
+
+func TestPending(t *testing.T) {
+	q, _ := newMockQueen(t, "10", "2", "1")
+	ctx := context.Background()
+
+	pending, err := q.Pending(ctx)
+	if err != nil {
+		t.Fatalf("Pending failed: %v", err)
+	}
+
+	want := []string{"1", "2", "10"}
+	if len(pending) != len(want) {
+		t.Fatalf("Expected %d pending, got %d", len(want), len(pending))
+	}
+	for i, m := range pending {
+		if m.Version != want[i] {
+			t.Errorf("pending[%d] = %s, want %s", i, m.Version, want[i])
+		}
+	}
+
+	if err := q.UpSteps(ctx, 2); err != nil {
+		t.Fatalf("UpSteps failed: %v", err)
+	}
+
+	pending, err = q.Pending(ctx)
+	if err != nil {
+		t.Fatalf("Pending failed: %v", err)
+	}
+	if len(pending) != 1 || pending[0].Version != "10" {
+		t.Errorf("Expected only 10 pending, got %v", pending)
+	}
+}
+
+func TestApplied(t *testing.T) {
+	q, _ := newMockQueen(t, "001", "002")
+	ctx := context.Background()
+
+	applied, err := q.Applied(ctx)
+	if err != nil {
+		t.Fatalf("Applied failed: %v", err)
+	}
+	if len(applied) != 0 {
+		t.Errorf("Expected no applied migrations, got %d", len(applied))
+	}
+
+	if err := q.Up(ctx); err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+
+	applied, err = q.Applied(ctx)
+	if err != nil {
+		t.Fatalf("Applied failed: %v", err)
+	}
+	if len(applied) != 2 {
+		t.Errorf("Expected 2 applied migrations, got %d", len(applied))
+	}
+}
+
+func TestPendingNoDriver(t *testing.T) {
+	q := queen.New(nil)
+
+	if _, err := q.Pending(context.Background()); !errors.Is(err, queen.ErrNoDriver) {
+		t.Errorf("Expected ErrNoDriver, got %v", err)
+	}
+	if _, err := q.Applied(context.Background()); !errors.Is(err, queen.ErrNoDriver) {
+		t.Errorf("Expected ErrNoDriver, got %v", err)
+	}
+}
+
+func TestSubmitFlush(t *testing.T) {
+	q, driver := newMockQueen(t, "001")
+	ctx := context.Background()
+
+	if err := q.Submit(queen.M{Version: "002", Name: "plugin", ManualChecksum: "v1", UpFunc: noop}); err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+
+	err := q.Submit(queen.M{Version: "001", Name: "dup", UpFunc: noop})
+	if !errors.Is(err, queen.ErrVersionConflict) {
+		t.Errorf("Expected ErrVersionConflict for registered version, got %v", err)
+	}
+
+	err = q.Submit(queen.M{Version: "002", Name: "dup", UpFunc: noop})
+	if !errors.Is(err, queen.ErrVersionConflict) {
+		t.Errorf("Expected ErrVersionConflict for queued version, got %v", err)
+	}
+
+	if driver.AppliedCount() != 0 {
+		t.Fatalf("Submit must not apply migrations, got %d applied", driver.AppliedCount())
+	}
+
+	if err := q.Flush(ctx); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+
+	if !driver.HasVersion("001") || !driver.HasVersion("002") {
+		t.Error("Expected registered and submitted migrations to be applied")
+	}
+}
+
+func TestSubmitDuringFlush(t *testing.T) {
+	q, driver := newMockQueen(t, "000")
+	ctx := context.Background()
+
+	const plugins, perPlugin = 4, 25
+	var wg sync.WaitGroup
+	for p := range plugins {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range perPlugin {
+				m := queen.M{Version: fmt.Sprintf("%d%03d", p+1, i), Name: "plugin", UpFunc: noop}
+				if err := q.Submit(m); err != nil {
+					t.Errorf("Submit %s failed: %v", m.Version, err)
+				}
+			}
+		}()
+	}
+
+	done := make(chan struct{})
+	flushed := make(chan error, 1)
+	go func() {
+		for {
+			select {
+			case <-done:
+				flushed <- nil
+				return
+			default:
+			}
+			if err := q.Flush(ctx); err != nil {
+				flushed <- err
+				return
+			}
+		}
+	}()
+
+	wg.Wait()
+	close(done)
+	if err := <-flushed; err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	if err := q.Flush(ctx); err != nil {
+		t.Fatalf("Final Flush failed: %v", err)
+	}
+
+	if got, want := driver.AppliedCount(), plugins*perPlugin+1; got != want {
+		t.Errorf("Expected %d applied, got %d", want, got)
+	}
+}
+
+func TestFlushConflict(t *testing.T) {
+	q, driver := newMockQueen(t)
+
+	if err := q.Submit(queen.M{Version: "001", Name: "plugin", UpFunc: noop}); err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+	q.MustAdd(queen.M{Version: "001", Name: "core", UpFunc: noop})
+
+	if err := q.Flush(context.Background()); !errors.Is(err, queen.ErrVersionConflict) {
+		t.Errorf("Expected ErrVersionConflict, got %v", err)
+	}
+	if driver.AppliedCount() != 0 {
+		t.Errorf("Expected nothing applied, got %d", driver.AppliedCount())
+	}
+}
+
+func TestRemoveReplace(t *testing.T) {
+	q, driver := newMockQueen(t, "001", "002", "003")
+	ctx := context.Background()
+
+	if err := q.Remove("002"); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	if err := q.Remove("002"); !errors.Is(err, queen.ErrMigrationNotFound) {
+		t.Errorf("Expected ErrMigrationNotFound for a removed version, got %v", err)
+	}
+
+	replaced := false
+	err := q.Replace(queen.M{Version: "003", Name: "substitute", ManualChecksum: "v2",
+		UpFunc: func(ctx context.Context, tx *sql.Tx) error {
+			replaced = true
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("Replace failed: %v", err)
+	}
+	if err := q.Replace(queen.M{Version: "004", Name: "unknown", UpFunc: noop}); !errors.Is(err, queen.ErrMigrationNotFound) {
+		t.Errorf("Expected ErrMigrationNotFound for an unknown version, got %v", err)
+	}
+	if err := q.Replace(queen.M{Version: "001"}); !errors.Is(err, queen.ErrInvalidMigration) {
+		t.Errorf("Expected ErrInvalidMigration for an invalid migration, got %v", err)
+	}
+
+	if err := q.Up(ctx); err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+	if driver.HasVersion("002") || !driver.HasVersion("003") || !replaced {
+		t.Error("Expected 002 skipped and the substitute of 003 applied")
+	}
+}
+
+// counterMigration is a Migrator carrying its state as fields.
+type counterMigration struct {
+	version string
+	count   *int
+}
+
+func (c counterMigration) Version() string  { return c.version }
+func (c counterMigration) Name() string     { return "count_" + c.version }
+func (c counterMigration) Checksum() string { return "counter-v1" }
+
+func (c counterMigration) Up(ctx context.Context, tx *sql.Tx) error {
+	*c.count++
+	return nil
+}
+
+func (c counterMigration) Down(ctx context.Context, tx *sql.Tx) error {
+	*c.count--
+	return nil
+}
+
+func TestAddAll(t *testing.T) {
+	q, _ := newMockQueen(t, "001")
+
+	err := q.AddAll(
+		queen.M{Version: "002", Name: "ok", UpFunc: noop},
+		queen.M{Version: "001", Name: "registered", UpFunc: noop},
+		queen.M{Version: "003", Name: "no_up"},
+		queen.M{Version: "004", Name: "first", UpFunc: noop},
+		queen.M{Version: "004", Name: "second", UpFunc: noop},
+	)
+
+	var errs interface{ Unwrap() []error }
+	if !errors.As(err, &errs) || len(errs.Unwrap()) != 3 {
+		t.Fatalf("Expected 3 joined errors, got %v", err)
+	}
+	if !errors.Is(err, queen.ErrVersionConflict) || !errors.Is(err, queen.ErrInvalidMigration) {
+		t.Errorf("Expected ErrVersionConflict and ErrInvalidMigration, got %v", err)
+	}
+	for _, version := range []string{"001", "003", "004"} {
+		if !strings.Contains(err.Error(), "migration "+version) {
+			t.Errorf("Expected %s reported in %v", version, err)
+		}
+	}
+
+	if err := q.Add(queen.M{Version: "002", Name: "ok", UpFunc: noop}); err != nil {
+		t.Errorf("Expected nothing registered by the failed AddAll, got %v", err)
+	}
+
+	if err := q.AddAll(queen.M{Version: "003", Name: "a", UpFunc: noop}, queen.M{Version: "004", Name: "b", UpFunc: noop}); err != nil {
+		t.Fatalf("AddAll failed: %v", err)
+	}
+	if err := q.Remove("004"); err != nil {
+		t.Errorf("Expected 004 registered: %v", err)
+	}
+}
+
+func TestVersionPattern(t *testing.T) {
+	q := queen.NewWithConfig(mock.New(), &queen.Config{
+		VersionPattern: regexp.MustCompile(`^\d{3}$`),
+		ValidateVersion: func(version string) error {
+			if version == "000" {
+				return errors.New("versions start at 001")
+			}
+			return nil
+		},
+	})
+
+	if err := q.Add(queen.M{Version: "001", Name: "ok", UpFunc: noop}); err != nil {
+		t.Errorf("Add failed for a matching version: %v", err)
+	}
+	if err := q.Module("billing").Add(queen.M{Version: "001", Name: "ok", UpFunc: noop}); err != nil {
+		t.Errorf("Add failed for a matching module version: %v", err)
+	}
+
+	err := q.Add(queen.M{Version: "01", Name: "short", UpFunc: noop})
+	if !errors.Is(err, queen.ErrInvalidMigration) || !strings.Contains(err.Error(), `version "01" doesn't match ^\d{3}$`) {
+		t.Errorf("Expected a pattern mismatch, got %v", err)
+	}
+
+	err = q.Add(queen.M{Version: "000", Name: "zero", UpFunc: noop})
+	if !errors.Is(err, queen.ErrInvalidMigration) || !strings.Contains(err.Error(), "versions start at 001") {
+		t.Errorf("Expected the validator's error, got %v", err)
+	}
+
+	err = q.Submit(queen.M{Version: "02", Name: "plugin", UpFunc: noop})
+	if !errors.Is(err, queen.ErrInvalidMigration) {
+		t.Errorf("Expected Submit to enforce the pattern, got %v", err)
+	}
+}
+
+func TestAddMigrator(t *testing.T) {
+	driver := mock.New()
+	q := queen.New(driver)
+	ctx := context.Background()
+
+	count := 0
+	q.MustAddMigrator(counterMigration{version: "001", count: &count})
+	q.MustAddMigrator(counterMigration{version: "002", count: &count})
+	if err := q.AddMigrator(counterMigration{version: "002", count: &count}); !errors.Is(err, queen.ErrVersionConflict) {
+		t.Errorf("Expected ErrVersionConflict for a duplicate Migrator, got %v", err)
+	}
+
+	if err := q.Up(ctx); err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("Expected 2 Up calls, got %d", count)
+	}
+
+	if err := q.Down(ctx, 1); err != nil {
+		t.Fatalf("Down failed: %v", err)
+	}
+	if count != 1 || driver.HasVersion("002") {
+		t.Errorf("Expected 002 rolled back, count = %d", count)
+	}
+
+	m := queen.FromMigrator(counterMigration{version: "003", count: &count})
+	if m.Name != "count_003" || m.ManualChecksum != "counter-v1" {
+		t.Errorf("FromMigrator() = %q, %q; want count_003, counter-v1", m.Name, m.ManualChecksum)
+	}
+}
+
+func TestCurrentVersion(t *testing.T) {
+	q, _ := newMockQueen(t, "1", "2", "10")
+	ctx := context.Background()
+
+	if _, err := q.CurrentVersion(ctx); !errors.Is(err, queen.ErrNoneApplied) {
+		t.Errorf("Expected ErrNoneApplied, got %v", err)
+	}
+
+	if err := q.Up(ctx); err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+
+	version, err := q.CurrentVersion(ctx)
+	if err != nil {
+		t.Fatalf("CurrentVersion failed: %v", err)
+	}
+	if version != "10" {
+		t.Errorf("Expected current version 10, got %s", version)
+	}
+}
+
+func TestRollbackBatch(t *testing.T) {
+	q, driver := newMockQueen(t, "001", "002")
+	ctx := context.Background()
+
+	if err := q.Up(ctx); err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+
+	q.MustAdd(queen.M{Version: "003", Name: "third", ManualChecksum: "v1", UpFunc: noop, DownFunc: noop})
+	q.MustAdd(queen.M{Version: "004", Name: "fourth", ManualChecksum: "v1", UpFunc: noop, DownFunc: noop})
+	if err := q.Up(ctx); err != nil {
+		t.Fatalf("second Up failed: %v", err)
+	}
+
+	applied, err := q.Applied(ctx)
+	if err != nil {
+		t.Fatalf("Applied failed: %v", err)
+	}
+	for _, a := range applied {
+		want := 1
+		if a.Version == "003" || a.Version == "004" {
+			want = 2
+		}
+		if a.Batch != want {
+			t.Errorf("version %s batch = %d, want %d", a.Version, a.Batch, want)
+		}
+	}
+
+	if err := q.RollbackBatch(ctx); err != nil {
+		t.Fatalf("RollbackBatch failed: %v", err)
+	}
+
+	if driver.AppliedCount() != 2 || driver.HasVersion("003") || driver.HasVersion("004") {
+		t.Errorf("Expected only the first batch to remain applied")
+	}
+
+	if err := q.RollbackBatch(ctx); err != nil {
+		t.Fatalf("second RollbackBatch failed: %v", err)
+	}
+	if driver.AppliedCount() != 0 {
+		t.Errorf("Expected no migrations applied, got %d", driver.AppliedCount())
+	}
+}
+
+func TestReportingViewsUnsupported(t *testing.T) {
+	q := queen.NewWithConfig(mock.New(), &queen.Config{ReportingViews: true})
+	q.MustAdd(queen.M{Version: "001", Name: "first", UpFunc: noop})
+
+	if err := q.Up(context.Background()); !errors.Is(err, queen.ErrUnsupported) {
+		t.Errorf("Expected ErrUnsupported, got %v", err)
+	}
+}
+
+func TestMigrationTimeout(t *testing.T) {
+	driver := mock.New()
+	q := queen.New(driver)
+	q.MustAdd(queen.M{
+		Version: "001",
+		Name:    "slow",
+		Timeout: 10 * time.Millisecond,
+		UpFunc: func(ctx context.Context, tx *sql.Tx) error {
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	})
+
+	err := q.Up(context.Background())
+	if !errors.Is(err, queen.ErrTimeout) {
+		t.Fatalf("Expected ErrTimeout, got %v", err)
+	}
+	if driver.HasVersion("001") {
+		t.Error("Expected timed out migration not to be recorded")
+	}
+}
+
+func TestCheckPermissionsUnsupported(t *testing.T) {
+	q := queen.New(mock.New())
+	if err := q.CheckPermissions(context.Background()); !errors.Is(err, queen.ErrUnsupported) {
+		t.Errorf("Expected ErrUnsupported, got %v", err)
+	}
+}
+
+func TestTemplateVarsInvalid(t *testing.T) {
+	q := queen.NewWithConfig(mock.New(), &queen.Config{
+		TemplateVars: map[string]any{"TablePrefix": "app_"},
+	})
+
+	tests := map[string]string{
+		"missing variable": "CREATE TABLE {{.Schema}}.users (id INT)",
+		"syntax error":     "CREATE TABLE {{.TablePrefix users (id INT)",
+	}
+	for name, upSQL := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := q.Add(queen.M{Version: "001", Name: "users", UpSQL: upSQL})
+			if !errors.Is(err, queen.ErrInvalidMigration) {
+				t.Errorf("Expected ErrInvalidMigration, got %v", err)
+			}
+		})
+	}
+
+	// Without TemplateVars, SQL is used verbatim
+	q = queen.New(mock.New())
+	if err := q.Add(queen.M{Version: "001", Name: "users", UpSQL: tests["missing variable"]}); err != nil {
+		t.Errorf("Add without TemplateVars failed: %v", err)
+	}
+}
+
+func TestEnvironments(t *testing.T) {
+	driver := mock.New()
+	q := queen.NewWithConfig(driver, &queen.Config{Environment: "production"})
+	ctx := context.Background()
+
+	q.MustAdd(queen.M{Version: "001", Name: "create_users", UpFunc: noop})
+	q.MustAdd(queen.M{Version: "002", Name: "seed_users", UpFunc: noop, Environments: []string{"dev", "staging"}})
+	q.MustAdd(queen.M{Version: "003", Name: "add_index", UpFunc: noop, Environments: []string{"production"}})
+
+	if err := q.Up(ctx); err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+
+	if !driver.HasVersion("001") || driver.HasVersion("002") || !driver.HasVersion("003") {
+		t.Errorf("Expected 001 and 003 applied and 002 skipped")
+	}
+
+	statuses, err := q.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status failed: %v", err)
+	}
+	if statuses[1].Status != queen.StatusSkipped {
+		t.Errorf("Expected 002 to be skipped, got %s", statuses[1].Status)
+	}
+
+	pending, err := q.Pending(ctx)
+	if err != nil {
+		t.Fatalf("Pending failed: %v", err)
+	}
+	if len(pending) != 0 {
+		t.Errorf("Expected no pending migrations, got %d", len(pending))
+	}
+}
+
+func TestStatusDuration(t *testing.T) {
+	q := queen.New(mock.New())
+	ctx := context.Background()
+
+	q.MustAdd(queen.M{
+		Version: "001",
+		Name:    "slow",
+		UpFunc: func(ctx context.Context, tx *sql.Tx) error {
+			time.Sleep(5 * time.Millisecond)
+			return nil
+		},
+	})
+	q.MustAdd(queen.M{Version: "002", Name: "pending", UpFunc: noop})
+
+	if err := q.UpSteps(ctx, 1); err != nil {
+		t.Fatalf("UpSteps failed: %v", err)
+	}
+
+	statuses, err := q.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status failed: %v", err)
+	}
+	if statuses[0].Duration < 5*time.Millisecond {
+		t.Errorf("Expected duration of at least 5ms, got %v", statuses[0].Duration)
+	}
+	if statuses[1].Duration != 0 {
+		t.Errorf("Expected zero duration for pending migration, got %v", statuses[1].Duration)
+	}
+}
+
+func TestStatusBuildInfo(t *testing.T) {
+	config := queen.DefaultConfig()
+	config.BuildInfo = "v1.4.0 3f2a9c1"
+
+	q := queen.NewWithConfig(mock.New(), config)
+	ctx := context.Background()
+
+	q.MustAdd(queen.M{Version: "001", Name: "first", UpFunc: noop})
+	q.MustAdd(queen.M{Version: "002", Name: "pending", UpFunc: noop})
+
+	if err := q.UpSteps(ctx, 1); err != nil {
+		t.Fatalf("UpSteps failed: %v", err)
+	}
+
+	statuses, err := q.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status failed: %v", err)
+	}
+	if statuses[0].BuildInfo != "v1.4.0 3f2a9c1" {
+		t.Errorf("Expected build info of applied migration, got %q", statuses[0].BuildInfo)
+	}
+	if statuses[1].BuildInfo != "" {
+		t.Errorf("Expected no build info for pending migration, got %q", statuses[1].BuildInfo)
+	}
+}
+
+func TestStatusJSON(t *testing.T) {
+	q, _ := newMockQueen(t, "001", "002")
+	ctx := context.Background()
+
+	if err := q.UpSteps(ctx, 1); err != nil {
+		t.Fatalf("UpSteps failed: %v", err)
+	}
+
+	data, err := q.StatusJSON(ctx)
+	if err != nil {
+		t.Fatalf("StatusJSON failed: %v", err)
+	}
+
+	var raw []map[string]any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		t.Fatalf("StatusJSON returned invalid JSON: %v", err)
+	}
+	if len(raw) != 2 || raw[0]["version"] != "001" || raw[0]["status"] != "applied" || raw[1]["status"] != "pending" {
+		t.Errorf("StatusJSON = %s", data)
+	}
+	if _, ok := raw[0]["applied_at"]; !ok {
+		t.Errorf("Expected applied_at for the applied migration, got %s", data)
+	}
+	if _, ok := raw[1]["applied_at"]; ok {
+		t.Errorf("Expected no applied_at for the pending migration, got %s", data)
+	}
+
+	var statuses []queen.MigrationStatus
+	if err := json.Unmarshal(data, &statuses); err != nil {
+		t.Fatalf("Unmarshal into MigrationStatus failed: %v", err)
+	}
+	if statuses[0].Status != queen.StatusApplied || statuses[1].Status != queen.StatusPending {
+		t.Errorf("Expected statuses to round-trip, got %v and %v", statuses[0].Status, statuses[1].Status)
+	}
+
+	var b strings.Builder
+	if err := queen.WriteStatusCSV(&b, statuses); err != nil {
+		t.Fatalf("WriteStatusCSV failed: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "version,name,status,applied_at,") || !strings.HasPrefix(lines[2], "002,migration_002,pending,,0,") {
+		t.Errorf("WriteStatusCSV wrote:\n%s", b.String())
+	}
+}
+
+func TestHistory(t *testing.T) {
+	q := queen.New(mock.New())
+	ctx := context.Background()
+
+	q.MustAdd(queen.M{Version: "001", Name: "first", UpFunc: noop, DownFunc: noop})
+	q.MustAdd(queen.M{
+		Version: "002",
+		Name:    "broken",
+		UpFunc: func(ctx context.Context, tx *sql.Tx) error {
+			return errors.New("boom")
+		},
+	})
+
+	if err := q.UpSteps(ctx, 1); err != nil {
+		t.Fatalf("UpSteps failed: %v", err)
+	}
+	if err := q.Up(ctx); err == nil {
+		t.Fatal("Expected Up to fail")
+	}
+	if err := q.Down(ctx, 1); err != nil {
+		t.Fatalf("Down failed: %v", err)
+	}
+
+	history, err := q.History(ctx)
+	if err != nil {
+		t.Fatalf("History failed: %v", err)
+	}
+
+	want := []struct {
+		version string
+		down    bool
+		failed  bool
+	}{
+		{"001", false, false},
+		{"002", false, true},
+		{"001", true, false},
+	}
+	if len(history) != len(want) {
+		t.Fatalf("Expected %d history entries, got %d", len(want), len(history))
+	}
+	for i, w := range want {
+		e := history[i]
+		if e.Version != w.version || e.Down != w.down || (e.Error != "") != w.failed {
+			t.Errorf("Entry %d = %+v; want version %s, down %v, failed %v", i, e, w.version, w.down, w.failed)
+		}
+		if e.RunID == "" {
+			t.Errorf("Entry %d has no run ID", i)
+		}
+	}
+
+	// Applied state no longer shows any of it
+	if applied, _ := q.Applied(ctx); len(applied) != 0 {
+		t.Errorf("Expected no applied migrations, got %d", len(applied))
+	}
+}
+
+func TestIDGenerator(t *testing.T) {
+	id := queen.ULID.NewID()
+	if len(id) != 26 || strings.Trim(id, "0123456789ABCDEFGHJKMNPQRSTVWXYZ") != "" {
+		t.Errorf("ULID = %q, want 26 Crockford base32 characters", id)
+	}
+	time.Sleep(2 * time.Millisecond)
+	if later := queen.ULID.NewID(); later <= id {
+		t.Errorf("ULIDs don't sort by time: %q <= %q", later, id)
+	}
+
+	config := queen.DefaultConfig()
+	config.IDGenerator = queen.IDGeneratorFunc(func() string { return "req-42" })
+
+	q := queen.NewWithConfig(mock.New(), config)
+	q.MustAdd(queen.M{Version: "001", Name: "first", UpFunc: noop})
+	ctx := context.Background()
+	if err := q.Up(ctx); err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+
+	history, err := q.History(ctx)
+	if err != nil {
+		t.Fatalf("History failed: %v", err)
+	}
+	if len(history) != 1 || history[0].RunID != "req-42" {
+		t.Errorf("history = %+v, want one entry of run req-42", history)
+	}
+}
+
+// positionDriver is a mock driver whose replication position advances by
+// one on every read, or fails with err.
+type positionDriver struct {
+	*mock.Driver
+	offset int64
+	err    error
+}
+
+func (d *positionDriver) ReplicationPosition(ctx context.Context) (*queen.ReplicationPosition, error) {
+	if d.err != nil {
+		return nil, d.err
+	}
+	d.offset++
+	return &queen.ReplicationPosition{File: "binlog.000001", Offset: d.offset}, nil
+}
+
+// reportSink keeps the last archived run report.
+type reportSink struct{ report *queen.RunReport }
+
+func (s *reportSink) Archive(ctx context.Context, report *queen.RunReport) error {
+	s.report = report
+	return nil
+}
+
+func TestReplicationPosition(t *testing.T) {
+	ctx := context.Background()
+	driver := &positionDriver{Driver: mock.New()}
+	sink := &reportSink{}
+
+	q := queen.NewWithConfig(driver, &queen.Config{Archive: sink})
+	q.MustAdd(queen.M{Version: "001", Name: "first", UpFunc: noop})
+	if err := q.Up(ctx); err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+
+	start, end := sink.report.StartPosition, sink.report.EndPosition
+	if start == nil || end == nil || start.Offset != 1 || end.Offset != 2 {
+		t.Errorf("Expected positions 1 and 2, got %+v and %+v", start, end)
+	}
+
+	driver.err = errors.New("access denied")
+	q.MustAdd(queen.M{Version: "002", Name: "second", UpFunc: noop})
+	if err := q.Up(ctx); err != nil {
+		t.Fatalf("Up failed without privileges for the position: %v", err)
+	}
+	if sink.report.StartPosition != nil || len(sink.report.Warnings) != 2 {
+		t.Errorf("Expected no position and 2 warnings, got %+v and %v", sink.report.StartPosition, sink.report.Warnings)
+	}
+}
+
+func TestMetadata(t *testing.T) {
+	config := queen.DefaultConfig()
+	config.Metadata = map[string]any{"app_version": "1.4.2", "deploy": 7}
+
+	driver := mock.New()
+	q := queen.NewWithConfig(driver, config)
+	q.MustAdd(queen.M{Version: "001", Name: "first", UpFunc: noop})
+	ctx := context.Background()
+	if err := q.Up(ctx); err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+
+	// A new instance reads the metadata back through the serializer
+	q2 := queen.New(driver)
+	q2.MustAdd(queen.M{Version: "001", Name: "first", UpFunc: noop})
+	statuses, err := q2.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status failed: %v", err)
+	}
+	metadata := statuses[0].Metadata
+	if metadata["app_version"] != "1.4.2" || metadata["deploy"] != float64(7) {
+		t.Errorf("Metadata = %v, want app_version 1.4.2 and deploy 7", metadata)
+	}
+}
+
+func TestLint(t *testing.T) {
+	config := queen.DefaultConfig()
+	config.TemplateVars = map[string]any{"Table": "users"}
+
+	q := queen.NewWithConfig(mock.New(), config)
+	q.MustAdd(queen.M{Version: "010", Name: "drop_users", UpSQL: "DROP TABLE {{.Table}}"})
+	q.MustAdd(queen.M{Version: "002", Name: "create_orders", UpSQL: "CREATE TABLE Orders (id INT)"})
+	q.MustAdd(queen.M{Version: "003", Name: "backfill", UpFunc: noop})
+
+	var got []string
+	for _, f := range q.Lint() {
+		got = append(got, f.String())
+	}
+	want := []string{
+		"002 (up): lowercase-identifiers: identifier Orders is not lowercase, quote it or use orders",
+		"010 (up): missing-if-exists: DROP TABLE without IF EXISTS",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Lint() = %q, want %q", got, want)
+	}
+}
+
+// minimalDriver implements only Driver, hiding the optional interfaces of
+// the driver it wraps.
+type minimalDriver struct {
+	queen.Driver
+}
+
+func TestUp_WithoutMetaRecorder(t *testing.T) {
+	ctx := context.Background()
+
+	config := queen.DefaultConfig()
+	config.SkipLock = true
+	driver := mock.New()
+	q := queen.NewWithConfig(minimalDriver{driver}, config)
+	q.MustAdd(queen.M{Version: "001", Name: "first", UpFunc: noop})
+	q.MustAdd(queen.M{Version: "002", Name: "second", UpFunc: func(ctx context.Context, tx *sql.Tx) error {
+		return errors.New("boom")
+	}})
+
+	if err := q.Up(ctx); err == nil {
+		t.Fatal("expected Up to fail")
+	}
+
+	// Recorded with Record only: no dirty marker is left behind
+	applied, err := driver.GetApplied(ctx)
+	if err != nil {
+		t.Fatalf("GetApplied failed: %v", err)
+	}
+	if len(applied) != 1 || applied[0].Version != "001" || applied[0].Batch != 0 {
+		t.Errorf("expected only 001 recorded without a batch, got %+v", applied)
+	}
+}
+
+// noLockDriver implements Locker but reports it unsupported.
+type noLockDriver struct {
+	*mock.Driver
+}
+
+func (noLockDriver) SupportsFeature(f queen.Feature) bool {
+	return f != queen.FeatureLocking
+}
+
+func TestFeatures(t *testing.T) {
+	ctx := context.Background()
+
+	q := queen.New(mock.New())
+	if !slices.Contains(q.Features(), queen.FeatureLocking) || !queen.Supports(mock.New(), queen.FeatureNoTransaction) {
+		t.Errorf("Features() = %v, want locking and no-transaction", q.Features())
+	}
+
+	// Without Locker, migrating requires SkipLock
+	q = queen.New(minimalDriver{mock.New()})
+	if len(q.Features()) != 0 {
+		t.Errorf("Features() = %v, want none", q.Features())
+	}
+	q.MustAdd(queen.M{Version: "001", Name: "first", UpFunc: noop})
+	if err := q.Up(ctx); !errors.Is(err, queen.ErrUnsupported) {
+		t.Errorf("Up without Locker: expected ErrUnsupported, got %v", err)
+	}
+
+	config := queen.DefaultConfig()
+	config.SkipLock = true
+	q = queen.NewWithConfig(minimalDriver{mock.New()}, config)
+	q.MustAdd(queen.M{Version: "001", Name: "first", UpFunc: noop})
+	q.MustAdd(queen.M{Version: "002", Name: "index", UpSQL: "CREATE INDEX CONCURRENTLY i ON t (c)", NoTransaction: true})
+	if err := q.Up(ctx); !errors.Is(err, queen.ErrUnsupported) {
+		t.Errorf("Up without NoTxExecer: expected ErrUnsupported, got %v", err)
+	}
+	if applied, _ := q.Applied(ctx); len(applied) != 0 {
+		t.Errorf("expected nothing applied before failing, got %v", applied)
+	}
+
+	// A FeatureReporter can turn off an implemented feature
+	driver := noLockDriver{mock.New()}
+	if queen.Supports(driver, queen.FeatureLocking) || !queen.Supports(driver, queen.FeatureHistory) {
+		t.Error("expected locking off and history on")
+	}
+	q = queen.New(driver)
+	q.MustAdd(queen.M{Version: "001", Name: "first", UpFunc: noop})
+	if err := q.Up(ctx); !errors.Is(err, queen.ErrUnsupported) || driver.IsLocked() {
+		t.Errorf("Up with locking reported unsupported: expected ErrUnsupported, got %v", err)
+	}
+}
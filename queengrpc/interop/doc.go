@@ -0,0 +1,4 @@
+// Package interop tests that grpc-go clients can call the queengrpc
+// service. It is a module of its own so that queen doesn't depend on
+// grpc-go.
+package interop
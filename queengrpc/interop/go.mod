@@ -0,0 +1,22 @@
+module github.com/honeynil/queen/queengrpc/interop
+
+go 1.25.5
+
+require (
+	github.com/honeynil/queen v0.0.0-00010101000000-000000000000
+	google.golang.org/grpc v1.84.0
+	google.golang.org/protobuf v1.36.11
+)
+
+require (
+	golang.org/x/net v0.57.0 // indirect
+	golang.org/x/sys v0.47.0 // indirect
+	golang.org/x/text v0.40.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20260706201446-f0a921348800 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+// This module only tests queengrpc against grpc-go, keeping grpc-go out of
+// queen's dependencies. It is never published, so it always builds against
+// the queen in this repository.
+replace github.com/honeynil/queen => ../../
@@ -0,0 +1,179 @@
+package interop
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/honeynil/queen"
+	"github.com/honeynil/queen/drivers/mock"
+	"github.com/honeynil/queen/queengrpc"
+)
+
+func noop(ctx context.Context, tx *sql.Tx) error { return nil }
+
+// rawCodec passes messages encoded with protowire through as they are, so
+// the test needs no generated code.
+type rawCodec struct{}
+
+func (rawCodec) Marshal(v any) ([]byte, error) { return v.([]byte), nil }
+
+func (rawCodec) Unmarshal(data []byte, v any) error {
+	*v.(*[]byte) = append([]byte(nil), data...)
+	return nil
+}
+
+func (rawCodec) Name() string { return "proto" }
+
+// dial serves s over unencrypted HTTP/2 and returns a grpc-go connection
+// to it.
+func dial(t *testing.T, s *queengrpc.Server) *grpc.ClientConn {
+	t.Helper()
+
+	srv := httptest.NewUnstartedServer(s)
+	srv.Config.Protocols = new(http.Protocols)
+	srv.Config.Protocols.SetUnencryptedHTTP2(true)
+	srv.Start()
+	t.Cleanup(srv.Close)
+
+	conn, err := grpc.NewClient(strings.TrimPrefix(srv.URL, "http://"),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return conn
+}
+
+// invoke calls method with req and returns the fields of the response.
+func invoke(ctx context.Context, conn *grpc.ClientConn, method string, req []byte) (map[protowire.Number][][]byte, error) {
+	var resp []byte
+	err := conn.Invoke(ctx, "/queen.v1.Migrations/"+method, req, &resp, grpc.ForceCodec(rawCodec{}))
+	if err != nil {
+		return nil, err
+	}
+	return fields(resp)
+}
+
+// fields decodes a message into its length-delimited and varint fields,
+// varints encoded as decimal strings.
+func fields(b []byte) (map[protowire.Number][][]byte, error) {
+	fs := make(map[protowire.Number][][]byte)
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		switch typ {
+		case protowire.BytesType:
+			v, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			fs[num] = append(fs[num], v)
+			b = b[n:]
+		case protowire.VarintType:
+			v, n := protowire.ConsumeVarint(b)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			fs[num] = append(fs[num], fmt.Appendf(nil, "%d", v))
+			b = b[n:]
+		default:
+			n := protowire.ConsumeFieldValue(num, typ, b)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			b = b[n:]
+		}
+	}
+	return fs, nil
+}
+
+// runRequest encodes an UpRequest or DownRequest.
+func runRequest(versions ...string) []byte {
+	var b []byte
+	for _, v := range versions {
+		b = protowire.AppendTag(b, 1, protowire.BytesType)
+		b = protowire.AppendString(b, v)
+	}
+	return b
+}
+
+func TestGRPCGoClient(t *testing.T) {
+	q := queen.New(mock.New())
+	t.Cleanup(func() { _ = q.Close() })
+	q.MustAdd(queen.M{Version: "001", Name: "create_users", UpFunc: noop, DownFunc: noop})
+	q.MustAdd(queen.M{Version: "002", Name: "create_posts", UpFunc: noop, DownFunc: noop})
+
+	conn := dial(t, queengrpc.NewServer(q, queengrpc.Options{Token: "secret"}))
+	ctx := context.Background()
+	authed := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer secret")
+
+	if _, err := invoke(ctx, conn, "Status", nil); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Status without a token = %v; want Unauthenticated", err)
+	}
+
+	resp, err := invoke(authed, conn, "Status", nil)
+	if err != nil {
+		t.Fatalf("Status failed: %v", err)
+	}
+	var versions []string
+	for _, m := range resp[1] {
+		mfs, err := fields(m)
+		if err != nil {
+			t.Fatalf("Decoding MigrationStatus failed: %v", err)
+		}
+		versions = append(versions, string(mfs[1][0])+" "+string(mfs[3][0]))
+	}
+	if want := []string{"001 pending", "002 pending"}; !slices.Equal(versions, want) {
+		t.Errorf("Status = %q; want %q", versions, want)
+	}
+
+	// Errors carry their code and message
+	_, err = invoke(authed, conn, "Up", runRequest("002"))
+	if st, _ := status.FromError(err); st.Code() != codes.FailedPrecondition || !strings.Contains(st.Message(), "the plan is 001") {
+		t.Errorf("Up(002) = %v; want FailedPrecondition naming the plan", err)
+	}
+
+	resp, err = invoke(authed, conn, "Up", runRequest("001", "002"))
+	if err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+	if string(resp[1][0]) != "up" || len(resp[2]) != 2 {
+		t.Errorf("Up response = %q; want up with 001, 002", resp)
+	}
+
+	resp, err = invoke(authed, conn, "Down", runRequest("002"))
+	if err != nil {
+		t.Fatalf("Down failed: %v", err)
+	}
+	if string(resp[1][0]) != "down" || len(resp[2]) != 1 || string(resp[2][0]) != "002" {
+		t.Errorf("Down response = %q; want down with 002", resp)
+	}
+
+	// An empty message decodes as one with every field unset
+	resp, err = invoke(authed, conn, "LockInfo", nil)
+	if err != nil || len(resp) != 0 {
+		t.Errorf("LockInfo = %q, %v; want an empty message", resp, err)
+	}
+
+	if _, err := invoke(authed, conn, "Missing", nil); status.Code(err) != codes.Unimplemented {
+		t.Errorf("Missing = %v; want Unimplemented", err)
+	}
+}
@@ -0,0 +1,331 @@
+// Package queengrpc serves the queen.v1.Migrations gRPC service defined in
+// queen.proto, so a central deployment orchestrator can check status,
+// apply and roll back migrations and see who holds the lock uniformly
+// across services.
+//
+// The server speaks the gRPC protocol over net/http and needs no gRPC
+// dependency. gRPC runs over HTTP/2: serve it with TLS, or enable
+// unencrypted HTTP/2 inside a trusted network:
+//
+//	srv := &http.Server{
+//	    Addr:      ":9090",
+//	    Handler:   queengrpc.NewServer(q, queengrpc.Options{Token: os.Getenv("QUEEN_GRPC_TOKEN")}),
+//	    Protocols: new(http.Protocols),
+//	}
+//	srv.Protocols.SetUnencryptedHTTP2(true)
+//	err := srv.ListenAndServe()
+//
+// Clients authenticate with Options.Token as "authorization: Bearer <token>"
+// metadata, or pass Options.Authorize. Up and Down carry the same
+// safeguards as the httpqueen admin endpoints: the versions of the request
+// must match what the call would run, destructive migrations need
+// allow_destructive, and one run is allowed at a time.
+//
+// The server implements the unary calls of gRPC over HTTP/2 that the
+// service needs, and is tested against grpc-go clients; compressed
+// messages are not supported.
+package queengrpc
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/honeynil/queen"
+)
+
+// gRPC status codes returned by the service.
+const (
+	codeOK                 = 0
+	codeInvalidArgument    = 3
+	codePermissionDenied   = 7
+	codeFailedPrecondition = 9
+	codeAborted            = 10
+	codeUnimplemented      = 12
+	codeInternal           = 13
+	codeUnauthenticated    = 16
+)
+
+// servicePath prefixes the paths of the service's methods.
+const servicePath = "/queen.v1.Migrations/"
+
+// Options configures a Server.
+type Options struct {
+	// Token is the bearer token clients must send in the authorization
+	// metadata. Default: "" (no token accepted)
+	Token string
+
+	// Authorize accepts or rejects calls not carrying Token, e.g. by
+	// checking the client certificate in r.TLS. An error rejects the call
+	// with PERMISSION_DENIED. Default: nil (only Token is accepted)
+	Authorize func(r *http.Request) error
+
+	// ReadOnly rejects Up and Down with PERMISSION_DENIED. Default: false
+	ReadOnly bool
+}
+
+// Server serves the Migrations service of a Queen. It implements
+// http.Handler.
+type Server struct {
+	q    *queen.Queen
+	opts Options
+
+	// mu serializes the calls that load state into q
+	mu sync.Mutex
+}
+
+// statusError is a call failure with its gRPC status code.
+type statusError struct {
+	code int
+	msg  string
+}
+
+func (e *statusError) Error() string { return e.msg }
+
+func errorf(code int, format string, args ...any) error {
+	return &statusError{code: code, msg: fmt.Sprintf(format, args...)}
+}
+
+// NewServer returns a Server for q. Without Token or Authorize, every call
+// is rejected.
+func NewServer(q *queen.Queen, opts Options) *Server {
+	return &Server{q: q, opts: opts}
+}
+
+// ServeHTTP serves a gRPC call.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || !strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+		http.Error(w, "gRPC requests only", http.StatusUnsupportedMediaType)
+		return
+	}
+	w.Header().Set("Content-Type", "application/grpc+proto")
+
+	resp, err := s.call(r)
+	if err != nil {
+		// A failed call has no message, its status goes in the headers
+		writeStatus(w, err)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	w.Header().Set("Trailer", "Grpc-Status, Grpc-Message")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(appendFrame(nil, resp))
+	writeStatus(w, nil)
+}
+
+// call authenticates r and runs the method it calls, returning the
+// encoded response.
+func (s *Server) call(r *http.Request) ([]byte, error) {
+	if err := s.authorize(r); err != nil {
+		return nil, err
+	}
+
+	method, ok := strings.CutPrefix(r.URL.Path, servicePath)
+	if !ok {
+		return nil, errorf(codeUnimplemented, "unknown service %s", r.URL.Path)
+	}
+
+	req, err := readFrame(r.Body)
+	if err != nil {
+		return nil, errorf(codeInvalidArgument, "%v", err)
+	}
+
+	ctx := r.Context()
+	switch method {
+	case "Status":
+		return s.status(ctx)
+	case "LockInfo":
+		return s.lockInfo(), nil
+	case "Up":
+		return s.run(ctx, req, queen.OperationUp)
+	case "Down":
+		return s.run(ctx, req, queen.OperationDown)
+	default:
+		return nil, errorf(codeUnimplemented, "unknown method %s", method)
+	}
+}
+
+func (s *Server) authorize(r *http.Request) error {
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok && s.opts.Token != "" &&
+		subtle.ConstantTimeCompare([]byte(token), []byte(s.opts.Token)) == 1 {
+		return nil
+	}
+
+	if s.opts.Authorize == nil {
+		return errorf(codeUnauthenticated, "missing or invalid bearer token")
+	}
+	if err := s.opts.Authorize(r); err != nil {
+		return errorf(codePermissionDenied, "%v", err)
+	}
+	return nil
+}
+
+// lock takes s.mu, failing with ABORTED if a run holds it.
+func (s *Server) lock() error {
+	if !s.mu.TryLock() {
+		return errorf(codeAborted, "a run is in progress, see LockInfo")
+	}
+	return nil
+}
+
+func (s *Server) status(ctx context.Context) ([]byte, error) {
+	if err := s.lock(); err != nil {
+		return nil, err
+	}
+	defer s.mu.Unlock()
+
+	statuses, err := s.q.Status(ctx)
+	if err != nil {
+		return nil, errorf(codeInternal, "%v", err)
+	}
+
+	var resp encoder
+	for _, st := range statuses {
+		var m encoder
+		m.string(1, st.Version)
+		m.string(2, st.Name)
+		m.string(3, st.Status.String())
+		if st.AppliedAt != nil {
+			m.int64(4, st.AppliedAt.UnixMilli())
+		}
+		m.int64(5, st.Duration.Milliseconds())
+		m.string(6, st.AppliedBy)
+		m.string(7, st.Checksum)
+		m.bool(8, st.HasRollback)
+		m.bool(9, st.Destructive)
+		resp.message(1, m.b)
+	}
+	return resp.b, nil
+}
+
+func (s *Server) lockInfo() []byte {
+	var resp encoder
+	run := s.q.CurrentRun()
+	if run == nil {
+		return resp.b
+	}
+
+	resp.bool(1, true)
+	resp.string(2, run.ID)
+	resp.string(3, string(run.Operation))
+	resp.int64(4, run.StartedAt.UnixMilli())
+	resp.int64(5, int64(len(run.Completed)))
+	resp.int64(6, int64(len(run.Plan)))
+	resp.string(7, run.Current)
+	return resp.b
+}
+
+// run serves Up and Down, whose requests share their layout.
+func (s *Server) run(ctx context.Context, req []byte, op queen.Operation) ([]byte, error) {
+	if s.opts.ReadOnly {
+		return nil, errorf(codePermissionDenied, "read-only")
+	}
+
+	var want []string
+	var allowDestructive bool
+	err := decode(req, func(f field) error {
+		switch {
+		case f.num == 1 && f.wire == wireBytes:
+			want = append(want, string(f.bytes))
+		case f.num == 2 && f.wire == wireVarint:
+			allowDestructive = f.varint != 0
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errorf(codeInvalidArgument, "%v", err)
+	}
+	if len(want) == 0 {
+		return nil, errorf(codeInvalidArgument, "versions must list the migrations to run")
+	}
+
+	if err := s.lock(); err != nil {
+		return nil, err
+	}
+	defer s.mu.Unlock()
+
+	// The run goes on if the client cancels, rather than stopping between
+	// migrations
+	ctx = context.WithoutCancel(ctx)
+
+	var plan *queen.Plan
+	if op == queen.OperationUp {
+		plan, err = s.q.Plan(ctx)
+	} else {
+		plan, err = s.q.PlanDown(ctx, len(want))
+	}
+	if err != nil {
+		return nil, errorf(codeInternal, "%v", err)
+	}
+
+	steps := plan.Steps
+	if len(steps) > len(want) {
+		steps = steps[:len(want)]
+	}
+	var got, destructive []string
+	for _, st := range steps {
+		got = append(got, st.Version)
+		if st.Destructive {
+			destructive = append(destructive, st.Version)
+		}
+	}
+	if !slices.Equal(got, want) {
+		return nil, errorf(codeFailedPrecondition, "the plan is %s, not %s", strings.Join(got, ","), strings.Join(want, ","))
+	}
+	if len(destructive) > 0 && !allowDestructive {
+		return nil, errorf(codeFailedPrecondition, "%s destructive, set allow_destructive to run anyway",
+			strings.Join(destructive, ","))
+	}
+
+	if op == queen.OperationUp {
+		err = s.q.UpSteps(ctx, len(want))
+	} else {
+		err = s.q.Down(ctx, len(want))
+	}
+	if err != nil {
+		return nil, errorf(codeInternal, "%v", err)
+	}
+
+	var resp encoder
+	resp.string(1, string(op))
+	resp.strings(2, want)
+	return resp.b, nil
+}
+
+// writeStatus sets the gRPC status of a call from err.
+func writeStatus(w http.ResponseWriter, err error) {
+	code, msg := codeOK, ""
+	if err != nil {
+		code, msg = codeInternal, err.Error()
+		var se *statusError
+		if errors.As(err, &se) {
+			code = se.code
+		}
+	}
+
+	w.Header().Set("Grpc-Status", strconv.Itoa(code))
+	if msg != "" {
+		w.Header().Set("Grpc-Message", percentEncode(msg))
+	}
+}
+
+// percentEncode escapes msg for the grpc-message trailer.
+func percentEncode(msg string) string {
+	var b strings.Builder
+	for i := 0; i < len(msg); i++ {
+		c := msg[i]
+		if c < 0x20 || c > 0x7e || c == '%' {
+			fmt.Fprintf(&b, "%%%02X", c)
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
@@ -0,0 +1,212 @@
+package queengrpc
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/honeynil/queen"
+	"github.com/honeynil/queen/drivers/mock"
+)
+
+func noop(ctx context.Context, tx *sql.Tx) error { return nil }
+
+// client calls the service over unencrypted HTTP/2, like a gRPC client.
+type client struct {
+	t     *testing.T
+	url   string
+	token string
+	http  *http.Client
+}
+
+func newClient(t *testing.T, s *Server, token string) *client {
+	t.Helper()
+
+	ts := httptest.NewUnstartedServer(s)
+	ts.Config.Protocols = new(http.Protocols)
+	ts.Config.Protocols.SetUnencryptedHTTP2(true)
+	ts.Start()
+	t.Cleanup(ts.Close)
+
+	transport := &http.Transport{Protocols: new(http.Protocols)}
+	transport.Protocols.SetUnencryptedHTTP2(true)
+	return &client{t: t, url: ts.URL, token: token, http: &http.Client{Transport: transport}}
+}
+
+// call invokes method with req, returning the response message and the
+// gRPC status code and message.
+func (c *client) call(method string, req []byte) ([]byte, string, string) {
+	c.t.Helper()
+
+	httpReq, err := http.NewRequest(http.MethodPost, c.url+servicePath+method, bytes.NewReader(appendFrame(nil, req)))
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	httpReq.Header.Set("Content-Type", "application/grpc")
+	httpReq.Header.Set("TE", "trailers")
+	if c.token != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.http.Do(httpReq)
+	if err != nil {
+		c.t.Fatalf("%s: %v", method, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.ProtoMajor != 2 {
+		c.t.Fatalf("%s over HTTP/%d; want HTTP/2", method, resp.ProtoMajor)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+
+	// Trailers-only responses carry the status in the headers
+	status, msg := resp.Header.Get("Grpc-Status"), resp.Header.Get("Grpc-Message")
+	if status == "" {
+		status, msg = resp.Trailer.Get("Grpc-Status"), resp.Trailer.Get("Grpc-Message")
+	}
+
+	var msgBytes []byte
+	if len(body) > 0 {
+		msgBytes, err = readFrame(bytes.NewReader(body))
+		if err != nil {
+			c.t.Fatalf("%s response: %v", method, err)
+		}
+	}
+	return msgBytes, status, msg
+}
+
+func runRequest(allowDestructive bool, versions ...string) []byte {
+	var e encoder
+	e.strings(1, versions)
+	e.bool(2, allowDestructive)
+	return e.b
+}
+
+// statusVersions decodes the versions and statuses of a StatusResponse.
+func statusVersions(t *testing.T, resp []byte) []string {
+	t.Helper()
+
+	var out []string
+	err := decode(resp, func(f field) error {
+		var version, status string
+		err := decode(f.bytes, func(f field) error {
+			switch f.num {
+			case 1:
+				version = string(f.bytes)
+			case 3:
+				status = string(f.bytes)
+			}
+			return nil
+		})
+		out = append(out, version+":"+status)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestServer(t *testing.T) {
+	driver := mock.New()
+	q := queen.New(driver)
+	t.Cleanup(func() { _ = q.Close() })
+	q.MustAdd(queen.M{Version: "001", Name: "create_users", UpFunc: noop, DownFunc: noop})
+	q.MustAdd(queen.M{Version: "002", Name: "create_posts", UpFunc: noop, DownFunc: noop})
+
+	s := NewServer(q, Options{Token: "secret"})
+
+	if _, status, _ := newClient(t, s, "").call("Status", nil); status != "16" {
+		t.Errorf("Status without token = %s; want UNAUTHENTICATED (16)", status)
+	}
+
+	c := newClient(t, s, "secret")
+
+	resp, status, msg := c.call("Status", nil)
+	if status != "0" {
+		t.Fatalf("Status = %s %s; want OK", status, msg)
+	}
+	if got := statusVersions(t, resp); !slices.Equal(got, []string{"001:pending", "002:pending"}) {
+		t.Errorf("Status = %v; want both pending", got)
+	}
+
+	if _, status, _ := c.call("Up", runRequest(false, "002")); status != "9" {
+		t.Errorf("Up(002) = %s; want FAILED_PRECONDITION (9)", status)
+	}
+	if _, status, _ := c.call("Up", nil); status != "3" {
+		t.Errorf("Up() = %s; want INVALID_ARGUMENT (3)", status)
+	}
+
+	resp, status, msg = c.call("Up", runRequest(false, "001", "002"))
+	if status != "0" {
+		t.Fatalf("Up(001, 002) = %s %s; want OK", status, msg)
+	}
+	var op string
+	var versions []string
+	_ = decode(resp, func(f field) error {
+		if f.num == 1 {
+			op = string(f.bytes)
+		} else {
+			versions = append(versions, string(f.bytes))
+		}
+		return nil
+	})
+	if op != "up" || !slices.Equal(versions, []string{"001", "002"}) {
+		t.Errorf("Up response = %s %v; want up 001, 002", op, versions)
+	}
+	if driver.AppliedCount() != 2 {
+		t.Errorf("Applied %d migrations; want 2", driver.AppliedCount())
+	}
+
+	if _, status, msg := c.call("Down", runRequest(false, "002")); status != "0" {
+		t.Fatalf("Down(002) = %s %s; want OK", status, msg)
+	}
+	if driver.HasVersion("002") || !driver.HasVersion("001") {
+		t.Error("Expected only 002 rolled back")
+	}
+
+	resp, status, _ = c.call("LockInfo", nil)
+	if status != "0" || len(resp) != 0 {
+		t.Errorf("LockInfo between runs = %s %x; want OK and an empty message", status, resp)
+	}
+
+	if _, status, _ := c.call("Reset", nil); status != "12" {
+		t.Errorf("Reset = %s; want UNIMPLEMENTED (12)", status)
+	}
+}
+
+func TestServer_Safeguards(t *testing.T) {
+	driver := mock.New()
+	q := queen.New(driver)
+	t.Cleanup(func() { _ = q.Close() })
+	q.MustAdd(queen.M{Version: "001", Name: "drop_users", UpSQL: "DROP TABLE users"})
+
+	c := newClient(t, NewServer(q, Options{Token: "secret", ReadOnly: true}), "secret")
+	if _, status, _ := c.call("Up", runRequest(true, "001")); status != "7" {
+		t.Errorf("Up read-only = %s; want PERMISSION_DENIED (7)", status)
+	}
+
+	c = newClient(t, NewServer(q, Options{Token: "secret"}), "secret")
+	_, status, msg := c.call("Up", runRequest(false, "001"))
+	if status != "9" || !strings.Contains(msg, "destructive") {
+		t.Errorf("Up destructive = %s %q; want FAILED_PRECONDITION naming destructive", status, msg)
+	}
+	if driver.AppliedCount() != 0 {
+		t.Error("Expected the destructive migration not applied")
+	}
+}
+
+func TestPercentEncode(t *testing.T) {
+	if got := percentEncode("100% done\nnext: é"); got != "100%25 done%0Anext: %C3%A9" {
+		t.Errorf("percentEncode = %q", got)
+	}
+}
@@ -0,0 +1,151 @@
+package queengrpc
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Protocol buffer wire types.
+const (
+	wireVarint = 0
+	wireI64    = 1
+	wireBytes  = 2
+	wireI32    = 5
+)
+
+// maxMessageSize bounds request messages, like the 4 MiB default of gRPC
+// servers.
+const maxMessageSize = 4 << 20
+
+// encoder appends protocol buffer fields, skipping proto3 default values.
+type encoder struct {
+	b []byte
+}
+
+func (e *encoder) tag(field, wire int) {
+	e.b = binary.AppendUvarint(e.b, uint64(field)<<3|uint64(wire))
+}
+
+func (e *encoder) string(field int, s string) {
+	if s == "" {
+		return
+	}
+	e.tag(field, wireBytes)
+	e.b = binary.AppendUvarint(e.b, uint64(len(s)))
+	e.b = append(e.b, s...)
+}
+
+func (e *encoder) strings(field int, ss []string) {
+	// Repeated strings are written even when empty
+	for _, s := range ss {
+		e.tag(field, wireBytes)
+		e.b = binary.AppendUvarint(e.b, uint64(len(s)))
+		e.b = append(e.b, s...)
+	}
+}
+
+func (e *encoder) int64(field int, v int64) {
+	if v == 0 {
+		return
+	}
+	e.tag(field, wireVarint)
+	e.b = binary.AppendUvarint(e.b, uint64(v))
+}
+
+func (e *encoder) bool(field int, v bool) {
+	if v {
+		e.int64(field, 1)
+	}
+}
+
+func (e *encoder) message(field int, m []byte) {
+	e.tag(field, wireBytes)
+	e.b = binary.AppendUvarint(e.b, uint64(len(m)))
+	e.b = append(e.b, m...)
+}
+
+// field is a decoded protocol buffer field: its varint value, or its bytes
+// for length-delimited fields.
+type field struct {
+	num    int
+	wire   int
+	varint uint64
+	bytes  []byte
+}
+
+// decode calls fn for every field of the message b, skipping fixed-size
+// fields no message here uses.
+func decode(b []byte, fn func(f field) error) error {
+	for len(b) > 0 {
+		key, n := binary.Uvarint(b)
+		if n <= 0 || key>>3 == 0 || key>>3 > math.MaxInt32 {
+			return errors.New("malformed field key")
+		}
+		b = b[n:]
+
+		f := field{num: int(key >> 3), wire: int(key & 7)}
+		switch f.wire {
+		case wireVarint:
+			f.varint, n = binary.Uvarint(b)
+			if n <= 0 {
+				return errors.New("malformed varint")
+			}
+			b = b[n:]
+		case wireBytes:
+			size, n := binary.Uvarint(b)
+			if n <= 0 || size > uint64(len(b)-n) {
+				return errors.New("malformed length-delimited field")
+			}
+			f.bytes, b = b[n:n+int(size)], b[n+int(size):]
+		case wireI64, wireI32:
+			size := 8
+			if f.wire == wireI32 {
+				size = 4
+			}
+			if len(b) < size {
+				return errors.New("truncated fixed-size field")
+			}
+			b = b[size:]
+			continue
+		default:
+			return fmt.Errorf("unsupported wire type %d", f.wire)
+		}
+
+		if err := fn(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readFrame reads one length-prefixed gRPC message from r.
+func readFrame(r io.Reader) ([]byte, error) {
+	var header [5]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, fmt.Errorf("read message header: %w", err)
+	}
+	if header[0] != 0 {
+		return nil, errors.New("compressed messages are not supported")
+	}
+
+	size := binary.BigEndian.Uint32(header[1:])
+	if size > maxMessageSize {
+		return nil, fmt.Errorf("message of %d bytes exceeds %d", size, maxMessageSize)
+	}
+
+	msg := make([]byte, size)
+	if _, err := io.ReadFull(r, msg); err != nil {
+		return nil, fmt.Errorf("read message: %w", err)
+	}
+	return msg, nil
+}
+
+// appendFrame appends msg to b as a length-prefixed gRPC message.
+func appendFrame(b, msg []byte) []byte {
+	b = append(b, 0)
+	b = binary.BigEndian.AppendUint32(b, uint32(len(msg)))
+	return append(b, msg...)
+}
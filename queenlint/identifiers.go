@@ -0,0 +1,150 @@
+package queenlint
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// RuleIdentifierLength flags identifiers longer than the limit of a
+	// dialect in Config.Dialects: PostgreSQL silently truncates names to 63
+	// bytes, so two long index names can collide, and MySQL rejects names
+	// longer than 64 characters.
+	RuleIdentifierLength Rule = "identifier-length"
+
+	// RuleReservedWord flags unquoted identifiers that are reserved words in
+	// a dialect of Config.Dialects, such as user or order, which fail there
+	// unless quoted, possibly in only one of the databases run against.
+	RuleReservedWord Rule = "reserved-word"
+)
+
+// Dialect names a database whose limits identifiers are checked against.
+type Dialect string
+
+const (
+	DialectPostgres Dialect = "postgres"
+	DialectMySQL    Dialect = "mysql"
+)
+
+// defaultDialects are the dialects checked when Config.Dialects is empty.
+var defaultDialects = []Dialect{DialectPostgres, DialectMySQL}
+
+// dialectNames are the names of dialects used in messages.
+var dialectNames = map[Dialect]string{
+	DialectPostgres: "PostgreSQL",
+	DialectMySQL:    "MySQL",
+}
+
+// postgresReserved are the reserved key words of PostgreSQL, including
+// those allowed only as function or type names.
+var postgresReserved = []string{
+	"all", "analyse", "analyze", "and", "any", "array", "as", "asc",
+	"asymmetric", "authorization", "binary", "both", "case", "cast", "check",
+	"collate", "collation", "column", "concurrently", "constraint", "create",
+	"cross", "current_catalog", "current_date", "current_role",
+	"current_schema", "current_time", "current_timestamp", "current_user",
+	"default", "deferrable", "desc", "distinct", "do", "else", "end",
+	"except", "false", "fetch", "for", "foreign", "freeze", "from", "full",
+	"grant", "group", "having", "ilike", "in", "initially", "inner",
+	"intersect", "into", "is", "isnull", "join", "lateral", "leading",
+	"left", "like", "limit", "localtime", "localtimestamp", "natural", "not",
+	"notnull", "null", "offset", "on", "only", "or", "order", "outer",
+	"overlaps", "placing", "primary", "references", "returning", "right",
+	"select", "session_user", "similar", "some", "symmetric", "system_user",
+	"table", "tablesample", "then", "to", "trailing", "true", "union",
+	"unique", "user", "using", "variadic", "verbose", "when", "where",
+	"window", "with",
+}
+
+// mysqlReserved are the reserved words of MySQL 8.0 likely to be chosen as
+// names, leaving out the interval units such as day_hour.
+var mysqlReserved = []string{
+	"accessible", "add", "all", "alter", "analyze", "and", "as", "asc",
+	"before", "between", "bigint", "binary", "blob", "both", "by", "call",
+	"cascade", "case", "change", "char", "character", "check", "collate",
+	"column", "condition", "constraint", "continue", "convert", "create",
+	"cross", "cube", "cume_dist", "current_date", "current_time",
+	"current_timestamp", "current_user", "cursor", "database", "databases",
+	"dec", "decimal", "declare", "default", "delayed", "delete",
+	"dense_rank", "desc", "describe", "distinct", "div", "double", "drop",
+	"dual", "each", "else", "elseif", "empty", "enclosed", "escaped",
+	"except", "exists", "exit", "explain", "false", "fetch", "first_value",
+	"float", "for", "force", "foreign", "from", "fulltext", "function",
+	"generated", "get", "grant", "group", "grouping", "groups", "having",
+	"high_priority", "if", "ignore", "in", "index", "infile", "inner",
+	"inout", "insert", "int", "integer", "interval", "into", "is", "iterate",
+	"join", "json_table", "key", "keys", "kill", "lag", "last_value",
+	"lateral", "lead", "leading", "leave", "left", "like", "limit", "linear",
+	"lines", "load", "localtime", "localtimestamp", "lock", "long", "loop",
+	"low_priority", "match", "maxvalue", "mod", "modifies", "natural", "not",
+	"nth_value", "ntile", "null", "numeric", "of", "on", "optimize",
+	"option", "optionally", "or", "order", "out", "outer", "outfile", "over",
+	"partition", "percent_rank", "precision", "primary", "procedure",
+	"purge", "range", "rank", "read", "reads", "real", "recursive",
+	"references", "regexp", "release", "rename", "repeat", "replace",
+	"require", "resignal", "restrict", "return", "revoke", "right", "rlike",
+	"row", "row_number", "rows", "schema", "schemas", "select", "separator",
+	"set", "show", "signal", "smallint", "spatial", "specific", "sql",
+	"sqlexception", "sqlstate", "sqlwarning", "starting", "stored",
+	"straight_join", "system", "table", "terminated", "then", "to",
+	"trailing", "trigger", "true", "undo", "union", "unique", "unlock",
+	"unsigned", "update", "usage", "use", "using", "utc_date", "utc_time",
+	"utc_timestamp", "values", "varbinary", "varchar", "varcharacter",
+	"varying", "virtual", "when", "where", "while", "window", "with",
+	"write", "xor", "zerofill",
+}
+
+// dialects returns Dialects, or the default.
+func (c *Config) dialects() []Dialect {
+	if len(c.Dialects) > 0 {
+		return c.Dialects
+	}
+	return defaultDialects
+}
+
+// identifierLength returns why ident is too long, or "" if it isn't.
+func (c *Config) identifierLength(ident string) string {
+	if c.MaxIdentifierLength > 0 {
+		if len(ident) > c.MaxIdentifierLength {
+			return fmt.Sprintf("identifier %s is %d bytes long, the limit is %d", ident, len(ident), c.MaxIdentifierLength)
+		}
+		return ""
+	}
+
+	for _, d := range c.dialects() {
+		switch d {
+		case DialectPostgres:
+			if len(ident) > 63 {
+				return fmt.Sprintf("identifier %s is %d bytes long, PostgreSQL truncates it to 63", ident, len(ident))
+			}
+		case DialectMySQL:
+			if n := utf8.RuneCountInString(ident); n > 64 {
+				return fmt.Sprintf("identifier %s is %d characters long, MySQL rejects names over 64", ident, n)
+			}
+		}
+	}
+	return ""
+}
+
+// reservedIn returns the names of the dialects ident is a reserved word
+// of, e.g. "PostgreSQL and MySQL".
+func (c *Config) reservedIn(ident string) string {
+	word := strings.ToLower(ident)
+
+	var names []string
+	for _, d := range c.dialects() {
+		var reserved []string
+		switch d {
+		case DialectPostgres:
+			reserved = postgresReserved
+		case DialectMySQL:
+			reserved = mysqlReserved
+		}
+		if slices.Contains(reserved, word) {
+			names = append(names, dialectNames[d])
+		}
+	}
+	return strings.Join(names, " and ")
+}
@@ -0,0 +1,218 @@
+package queenlint
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/honeynil/queen/internal/checksum"
+	"github.com/honeynil/queen/internal/split"
+)
+
+const (
+	// RuleVolatileDefault flags columns added with a default that makes
+	// PostgreSQL rewrite the table under an ACCESS EXCLUSIVE lock: a
+	// volatile default such as random() or a serial type, a stored
+	// generated column, or before PostgreSQL 11 any default at all.
+	RuleVolatileDefault Rule = "volatile-default"
+
+	// RuleAlterColumnType flags ALTER COLUMN ... TYPE, which usually
+	// rewrites the table and its indexes under an ACCESS EXCLUSIVE lock.
+	RuleAlterColumnType Rule = "alter-column-type"
+
+	// RuleIndexNotConcurrent flags CREATE INDEX without CONCURRENTLY, which
+	// blocks writes to the table until the index is built.
+	RuleIndexNotConcurrent Rule = "index-not-concurrent"
+)
+
+// volatileFunctions are common volatile functions used as column defaults.
+var volatileFunctions = []string{
+	"random", "clock_timestamp", "timeofday", "nextval",
+	"gen_random_uuid", "uuid_generate_v1", "uuid_generate_v1mc", "uuid_generate_v4",
+}
+
+// serialTypes are column types with an implied nextval() default.
+var serialTypes = []string{"SERIAL", "SMALLSERIAL", "BIGSERIAL", "SERIAL2", "SERIAL4", "SERIAL8"}
+
+// PostgresLockHazards returns the statements of query that hold long locks
+// on PostgreSQL tables that already exist: rewrites under an ACCESS
+// EXCLUSIVE lock (RuleVolatileDefault, RuleAlterColumnType) and index
+// builds blocking writes (RuleIndexNotConcurrent). Tables created earlier
+// in query are new and empty, so statements on them are not reported.
+//
+// major is the major version of the server, e.g. 15, used for defaults
+// whose cost changed between versions; 0 means a current version.
+//
+// Disabled rules in config are skipped; a nil config checks every rule.
+func PostgresLockHazards(query string, major int, config *Config) []Finding {
+	if query == "" {
+		return nil
+	}
+	if config == nil {
+		config = &Config{}
+	}
+
+	query = checksum.Normalize(query, checksum.StripComments|checksum.CollapseWhitespace)
+
+	var findings []Finding
+	report := func(rule Rule, stmt, format string, args ...any) {
+		if !slices.Contains(config.Disabled, rule) {
+			findings = append(findings, Finding{Rule: rule, Message: fmt.Sprintf(format, args...), Statement: stmt})
+		}
+	}
+
+	created := make(map[string]bool)
+	for _, stmt := range split.Split(query, split.Postgres) {
+		tokens := tokenize(stmt)
+		if len(tokens) < 3 {
+			continue
+		}
+
+		switch {
+		case tokens[0].is("CREATE") && tokens[1].is("TABLE"):
+			if table, _ := tableName(tokens, 2); table != "" {
+				created[table] = true
+			}
+
+		case tokens[0].is("ALTER") && tokens[1].is("TABLE"):
+			table, i := tableName(tokens, 2)
+			if table == "" || created[table] {
+				continue
+			}
+			for _, action := range splitTopLevel(tokens[i:]) {
+				if rule, format, args := alterHazard(action, major); rule != "" {
+					report(rule, stmt, format, append(args, table)...)
+				}
+			}
+
+		case tokens[0].is("CREATE"):
+			i := 1
+			if tokens[i].is("UNIQUE") {
+				i++
+			}
+			if !tokens[i].is("INDEX") || (i+1 < len(tokens) && tokens[i+1].is("CONCURRENTLY")) {
+				continue
+			}
+			on := slices.IndexFunc(tokens, func(t token) bool { return t.is("ON") })
+			if on < 0 {
+				continue
+			}
+			if table, _ := tableName(tokens, on+1); table != "" && !created[table] {
+				report(RuleIndexNotConcurrent, stmt, "CREATE INDEX blocks writes to %s until built, use CREATE INDEX CONCURRENTLY in a NoTransaction migration", table)
+			}
+		}
+	}
+
+	return findings
+}
+
+// alterHazard checks a single ALTER TABLE action. It returns the rule and
+// a message format whose last verb is the table name.
+func alterHazard(action []token, major int) (Rule, string, []any) {
+	if len(action) < 2 {
+		return "", "", nil
+	}
+
+	switch {
+	case action[0].is("ADD"):
+		i := 1
+		if action[i].is("COLUMN") {
+			i++
+		}
+		for i < len(action) && slices.ContainsFunc(skipKeywords[:3], action[i].is) {
+			i++
+		}
+		if i >= len(action) || slices.ContainsFunc(constraintKeywords, action[i].is) {
+			return "", "", nil
+		}
+		column := action[i].text
+
+		if i+1 < len(action) && slices.ContainsFunc(serialTypes, action[i+1].is) {
+			return RuleVolatileDefault, "ADD COLUMN %s %s rewrites %s under an ACCESS EXCLUSIVE lock, add an integer column and attach a sequence",
+				[]any{column, strings.ToUpper(action[i+1].text)}
+		}
+
+		for j := i + 1; j < len(action); j++ {
+			switch {
+			case action[j].is("STORED"):
+				return RuleVolatileDefault, "ADD COLUMN %s as a stored generated column rewrites %s under an ACCESS EXCLUSIVE lock",
+					[]any{column}
+			case !action[j].is("DEFAULT") || j+1 >= len(action):
+				continue
+			}
+
+			if major > 0 && major < 11 && !action[j+1].is("NULL") {
+				return RuleVolatileDefault, "ADD COLUMN %s with a default rewrites %s under an ACCESS EXCLUSIVE lock before PostgreSQL 11, add it without a default and backfill",
+					[]any{column}
+			}
+			for _, t := range action[j+1:] {
+				if !t.quoted && slices.Contains(volatileFunctions, strings.ToLower(t.text)) {
+					return RuleVolatileDefault, "ADD COLUMN %s with volatile default %s() rewrites %s under an ACCESS EXCLUSIVE lock, add it without a default and backfill",
+						[]any{column, strings.ToLower(t.text)}
+				}
+			}
+		}
+
+	case action[0].is("ALTER"):
+		i := 1
+		if action[i].is("COLUMN") {
+			i++
+		}
+		if i+1 >= len(action) {
+			return "", "", nil
+		}
+		column := action[i].text
+		rest := action[i+1:]
+		if rest[0].is("TYPE") || (len(rest) > 2 && rest[0].is("SET") && rest[1].is("DATA") && rest[2].is("TYPE")) {
+			return RuleAlterColumnType, "ALTER COLUMN %s TYPE rewrites %s under an ACCESS EXCLUSIVE lock, add a new column and backfill instead",
+				[]any{column}
+		}
+	}
+
+	return "", "", nil
+}
+
+// tableName returns the lowercased, possibly qualified table name at
+// tokens[i], skipping IF [NOT] EXISTS and ONLY, and the index after it.
+// Unquoted names are folded to lowercase like PostgreSQL does.
+func tableName(tokens []token, i int) (string, int) {
+	for i < len(tokens) && slices.ContainsFunc(skipKeywords, tokens[i].is) {
+		i++
+	}
+
+	var parts []string
+	for i < len(tokens) && tokens[i].word {
+		part := tokens[i].text
+		if !tokens[i].quoted {
+			part = strings.ToLower(part)
+		}
+		parts = append(parts, part)
+		if i+2 >= len(tokens) || tokens[i+1].text != "." {
+			i++
+			break
+		}
+		i += 2
+	}
+
+	return strings.Join(parts, "."), i
+}
+
+// splitTopLevel splits tokens on commas outside parentheses.
+func splitTopLevel(tokens []token) [][]token {
+	var parts [][]token
+	depth, start := 0, 0
+	for i, t := range tokens {
+		switch t.text {
+		case "(":
+			depth++
+		case ")":
+			depth--
+		case ",":
+			if depth == 0 {
+				parts = append(parts, tokens[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, tokens[start:])
+}
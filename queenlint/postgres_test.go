@@ -0,0 +1,69 @@
+package queenlint_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/honeynil/queen/queenlint"
+)
+
+func TestPostgresLockHazards(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		major int
+		want  []queenlint.Rule
+	}{
+		{"concurrent index", "CREATE INDEX CONCURRENTLY idx ON users (email)", 0, nil},
+		{"index", "CREATE INDEX idx ON users (email)", 0, []queenlint.Rule{queenlint.RuleIndexNotConcurrent}},
+		{"unique index", "CREATE UNIQUE INDEX IF NOT EXISTS idx ON ONLY public.users (email)", 0, []queenlint.Rule{queenlint.RuleIndexNotConcurrent}},
+		{"index on new table", "CREATE TABLE Users (id INT); CREATE INDEX idx ON users (id)", 0, nil},
+		{"index on other schema", "CREATE TABLE users (id INT); CREATE INDEX idx ON billing.users (id)", 0, []queenlint.Rule{queenlint.RuleIndexNotConcurrent}},
+
+		{"alter type", "ALTER TABLE users ALTER COLUMN id TYPE BIGINT", 0, []queenlint.Rule{queenlint.RuleAlterColumnType}},
+		{"set data type", "ALTER TABLE users ALTER id SET DATA TYPE BIGINT", 0, []queenlint.Rule{queenlint.RuleAlterColumnType}},
+		{"set default", "ALTER TABLE users ALTER COLUMN id SET DEFAULT 0", 0, nil},
+
+		{"constant default", "ALTER TABLE users ADD COLUMN active BOOLEAN NOT NULL DEFAULT TRUE", 0, nil},
+		{"constant default before 11", "ALTER TABLE users ADD COLUMN active BOOLEAN NOT NULL DEFAULT TRUE", 10, []queenlint.Rule{queenlint.RuleVolatileDefault}},
+		{"null default before 11", "ALTER TABLE users ADD COLUMN note TEXT DEFAULT NULL", 10, nil},
+		{"stable default", "ALTER TABLE users ADD COLUMN created_at TIMESTAMPTZ DEFAULT now()", 0, nil},
+		{"volatile default", "ALTER TABLE users ADD COLUMN token UUID DEFAULT gen_random_uuid()", 0, []queenlint.Rule{queenlint.RuleVolatileDefault}},
+		{"serial", "ALTER TABLE users ADD COLUMN seq BIGSERIAL", 0, []queenlint.Rule{queenlint.RuleVolatileDefault}},
+		{"stored generated", "ALTER TABLE users ADD COLUMN total INT GENERATED ALWAYS AS (a + b) STORED", 0, []queenlint.Rule{queenlint.RuleVolatileDefault}},
+		{"add constraint", "ALTER TABLE users ADD CONSTRAINT users_email_key UNIQUE (email)", 0, nil},
+		{"several actions", "ALTER TABLE users ADD COLUMN token UUID DEFAULT gen_random_uuid(), ALTER COLUMN id TYPE BIGINT", 0,
+			[]queenlint.Rule{queenlint.RuleVolatileDefault, queenlint.RuleAlterColumnType}},
+		{"new table", "CREATE TABLE users (id INT); ALTER TABLE users ALTER COLUMN id TYPE BIGINT", 0, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []queenlint.Rule
+			for _, f := range queenlint.PostgresLockHazards(tt.query, tt.major, nil) {
+				got = append(got, f.Rule)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("PostgresLockHazards(%q) = %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPostgresLockHazardsMessage(t *testing.T) {
+	findings := queenlint.PostgresLockHazards("ALTER TABLE users ADD COLUMN token UUID DEFAULT gen_random_uuid()", 0,
+		&queenlint.Config{Disabled: []queenlint.Rule{queenlint.RuleIndexNotConcurrent}})
+	if len(findings) != 1 {
+		t.Fatalf("expected one finding, got %v", findings)
+	}
+
+	want := "ADD COLUMN token with volatile default gen_random_uuid() rewrites users under an ACCESS EXCLUSIVE lock, add it without a default and backfill"
+	if findings[0].Message != want {
+		t.Errorf("Message = %q, want %q", findings[0].Message, want)
+	}
+
+	disabled := &queenlint.Config{Disabled: []queenlint.Rule{queenlint.RuleVolatileDefault}}
+	if findings := queenlint.PostgresLockHazards(findings[0].Statement, 0, disabled); len(findings) != 0 {
+		t.Errorf("expected disabled rule to be skipped, got %v", findings)
+	}
+}
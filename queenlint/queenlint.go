@@ -0,0 +1,372 @@
+// Package queenlint statically checks SQL migrations for patterns that
+// tend to break deployments: drops that fail when the object is already
+// gone, migrations mixing schema changes with data changes, identifiers
+// whose case depends on the database, identifiers too long or reserved in
+// one of the databases, and oversized statements.
+//
+// Queen.Lint runs it over the registered migrations. It can also be used
+// on its own:
+//
+//	findings := queenlint.Lint([]queenlint.Migration{
+//	    {Version: "001", UpSQL: "DROP TABLE users"},
+//	}, nil)
+//	for _, f := range findings {
+//	    fmt.Println(f) // 001 (up): missing-if-exists: DROP TABLE without IF EXISTS
+//	}
+package queenlint
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/honeynil/queen/internal/checksum"
+	"github.com/honeynil/queen/internal/split"
+)
+
+// Rule identifies a check.
+type Rule string
+
+const (
+	// RuleMissingIfExists flags DROP statements without IF EXISTS, which
+	// fail when re-run after a partial failure or against a database where
+	// the object was already removed by hand.
+	RuleMissingIfExists Rule = "missing-if-exists"
+
+	// RuleMixedDDLDML flags migrations mixing schema changes (CREATE,
+	// ALTER, DROP, ...) with data changes (INSERT, UPDATE, DELETE, ...).
+	// Some databases commit DDL implicitly, and data changes are better
+	// run and retried on their own.
+	RuleMixedDDLDML Rule = "mixed-ddl-dml"
+
+	// RuleLowercaseIdentifiers flags unquoted identifiers that aren't
+	// lowercase. PostgreSQL folds them to lowercase and MySQL keeps their
+	// case, so the name that ends up in the schema depends on the database.
+	RuleLowercaseIdentifiers Rule = "lowercase-identifiers"
+
+	// RuleStatementLength flags statements longer than
+	// Config.MaxStatementLength, usually generated data that belongs in a
+	// fixture or a Go migration.
+	RuleStatementLength Rule = "statement-length"
+)
+
+// DefaultMaxStatementLength is the default Config.MaxStatementLength.
+const DefaultMaxStatementLength = 10000
+
+// Config configures Lint.
+type Config struct {
+	// MaxStatementLength is the longest statement, in bytes with comments
+	// removed and whitespace collapsed, that RuleStatementLength accepts.
+	// Default: DefaultMaxStatementLength
+	MaxStatementLength int
+
+	// Dialects lists the databases the migrations run on, whose limits
+	// RuleIdentifierLength and RuleReservedWord check identifiers against.
+	// Default: DialectPostgres and DialectMySQL
+	Dialects []Dialect
+
+	// MaxIdentifierLength replaces the identifier length limits of
+	// Dialects, in bytes, e.g. to leave room for a prefix added when the
+	// SQL is rendered. Default: 0 (the limits of Dialects)
+	MaxIdentifierLength int
+
+	// Disabled lists rules not to check. Default: nil (all rules)
+	Disabled []Rule
+}
+
+// Migration is the SQL of a migration to lint.
+type Migration struct {
+	Version string
+	Name    string
+	UpSQL   string
+	DownSQL string
+}
+
+// Finding is a problem found in a migration.
+type Finding struct {
+	// Version and Name identify the migration.
+	Version string
+	Name    string
+
+	// Down is set if the finding is in DownSQL rather than UpSQL.
+	Down bool
+
+	// Rule is the rule that found the problem.
+	Rule Rule
+
+	// Message describes the problem.
+	Message string
+
+	// Statement is the offending statement, with comments removed and
+	// whitespace collapsed.
+	Statement string
+}
+
+// String returns a one-line description of the finding, e.g.
+// "001 (up): missing-if-exists: DROP TABLE without IF EXISTS".
+func (f Finding) String() string {
+	direction := "up"
+	if f.Down {
+		direction = "down"
+	}
+	return fmt.Sprintf("%s (%s): %s: %s", f.Version, direction, f.Rule, f.Message)
+}
+
+// Lint checks the SQL of migrations and returns the findings, in the order
+// of migrations and statements. A nil config uses the defaults.
+func Lint(migrations []Migration, config *Config) []Finding {
+	if config == nil {
+		config = &Config{}
+	}
+
+	var findings []Finding
+	for _, m := range migrations {
+		for _, down := range []bool{false, true} {
+			query := m.UpSQL
+			if down {
+				query = m.DownSQL
+			}
+
+			for _, f := range config.check(query) {
+				f.Version, f.Name, f.Down = m.Version, m.Name, down
+				findings = append(findings, f)
+			}
+		}
+	}
+
+	return findings
+}
+
+// check returns the findings of a single script.
+func (c *Config) check(query string) []Finding {
+	if query == "" {
+		return nil
+	}
+
+	query = checksum.Normalize(query, checksum.StripComments|checksum.CollapseWhitespace)
+	statements := split.Split(query, split.Postgres)
+
+	var findings []Finding
+	report := func(rule Rule, stmt, format string, args ...any) {
+		if !slices.Contains(c.Disabled, rule) {
+			findings = append(findings, Finding{Rule: rule, Message: fmt.Sprintf(format, args...), Statement: stmt})
+		}
+	}
+
+	var ddl, dml string
+	for _, stmt := range statements {
+		tokens := tokenize(stmt)
+
+		switch kind(tokens) {
+		case "ddl":
+			if ddl == "" {
+				ddl = stmt
+			}
+		case "dml":
+			if dml == "" {
+				dml = stmt
+			}
+		}
+
+		if object, ok := dropWithoutIfExists(tokens); ok {
+			report(RuleMissingIfExists, stmt, "DROP %s without IF EXISTS", object)
+		}
+
+		for _, ident := range identifiers(tokens) {
+			if !ident.quoted && ident.text != strings.ToLower(ident.text) {
+				report(RuleLowercaseIdentifiers, stmt, "identifier %s is not lowercase, quote it or use %s", ident.text, strings.ToLower(ident.text))
+			}
+			if msg := c.identifierLength(ident.text); msg != "" {
+				report(RuleIdentifierLength, stmt, "%s", msg)
+			}
+			if dialects := c.reservedIn(ident.text); dialects != "" && !ident.quoted {
+				report(RuleReservedWord, stmt, "identifier %s is a reserved word in %s, rename or quote it", ident.text, dialects)
+			}
+		}
+
+		if limit := c.maxStatementLength(); len(stmt) > limit {
+			report(RuleStatementLength, stmt, "statement is %d bytes long, the limit is %d", len(stmt), limit)
+		}
+	}
+
+	if ddl != "" && dml != "" {
+		report(RuleMixedDDLDML, dml, "schema changes mixed with data changes, move %s to its own migration", firstWord(dml))
+	}
+
+	return findings
+}
+
+// maxStatementLength returns MaxStatementLength, or the default.
+func (c *Config) maxStatementLength() int {
+	if c.MaxStatementLength > 0 {
+		return c.MaxStatementLength
+	}
+	return DefaultMaxStatementLength
+}
+
+// ddlKeywords and dmlKeywords classify statements by their first keyword.
+var (
+	ddlKeywords = []string{"CREATE", "ALTER", "DROP", "TRUNCATE", "RENAME", "COMMENT"}
+	dmlKeywords = []string{"INSERT", "UPDATE", "DELETE", "MERGE", "COPY", "REPLACE"}
+)
+
+// kind returns "ddl" or "dml" for schema and data changes, or "" for other
+// statements. Data-modifying WITH queries count as data changes.
+func kind(tokens []token) string {
+	if len(tokens) == 0 || tokens[0].quoted {
+		return ""
+	}
+
+	first := strings.ToUpper(tokens[0].text)
+	switch {
+	case slices.Contains(ddlKeywords, first):
+		return "ddl"
+	case slices.Contains(dmlKeywords, first):
+		return "dml"
+	case first == "WITH":
+		for _, t := range tokens {
+			if !t.quoted && slices.Contains(dmlKeywords[:3], strings.ToUpper(t.text)) {
+				return "dml"
+			}
+		}
+	}
+
+	return ""
+}
+
+// dropObjects are the objects dropWithoutIfExists checks.
+var dropObjects = []string{
+	"TABLE", "VIEW", "INDEX", "SEQUENCE", "SCHEMA", "DATABASE", "TYPE",
+	"DOMAIN", "FUNCTION", "PROCEDURE", "TRIGGER", "EXTENSION",
+}
+
+// dropWithoutIfExists reports whether tokens are a DROP statement without
+// IF EXISTS, returning the dropped object kind, e.g. "TABLE".
+func dropWithoutIfExists(tokens []token) (string, bool) {
+	if len(tokens) < 2 || !tokens[0].is("DROP") {
+		return "", false
+	}
+
+	i := 1
+	if tokens[i].is("MATERIALIZED") && i+1 < len(tokens) {
+		i++
+	}
+	object := strings.ToUpper(tokens[i].text)
+	if tokens[i].quoted || !slices.Contains(dropObjects, object) {
+		return "", false
+	}
+	if tokens[i-1].is("MATERIALIZED") {
+		object = "MATERIALIZED " + object
+	}
+
+	i++
+	if i < len(tokens) && tokens[i].is("CONCURRENTLY") {
+		i++
+	}
+	if i+1 < len(tokens) && tokens[i].is("IF") && tokens[i+1].is("EXISTS") {
+		return "", false
+	}
+
+	return object, true
+}
+
+// nameKeywords are keywords followed by the name of a schema object.
+var nameKeywords = []string{
+	"TABLE", "VIEW", "INDEX", "SEQUENCE", "SCHEMA", "COLUMN", "CONSTRAINT",
+	"REFERENCES", "ON", "INTO", "FROM", "JOIN",
+}
+
+// skipKeywords may come between a name keyword and the name.
+var skipKeywords = []string{"IF", "NOT", "EXISTS", "ONLY", "CONCURRENTLY"}
+
+// notNames are keywords that can follow a name keyword in place of a name,
+// as in ON DELETE CASCADE or ON CONFLICT. So can nameKeywords, as in
+// COMMENT ON TABLE.
+var notNames = []string{"DELETE", "UPDATE", "CONFLICT", "COMMIT", "SELECT", "LATERAL"}
+
+// constraintKeywords start table constraints rather than column definitions.
+var constraintKeywords = []string{
+	"CONSTRAINT", "PRIMARY", "FOREIGN", "UNIQUE", "CHECK", "EXCLUDE",
+	"INDEX", "KEY", "LIKE", "FULLTEXT", "SPATIAL",
+}
+
+// identifiers returns the names of schema objects in tokens: names
+// following nameKeywords, the table of an UPDATE, and the columns defined
+// by a CREATE TABLE. Qualified names yield each part.
+func identifiers(tokens []token) []token {
+	var idents []token
+	name := func(i int) int {
+		for i < len(tokens) && tokens[i].word && slices.Contains(skipKeywords, strings.ToUpper(tokens[i].text)) {
+			i++
+		}
+		for i < len(tokens) && tokens[i].word {
+			if kw := strings.ToUpper(tokens[i].text); !tokens[i].quoted &&
+				(slices.Contains(notNames, kw) || slices.Contains(nameKeywords, kw)) {
+				return i
+			}
+			idents = append(idents, tokens[i])
+			if i+2 >= len(tokens) || tokens[i+1].text != "." {
+				return i + 1
+			}
+			i += 2
+		}
+		return i
+	}
+
+	if len(tokens) > 1 && tokens[0].is("UPDATE") {
+		name(1)
+	}
+
+	for i := 0; i < len(tokens); i++ {
+		if tokens[i].word && !tokens[i].quoted && slices.Contains(nameKeywords, strings.ToUpper(tokens[i].text)) {
+			end := name(i + 1)
+
+			// Column definitions of CREATE TABLE name (...)
+			if i > 0 && tokens[i].is("TABLE") && tokens[0].is("CREATE") && end < len(tokens) && tokens[end].text == "(" {
+				idents = append(idents, columns(tokens[end:])...)
+			}
+		}
+	}
+
+	return idents
+}
+
+// columns returns the column names defined in the parenthesized list at
+// the start of tokens.
+func columns(tokens []token) []token {
+	var cols []token
+	depth := 0
+	start := true
+	for _, t := range tokens {
+		switch t.text {
+		case "(":
+			depth++
+			if depth == 1 {
+				start = true
+				continue
+			}
+		case ")":
+			depth--
+			if depth == 0 {
+				return cols
+			}
+		case ",":
+			if depth == 1 {
+				start = true
+				continue
+			}
+		}
+
+		if start && depth == 1 && t.word && (t.quoted || !slices.Contains(constraintKeywords, strings.ToUpper(t.text))) {
+			cols = append(cols, t)
+		}
+		start = false
+	}
+	return cols
+}
+
+// firstWord returns the first word of stmt.
+func firstWord(stmt string) string {
+	word, _, _ := strings.Cut(stmt, " ")
+	return strings.ToUpper(word)
+}
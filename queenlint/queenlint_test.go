@@ -0,0 +1,145 @@
+package queenlint_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/honeynil/queen/queenlint"
+)
+
+func TestLint(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  []string // messages
+	}{
+		{"clean", "CREATE TABLE IF NOT EXISTS users (id INT PRIMARY KEY, email TEXT)", nil},
+		{"empty", "", nil},
+
+		// missing-if-exists
+		{"drop table", "DROP TABLE users", []string{"missing-if-exists: DROP TABLE without IF EXISTS"}},
+		{"drop table if exists", "DROP TABLE IF EXISTS users", nil},
+		{"drop index concurrently", "DROP INDEX CONCURRENTLY idx_users_email",
+			[]string{"missing-if-exists: DROP INDEX without IF EXISTS"}},
+		{"drop index concurrently if exists", "DROP INDEX CONCURRENTLY IF EXISTS idx_users_email", nil},
+		{"drop materialized view", "DROP MATERIALIZED VIEW stats",
+			[]string{"missing-if-exists: DROP MATERIALIZED VIEW without IF EXISTS"}},
+		{"lowercase drop", "drop view stats", []string{"missing-if-exists: DROP VIEW without IF EXISTS"}},
+		{"drop column", "ALTER TABLE users DROP COLUMN email", nil},
+		{"drop in comment", "-- DROP TABLE users\nSELECT 1", nil},
+		{"drop in string", "INSERT INTO log VALUES ('DROP TABLE users')", nil},
+
+		// mixed-ddl-dml
+		{"mixed", "CREATE TABLE IF NOT EXISTS roles (id INT); INSERT INTO roles VALUES (1)",
+			[]string{"mixed-ddl-dml: schema changes mixed with data changes, move INSERT to its own migration"}},
+		{"only dml", "INSERT INTO roles VALUES (1); UPDATE roles SET id = 2", nil},
+		{"data-modifying with", "ALTER TABLE roles ADD COLUMN name TEXT; WITH r AS (SELECT 1) UPDATE roles SET name = 'x'",
+			[]string{"mixed-ddl-dml: schema changes mixed with data changes, move WITH to its own migration"}},
+		{"select is not dml", "CREATE TABLE IF NOT EXISTS roles (id INT); SELECT 1", nil},
+
+		// lowercase-identifiers
+		{"mixed case table", "CREATE TABLE IF NOT EXISTS Users (id INT)",
+			[]string{"lowercase-identifiers: identifier Users is not lowercase, quote it or use users"}},
+		{"mixed case column", "CREATE TABLE IF NOT EXISTS users (id INT, createdAt TIMESTAMP, PRIMARY KEY (id))",
+			[]string{"lowercase-identifiers: identifier createdAt is not lowercase, quote it or use createdat"}},
+		{"quoted", `CREATE TABLE IF NOT EXISTS "Users" ("createdAt" TIMESTAMP)`, nil},
+		{"qualified", "ALTER TABLE Billing.invoices ADD COLUMN total INT",
+			[]string{"lowercase-identifiers: identifier Billing is not lowercase, quote it or use billing"}},
+		{"references", "ALTER TABLE orders ADD CONSTRAINT fk FOREIGN KEY (org_id) REFERENCES Orgs (id) ON DELETE CASCADE",
+			[]string{"lowercase-identifiers: identifier Orgs is not lowercase, quote it or use orgs"}},
+		{"update", "UPDATE Users SET name = 'x' WHERE id = 1",
+			[]string{"lowercase-identifiers: identifier Users is not lowercase, quote it or use users"}},
+		{"keywords", "COMMENT ON TABLE users IS 'People'; CREATE INDEX ON users (email) WHERE Active", nil},
+		{"on conflict", "INSERT INTO users VALUES (1) ON CONFLICT DO NOTHING", nil},
+		{"function body", "CREATE FUNCTION f() RETURNS INT AS $$ SELECT 1 FROM Users $$ LANGUAGE sql", nil},
+
+		// identifier-length
+		{"long index name", "CREATE INDEX IF NOT EXISTS idx_aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa ON users (email)",
+			[]string{"identifier-length: identifier idx_aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa is 64 bytes long, PostgreSQL truncates it to 63"}},
+		{"long quoted name", `CREATE TABLE IF NOT EXISTS "idx_aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa" (id INT)`,
+			[]string{"identifier-length: identifier idx_aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa is 64 bytes long, PostgreSQL truncates it to 63"}},
+
+		// reserved-word
+		{"reserved table", "CREATE TABLE IF NOT EXISTS user (id INT)",
+			[]string{"reserved-word: identifier user is a reserved word in PostgreSQL, rename or quote it"}},
+		{"reserved column", "CREATE TABLE IF NOT EXISTS orders (id INT, rank INT)",
+			[]string{"reserved-word: identifier rank is a reserved word in MySQL, rename or quote it"}},
+		{"reserved in both", "ALTER TABLE orders ADD COLUMN order INT",
+			[]string{"reserved-word: identifier order is a reserved word in PostgreSQL and MySQL, rename or quote it"}},
+		{"quoted reserved", `CREATE TABLE IF NOT EXISTS "user" ("order" INT)`, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			findings := queenlint.Lint([]queenlint.Migration{{Version: "001", UpSQL: tt.query}}, nil)
+
+			var got []string
+			for _, f := range findings {
+				got = append(got, string(f.Rule)+": "+f.Message)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Lint(%q) = %q, want %q", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLintConfig(t *testing.T) {
+	long := "INSERT INTO t VALUES " + strings.Repeat("(1),", 30) + "(1)"
+	migrations := []queenlint.Migration{
+		{Version: "001", Name: "seed", UpSQL: long, DownSQL: "DROP TABLE t"},
+	}
+
+	findings := queenlint.Lint(migrations, &queenlint.Config{MaxStatementLength: 100})
+	if len(findings) != 2 {
+		t.Fatalf("expected 2 findings, got %v", findings)
+	}
+	if f := findings[0]; f.Rule != queenlint.RuleStatementLength || f.Down || f.Version != "001" || f.Name != "seed" {
+		t.Errorf("findings[0] = %+v, want statement-length in 001 (up)", f)
+	}
+	if got := findings[1].String(); got != "001 (down): missing-if-exists: DROP TABLE without IF EXISTS" {
+		t.Errorf("findings[1] = %q", got)
+	}
+
+	findings = queenlint.Lint(migrations, &queenlint.Config{
+		MaxStatementLength: 100,
+		Disabled:           []queenlint.Rule{queenlint.RuleStatementLength, queenlint.RuleMissingIfExists},
+	})
+	if len(findings) != 0 {
+		t.Errorf("expected disabled rules to be skipped, got %v", findings)
+	}
+
+	if findings := queenlint.Lint(migrations, nil); len(findings) != 1 {
+		t.Errorf("expected the default length limit to accept the statement, got %v", findings)
+	}
+}
+
+func TestLintDialects(t *testing.T) {
+	migrations := []queenlint.Migration{
+		{Version: "001", UpSQL: "CREATE TABLE IF NOT EXISTS tenant_settings (user INT, rank INT)"},
+	}
+
+	rules := func(findings []queenlint.Finding) []string {
+		var got []string
+		for _, f := range findings {
+			got = append(got, string(f.Rule)+": "+f.Message)
+		}
+		return got
+	}
+
+	got := rules(queenlint.Lint(migrations, &queenlint.Config{Dialects: []queenlint.Dialect{queenlint.DialectMySQL}}))
+	want := []string{"reserved-word: identifier rank is a reserved word in MySQL, rename or quote it"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Lint() for MySQL = %q, want %q", got, want)
+	}
+
+	got = rules(queenlint.Lint(migrations, &queenlint.Config{
+		MaxIdentifierLength: 10,
+		Disabled:            []queenlint.Rule{queenlint.RuleReservedWord},
+	}))
+	want = []string{"identifier-length: identifier tenant_settings is 15 bytes long, the limit is 10"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Lint() with MaxIdentifierLength = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,120 @@
+package queenlint
+
+import "strings"
+
+// token is a lexical token of a statement.
+type token struct {
+	// text is the token, without the quotes of a quoted identifier.
+	text string
+
+	// word is set for keywords and identifiers, quoted or not.
+	word bool
+
+	// quoted is set for quoted identifiers.
+	quoted bool
+}
+
+// is reports whether t is the unquoted keyword kw, in any case.
+func (t token) is(kw string) bool {
+	return t.word && !t.quoted && strings.EqualFold(t.text, kw)
+}
+
+// tokenize splits stmt into words, quoted identifiers, literals and
+// punctuation. Whitespace is dropped; string literals and dollar-quoted
+// bodies become single non-word tokens.
+func tokenize(stmt string) []token {
+	var tokens []token
+	for i := 0; i < len(stmt); {
+		c := stmt[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+
+		case c == '"' || c == '`':
+			end := closing(stmt, i, c)
+			text := stmt[i+1 : end]
+			if end < len(stmt) {
+				end++
+			}
+			tokens = append(tokens, token{text: strings.ReplaceAll(text, string([]byte{c, c}), string(c)), word: true, quoted: true})
+			i = end
+
+		case c == '\'':
+			end := closing(stmt, i, c)
+			if end < len(stmt) {
+				end++
+			}
+			tokens = append(tokens, token{text: stmt[i:end]})
+			i = end
+
+		case c == '$':
+			end := dollarQuoteEnd(stmt, i)
+			tokens = append(tokens, token{text: stmt[i:end]})
+			i = end
+
+		case isWordStart(c):
+			end := i + 1
+			for end < len(stmt) && (isWordStart(stmt[end]) || isDigit(stmt[end]) || stmt[end] == '$') {
+				end++
+			}
+			tokens = append(tokens, token{text: stmt[i:end], word: true})
+			i = end
+
+		case isDigit(c):
+			end := i + 1
+			for end < len(stmt) && (isDigit(stmt[end]) || stmt[end] == '.') {
+				end++
+			}
+			tokens = append(tokens, token{text: stmt[i:end]})
+			i = end
+
+		default:
+			tokens = append(tokens, token{text: stmt[i : i+1]})
+			i++
+		}
+	}
+	return tokens
+}
+
+// closing returns the offset of the quote q closing the one at i, or
+// len(s) if it is unterminated. A doubled quote doesn't close it.
+func closing(s string, i int, q byte) int {
+	for j := i + 1; j < len(s); j++ {
+		if s[j] != q {
+			continue
+		}
+		if j+1 < len(s) && s[j+1] == q {
+			j++
+			continue
+		}
+		return j
+	}
+	return len(s)
+}
+
+// dollarQuoteEnd returns the offset just past the $tag$ ... $tag$ string
+// starting at i, or i+1 if the '$' doesn't open one, as in $1.
+func dollarQuoteEnd(s string, i int) int {
+	j := i + 1
+	for j < len(s) && (isWordStart(s[j]) || isDigit(s[j])) {
+		j++
+	}
+	if j >= len(s) || s[j] != '$' || (j > i+1 && isDigit(s[i+1])) {
+		return i + 1
+	}
+
+	tag := s[i : j+1]
+	n := strings.Index(s[j+1:], tag)
+	if n < 0 {
+		return len(s)
+	}
+	return j + 1 + n + len(tag)
+}
+
+func isWordStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c >= 0x80
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
@@ -0,0 +1,84 @@
+package queen
+
+import (
+	"context"
+	"fmt"
+	"slices"
+)
+
+// Submit queues a migration for the next Flush.
+//
+// Submit is intended for plugins or modules loaded at runtime: instead of
+// each of them calling Up independently, they submit their migrations and a
+// central coordinator applies everything in one locked batch by calling
+// Flush. Submit is safe for concurrent use, including while Flush runs: a
+// migration submitted after Flush has taken the queue waits for the next
+// Flush. Like Add, it must not run concurrently with the other methods.
+//
+// Returns ErrVersionConflict if the version is already registered or queued,
+// and ErrNotLocked if Config.LockFile is set and doesn't pin the migration.
+func (q *Queen) Submit(m M) error {
+	if err := q.checkNew(&m); err != nil {
+		return err
+	}
+
+	q.queueMu.Lock()
+	defer q.queueMu.Unlock()
+
+	if q.hasVersion(m.Version) {
+		return fmt.Errorf("%w: %s", ErrVersionConflict, m.Version)
+	}
+	for _, queued := range q.queue {
+		if queued.Version == m.Version {
+			return fmt.Errorf("%w: %s", ErrVersionConflict, m.Version)
+		}
+	}
+
+	migration := q.registered(m)
+	if err := q.checkLocked(migration); err != nil {
+		return err
+	}
+
+	q.queue = append(q.queue, migration)
+
+	return nil
+}
+
+// Flush registers all submitted migrations and applies every pending
+// migration under a single lock, as Up does.
+//
+// If a queued migration conflicts with one registered after it was
+// submitted, nothing is registered and the queue is left intact. Flush may
+// run concurrently with Submit and other Flush calls, which wait for it; it
+// registers like Add, so it must not run concurrently with other methods.
+func (q *Queen) Flush(ctx context.Context) error {
+	q.flushMu.Lock()
+	defer q.flushMu.Unlock()
+
+	q.queueMu.Lock()
+	queued := q.queue
+	for _, m := range queued {
+		if q.hasVersion(m.Version) {
+			q.queueMu.Unlock()
+			return fmt.Errorf("%w: %s", ErrVersionConflict, m.Version)
+		}
+	}
+	q.migrations = append(q.migrations, queued...)
+	q.queue = nil
+	q.queueMu.Unlock()
+
+	return q.Up(ctx)
+}
+
+// hasVersion reports whether a migration with the given version is registered.
+func (q *Queen) hasVersion(version string) bool {
+	return q.indexOf(version) >= 0
+}
+
+// indexOf returns the index of the registered migration with the given
+// version, or -1.
+func (q *Queen) indexOf(version string) int {
+	return slices.IndexFunc(q.migrations, func(m *Migration) bool {
+		return m.Version == version
+	})
+}
@@ -0,0 +1,55 @@
+// Package registry collects migrations registered from package init
+// functions across an application, so modular codebases don't have to
+// pass a *queen.Queen to every package that owns migrations:
+//
+//	// users/migrations.go
+//	package users
+//
+//	func init() {
+//	    registry.Register(queen.M{
+//	        Version: "users_001",
+//	        Name:    "create_users",
+//	        UpSQL:   `CREATE TABLE users (...)`,
+//	        DownSQL: `DROP TABLE users`,
+//	    })
+//	}
+//
+//	// main.go
+//	import _ "example.com/app/users"
+//
+//	q, err := queen.NewFromRegistry(driver)
+//
+// Registration order doesn't matter: Queen sorts migrations by version.
+package registry
+
+import (
+	"fmt"
+
+	"github.com/honeynil/queen"
+	"github.com/honeynil/queen/internal/globalreg"
+)
+
+// Register adds m to the global registry. It is meant to be called from
+// init functions and, like database/sql.Register, panics if m is invalid
+// or its version is already registered, so mistakes surface at startup.
+func Register(m queen.M) {
+	if err := m.Validate(); err != nil {
+		panic(fmt.Sprintf("registry: %v", err))
+	}
+
+	if !globalreg.Add(m.Version, m) {
+		panic(fmt.Sprintf("registry: %v: %s", queen.ErrVersionConflict, m.Version))
+	}
+}
+
+// Migrations returns the registered migrations in registration order.
+func Migrations() []queen.M {
+	entries := globalreg.All()
+
+	migrations := make([]queen.M, len(entries))
+	for i, m := range entries {
+		migrations[i] = m.(queen.M)
+	}
+
+	return migrations
+}
@@ -0,0 +1,76 @@
+package registry_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/honeynil/queen"
+	"github.com/honeynil/queen/drivers/mock"
+	"github.com/honeynil/queen/registry"
+)
+
+func noop(ctx context.Context, tx *sql.Tx) error { return nil }
+
+func init() {
+	registry.Register(queen.M{Version: "posts_001", Name: "create_posts", ManualChecksum: "v1", UpFunc: noop})
+	registry.Register(queen.M{Version: "users_001", Name: "create_users", ManualChecksum: "v1", UpFunc: noop})
+}
+
+func TestNewFromRegistry(t *testing.T) {
+	driver := mock.New()
+	q, err := queen.NewFromRegistry(driver)
+	if err != nil {
+		t.Fatalf("NewFromRegistry failed: %v", err)
+	}
+
+	if err := q.Up(context.Background()); err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+	if !driver.HasVersion("posts_001") || !driver.HasVersion("users_001") {
+		t.Error("Expected every registered migration to be applied")
+	}
+
+	migrations := registry.Migrations()
+	if len(migrations) != 2 || migrations[0].Version != "posts_001" {
+		t.Errorf("Migrations() = %+v, want posts_001 and users_001 in registration order", migrations)
+	}
+}
+
+func TestNewFromRegistryWithConfig(t *testing.T) {
+	_, err := queen.NewFromRegistryWithConfig(mock.New(), &queen.Config{
+		LockFile: &queen.LockFile{},
+	})
+	if !errors.Is(err, queen.ErrNotLocked) {
+		t.Errorf("Expected ErrNotLocked, got %v", err)
+	}
+}
+
+func TestRegisterPanics(t *testing.T) {
+	tests := []struct {
+		name string
+		m    queen.M
+		want error
+	}{
+		{"duplicate", queen.M{Version: "users_001", Name: "again", UpFunc: noop}, queen.ErrVersionConflict},
+		{"invalid", queen.M{Version: "users_002"}, queen.ErrInvalidMigration},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				r := recover()
+				if r == nil {
+					t.Fatal("Expected Register to panic")
+				}
+				if msg := fmt.Sprint(r); !strings.Contains(msg, tt.want.Error()) {
+					t.Errorf("panic = %q, want it to mention %q", msg, tt.want)
+				}
+			}()
+			registry.Register(tt.m)
+		})
+	}
+}
@@ -0,0 +1,243 @@
+package queen
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	naturalsort "github.com/honeynil/queen/internal/sort"
+)
+
+// Repair clears the dirty state left by a failed or interrupted run by
+// deleting every dirty record, i.e. declaring those migrations not applied.
+//
+// Use Repair after manually reverting whatever a failed migration changed;
+// the migrations will run again on the next Up. If the migration's changes
+// were completed by hand instead, use Force.
+//
+// Returns the versions that were cleared.
+func (q *Queen) Repair(ctx context.Context) ([]string, error) {
+	var cleared []string
+
+	err := q.withLock(ctx, func() error {
+		for _, version := range q.dirtyVersions() {
+			if err := q.driver.Remove(ctx, version); err != nil {
+				return err
+			}
+			delete(q.applied, version)
+			cleared = append(cleared, version)
+		}
+		return nil
+	})
+
+	return cleared, err
+}
+
+// Force marks versions as cleanly applied, clearing their dirty flag or
+// recording them if they have no record yet. Like golang-migrate's force
+// command, it does not execute any SQL. Versions without a record are
+// recorded in one new batch, all or none if the driver implements
+// BatchRecorder.
+//
+// Returns ErrMigrationNotFound, before anything is marked, if a version is
+// not registered.
+func (q *Queen) Force(ctx context.Context, versions ...string) error {
+	migrations := make([]*Migration, 0, len(versions))
+	for _, version := range versions {
+		var m *Migration
+		for _, registered := range q.migrations {
+			if registered.Version == version {
+				m = registered
+				break
+			}
+		}
+		if m == nil {
+			return fmt.Errorf("%w: %s", ErrMigrationNotFound, version)
+		}
+		migrations = append(migrations, m)
+	}
+
+	return q.withLock(ctx, func() error {
+		var unrecorded []*Migration
+		for _, m := range migrations {
+			if _, ok := q.applied[m.Version]; !ok {
+				unrecorded = append(unrecorded, m)
+				continue
+			}
+			if err := q.setDirty(ctx, m, false); err != nil {
+				return err
+			}
+		}
+		if len(unrecorded) == 0 {
+			return nil
+		}
+		return q.recordAll(ctx, unrecorded, RecordMeta{Batch: q.lastBatch() + 1})
+	})
+}
+
+// setDirty sets or clears the dirty flag of the record of m. Without
+// DirtyMarker, a record isn't marked, and is cleared by recording m again
+// with the metadata it was applied with.
+func (q *Queen) setDirty(ctx context.Context, m *Migration, dirty bool) error {
+	if marker, ok := optional[DirtyMarker](q.driver, FeatureDirty); ok {
+		return marker.SetDirty(ctx, m.Version, dirty)
+	}
+
+	applied, ok := q.applied[m.Version]
+	if dirty || !ok || !applied.Dirty {
+		return nil
+	}
+	return q.record(ctx, m, RecordMeta{
+		Batch:     applied.Batch,
+		Duration:  applied.Duration,
+		AppliedBy: applied.AppliedBy,
+		Hostname:  applied.Hostname,
+		Operator:  applied.Operator,
+		BuildInfo: applied.BuildInfo,
+	})
+}
+
+// RepairChecksums rewrites the stored checksum of applied migrations to
+// match their registered content, for migrations that were edited on
+// purpose, e.g. reformatted. If versions are given, only those are
+// rewritten; otherwise every applied migration whose checksum differs is.
+//
+// Returns the versions whose checksum was rewritten. Returns
+// ErrMigrationNotFound if a given version is not both registered and
+// applied, and ErrUnsupported if the driver doesn't implement
+// ChecksumUpdater.
+func (q *Queen) RepairChecksums(ctx context.Context, versions ...string) ([]string, error) {
+	updater, ok := optional[ChecksumUpdater](q.driver, FeatureChecksumUpdate)
+	if q.driver != nil && !ok {
+		return nil, fmt.Errorf("%w: checksum repair", ErrUnsupported)
+	}
+
+	var repaired []string
+
+	err := q.withLock(ctx, func() error {
+		allowed := make(map[string]bool, len(versions))
+		for _, version := range versions {
+			if _, applied := q.applied[version]; !applied || !q.hasVersion(version) {
+				return fmt.Errorf("%w: %s", ErrMigrationNotFound, version)
+			}
+			allowed[version] = true
+		}
+
+		for _, m := range q.migrations {
+			applied, ok := q.applied[m.Version]
+			if !ok || applied.Checksum == m.Checksum() {
+				continue
+			}
+			if len(allowed) > 0 && !allowed[m.Version] {
+				continue
+			}
+
+			if err := updater.UpdateChecksum(ctx, m.Version, m.Checksum()); err != nil {
+				return err
+			}
+			applied.Checksum = m.Checksum()
+			repaired = append(repaired, m.Version)
+		}
+		return nil
+	})
+
+	sort.Slice(repaired, func(i, j int) bool {
+		return naturalsort.Compare(repaired[i], repaired[j]) < 0
+	})
+
+	return repaired, err
+}
+
+// ResetHard empties the database and applies every migration again. Unlike
+// Reset, it doesn't run down migrations: the driver drops everything in the
+// schema, including the tracking table and history, which is faster and
+// works when down migrations are broken or missing. Meant for development
+// databases.
+//
+// Returns ErrUnsupported if the driver doesn't implement SchemaDropper.
+func (q *Queen) ResetHard(ctx context.Context) error {
+	dropper, ok := optional[SchemaDropper](q.driver, FeatureDropSchema)
+	if q.driver != nil && !ok {
+		return fmt.Errorf("%w: schema drop", ErrUnsupported)
+	}
+
+	if len(q.migrations) == 0 {
+		return ErrNoMigrations
+	}
+
+	return q.withLock(ctx, func() error {
+		if err := dropper.DropSchema(ctx); err != nil {
+			return err
+		}
+
+		if err := q.init(ctx); err != nil {
+			return err
+		}
+
+		return q.upLocked(ctx, 0, nil)
+	})
+}
+
+// Fresh drops everything in the database and applies all migrations from
+// scratch, like Laravel's migrate:fresh. It is ResetHard with a guard: when
+// Config.Environment is "production", it returns ErrProduction unless
+// force is true.
+func (q *Queen) Fresh(ctx context.Context, force bool) error {
+	if q.config.Environment == "production" && !force {
+		return fmt.Errorf("%w: fresh drops all data, pass force to run it anyway", ErrProduction)
+	}
+
+	return q.ResetHard(ctx)
+}
+
+// withLock initializes the driver, takes the migration lock and loads
+// applied migrations before calling fn.
+func (q *Queen) withLock(ctx context.Context, fn func() error) error {
+	if q.driver == nil {
+		return ErrNoDriver
+	}
+
+	if err := q.init(ctx); err != nil {
+		return err
+	}
+
+	unlock, err := q.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := q.loadApplied(ctx); err != nil {
+		return err
+	}
+
+	return fn()
+}
+
+// dirtyVersions returns versions with a dirty record, in natural sort order.
+func (q *Queen) dirtyVersions() []string {
+	var versions []string
+	for version, a := range q.applied {
+		if a.Dirty {
+			versions = append(versions, version)
+		}
+	}
+
+	sort.Slice(versions, func(i, j int) bool {
+		return naturalsort.Compare(versions[i], versions[j]) < 0
+	})
+
+	return versions
+}
+
+// checkDirty returns ErrDirty if any applied migration is dirty.
+func (q *Queen) checkDirty() error {
+	dirty := q.dirtyVersions()
+	if len(dirty) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("%w: %s (fix the database, then run Repair or Force)",
+		ErrDirty, strings.Join(dirty, ", "))
+}
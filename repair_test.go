@@ -0,0 +1,200 @@
+package queen_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/honeynil/queen"
+	"github.com/honeynil/queen/drivers/mock"
+)
+
+func TestRepair(t *testing.T) {
+	q, driver := newMockQueen(t, "001", "002")
+	ctx := context.Background()
+
+	// Simulate a run interrupted while applying 002
+	if err := q.UpSteps(ctx, 1); err != nil {
+		t.Fatalf("UpSteps failed: %v", err)
+	}
+	m := &queen.M{Version: "002", Name: "migration_002", ManualChecksum: "v1", UpFunc: noop}
+	if err := driver.RecordWithMeta(ctx, m, queen.RecordMeta{Batch: 2, Dirty: true}); err != nil {
+		t.Fatalf("Record failed: %v", err)
+	}
+
+	if err := q.Up(ctx); !errors.Is(err, queen.ErrDirty) {
+		t.Fatalf("Expected ErrDirty from Up, got %v", err)
+	}
+	if err := q.Down(ctx, 1); !errors.Is(err, queen.ErrDirty) {
+		t.Fatalf("Expected ErrDirty from Down, got %v", err)
+	}
+
+	statuses, err := q.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status failed: %v", err)
+	}
+	if statuses[1].Status != queen.StatusDirty {
+		t.Errorf("Expected 002 to be dirty, got %s", statuses[1].Status)
+	}
+
+	cleared, err := q.Repair(ctx)
+	if err != nil {
+		t.Fatalf("Repair failed: %v", err)
+	}
+	if len(cleared) != 1 || cleared[0] != "002" {
+		t.Errorf("Expected 002 to be cleared, got %v", cleared)
+	}
+
+	if err := q.Up(ctx); err != nil {
+		t.Fatalf("Up after Repair failed: %v", err)
+	}
+	if !driver.HasVersion("002") {
+		t.Error("Expected 002 to be applied after Repair and Up")
+	}
+}
+
+func TestForce(t *testing.T) {
+	q, driver := newMockQueen(t, "001", "002")
+	ctx := context.Background()
+
+	m := &queen.M{Version: "001", Name: "migration_001", ManualChecksum: "v1", UpFunc: noop}
+	if err := driver.RecordWithMeta(ctx, m, queen.RecordMeta{Batch: 1, Dirty: true}); err != nil {
+		t.Fatalf("Record failed: %v", err)
+	}
+
+	if err := q.Force(ctx, "001"); err != nil {
+		t.Fatalf("Force failed: %v", err)
+	}
+	if err := q.Force(ctx, "002"); err != nil {
+		t.Fatalf("Force of unrecorded version failed: %v", err)
+	}
+	if err := q.Force(ctx, "999"); !errors.Is(err, queen.ErrMigrationNotFound) {
+		t.Errorf("Expected ErrMigrationNotFound, got %v", err)
+	}
+
+	if err := q.Validate(ctx); err != nil {
+		t.Fatalf("Validate after Force failed: %v", err)
+	}
+	if driver.AppliedCount() != 2 {
+		t.Errorf("Expected 2 applied migrations, got %d", driver.AppliedCount())
+	}
+}
+
+// noDirtyDriver implements DirtyMarker but reports it unsupported.
+type noDirtyDriver struct {
+	*mock.Driver
+}
+
+func (noDirtyDriver) SupportsFeature(f queen.Feature) bool {
+	return f != queen.FeatureDirty
+}
+
+func TestForce_WithoutDirtyMarker(t *testing.T) {
+	driver := noDirtyDriver{mock.New()}
+	q := queen.New(driver)
+	q.MustAdd(queen.M{Version: "001", Name: "first", ManualChecksum: "v1", UpFunc: noop})
+	ctx := context.Background()
+
+	m := &queen.M{Version: "001", Name: "first", ManualChecksum: "v1", UpFunc: noop}
+	if err := driver.RecordWithMeta(ctx, m, queen.RecordMeta{Batch: 3, Dirty: true, Operator: "alice"}); err != nil {
+		t.Fatalf("Record failed: %v", err)
+	}
+
+	if err := q.Force(ctx, "001"); err != nil {
+		t.Fatalf("Force failed: %v", err)
+	}
+
+	applied, err := driver.GetApplied(ctx)
+	if err != nil {
+		t.Fatalf("GetApplied failed: %v", err)
+	}
+	if len(applied) != 1 || applied[0].Dirty || applied[0].Batch != 3 || applied[0].Operator != "alice" {
+		t.Errorf("expected a clean record keeping batch and operator, got %+v", applied)
+	}
+}
+
+func TestRepairChecksums(t *testing.T) {
+	q, driver := newMockQueen(t, "001", "002", "003")
+	ctx := context.Background()
+
+	if err := q.Up(ctx); err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+
+	// Edited on purpose after being applied
+	q = queen.New(driver)
+	for _, v := range []string{"001", "002", "003"} {
+		sum := "v1"
+		if v != "001" {
+			sum = "v2"
+		}
+		q.MustAdd(queen.M{Version: v, Name: "migration_" + v, ManualChecksum: sum, UpFunc: noop})
+	}
+
+	if _, err := q.RepairChecksums(ctx, "004"); !errors.Is(err, queen.ErrMigrationNotFound) {
+		t.Fatalf("Expected ErrMigrationNotFound, got %v", err)
+	}
+
+	repaired, err := q.RepairChecksums(ctx, "002")
+	if err != nil {
+		t.Fatalf("RepairChecksums failed: %v", err)
+	}
+	if len(repaired) != 1 || repaired[0] != "002" {
+		t.Errorf("Expected 002 to be repaired, got %v", repaired)
+	}
+	if err := q.Validate(ctx); !errors.Is(err, queen.ErrChecksumMismatch) {
+		t.Fatalf("Expected 003 to still mismatch, got %v", err)
+	}
+
+	repaired, err = q.RepairChecksums(ctx)
+	if err != nil {
+		t.Fatalf("RepairChecksums failed: %v", err)
+	}
+	if len(repaired) != 1 || repaired[0] != "003" {
+		t.Errorf("Expected 003 to be repaired, got %v", repaired)
+	}
+	if err := q.Validate(ctx); err != nil {
+		t.Errorf("Validate after repair failed: %v", err)
+	}
+}
+
+func TestFresh(t *testing.T) {
+	driver := mock.New()
+	ctx := context.Background()
+
+	config := queen.DefaultConfig()
+	config.Environment = "production"
+	q := queen.NewWithConfig(driver, config)
+	q.MustAdd(queen.M{Version: "001", Name: "first", UpFunc: noop})
+
+	if err := q.Up(ctx); err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+	if err := q.Fresh(ctx, false); !errors.Is(err, queen.ErrProduction) {
+		t.Fatalf("Expected ErrProduction, got %v", err)
+	}
+
+	history, err := q.History(ctx)
+	if err != nil {
+		t.Fatalf("History failed: %v", err)
+	}
+	if len(history) != 1 {
+		t.Fatalf("Expected refused Fresh to keep history, got %d entries", len(history))
+	}
+
+	if err := q.Fresh(ctx, true); err != nil {
+		t.Fatalf("Fresh with force failed: %v", err)
+	}
+	if !driver.HasVersion("001") {
+		t.Error("Expected 001 to be applied again")
+	}
+
+	// Dropped along with everything else, then written by the new run
+	history, err = q.History(ctx)
+	if err != nil {
+		t.Fatalf("History failed: %v", err)
+	}
+	if len(history) != 1 {
+		t.Errorf("Expected one history entry after Fresh, got %d", len(history))
+	}
+}
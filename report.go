@@ -0,0 +1,200 @@
+package queen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Operation identifies the kind of run that produced a RunReport.
+type Operation string
+
+// Operations reported in RunReport.
+const (
+	OperationUp            Operation = "up"
+	OperationDown          Operation = "down"
+	OperationReset         Operation = "reset"
+	OperationRollbackBatch Operation = "rollback_batch"
+)
+
+// RunReport is the full record of a single Up, Down, Reset or RollbackBatch
+// run. It is passed to Config.Archive after the run completes.
+type RunReport struct {
+	// ID uniquely identifies the run. See Config.IDGenerator.
+	ID string `json:"id"`
+
+	// Operation is the kind of run.
+	Operation Operation `json:"operation"`
+
+	// StartedAt and FinishedAt bound the run.
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+
+	// Plan lists the versions the run intended to apply or roll back, in order.
+	Plan []string `json:"plan"`
+
+	// Migrations holds one entry per migration that was executed.
+	Migrations []MigrationReport `json:"migrations"`
+
+	// Warnings collects messages from EventWarning events emitted during the run.
+	Warnings []string `json:"warnings,omitempty"`
+
+	// Error is the error the run returned, if any.
+	Error string `json:"error,omitempty"`
+
+	// ChangeTicket is the reference of the change ticket opened by
+	// Config.ChangeManagement, if any.
+	ChangeTicket string `json:"change_ticket,omitempty"`
+
+	// LockWait is how long the run waited to acquire the migration lock.
+	LockWait time.Duration `json:"lock_wait_ns,omitempty"`
+
+	// StartPosition and EndPosition are the database's replication
+	// position before and after the run, if the driver implements
+	// PositionReporter and could read it.
+	StartPosition *ReplicationPosition `json:"start_position,omitempty"`
+	EndPosition   *ReplicationPosition `json:"end_position,omitempty"`
+}
+
+// ReplicationPosition is a point in the replication log of a database,
+// to align point-in-time recovery or a replica rebuild with the start or
+// end of a run.
+type ReplicationPosition struct {
+	// File and Offset locate the point in the binary log, e.g.
+	// "binlog.000042" and 1547.
+	File   string `json:"file,omitempty"`
+	Offset int64  `json:"offset,omitempty"`
+
+	// GTIDSet is the set of executed global transaction IDs, if the
+	// database has them enabled.
+	GTIDSet string `json:"gtid_set,omitempty"`
+}
+
+// PositionReporter is implemented by drivers that can read the database's
+// replication position, recorded in every RunReport.
+type PositionReporter interface {
+	// ReplicationPosition returns the current position. It typically
+	// needs privileges migrations don't; failing to read it only adds a
+	// warning to the report.
+	ReplicationPosition(ctx context.Context) (*ReplicationPosition, error)
+}
+
+// MigrationReport records the execution of a single migration within a run.
+type MigrationReport struct {
+	Version    string    `json:"version"`
+	Name       string    `json:"name"`
+	Down       bool      `json:"down"`
+	StartedAt  time.Time `json:"started_at"`
+	DurationMS int64     `json:"duration_ms"`
+	Error      string    `json:"error,omitempty"`
+	Backup     string    `json:"backup,omitempty"`
+}
+
+// ArchiveSink receives the report of every run, e.g. to retain it in
+// object storage for audits. See the archive package for implementations.
+type ArchiveSink interface {
+	Archive(ctx context.Context, report *RunReport) error
+}
+
+// run executes fn as a reported run over plan, within a change ticket if
+// Config.ChangeManagement is set. The report is handed to the configured
+// ArchiveSink once fn returns; ticket and archive failures are joined with
+// the run's own error.
+func (q *Queen) run(ctx context.Context, op Operation, plan []*Migration, fn func() error) error {
+	report := &RunReport{
+		ID:         q.newID(),
+		Operation:  op,
+		StartedAt:  time.Now(),
+		LockWait:   q.lockWait,
+		Plan:       make([]string, len(plan)),
+		Migrations: make([]MigrationReport, 0, len(plan)),
+	}
+	for i, m := range plan {
+		report.Plan[i] = m.Version
+	}
+
+	if err := q.openChange(ctx, report, plan); err != nil {
+		return err
+	}
+
+	report.StartPosition = q.replicationPosition(ctx, report)
+	q.report = report
+	q.startLive(ctx, report, plan)
+	err := fn()
+	q.stopLive()
+	q.report = nil
+	report.EndPosition = q.replicationPosition(ctx, report)
+
+	report.FinishedAt = time.Now()
+	if err != nil {
+		report.Error = err.Error()
+	}
+	err = q.closeChange(ctx, report, err)
+
+	if q.config.Archive != nil {
+		if archiveErr := q.config.Archive.Archive(ctx, report); archiveErr != nil {
+			err = errors.Join(err, fmt.Errorf("archive run report: %w", archiveErr))
+		}
+	}
+
+	return err
+}
+
+// replicationPosition reads the replication position for report, if the
+// driver implements PositionReporter. Failures are added to its warnings.
+func (q *Queen) replicationPosition(ctx context.Context, report *RunReport) *ReplicationPosition {
+	reporter, ok := optional[PositionReporter](q.driver, FeatureReplicationPosition)
+	if !ok {
+		return nil
+	}
+
+	pos, err := reporter.ReplicationPosition(ctx)
+	if err != nil {
+		report.Warnings = append(report.Warnings, fmt.Sprintf("replication position unavailable: %v", err))
+		return nil
+	}
+	return pos
+}
+
+// emit tracks the progress of the current run, records warnings and
+// executions in its report, appends executions to the history log and
+// forwards the event to hooks.
+func (q *Queen) emit(ctx context.Context, e Event) error {
+	q.trackLive(e)
+	switch e.Kind {
+	case EventBeforeUp, EventAfterUp, EventBeforeDown, EventAfterDown:
+		if p := q.CurrentRun(); p != nil {
+			e.ETA = p.ETA
+		}
+	}
+
+	if q.report != nil {
+		switch e.Kind {
+		case EventWarning:
+			if e.Err != nil {
+				q.report.Warnings = append(q.report.Warnings, e.Err.Error())
+			}
+		case EventAfterUp, EventAfterDown, EventFailed:
+			mr := MigrationReport{
+				Version:    e.Migration.Version,
+				Name:       e.Migration.Name,
+				Down:       e.Down,
+				StartedAt:  time.Now().Add(-e.Duration),
+				DurationMS: e.Duration.Milliseconds(),
+				Backup:     e.Backup,
+			}
+			if e.Err != nil {
+				mr.Error = e.Err.Error()
+			}
+			q.report.Migrations = append(q.report.Migrations, mr)
+		}
+	}
+
+	switch e.Kind {
+	case EventAfterUp, EventAfterDown, EventFailed:
+		q.recordHistory(ctx, e)
+	}
+
+	return q.hooks.emit(ctx, e)
+}
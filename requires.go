@@ -0,0 +1,253 @@
+package queen
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Requirement kinds accepted in Migration.Requires, written "kind:value".
+const (
+	// RequireExtension requires an installed database extension,
+	// e.g. "extension:postgis".
+	RequireExtension = "extension"
+
+	// RequireFlag requires a flag enabled in Config.Flags,
+	// e.g. "flag:allow_big_table_rewrite".
+	RequireFlag = "flag"
+
+	// RequireMinVersion requires a minimum server version of any dialect,
+	// e.g. "min_version:8.0". Dialect-specific forms are written
+	// "min_<dialect>_version", e.g. "min_pg_version:14".
+	RequireMinVersion = "min_version"
+)
+
+// dialectAliases maps short dialect names used in requirements to the
+// names reported in Capabilities.Dialect.
+var dialectAliases = map[string]string{
+	"pg": "postgres",
+}
+
+// dialectNames are display names used in requirement errors.
+var dialectNames = map[string]string{
+	"postgres": "PostgreSQL",
+	"mysql":    "MySQL",
+	"sqlite":   "SQLite",
+}
+
+// Capabilities describes the database a driver is connected to.
+type Capabilities struct {
+	// Dialect is the database kind, e.g. "postgres", "mysql" or "sqlite".
+	Dialect string
+
+	// Version is the server version, e.g. "14.5" or "8.0.36".
+	Version string
+
+	// Extensions lists installed extensions.
+	Extensions []string
+}
+
+// CapabilityReporter is implemented by drivers that can describe their
+// database, so migrations can declare prerequisites in Migration.Requires.
+type CapabilityReporter interface {
+	// Capabilities reports the database kind, version and extensions.
+	Capabilities(ctx context.Context) (Capabilities, error)
+}
+
+// RequirementError reports a requirement of a migration that isn't met.
+// It matches ErrUnmetRequirement with errors.Is.
+type RequirementError struct {
+	// Requirement is the unmet entry from Migration.Requires, or the
+	// "min_version:" requirement implied by Migration.MinDBVersion.
+	Requirement string
+
+	// Reason explains why the requirement isn't met.
+	Reason string
+}
+
+func (e *RequirementError) Error() string {
+	return fmt.Sprintf("%v %q: %s", ErrUnmetRequirement, e.Requirement, e.Reason)
+}
+
+// Is reports whether target is ErrUnmetRequirement.
+func (e *RequirementError) Is(target error) bool {
+	return target == ErrUnmetRequirement
+}
+
+// parseRequirement splits a requirement into its kind and value. For
+// dialect-specific minimum versions, dialect is set and kind is
+// RequireMinVersion.
+func parseRequirement(req string) (kind, dialect, value string, ok bool) {
+	kind, value, ok = strings.Cut(req, ":")
+	if !ok || value == "" {
+		return "", "", "", false
+	}
+
+	switch kind {
+	case RequireExtension, RequireFlag:
+		return kind, "", value, true
+	case RequireMinVersion:
+		return kind, "", value, len(versionParts(value)) > 0
+	}
+
+	if name, found := strings.CutPrefix(kind, "min_"); found {
+		if name, found = strings.CutSuffix(name, "_version"); found && name != "" {
+			if alias, ok := dialectAliases[name]; ok {
+				name = alias
+			}
+			return RequireMinVersion, name, value, len(versionParts(value)) > 0
+		}
+	}
+
+	return "", "", "", false
+}
+
+// checkRequirements verifies the requirements of every migration before
+// any of them runs. Capabilities are fetched once, and only if needed.
+func (q *Queen) checkRequirements(ctx context.Context, migrations []*Migration) error {
+	return q.checkRequirementsWith(migrations, func() (*Capabilities, string, error) {
+		reporter, ok := optional[CapabilityReporter](q.driver, FeatureCapabilities)
+		if !ok {
+			return nil, "driver does not report capabilities", nil
+		}
+		c, err := reporter.Capabilities(ctx)
+		if err != nil {
+			return nil, "", err
+		}
+		return &c, "", nil
+	})
+}
+
+// checkRequirementsWith verifies requirements like checkRequirements, getting
+// capabilities from capabilities on first use. capabilities returns a reason
+// instead of capabilities if they are unavailable, or neither to skip all
+// but flag requirements.
+func (q *Queen) checkRequirementsWith(migrations []*Migration, capabilities func() (*Capabilities, string, error)) error {
+	var caps *Capabilities
+	skip := false
+
+	for _, m := range migrations {
+		for _, req := range m.requirements() {
+			kind, dialect, value, _ := parseRequirement(req)
+
+			if kind == RequireFlag {
+				if !q.config.Flags[value] {
+					return newMigrationError(m.Version, m.Name,
+						&RequirementError{Requirement: req, Reason: "flag not enabled in Config.Flags"})
+				}
+				continue
+			}
+
+			if skip {
+				continue
+			}
+			if caps == nil {
+				c, reason, err := capabilities()
+				if err != nil {
+					return err
+				}
+				if c == nil && reason == "" {
+					skip = true
+					continue
+				}
+				if c == nil {
+					return newMigrationError(m.Version, m.Name, &RequirementError{Requirement: req, Reason: reason})
+				}
+				caps = c
+			}
+
+			if reason := caps.unmet(kind, dialect, value); reason != "" {
+				return newMigrationError(m.Version, m.Name, &RequirementError{Requirement: req, Reason: reason})
+			}
+		}
+	}
+
+	return nil
+}
+
+// unmet returns why a requirement isn't met, or "" if it is.
+func (c *Capabilities) unmet(kind, dialect, value string) string {
+	switch kind {
+	case RequireExtension:
+		if !slices.Contains(c.Extensions, value) {
+			return "extension not installed"
+		}
+
+	case RequireMinVersion:
+		if dialect != "" && dialect != c.Dialect {
+			return fmt.Sprintf("requires %s, found %s", dialectName(dialect), dialectName(c.Dialect))
+		}
+		if compareVersions(c.Version, value) < 0 {
+			name := dialectName(c.Dialect)
+			if dialect != "" {
+				name = dialectName(dialect)
+			}
+			return fmt.Sprintf("requires %s ≥ %s, found %s", name, value, c.Version)
+		}
+	}
+
+	return ""
+}
+
+// requirements returns Requires plus the requirement implied by MinDBVersion.
+func (m *Migration) requirements() []string {
+	if m.MinDBVersion == "" {
+		return m.Requires
+	}
+	return append(slices.Clip(m.Requires), RequireMinVersion+":"+m.MinDBVersion)
+}
+
+// dialectName returns the display name of a dialect.
+func dialectName(dialect string) string {
+	if name, ok := dialectNames[dialect]; ok {
+		return name
+	}
+	return dialect
+}
+
+// compareVersions compares the leading dotted numbers of two version
+// strings, so "14.5 (Debian 14.5-1)" compares greater than "14".
+func compareVersions(a, b string) int {
+	pa, pb := versionParts(a), versionParts(b)
+
+	for i := 0; i < max(len(pa), len(pb)); i++ {
+		var x, y int
+		if i < len(pa) {
+			x = pa[i]
+		}
+		if i < len(pb) {
+			y = pb[i]
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+
+	return 0
+}
+
+// versionParts parses the leading dotted numbers of a version string.
+func versionParts(v string) []int {
+	end := strings.IndexFunc(v, func(r rune) bool {
+		return r != '.' && (r < '0' || r > '9')
+	})
+	if end >= 0 {
+		v = v[:end]
+	}
+
+	var parts []int
+	for _, s := range strings.Split(v, ".") {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			break
+		}
+		parts = append(parts, n)
+	}
+
+	return parts
+}
@@ -0,0 +1,119 @@
+package queen_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/honeynil/queen"
+	"github.com/honeynil/queen/drivers/mock"
+)
+
+// capsDriver is a mock driver reporting fixed capabilities.
+type capsDriver struct {
+	*mock.Driver
+	caps queen.Capabilities
+}
+
+func (d *capsDriver) Capabilities(ctx context.Context) (queen.Capabilities, error) {
+	return d.caps, nil
+}
+
+func TestRequires(t *testing.T) {
+	caps := queen.Capabilities{Dialect: "postgres", Version: "14.5 (Debian 14.5-1)", Extensions: []string{"postgis"}}
+
+	tests := []struct {
+		requires string
+		flags    map[string]bool
+		wantErr  bool
+	}{
+		{requires: "extension:postgis"},
+		{requires: "extension:pg_trgm", wantErr: true},
+		{requires: "min_version:9.6"},
+		{requires: "min_pg_version:14"},
+		{requires: "min_postgres_version:14.5"},
+		{requires: "min_pg_version:14.6", wantErr: true},
+		{requires: "min_pg_version:15", wantErr: true},
+		{requires: "min_mysql_version:5.7", wantErr: true},
+		{requires: "flag:allow_big_table_rewrite", flags: map[string]bool{"allow_big_table_rewrite": true}},
+		{requires: "flag:allow_big_table_rewrite", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.requires, func(t *testing.T) {
+			driver := &capsDriver{Driver: mock.New(), caps: caps}
+			q := queen.NewWithConfig(driver, &queen.Config{Flags: tt.flags})
+			q.MustAdd(queen.M{Version: "001", Name: "first", UpFunc: noop})
+			q.MustAdd(queen.M{Version: "002", Name: "second", UpFunc: noop, Requires: []string{tt.requires}})
+
+			err := q.Up(context.Background())
+			if tt.wantErr {
+				if !errors.Is(err, queen.ErrUnmetRequirement) {
+					t.Fatalf("Expected ErrUnmetRequirement, got %v", err)
+				}
+				// Nothing runs if any requirement is unmet
+				if driver.AppliedCount() != 0 {
+					t.Errorf("Expected no migrations applied, got %d", driver.AppliedCount())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Up failed: %v", err)
+			}
+		})
+	}
+}
+
+func TestRequiresWithoutCapabilities(t *testing.T) {
+	q := queen.New(mock.New())
+	q.MustAdd(queen.M{Version: "001", Name: "first", UpFunc: noop, Requires: []string{"extension:postgis"}})
+
+	var reqErr *queen.RequirementError
+	if err := q.Up(context.Background()); !errors.As(err, &reqErr) {
+		t.Fatalf("Expected RequirementError, got %v", err)
+	}
+	if reqErr.Requirement != "extension:postgis" {
+		t.Errorf("Requirement = %q, want %q", reqErr.Requirement, "extension:postgis")
+	}
+}
+
+func TestRequiresInvalid(t *testing.T) {
+	q := queen.New(mock.New())
+
+	for _, req := range []string{"postgis", "extension:", "max_version:1", "min__version:1"} {
+		err := q.Add(queen.M{Version: "001", Name: "first", UpFunc: noop, Requires: []string{req}})
+		if !errors.Is(err, queen.ErrInvalidMigration) {
+			t.Errorf("Add with requirement %q: expected ErrInvalidMigration, got %v", req, err)
+		}
+	}
+}
+
+func TestMinDBVersion(t *testing.T) {
+	driver := &capsDriver{Driver: mock.New(), caps: queen.Capabilities{Dialect: "mysql", Version: "5.7.44-log"}}
+	q := queen.New(driver)
+	ctx := context.Background()
+
+	q.MustAdd(queen.M{Version: "001", Name: "create_users", UpFunc: noop, MinDBVersion: "5.7"})
+	q.MustAdd(queen.M{Version: "002", Name: "add_check", UpFunc: noop, MinDBVersion: "8.0.16"})
+
+	// Validate fails fast, before anything is applied
+	err := q.Validate(ctx)
+	if !errors.Is(err, queen.ErrUnmetRequirement) {
+		t.Fatalf("Expected ErrUnmetRequirement, got %v", err)
+	}
+	if want := "requires MySQL ≥ 8.0.16, found 5.7.44-log"; !strings.Contains(err.Error(), want) {
+		t.Errorf("Expected error to contain %q, got %q", want, err.Error())
+	}
+
+	if err := q.Up(ctx); !errors.Is(err, queen.ErrUnmetRequirement) {
+		t.Fatalf("Expected ErrUnmetRequirement from Up, got %v", err)
+	}
+	if driver.AppliedCount() != 0 {
+		t.Errorf("Expected no migrations applied, got %d", driver.AppliedCount())
+	}
+
+	if err := q.Add(queen.M{Version: "003", Name: "bad", UpFunc: noop, MinDBVersion: "latest"}); !errors.Is(err, queen.ErrInvalidMigration) {
+		t.Errorf("Expected ErrInvalidMigration for malformed MinDBVersion, got %v", err)
+	}
+}
@@ -0,0 +1,112 @@
+package queen
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"syscall"
+	"time"
+)
+
+// Defaults of RetryConfig.
+const (
+	DefaultRetryAttempts   = 10
+	DefaultRetryBackoff    = 500 * time.Millisecond
+	DefaultRetryMaxBackoff = 30 * time.Second
+)
+
+// RetryConfig configures UpWithRetry.
+type RetryConfig struct {
+	// MaxAttempts is how many times Up is tried in all.
+	// Default: DefaultRetryAttempts
+	MaxAttempts int
+
+	// Backoff is the wait before the first retry, doubled for each next
+	// one up to MaxBackoff. Each wait is jittered down by up to half, so
+	// replicas starting together don't retry in lockstep.
+	// Default: DefaultRetryBackoff
+	Backoff time.Duration
+
+	// MaxBackoff caps the wait between attempts.
+	// Default: DefaultRetryMaxBackoff
+	MaxBackoff time.Duration
+
+	// Retryable reports whether a failed attempt is tried again.
+	// Default: IsTransient
+	Retryable func(err error) bool
+
+	// OnRetry, if set, is called before waiting to retry a failed attempt,
+	// e.g. to log it. attempt counts from 1.
+	OnRetry func(attempt int, err error, wait time.Duration)
+}
+
+// UpWithRetry applies all pending migrations like Up, retrying failures
+// that are likely to pass with time, such as a database that isn't
+// reachable yet or a lock held by another replica, with exponential
+// backoff. It is meant for the start of a service, e.g. in a Kubernetes
+// init container.
+//
+// Once attempts run out, the last error is returned. A cancelled ctx stops
+// retrying.
+func (q *Queen) UpWithRetry(ctx context.Context, config RetryConfig) error {
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = DefaultRetryAttempts
+	}
+	if config.Backoff <= 0 {
+		config.Backoff = DefaultRetryBackoff
+	}
+	if config.MaxBackoff <= 0 {
+		config.MaxBackoff = DefaultRetryMaxBackoff
+	}
+	if config.Retryable == nil {
+		config.Retryable = IsTransient
+	}
+
+	backoff := config.Backoff
+	for attempt := 1; ; attempt++ {
+		err := q.Up(ctx)
+		if err == nil || !config.Retryable(err) || ctx.Err() != nil {
+			return err
+		}
+		if attempt >= config.MaxAttempts {
+			return fmt.Errorf("after %d attempts: %w", attempt, err)
+		}
+
+		wait := backoff - rand.N(backoff/2+1)
+		if config.OnRetry != nil {
+			config.OnRetry(attempt, err, wait)
+		}
+
+		select {
+		case <-time.After(wait):
+			backoff = min(backoff*2, config.MaxBackoff)
+		case <-ctx.Done():
+			return errors.Join(err, ctx.Err())
+		}
+	}
+}
+
+// IsTransient reports whether err is a failure to reach the database or
+// to take the migration lock, which UpWithRetry retries by default.
+// Failures of a migration itself aren't transient: it may have left
+// partial changes that need a look before running it again.
+func IsTransient(err error) bool {
+	var migrationErr *MigrationError
+	if errors.As(err, &migrationErr) {
+		return false
+	}
+
+	var netErr net.Error
+	return errors.Is(err, ErrLockTimeout) ||
+		errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, sql.ErrConnDone) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.As(err, &netErr)
+}
@@ -0,0 +1,100 @@
+package queen_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/honeynil/queen"
+	"github.com/honeynil/queen/drivers/mock"
+)
+
+func TestUpWithRetry(t *testing.T) {
+	driver := mock.New()
+	q := queen.New(driver)
+	ctx := context.Background()
+	q.MustAdd(queen.M{Version: "001", Name: "create_users", UpFunc: noop})
+
+	// The database is unreachable, then its lock is held by another
+	// replica, then it is free
+	driver.SetInitError(&net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED})
+	var retried []int
+	err := q.UpWithRetry(ctx, queen.RetryConfig{
+		Backoff: time.Millisecond,
+		OnRetry: func(attempt int, err error, wait time.Duration) {
+			retried = append(retried, attempt)
+			switch attempt {
+			case 1:
+				driver.SetInitError(nil)
+				driver.SetLockError(fmt.Errorf("held by replica-2: %w", queen.ErrLockTimeout))
+			case 2:
+				driver.SetLockError(nil)
+			}
+			if wait > time.Duration(attempt)*time.Millisecond*2 {
+				t.Errorf("Wait %d = %v; want exponential backoff from 1ms", attempt, wait)
+			}
+		},
+	})
+	if err != nil {
+		t.Fatalf("UpWithRetry failed: %v", err)
+	}
+	if len(retried) != 2 || !driver.HasVersion("001") {
+		t.Errorf("Retried %v, applied %v; want 2 retries then 001 applied", retried, driver.HasVersion("001"))
+	}
+}
+
+func TestUpWithRetry_GivesUp(t *testing.T) {
+	driver := mock.New()
+	q := queen.New(driver)
+	ctx := context.Background()
+	q.MustAdd(queen.M{Version: "001", Name: "create_users", UpFunc: noop})
+
+	driver.SetLockError(queen.ErrLockTimeout)
+	attempts := 0
+	err := q.UpWithRetry(ctx, queen.RetryConfig{
+		MaxAttempts: 3,
+		Backoff:     time.Millisecond,
+		OnRetry:     func(int, error, time.Duration) { attempts++ },
+	})
+	if !errors.Is(err, queen.ErrLockTimeout) || attempts != 2 {
+		t.Errorf("UpWithRetry = %v after %d retries; want ErrLockTimeout after 2", err, attempts)
+	}
+
+	// Failing migrations aren't retried
+	driver.SetLockError(nil)
+	q = queen.New(driver)
+	q.MustAdd(queen.M{Version: "001", Name: "broken", UpFunc: func(ctx context.Context, tx *sql.Tx) error {
+		return &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}
+	}})
+	attempts = 0
+	err = q.UpWithRetry(ctx, queen.RetryConfig{
+		Backoff: time.Millisecond,
+		OnRetry: func(int, error, time.Duration) { attempts++ },
+	})
+	if err == nil || attempts != 0 {
+		t.Errorf("UpWithRetry = %v after %d retries; want the migration's error without retries", err, attempts)
+	}
+}
+
+func TestIsTransient(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{queen.ErrLockTimeout, true},
+		{fmt.Errorf("init: %w", &net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}), true},
+		{sql.ErrConnDone, true},
+		{queen.ErrChecksumMismatch, false},
+		{&queen.MigrationError{Version: "001", Err: sql.ErrConnDone}, false},
+	}
+	for _, tt := range tests {
+		if got := queen.IsTransient(tt.err); got != tt.want {
+			t.Errorf("IsTransient(%v) = %v; want %v", tt.err, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package queen
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// RowDelta bounds the change in a table's row count made by applying a
+// migration. See Migration.ExpectRowDelta.
+type RowDelta struct {
+	// Table is the table counted, optionally qualified with a schema. It
+	// must be a plain identifier.
+	Table string
+
+	// Min and Max bound the rows after the migration minus the rows
+	// before, inclusive, e.g. -100 and 0 for a cleanup deleting at most
+	// 100 rows.
+	Min, Max int64
+}
+
+// rowCounter is a transaction or connection row counts are taken in.
+type rowCounter interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// validateRowDeltas checks the tables and bounds of m.ExpectRowDelta.
+func (m *Migration) validateRowDeltas() error {
+	for _, d := range m.ExpectRowDelta {
+		if !plainTableName.MatchString(d.Table) {
+			return fmt.Errorf("%w: row delta of %q: table name must be a plain identifier", ErrInvalidMigration, d.Table)
+		}
+		if d.Min > d.Max {
+			return fmt.Errorf("%w: row delta of %s: min %d is greater than max %d", ErrInvalidMigration, d.Table, d.Min, d.Max)
+		}
+	}
+	return nil
+}
+
+// countRows returns the row count of each table of m.ExpectRowDelta.
+func (m *Migration) countRows(ctx context.Context, db rowCounter) ([]int64, error) {
+	var counts []int64
+	for _, d := range m.ExpectRowDelta {
+		var n int64
+		if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+d.Table).Scan(&n); err != nil {
+			return nil, fmt.Errorf("count rows of %s: %w", d.Table, err)
+		}
+		counts = append(counts, n)
+	}
+	return counts, nil
+}
+
+// checkRowDeltas compares the row counts before the migration with the
+// current ones and returns ErrRowDelta for the first out of bounds.
+func (m *Migration) checkRowDeltas(ctx context.Context, db rowCounter, before []int64) error {
+	after, err := m.countRows(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	for i, d := range m.ExpectRowDelta {
+		if delta := after[i] - before[i]; delta < d.Min || delta > d.Max {
+			return fmt.Errorf("%w: %s changed by %d rows (%d to %d), expected %d to %d",
+				ErrRowDelta, d.Table, delta, before[i], after[i], d.Min, d.Max)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,197 @@
+package queen
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ScaffoldOptions configures Scaffold.
+type ScaffoldOptions struct {
+	// Go creates a Go migration registered with the registry package
+	// instead of a pair of SQL files. Default: false
+	Go bool
+
+	// Package is the package clause of the Go file.
+	// Default: the base name of the directory
+	Package string
+
+	// Timestamp uses the current UTC time as the version, e.g.
+	// 20261018093000, instead of the next number. Directories whose latest
+	// version is a timestamp keep using timestamps. Default: false
+	Timestamp bool
+
+	// Digits is the width numeric versions are zero-padded to when the
+	// directory has no migrations yet; otherwise the widest existing
+	// version is followed. Default: 3
+	Digits int
+}
+
+// scaffoldVersion matches the version prefix of a migration file name.
+var scaffoldVersion = regexp.MustCompile(`^([^_]+)_[^.]+\.(up\.sql|down\.sql|sql|go)$`)
+
+// Scaffold creates a new migration named name in dir, with the version
+// after the latest one found in dir's file names, and returns the paths of
+// the files it created. By default these are <version>_<name>.up.sql and
+// <version>_<name>.down.sql, as read by the source/sqlfs package; with
+// opts.Go it is <version>_<name>.go, registering a function migration with
+// the registry package:
+//
+//	files, err := queen.Scaffold("migrations", "add email index", queen.ScaffoldOptions{})
+//	// migrations/004_add_email_index.up.sql, migrations/004_add_email_index.down.sql
+//
+// name is lowercased, with runs of other characters than letters and
+// digits replaced by underscores. dir is created if needed, and existing
+// files are never overwritten.
+func Scaffold(dir, name string, opts ScaffoldOptions) ([]string, error) {
+	name = scaffoldName(name)
+	if name == "" {
+		return nil, fmt.Errorf("%w: scaffold: name has no letters or digits", ErrInvalidMigration)
+	}
+
+	version, err := nextVersion(dir, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	base := filepath.Join(dir, version+"_"+name)
+	files := []struct{ path, content string }{
+		{base + ".up.sql", "-- " + version + " " + name + ": up\n"},
+		{base + ".down.sql", "-- " + version + " " + name + ": down\n"},
+	}
+	if opts.Go {
+		pkg := opts.Package
+		if pkg == "" {
+			pkg = scaffoldName(filepath.Base(dir))
+		}
+		if pkg == "" || pkg[0] >= '0' && pkg[0] <= '9' {
+			pkg = "migrations"
+		}
+		files = files[:1]
+		files[0].path, files[0].content = base+".go", fmt.Sprintf(goStub, pkg, version, name)
+	}
+
+	var created []string
+	for _, f := range files {
+		if err := writeNew(f.path, f.content); err != nil {
+			for _, path := range created {
+				_ = os.Remove(path)
+			}
+			return nil, err
+		}
+		created = append(created, f.path)
+	}
+
+	return created, nil
+}
+
+// goStub is the content of a scaffolded Go migration, formatted with its
+// package, version and name.
+const goStub = `package %s
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/honeynil/queen"
+	"github.com/honeynil/queen/registry"
+)
+
+func init() {
+	registry.Register(queen.M{
+		Version:        %q,
+		Name:           %q,
+		ManualChecksum: "v1",
+		UpFunc: func(ctx context.Context, tx *sql.Tx) error {
+			return nil
+		},
+		DownFunc: func(ctx context.Context, tx *sql.Tx) error {
+			return nil
+		},
+	})
+}
+`
+
+// scaffoldName lowercases name and replaces runs of other characters than
+// letters and digits with underscores.
+func scaffoldName(name string) string {
+	var b strings.Builder
+	underscore := false
+	for _, r := range strings.ToLower(name) {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' {
+			if underscore && b.Len() > 0 {
+				b.WriteByte('_')
+			}
+			b.WriteRune(r)
+			underscore = false
+		} else {
+			underscore = true
+		}
+	}
+	return b.String()
+}
+
+// nextVersion returns the version following the latest one in dir.
+func nextVersion(dir string, opts ScaffoldOptions) (string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+
+	var latest uint64
+	width := opts.Digits
+	if width <= 0 {
+		width = 3
+	}
+	found := false
+	for _, e := range entries {
+		match := scaffoldVersion.FindStringSubmatch(e.Name())
+		if e.IsDir() || match == nil || strings.HasSuffix(e.Name(), "_test.go") {
+			continue
+		}
+
+		n, err := strconv.ParseUint(match[1], 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("%w: scaffold: version %s of %s is not a number", ErrInvalidMigration, match[1], e.Name())
+		}
+		if !found || n > latest {
+			latest = n
+		}
+		if !found {
+			width = 0
+		}
+		width = max(width, len(match[1]))
+		found = true
+	}
+
+	if opts.Timestamp || found && width == len(timestampLayout) {
+		version := time.Now().UTC().Format(timestampLayout)
+		if found && version <= strconv.FormatUint(latest, 10) {
+			return "", fmt.Errorf("%w: scaffold: latest version %d is not before now", ErrInvalidMigration, latest)
+		}
+		return version, nil
+	}
+
+	return fmt.Sprintf("%0*d", width, latest+1), nil
+}
+
+// writeNew writes content to a new file at path, failing if it exists.
+func writeNew(path, content string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(content); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,84 @@
+package queen_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/honeynil/queen"
+	"github.com/honeynil/queen/source/sqlfs"
+)
+
+func TestScaffold(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "migrations")
+
+	files, err := queen.Scaffold(dir, "Create users", queen.ScaffoldOptions{})
+	if err != nil {
+		t.Fatalf("Scaffold() failed: %v", err)
+	}
+	want := []string{filepath.Join(dir, "001_create_users.up.sql"), filepath.Join(dir, "001_create_users.down.sql")}
+	if len(files) != 2 || files[0] != want[0] || files[1] != want[1] {
+		t.Errorf("Scaffold() = %v; want %v", files, want)
+	}
+
+	// The width of existing versions is kept
+	if err := os.WriteFile(filepath.Join(dir, "0009_add_email.up.sql"), []byte("SELECT 1"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	files, err = queen.Scaffold(dir, "add-email index!", queen.ScaffoldOptions{})
+	if err != nil {
+		t.Fatalf("Scaffold() failed: %v", err)
+	}
+	if filepath.Base(files[0]) != "0010_add_email_index.up.sql" {
+		t.Errorf("Scaffold() = %v; want version 0010", files)
+	}
+
+	migrations, err := sqlfs.Load(os.DirFS(dir), ".")
+	if err != nil {
+		t.Fatalf("sqlfs.Load() of scaffolded files failed: %v", err)
+	}
+	if len(migrations) != 3 || migrations[2].Version != "0010" {
+		t.Errorf("sqlfs.Load() = %+v", migrations)
+	}
+
+	files, err = queen.Scaffold(dir, "normalize emails", queen.ScaffoldOptions{Go: true})
+	if err != nil {
+		t.Fatalf("Scaffold() failed: %v", err)
+	}
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"package migrations", `Version:        "0011"`, `Name:           "normalize_emails"`, "registry.Register"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("Go stub doesn't contain %q:\n%s", want, data)
+		}
+	}
+
+	if _, err := queen.Scaffold(dir, "--", queen.ScaffoldOptions{}); !errors.Is(err, queen.ErrInvalidMigration) {
+		t.Errorf("Scaffold() = %v; want ErrInvalidMigration for an empty name", err)
+	}
+}
+
+func TestScaffoldTimestamp(t *testing.T) {
+	dir := t.TempDir()
+
+	files, err := queen.Scaffold(dir, "create_users", queen.ScaffoldOptions{Timestamp: true, Go: true, Package: "db"})
+	if err != nil {
+		t.Fatalf("Scaffold() failed: %v", err)
+	}
+	version, _, _ := strings.Cut(filepath.Base(files[0]), "_")
+	if len(version) != 14 {
+		t.Errorf("version = %s; want a timestamp", version)
+	}
+
+	// Timestamps are kept once used, and must move forward
+	if err := os.WriteFile(filepath.Join(dir, "29991231235959_future.up.sql"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := queen.Scaffold(dir, "add_email", queen.ScaffoldOptions{}); !errors.Is(err, queen.ErrInvalidMigration) {
+		t.Errorf("Scaffold() = %v; want ErrInvalidMigration after a future version", err)
+	}
+}
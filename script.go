@@ -0,0 +1,90 @@
+package queen
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// RecordScripter is implemented by drivers that can write the statement
+// recording a migration as SQL text, for GenerateScript.
+type RecordScripter interface {
+	// RecordScript returns a statement with the effect of RecordWithMeta, its
+	// values inlined as literals in the driver's dialect and terminated
+	// with a semicolon.
+	RecordScript(m *Migration, meta RecordMeta) string
+}
+
+// GenerateScript writes the pending migrations to w as one SQL script, for
+// DBAs who apply changes by hand through change-management tooling. Each
+// migration runs in its own transaction, unless it sets NoTransaction,
+// followed by the statement recording it in the tracking table, so the
+// database ends up as if Up had run. Templates are expanded.
+//
+// The tracking table is created if missing, since pending migrations are
+// found by reading it. The records carry Config.Operator and the build
+// info; the user and host that apply the script aren't known. Nothing
+// else is written to the database, and the checks Up runs aren't.
+//
+// Returns ErrUnsupported if the driver doesn't implement RecordScripter,
+// or if a pending migration is a Go function, before anything is written.
+func (q *Queen) GenerateScript(ctx context.Context, w io.Writer) error {
+	if q.driver == nil {
+		return ErrNoDriver
+	}
+
+	scripter, ok := optional[RecordScripter](q.driver, FeatureScript)
+	if !ok {
+		return fmt.Errorf("%w: SQL scripts", ErrUnsupported)
+	}
+
+	pending, err := q.Pending(ctx)
+	if err != nil {
+		return err
+	}
+
+	rendered := make([]*Migration, len(pending))
+	for i, m := range pending {
+		if m.UpFunc != nil {
+			return newMigrationError(m.Version, m.Name, fmt.Errorf("%w: scripting a Go function", ErrUnsupported))
+		}
+		if len(m.UpArgs) > 0 {
+			return newMigrationError(m.Version, m.Name, fmt.Errorf("%w: scripting bound arguments", ErrUnsupported))
+		}
+		if rendered[i], err = q.render(m); err != nil {
+			return newMigrationError(m.Version, m.Name, err)
+		}
+	}
+
+	meta := q.recordMeta()
+	meta.AppliedBy, meta.Hostname = "", ""
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "-- Generated by queen at %s: %d pending migrations.\n", time.Now().UTC().Format(time.RFC3339), len(pending))
+	b.WriteString("-- Each migration commits on its own; stop at the first error.\n")
+	for i, m := range rendered {
+		fmt.Fprintf(&b, "\n-- %s %s\n", m.Version, m.Name)
+		if m.NoTransaction {
+			b.WriteString("-- Runs outside a transaction.\n")
+		} else {
+			b.WriteString("BEGIN;\n")
+		}
+
+		up := strings.TrimSpace(m.UpSQL)
+		if !strings.HasSuffix(up, ";") {
+			up += ";"
+		}
+		b.WriteString(up + "\n")
+
+		// Checksums and down SQL are recorded unexpanded, as Up records them
+		b.WriteString(scripter.RecordScript(q.recordable(pending[i]), meta) + "\n")
+		if !m.NoTransaction {
+			b.WriteString("COMMIT;\n")
+		}
+	}
+
+	_, err = io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,53 @@
+package queen_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/honeynil/queen"
+	"github.com/honeynil/queen/drivers/mock"
+)
+
+func TestGenerateScript(t *testing.T) {
+	ctx := context.Background()
+	driver := mock.New()
+	q := queen.NewWithConfig(driver, &queen.Config{TemplateVars: map[string]any{"Schema": "app"}})
+	q.MustAdd(queen.M{Version: "001", Name: "create_users", UpFunc: noop})
+	q.MustAdd(queen.M{Version: "002", Name: "add_email", UpSQL: "ALTER TABLE {{.Schema}}.users ADD COLUMN email TEXT"})
+	q.MustAdd(queen.M{Version: "003", Name: "index_email", NoTransaction: true, UpSQL: "CREATE INDEX CONCURRENTLY users_email ON app.users (email);"})
+
+	var b strings.Builder
+	if err := q.GenerateScript(ctx, &b); !errors.Is(err, queen.ErrUnsupported) || !strings.Contains(err.Error(), "001") {
+		t.Fatalf("GenerateScript = %v; want ErrUnsupported for the Go function 001", err)
+	}
+	if b.Len() != 0 {
+		t.Errorf("expected nothing written, got\n%s", b.String())
+	}
+
+	if err := q.UpSteps(ctx, 1); err != nil {
+		t.Fatalf("UpSteps failed: %v", err)
+	}
+	if err := q.GenerateScript(ctx, &b); err != nil {
+		t.Fatalf("GenerateScript failed: %v", err)
+	}
+
+	script := b.String()
+	for _, want := range []string{
+		"2 pending migrations",
+		"-- 002 add_email\nBEGIN;\nALTER TABLE app.users ADD COLUMN email TEXT;\nINSERT INTO queen_migrations (version, name, checksum, batch) VALUES ('002', 'add_email', ",
+		", 2);\nCOMMIT;\n",
+		"-- 003 index_email\n-- Runs outside a transaction.\nCREATE INDEX CONCURRENTLY users_email ON app.users (email);\nINSERT",
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("script =\n%s\nwant %q", script, want)
+		}
+	}
+	if strings.Count(script, "BEGIN;") != 1 {
+		t.Errorf("script =\n%s\nwant a transaction for 002 only", script)
+	}
+	if driver.AppliedCount() != 1 {
+		t.Errorf("AppliedCount() = %d; want nothing applied by GenerateScript", driver.AppliedCount())
+	}
+}
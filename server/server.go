@@ -0,0 +1,315 @@
+// Package server runs Queen as a long-lived process that keeps a database
+// in line with a migration Source: every Interval it re-validates the
+// database, applies migrations newly published by the source, and reports
+// what it did over HTTP, as JSON status and Prometheus metrics.
+//
+//	s := server.New(postgres.New(db), server.SourceFunc(loadBundle), server.Options{
+//	    Interval: time.Minute,
+//	})
+//	http.Handle("/", s.Handler()) // /status, /healthz, /metrics
+//	go http.ListenAndServe(":8080", nil)
+//
+//	if err := s.Run(ctx); err != nil && !errors.Is(err, context.Canceled) {
+//	    log.Fatal(err)
+//	}
+//
+// Each pass builds a new Queen from the source's migrations, so migrations
+// can be added or replaced between passes. The migration lock keeps
+// several servers, or a server and a deployment, from migrating at once.
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/honeynil/queen"
+)
+
+// DefaultInterval is the default time between reconciliation passes.
+const DefaultInterval = time.Minute
+
+// Source provides the migrations the database should have, e.g. the
+// latest published bundle of SQL files.
+type Source interface {
+	Migrations(ctx context.Context) ([]queen.M, error)
+}
+
+// SourceFunc adapts a function to Source.
+type SourceFunc func(ctx context.Context) ([]queen.M, error)
+
+// Migrations calls f.
+func (f SourceFunc) Migrations(ctx context.Context) ([]queen.M, error) {
+	return f(ctx)
+}
+
+// Options configures a Server.
+type Options struct {
+	// Interval is the time between reconciliation passes.
+	// Default: DefaultInterval
+	Interval time.Duration
+
+	// Config configures the Queen of each pass. Default: queen.DefaultConfig()
+	Config *queen.Config
+
+	// ValidateOnly makes passes validate the database and report pending
+	// migrations without applying them. Default: false
+	ValidateOnly bool
+
+	// OnReconcile is called after every pass with its result, e.g. to log
+	// it. Default: nil
+	OnReconcile func(Result)
+}
+
+// Result is the outcome of a reconciliation pass.
+type Result struct {
+	// Started and Duration time the pass.
+	Started  time.Time
+	Duration time.Duration
+
+	// Applied lists the versions the pass applied.
+	Applied []string
+
+	// Migrations is the status of every migration of the source after
+	// the pass, if it could be determined.
+	Migrations []queen.MigrationStatus
+
+	// Err is why the pass failed, nil if it succeeded.
+	Err error
+}
+
+// Pending returns the number of migrations left pending after the pass.
+func (r Result) Pending() int {
+	n := 0
+	for _, s := range r.Migrations {
+		if s.Status == queen.StatusPending {
+			n++
+		}
+	}
+	return n
+}
+
+// Server reconciles a database with a Source. Its methods are safe for
+// concurrent use; passes never overlap.
+type Server struct {
+	driver queen.Driver
+	source Source
+	opts   Options
+
+	// run serializes passes
+	run sync.Mutex
+
+	mu          sync.Mutex
+	last        *Result
+	lastSuccess time.Time
+	passes      int64
+	failures    int64
+	applied     int64
+}
+
+// New creates a Server applying the migrations of source with driver.
+func New(driver queen.Driver, source Source, opts Options) *Server {
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultInterval
+	}
+	return &Server{driver: driver, source: source, opts: opts}
+}
+
+// Run reconciles immediately and then every Interval until ctx is done,
+// returning ctx's error. Failed passes don't stop it; they are reported
+// by Last, the handlers and OnReconcile.
+func (s *Server) Run(ctx context.Context) error {
+	ticker := time.NewTicker(s.opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		s.Reconcile(ctx)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Reconcile runs one pass: it loads the source's migrations, validates the
+// database against them and applies the pending ones, unless ValidateOnly
+// is set.
+func (s *Server) Reconcile(ctx context.Context) Result {
+	s.run.Lock()
+	defer s.run.Unlock()
+
+	r := Result{Started: time.Now()}
+	r.Err = s.reconcile(ctx, &r)
+	r.Duration = time.Since(r.Started)
+
+	s.mu.Lock()
+	s.last = &r
+	s.passes++
+	if r.Err != nil {
+		s.failures++
+	} else {
+		s.lastSuccess = r.Started
+	}
+	s.applied += int64(len(r.Applied))
+	s.mu.Unlock()
+
+	if s.opts.OnReconcile != nil {
+		s.opts.OnReconcile(r)
+	}
+
+	return r
+}
+
+// reconcile runs a pass, filling r.
+func (s *Server) reconcile(ctx context.Context, r *Result) error {
+	migrations, err := s.source.Migrations(ctx)
+	if err != nil {
+		return fmt.Errorf("load migrations: %w", err)
+	}
+
+	config := queen.DefaultConfig()
+	if s.opts.Config != nil {
+		c := *s.opts.Config
+		config = &c
+	}
+
+	// Closing the Queen would close the driver, which outlives the pass
+	q := queen.NewWithConfig(s.driver, config)
+	if err := q.AddAll(migrations...); err != nil {
+		return err
+	}
+
+	q.Hooks().MustRegister(queen.Hook{Name: "server", Func: func(ctx context.Context, e queen.Event) error {
+		if e.Kind == queen.EventAfterUp {
+			r.Applied = append(r.Applied, e.Migration.Version)
+		}
+		return nil
+	}})
+
+	defer func() {
+		if statuses, err := q.Status(ctx); err == nil {
+			r.Migrations = statuses
+		}
+	}()
+
+	// An empty source has nothing to validate or apply, e.g. before the
+	// first bundle is published
+	if len(migrations) == 0 {
+		return nil
+	}
+
+	if err := q.Validate(ctx); err != nil {
+		return err
+	}
+	if s.opts.ValidateOnly {
+		return nil
+	}
+
+	return q.Up(ctx)
+}
+
+// Last returns the result of the latest pass, or nil before the first.
+func (s *Server) Last() *Result {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.last
+}
+
+// Handler returns an http.Handler serving:
+//
+//   - /status: the latest pass as JSON, its migrations encoded like
+//     Queen.StatusJSON, with status 503 if it failed or left migrations
+//     pending
+//   - /healthz: 200 once a pass succeeded, 503 otherwise
+//   - /metrics: counters and gauges in the Prometheus text format
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/status", s.serveStatus)
+	mux.HandleFunc("/healthz", s.serveHealth)
+	mux.HandleFunc("/metrics", s.serveMetrics)
+	return mux
+}
+
+func (s *Server) serveStatus(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	last, lastSuccess := s.last, s.lastSuccess
+	s.mu.Unlock()
+
+	resp := statusResponse{Migrations: []queen.MigrationStatus{}}
+	if !lastSuccess.IsZero() {
+		resp.LastSuccess = &lastSuccess
+	}
+	if last != nil {
+		resp.LastRun = &last.Started
+		resp.Applied = last.Applied
+		resp.Pending = last.Pending()
+		if last.Err != nil {
+			resp.Error = last.Err.Error()
+		}
+		if last.Migrations != nil {
+			resp.Migrations = last.Migrations
+		}
+	}
+	resp.Ready = last != nil && last.Err == nil && resp.Pending == 0
+
+	code := http.StatusOK
+	if !resp.Ready {
+		code = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+func (s *Server) serveHealth(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	healthy := !s.lastSuccess.IsZero()
+	s.mu.Unlock()
+
+	if !healthy {
+		http.Error(w, "no successful reconciliation yet", http.StatusServiceUnavailable)
+		return
+	}
+	_, _ = w.Write([]byte("ok\n"))
+}
+
+func (s *Server) serveMetrics(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+
+	metric := func(name, kind, help string, value any) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %v\n", name, help, name, kind, name, value)
+	}
+	metric("queen_reconcile_total", "counter", "Reconciliation passes run.", s.passes)
+	metric("queen_reconcile_failures_total", "counter", "Reconciliation passes that failed.", s.failures)
+	metric("queen_migrations_applied_total", "counter", "Migrations applied by reconciliation passes.", s.applied)
+
+	var lastSuccess float64
+	if !s.lastSuccess.IsZero() {
+		lastSuccess = float64(s.lastSuccess.UnixNano()) / 1e9
+	}
+	metric("queen_reconcile_last_success_timestamp_seconds", "gauge", "Start time of the latest successful pass.", lastSuccess)
+
+	if s.last != nil {
+		metric("queen_reconcile_duration_seconds", "gauge", "Duration of the latest pass.", s.last.Duration.Seconds())
+		metric("queen_migrations_pending", "gauge", "Migrations pending after the latest pass.", s.last.Pending())
+	}
+}
+
+type statusResponse struct {
+	Ready       bool                    `json:"ready"`
+	LastRun     *time.Time              `json:"last_run,omitempty"`
+	LastSuccess *time.Time              `json:"last_success,omitempty"`
+	Applied     []string                `json:"applied,omitempty"`
+	Pending     int                     `json:"pending"`
+	Error       string                  `json:"error,omitempty"`
+	Migrations  []queen.MigrationStatus `json:"migrations"`
+}
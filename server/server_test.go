@@ -0,0 +1,151 @@
+package server_test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/honeynil/queen"
+	"github.com/honeynil/queen/drivers/mock"
+	"github.com/honeynil/queen/server"
+)
+
+func noop(ctx context.Context, tx *sql.Tx) error { return nil }
+
+func TestReconcile(t *testing.T) {
+	driver := mock.New()
+	ctx := context.Background()
+
+	bundle := []queen.M{{Version: "001", Name: "create_users", UpFunc: noop}}
+	var loadErr error
+	source := server.SourceFunc(func(ctx context.Context) ([]queen.M, error) {
+		return bundle, loadErr
+	})
+
+	var results []server.Result
+	s := server.New(driver, source, server.Options{OnReconcile: func(r server.Result) {
+		results = append(results, r)
+	}})
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		s.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	if rec := get("/healthz"); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("/healthz before a pass = %d; want 503", rec.Code)
+	}
+
+	if r := s.Reconcile(ctx); r.Err != nil || !slices.Equal(r.Applied, []string{"001"}) {
+		t.Fatalf("Reconcile() = %v, applied %v; want 001 applied", r.Err, r.Applied)
+	}
+
+	// A newly published migration is applied by the next pass
+	bundle = append(bundle, queen.M{Version: "002", Name: "create_posts", UpFunc: noop})
+	if r := s.Reconcile(ctx); r.Err != nil || !slices.Equal(r.Applied, []string{"002"}) {
+		t.Fatalf("Reconcile() = %v, applied %v; want 002 applied", r.Err, r.Applied)
+	}
+	if !driver.HasVersion("002") {
+		t.Error("Expected 002 applied")
+	}
+
+	rec := get("/status")
+	var status struct {
+		Ready      bool
+		Pending    int
+		Migrations []struct{ Version, Status string }
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&status); err != nil {
+		t.Fatalf("failed to decode /status: %v", err)
+	}
+	if rec.Code != http.StatusOK || !status.Ready || len(status.Migrations) != 2 || status.Migrations[1].Status != "applied" {
+		t.Errorf("/status = %d %+v; want ready with 2 applied", rec.Code, status)
+	}
+
+	loadErr = errors.New("bundle unavailable")
+	if r := s.Reconcile(ctx); !errors.Is(r.Err, loadErr) {
+		t.Errorf("Reconcile() = %v; want the source's error", r.Err)
+	}
+	if rec := get("/status"); rec.Code != http.StatusServiceUnavailable || !strings.Contains(rec.Body.String(), "bundle unavailable") {
+		t.Errorf("/status after a failure = %d %s", rec.Code, rec.Body)
+	}
+	if rec := get("/healthz"); rec.Code != http.StatusOK {
+		t.Errorf("/healthz after a success = %d; want 200", rec.Code)
+	}
+
+	metrics := get("/metrics").Body.String()
+	for _, want := range []string{"queen_reconcile_total 3", "queen_reconcile_failures_total 1", "queen_migrations_applied_total 2"} {
+		if !strings.Contains(metrics, want) {
+			t.Errorf("/metrics lacks %q:\n%s", want, metrics)
+		}
+	}
+
+	if len(results) != 3 {
+		t.Errorf("OnReconcile called %d times; want 3", len(results))
+	}
+}
+
+func TestValidateOnly(t *testing.T) {
+	driver := mock.New()
+	source := server.SourceFunc(func(ctx context.Context) ([]queen.M, error) {
+		return []queen.M{{Version: "001", Name: "create_users", UpFunc: noop}}, nil
+	})
+
+	s := server.New(driver, source, server.Options{ValidateOnly: true})
+	r := s.Reconcile(context.Background())
+	if r.Err != nil || driver.HasVersion("001") || r.Pending() != 1 {
+		t.Errorf("Reconcile() = %v, pending %d; want 001 left pending", r.Err, r.Pending())
+	}
+}
+
+func TestReconcile_EmptySource(t *testing.T) {
+	source := server.SourceFunc(func(ctx context.Context) ([]queen.M, error) {
+		return nil, nil
+	})
+
+	s := server.New(mock.New(), source, server.Options{})
+	if r := s.Reconcile(context.Background()); r.Err != nil || len(r.Applied) != 0 {
+		t.Fatalf("Reconcile() = %v, applied %v; want success with nothing applied", r.Err, r.Applied)
+	}
+
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("/healthz after an empty pass = %d; want 200", rec.Code)
+	}
+}
+
+func TestRun(t *testing.T) {
+	source := server.SourceFunc(func(ctx context.Context) ([]queen.M, error) { return nil, nil })
+
+	passes := make(chan struct{})
+	s := server.New(mock.New(), source, server.Options{
+		Interval: time.Millisecond,
+		OnReconcile: func(server.Result) {
+			select {
+			case passes <- struct{}{}:
+			default:
+			}
+		},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- s.Run(ctx) }()
+
+	<-passes
+	<-passes
+	cancel()
+
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("Run() = %v; want context.Canceled", err)
+	}
+}
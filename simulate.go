@@ -0,0 +1,276 @@
+package queen
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	naturalsort "github.com/honeynil/queen/internal/sort"
+)
+
+// Simulation replays planning decisions against a synthetic applied state,
+// without a driver or a database. Each operation plans like the real one,
+// including environments, the OutOfOrder and UnknownApplied policies, flag
+// requirements, dirty records and missing down migrations, then updates the
+// simulated state as if every migration in the plan succeeded.
+//
+// Use it to unit test migration sets and policies, or to show what a
+// sequence of operations would do:
+//
+//	sim := q.Simulate([]queen.Applied{{Version: "001"}, {Version: "003"}})
+//	sim.Up(0)
+//	sim.Down(2)
+//	fmt.Print(sim)
+//	// 1. up [002 004] -> applied [001 002 003 004]
+//	// 2. down [004 003] -> applied [001 002]
+//
+// A Simulation is not safe for concurrent use.
+type Simulation struct {
+	// Capabilities of the simulated database, checked against extension
+	// and version requirements. If nil, only flag requirements are checked.
+	Capabilities *Capabilities
+
+	q     *Queen
+	steps []SimulationStep
+}
+
+// SimulationStep is the outcome of one simulated operation.
+type SimulationStep struct {
+	// Operation is the simulated operation.
+	Operation Operation
+
+	// Plan lists the versions the operation would apply or roll back, in
+	// order. It is set even if Err is, as it is for RunReport.
+	Plan []string
+
+	// Warnings collects the EventWarning messages the operation would emit.
+	Warnings []string
+
+	// Err is the error the operation would return.
+	Err error
+
+	// Applied lists the applied versions after the operation, in natural
+	// sort order.
+	Applied []string
+}
+
+// String returns a one-line summary of the step, e.g.
+// "up [002 004] -> applied [001 002 003 004]".
+func (s SimulationStep) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %v", s.Operation, s.Plan)
+	for _, w := range s.Warnings {
+		fmt.Fprintf(&b, " (warning: %s)", w)
+	}
+	if s.Err != nil {
+		fmt.Fprintf(&b, " (error: %v)", s.Err)
+	}
+	fmt.Fprintf(&b, " -> applied %v", s.Applied)
+	return b.String()
+}
+
+// Simulate starts a simulation of the registered migrations, starting
+// from applied as if it had been returned by Driver.GetApplied. The
+// Queen's configuration applies; its driver is not used.
+func (q *Queen) Simulate(applied []Applied) *Simulation {
+	sim := &Queen{
+		migrations: q.migrations,
+		config:     q.config,
+		applied:    make(map[string]*Applied, len(applied)),
+		modules:    q.modules,
+	}
+	for i := range applied {
+		a := applied[i]
+		sim.applied[a.Version] = &a
+	}
+
+	return &Simulation{q: sim}
+}
+
+// Up simulates UpSteps(ctx, n). If n <= 0, all pending migrations are
+// planned.
+func (s *Simulation) Up(n int) SimulationStep {
+	q := s.q
+	step := SimulationStep{Operation: OperationUp}
+
+	pending := q.getPending()
+	if n > 0 && n < len(pending) {
+		pending = pending[:n]
+	}
+
+	step.Err = s.checkUp(pending, &step)
+	step.Plan = versions(pending)
+
+	if step.Err == nil && len(pending) > 0 {
+		batch := q.lastBatch() + 1
+		for _, m := range pending {
+			q.applied[m.Version] = &Applied{
+				Version:  m.Version,
+				Name:     m.Name,
+				Checksum: m.Checksum(),
+				Batch:    batch,
+				DownSQL:  m.DownSQL,
+			}
+		}
+	}
+
+	return s.record(step)
+}
+
+// checkUp runs the checks Up makes before applying pending.
+func (s *Simulation) checkUp(pending []*Migration, step *SimulationStep) error {
+	q := s.q
+
+	if len(q.migrations) == 0 {
+		return ErrNoMigrations
+	}
+	if err := q.checkDirty(); err != nil {
+		return err
+	}
+
+	if q.config.UnknownApplied >= UnknownAppliedWarn {
+		if versions := q.unknownApplied(); len(versions) > 0 {
+			err := &UnregisteredError{Versions: versions}
+			if q.config.UnknownApplied == UnknownAppliedError {
+				return err
+			}
+			step.Warnings = append(step.Warnings, err.Error())
+		}
+	}
+
+	if q.config.OutOfOrder != OutOfOrderAllow {
+		for _, m := range pending {
+			err := q.orderError(m)
+			if err == nil {
+				continue
+			}
+			if q.config.OutOfOrder == OutOfOrderError {
+				return err
+			}
+			step.Warnings = append(step.Warnings, err.Error())
+		}
+	}
+
+	return s.checkRequirements(pending)
+}
+
+// Down simulates Down(ctx, n). If n <= 0, only the last migration is
+// planned.
+func (s *Simulation) Down(n int) SimulationStep {
+	if n <= 0 {
+		n = 1
+	}
+
+	step := SimulationStep{Operation: OperationDown}
+	if step.Err = s.q.checkDirty(); step.Err == nil {
+		applied := s.q.getAppliedMigrations()
+		s.rollback(applied[:min(n, len(applied))], &step)
+	}
+
+	return s.record(step)
+}
+
+// Reset simulates Reset.
+func (s *Simulation) Reset() SimulationStep {
+	step := SimulationStep{Operation: OperationReset}
+	if step.Err = s.q.checkDirty(); step.Err == nil {
+		s.rollback(s.q.getAppliedMigrations(), &step)
+	}
+
+	return s.record(step)
+}
+
+// RollbackBatch simulates RollbackBatch.
+func (s *Simulation) RollbackBatch() SimulationStep {
+	step := SimulationStep{Operation: OperationRollbackBatch}
+	if step.Err = s.q.checkDirty(); step.Err != nil || len(s.q.applied) == 0 {
+		return s.record(step)
+	}
+
+	migrations, err := s.q.lastBatchMigrations()
+	if err != nil {
+		step.Err = err
+		return s.record(step)
+	}
+	s.rollback(migrations, &step)
+
+	return s.record(step)
+}
+
+// rollback plans rolling back migrations in order. Like a real rollback, it
+// stops at the first migration without a down method, leaving the ones
+// before it rolled back.
+func (s *Simulation) rollback(migrations []*Migration, step *SimulationStep) {
+	step.Plan = versions(migrations)
+
+	if step.Err = s.checkRequirements(migrations); step.Err != nil {
+		return
+	}
+
+	for _, m := range migrations {
+		if !m.HasRollback() {
+			step.Err = newMigrationError(m.Version, m.Name, fmt.Errorf("no down migration defined"))
+			return
+		}
+		delete(s.q.applied, m.Version)
+	}
+}
+
+// checkRequirements checks requirements against s.Capabilities.
+func (s *Simulation) checkRequirements(migrations []*Migration) error {
+	return s.q.checkRequirementsWith(migrations, func() (*Capabilities, string, error) {
+		return s.Capabilities, "", nil
+	})
+}
+
+// Applied returns the simulated applied state, in natural sort order.
+func (s *Simulation) Applied() []Applied {
+	applied := make([]Applied, 0, len(s.q.applied))
+	for _, a := range s.q.applied {
+		applied = append(applied, *a)
+	}
+
+	sort.Slice(applied, func(i, j int) bool {
+		return naturalsort.Compare(applied[i].Version, applied[j].Version) < 0
+	})
+
+	return applied
+}
+
+// Pending returns the versions Up would apply next.
+func (s *Simulation) Pending() []string {
+	return versions(s.q.getPending())
+}
+
+// Steps returns every simulated step so far, oldest first.
+func (s *Simulation) Steps() []SimulationStep {
+	return s.steps
+}
+
+// String returns the simulated steps, one per line.
+func (s *Simulation) String() string {
+	var b strings.Builder
+	for i, step := range s.steps {
+		fmt.Fprintf(&b, "%d. %s\n", i+1, step)
+	}
+	return b.String()
+}
+
+// record completes step with the applied state and appends it to the log.
+func (s *Simulation) record(step SimulationStep) SimulationStep {
+	for _, a := range s.Applied() {
+		step.Applied = append(step.Applied, a.Version)
+	}
+
+	s.steps = append(s.steps, step)
+	return step
+}
+
+// versions returns the versions of migrations.
+func versions(migrations []*Migration) []string {
+	out := make([]string, len(migrations))
+	for i, m := range migrations {
+		out[i] = m.Version
+	}
+	return out
+}
@@ -0,0 +1,88 @@
+package queen_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/honeynil/queen"
+)
+
+func TestSimulate(t *testing.T) {
+	q := queen.New(nil)
+	for _, v := range []string{"001", "002", "003", "004"} {
+		q.MustAdd(queen.M{Version: v, Name: "m" + v, UpSQL: "SELECT 1", DownSQL: "SELECT 1"})
+	}
+
+	sim := q.Simulate([]queen.Applied{{Version: "001", Batch: 1}, {Version: "003", Batch: 1}})
+
+	if got := sim.Pending(); !slices.Equal(got, []string{"002", "004"}) {
+		t.Errorf("Pending() = %v", got)
+	}
+
+	steps := []struct {
+		step    queen.SimulationStep
+		plan    []string
+		applied []string
+	}{
+		{sim.Up(1), []string{"002"}, []string{"001", "002", "003"}},
+		{sim.Up(0), []string{"004"}, []string{"001", "002", "003", "004"}},
+		{sim.Down(2), []string{"004", "003"}, []string{"001", "002"}},
+		{sim.Up(0), []string{"003", "004"}, []string{"001", "002", "003", "004"}},
+		{sim.RollbackBatch(), []string{"004", "003"}, []string{"001", "002"}},
+		{sim.Reset(), []string{"002", "001"}, nil},
+	}
+	for i, s := range steps {
+		if s.step.Err != nil {
+			t.Errorf("step %d: %v", i+1, s.step.Err)
+		}
+		if !slices.Equal(s.step.Plan, s.plan) {
+			t.Errorf("step %d: plan = %v, want %v", i+1, s.step.Plan, s.plan)
+		}
+		if !slices.Equal(s.step.Applied, s.applied) {
+			t.Errorf("step %d: applied = %v, want %v", i+1, s.step.Applied, s.applied)
+		}
+	}
+
+	if got := len(sim.Steps()); got != len(steps) {
+		t.Errorf("Steps() has %d steps, want %d", got, len(steps))
+	}
+}
+
+func TestSimulatePolicies(t *testing.T) {
+	config := queen.DefaultConfig()
+	config.OutOfOrder = queen.OutOfOrderWarn
+
+	q := queen.NewWithConfig(nil, config)
+	q.MustAdd(queen.M{Version: "001", Name: "a", UpSQL: "SELECT 1"})
+	q.MustAdd(queen.M{Version: "002", Name: "b", UpSQL: "SELECT 1", Requires: []string{"flag:big"}})
+	q.MustAdd(queen.M{Version: "003", Name: "c", UpSQL: "SELECT 1", DownSQL: "SELECT 1"})
+
+	sim := q.Simulate([]queen.Applied{{Version: "003"}})
+
+	step := sim.Up(1)
+	if step.Err != nil || len(step.Warnings) != 1 {
+		t.Errorf("Up(1) = %v, warnings %v; want one out-of-order warning", step.Err, step.Warnings)
+	}
+
+	step = sim.Up(0)
+	if !errors.Is(step.Err, queen.ErrUnmetRequirement) {
+		t.Errorf("Up() error = %v, want ErrUnmetRequirement", step.Err)
+	}
+	if !slices.Equal(step.Applied, []string{"001", "003"}) {
+		t.Errorf("failed Up() changed applied state: %v", step.Applied)
+	}
+
+	step = sim.Down(2)
+	if step.Err == nil {
+		t.Error("Down(2) succeeded past a migration without rollback")
+	}
+	if !slices.Equal(step.Applied, []string{"001"}) {
+		t.Errorf("Down(2) applied = %v, want [001]", step.Applied)
+	}
+
+	dirty := q.Simulate([]queen.Applied{{Version: "001", Dirty: true}})
+	if step := dirty.Up(0); !errors.Is(step.Err, queen.ErrDirty) {
+		t.Errorf("Up() with dirty record error = %v, want ErrDirty", step.Err)
+	}
+}
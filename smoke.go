@@ -0,0 +1,54 @@
+package queen
+
+import (
+	"context"
+	"database/sql"
+)
+
+// smokeTable is the temporary table created by SmokeTest.
+const smokeTable = "queen_smoke_test"
+
+// SmokeTest verifies that migrations could run without touching the real
+// schema: it takes the migration lock, records a throwaway migration,
+// creates and drops a temporary table in a transaction, and removes the
+// record again. Use it as a pre-deploy gate to check connectivity,
+// permissions, locking and that the tracking table is writable.
+//
+// Registered migrations, hooks and archive sinks are not involved.
+func (q *Queen) SmokeTest(ctx context.Context) error {
+	return q.withLock(ctx, func() error {
+		m := &Migration{
+			Version: "smoke-" + q.newID(),
+			Name:    "smoke_test",
+			UpSQL:   "CREATE TEMPORARY TABLE " + smokeTable + " (id INTEGER)",
+			DownSQL: "DROP TABLE " + smokeTable,
+		}
+
+		// Without MetaRecorder the record can't be marked dirty, so it is
+		// written clean
+		_, dirty := optional[MetaRecorder](q.driver, FeatureRecordMeta)
+		if err := q.record(ctx, m, RecordMeta{Batch: q.lastBatch() + 1, Dirty: dirty}); err != nil {
+			return newMigrationError(m.Version, m.Name, err)
+		}
+
+		err := q.driver.Exec(ctx, func(tx *sql.Tx) error {
+			if err := m.executeUp(ctx, tx, sqlRunner{}); err != nil {
+				return err
+			}
+			return m.executeDown(ctx, tx, sqlRunner{})
+		})
+		if marker, ok := optional[DirtyMarker](q.driver, FeatureDirty); ok && err == nil {
+			err = marker.SetDirty(ctx, m.Version, false)
+		}
+
+		// Always try to clean up the record, even after a failure
+		if rmErr := q.driver.Remove(ctx, m.Version); err == nil {
+			err = rmErr
+		}
+		if err != nil {
+			return newMigrationError(m.Version, m.Name, err)
+		}
+
+		return nil
+	})
+}
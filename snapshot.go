@@ -0,0 +1,121 @@
+package queen
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// snapshotsTable records the snapshots taken by SnapshotTable, so
+// RestoreSnapshots can find the latest one of a table.
+const snapshotsTable = "queen_snapshots"
+
+// plainTableName matches the table names SnapshotTable accepts. They are
+// used unquoted, since the quoting rules of the database aren't known.
+var plainTableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// SnapshotTable copies the rows of table into a new table named
+// <table>_snapshot_<UTC timestamp> in tx, and returns that name as the
+// reference for RestoreTable. Use it at the start of a Go migration
+// rewriting data, with RestoreSnapshots as its DownFunc:
+//
+//	UpFunc: func(ctx context.Context, tx *sql.Tx) error {
+//	    if _, err := queen.SnapshotTable(ctx, tx, "users"); err != nil {
+//	        return err
+//	    }
+//	    _, err := tx.ExecContext(ctx, "UPDATE users SET email = LOWER(email)")
+//	    return err
+//	},
+//	DownFunc: queen.RestoreSnapshots("users"),
+//
+// The copy has the columns and rows of table but no indexes or constraints.
+// table may be qualified with a schema and must be a plain identifier.
+// Snapshots are recorded in the queen_snapshots table.
+func SnapshotTable(ctx context.Context, tx *sql.Tx, table string) (string, error) {
+	if !plainTableName.MatchString(table) {
+		return "", fmt.Errorf("%w: snapshot of %q: table name must be a plain identifier", ErrInvalidMigration, table)
+	}
+
+	if _, err := tx.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS `+snapshotsTable+` (
+		ref VARCHAR(255) PRIMARY KEY,
+		source VARCHAR(255) NOT NULL
+	)`); err != nil {
+		return "", err
+	}
+
+	ref := table + "_snapshot_" + time.Now().UTC().Format("20060102150405")
+	if _, err := tx.ExecContext(ctx, "CREATE TABLE "+ref+" AS SELECT * FROM "+table); err != nil {
+		return "", fmt.Errorf("snapshot of %s: %w", table, err)
+	}
+
+	// Both names are plain identifiers, safe as literals in every dialect
+	if _, err := tx.ExecContext(ctx, "INSERT INTO "+snapshotsTable+" (ref, source) VALUES ('"+ref+"', '"+table+"')"); err != nil {
+		return "", err
+	}
+
+	return ref, nil
+}
+
+// RestoreTable replaces the rows of the table snapshotted as ref with those
+// of the snapshot, then drops the snapshot. The table must have the columns
+// it had when the snapshot was taken.
+func RestoreTable(ctx context.Context, tx *sql.Tx, ref string) error {
+	if !plainTableName.MatchString(ref) {
+		return fmt.Errorf("%w: snapshot %q", ErrObjectNotFound, ref)
+	}
+
+	var table string
+	err := tx.QueryRowContext(ctx, "SELECT source FROM "+snapshotsTable+" WHERE ref = '"+ref+"'").Scan(&table)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("%w: snapshot %s", ErrObjectNotFound, ref)
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, query := range []string{
+		"DELETE FROM " + table,
+		"INSERT INTO " + table + " SELECT * FROM " + ref,
+		"DROP TABLE " + ref,
+		"DELETE FROM " + snapshotsTable + " WHERE ref = '" + ref + "'",
+	} {
+		if _, err := tx.ExecContext(ctx, query); err != nil {
+			return fmt.Errorf("restore of %s from %s: %w", table, ref, err)
+		}
+	}
+
+	return nil
+}
+
+// RestoreSnapshots returns a DownFunc restoring the latest snapshot of each
+// table taken by SnapshotTable, in reverse order.
+func RestoreSnapshots(tables ...string) func(ctx context.Context, tx *sql.Tx) error {
+	return func(ctx context.Context, tx *sql.Tx) error {
+		for i := len(tables) - 1; i >= 0; i-- {
+			table := tables[i]
+			if !plainTableName.MatchString(table) {
+				return fmt.Errorf("%w: snapshot of %q: table name must be a plain identifier", ErrInvalidMigration, table)
+			}
+
+			// Timestamps sort as strings, so the greatest ref is the latest
+			var ref string
+			err := tx.QueryRowContext(ctx,
+				"SELECT ref FROM "+snapshotsTable+" WHERE source = '"+table+"' ORDER BY ref DESC LIMIT 1").Scan(&ref)
+			if errors.Is(err, sql.ErrNoRows) {
+				return fmt.Errorf("%w: snapshot of %s", ErrObjectNotFound, table)
+			}
+			if err != nil {
+				return err
+			}
+
+			if err := RestoreTable(ctx, tx, ref); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+}
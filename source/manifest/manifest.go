@@ -0,0 +1,169 @@
+// Package manifest loads migrations from a declarative YAML or JSON
+// manifest listing their SQL files and flags, so the migration catalog can
+// be reviewed and edited without reading Go:
+//
+//	migrations:
+//	  - version: "001"
+//	    name: create_users
+//	    up: sql/001_create_users.up.sql
+//	    down: sql/001_create_users.down.sql
+//	  - version: "002"
+//	    name: index_users_email
+//	    up: sql/002_index_users_email.up.sql
+//	    no_transaction: true
+//	    timeout: 30m
+//	  - version: "003"
+//	    name: seed_demo_data
+//	    up: sql/003_seed_demo_data.up.sql
+//	    environments: [dev, staging]
+//
+// Load it from any fs.FS, e.g. embedded with go:embed:
+//
+//	migrations, err := manifest.Load(migrationsFS, "migrations/manifest.yaml")
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	if err := q.AddAll(migrations...); err != nil {
+//	    log.Fatal(err)
+//	}
+//
+// File paths are relative to the manifest. Files ending in .json are read
+// as JSON, others as YAML, with the same field names. Unknown fields are
+// an error, so a misspelled flag isn't silently ignored.
+package manifest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/honeynil/queen"
+)
+
+// ErrInvalidManifest is returned for a manifest that can't be parsed or
+// whose entries are incomplete.
+var ErrInvalidManifest = errors.New("invalid migration manifest")
+
+// Manifest is the content of a manifest file.
+type Manifest struct {
+	Migrations []Entry `yaml:"migrations" json:"migrations"`
+}
+
+// Entry describes a migration of a manifest. It mirrors the fields of
+// queen.Migration that can be set without Go.
+type Entry struct {
+	Version string `yaml:"version" json:"version"`
+	Name    string `yaml:"name" json:"name"`
+
+	// Up and Down are the paths of the SQL files, relative to the
+	// manifest. Down is optional.
+	Up   string `yaml:"up" json:"up"`
+	Down string `yaml:"down,omitempty" json:"down,omitempty"`
+
+	NoTransaction bool `yaml:"no_transaction,omitempty" json:"no_transaction,omitempty"`
+
+	// Timeout is a duration such as "30s" or "1h".
+	Timeout string `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+
+	Requires       []string `yaml:"requires,omitempty" json:"requires,omitempty"`
+	MinDBVersion   string   `yaml:"min_db_version,omitempty" json:"min_db_version,omitempty"`
+	Environments   []string `yaml:"environments,omitempty" json:"environments,omitempty"`
+	IrreversibleOK bool     `yaml:"irreversible_ok,omitempty" json:"irreversible_ok,omitempty"`
+	Backup         bool     `yaml:"backup,omitempty" json:"backup,omitempty"`
+	LockHazardOK   bool     `yaml:"lock_hazard_ok,omitempty" json:"lock_hazard_ok,omitempty"`
+}
+
+// Load reads the manifest at file in fsys and the SQL files it lists, and
+// returns the migrations in manifest order.
+func Load(fsys fs.FS, file string) ([]queen.M, error) {
+	data, err := fs.ReadFile(fsys, file)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest, err := Parse(data, strings.HasSuffix(file, ".json"))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+
+	dir := path.Dir(file)
+	migrations := make([]queen.M, 0, len(manifest.Migrations))
+	for i, e := range manifest.Migrations {
+		m, err := e.migration(func(name string) (string, error) {
+			data, err := fs.ReadFile(fsys, path.Join(dir, name))
+			return string(data), err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("%s: migration %d: %w", file, i+1, err)
+		}
+		migrations = append(migrations, m)
+	}
+
+	return migrations, nil
+}
+
+// Parse parses a manifest, as JSON if isJSON is set and as YAML otherwise.
+func Parse(data []byte, isJSON bool) (*Manifest, error) {
+	var m Manifest
+	if isJSON {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&m); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidManifest, err)
+		}
+	} else {
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(&m); err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidManifest, err)
+		}
+	}
+	return &m, nil
+}
+
+// migration converts e to a Migration, reading its SQL files with read.
+func (e Entry) migration(read func(name string) (string, error)) (queen.M, error) {
+	if e.Version == "" || e.Name == "" || e.Up == "" {
+		return queen.M{}, fmt.Errorf("%w: version, name and up are required", ErrInvalidManifest)
+	}
+
+	m := queen.M{
+		Version:        e.Version,
+		Name:           e.Name,
+		NoTransaction:  e.NoTransaction,
+		Requires:       e.Requires,
+		MinDBVersion:   e.MinDBVersion,
+		Environments:   e.Environments,
+		IrreversibleOK: e.IrreversibleOK,
+		Backup:         e.Backup,
+		LockHazardOK:   e.LockHazardOK,
+	}
+
+	if e.Timeout != "" {
+		timeout, err := time.ParseDuration(e.Timeout)
+		if err != nil {
+			return queen.M{}, fmt.Errorf("%w: %s: timeout: %w", ErrInvalidManifest, e.Version, err)
+		}
+		m.Timeout = timeout
+	}
+
+	var err error
+	if m.UpSQL, err = read(e.Up); err != nil {
+		return queen.M{}, fmt.Errorf("%s: %w", e.Version, err)
+	}
+	if e.Down != "" {
+		if m.DownSQL, err = read(e.Down); err != nil {
+			return queen.M{}, fmt.Errorf("%s: %w", e.Version, err)
+		}
+	}
+
+	return m, nil
+}
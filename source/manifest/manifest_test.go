@@ -0,0 +1,88 @@
+package manifest_test
+
+import (
+	"errors"
+	"io/fs"
+	"reflect"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/honeynil/queen/source/manifest"
+)
+
+var files = fstest.MapFS{
+	"migrations/sql/001.up.sql":   {Data: []byte("CREATE TABLE users (id INT)")},
+	"migrations/sql/001.down.sql": {Data: []byte("DROP TABLE users")},
+	"migrations/sql/002.up.sql":   {Data: []byte("CREATE INDEX CONCURRENTLY idx_users_id ON users (id)")},
+}
+
+func withManifest(name, content string) fstest.MapFS {
+	fsys := fstest.MapFS{"migrations/" + name: {Data: []byte(content)}}
+	for k, v := range files {
+		fsys[k] = v
+	}
+	return fsys
+}
+
+func TestLoad(t *testing.T) {
+	yamlFS := withManifest("manifest.yaml", `
+migrations:
+  - version: 001
+    name: create_users
+    up: sql/001.up.sql
+    down: sql/001.down.sql
+  - version: "002"
+    name: index_users
+    up: sql/002.up.sql
+    no_transaction: true
+    timeout: 30m
+    environments: [prod]
+`)
+	jsonFS := withManifest("manifest.json", `{"migrations": [
+		{"version": "001", "name": "create_users", "up": "sql/001.up.sql", "down": "sql/001.down.sql"},
+		{"version": "002", "name": "index_users", "up": "sql/002.up.sql", "no_transaction": true, "timeout": "30m", "environments": ["prod"]}
+	]}`)
+
+	for file, fsys := range map[string]fs.FS{"migrations/manifest.yaml": yamlFS, "migrations/manifest.json": jsonFS} {
+		t.Run(file, func(t *testing.T) {
+			migrations, err := manifest.Load(fsys, file)
+			if err != nil {
+				t.Fatalf("Load() failed: %v", err)
+			}
+			if len(migrations) != 2 {
+				t.Fatalf("Load() returned %d migrations; want 2", len(migrations))
+			}
+
+			m := migrations[0]
+			if m.Version != "001" || m.Name != "create_users" || m.UpSQL != "CREATE TABLE users (id INT)" || m.DownSQL != "DROP TABLE users" {
+				t.Errorf("migrations[0] = %+v", m)
+			}
+			m = migrations[1]
+			if !m.NoTransaction || m.Timeout != 30*time.Minute || !reflect.DeepEqual(m.Environments, []string{"prod"}) || m.DownSQL != "" {
+				t.Errorf("migrations[1] = %+v", m)
+			}
+		})
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	tests := map[string]string{
+		"unknown field":   "migrations:\n  - {version: '001', name: a, up: sql/001.up.sql, no_transation: true}",
+		"missing up":      "migrations:\n  - {version: '001', name: a}",
+		"invalid timeout": "migrations:\n  - {version: '001', name: a, up: sql/001.up.sql, timeout: soon}",
+	}
+	for name, content := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := manifest.Load(withManifest("manifest.yaml", content), "migrations/manifest.yaml")
+			if !errors.Is(err, manifest.ErrInvalidManifest) {
+				t.Errorf("Load() = %v; want ErrInvalidManifest", err)
+			}
+		})
+	}
+
+	_, err := manifest.Load(withManifest("manifest.yaml", "migrations:\n  - {version: '003', name: a, up: sql/003.up.sql}"), "migrations/manifest.yaml")
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Load() = %v; want fs.ErrNotExist for a missing SQL file", err)
+	}
+}
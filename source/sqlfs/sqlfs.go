@@ -0,0 +1,182 @@
+// Package sqlfs loads SQL migrations from files, e.g. embedded with
+// go:embed, so they run with Queen like migrations written in Go:
+//
+//	//go:embed migrations/*.sql
+//	var migrationsFS embed.FS
+//
+//	migrations, err := sqlfs.Load(migrationsFS, "migrations")
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	if err := q.AddAll(migrations...); err != nil {
+//	    log.Fatal(err)
+//	}
+//
+// Each migration is a pair of files named <version>_<name>.up.sql and
+// <version>_<name>.down.sql, e.g. 0001_create_users.up.sql; the down file
+// is optional. Checksums are computed from the file contents like for any
+// SQL migration, so editing an applied file is reported as a mismatch.
+//
+// A migration can also be a single <version>_<name>.sql file with
+// annotated sections, as written for goose, so goose projects can move to
+// Queen without splitting their files:
+//
+//	-- +queen Up
+//	CREATE INDEX CONCURRENTLY idx_users_email ON users (email);
+//
+//	-- +queen Down
+//	DROP INDEX CONCURRENTLY idx_users_email;
+//
+//	-- +queen NoTransaction
+//
+// "-- +goose" annotations are read the same way, including goose's
+// "NO TRANSACTION" spelling. Other annotations, such as StatementBegin and
+// StatementEnd, are left in the SQL as comments.
+package sqlfs
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/honeynil/queen"
+	naturalsort "github.com/honeynil/queen/internal/sort"
+)
+
+// ErrInvalidFile is returned for a .sql file whose name, pairing or
+// annotations don't follow the scheme.
+var ErrInvalidFile = errors.New("invalid migration file")
+
+// Load reads the migrations in the directory dir of fsys, sorted by
+// version. Files not ending in .sql and subdirectories are ignored.
+func Load(fsys fs.FS, dir string) ([]queen.M, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[string]*queen.M)
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".sql") {
+			continue
+		}
+
+		version, name, kind, err := parseName(e.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		data, err := fs.ReadFile(fsys, path.Join(dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &queen.M{Version: version, Name: name}
+			byVersion[version] = m
+		}
+		if m.Name != name {
+			return nil, fmt.Errorf("%w: %s: version %s is also named %s", ErrInvalidFile, e.Name(), version, m.Name)
+		}
+		if m.UpSQL != "" && kind != ".down" || m.DownSQL != "" && kind != ".up" {
+			return nil, fmt.Errorf("%w: %s: version %s is defined twice", ErrInvalidFile, e.Name(), version)
+		}
+
+		switch kind {
+		case ".up":
+			m.UpSQL = string(data)
+		case ".down":
+			m.DownSQL = string(data)
+		default:
+			if err := parseAnnotated(m, string(data)); err != nil {
+				return nil, fmt.Errorf("%w: %s: %w", ErrInvalidFile, e.Name(), err)
+			}
+		}
+	}
+
+	migrations := make([]queen.M, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.UpSQL == "" {
+			return nil, fmt.Errorf("%w: %s_%s.down.sql has no up file", ErrInvalidFile, m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return naturalsort.Compare(migrations[i].Version, migrations[j].Version) < 0
+	})
+
+	return migrations, nil
+}
+
+// parseName splits a file name like 0001_create_users.up.sql into its
+// version, name and kind: ".up", ".down", or "" for an annotated file.
+func parseName(file string) (version, name, kind string, err error) {
+	base := strings.TrimSuffix(file, ".sql")
+	for _, suffix := range []string{".up", ".down"} {
+		if strings.HasSuffix(base, suffix) {
+			base, kind = strings.TrimSuffix(base, suffix), suffix
+			break
+		}
+	}
+
+	version, name, ok := strings.Cut(base, "_")
+	if !ok || version == "" || name == "" || strings.Contains(name, ".") {
+		return "", "", "", fmt.Errorf("%w: %s: want <version>_<name>.sql, .up.sql or .down.sql", ErrInvalidFile, file)
+	}
+
+	return version, name, kind, nil
+}
+
+// parseAnnotated sets the UpSQL, DownSQL and NoTransaction of m from the
+// annotated sections of an annotated file.
+func parseAnnotated(m *queen.M, content string) error {
+	var up, down strings.Builder
+	var section *strings.Builder
+
+	for _, line := range strings.SplitAfter(content, "\n") {
+		switch annotation(line) {
+		case "up":
+			section = &up
+		case "down":
+			section = &down
+		case "notransaction", "no transaction":
+			m.NoTransaction = true
+		default:
+			if section != nil {
+				section.WriteString(line)
+			} else if strings.TrimSpace(line) != "" && !strings.HasPrefix(strings.TrimSpace(line), "--") {
+				return errors.New("SQL before the first -- +queen Up or Down annotation")
+			}
+		}
+	}
+
+	m.UpSQL = strings.TrimSpace(up.String())
+	m.DownSQL = strings.TrimSpace(down.String())
+	if m.UpSQL == "" {
+		return errors.New("no -- +queen Up section")
+	}
+	return nil
+}
+
+// annotation returns the lowercased annotation of a "-- +queen" or
+// "-- +goose" line, e.g. "up", or "" for other lines.
+func annotation(line string) string {
+	line = strings.TrimSpace(line)
+	rest, ok := strings.CutPrefix(line, "--")
+	if !ok {
+		return ""
+	}
+	rest = strings.TrimSpace(rest)
+
+	for _, prefix := range []string{"+queen ", "+goose "} {
+		if a, ok := strings.CutPrefix(rest, prefix); ok {
+			return strings.ToLower(strings.TrimSpace(a))
+		}
+	}
+	return ""
+}
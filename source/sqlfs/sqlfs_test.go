@@ -0,0 +1,117 @@
+package sqlfs_test
+
+import (
+	"errors"
+	"testing"
+	"testing/fstest"
+
+	"github.com/honeynil/queen/source/sqlfs"
+)
+
+func TestLoad(t *testing.T) {
+	fsys := fstest.MapFS{
+		"migrations/0002_create_posts.up.sql":   {Data: []byte("CREATE TABLE posts (id INT)")},
+		"migrations/0001_create_users.up.sql":   {Data: []byte("CREATE TABLE users (id INT)")},
+		"migrations/0001_create_users.down.sql": {Data: []byte("DROP TABLE users")},
+		"migrations/0010_add_email.up.sql":      {Data: []byte("ALTER TABLE users ADD COLUMN email TEXT")},
+		"migrations/README.md":                  {Data: []byte("ignored")},
+		"migrations/old/0001_legacy.up.sql":     {Data: []byte("ignored")},
+	}
+
+	migrations, err := sqlfs.Load(fsys, "migrations")
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+
+	if len(migrations) != 3 {
+		t.Fatalf("Load() returned %d migrations; want 3", len(migrations))
+	}
+	m := migrations[0]
+	if m.Version != "0001" || m.Name != "create_users" || m.UpSQL != "CREATE TABLE users (id INT)" || m.DownSQL != "DROP TABLE users" {
+		t.Errorf("migrations[0] = %+v", m)
+	}
+	if migrations[1].Version != "0002" || migrations[1].DownSQL != "" || migrations[2].Version != "0010" {
+		t.Errorf("expected 0002 without down, then 0010, got %s and %s", migrations[1].Version, migrations[2].Version)
+	}
+
+	// Checksums follow the file contents
+	edited := fstest.MapFS{
+		"migrations/0001_create_users.up.sql":   {Data: []byte("CREATE TABLE users (id BIGINT)")},
+		"migrations/0001_create_users.down.sql": {Data: []byte("DROP TABLE users")},
+	}
+	again, err := sqlfs.Load(edited, "migrations")
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if again[0].Checksum() == m.Checksum() {
+		t.Error("expected the checksum to change with the file contents")
+	}
+}
+
+func TestLoadAnnotated(t *testing.T) {
+	fsys := fstest.MapFS{
+		"migrations/0001_create_users.sql": {Data: []byte(`-- Users of the app
+-- +queen Up
+CREATE TABLE users (id INT);
+
+-- +queen Down
+DROP TABLE users;
+`)},
+		"migrations/0002_index_users.sql": {Data: []byte(`-- +goose NO TRANSACTION
+-- +goose Up
+-- +goose StatementBegin
+CREATE INDEX CONCURRENTLY idx_users_id ON users (id);
+-- +goose StatementEnd
+`)},
+		"migrations/0003_add_email.up.sql": {Data: []byte("ALTER TABLE users ADD COLUMN email TEXT")},
+	}
+
+	migrations, err := sqlfs.Load(fsys, "migrations")
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if len(migrations) != 3 {
+		t.Fatalf("Load() returned %d migrations; want 3", len(migrations))
+	}
+
+	m := migrations[0]
+	if m.UpSQL != "CREATE TABLE users (id INT);" || m.DownSQL != "DROP TABLE users;" || m.NoTransaction {
+		t.Errorf("migrations[0] = %+v", m)
+	}
+
+	m = migrations[1]
+	if !m.NoTransaction {
+		t.Error("expected NO TRANSACTION to set NoTransaction")
+	}
+	want := "-- +goose StatementBegin\nCREATE INDEX CONCURRENTLY idx_users_id ON users (id);\n-- +goose StatementEnd"
+	if m.UpSQL != want || m.DownSQL != "" {
+		t.Errorf("migrations[1].UpSQL = %q; want %q", m.UpSQL, want)
+	}
+
+	if migrations[2].Version != "0003" || migrations[2].Name != "add_email" {
+		t.Errorf("migrations[2] = %+v", migrations[2])
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	tests := map[string]fstest.MapFS{
+		"no up section": {"m/0001_create_users.sql": {Data: []byte("-- +queen Down\nDROP TABLE users")}},
+		"no annotation": {"m/0001_create_users.sql": {Data: []byte("CREATE TABLE users (id INT)")}},
+		"no name":       {"m/0001.up.sql": {}},
+		"down only":     {"m/0001_create_users.down.sql": {Data: []byte("DROP TABLE users")}},
+		"name mismatch": {"m/0001_create_users.up.sql": {Data: []byte("x")}, "m/0001_users.down.sql": {Data: []byte("y")}},
+		"defined twice": {"m/0001_create_users.up.sql": {Data: []byte("x")}, "m/0001_create_users.sql": {Data: []byte("-- +queen Up\ny")}},
+	}
+
+	for name, fsys := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := sqlfs.Load(fsys, "m"); !errors.Is(err, sqlfs.ErrInvalidFile) {
+				t.Errorf("Load() = %v; want ErrInvalidFile", err)
+			}
+		})
+	}
+
+	if _, err := sqlfs.Load(fstest.MapFS{}, "missing"); err == nil {
+		t.Error("expected an error for a missing directory")
+	}
+}
@@ -1,6 +1,14 @@
 package queen
 
-import "time"
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
 
 // Status represents the current state of a migration.
 type Status int
@@ -15,6 +23,19 @@ const (
 	// StatusModified indicates the migration has been applied,
 	// but its content has changed (checksum mismatch).
 	StatusModified
+
+	// StatusDirty indicates the migration failed or was interrupted midway
+	// and the database may be partially migrated. See Queen.Repair.
+	StatusDirty
+
+	// StatusSkipped indicates the migration is not applied and is excluded
+	// from the configured environment (see Migration.Environments).
+	StatusSkipped
+
+	// StatusUnknown indicates the migration is applied but not registered,
+	// e.g. after deploying an older binary. It is only reported if
+	// Config.UnknownApplied is not UnknownAppliedIgnore.
+	StatusUnknown
 )
 
 // String returns a human-readable representation of the status.
@@ -26,32 +47,122 @@ func (s Status) String() string {
 		return "applied"
 	case StatusModified:
 		return "modified"
+	case StatusDirty:
+		return "dirty"
+	case StatusSkipped:
+		return "skipped"
+	case StatusUnknown:
+		return "unknown"
 	default:
 		return "unknown"
 	}
 }
 
+// MarshalText returns the String of s, so statuses encode as e.g.
+// "applied" in JSON.
+func (s Status) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText parses a status encoded by MarshalText.
+func (s *Status) UnmarshalText(text []byte) error {
+	for _, status := range []Status{StatusPending, StatusApplied, StatusModified, StatusDirty, StatusSkipped, StatusUnknown} {
+		if status.String() == string(text) {
+			*s = status
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown migration status %q", text)
+}
+
 // MigrationStatus contains detailed information about a migration's current state.
 // This is returned by Queen.Status().
+//
+// Its JSON field names are stable, for CI pipelines and dashboards; see
+// also Queen.StatusJSON and WriteStatusCSV.
 type MigrationStatus struct {
 	// Version is the unique version identifier of the migration.
-	Version string
+	Version string `json:"version"`
 
 	// Name is the human-readable name of the migration.
-	Name string
+	Name string `json:"name"`
 
 	// Status indicates whether the migration is pending, applied, or modified.
-	Status Status
+	Status Status `json:"status"`
 
 	// AppliedAt is when the migration was applied (nil if not applied).
-	AppliedAt *time.Time
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+
+	// Duration is how long the migration took to execute (zero if not applied).
+	Duration time.Duration `json:"duration_ns,omitempty"`
+
+	// AppliedBy, Hostname and Operator identify who applied the migration
+	// and from where (empty if not applied). See Applied.
+	AppliedBy string `json:"applied_by,omitempty"`
+	Hostname  string `json:"hostname,omitempty"`
+	Operator  string `json:"operator,omitempty"`
+
+	// BuildInfo identifies the binary that applied the migration
+	// (empty if not applied). See Applied.BuildInfo.
+	BuildInfo string `json:"build_info,omitempty"`
+
+	// Metadata holds the extension fields stored with the migration
+	// (nil if not applied). See Applied.Metadata.
+	Metadata map[string]any `json:"metadata,omitempty"`
 
 	// Checksum is the current checksum of the migration.
-	Checksum string
+	Checksum string `json:"checksum"`
 
 	// HasRollback indicates if the migration has a down migration.
-	HasRollback bool
+	HasRollback bool `json:"has_rollback"`
+
+	// Destructive indicates if the migration contains destructive operations.
+	// See Migration.IsDestructive.
+	Destructive bool `json:"destructive"`
+}
+
+// StatusJSON returns Status as a JSON array, with the field names of
+// MigrationStatus.
+func (q *Queen) StatusJSON(ctx context.Context) ([]byte, error) {
+	statuses, err := q.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if statuses == nil {
+		statuses = []MigrationStatus{}
+	}
+	return json.Marshal(statuses)
+}
+
+// statusCSVHeader is the header row written by WriteStatusCSV.
+var statusCSVHeader = []string{
+	"version", "name", "status", "applied_at", "duration_ns", "applied_by",
+	"hostname", "operator", "build_info", "checksum", "has_rollback", "destructive",
+}
+
+// WriteStatusCSV writes statuses to w as CSV, with a header row of the
+// JSON field names of MigrationStatus except metadata. Times are RFC 3339
+// and empty when the migration isn't applied.
+func WriteStatusCSV(w io.Writer, statuses []MigrationStatus) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(statusCSVHeader); err != nil {
+		return err
+	}
+
+	for _, s := range statuses {
+		appliedAt := ""
+		if s.AppliedAt != nil {
+			appliedAt = s.AppliedAt.Format(time.RFC3339Nano)
+		}
+		if err := cw.Write([]string{
+			s.Version, s.Name, s.Status.String(), appliedAt, strconv.FormatInt(int64(s.Duration), 10),
+			s.AppliedBy, s.Hostname, s.Operator, s.BuildInfo, s.Checksum,
+			strconv.FormatBool(s.HasRollback), strconv.FormatBool(s.Destructive),
+		}); err != nil {
+			return err
+		}
+	}
 
-	// Destructive indicates if the down migration contains destructive operations.
-	Destructive bool
+	cw.Flush()
+	return cw.Error()
 }
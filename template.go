@@ -0,0 +1,55 @@
+package queen
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// render returns m with UpSQL and DownSQL expanded as text/template
+// templates over Config.TemplateVars. Without TemplateVars, m is returned
+// unchanged so SQL containing "{{" needs no escaping.
+//
+// Checksums are computed from the unexpanded SQL, so the same migration
+// has the same checksum in every environment.
+func (q *Queen) render(m *Migration) (*Migration, error) {
+	if q.config.TemplateVars == nil {
+		return m, nil
+	}
+
+	up, err := q.renderSQL(m.Version+"/up", m.UpSQL)
+	if err != nil {
+		return nil, err
+	}
+
+	down, err := q.renderSQL(m.Version+"/down", m.DownSQL)
+	if err != nil {
+		return nil, err
+	}
+
+	rendered := *m
+	rendered.UpSQL = up
+	rendered.DownSQL = down
+
+	return &rendered, nil
+}
+
+// renderSQL expands a single SQL template. Referencing a variable missing
+// from TemplateVars is an error.
+func (q *Queen) renderSQL(name, text string) (string, error) {
+	if !strings.Contains(text, "{{") {
+		return text, nil
+	}
+
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrInvalidMigration, err)
+	}
+
+	var b strings.Builder
+	if err := tmpl.Execute(&b, q.config.TemplateVars); err != nil {
+		return "", fmt.Errorf("%w: %w", ErrInvalidMigration, err)
+	}
+
+	return b.String(), nil
+}
@@ -2,9 +2,17 @@ package queen
 
 import (
 	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"slices"
 	"testing"
 )
 
+// errRollback makes RunInRollback's transaction roll back.
+var errRollback = errors.New("rollback")
+
 // TestHelper provides testing utilities for migrations.
 //
 // TestHelper wraps a Queen instance with test-specific helpers that
@@ -165,3 +173,95 @@ func (th *TestHelper) MustValidate() {
 		th.t.Fatalf("Migration validation failed: %v", err)
 	}
 }
+
+// RunInRollback applies pending migrations, then runs fn in a transaction
+// that is always rolled back, so test cases can share one migrated schema
+// without re-migrating or cleaning up:
+//
+//	q := queen.NewTest(t, driver)
+//	q.MustAdd(queen.M{...})
+//
+//	t.Run("insert", func(t *testing.T) {
+//	    q.RunInRollback(t, func(tx *sql.Tx) {
+//	        // Changes made through tx are discarded afterwards
+//	    })
+//	})
+//
+// Failures are reported to t, which may be a subtest of the one passed
+// to NewTest. Statements that commit implicitly, such as DDL on MySQL,
+// are not rolled back.
+func (th *TestHelper) RunInRollback(t *testing.T, fn func(tx *sql.Tx)) {
+	t.Helper()
+
+	if err := th.Up(th.ctx); err != nil {
+		t.Fatalf("Failed to apply migrations: %v", err)
+	}
+
+	// Canceling the context rolls the transaction back even if fn
+	// stops the test with t.FailNow
+	ctx, cancel := context.WithCancel(th.ctx)
+	defer cancel()
+
+	err := th.driver.Exec(ctx, func(tx *sql.Tx) error {
+		fn(tx)
+		return errRollback
+	})
+	if err != nil && !errors.Is(err, errRollback) {
+		t.Fatalf("Failed to run in transaction: %v", err)
+	}
+}
+
+// Isolated returns a helper working in a new, uniquely named schema or
+// database (a PostgreSQL schema, a MySQL database or an SQLite file) with
+// all registered migrations applied. It is dropped when t ends, so
+// parallel tests don't collide on a shared tracking table:
+//
+//	q := queen.NewTest(t, driver)
+//	q.MustAdd(queen.M{...})
+//
+//	t.Run("case", func(t *testing.T) {
+//	    t.Parallel()
+//	    iso := q.Isolated(t)
+//	    iso.RunInRollback(t, func(tx *sql.Tx) { ... })
+//	})
+//
+// Work in the isolated schema through the returned helper, e.g. with
+// RunInRollback or LoadFixtures: connections outside it still use the
+// original one. Requires a driver implementing Isolator.
+func (th *TestHelper) Isolated(t *testing.T) *TestHelper {
+	t.Helper()
+
+	isolator, ok := optional[Isolator](th.driver, FeatureIsolation)
+	if !ok {
+		t.Fatalf("Driver %T does not support isolated tests", th.driver)
+	}
+
+	var suffix [8]byte
+	_, _ = rand.Read(suffix[:])
+	name := "queen_test_" + hex.EncodeToString(suffix[:])
+
+	driver, drop, err := isolator.Isolate(th.ctx, name)
+	if err != nil {
+		t.Fatalf("Failed to create isolated schema: %v", err)
+	}
+	// The isolated driver shares the parent's connections, so it is
+	// dropped rather than closed
+	t.Cleanup(func() {
+		if err := drop(context.Background()); err != nil {
+			t.Errorf("Failed to drop isolated schema %s: %v", name, err)
+		}
+	})
+
+	q := NewWithConfig(driver, th.config)
+	q.migrations = slices.Clone(th.migrations)
+	q.hooks = th.hooks
+
+	if err := driver.Init(th.ctx); err != nil {
+		t.Fatalf("Failed to initialize driver: %v", err)
+	}
+
+	iso := &TestHelper{Queen: q, t: t, ctx: th.ctx}
+	iso.MustUp()
+
+	return iso
+}
@@ -0,0 +1,14 @@
+module github.com/honeynil/queen/wirequeen
+
+go 1.25.5
+
+require (
+	github.com/google/wire v0.7.0
+	github.com/honeynil/queen v0.0.0-20261018045953-b1da0e9ee158
+)
+
+require gopkg.in/yaml.v3 v3.0.1 // indirect
+
+// Builds in this repository use the queen next to this module; users get
+// the version required above, the first with this module split out.
+replace github.com/honeynil/queen => ../
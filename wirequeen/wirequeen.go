@@ -0,0 +1,58 @@
+// Package wirequeen provides github.com/google/wire providers for Queen.
+//
+// ProviderSet builds a *queen.Queen from a queen.Driver, an optional
+// *queen.Config and a slice of migrations, and a *lifecycle.Component that
+// is started as soon as it is constructed. The cleanup function returned by
+// the injector stops the component.
+//
+// # Usage
+//
+//	func initMigrations(ctx context.Context, d queen.Driver, cfg *queen.Config,
+//	    ms []queen.M, opts lifecycle.Options) (*lifecycle.Component, func(), error) {
+//	    wire.Build(wirequeen.ProviderSet)
+//	    return nil, nil, nil
+//	}
+package wirequeen
+
+import (
+	"context"
+
+	"github.com/google/wire"
+
+	"github.com/honeynil/queen"
+	"github.com/honeynil/queen/lifecycle"
+)
+
+// ProviderSet provides *queen.Queen and *lifecycle.Component.
+var ProviderSet = wire.NewSet(ProvideQueen, ProvideComponent)
+
+// ProvideQueen creates a Queen instance and registers migrations.
+// A nil config falls back to queen.DefaultConfig.
+func ProvideQueen(driver queen.Driver, config *queen.Config, migrations []queen.M) (*queen.Queen, error) {
+	q := queen.NewWithConfig(driver, config)
+
+	for _, m := range migrations {
+		if err := q.Add(m); err != nil {
+			return nil, err
+		}
+	}
+
+	return q, nil
+}
+
+// ProvideComponent creates and starts a lifecycle component for q.
+// The returned cleanup function stops the component.
+func ProvideComponent(ctx context.Context, q *queen.Queen, opts lifecycle.Options) (*lifecycle.Component, func(), error) {
+	c := lifecycle.New(q, opts)
+
+	if err := c.Start(ctx); err != nil {
+		return nil, nil, err
+	}
+
+	cleanup := func() {
+		// Cleanup has no way to report errors; Stop errors are ignored.
+		_ = c.Stop(context.Background())
+	}
+
+	return c, cleanup, nil
+}
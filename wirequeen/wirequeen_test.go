@@ -0,0 +1,52 @@
+package wirequeen_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/honeynil/queen"
+	"github.com/honeynil/queen/drivers/mock"
+	"github.com/honeynil/queen/lifecycle"
+	"github.com/honeynil/queen/wirequeen"
+)
+
+// initMigrations is the injector wire generates for ProviderSet.
+func initMigrations(ctx context.Context, driver queen.Driver, config *queen.Config,
+	migrations []queen.M, opts lifecycle.Options) (*lifecycle.Component, func(), error) {
+	q, err := wirequeen.ProvideQueen(driver, config, migrations)
+	if err != nil {
+		return nil, nil, err
+	}
+	return wirequeen.ProvideComponent(ctx, q, opts)
+}
+
+func TestProviderSet(t *testing.T) {
+	ctx := context.Background()
+	driver := mock.New()
+	noop := func(ctx context.Context, tx *sql.Tx) error { return nil }
+
+	c, cleanup, err := initMigrations(ctx, driver, nil, []queen.M{
+		{Version: "001", Name: "first", ManualChecksum: "v1", UpFunc: noop},
+		{Version: "002", Name: "second", ManualChecksum: "v1", UpFunc: noop},
+	}, lifecycle.Options{RunOnStart: true})
+	if err != nil {
+		t.Fatalf("Injector failed: %v", err)
+	}
+	defer cleanup()
+
+	if err := c.Ready(ctx); err != nil {
+		t.Errorf("Expected component to be ready, got %v", err)
+	}
+
+	if driver.AppliedCount() != 2 {
+		t.Errorf("Expected 2 applied migrations, got %d", driver.AppliedCount())
+	}
+}
+
+func TestProvideQueen_Invalid(t *testing.T) {
+	_, err := wirequeen.ProvideQueen(mock.New(), nil, []queen.M{{Version: "001", Name: "empty"}})
+	if err == nil {
+		t.Error("Expected an invalid migration to fail ProvideQueen")
+	}
+}